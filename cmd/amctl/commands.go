@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// indexer is a repository that can create its collection's indexes.
+type indexer interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+type namedIndexer struct {
+	collection string
+	repo       indexer
+}
+
+// app holds the services the commands run against and how they were invoked.
+type app struct {
+	users         *services.UserService
+	goals         *services.GoalService
+	counters      *services.CounterService
+	notifications *services.NotificationService
+	indexes       []namedIndexer
+
+	dryRun bool
+	yes    bool
+	in     io.Reader
+	out    io.Writer
+}
+
+// run dispatches noun and verb to the matching command.
+func (a *app) run(ctx context.Context, noun, verb string, args []string) error {
+	switch noun + " " + verb {
+	case "user verify":
+		return a.userVerify(ctx, args)
+	case "user resend":
+		return a.userResend(ctx, args)
+	case "user promote":
+		return a.userPromote(ctx, args)
+	case "goal unlock":
+		return a.goalUnlock(ctx, args)
+	case "goal recount":
+		return a.goalRecount(ctx, args)
+	case "notification purge-expired":
+		return a.purgeExpiredNotifications(ctx)
+	case "index ensure":
+		return a.ensureIndexes(ctx)
+	}
+	return fmt.Errorf("unknown command %q", noun+" "+verb)
+}
+
+// oneArg returns the single argument a command expects.
+func oneArg(args []string, name string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected exactly one argument: %s", name)
+	}
+	return args[0], nil
+}
+
+// confirm asks before a destructive action. It is skipped with -yes.
+func (a *app) confirm(action string) error {
+	if a.yes {
+		return nil
+	}
+	fmt.Fprintf(a.out, "%s? Type yes to continue: ", action)
+	answer, _ := bufio.NewReader(a.in).ReadString('\n')
+	if strings.TrimSpace(strings.ToLower(answer)) != "yes" {
+		return fmt.Errorf("aborted")
+	}
+	return nil
+}
+
+func (a *app) findUser(ctx context.Context, args []string) (*models.User, error) {
+	ref, err := oneArg(args, "user id or email")
+	if err != nil {
+		return nil, err
+	}
+	return a.users.FindUser(ctx, ref)
+}
+
+func (a *app) userVerify(ctx context.Context, args []string) error {
+	user, err := a.findUser(ctx, args)
+	if err != nil {
+		return err
+	}
+	if user.IsVerified {
+		return services.ErrAlreadyVerified
+	}
+	if a.dryRun {
+		fmt.Fprintf(a.out, "Would verify %s (%s)\n", user.Email, user.ID.Hex())
+		return nil
+	}
+	if err := a.users.VerifyUser(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Verified %s (%s)\n", user.Email, user.ID.Hex())
+	return nil
+}
+
+func (a *app) userResend(ctx context.Context, args []string) error {
+	user, err := a.findUser(ctx, args)
+	if err != nil {
+		return err
+	}
+	if user.IsVerified {
+		return services.ErrAlreadyVerified
+	}
+	if a.dryRun {
+		fmt.Fprintf(a.out, "Would send a new verification link to %s\n", user.Email)
+		return nil
+	}
+	if err := a.users.ResendVerification(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Sent a new verification link to %s\n", user.Email)
+	return nil
+}
+
+func (a *app) userPromote(ctx context.Context, args []string) error {
+	user, err := a.findUser(ctx, args)
+	if err != nil {
+		return err
+	}
+	if user.Role == services.RoleAdmin {
+		fmt.Fprintf(a.out, "%s is already an admin\n", user.Email)
+		return nil
+	}
+	if a.dryRun {
+		fmt.Fprintf(a.out, "Would promote %s (%s) from %s to admin\n", user.Email, user.ID.Hex(), user.Role)
+		return nil
+	}
+	if err := a.confirm(fmt.Sprintf("Give %s admin rights", user.Email)); err != nil {
+		return err
+	}
+	if _, err := a.users.SetRole(ctx, user.ID, services.RoleAdmin); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Promoted %s to admin\n", user.Email)
+	return nil
+}
+
+func (a *app) goalUnlock(ctx context.Context, args []string) error {
+	goalID, err := oneArg(args, "goal id")
+	if err != nil {
+		return err
+	}
+	goal, err := a.goals.GetGoal(ctx, goalID)
+	if err != nil {
+		return err
+	}
+	if goal.Status != services.GoalStatusClosed {
+		return services.ErrGoalNotClosed
+	}
+	if a.dryRun {
+		fmt.Fprintf(a.out, "Would reopen %q (%s), closed because: %s\n", goal.Name, goal.ID.Hex(), goal.ClosedReason)
+		return nil
+	}
+	reopened, err := a.goals.ReopenGoal(ctx, goalID, goal.UserID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Reopened %q, status is now %s\n", reopened.Name, reopened.Status)
+	return nil
+}
+
+func (a *app) goalRecount(ctx context.Context, args []string) error {
+	ref, err := oneArg(args, `user id, email or "all"`)
+	if err != nil {
+		return err
+	}
+
+	if ref == "all" {
+		if a.dryRun {
+			fmt.Fprintln(a.out, "Would recount the counters of every user")
+			return nil
+		}
+		updated, err := a.counters.Reconcile(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.out, "Recounted the counters of %d users\n", updated)
+		return nil
+	}
+
+	user, err := a.users.FindUser(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if a.dryRun {
+		// GetCounters would compute and store missing counters, so only
+		// read what is already there
+		stored, err := a.counters.GetCountersFor(ctx, []primitive.ObjectID{user.ID})
+		if err != nil {
+			return err
+		}
+		if current, ok := stored[user.ID]; ok {
+			fmt.Fprintf(a.out, "Would recount %s, currently %+v\n", user.Email, current)
+		} else {
+			fmt.Fprintf(a.out, "Would compute the counters of %s, none are stored yet\n", user.Email)
+		}
+		return nil
+	}
+	counters, err := a.counters.ReconcileUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Recounted %s: %+v\n", user.Email, *counters)
+	return nil
+}
+
+func (a *app) purgeExpiredNotifications(ctx context.Context) error {
+	count, err := a.notifications.CountExpiredNotifications(ctx)
+	if err != nil {
+		return err
+	}
+	if count == 0 || a.dryRun {
+		fmt.Fprintf(a.out, "%d expired notifications to delete\n", count)
+		return nil
+	}
+	if err := a.confirm(fmt.Sprintf("Delete %d expired notifications", count)); err != nil {
+		return err
+	}
+	if err := a.notifications.DeleteExpiredNotifications(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Deleted expired notifications\n")
+	return nil
+}
+
+func (a *app) ensureIndexes(ctx context.Context) error {
+	for _, idx := range a.indexes {
+		if a.dryRun {
+			fmt.Fprintf(a.out, "Would ensure indexes on %s\n", idx.collection)
+			continue
+		}
+		if err := idx.repo.EnsureIndexes(ctx); err != nil {
+			return fmt.Errorf("%s: %v", idx.collection, err)
+		}
+		fmt.Fprintf(a.out, "Ensured indexes on %s\n", idx.collection)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Command amctl runs common admin operations against the configured database.
+// It reuses the server's config, repositories and services, so it goes
+// through the same validation as the HTTP API.
+//
+// Usage:
+//
+//	amctl [-dry-run] [-yes] <noun> <verb> [arg]
+//
+//	user verify <id|email>         mark the email as verified
+//	user resend <id|email>         email a new verification link
+//	user promote <id|email>        give the user the admin role
+//	goal unlock <goal id>          reopen a closed goal
+//	goal recount <user id|email>   recompute the user's counters ("all" for everyone)
+//	notification purge-expired     delete expired notifications
+//	index ensure                   create the collection indexes
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/config"
+	"github.com/Dias221467/Achievemenet_Manager/internal/database"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// commandTimeout bounds a single amctl run.
+const commandTimeout = 5 * time.Minute
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "show what would change without writing anything")
+	yes := flag.Bool("yes", false, "skip the confirmation prompt for destructive actions")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.LoadConfig()
+	logger.InitLogger()
+
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Database connection error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	app := newApp(cfg, db)
+	app.dryRun = *dryRun
+	app.yes = *yes
+	app.in = os.Stdin
+	app.out = os.Stdout
+
+	if err := app.run(ctx, args[0], args[1], args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "amctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: amctl [-dry-run] [-yes] <noun> <verb> [arg]
+
+  user verify <id|email>         mark the email as verified
+  user resend <id|email>         email a new verification link
+  user promote <id|email>        give the user the admin role
+  goal unlock <goal id>          reopen a closed goal
+  goal recount <user id|email>   recompute the user's counters ("all" for everyone)
+  notification purge-expired     delete expired notifications
+  index ensure                   create the collection indexes
+
+Flags:`)
+	flag.PrintDefaults()
+}
+
+// newApp wires the repositories and services amctl needs the same way the server does.
+func newApp(cfg *config.Config, db *mongo.Database) *app {
+	userRepo := repository.NewUserRepository(db)
+	goalRepo := repository.NewGoalRepository(db)
+	friendRepo := repository.NewFriendRepository(db)
+	activityRepo := repository.NewActivityRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db, repository.NotificationRetention{
+		Default: cfg.NotificationRetentionDefault,
+		ByType:  cfg.NotificationRetention,
+	})
+	progressRepo := repository.NewProgressRepository(db)
+	invitationRepo := repository.NewGoalInvitationRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	preferencesRepo := repository.NewPreferencesRepository(db)
+	badgeRepo := repository.NewBadgeRepository(db)
+	counterRepo := repository.NewCounterRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	jobRunRepo := repository.NewJobRunRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+	templateBookmarkRepo := repository.NewTemplateBookmarkRepository(db)
+	templateVersionRepo := repository.NewTemplateVersionRepository(db)
+	chatRepo := repository.NewChatRepository(db)
+	friendInvitationRepo := repository.NewFriendInvitationRepository(db)
+
+	progressService := services.NewProgressService(progressRepo)
+	categoryService := services.NewCategoryService(categoryRepo, goalRepo)
+	counterService := services.NewCounterService(counterRepo, userRepo, goalRepo, notificationRepo, friendRepo)
+	notificationService := services.NewNotificationService(notificationRepo, userRepo, goalRepo, preferencesRepo, counterService, nil)
+	// No token blacklist since amctl never logs anyone out, and no mailer since
+	// queued emails would be lost when the process exits
+	userService := services.NewUserService(userRepo, goalRepo, activityRepo, preferencesRepo, refreshTokenRepo, repository.NewTokenBlacklistRepository(nil), nil, notificationService, nil, cfg.LastActiveInterval)
+	badgeService := services.NewBadgeService(badgeRepo, goalRepo, userRepo, notificationService, userService)
+	goalService := services.NewGoalService(goalRepo, userRepo, invitationRepo, notificationService, progressService, badgeService, counterService, categoryService, userService, cfg.MaxPinnedGoals)
+
+	return &app{
+		users:         userService,
+		goals:         goalService,
+		counters:      counterService,
+		notifications: notificationService,
+		indexes: []namedIndexer{
+			{"friend_requests", friendRepo},
+			{"refresh_tokens", refreshTokenRepo},
+			{"user_preferences", preferencesRepo},
+			{"badges", badgeRepo},
+			{"user_counters", counterRepo},
+			{"user_categories", categoryRepo},
+			{"job_runs", jobRunRepo},
+			{"activities", activityRepo},
+			{"templates", templateRepo},
+			{"template_bookmarks", templateBookmarkRepo},
+			{"template_versions", templateVersionRepo},
+			{"chats", chatRepo},
+			{"friend_invitations", friendInvitationRepo},
+		},
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/config"
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestApp wires amctl against a fresh test database. Answers to
+// confirmation prompts are read from stdin.
+func newTestApp(t *testing.T, stdin string) (*app, *testutil.Repositories, *bytes.Buffer) {
+	t.Helper()
+	repos := testutil.NewRepositories(t)
+	cfg := &config.Config{
+		NotificationRetentionDefault: 7 * 24 * time.Hour,
+		LastActiveInterval:           time.Minute,
+		MaxPinnedGoals:               3,
+	}
+	out := &bytes.Buffer{}
+	a := newApp(cfg, repos.DB)
+	a.in = strings.NewReader(stdin)
+	a.out = out
+	return a, repos, out
+}
+
+// seedUnverifiedUser seeds a user whose email was never confirmed.
+func seedUnverifiedUser(t *testing.T, repos *testutil.Repositories) *models.User {
+	t.Helper()
+	user := testutil.SeedUser(t, repos, models.User{})
+	if _, err := repos.Users.UpdateUser(context.Background(), user.ID, map[string]interface{}{"is_verified": false}); err != nil {
+		t.Fatalf("failed to unverify user: %v", err)
+	}
+	return user
+}
+
+func loadUser(t *testing.T, repos *testutil.Repositories, user *models.User) *models.User {
+	t.Helper()
+	stored, err := repos.Users.GetUserByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	return stored
+}
+
+func TestUserVerify(t *testing.T) {
+	a, repos, out := newTestApp(t, "")
+	ctx := context.Background()
+	user := seedUnverifiedUser(t, repos)
+
+	a.dryRun = true
+	if err := a.run(ctx, "user", "verify", []string{user.Email}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if loadUser(t, repos, user).IsVerified {
+		t.Fatal("dry run verified the user")
+	}
+	if !strings.Contains(out.String(), "Would verify") {
+		t.Errorf("dry run output = %q", out)
+	}
+
+	a.dryRun = false
+	if err := a.run(ctx, "user", "verify", []string{user.ID.Hex()}); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !loadUser(t, repos, user).IsVerified {
+		t.Error("user not verified")
+	}
+
+	// Same rules as the API: an address is only verified once, and a resend
+	// makes no sense afterwards
+	if err := a.run(ctx, "user", "verify", []string{user.Email}); !errors.Is(err, services.ErrAlreadyVerified) {
+		t.Errorf("second verify: err = %v, want ErrAlreadyVerified", err)
+	}
+	if err := a.run(ctx, "user", "resend", []string{user.Email}); !errors.Is(err, services.ErrAlreadyVerified) {
+		t.Errorf("resend: err = %v, want ErrAlreadyVerified", err)
+	}
+}
+
+func TestUserResendDryRun(t *testing.T) {
+	a, repos, out := newTestApp(t, "")
+	user := seedUnverifiedUser(t, repos)
+
+	a.dryRun = true
+	if err := a.run(context.Background(), "user", "resend", []string{user.Email}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(out.String(), "Would send a new verification link to "+user.Email) {
+		t.Errorf("output = %q", out)
+	}
+	if token := loadUser(t, repos, user).VerifyToken; token != user.VerifyToken {
+		t.Error("dry run replaced the verification token")
+	}
+}
+
+func TestUserPromoteConfirmation(t *testing.T) {
+	ctx := context.Background()
+
+	a, repos, out := newTestApp(t, "no\n")
+	user := testutil.SeedUser(t, repos, models.User{})
+	if err := a.run(ctx, "user", "promote", []string{user.Email}); err == nil || err.Error() != "aborted" {
+		t.Fatalf("declined promote: err = %v, want aborted", err)
+	}
+	if !strings.Contains(out.String(), "Type yes to continue") {
+		t.Errorf("no confirmation prompt in %q", out)
+	}
+	if role := loadUser(t, repos, user).Role; role != services.RoleUser {
+		t.Fatalf("role = %q after declining, want %q", role, services.RoleUser)
+	}
+
+	a.in = strings.NewReader("yes\n")
+	if err := a.run(ctx, "user", "promote", []string{user.Email}); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if role := loadUser(t, repos, user).Role; role != services.RoleAdmin {
+		t.Errorf("role = %q, want %q", role, services.RoleAdmin)
+	}
+
+	// -yes skips the prompt
+	other := testutil.SeedUser(t, repos, models.User{})
+	a.in = strings.NewReader("")
+	a.yes = true
+	if err := a.run(ctx, "user", "promote", []string{other.ID.Hex()}); err != nil {
+		t.Fatalf("promote with -yes: %v", err)
+	}
+	if role := loadUser(t, repos, other).Role; role != services.RoleAdmin {
+		t.Errorf("role = %q, want %q", role, services.RoleAdmin)
+	}
+}
+
+func TestGoalUnlock(t *testing.T) {
+	a, repos, _ := newTestApp(t, "")
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+	goal := testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "paused", Status: "in_progress"})
+
+	if err := a.run(ctx, "goal", "unlock", []string{goal.ID.Hex()}); !errors.Is(err, services.ErrGoalNotClosed) {
+		t.Fatalf("unlock of an open goal: err = %v, want ErrGoalNotClosed", err)
+	}
+
+	if _, err := a.goals.CloseGoal(ctx, goal.ID.Hex(), owner.ID, "on hold"); err != nil {
+		t.Fatalf("CloseGoal: %v", err)
+	}
+	a.dryRun = true
+	if err := a.run(ctx, "goal", "unlock", []string{goal.ID.Hex()}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if stored, _ := repos.Goals.GetGoalByID(ctx, goal.ID); stored.Status != services.GoalStatusClosed {
+		t.Fatalf("dry run changed status to %q", stored.Status)
+	}
+
+	a.dryRun = false
+	if err := a.run(ctx, "goal", "unlock", []string{goal.ID.Hex()}); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	stored, err := repos.Goals.GetGoalByID(ctx, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	if stored.Status == services.GoalStatusClosed {
+		t.Error("goal still closed after unlock")
+	}
+}
+
+func TestGoalRecount(t *testing.T) {
+	a, repos, out := newTestApp(t, "")
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Status: "completed"})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Status: "in_progress"})
+	testutil.SeedNotification(t, repos, owner.ID, "streak")
+
+	// Seeding bypassed the services, so the stored counters are empty
+	a.dryRun = true
+	if err := a.run(ctx, "goal", "recount", []string{owner.Email}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	stored, err := a.counters.GetCountersFor(ctx, []primitive.ObjectID{owner.ID})
+	if err != nil {
+		t.Fatalf("GetCountersFor: %v", err)
+	}
+	if len(stored) != 0 {
+		t.Fatalf("dry run stored counters %+v", stored)
+	}
+	if !strings.Contains(out.String(), "none are stored yet") {
+		t.Errorf("dry run output = %q", out)
+	}
+
+	a.dryRun = false
+	if err := a.run(ctx, "goal", "recount", []string{owner.Email}); err != nil {
+		t.Fatalf("recount: %v", err)
+	}
+	counters, err := a.counters.GetCounters(ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("GetCounters: %v", err)
+	}
+	if counters.Goals != 2 || counters.CompletedGoals != 1 || counters.UnreadNotifications != 1 {
+		t.Errorf("counters = %+v, want 2 goals, 1 completed, 1 unread", *counters)
+	}
+
+	if err := a.run(ctx, "goal", "recount", []string{"all"}); err != nil {
+		t.Fatalf("recount all: %v", err)
+	}
+}
+
+func TestNotificationPurgeExpired(t *testing.T) {
+	a, repos, out := newTestApp(t, "yes\n")
+	ctx := context.Background()
+	user := testutil.SeedUser(t, repos, models.User{})
+	testutil.SeedNotification(t, repos, user.ID, "streak")
+	testutil.SeedExpiredNotification(t, repos, user.ID, "streak", time.Hour)
+	testutil.SeedExpiredNotification(t, repos, user.ID, "streak", time.Hour)
+
+	count := func() int64 {
+		t.Helper()
+		n, err := repos.DB.Collection("notifications").CountDocuments(ctx, bson.M{"user_id": user.ID})
+		if err != nil {
+			t.Fatalf("failed to count notifications: %v", err)
+		}
+		return n
+	}
+
+	a.dryRun = true
+	if err := a.run(ctx, "notification", "purge-expired", nil); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !strings.Contains(out.String(), "2 expired notifications") || count() != 3 {
+		t.Fatalf("dry run output %q, %d notifications left", out, count())
+	}
+
+	a.dryRun = false
+	if err := a.run(ctx, "notification", "purge-expired", nil); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("%d notifications left, want the live one", n)
+	}
+}
+
+func TestIndexEnsure(t *testing.T) {
+	a, _, out := newTestApp(t, "")
+	ctx := context.Background()
+
+	a.dryRun = true
+	if err := a.run(ctx, "index", "ensure", nil); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if got := strings.Count(out.String(), "Would ensure"); got != len(a.indexes) {
+		t.Errorf("dry run listed %d collections, want %d", got, len(a.indexes))
+	}
+
+	a.dryRun = false
+	out.Reset()
+	if err := a.run(ctx, "index", "ensure", nil); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if got := strings.Count(out.String(), "Ensured indexes"); got != len(a.indexes) {
+		t.Errorf("ensured %d collections, want %d", got, len(a.indexes))
+	}
+}
+
+func TestRunRejectsBadInvocations(t *testing.T) {
+	a, _, _ := newTestApp(t, "")
+	ctx := context.Background()
+
+	if err := a.run(ctx, "goal", "explode", nil); err == nil {
+		t.Error("unknown command succeeded")
+	}
+	if err := a.run(ctx, "user", "verify", nil); err == nil {
+		t.Error("verify without a user succeeded")
+	}
+	if err := a.run(ctx, "user", "verify", []string{"nobody@example.com"}); err == nil {
+		t.Error("verify of an unknown user succeeded")
+	}
+}
@@ -5,19 +5,30 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/config"
 	"github.com/Dias221467/Achievemenet_Manager/internal/database"
 	"github.com/Dias221467/Achievemenet_Manager/internal/handlers"
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
 	"github.com/Dias221467/Achievemenet_Manager/internal/jobs"
+	"github.com/Dias221467/Achievemenet_Manager/internal/migrations"
 	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	cron "github.com/Dias221467/Achievemenet_Manager/internal/scheduler"
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/email"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/metrics"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func main() {
@@ -33,56 +44,261 @@ func main() {
 		log.Fatalf("Database connection error: %v", err)
 	}
 
+	// Redis is optional; without it logged-out access tokens stay valid until they expire
+	redisClient, err := database.ConnectRedis(cfg)
+	if err != nil {
+		logger.Log.WithError(err).Warn("Redis unavailable, token blacklist disabled until it is reachable")
+	}
+
 	// --- Repositories ---
 	userRepo := repository.NewUserRepository(db)
 	goalRepo := repository.NewGoalRepository(db)
 	friendRepo := repository.NewFriendRepository(db)
 	templateRepo := repository.NewTemplateRepository(db)
+	templateCopyRepo := repository.NewTemplateCopyRepository(db)
+	templateBookmarkRepo := repository.NewTemplateBookmarkRepository(db)
+	templateVersionRepo := repository.NewTemplateVersionRepository(db)
 	wishRepo := repository.NewWishRepository(db)
+	wishSuggestionRepo := repository.NewWishSuggestionRepository(db)
 	activityRepo := repository.NewActivityRepository(db)
-	notificationRepo := repository.NewNotificationRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db, repository.NotificationRetention{
+		Default: cfg.NotificationRetentionDefault,
+		ByType:  cfg.NotificationRetention,
+	})
+	progressRepo := repository.NewProgressRepository(db)
+	featureFlagRepo := repository.NewFeatureFlagRepository(db)
+	snippetRepo := repository.NewStepSnippetRepository(db)
+	invitationRepo := repository.NewGoalInvitationRepository(db)
+	friendInvitationRepo := repository.NewFriendInvitationRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	chatRepo := repository.NewChatRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	tokenBlacklistRepo := repository.NewTokenBlacklistRepository(redisClient)
+	secretRepo := repository.NewSecretRepository(db)
+	preferencesRepo := repository.NewPreferencesRepository(db)
+	badgeRepo := repository.NewBadgeRepository(db)
+	counterRepo := repository.NewCounterRepository(db)
+	categoryRepo := repository.NewCategoryRepository(db)
+	jobRunRepo := repository.NewJobRunRepository(db)
+	lockRepo := repository.NewLockRepository(db)
+
+	// Bring older documents up to the current schema before serving requests
+	if err := migrations.Run(context.Background(), db, lockRepo); err != nil {
+		logger.Log.WithError(err).Error("Failed to run schema migrations")
+	}
+
+	if err := friendRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create friend request indexes")
+	}
+	if err := refreshTokenRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create refresh token indexes")
+	}
+	if err := preferencesRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create preferences indexes")
+	}
+	if err := badgeRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create badge indexes")
+	}
+	if err := counterRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create user counters indexes")
+	}
+	if err := categoryRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create user category indexes")
+	}
+	if err := jobRunRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create job run indexes")
+	}
+	if err := activityRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create activity indexes")
+	}
+	if err := templateRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create template indexes")
+	}
+	if err := templateBookmarkRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create template bookmark indexes")
+	}
+	if err := templateVersionRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create template version indexes")
+	}
+	if err := chatRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create chat indexes")
+	}
+	if err := friendInvitationRepo.EnsureIndexes(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to create friend invitation indexes")
+	}
+
+	mailer := email.NewMailer(100)
 
 	// --- Services ---
-	userService := services.NewUserService(userRepo)
-	goalService := services.NewGoalService(goalRepo, userRepo, services.NewNotificationService(notificationRepo, userRepo, goalRepo))
-	friendService := services.NewFriendService(friendRepo, userRepo)
-	templateService := services.NewTemplateService(templateRepo, goalRepo)
-	wishService := services.NewWishService(wishRepo, goalRepo)
+	progressService := services.NewProgressService(progressRepo)
+	categoryService := services.NewCategoryService(categoryRepo, goalRepo)
+	counterService := services.NewCounterService(counterRepo, userRepo, goalRepo, notificationRepo, friendRepo)
+	// Connected WebSocket clients; notifications are pushed through it
+	chatHub := hub.New(func(ctx context.Context, userID string) ([]string, error) {
+		id, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := userRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		friends := make([]string, 0, len(user.Friends))
+		for _, friendID := range user.Friends {
+			friends = append(friends, friendID.Hex())
+		}
+		return friends, nil
+	})
+	notificationService := services.NewNotificationService(notificationRepo, userRepo, goalRepo, preferencesRepo, counterService, chatHub)
+	userService := services.NewUserService(userRepo, goalRepo, activityRepo, preferencesRepo, refreshTokenRepo, tokenBlacklistRepo, mailer, notificationService, chatHub, cfg.LastActiveInterval)
+	badgeService := services.NewBadgeService(badgeRepo, goalRepo, userRepo, notificationService, userService)
+	if err := badgeService.SeedBadges(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to seed badges")
+	}
+	goalService := services.NewGoalService(goalRepo, userRepo, invitationRepo, notificationService, progressService, badgeService, counterService, categoryService, userService, cfg.MaxPinnedGoals)
+	friendService := services.NewFriendService(friendRepo, userRepo, activityRepo, friendInvitationRepo, badgeService, counterService, chatHub)
 	activityService := services.NewActivityService(activityRepo)
-	notificationService := services.NewNotificationService(notificationRepo, userRepo, goalRepo)
+	templateService := services.NewTemplateService(templateRepo, templateCopyRepo, templateBookmarkRepo, templateVersionRepo, goalRepo, userRepo, notificationService, counterService, categoryService)
+	featureFlagService := services.NewFeatureFlagService(featureFlagRepo)
+	snippetService := services.NewStepSnippetService(snippetRepo, goalService)
+	commentService := services.NewCommentService(commentRepo)
+	chatService := services.NewChatService(chatRepo, userRepo, chatHub)
+	groupChatService := services.NewGroupChatService(chatRepo, userRepo, chatHub)
+	wishService := services.NewWishService(wishRepo, wishSuggestionRepo, goalRepo, userRepo, notificationService, counterService, cfg.StaleWishAge)
+	keyRotationService, err := services.NewKeyRotationService(secretRepo, cfg.JWTKeys, cfg.JWTSecret, cfg.JWTKeyEncryptionKey, cfg.JWTRotationGrace)
+	if err != nil {
+		logger.Log.Fatalf("Failed to set up JWT key rotation: %v", err)
+	}
+	if err := keyRotationService.LoadKeys(context.Background()); err != nil {
+		logger.Log.WithError(err).Error("Failed to load rotated JWT signing keys")
+	}
+	jobService := services.NewJobService(jobRunRepo)
+	accountService := services.NewAccountService(userRepo, goalRepo, notificationRepo, activityRepo, preferencesRepo, badgeRepo, categoryRepo, notificationService, counterService)
+
+	// Browser origins allowed to call the API and open the chat WebSocket
+	allowedOrigins := []string{"http://localhost:3000"} // adjust to frontend origin
 
 	// --- Handlers ---
-	userHandler := handlers.NewUserHandler(userService, cfg)
+	userHandler := handlers.NewUserHandler(userService, friendService, cfg)
+	oauthHandler := handlers.NewOAuthHandler(userService, cfg)
 	goalHandler := handlers.NewGoalHandler(goalService, activityService, notificationService)
-	friendHandler := handlers.NewFriendHandler(friendService, activityService, notificationService, userService)
+	friendHandler := handlers.NewFriendHandler(friendService, activityService, notificationService, userService, cfg)
 	templateHandler := handlers.NewTemplateHandler(templateService, goalService, activityService)
 	wishHandler := handlers.NewWishHandler(wishService, goalService, activityService)
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
-
-	// ----deadline_notifier ----
-	deadlinRepo := jobs.NewDeadlineNotifier(goalService, notificationService)
+	chatHandler := handlers.NewChatHandler(chatService)
+	groupChatHandler := handlers.NewGroupChatHandler(groupChatService)
+	chatSocketHandler := handlers.NewChatSocketHandler(chatHub, groupChatService, allowedOrigins)
+	progressHandler := handlers.NewProgressHandler(progressService, goalService)
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlagService)
+	snippetHandler := handlers.NewStepSnippetHandler(snippetService, goalService, activityService)
+	commentHandler := handlers.NewCommentHandler(commentService, goalService, activityService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	securityHandler := handlers.NewSecurityHandler(keyRotationService, activityService)
+	impersonationHandler := handlers.NewImpersonationHandler(userService, activityService, cfg)
+	badgeHandler := handlers.NewBadgeHandler(badgeService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	jobHandler := handlers.NewJobHandler(jobService)
+	healthHandler := handlers.NewHealthHandler(db)
 
 	// Initialize Gorilla Mux router
 	router := mux.NewRouter()
 
+	// Liveness and readiness probes; no authentication
+	router.HandleFunc("/health", healthHandler.HealthHandler).Methods("GET")
+	router.HandleFunc("/ready", healthHandler.ReadyHandler).Methods("GET")
+
+	// Prometheus scrape endpoint for request, job and application metrics
+	prometheus.MustRegister(jobService)
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// Public read-only view of shared goals; registered before the
+	// authenticated /goals subrouter so it is matched without a token
+	router.HandleFunc("/goals/shared/{token}", goalHandler.GetSharedGoalHandler).Methods("GET")
+	// Deprecated alias from the first version of share links; new links
+	// point at /goals/shared/{token}
+	router.HandleFunc("/shared/goals/{token}", middleware.Deprecated(func(r *http.Request) string {
+		return "/goals/shared/" + mux.Vars(r)["token"]
+	}, goalHandler.GetSharedGoalHandler)).Methods("GET")
+	// Calendar feed for calendar apps, which cannot send a JWT
+	router.HandleFunc("/goals/calendar/{token}.ics", goalHandler.CalendarFeedHandler).Methods("GET")
+	// Link in friend invitation emails, opened by people without an account
+	router.HandleFunc("/friends/accept-invite", friendHandler.AcceptInviteHandler).Methods("GET")
+
+	// Read-only template preview for logged-out visitors, with its own per-IP limit
+	publicTemplateRoutes := router.PathPrefix("/public/templates").Subrouter()
+	publicTemplateRoutes.Use(middleware.PerIPRateLimitMiddleware(cfg.PublicTemplatesRateLimit, cfg.RateLimitWindow))
+
+	publicTemplateRoutes.HandleFunc("", templateHandler.GetTemplatePreviewsHandler).Methods("GET")
+	publicTemplateRoutes.HandleFunc("/{id}", templateHandler.GetTemplatePreviewHandler).Methods("GET")
+
 	// Apply authentication middleware to goal routes
 	protectedRoutes := router.PathPrefix("/goals").Subrouter()
-	protectedRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protectedRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
 	protectedRoutes.Use(middleware.UpdateLastActiveMiddleware(userService))
 
 	protectedRoutes.HandleFunc("", goalHandler.CreateGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/export", goalHandler.ExportGoalsHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/export.ics", goalHandler.ExportICalHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/import", goalHandler.ImportGoalsHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/bulk", goalHandler.BulkGoalsHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/tags", goalHandler.GetGoalTagsHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/calendar", goalHandler.GetCalendarHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/shared", goalHandler.GetSharedGoalsHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/stats", goalHandler.GetGoalStatsHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/trash", goalHandler.GetTrashHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/trash", goalHandler.EmptyTrashHandler).Methods("DELETE")
+	protectedRoutes.HandleFunc("/invites", goalHandler.GetGoalInvitationsHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/invites/{id}/respond", goalHandler.RespondToGoalInvitationHandler).Methods("POST")
 	protectedRoutes.HandleFunc("/{id}", goalHandler.GetGoalHandler).Methods("GET")
 	protectedRoutes.HandleFunc("/{id}", goalHandler.UpdateGoalHandler).Methods("PUT")
 	protectedRoutes.HandleFunc("/{id}", goalHandler.DeleteGoalHandler).Methods("DELETE")
+	protectedRoutes.HandleFunc("/{id}/restore", goalHandler.RestoreGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/permanent", goalHandler.PermanentDeleteGoalHandler).Methods("DELETE")
 	protectedRoutes.HandleFunc("/{id}/progress", goalHandler.UpdateGoalProgressHandler).Methods("PATCH")
 	protectedRoutes.HandleFunc("/{id}/progress", goalHandler.GetGoalProgressHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/{id}/history", progressHandler.GetGoalHistoryHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/{id}/comments", commentHandler.CreateCommentHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/comments", commentHandler.GetCommentsHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/{id}/activity", goalHandler.GetGoalActivityHandler).Methods("GET")
+	protectedRoutes.HandleFunc("/{id}/comments/{commentID}", commentHandler.DeleteCommentHandler).Methods("DELETE")
+	protectedRoutes.HandleFunc("/{id}/confirm-completion", goalHandler.ConfirmCompletionHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/close", goalHandler.CloseGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/reopen", goalHandler.ReopenGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/pin", goalHandler.PinGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/unpin", goalHandler.UnpinGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/blocked-by", goalHandler.SetBlockedByHandler).Methods("PUT")
+	protectedRoutes.HandleFunc("/{id}/progress/bulk", goalHandler.BulkUpdateProgressHandler).Methods("PATCH")
 	protectedRoutes.HandleFunc("", goalHandler.GetGoalsHandler).Methods("GET")
 	protectedRoutes.HandleFunc("/{id}/invite", goalHandler.InviteCollaboratorHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/transfer", goalHandler.TransferGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/make-template", templateHandler.MakeTemplateHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/share-link", goalHandler.ShareGoalHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/share-link", goalHandler.RevokeShareHandler).Methods("DELETE")
+	// Deprecated alias of /{id}/share-link
+	shareLink := func(r *http.Request) string { return "/goals/" + mux.Vars(r)["id"] + "/share-link" }
+	protectedRoutes.HandleFunc("/{id}/share", middleware.Deprecated(shareLink, goalHandler.ShareGoalHandler)).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/share", middleware.Deprecated(shareLink, goalHandler.RevokeShareHandler)).Methods("DELETE")
+	protectedRoutes.HandleFunc("/{id}/steps/{stepIndex:[0-9]+}/substeps/reorder", goalHandler.ReorderSubstepsHandler).Methods("PATCH")
+	protectedRoutes.HandleFunc("/{id}/steps/{stepIndex:[0-9]+}/note", goalHandler.SetStepNoteHandler).Methods("PATCH")
+	protectedRoutes.HandleFunc("/{id}/substeps/move", goalHandler.MoveSubstepHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/steps/from-snippet/{snippetId}", snippetHandler.ApplySnippetHandler).Methods("POST")
+	protectedRoutes.HandleFunc("/{id}/steps/{stepName}", goalHandler.UpdateStepHandler).Methods("PATCH")
+	protectedRoutes.HandleFunc("/{id}/steps/{stepName}/substeps/{substepIndex}", goalHandler.UpdateSubstepHandler).Methods("PATCH")
 
 	// Register User routes
 	router.HandleFunc("/users/register", userHandler.RegisterUserHandler).Methods("POST")
 	router.HandleFunc("/users/login", userHandler.LoginUserHandler).Methods("POST")
+	router.HandleFunc("/users/login/totp", userHandler.LoginTOTPHandler).Methods("POST")
+	router.HandleFunc("/users/token/refresh", userHandler.RefreshTokenHandler).Methods("POST")
+	router.HandleFunc("/users/logout", userHandler.LogoutHandler).Methods("POST")
+	router.HandleFunc("/auth/google", oauthHandler.GoogleLoginHandler).Methods("GET")
+	router.HandleFunc("/auth/google/callback", oauthHandler.GoogleCallbackHandler).Methods("GET")
 	router.HandleFunc("/users/verify", userHandler.VerifyEmailHandler).Methods("GET")
+	router.HandleFunc("/users/verify-email-change", userHandler.VerifyEmailChangeHandler).Methods("GET")
+
+	// Public profiles; a token is only needed for profiles visible to friends
+	router.Handle("/users/{id}/profile", middleware.OptionalAuthMiddleware(cfg.JWTKeys, userService)(http.HandlerFunc(userHandler.GetProfileHandler))).Methods("GET")
 
 	// Password reset routes
 	router.HandleFunc("/users/request-password-reset", userHandler.RequestPasswordResetHandler).Methods("POST")
@@ -90,101 +306,222 @@ func main() {
 
 	// Protected user routes (only authenticated users can access)
 	protectedUserRoutes := router.PathPrefix("/users").Subrouter()
-	protectedUserRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protectedUserRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
 	protectedUserRoutes.Use(middleware.UpdateLastActiveMiddleware(userService))
 
+	protectedUserRoutes.HandleFunc("/resolve", userHandler.ResolveUsersHandler).Methods("POST")
+	protectedUserRoutes.HandleFunc("/blocked", userHandler.GetBlockedUsersHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/me/streak", userHandler.GetCompletionStreakHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/me/badges", badgeHandler.GetMyBadgesHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/me/calendar-token", userHandler.CreateCalendarTokenHandler).Methods("POST")
+	protectedUserRoutes.HandleFunc("/me/calendar-token", userHandler.RevokeCalendarTokenHandler).Methods("DELETE")
+	protectedUserRoutes.HandleFunc("/me/categories", categoryHandler.ListCategoriesHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/me/categories", categoryHandler.CreateCategoryHandler).Methods("POST")
+	protectedUserRoutes.HandleFunc("/me/categories/{categoryId}", categoryHandler.RenameCategoryHandler).Methods("PATCH")
+	protectedUserRoutes.HandleFunc("/me/categories/{categoryId}", categoryHandler.DeleteCategoryHandler).Methods("DELETE")
 	protectedUserRoutes.HandleFunc("/{id}", userHandler.GetUserHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/{id}/preferences", userHandler.GetPreferencesHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/{id}/preferences", userHandler.SavePreferencesHandler).Methods("POST", "PUT")
+	protectedUserRoutes.HandleFunc("/{id}/stats", userHandler.GetStatsHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/{id}/badges", badgeHandler.GetUserBadgesHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/{id}/mutual-friends", friendHandler.GetMutualFriendsHandler).Methods("GET")
+	protectedUserRoutes.HandleFunc("/{id}/block", userHandler.BlockUserHandler).Methods("POST")
+	protectedUserRoutes.HandleFunc("/{id}/block", userHandler.UnblockUserHandler).Methods("DELETE")
+	protectedUserRoutes.HandleFunc("/{id}/streak-freeze", userHandler.BuyStreakFreezeHandler).Methods("POST")
+	protectedUserRoutes.Handle("/{id}/change-email", middleware.DenyImpersonation(http.HandlerFunc(userHandler.ChangeEmailHandler))).Methods("POST")
+	protectedUserRoutes.Handle("/{id}/request-deletion", middleware.DenyImpersonation(http.HandlerFunc(accountHandler.RequestDeletionHandler))).Methods("POST")
+	protectedUserRoutes.Handle("/{id}/confirm-deletion", middleware.DenyImpersonation(http.HandlerFunc(accountHandler.ConfirmDeletionHandler))).Methods("DELETE")
+	protectedUserRoutes.Handle("/{id}/2fa/setup", middleware.DenyImpersonation(http.HandlerFunc(userHandler.SetupTOTPHandler))).Methods("POST")
+	protectedUserRoutes.Handle("/{id}/2fa/confirm", middleware.DenyImpersonation(http.HandlerFunc(userHandler.ConfirmTOTPHandler))).Methods("POST")
+	protectedUserRoutes.Handle("/{id}/2fa/disable", middleware.DenyImpersonation(http.HandlerFunc(userHandler.DisableTOTPHandler))).Methods("POST")
 	protectedUserRoutes.HandleFunc("/{id}", userHandler.UpdateUserHandler).Methods("PATCH")
 	protectedUserRoutes.HandleFunc("", userHandler.GetAllUsersHandler).Methods("GET")
 
 	// Template-related routes
 	protectedTemplateRoutes := router.PathPrefix("/templates").Subrouter()
-	protectedTemplateRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protectedTemplateRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
 	protectedTemplateRoutes.Use(middleware.UpdateLastActiveMiddleware(userService))
 
 	protectedTemplateRoutes.HandleFunc("", templateHandler.CreateTemplateHandler).Methods("POST")
 	protectedTemplateRoutes.HandleFunc("", templateHandler.GetTemplatesHandler).Methods("GET")
 	protectedTemplateRoutes.HandleFunc("/public", templateHandler.GetPublicTemplatesHandler).Methods("GET")
+	protectedTemplateRoutes.HandleFunc("/trending", templateHandler.GetTrendingTemplatesHandler).Methods("GET")
+	protectedTemplateRoutes.HandleFunc("/tags", templateHandler.GetTemplateTagsHandler).Methods("GET")
+	protectedTemplateRoutes.HandleFunc("/bookmarked", templateHandler.GetBookmarkedTemplatesHandler).Methods("GET")
 	protectedTemplateRoutes.HandleFunc("/user/{id}", templateHandler.GetTemplatesByUserHandler).Methods("GET")
 	protectedTemplateRoutes.HandleFunc("/{id}", templateHandler.GetTemplateByIDHandler).Methods("GET")
+	protectedTemplateRoutes.HandleFunc("/{id}", templateHandler.UpdateTemplateHandler).Methods("PATCH")
+	protectedTemplateRoutes.HandleFunc("/{id}/versions", templateHandler.GetTemplateVersionsHandler).Methods("GET")
+	protectedTemplateRoutes.HandleFunc("/{id}/versions/{version:[0-9]+}", templateHandler.GetTemplateVersionHandler).Methods("GET")
 	protectedTemplateRoutes.HandleFunc("/{id}/copy", templateHandler.CopyTemplateHandler).Methods("POST")
+	protectedTemplateRoutes.HandleFunc("/{id}/bookmark", templateHandler.BookmarkTemplateHandler).Methods("POST")
+	protectedTemplateRoutes.HandleFunc("/{id}/bookmark", templateHandler.UnbookmarkTemplateHandler).Methods("DELETE")
+
+	// Step snippet routes
+	protectedSnippetRoutes := router.PathPrefix("/snippets").Subrouter()
+	protectedSnippetRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
+	protectedSnippetRoutes.Use(middleware.UpdateLastActiveMiddleware(userService))
+
+	protectedSnippetRoutes.HandleFunc("", snippetHandler.CreateSnippetHandler).Methods("POST")
+	protectedSnippetRoutes.HandleFunc("", snippetHandler.GetSnippetsHandler).Methods("GET")
+	protectedSnippetRoutes.HandleFunc("/{id}", snippetHandler.GetSnippetHandler).Methods("GET")
+	protectedSnippetRoutes.HandleFunc("/{id}", snippetHandler.UpdateSnippetHandler).Methods("PUT")
+	protectedSnippetRoutes.HandleFunc("/{id}", snippetHandler.DeleteSnippetHandler).Methods("DELETE")
 
 	// Friend routes
 	protectedFriendRoutes := router.PathPrefix("/friends").Subrouter()
-	protectedFriendRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protectedFriendRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
 	protectedFriendRoutes.Use(middleware.UpdateLastActiveMiddleware(userService))
 
+	protectedFriendRoutes.HandleFunc("/invite", friendHandler.InviteFriendHandler).Methods("POST")
 	protectedFriendRoutes.HandleFunc("/{id}/request", friendHandler.SendFriendRequestHandler).Methods("POST")
 	protectedFriendRoutes.HandleFunc("/requests", friendHandler.GetPendingRequestsHandler).Methods("GET")
 	protectedFriendRoutes.HandleFunc("/requests/{id}/respond", friendHandler.RespondToFriendRequestHandler).Methods("POST")
 	protectedFriendRoutes.HandleFunc("", friendHandler.GetFriendsHandler).Methods("GET")
+	protectedFriendRoutes.HandleFunc("/leaderboard", friendHandler.GetLeaderboardHandler).Methods("GET")
+	protectedFriendRoutes.HandleFunc("/suggestions", friendHandler.GetSuggestionsHandler).Methods("GET")
+	protectedFriendRoutes.HandleFunc("/feed", friendHandler.GetFriendFeedHandler).Methods("GET")
 	protectedFriendRoutes.HandleFunc("/{id}", friendHandler.RemoveFriendHandler).Methods("DELETE")
 
+	// Chat routes
+	protectedChatRoutes := router.PathPrefix("/chat").Subrouter()
+	protectedChatRoutes.Use(middleware.WebSocketTokenMiddleware)
+	protectedChatRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
+	protectedChatRoutes.Use(middleware.UpdateLastActiveMiddleware(userService))
+	protectedChatRoutes.Use(middleware.RequireFeature(featureFlagService, services.FlagChat))
+
+	// Fixed paths come before /{friendId} so "ws" and "groups" are not read as friend IDs
+	protectedChatRoutes.HandleFunc("/ws", chatSocketHandler.ServeWS).Methods("GET")
+	protectedChatRoutes.HandleFunc("/groups", groupChatHandler.CreateGroupChatHandler).Methods("POST")
+	protectedChatRoutes.HandleFunc("/groups", groupChatHandler.GetGroupChatsHandler).Methods("GET")
+	protectedChatRoutes.HandleFunc("/groups/{id}/members", groupChatHandler.AddGroupMembersHandler).Methods("POST")
+	protectedChatRoutes.HandleFunc("/groups/{id}/members/{userID}", groupChatHandler.RemoveGroupMemberHandler).Methods("DELETE")
+	protectedChatRoutes.HandleFunc("/groups/{id}/history", groupChatHandler.GetGroupHistoryHandler).Methods("GET")
+	protectedChatRoutes.HandleFunc("/messages/{id}", chatHandler.DeleteMessageHandler).Methods("DELETE")
+	protectedChatRoutes.HandleFunc("/messages/{id}/react", chatHandler.AddReactionHandler).Methods("POST")
+	protectedChatRoutes.HandleFunc("/messages/{id}/react", chatHandler.RemoveReactionHandler).Methods("DELETE")
+	protectedChatRoutes.HandleFunc("/{friendId}", chatHandler.SendMessageHandler).Methods("POST")
+	protectedChatRoutes.HandleFunc("/{friendId}", chatHandler.GetChatHistoryHandler).Methods("GET")
+	protectedChatRoutes.HandleFunc("/{friendId}/unread-count", chatHandler.GetUnreadCountHandler).Methods("GET")
+
 	// Wish routes
 	protectedWishRoutes := router.PathPrefix("/wishes").Subrouter()
-	protectedWishRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protectedWishRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
 	protectedWishRoutes.Use(middleware.UpdateLastActiveMiddleware(userService))
 
 	protectedWishRoutes.HandleFunc("", wishHandler.CreateWishHandler).Methods("POST")
 	protectedWishRoutes.HandleFunc("", wishHandler.GetWishesHandler).Methods("GET")
+	protectedWishRoutes.HandleFunc("/stale", wishHandler.GetStaleWishesHandler).Methods("GET")
+	protectedWishRoutes.HandleFunc("/suggestions/{token}/accept", wishHandler.AcceptWishSuggestionHandler).Methods("POST")
 	protectedWishRoutes.HandleFunc("/{id}", wishHandler.GetWishByIDHandler).Methods("GET")
 	protectedWishRoutes.HandleFunc("/{id}", wishHandler.UpdateWishHandler).Methods("PUT")
 	protectedWishRoutes.HandleFunc("/{id}", wishHandler.DeleteWishHandler).Methods("DELETE")
 	protectedWishRoutes.HandleFunc("/{id}/promote", wishHandler.PromoteWishHandler).Methods("POST")
+	protectedWishRoutes.HandleFunc("/{id}/duplicate", wishHandler.DuplicateWishHandler).Methods("POST")
+	// Only sending suggestions is rolled out; anyone may accept one they got
+	requireWishSharing := middleware.RequireFeature(featureFlagService, services.FlagWishSharing)
+	protectedWishRoutes.Handle("/{id}/suggest", requireWishSharing(http.HandlerFunc(wishHandler.SuggestWishHandler))).Methods("POST")
 
 	protectedWishRoutes.HandleFunc("/{id}/upload", wishHandler.UploadWishImageHandler).Methods("POST")
 	router.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir("./uploads/"))))
 
 	// Notifications routes
 	protectedNotificationRoutes := router.PathPrefix("/notifications").Subrouter()
-	protectedNotificationRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	protectedNotificationRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
 
 	protectedNotificationRoutes.HandleFunc("", notificationHandler.GetUserNotificationsHandler).Methods("GET")
+	protectedNotificationRoutes.HandleFunc("/summary", notificationHandler.GetNotificationSummaryHandler).Methods("GET")
+	protectedNotificationRoutes.HandleFunc("/count", notificationHandler.GetNotificationCountHandler).Methods("GET")
 	protectedNotificationRoutes.HandleFunc("/{id}/read", notificationHandler.MarkAsReadHandler).Methods("POST")
 	protectedNotificationRoutes.HandleFunc("/{id}", notificationHandler.DeleteNotificationHandler).Methods("DELETE")
 
+	// Feature flags evaluated for the caller
+	flagRoutes := router.PathPrefix("/flags").Subrouter()
+	flagRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
+	flagRoutes.HandleFunc("", featureFlagHandler.GetMyFlagsHandler).Methods("GET")
+
 	// Admin routes
 	adminRoutes := router.PathPrefix("/admin").Subrouter()
-	adminRoutes.Use(middleware.AuthMiddleware(cfg.JWTSecret))
+	adminRoutes.Use(middleware.AuthMiddleware(cfg.JWTKeys, userService))
 
 	adminRoutes.Use(middleware.RequireRole("admin"))
 	adminRoutes.HandleFunc("/goals", goalHandler.GetAllGoalsHandler).Methods("GET")
 	adminRoutes.HandleFunc("/templates", templateHandler.AdminGetAllTemplatesHandler).Methods("GET")
-
-	// Apply middleware for logging
+	adminRoutes.HandleFunc("/templates/copiers", templateHandler.AdminTemplateCopiersHandler).Methods("GET")
+	adminRoutes.HandleFunc("/notifications/summary", notificationHandler.AdminNotificationSummaryHandler).Methods("GET")
+	adminRoutes.HandleFunc("/flags", featureFlagHandler.ListFlagsHandler).Methods("GET")
+	adminRoutes.HandleFunc("/flags/{name}", featureFlagHandler.GetFlagHandler).Methods("GET")
+	adminRoutes.HandleFunc("/flags/{name}", featureFlagHandler.SetFlagHandler).Methods("PUT")
+	adminRoutes.HandleFunc("/security/rotate-jwt", securityHandler.RotateJWTKeyHandler).Methods("POST")
+	adminRoutes.HandleFunc("/impersonate/{userId}", impersonationHandler.ImpersonateHandler).Methods("POST")
+	adminRoutes.HandleFunc("/jobs/status", jobHandler.JobStatusHandler).Methods("GET")
+
+	// Apply middleware for logging and request metrics
 	router.Use(middleware.LoggingMiddleware)
+	router.Use(middleware.MetricsMiddleware)
 
 	// Start the HTTP server
 	port := cfg.Port
 	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000"}, // adjust to frontend origin
+		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Request-ID"},
+		ExposedHeaders:   []string{"X-Request-ID"},
 		AllowCredentials: true,
 	})
 
-	handler := c.Handler(router)
+	// Count every response by status, including requests no route matched
+	instrumented := promhttp.InstrumentHandlerCounter(metrics.HTTPResponses, router)
+	// Rate limits wrap the router so they apply before any route is matched
+	rateLimited := middleware.RateLimiterMiddleware(cfg.RateLimitPerIP, cfg.RateLimitPerUser, cfg.RateLimitWindow)(instrumented)
+	// Request IDs are assigned first so every later log line can carry them;
+	// panic recovery wraps everything so no middleware can crash the server
+	handler := middleware.RecoveryMiddleware(middleware.RequestIDMiddleware(c.Handler(rateLimited)))
 
 	notifier := jobs.NewDeadlineNotifier(goalService, notificationService)
-	go func() {
-		for {
-			notifier.RunDailyScan(context.Background())
-			time.Sleep(24 * time.Hour)
-		}
-	}()
+	cron.Every(jobService, "deadline_scan", 24*time.Hour, true, func(ctx context.Context) (int, error) {
+		return 0, notifier.RunDailyScan(ctx)
+	})
+
+	cron.Every(jobService, "inactive_users", 24*time.Hour, false, func(ctx context.Context) (int, error) {
+		return 0, notificationService.CheckInactiveUsers(ctx)
+	})
+
+	// Push notifications held back by quiet hours once the window ends
+	cron.Every(jobService, "deferred_notifications", time.Minute, true, notificationService.DeliverDeferred)
 
+	trashPurger := jobs.NewTrashPurger(goalService)
+	cron.Every(jobService, "trash_purge", 24*time.Hour, true, trashPurger.RunDailyPurge)
+
+	// Persist throttled last-active timestamps in batches
+	cron.Every(jobService, "flush_last_active", cfg.LastActiveInterval, false, func(ctx context.Context) (int, error) {
+		return 0, userService.FlushLastActive(ctx)
+	})
+
+	cron.StartOnboardingCronJobs(jobService, notificationService)
+	cron.StartWishCronJobs(jobService, wishService)
+	cron.StartCounterCronJobs(jobService, counterService)
+
+	server := &http.Server{Addr: ":" + port, Handler: handler}
 	go func() {
-		ticker := time.NewTicker(24 * time.Hour)
-		for range ticker.C {
-			ctx := context.Background()
-			if err := notificationService.CheckInactiveUsers(ctx); err != nil {
-				logrus.WithError(err).Error("Failed to run inactive user check")
-			}
+		fmt.Printf("Server running on port %s\n", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
 		}
 	}()
 
-	go deadlinRepo.RunDailyScan(context.Background())
+	// Wait for a shutdown signal, then drain requests and flush pending writes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
 
-	fmt.Printf("Server running on port %s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, handler))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logrus.WithError(err).Error("Server shutdown failed")
+	}
+	if err := userService.FlushLastActive(shutdownCtx); err != nil {
+		logrus.WithError(err).Error("Failed to flush last active times on shutdown")
+	}
+	logger.Log.Info("Server stopped")
 }
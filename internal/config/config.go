@@ -3,18 +3,47 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
 	"github.com/joho/godotenv"
 )
 
 // Config struct holds application configuration
 type Config struct {
-	MongoURI    string
-	Database    string
-	Port        string
-	JWTSecret   string
-	TokenExpiry time.Duration
+	MongoURI           string
+	RedisURL           string // Optional; logged-out tokens are only blacklisted when set
+	Database           string
+	Port               string
+	JWTSecret          string
+	TokenExpiry        time.Duration // Lifetime of access tokens
+	RefreshTokenExpiry time.Duration // Lifetime of refresh tokens
+
+	JWTKeys             *jwtutil.KeyRing // Signing keys; starts with JWTSecret and gains rotated keys at runtime
+	JWTKeyEncryptionKey string           // Encrypts rotated signing keys at rest; falls back to JWTSecret
+	JWTRotationGrace    time.Duration    // How long the previous signing key stays valid after a rotation; stored refresh tokens outlive it
+
+	StaleWishAge       time.Duration // Wishes older than this are suggested for review
+	LastActiveInterval time.Duration // Minimum time between last_active_at writes per user
+	MaxPinnedGoals     int           // How many goals a user may pin
+
+	RateLimitPerIP   int           // Unauthenticated requests allowed per IP per window; 0 disables
+	RateLimitPerUser int           // Authenticated requests allowed per user per window; 0 disables
+	RateLimitWindow  time.Duration // Length of the sliding rate limit window
+
+	PublicTemplatesRateLimit int // Requests per IP per RateLimitWindow to the logged-out template preview; 0 disables
+
+	NotificationRetentionDefault time.Duration            // How long notifications live unless their type is listed below
+	NotificationRetention        map[string]time.Duration // Per-type retention; 0 keeps the notification until it is deleted
+
+	GoogleClientID     string
+	GoogleClientSecret string
+	GoogleRedirectURL  string // Our /auth/google/callback URL as registered with Google
+	OAuthSuccessURL    string // Frontend page that receives the JWT after a Google login
+
+	InviteRegisterURL string // Frontend registration page that emailed friend invitations lead to
 }
 
 // LoadConfig reads from the .env file
@@ -28,15 +57,143 @@ func LoadConfig() *Config {
 	// Convert string to time.Duration
 	expiry, err := time.ParseDuration(expiryStr)
 	if err != nil {
-		log.Printf("Invalid TOKEN_EXPIRY format, defaulting to 24h: %v", err)
-		expiry = 24 * time.Hour // Default to 24 hours if parsing fails
+		log.Printf("Invalid TOKEN_EXPIRY format, defaulting to 15m: %v", err)
+		expiry = 15 * time.Minute // Default to 15 minutes if parsing fails
+	}
+
+	refreshExpiry, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_EXPIRY"))
+	if err != nil || refreshExpiry <= 0 {
+		refreshExpiry = 30 * 24 * time.Hour // Default to 30 days
+	}
+
+	staleWishAge, err := time.ParseDuration(os.Getenv("STALE_WISH_AGE"))
+	if err != nil {
+		staleWishAge = 90 * 24 * time.Hour // Default to 90 days
+	}
+
+	lastActiveInterval, err := time.ParseDuration(os.Getenv("LAST_ACTIVE_WRITE_INTERVAL"))
+	if err != nil || lastActiveInterval <= 0 {
+		lastActiveInterval = 5 * time.Minute // Default to 5 minutes
+	}
+
+	maxPinned, err := strconv.Atoi(os.Getenv("MAX_PINNED_GOALS"))
+	if err != nil || maxPinned <= 0 {
+		maxPinned = 5 // Default to 5 goals
+	}
+
+	rateLimitPerIP, err := strconv.Atoi(os.Getenv("RATE_LIMIT_PER_IP"))
+	if err != nil || rateLimitPerIP < 0 {
+		rateLimitPerIP = 60 // Default to 60 requests per window
+	}
+
+	rateLimitPerUser, err := strconv.Atoi(os.Getenv("RATE_LIMIT_PER_USER"))
+	if err != nil || rateLimitPerUser < 0 {
+		rateLimitPerUser = 300 // Default to 300 requests per window
+	}
+
+	publicTemplatesRateLimit, err := strconv.Atoi(os.Getenv("PUBLIC_TEMPLATES_RATE_LIMIT"))
+	if err != nil || publicTemplatesRateLimit < 0 {
+		publicTemplatesRateLimit = 30 // Default to 30 requests per window
+	}
+
+	rateLimitWindow, err := time.ParseDuration(os.Getenv("RATE_LIMIT_WINDOW"))
+	if err != nil || rateLimitWindow <= 0 {
+		rateLimitWindow = time.Minute // Default to 1 minute
+	}
+
+	rotationGrace, err := time.ParseDuration(os.Getenv("JWT_ROTATION_GRACE"))
+	if err != nil || rotationGrace <= 0 {
+		rotationGrace = 24 * time.Hour // Default to 1 day
+	}
+
+	keyEncryptionKey := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if keyEncryptionKey == "" {
+		log.Println("Warning: JWT_KEY_ENCRYPTION_KEY not set, rotated signing keys are encrypted with JWT_SECRET.")
+		keyEncryptionKey = os.Getenv("JWT_SECRET")
+	}
+
+	retentionDefault, err := time.ParseDuration(os.Getenv("NOTIFICATION_RETENTION_DEFAULT"))
+	if err != nil || retentionDefault <= 0 {
+		retentionDefault = 7 * 24 * time.Hour // Default to 7 days
+	}
+
+	oauthSuccessURL := os.Getenv("OAUTH_SUCCESS_URL")
+	if oauthSuccessURL == "" {
+		oauthSuccessURL = "http://localhost:3000/auth/callback"
+	}
+
+	inviteRegisterURL := os.Getenv("INVITE_REGISTER_URL")
+	if inviteRegisterURL == "" {
+		inviteRegisterURL = "http://localhost:3000/register"
 	}
 
 	return &Config{
-		MongoURI:    os.Getenv("MONGO_URI"),
-		Database:    os.Getenv("DB_NAME"),
-		Port:        os.Getenv("PORT"),
-		JWTSecret:   os.Getenv("JWT_SECRET"),
-		TokenExpiry: expiry,
+		MongoURI:           os.Getenv("MONGO_URI"),
+		RedisURL:           os.Getenv("REDIS_URL"),
+		Database:           os.Getenv("DB_NAME"),
+		Port:               os.Getenv("PORT"),
+		JWTSecret:          os.Getenv("JWT_SECRET"),
+		TokenExpiry:        expiry,
+		RefreshTokenExpiry: refreshExpiry,
+
+		JWTKeys:             jwtutil.NewKeyRing(os.Getenv("JWT_SECRET")),
+		JWTKeyEncryptionKey: keyEncryptionKey,
+		JWTRotationGrace:    rotationGrace,
+
+		StaleWishAge:       staleWishAge,
+		LastActiveInterval: lastActiveInterval,
+		MaxPinnedGoals:     maxPinned,
+
+		RateLimitPerIP:   rateLimitPerIP,
+		RateLimitPerUser: rateLimitPerUser,
+		RateLimitWindow:  rateLimitWindow,
+
+		PublicTemplatesRateLimit: publicTemplatesRateLimit,
+
+		NotificationRetentionDefault: retentionDefault,
+		NotificationRetention:        parseRetention(os.Getenv("NOTIFICATION_RETENTION")),
+
+		GoogleClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+		GoogleClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+		GoogleRedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+		OAuthSuccessURL:    oauthSuccessURL,
+
+		InviteRegisterURL: inviteRegisterURL,
+	}
+}
+
+// defaultNotificationRetention keeps notifications that ask the user to act
+// until they are handled, keeps completed goals around longer and drops
+// inactivity nudges sooner than the default.
+var defaultNotificationRetention = map[string]time.Duration{
+	"friend_request_received": 0,
+	"goal_invite":             0,
+	"wish_suggested":          0,
+	"goal_completed":          30 * 24 * time.Hour,
+	"user_inactive":           3 * 24 * time.Hour,
+}
+
+// parseRetention reads per-type overrides in the form
+// "goal_due_soon=72h,friend_request_received=0" on top of the defaults.
+// Invalid entries are logged and skipped.
+func parseRetention(raw string) map[string]time.Duration {
+	retention := make(map[string]time.Duration, len(defaultNotificationRetention))
+	for notifType, ttl := range defaultNotificationRetention {
+		retention[notifType] = ttl
+	}
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		notifType, value, ok := strings.Cut(entry, "=")
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || ttl < 0 {
+			log.Printf("Ignoring invalid NOTIFICATION_RETENTION entry %q", entry)
+			continue
+		}
+		retention[strings.TrimSpace(notifType)] = ttl
 	}
+	return retention
 }
@@ -25,3 +25,8 @@ func ConnectDB(cfg *config.Config) (*mongo.Database, error) {
 	log.Println("Connected to MongoDB")
 	return db, nil
 }
+
+// Ping checks that the MongoDB server behind db is reachable.
+func Ping(ctx context.Context, db *mongo.Database) error {
+	return db.Client().Ping(ctx, nil)
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// ConnectRedis initializes a Redis client. It returns nil when no Redis URL
+// is configured. A client is returned even if the first ping fails, so the
+// server keeps working and picks Redis up once it becomes reachable.
+func ConnectRedis(cfg *config.Config) (*redis.Client, error) {
+	if cfg.RedisURL == "" {
+		return nil, nil
+	}
+
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return client, fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	log.Println("Connected to Redis")
+	return client, nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+)
+
+// AccountHandler exposes account deletion endpoints.
+type AccountHandler struct {
+	Service *services.AccountService
+}
+
+// NewAccountHandler creates a new AccountHandler.
+func NewAccountHandler(service *services.AccountService) *AccountHandler {
+	return &AccountHandler{Service: service}
+}
+
+// RequestDeletionHandler marks the caller's account for deletion and emails
+// a confirmation token.
+// POST /users/{id}/request-deletion
+func (h *AccountHandler) RequestDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	scheduledFor, err := h.Service.RequestDeletion(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to request account deletion")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":                "A confirmation email has been sent. Export your data with GET /goals/export before confirming.",
+		"deletion_scheduled_for": scheduledFor.Format(time.RFC3339),
+	})
+}
+
+// ConfirmDeletionHandler permanently deletes the caller's account and data.
+// DELETE /users/{id}/confirm-deletion?token=...
+func (h *AccountHandler) ConfirmDeletionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Missing deletion token", nil)
+		return
+	}
+
+	err := h.Service.ConfirmDeletion(r.Context(), userID, token)
+	switch {
+	case errors.Is(err, services.ErrInvalidDeletionToken):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Error("Failed to delete account")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to delete account", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BadgeHandler exposes the badges users unlocked.
+type BadgeHandler struct {
+	Service *services.BadgeService
+}
+
+// NewBadgeHandler creates a new BadgeHandler.
+func NewBadgeHandler(service *services.BadgeService) *BadgeHandler {
+	return &BadgeHandler{Service: service}
+}
+
+// GetMyBadgesHandler lists the badges the logged-in user has earned.
+// GET /users/me/badges
+func (h *BadgeHandler) GetMyBadgesHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+	h.writeBadges(w, r, userID)
+}
+
+// GetUserBadgesHandler lists the badges a user has earned.
+// GET /users/{id}/badges
+func (h *BadgeHandler) GetUserBadgesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+	h.writeBadges(w, r, userID)
+}
+
+func (h *BadgeHandler) writeBadges(w http.ResponseWriter, r *http.Request, userID primitive.ObjectID) {
+	badges, err := h.Service.GetUserBadges(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to fetch user badges")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch badges", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(badges)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CategoryHandler exposes the caller's custom goal categories.
+type CategoryHandler struct {
+	Service *services.CategoryService
+}
+
+// NewCategoryHandler creates a new CategoryHandler.
+func NewCategoryHandler(service *services.CategoryService) *CategoryHandler {
+	return &CategoryHandler{Service: service}
+}
+
+// ListCategoriesHandler returns the built-in categories and the caller's own.
+// GET /users/me/categories
+func (h *CategoryHandler) ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := callerID(w, r)
+	if !ok {
+		return
+	}
+
+	categories, err := h.Service.ListCategories(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to list categories")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to list categories", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// CreateCategoryHandler adds a custom category.
+// POST /users/me/categories
+func (h *CategoryHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	category, err := h.Service.CreateCategory(r.Context(), userID, body.Name)
+	if err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// RenameCategoryHandler renames a custom category; goals using it follow.
+// PATCH /users/me/categories/{categoryId}
+func (h *CategoryHandler) RenameCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	category, err := h.Service.RenameCategory(r.Context(), userID, mux.Vars(r)["categoryId"], body.Name)
+	if err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteCategoryHandler removes a custom category. Categories still used by
+// goals are only deleted with ?reassign=true, which clears the goals' category.
+// DELETE /users/me/categories/{categoryId}
+func (h *CategoryHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := callerID(w, r)
+	if !ok {
+		return
+	}
+
+	reassign := r.URL.Query().Get("reassign") == "true"
+	if err := h.Service.DeleteCategory(r.Context(), userID, mux.Vars(r)["categoryId"], reassign); err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// callerID returns the authenticated user's ID for /users/me routes.
+func callerID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return primitive.NilObjectID, false
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func writeCategoryError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCategory):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+	case errors.Is(err, services.ErrCategoryNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrCategoryExists), errors.Is(err, services.ErrCategoryInUse):
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+	default:
+		requestLogger(r).WithError(err).Error("Category operation failed")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update categories", nil)
+	}
+}
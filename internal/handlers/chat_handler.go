@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatHandler serves direct chats between friends.
+type ChatHandler struct {
+	Service *services.ChatService
+}
+
+// NewChatHandler initializes a new ChatHandler.
+func NewChatHandler(service *services.ChatService) *ChatHandler {
+	return &ChatHandler{Service: service}
+}
+
+// sendMessageRequest is the body of the send endpoint.
+type sendMessageRequest struct {
+	Text string `json:"text"`
+}
+
+// reactionRequest is the body of the reaction endpoints.
+type reactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+// callerID returns the authenticated user's ID, writing an error response
+// when there is none.
+func (h *ChatHandler) callerID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return primitive.NilObjectID, false
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+// writeChatError maps ChatService errors to responses.
+func writeChatError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrMessageNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Message not found", nil)
+	case errors.Is(err, services.ErrChatNotFriend), errors.Is(err, services.ErrMessageNotOwner):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidMessage), errors.Is(err, services.ErrInvalidReaction),
+		errors.Is(err, services.ErrTooManyReactions):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+	default:
+		requestLogger(r).WithError(err).Errorf("Failed to %s", action)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to "+action, nil)
+	}
+}
+
+// SendMessageHandler sends a text message to one of the caller's friends.
+// POST /chat/{friendId} {"text": "..."}
+func (h *ChatHandler) SendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	friendID, err := primitive.ObjectIDFromHex(mux.Vars(r)["friendId"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid friend ID", nil)
+		return
+	}
+
+	var req sendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	message, err := h.Service.SendMessage(r.Context(), userID, friendID, req.Text)
+	if err != nil {
+		writeChatError(w, r, err, "send message")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// GetChatHistoryHandler returns one page of the caller's chat with a friend,
+// newest first, and marks the friend's messages as read. Deleted messages are
+// returned as tombstones of type "deleted".
+// GET /chat/{friendId}?cursor=&limit=50
+func (h *ChatHandler) GetChatHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	friendID, err := primitive.ObjectIDFromHex(mux.Vars(r)["friendId"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid friend ID", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	var limit int64
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.Service.GetChatHistory(r.Context(), userID, friendID, query.Get("cursor"), limit)
+	if err != nil {
+		writeChatError(w, r, err, "fetch chat history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetUnreadCountHandler returns how many messages from a friend the caller
+// has not read yet. Fetching the chat history marks them as read.
+// GET /chat/{friendId}/unread-count
+func (h *ChatHandler) GetUnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	friendID, err := primitive.ObjectIDFromHex(mux.Vars(r)["friendId"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid friend ID", nil)
+		return
+	}
+
+	unread, err := h.Service.CountUnread(r.Context(), userID, friendID)
+	if err != nil {
+		writeChatError(w, r, err, "count unread messages")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"unread": unread})
+}
+
+// DeleteMessageHandler soft-deletes one of the caller's messages. It stays in
+// the history as a tombstone and both parties are sent a message_deleted frame.
+// DELETE /chat/messages/{id}
+func (h *ChatHandler) DeleteMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	messageID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid message ID", nil)
+		return
+	}
+
+	if err := h.Service.DeleteMessage(r.Context(), messageID, userID); err != nil {
+		writeChatError(w, r, err, "delete message")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddReactionHandler adds the caller's emoji reaction to a message.
+// POST /chat/messages/{id}/react {"emoji": "👍"}
+func (h *ChatHandler) AddReactionHandler(w http.ResponseWriter, r *http.Request) {
+	h.react(w, r, h.Service.AddReaction, "add reaction")
+}
+
+// RemoveReactionHandler takes back the caller's emoji reaction on a message.
+// DELETE /chat/messages/{id}/react {"emoji": "👍"}
+func (h *ChatHandler) RemoveReactionHandler(w http.ResponseWriter, r *http.Request) {
+	h.react(w, r, h.Service.RemoveReaction, "remove reaction")
+}
+
+func (h *ChatHandler) react(w http.ResponseWriter, r *http.Request, apply func(context.Context, primitive.ObjectID, primitive.ObjectID, string) error, action string) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	messageID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid message ID", nil)
+		return
+	}
+
+	var req reactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := apply(r.Context(), messageID, userID, req.Emoji); err != nil {
+		writeChatError(w, r, err, action)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Chat WebSocket limits.
+const (
+	socketWriteWait    = 10 * time.Second
+	socketPongWait     = 60 * time.Second
+	socketPingPeriod   = socketPongWait * 9 / 10
+	socketHelloWait    = 10 * time.Second
+	socketMaxFrameSize = 16 << 10
+	socketSendBuffer   = 64
+)
+
+var (
+	errSocketClosed  = errors.New("connection closed")
+	errSocketBacklog = errors.New("connection send buffer is full")
+)
+
+// ChatSocketHandler serves the chat WebSocket. Each connection is registered
+// with the hub so notifications, presence and chat frames reach the user.
+type ChatSocketHandler struct {
+	Hub       *hub.Hub
+	GroupChat *services.GroupChatService
+	upgrader  websocket.Upgrader
+}
+
+// NewChatSocketHandler initializes a ChatSocketHandler that accepts
+// connections from the given browser origins as well as from the server's own.
+func NewChatSocketHandler(wsHub *hub.Hub, groupChat *services.GroupChatService, allowedOrigins []string) *ChatSocketHandler {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+	return &ChatSocketHandler{
+		Hub:       wsHub,
+		GroupChat: groupChat,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || allowed[origin] || origin == "http://"+r.Host || origin == "https://"+r.Host
+			},
+		},
+	}
+}
+
+// socketConn adapts a WebSocket to hub.Conn. Frames are queued and written by
+// a single writer goroutine, so Send never blocks the hub.
+type socketConn struct {
+	ws       *websocket.Conn
+	log      *logrus.Entry
+	send     chan []byte
+	done     chan struct{} // closed when the reader stops
+	finished chan struct{} // closed when the writer has closed the socket
+}
+
+func (c *socketConn) Send(frame []byte) error {
+	select {
+	case <-c.done:
+		return errSocketClosed
+	default:
+	}
+	select {
+	case c.send <- frame:
+		return nil
+	case <-c.done:
+		return errSocketClosed
+	default:
+		return errSocketBacklog
+	}
+}
+
+// reply encodes a frame answering the client and queues it.
+func (c *socketConn) reply(id string, frame wsproto.Frame) {
+	data, err := wsproto.Encode(id, frame)
+	if err == nil {
+		err = c.Send(data)
+	}
+	if err != nil {
+		c.log.WithError(err).WithField("type", frame.FrameType()).Debug("Failed to reply on chat socket")
+	}
+}
+
+// writeLoop writes queued frames and keepalive pings until the reader stops,
+// then flushes what is left and closes the socket. A failed write closes the
+// socket early, which in turn stops the reader.
+func (c *socketConn) writeLoop() {
+	ticker := time.NewTicker(socketPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.ws.Close()
+		close(c.finished)
+	}()
+	for {
+		select {
+		case frame := <-c.send:
+			if c.write(websocket.TextMessage, frame) != nil {
+				return
+			}
+		case <-ticker.C:
+			if c.write(websocket.PingMessage, nil) != nil {
+				return
+			}
+		case <-c.done:
+			for {
+				select {
+				case frame := <-c.send:
+					if c.write(websocket.TextMessage, frame) != nil {
+						return
+					}
+				default:
+					c.write(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *socketConn) write(messageType int, data []byte) error {
+	c.ws.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return c.ws.WriteMessage(messageType, data)
+}
+
+// ServeWS upgrades the request to the chat WebSocket. The client must open
+// with a hello frame; the connection is registered with the hub once a
+// protocol version is agreed and unregistered when it closes, and the user's
+// friends are told when they come online or go offline.
+// GET /chat/ws (also accepts ?access_token= since browsers cannot set headers)
+func (h *ChatSocketHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already wrote the error response
+		requestLogger(r).WithError(err).Debug("Failed to upgrade chat socket")
+		return
+	}
+	conn := &socketConn{
+		ws:       ws,
+		log:      requestLogger(r).WithField("user_id", claims.UserID),
+		send:     make(chan []byte, socketSendBuffer),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	defer func() {
+		close(conn.done)
+		<-conn.finished
+	}()
+	go conn.writeLoop()
+
+	ws.SetReadLimit(socketMaxFrameSize)
+	helloID, version, ok := h.handshake(conn)
+	if !ok {
+		return
+	}
+
+	// Answer the hello only once the user is online, so the client knows
+	// frames for it are routed from then on
+	ctx := r.Context()
+	if h.Hub.Register(claims.UserID, conn) {
+		h.Hub.BroadcastStatus(ctx, claims.UserID, true)
+	}
+	defer func() {
+		if h.Hub.Unregister(claims.UserID, conn) {
+			h.Hub.BroadcastStatus(ctx, claims.UserID, false)
+		}
+	}()
+	conn.reply(helloID, wsproto.Hello{Version: version})
+
+	ws.SetReadDeadline(time.Now().Add(socketPongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+	for {
+		_, raw, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		ws.SetReadDeadline(time.Now().Add(socketPongWait))
+
+		env, frame, err := wsproto.Decode(raw)
+		if err != nil {
+			conn.reply("", wsproto.ErrorFor(env, err))
+			continue
+		}
+		h.handleFrame(ctx, conn, userID, env.ID, frame)
+	}
+}
+
+// handshake reads the client's hello and negotiates a protocol version. It
+// returns the hello's frame ID and the version, and reports whether the
+// connection may proceed; on failure the client has been sent an error frame.
+func (h *ChatSocketHandler) handshake(conn *socketConn) (string, int, bool) {
+	conn.ws.SetReadDeadline(time.Now().Add(socketHelloWait))
+	_, raw, err := conn.ws.ReadMessage()
+	if err != nil {
+		return "", 0, false
+	}
+
+	env, frame, err := wsproto.Decode(raw)
+	if err != nil {
+		conn.reply("", wsproto.ErrorFor(env, err))
+		return "", 0, false
+	}
+	hello, ok := frame.(*wsproto.Hello)
+	if !ok {
+		conn.reply("", wsproto.Error{Code: wsproto.CodeBadFrame, Message: "the first frame must be hello", RefID: env.ID})
+		return "", 0, false
+	}
+	version, err := wsproto.Negotiate(*hello)
+	if err != nil {
+		conn.reply("", wsproto.ErrorFor(env, err))
+		return "", 0, false
+	}
+	return env.ID, version, true
+}
+
+// handleFrame acts on one frame from the client. Failures are reported back
+// to the client as error frames referencing the frame's ID.
+func (h *ChatSocketHandler) handleFrame(ctx context.Context, conn *socketConn, userID primitive.ObjectID, id string, frame wsproto.Frame) {
+	var err error
+	switch f := frame.(type) {
+	case *wsproto.Typing:
+		err = h.relayTyping(ctx, userID, *f)
+	case *wsproto.GroupText:
+		err = h.sendGroupText(ctx, conn, userID, id, *f)
+	default:
+		err = fmt.Errorf("%w: clients cannot send %s frames", errFrameRejected, frame.FrameType())
+	}
+	if err != nil {
+		conn.reply("", conn.socketError(id, err))
+	}
+}
+
+// errFrameRejected marks frames the server understood but refused.
+var errFrameRejected = errors.New("frame rejected")
+
+// relayTyping forwards a typing frame to the other members of the group chat
+// named by its chat_id who have not blocked the typist.
+func (h *ChatSocketHandler) relayTyping(ctx context.Context, userID primitive.ObjectID, frame wsproto.Typing) error {
+	groupID, err := primitive.ObjectIDFromHex(frame.ChatID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid chat_id", errFrameRejected)
+	}
+	recipients, err := h.GroupChat.RecipientIDs(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	h.Hub.RelayTyping(userID.Hex(), frame, recipients)
+	return nil
+}
+
+// sendGroupText stores a group message and acks it to the sender; the service
+// relays it to the other online members.
+func (h *ChatSocketHandler) sendGroupText(ctx context.Context, conn *socketConn, userID primitive.ObjectID, id string, frame wsproto.GroupText) error {
+	groupID, err := primitive.ObjectIDFromHex(frame.GroupID)
+	if err != nil {
+		return fmt.Errorf("%w: invalid group_id", errFrameRejected)
+	}
+	message, err := h.GroupChat.SendText(ctx, groupID, userID, frame.Text)
+	if err != nil {
+		return err
+	}
+	conn.reply("", wsproto.Ack{RefID: id, MessageID: message.ID.Hex()})
+	return nil
+}
+
+// socketError maps a frame handling error to the error frame sent back.
+func (c *socketConn) socketError(refID string, err error) wsproto.Error {
+	frame := wsproto.Error{Code: wsproto.CodeRejected, Message: err.Error(), RefID: refID}
+	switch {
+	case errors.Is(err, errFrameRejected), errors.Is(err, services.ErrGroupChatNotFound),
+		errors.Is(err, services.ErrInvalidGroupChat):
+	default:
+		c.log.WithError(err).Error("Failed to handle chat frame")
+		frame.Message = "internal error"
+	}
+	return frame
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
+	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newChatSocketServer serves the chat WebSocket behind the logging middleware,
+// authenticating each connection as the user named by the ?user= parameter.
+func newChatSocketServer(t *testing.T, svc *testutil.Services) *httptest.Server {
+	t.Helper()
+	handler := NewChatSocketHandler(svc.Hub, svc.GroupChat, nil)
+	srv := httptest.NewServer(middleware.LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := primitive.ObjectIDFromHex(r.URL.Query().Get("user"))
+		if err != nil {
+			handler.ServeWS(w, r)
+			return
+		}
+		handler.ServeWS(w, asUser(r, userID))
+	})))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dialSocket(t *testing.T, srv *httptest.Server, userID primitive.ObjectID) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/chat/ws?user=" + userID.Hex()
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial chat socket: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func sendFrame(t *testing.T, ws *websocket.Conn, id string, frame wsproto.Frame) {
+	t.Helper()
+	data, err := wsproto.Encode(id, frame)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if err := ws.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatalf("failed to send %s frame: %v", frame.FrameType(), err)
+	}
+}
+
+func readFrame(t *testing.T, ws *websocket.Conn) (*wsproto.Envelope, wsproto.Frame) {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, raw, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	env, frame, err := wsproto.Decode(raw)
+	if err != nil {
+		t.Fatalf("Decode(%s): %v", raw, err)
+	}
+	return env, frame
+}
+
+// connectChat dials the socket and completes the hello handshake.
+func connectChat(t *testing.T, srv *httptest.Server, userID primitive.ObjectID) *websocket.Conn {
+	t.Helper()
+	ws := dialSocket(t, srv, userID)
+	sendFrame(t, ws, "hello", wsproto.Hello{Versions: []int{wsproto.Version}})
+	env, frame := readFrame(t, ws)
+	hello, ok := frame.(*wsproto.Hello)
+	if !ok || hello.Version != wsproto.Version || env.ID != "hello" {
+		t.Fatalf("handshake answer = %+v %+v, want hello version %d", env, frame, wsproto.Version)
+	}
+	return ws
+}
+
+// seedFriends seeds two users who are friends of each other.
+func seedFriends(t *testing.T, svc *testutil.Services) (*models.User, *models.User) {
+	t.Helper()
+	ctx := context.Background()
+	alice := testutil.SeedUser(t, svc.Repositories, models.User{})
+	bob := testutil.SeedUser(t, svc.Repositories, models.User{})
+	if err := svc.Users.AddFriend(ctx, alice.ID, bob.ID); err != nil {
+		t.Fatalf("AddFriend: %v", err)
+	}
+	if err := svc.Users.AddFriend(ctx, bob.ID, alice.ID); err != nil {
+		t.Fatalf("AddFriend: %v", err)
+	}
+	return alice, bob
+}
+
+func waitOffline(t *testing.T, svc *testutil.Services, userID primitive.ObjectID) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); svc.Hub.Online(userID.Hex()); {
+		if time.Now().After(deadline) {
+			t.Fatal("connection still registered after close")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestChatSocketHandshake(t *testing.T) {
+	svc := testutil.NewServices(t)
+	srv := newChatSocketServer(t, svc)
+	user := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	resp, err := http.Get(srv.URL + "/chat/ws")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d, want 401", resp.StatusCode)
+	}
+
+	tests := []struct {
+		name     string
+		frame    wsproto.Frame
+		wantCode string
+	}{
+		{"not hello", wsproto.Typing{ChatID: primitive.NewObjectID().Hex(), Typing: true}, wsproto.CodeBadFrame},
+		{"no common version", wsproto.Hello{Versions: []int{wsproto.Version + 1}}, wsproto.CodeUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := dialSocket(t, srv, user.ID)
+			sendFrame(t, ws, "first", tt.frame)
+			_, frame := readFrame(t, ws)
+			if e, ok := frame.(*wsproto.Error); !ok || e.Code != tt.wantCode || e.RefID != "first" {
+				t.Fatalf("answer = %+v, want a %s error", frame, tt.wantCode)
+			}
+			if _, _, err := ws.ReadMessage(); err == nil {
+				t.Error("connection still open after a failed handshake")
+			}
+			if svc.Hub.Online(user.ID.Hex()) {
+				t.Error("connection registered without a handshake")
+			}
+		})
+	}
+
+	ws := connectChat(t, srv, user.ID)
+	if !svc.Hub.Online(user.ID.Hex()) {
+		t.Fatal("connection not registered after the handshake")
+	}
+	ws.Close()
+	waitOffline(t, svc, user.ID)
+}
+
+func TestChatSocketPresenceAndTyping(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	srv := newChatSocketServer(t, svc)
+	alice, bob := seedFriends(t, svc)
+	stranger := testutil.SeedUser(t, svc.Repositories, models.User{})
+	group, err := svc.GroupChat.CreateGroupChat(ctx, alice.ID, "pair", []primitive.ObjectID{bob.ID})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	bobWS := connectChat(t, srv, bob.ID)
+	aliceWS := connectChat(t, srv, alice.ID)
+	strangerWS := connectChat(t, srv, stranger.ID)
+
+	// Bob hears about his friend, the stranger's arrival goes nowhere
+	_, frame := readFrame(t, bobWS)
+	if status, ok := frame.(*wsproto.Status); !ok || status.UserID != alice.ID.Hex() || !status.Online {
+		t.Fatalf("bob got %+v, want alice online", frame)
+	}
+
+	// Only the first of two quick typing frames is relayed
+	sendFrame(t, aliceWS, "t1", wsproto.Typing{ChatID: group.ID.Hex(), Typing: true})
+	sendFrame(t, aliceWS, "t2", wsproto.Typing{ChatID: group.ID.Hex(), Typing: true})
+	_, frame = readFrame(t, bobWS)
+	if typing, ok := frame.(*wsproto.Typing); !ok || typing.UserID != alice.ID.Hex() || typing.ChatID != group.ID.Hex() {
+		t.Fatalf("bob got %+v, want alice typing", frame)
+	}
+
+	// Typing into a group one is not part of is refused
+	sendFrame(t, strangerWS, "t3", wsproto.Typing{ChatID: group.ID.Hex(), Typing: true})
+	_, frame = readFrame(t, strangerWS)
+	if e, ok := frame.(*wsproto.Error); !ok || e.Code != wsproto.CodeRejected || e.RefID != "t3" {
+		t.Fatalf("stranger got %+v, want a rejected error", frame)
+	}
+
+	aliceWS.Close()
+	_, frame = readFrame(t, bobWS)
+	if status, ok := frame.(*wsproto.Status); !ok || status.UserID != alice.ID.Hex() || status.Online || status.LastSeen == nil {
+		t.Fatalf("bob got %+v, want alice offline", frame)
+	}
+	waitOffline(t, svc, alice.ID)
+}
+
+func TestChatSocketGroupText(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	srv := newChatSocketServer(t, svc)
+	alice, bob := seedFriends(t, svc)
+	group, err := svc.GroupChat.CreateGroupChat(ctx, alice.ID, "pair", []primitive.ObjectID{bob.ID})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	bobWS := connectChat(t, srv, bob.ID)
+	aliceWS := connectChat(t, srv, alice.ID)
+	readFrame(t, bobWS) // alice online
+
+	sendFrame(t, aliceWS, "m1", wsproto.GroupText{GroupID: group.ID.Hex(), Text: " hi all "})
+	_, frame := readFrame(t, aliceWS)
+	ack, ok := frame.(*wsproto.Ack)
+	if !ok || ack.RefID != "m1" || ack.MessageID == "" {
+		t.Fatalf("alice got %+v, want an ack for m1", frame)
+	}
+
+	_, frame = readFrame(t, bobWS)
+	text, ok := frame.(*wsproto.GroupText)
+	if !ok || text.MessageID != ack.MessageID || text.SenderID != alice.ID.Hex() || text.Text != "hi all" {
+		t.Fatalf("bob got %+v, want alice's message", frame)
+	}
+
+	page, err := svc.GroupChat.GetHistory(ctx, group.ID, bob.ID, "", 0)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].ID.Hex() != ack.MessageID {
+		t.Errorf("history = %+v, want the sent message", page.Messages)
+	}
+
+	tests := []struct {
+		name  string
+		frame wsproto.GroupText
+	}{
+		{"empty text", wsproto.GroupText{GroupID: group.ID.Hex(), Text: "  "}},
+		{"unknown group", wsproto.GroupText{GroupID: primitive.NewObjectID().Hex(), Text: "hi"}},
+		{"bad group id", wsproto.GroupText{GroupID: "nope", Text: "hi"}},
+	}
+	for _, tt := range tests {
+		sendFrame(t, aliceWS, tt.name, tt.frame)
+		_, frame := readFrame(t, aliceWS)
+		if e, ok := frame.(*wsproto.Error); !ok || e.Code != wsproto.CodeRejected || e.RefID != tt.name {
+			t.Errorf("%s: alice got %+v, want a rejected error", tt.name, frame)
+		}
+	}
+}
+
+func TestChatSocketBlockedUser(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	srv := newChatSocketServer(t, svc)
+	alice, bob := seedFriends(t, svc)
+	group, err := svc.GroupChat.CreateGroupChat(ctx, alice.ID, "pair", []primitive.ObjectID{bob.ID})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	bobWS := connectChat(t, srv, bob.ID)
+	aliceWS := connectChat(t, srv, alice.ID)
+	readFrame(t, bobWS) // alice online, which caches bob as her friend
+
+	if err := svc.User.BlockUser(ctx, bob.ID, alice.ID); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+
+	sendFrame(t, aliceWS, "t1", wsproto.Typing{ChatID: group.ID.Hex(), Typing: true})
+	sendFrame(t, aliceWS, "m1", wsproto.GroupText{GroupID: group.ID.Hex(), Text: "hi"})
+	if _, frame := readFrame(t, aliceWS); frame.FrameType() != (wsproto.Ack{}).FrameType() {
+		t.Fatalf("alice got %+v, want an ack for m1", frame)
+	}
+	aliceWS.Close()
+	waitOffline(t, svc, alice.ID)
+
+	// The first frame bob gets is the answer to his own, so neither alice's
+	// typing, her message nor her going offline reached him
+	sendFrame(t, bobWS, "bad", wsproto.Read{ChatID: "nope"})
+	if _, frame := readFrame(t, bobWS); frame.FrameType() != (wsproto.Error{}).FrameType() {
+		t.Fatalf("bob got %+v from the user he blocked", frame)
+	}
+}
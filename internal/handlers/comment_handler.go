@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentHandler serves the comment thread attached to each goal.
+type CommentHandler struct {
+	Service         *services.CommentService
+	GoalService     *services.GoalService
+	ActivityService *services.ActivityService
+}
+
+// NewCommentHandler initializes a new CommentHandler.
+func NewCommentHandler(service *services.CommentService, goalService *services.GoalService, activityService *services.ActivityService) *CommentHandler {
+	return &CommentHandler{
+		Service:         service,
+		GoalService:     goalService,
+		ActivityService: activityService,
+	}
+}
+
+// loadGoalForComments fetches the goal and checks that the caller is its owner
+// or a collaborator of any role. It writes the error response itself and
+// returns nil when the request should stop.
+func (h *CommentHandler) loadGoalForComments(w http.ResponseWriter, r *http.Request, log *logrus.Entry) (*models.Goal, primitive.ObjectID) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return nil, primitive.NilObjectID
+	}
+
+	goal, err := h.GoalService.GetGoal(r.Context(), mux.Vars(r)["id"])
+	if err != nil || goal == nil {
+		log.WithError(err).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return nil, primitive.NilObjectID
+	}
+
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleViewer) {
+		log.Warn("Forbidden: Not owner or collaborator")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden", nil)
+		return nil, primitive.NilObjectID
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	return goal, userID
+}
+
+// CreateCommentHandler posts a comment on a goal.
+// POST /goals/{id}/comments {"text": "..."}
+func (h *CommentHandler) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r).WithField("goalID", mux.Vars(r)["id"])
+
+	goal, userID := h.loadGoalForComments(w, r, log)
+	if goal == nil {
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	comment, err := h.Service.AddComment(r.Context(), goal, userID, req.Text)
+	if err != nil {
+		log.WithError(err).Warn("Failed to add comment")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "comment_added", goal.ID, "Commented on goal: "+goal.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// GetCommentsHandler returns a page of a goal's comments, oldest first.
+// GET /goals/{id}/comments?cursor=<last comment id>&limit=20
+func (h *CommentHandler) GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r).WithField("goalID", mux.Vars(r)["id"])
+
+	goal, _ := h.loadGoalForComments(w, r, log)
+	if goal == nil {
+		return
+	}
+
+	var limit int64
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.Service.GetComments(r.Context(), goal.ID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		log.WithError(err).Warn("Failed to fetch comments")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// DeleteCommentHandler hides a comment from the thread. The author and the
+// goal owner may delete it.
+func (h *CommentHandler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID := mux.Vars(r)["commentID"]
+	log := requestLogger(r).WithFields(logrus.Fields{"goalID": mux.Vars(r)["id"], "commentID": commentID})
+
+	goal, userID := h.loadGoalForComments(w, r, log)
+	if goal == nil {
+		return
+	}
+
+	if err := h.Service.DeleteComment(r.Context(), goal, commentID, userID); err != nil {
+		log.WithError(err).Warn("Failed to delete comment")
+		switch {
+		case errors.Is(err, services.ErrCommentNotFound):
+			apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Comment not found", nil)
+		case errors.Is(err, services.ErrCommentForbidden):
+			apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+		default:
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to delete comment", nil)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type FeatureFlagHandler struct {
+	Service *services.FeatureFlagService
+}
+
+func NewFeatureFlagHandler(service *services.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{Service: service}
+}
+
+// GET /flags
+func (h *FeatureFlagHandler) GetMyFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Service.EvaluateAll(r.Context(), claims.UserID))
+}
+
+// GET /admin/flags
+func (h *FeatureFlagHandler) ListFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.Service.ListFlags(r.Context())
+	if err != nil {
+		requestLogger(r).Errorf("Failed to fetch feature flags: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch feature flags", nil)
+		return
+	}
+	if flags == nil {
+		flags = []models.FeatureFlag{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flags)
+}
+
+// GET /admin/flags/{name}
+func (h *FeatureFlagHandler) GetFlagHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	flag, err := h.Service.GetFlag(r.Context(), name)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Flag not found", nil)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Errorf("Failed to fetch feature flag %s: %v", name, err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch feature flag", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
+
+// PUT /admin/flags/{name}
+func (h *FeatureFlagHandler) SetFlagHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	name := mux.Vars(r)["name"]
+
+	var req struct {
+		Enabled        bool   `json:"enabled"`
+		RolloutPercent *int   `json:"rollout_percent"`
+		Description    string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	// Without a percentage an enabled flag applies to everyone
+	rollout := 100
+	if req.RolloutPercent != nil {
+		rollout = *req.RolloutPercent
+	}
+
+	flag := &models.FeatureFlag{
+		Name:           name,
+		Enabled:        req.Enabled,
+		RolloutPercent: rollout,
+		Description:    req.Description,
+	}
+	if err := h.Service.SetFlag(r.Context(), flag); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	requestLogger(r).Infof("Admin %s set feature flag %s (enabled: %v, rollout: %d%%)", claims.UserID, name, flag.Enabled, flag.RolloutPercent)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
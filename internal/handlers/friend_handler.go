@@ -2,11 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/Dias221467/Achievemenet_Manager/internal/config"
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
-	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,15 +23,17 @@ type FriendHandler struct {
 	ActivityService     *services.ActivityService
 	NotificationService *services.NotificationService
 	UserService         *services.UserService
+	Config              *config.Config
 }
 
 // NewFriendHandler initializes a new FriendHandler.
-func NewFriendHandler(service *services.FriendService, activityService *services.ActivityService, notificationService *services.NotificationService, userService *services.UserService) *FriendHandler {
+func NewFriendHandler(service *services.FriendService, activityService *services.ActivityService, notificationService *services.NotificationService, userService *services.UserService, cfg *config.Config) *FriendHandler {
 	return &FriendHandler{
 		Service:             service,
 		ActivityService:     activityService,
 		NotificationService: notificationService,
 		UserService:         userService,
+		Config:              cfg,
 	}
 }
 
@@ -34,8 +41,8 @@ func NewFriendHandler(service *services.FriendService, activityService *services
 func (h *FriendHandler) SendFriendRequestHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to send friend request")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to send friend request")
 		return
 	}
 
@@ -43,42 +50,142 @@ func (h *FriendHandler) SendFriendRequestHandler(w http.ResponseWriter, r *http.
 	receiverIDHex := vars["id"]
 	receiverID, err := primitive.ObjectIDFromHex(receiverIDHex)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		logger.Log.Warnf("Invalid receiver ID: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		requestLogger(r).Warnf("Invalid receiver ID: %v", err)
 		return
 	}
 
 	senderID, _ := primitive.ObjectIDFromHex(claims.UserID)
 
 	request, err := h.Service.SendFriendRequest(r.Context(), senderID, receiverID)
+	if errors.Is(err, services.ErrUserBlocked) {
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+		requestLogger(r).Warnf("Blocked friend request from %s to %s", claims.UserID, receiverIDHex)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		logger.Log.Warnf("Failed to send friend request: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		requestLogger(r).Warnf("Failed to send friend request: %v", err)
 		return
 	}
 
 	_ = h.ActivityService.LogActivity(r.Context(), senderID, "friend_request_sent", receiverID, "Sent a friend request")
 
-	logger.Log.Infof("User %s sent a friend request to %s", claims.UserID, receiverIDHex)
+	requestLogger(r).Infof("User %s sent a friend request to %s", claims.UserID, receiverIDHex)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(request)
 }
 
-// GetPendingRequestsHandler shows all incoming friend requests.
+// InviteFriendHandler emails an invitation to someone without an account, or
+// sends a normal friend request when the address already belongs to a user.
+// POST /friends/invite {"email": "friend@example.com"}
+func (h *FriendHandler) InviteFriendHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	inviterID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	result, err := h.Service.InviteFriendByEmail(r.Context(), inviterID, req.Email)
+	switch {
+	case errors.Is(err, services.ErrInvalidInviteEmail):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	case errors.Is(err, services.ErrUserBlocked):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+		return
+	case errors.Is(err, services.ErrRateLimited):
+		apierror.WriteError(w, http.StatusTooManyRequests, apierror.CodeTooManyRequests, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).Warnf("Failed to invite friend: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	if result.Request != nil {
+		_ = h.ActivityService.LogActivity(r.Context(), inviterID, "friend_request_sent", result.Request.ReceiverID, "Sent a friend request")
+	}
+
+	requestLogger(r).Infof("User %s invited a friend by email (%s)", claims.UserID, result.Status)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// AcceptInviteHandler is the link in a friend invitation email. It sends the
+// invitee to the registration page with the token filled in; registering with
+// it makes them friends with the inviter.
+// GET /friends/accept-invite?token=...
+func (h *FriendHandler) AcceptInviteHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if _, err := h.Service.CheckInvitation(r.Context(), token); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	params := url.Values{"invite_token": {token}}
+	http.Redirect(w, r, fmt.Sprintf("%s?%s", h.Config.InviteRegisterURL, params.Encode()), http.StatusFound)
+}
+
+// GetPendingRequestsHandler shows incoming friend requests with their senders' profiles.
+// GET /friends/requests?sort=newest|oldest&limit=20&offset=0
 func (h *FriendHandler) GetPendingRequestsHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to get pending requests")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to get pending requests")
 		return
 	}
 
 	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
 
-	requests, err := h.Service.GetPendingRequests(r.Context(), userID)
+	query := r.URL.Query()
+	oldestFirst := false
+	switch query.Get("sort") {
+	case "", "newest":
+	case "oldest":
+		oldestFirst = true
+	default:
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid sort, use newest or oldest", nil)
+		return
+	}
+
+	var limit, offset int64
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
+	if v := query.Get("offset"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid offset", nil)
+			return
+		}
+		offset = parsed
+	}
+
+	requests, err := h.Service.GetPendingRequestsWithSenders(r.Context(), userID, oldestFirst, offset, limit)
 	if err != nil {
-		http.Error(w, "Failed to get requests", http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to get pending requests: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get requests", nil)
+		requestLogger(r).Errorf("Failed to get pending requests: %v", err)
 		return
 	}
 
@@ -93,8 +200,8 @@ func (h *FriendHandler) RespondToFriendRequestHandler(w http.ResponseWriter, r *
 
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized request to respond to a friend request")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized request to respond to a friend request")
 		return
 	}
 
@@ -103,8 +210,8 @@ func (h *FriendHandler) RespondToFriendRequestHandler(w http.ResponseWriter, r *
 
 	requestID, err := primitive.ObjectIDFromHex(requestIDHex)
 	if err != nil {
-		http.Error(w, "Invalid request ID", http.StatusBadRequest)
-		logger.Log.Warnf("Invalid friend request ID: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request ID", nil)
+		requestLogger(r).Warnf("Invalid friend request ID: %v", err)
 		return
 	}
 
@@ -112,8 +219,8 @@ func (h *FriendHandler) RespondToFriendRequestHandler(w http.ResponseWriter, r *
 		Accept bool `json:"accept"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		logger.Log.Warnf("Failed to decode response body: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		requestLogger(r).Warnf("Failed to decode response body: %v", err)
 		return
 	}
 	defer r.Body.Close()
@@ -121,8 +228,8 @@ func (h *FriendHandler) RespondToFriendRequestHandler(w http.ResponseWriter, r *
 	// Handle the friend request response
 	err = h.Service.RespondToRequest(r.Context(), requestID, body.Accept)
 	if err != nil {
-		http.Error(w, "Failed to respond to request", http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to respond to friend request %s: %v", requestIDHex, err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to respond to request", nil)
+		requestLogger(r).Errorf("Failed to respond to friend request %s: %v", requestIDHex, err)
 		return
 	}
 
@@ -130,24 +237,27 @@ func (h *FriendHandler) RespondToFriendRequestHandler(w http.ResponseWriter, r *
 
 	user, err := h.UserService.GetUser(r.Context(), claims.UserID)
 	if err != nil {
-		logger.Log.WithError(err).Warn("Failed to fetch user for notification")
+		requestLogger(r).WithError(err).Warn("Failed to fetch user for notification")
 		// Fallback message without username
 		go func() {
+			if !h.NotificationService.WantsFriendRequestNotifications(r.Context(), senderID) {
+				return
+			}
 			err := h.NotificationService.CreateNotification(
 				r.Context(),
 				senderID,
 				"friend_request_responded",
 				"🤝 Friend Request Response",
-				fmt.Sprintf("Your friend request was %s by %s", body.Accept, user.Username),
+				fmt.Sprintf("Your friend request was %v by %s", body.Accept, user.Username),
 				&receiverID, // Optional: reference to the responding user
 			)
 			if err != nil {
-				logger.Log.WithError(err).Warn("Failed to send friend request response notification")
+				requestLogger(r).WithError(err).Warn("Failed to send friend request response notification")
 			}
 		}()
 	}
 
-	logger.Log.Infof("User %s responded to friend request %s (accepted: %v)", claims.UserID, requestIDHex, body.Accept)
+	requestLogger(r).Infof("User %s responded to friend request %s (accepted: %v)", claims.UserID, requestIDHex, body.Accept)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Friend request response recorded",
@@ -158,8 +268,8 @@ func (h *FriendHandler) RespondToFriendRequestHandler(w http.ResponseWriter, r *
 func (h *FriendHandler) GetFriendsHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to get friends")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to get friends")
 		return
 	}
 
@@ -167,8 +277,126 @@ func (h *FriendHandler) GetFriendsHandler(w http.ResponseWriter, r *http.Request
 
 	friends, err := h.Service.GetFriends(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to get friends", http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to fetch friends for user %s: %v", claims.UserID, err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get friends", nil)
+		requestLogger(r).Errorf("Failed to fetch friends for user %s: %v", claims.UserID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(friends)
+}
+
+// GetSuggestionsHandler suggests friends of the caller's friends, ranked by
+// how many friends they share with the caller.
+// GET /friends/suggestions?limit=10
+func (h *FriendHandler) GetSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit, expected a positive number", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	suggestions, err := h.Service.GetSuggestions(r.Context(), userID, limit)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get friend suggestions", nil)
+		requestLogger(r).Errorf("Failed to build friend suggestions for user %s: %v", claims.UserID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// GetFriendFeedHandler lists the goals the caller's friends recently created
+// or completed, newest first. cursor is the next_cursor of the previous page.
+// GET /friends/feed?limit=20&cursor=<timestamp>
+func (h *FriendHandler) GetFriendFeedHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	var limit int64
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
+	var before time.Time
+	if v := query.Get("cursor"); v != "" {
+		before, err = time.Parse(services.FriendFeedCursorLayout, v)
+		if err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid cursor, expected an RFC 3339 timestamp", nil)
+			return
+		}
+	}
+
+	feed, err := h.Service.GetFriendFeed(r.Context(), userID, before, limit)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get friend feed", nil)
+		requestLogger(r).Errorf("Failed to build friend feed for user %s: %v", claims.UserID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+// GetMutualFriendsHandler returns the friends the caller shares with another user.
+// GET /users/{id}/mutual-friends
+func (h *FriendHandler) GetMutualFriendsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	callerID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+	targetID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	friends, err := h.Service.GetMutualFriends(r.Context(), callerID, targetID)
+	switch {
+	case errors.Is(err, services.ErrProfileNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "User not found", nil)
+		return
+	case errors.Is(err, services.ErrProfileNotVisible):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Error("Failed to get mutual friends")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get mutual friends", nil)
 		return
 	}
 
@@ -176,10 +404,41 @@ func (h *FriendHandler) GetFriendsHandler(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(friends)
 }
 
+// GetLeaderboardHandler ranks the caller and their friends.
+// GET /friends/leaderboard?sort=xp|completed_goals
+func (h *FriendHandler) GetLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy != "" && sortBy != services.LeaderboardByXP && sortBy != services.LeaderboardByCompletedGoals {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "sort must be xp or completed_goals", nil)
+		return
+	}
+
+	entries, err := h.Service.GetLeaderboard(r.Context(), userID, sortBy)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get leaderboard", nil)
+		requestLogger(r).Errorf("Failed to build leaderboard for user %s: %v", claims.UserID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
 func (h *FriendHandler) RemoveFriendHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
@@ -188,19 +447,19 @@ func (h *FriendHandler) RemoveFriendHandler(w http.ResponseWriter, r *http.Reque
 
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
 		return
 	}
 
 	friendObjID, err := primitive.ObjectIDFromHex(friendID)
 	if err != nil {
-		http.Error(w, "Invalid friend ID", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid friend ID", nil)
 		return
 	}
 
 	err = h.Service.RemoveFriend(r.Context(), userID, friendObjID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, err.Error(), nil)
 		return
 	}
 
@@ -1,19 +1,24 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
-	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // GoalHandler handles HTTP requests related to goals.
@@ -37,16 +42,16 @@ func (h *GoalHandler) CreateGoalHandler(w http.ResponseWriter, r *http.Request)
 	// Get the logged-in user from JWT token
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		logrus.Warn("Unauthorized access attempt during goal creation")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		requestLogger(r).Warn("Unauthorized access attempt during goal creation")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Decode request body
 	var goal models.Goal
 	if err := json.NewDecoder(r.Body).Decode(&goal); err != nil {
-		logrus.WithError(err).Warn("Invalid request payload during goal creation")
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		requestLogger(r).WithError(err).Warn("Invalid request payload during goal creation")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 	defer r.Body.Close()
@@ -54,8 +59,8 @@ func (h *GoalHandler) CreateGoalHandler(w http.ResponseWriter, r *http.Request)
 	// Convert UserID to ObjectID
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to convert user ID")
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
+		requestLogger(r).WithError(err).Error("Failed to convert user ID")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
 		return
 	}
 	goal.UserID = userID
@@ -65,18 +70,16 @@ func (h *GoalHandler) CreateGoalHandler(w http.ResponseWriter, r *http.Request)
 
 	//  Validate & Parse Due Date (Optional)
 	if !goal.DueDate.IsZero() && goal.DueDate.Before(time.Now()) {
-		logrus.Warn("Attempt to set a past due date for goal")
-		http.Error(w, "Due date cannot be in the past", http.StatusBadRequest)
+		requestLogger(r).Warn("Attempt to set a past due date for goal")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Due date cannot be in the past", nil)
 		return
 	}
 
 	//  Validate & Set Category (Optional)
-	if goal.Category != "" {
-		if _, exists := models.AllowedCategories[goal.Category]; !exists {
-			logrus.Warn("Invalid category provided: ", goal.Category)
-			http.Error(w, "Invalid category", http.StatusBadRequest)
-			return
-		}
+	if !h.Service.ValidCategory(r.Context(), userID, goal.Category) {
+		requestLogger(r).Warn("Invalid category provided: ", goal.Category)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid category", nil)
+		return
 	}
 
 	// Auto-calculate completion state of each step
@@ -93,16 +96,24 @@ func (h *GoalHandler) CreateGoalHandler(w http.ResponseWriter, r *http.Request)
 
 	// Save to DB
 	createdGoal, err := h.Service.CreateGoal(r.Context(), &goal)
+	if writeStepDueDateError(w, r, err) {
+		return
+	}
+	if errors.Is(err, services.ErrInvalidTags) || errors.Is(err, services.ErrInvalidNote) || errors.Is(err, services.ErrInvalidDependency) || errors.Is(err, services.ErrInvalidReminder) || errors.Is(err, services.ErrInvalidVisibility) {
+		requestLogger(r).WithError(err).Warn("Invalid goal provided")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		logrus.WithError(err).Error("Failed to create goal")
-		http.Error(w, "Failed to create goal", http.StatusInternalServerError)
+		requestLogger(r).WithError(err).Error("Failed to create goal")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to create goal", nil)
 		return
 	}
 
 	// Log activity
 	_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_created", createdGoal.ID, fmt.Sprintf("Created goal: %s", createdGoal.Name))
 
-	logrus.WithFields(logrus.Fields{
+	requestLogger(r).WithFields(logrus.Fields{
 		"userID": claims.UserID,
 		"goalID": createdGoal.ID.Hex(),
 	}).Info("Goal successfully created")
@@ -119,35 +130,32 @@ func (h *GoalHandler) GetGoalHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the logged-in user
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		logrus.Warn("Unauthorized goal fetch attempt")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		requestLogger(r).Warn("Unauthorized goal fetch attempt")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Fetch the goal from DB
 	goal, err := h.Service.GetGoal(r.Context(), goalID)
 	if err != nil || goal == nil {
-		logrus.WithField("goalID", goalID).Warn("Goal not found")
-		http.Error(w, "Goal not found", http.StatusNotFound)
+		requestLogger(r).WithField("goalID", goalID).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
 		return
 	}
 
 	//  Ensure the logged-in user is the owner of the goal
-	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID) {
-		logrus.WithFields(logrus.Fields{
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleViewer) {
+		requestLogger(r).WithFields(logrus.Fields{
 			"userID": claims.UserID,
 			"goalID": goalID,
 		}).Warn("Forbidden: User tried to access goal without permission")
-		http.Error(w, "Forbidden: You can only view your own or shared goals", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only view your own or shared goals", nil)
 		return
 	}
 
-	//  Check if the goal is overdue
-	if !goal.DueDate.IsZero() && goal.DueDate.Before(time.Now()) {
-		goal.Status = "expired"
-	}
+	goal.Status = displayStatus(goal, time.Now())
 
-	logrus.WithFields(logrus.Fields{
+	requestLogger(r).WithFields(logrus.Fields{
 		"userID": claims.UserID,
 		"goalID": goalID,
 	}).Info("Goal successfully fetched")
@@ -164,105 +172,116 @@ func (h *GoalHandler) UpdateGoalHandler(w http.ResponseWriter, r *http.Request)
 	// Get the logged-in user
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		logrus.Warn("Unauthorized update attempt")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		requestLogger(r).Warn("Unauthorized update attempt")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Convert goalID to ObjectID
 	objID, err := primitive.ObjectIDFromHex(goalID)
 	if err != nil {
-		logrus.WithError(err).Warn("Invalid goal ID format during update")
-		http.Error(w, "Invalid goal ID", http.StatusBadRequest)
+		requestLogger(r).WithError(err).Warn("Invalid goal ID format during update")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid goal ID", nil)
 		return
 	}
 
 	// Fetch the existing goal
-	existingGoal, err := h.Service.GetGoal(r.Context(), goalID)
+	existingGoal, err := h.Service.GetActiveGoal(r.Context(), goalID)
 	if err != nil || existingGoal == nil {
-		logrus.WithField("goalID", goalID).Warn("Goal not found during update")
-		http.Error(w, "Goal not found", http.StatusNotFound)
+		requestLogger(r).WithField("goalID", goalID).Warn("Goal not found during update")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
 		return
 	}
 
 	// Ensure the logged-in user is the owner of the goal
-	if existingGoal.UserID.Hex() != claims.UserID && !isCollaborator(existingGoal.Collaborators, claims.UserID) {
-		logrus.WithFields(logrus.Fields{
+	if existingGoal.UserID.Hex() != claims.UserID && !isCollaborator(existingGoal.Collaborators, claims.UserID, models.CollaboratorRoleEditor) {
+		requestLogger(r).WithFields(logrus.Fields{
 			"userID": claims.UserID,
 			"goalID": goalID,
 		}).Warn("Forbidden: Update attempt by non-owner and non-collaborator")
-		http.Error(w, "Forbidden: Only owner or collaborators can update the goal", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only owner or editors can update the goal", nil)
 		return
 	}
 
-	// Decode request body
-	var updatedGoal models.Goal
-	if err := json.NewDecoder(r.Body).Decode(&updatedGoal); err != nil {
-		logrus.WithError(err).Warn("Invalid update payload")
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	// Decode request body; version is the goal version the client last read
+	var req struct {
+		models.Goal
+		Version *int64 `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		requestLogger(r).WithError(err).Warn("Invalid update payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 	defer r.Body.Close()
 
+	if req.Version == nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "version is required", nil)
+		return
+	}
+	updatedGoal := req.Goal
+	updatedGoal.Version = *req.Version
+
 	//  Validate & Parse Due Date (Optional)
 	if !updatedGoal.DueDate.IsZero() && updatedGoal.DueDate.Before(time.Now()) {
-		http.Error(w, "Due date cannot be in the past", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Due date cannot be in the past", nil)
 		return
 	}
 
 	//  Validate & Set Category (Optional)
-	if updatedGoal.Category != "" {
-		if _, exists := models.AllowedCategories[updatedGoal.Category]; !exists {
-			http.Error(w, "Invalid category", http.StatusBadRequest)
-			return
-		}
+	if !h.Service.ValidCategory(r.Context(), existingGoal.UserID, updatedGoal.Category) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid category", nil)
+		return
 	}
 
-	// Auto-complete parent step when all substeps are done
-	for i := range updatedGoal.Steps {
-		step := &updatedGoal.Steps[i]
-		allSubstepsDone := true
-		for _, sub := range step.Substeps {
-			if !sub.Done {
-				allSubstepsDone = false
-				break
-			}
-		}
-		step.Completed = allSubstepsDone
+	// Only the owner decides whether completion needs their confirmation
+	if existingGoal.UserID.Hex() != claims.UserID {
+		updatedGoal.RequireCompletionConfirmation = existingGoal.RequireCompletionConfirmation
 	}
 
-	// Auto-update goal status based on steps
-	allStepsDone := true
-	for _, step := range updatedGoal.Steps {
-		if !step.Completed {
-			allStepsDone = false
-			break
-		}
-	}
-	if allStepsDone {
-		updatedGoal.Status = "completed"
-	} else {
-		updatedGoal.Status = "in_progress"
-	}
+	// The status is derived from the steps by the service, never taken from the payload
+	updatedGoal.Status = existingGoal.Status
+	updatedGoal.ClosedReason = existingGoal.ClosedReason
+	updatedGoal.ClosedAt = existingGoal.ClosedAt
+
+	// Pins are changed through POST /goals/{id}/pin and /unpin, which enforce the limit
+	updatedGoal.Pinned = existingGoal.Pinned
+
+	// Dependencies are changed through PUT /goals/{id}/blocked-by, which checks for cycles
+	updatedGoal.BlockedBy = existingGoal.BlockedBy
 
 	//  Assign updated values
 	updatedGoal.ID = objID
 	updatedGoal.UserID = existingGoal.UserID
 	updatedGoal.Collaborators = existingGoal.Collaborators
 	updatedGoal.CreatedAt = existingGoal.CreatedAt
+	updatedGoal.DeletedAt = existingGoal.DeletedAt
 	updatedGoal.UpdatedAt = time.Now()
 
 	// Save the updated goal
-	updatedGoalData, err := h.Service.UpdateGoal(r.Context(), goalID, &updatedGoal)
+	updatedGoalData, err := h.Service.UpdateGoal(r.Context(), goalID, &updatedGoal, actorID(claims))
+	if errors.Is(err, repository.ErrConflict) {
+		requestLogger(r).WithField("goalID", goalID).Warn("Goal update conflict")
+		h.writeVersionConflict(w, r, goalID)
+		return
+	}
+	if writeStepDueDateError(w, r, err) {
+		return
+	}
+	if errors.Is(err, services.ErrInvalidTags) || errors.Is(err, services.ErrInvalidNote) || errors.Is(err, services.ErrInvalidReminder) || errors.Is(err, services.ErrInvalidVisibility) {
+		requestLogger(r).WithError(err).Warn("Invalid goal update provided")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		logrus.WithError(err).Error("Failed to update goal")
-		http.Error(w, "Failed to update goal", http.StatusInternalServerError)
+		requestLogger(r).WithError(err).Error("Failed to update goal")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update goal", nil)
 		return
 	}
 
 	_ = h.ActivityService.LogActivity(r.Context(), existingGoal.UserID, "goal_updated", updatedGoal.ID, fmt.Sprintf("Updated goal: %s", updatedGoal.Name))
 
-	logrus.WithFields(logrus.Fields{
+	requestLogger(r).WithFields(logrus.Fields{
 		"userID": claims.UserID,
 		"goalID": goalID,
 	}).Info("Goal successfully updated")
@@ -274,118 +293,107 @@ func (h *GoalHandler) UpdateGoalHandler(w http.ResponseWriter, r *http.Request)
 func (h *GoalHandler) UpdateGoalProgressHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	goalID := vars["id"]
-	log := logrus.WithField("goalID", goalID)
+	log := requestLogger(r).WithField("goalID", goalID)
 
 	// Get logged-in user
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
 		log.Warn("Unauthorized access")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Fetch goal from DB
-	goal, err := h.Service.GetGoal(r.Context(), goalID)
+	goal, err := h.Service.GetActiveGoal(r.Context(), goalID)
 	if err != nil || goal == nil {
 		log.WithError(err).Warn("Goal not found")
-		http.Error(w, "Goal not found", http.StatusNotFound)
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
 		return
 	}
 
 	// Ensure the logged-in user owns the goal
-	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID) {
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleEditor) {
 		log.Warn("Forbidden: User is not the owner or a collaborator")
-		http.Error(w, "Forbidden: Only owner or collaborators can update progress", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only owner or editors can update progress", nil)
 		return
 	}
 
-	// Decode request body
-	var progressUpdate struct {
-		StepName   string `json:"step"`
-		SubstepIdx int    `json:"substep_index"`
-		Done       bool   `json:"done"`
+	if !h.checkNotBlocked(w, r, goal) {
+		return
 	}
+
+	// Decode request body
+	var progressUpdate progressItem
 	if err := json.NewDecoder(r.Body).Decode(&progressUpdate); err != nil {
 		log.WithError(err).Warn("Invalid request payload")
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 	defer r.Body.Close()
 
-	// Find the step by name
-	var stepFound bool
-	for i := range goal.Steps {
-		if goal.Steps[i].Name == progressUpdate.StepName {
-			stepFound = true
-			// Validate substep index
-			if progressUpdate.SubstepIdx < 0 || progressUpdate.SubstepIdx >= len(goal.Steps[i].Substeps) {
-				http.Error(w, "Invalid substep index", http.StatusBadRequest)
-				return
-			}
-
-			// Update the substep's done status
-			goal.Steps[i].Substeps[progressUpdate.SubstepIdx].Done = progressUpdate.Done
-
-			// Auto-complete the step if all substeps are done
-			allDone := true
-			for _, sub := range goal.Steps[i].Substeps {
-				if !sub.Done {
-					allDone = false
-					break
-				}
-			}
-			goal.Steps[i].Completed = allDone
-			break
-		}
-	}
-
-	if !stepFound {
-		http.Error(w, "Step not found", http.StatusBadRequest)
+	// Apply the update to the addressed step
+	stepIdx, err := applySubstepProgress(goal, progressUpdate)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
 		return
 	}
 
-	// Check if all steps are completed to set goal status
-	allStepsCompleted := true
-	for _, step := range goal.Steps {
-		if !step.Completed {
-			allStepsCompleted = false
-			break
-		}
-	}
-	if allStepsCompleted {
-		goal.Status = "completed"
-	} else {
-		goal.Status = "in_progress"
-	}
-
 	goal.UpdatedAt = time.Now()
 
 	// Save changes
-	updatedGoal, err := h.Service.UpdateGoal(r.Context(), goalID, goal)
+	updatedGoal, err := h.Service.UpdateGoal(r.Context(), goalID, goal, actorID(claims))
+	if errors.Is(err, repository.ErrConflict) {
+		log.Warn("Goal progress update conflict")
+		h.writeVersionConflict(w, r, goalID)
+		return
+	}
 	if err != nil {
 		log.WithError(err).Error("Failed to update goal progress in DB")
-		http.Error(w, "Failed to update progress", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update progress", nil)
 		return
 	}
 
 	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_progress_updated", goal.ID, fmt.Sprintf("Updated progress for goal: %s", goal.Name))
 
-	log.Info("Goal progress successfully updated")
+	log.WithField("stepIndex", stepIdx).Info("Goal progress successfully updated")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(updatedGoal)
+	json.NewEncoder(w).Encode(progressResponse{
+		Goal: updatedGoal,
+		ModifiedStep: modifiedStep{
+			StepIndex:    stepIdx,
+			StepName:     goal.Steps[stepIdx].Name,
+			SubstepIndex: progressUpdate.SubstepIdx,
+			Completed:    goal.Steps[stepIdx].Completed,
+		},
+	})
+}
+
+// progressResponse is the updated goal plus the step a progress update
+// touched, so clients can check the right step was changed.
+type progressResponse struct {
+	*models.Goal
+	ModifiedStep modifiedStep `json:"modified_step"`
+}
+
+// modifiedStep identifies the substep changed by a progress update.
+type modifiedStep struct {
+	StepIndex    int    `json:"step_index"`
+	StepName     string `json:"step"`
+	SubstepIndex int    `json:"substep_index"`
+	Completed    bool   `json:"step_completed"`
 }
 
 // DeleteGoalHandler handles deleting a goal by its ID.
 func (h *GoalHandler) DeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	goalID := vars["id"]
-	log := logrus.WithField("goalID", goalID)
+	log := requestLogger(r).WithField("goalID", goalID)
 
 	// Get the logged-in user from JWT token
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
 		log.Warn("Unauthorized access attempt")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
@@ -393,14 +401,14 @@ func (h *GoalHandler) DeleteGoalHandler(w http.ResponseWriter, r *http.Request)
 	goal, err := h.Service.GetGoal(r.Context(), goalID)
 	if err != nil || goal == nil {
 		log.WithError(err).Warn("Goal not found or fetch failed")
-		http.Error(w, "Goal not found", http.StatusNotFound)
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
 		return
 	}
 
 	// Check if the logged-in user is the owner
 	if goal.UserID.Hex() != claims.UserID {
 		log.Warn("Forbidden: User tried to delete another user's goal")
-		http.Error(w, "Forbidden: You can only delete your own goals", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only delete your own goals", nil)
 		return
 	}
 
@@ -408,56 +416,232 @@ func (h *GoalHandler) DeleteGoalHandler(w http.ResponseWriter, r *http.Request)
 	err = h.Service.DeleteGoal(r.Context(), goalID)
 	if err != nil {
 		log.WithError(err).Error("Failed to delete goal")
-		http.Error(w, "Failed to delete goal", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to delete goal", nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_deleted", goal.ID, fmt.Sprintf("Moved goal to trash: %s", goal.Name))
+
+	log.Info("Goal moved to trash")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTrashHandler lists the user's goals that are in the trash.
+func (h *GoalHandler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	goals, err := h.Service.GetDeletedGoals(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to retrieve trash")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve trash", nil)
+		return
+	}
+	if goals == nil {
+		goals = []models.Goal{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goals)
+}
+
+// EmptyTrashHandler permanently deletes every goal in the user's trash.
+// DELETE /goals/trash
+func (h *GoalHandler) EmptyTrashHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	deleted, err := h.Service.EmptyTrash(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to empty trash")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to empty trash", nil)
+		return
+	}
+
+	if deleted > 0 {
+		_ = h.ActivityService.LogActivity(r.Context(), userID, "trash_emptied", userID, fmt.Sprintf("Permanently deleted %d goals from the trash", deleted))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"deleted": deleted})
+}
+
+// RestoreGoalHandler takes a goal out of the trash.
+func (h *GoalHandler) RestoreGoalHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access attempt")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedTrashGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.RestoreGoal(r.Context(), goal); err != nil {
+		log.WithError(err).Warn("Failed to restore goal")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_restored", goal.ID, fmt.Sprintf("Restored goal from trash: %s", goal.Name))
+
+	restored, err := h.Service.GetGoal(r.Context(), goalID)
+	if err != nil {
+		log.WithError(err).Error("Failed to reload restored goal")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Goal restored, but failed to load it", nil)
+		return
+	}
+
+	log.Info("Goal restored from trash")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restored)
+}
+
+// PermanentDeleteGoalHandler deletes a trashed goal for good.
+func (h *GoalHandler) PermanentDeleteGoalHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access attempt")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedTrashGoal(w, r, goalID)
+	if !ok {
 		return
 	}
 
-	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_deleted", goal.ID, fmt.Sprintf("Deleted goal: %s", goal.Name))
+	if err := h.Service.PermanentDeleteGoal(r.Context(), goal); err != nil {
+		log.WithError(err).Warn("Failed to permanently delete goal")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
 
-	log.Info("Goal deleted successfully")
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_permanently_deleted", goal.ID, fmt.Sprintf("Permanently deleted goal: %s", goal.Name))
+
+	log.Info("Goal permanently deleted")
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// GetAllGoalsHandler handles fetching all goals, with an optional limit.
+// parsePageParams reads the optional page and limit query parameters. Missing
+// ones are returned as 0 so the service applies its defaults. It writes a 400
+// response and returns false when either is not a positive number.
+func parsePageParams(w http.ResponseWriter, r *http.Request) (page, limit int64, ok bool) {
+	parse := func(name string) (int64, bool) {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			return 0, true
+		}
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 1 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, fmt.Sprintf("Invalid %s, expected a positive number", name), nil)
+			return 0, false
+		}
+		return parsed, true
+	}
+	if page, ok = parse("page"); !ok {
+		return 0, 0, false
+	}
+	if limit, ok = parse("limit"); !ok {
+		return 0, 0, false
+	}
+	return page, limit, true
+}
 
-// Its not working right now, we will need it later when we will add admins and their rights with functions
+// GET /admin/goals?page=1&limit=10&user_id=&status=&category=&from=YYYY-MM-DD&to=YYYY-MM-DD
+// Lists all users' goals for admins, newest first, one page at a time.
+// from and to filter on the creation date; to is inclusive.
 func (h *GoalHandler) GetAllGoalsHandler(w http.ResponseWriter, r *http.Request) {
-	limitParam := r.URL.Query().Get("limit")
-	var limit int64 = 10 // default limit
-	log := logrus.WithField("defaultLimit", limit)
-
-	if limitParam != "" {
-		parsed, err := strconv.ParseInt(limitParam, 10, 64)
-		if err == nil {
-			limit = parsed
-			log = log.WithField("parsedLimit", limit)
-		} else {
-			log.WithError(err).Warn("Invalid limit query param")
+	query := r.URL.Query()
+	log := requestLogger(r)
+
+	page, limit, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	filter := repository.AdminGoalFilter{
+		Status:   query.Get("status"),
+		Category: query.Get("category"),
+	}
+	if v := query.Get("user_id"); v != "" {
+		userID, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user_id", nil)
+			return
+		}
+		filter.UserID = userID
+	}
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.CreatedFrom, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid from date, expected YYYY-MM-DD", nil)
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.CreatedTo, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid to date, expected YYYY-MM-DD", nil)
+			return
 		}
+		filter.CreatedTo = filter.CreatedTo.AddDate(0, 0, 1)
 	}
 
-	goals, err := h.Service.GetAllGoals(r.Context(), limit)
+	result, err := h.Service.GetAdminGoals(r.Context(), filter, page, limit)
 	if err != nil {
 		log.WithError(err).Error("Failed to fetch all goals")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch goals", nil)
 		return
 	}
 
-	log.WithField("goalCount", len(goals)).Info("Successfully fetched all goals")
+	log.WithFields(map[string]interface{}{
+		"page":  result.Page,
+		"count": len(result.Goals),
+		"total": result.Total,
+	}).Info("Successfully fetched all goals")
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(goals)
+	json.NewEncoder(w).Encode(result)
 }
 
 func (h *GoalHandler) GetGoalProgressHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	goalID := vars["id"]
-	log := logrus.WithField("goalID", goalID)
+	log := requestLogger(r).WithField("goalID", goalID)
 
 	// Get the logged-in user
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
 		log.Warn("Unauthorized access")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
@@ -465,14 +649,14 @@ func (h *GoalHandler) GetGoalProgressHandler(w http.ResponseWriter, r *http.Requ
 	goal, err := h.Service.GetGoal(r.Context(), goalID)
 	if err != nil || goal == nil {
 		log.WithError(err).Warn("Goal not found")
-		http.Error(w, "Goal not found", http.StatusNotFound)
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
 		return
 	}
 
 	// Ensure the logged-in user is the owner of the goal
-	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID) {
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleViewer) {
 		log.Warn("Forbidden: Not owner or collaborator")
-		http.Error(w, "Forbidden", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden", nil)
 		return
 	}
 
@@ -489,11 +673,11 @@ func (h *GoalHandler) GetGoalProgressHandler(w http.ResponseWriter, r *http.Requ
 func (h *GoalHandler) GetGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get logged-in user
 	claims := middleware.GetUserFromContext(r.Context())
-	log := logrus.WithField("userID", claims.UserID)
+	log := requestLogger(r).WithField("userID", claims.UserID)
 
 	if claims == nil {
 		log.Warn("Unauthorized access")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
@@ -501,19 +685,21 @@ func (h *GoalHandler) GetGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
 		log.WithError(err).Error("Invalid user ID format")
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
 		return
 	}
 
-	// Get category filter from query params (optional)
+	// Get category and tag filters from query params (optional)
 	category := r.URL.Query().Get("category")
-	log = log.WithField("category", category)
+	tag := r.URL.Query().Get("tag")
+	includeClosed := r.URL.Query().Get("include_closed") == "true"
+	log = log.WithFields(logrus.Fields{"category": category, "tag": tag})
 
-	// Fetch goals from DB with optional category filter
-	goals, err := h.Service.GetGoals(r.Context(), userID, category)
+	// Fetch goals from DB with optional filters
+	goals, err := h.Service.GetGoals(r.Context(), userID, category, tag, includeClosed)
 	if err != nil {
 		log.WithError(err).Error("Failed to retrieve user goals")
-		http.Error(w, "Failed to retrieve goals", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve goals", nil)
 		return
 	}
 
@@ -522,79 +708,1474 @@ func (h *GoalHandler) GetGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(goals)
 }
 
-func (h *GoalHandler) InviteCollaboratorHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	goalID := vars["id"]
-
+// GET /goals/shared?category=&include_closed=true&page=1&limit=20
+// Lists the goals the user collaborates on but does not own, with each
+// owner's username.
+func (h *GoalHandler) GetSharedGoalsHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to invite collaborator")
+		requestLogger(r).Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
+	log := requestLogger(r).WithField("userID", claims.UserID)
 
-	requesterID, err := primitive.ObjectIDFromHex(claims.UserID)
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
-		logger.Log.Errorf("Invalid user ID format: %v", err)
+		log.WithError(err).Error("Invalid user ID format")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
 		return
 	}
 
-	// Parse body to get collaboratorID
-	var req struct {
-		CollaboratorID string `json:"collaborator_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		logger.Log.Warn("Invalid request payload for collaborator invite")
+	page, limit, ok := parsePageParams(w, r)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
+	category := r.URL.Query().Get("category")
+	includeClosed := r.URL.Query().Get("include_closed") == "true"
 
-	collaboratorID, err := primitive.ObjectIDFromHex(req.CollaboratorID)
+	result, err := h.Service.GetSharedGoals(r.Context(), userID, category, includeClosed, page, limit)
 	if err != nil {
-		http.Error(w, "Invalid collaborator ID", http.StatusBadRequest)
-		logger.Log.Warnf("Invalid collaborator ID: %v", err)
+		log.WithError(err).Error("Failed to retrieve shared goals")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve goals", nil)
+		return
+	}
+
+	log.WithField("goalCount", len(result.Goals)).Info("Shared goals fetched successfully")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GET /goals/{id}/activity?cursor=<last activity id>&limit=20
+// Lists everything logged about the goal by its owner and collaborators,
+// newest first. Only they may see it.
+func (h *GoalHandler) GetGoalActivityHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, err := h.Service.GetGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		log.WithError(err).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
 		return
 	}
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleViewer) {
+		log.Warn("Forbidden: Not owner or collaborator")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	var limit int64
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
 
-	err = h.Service.InviteCollaborator(r.Context(), goalID, requesterID, collaboratorID)
+	page, err := h.ActivityService.GetGoalActivity(r.Context(), goal.ID, r.URL.Query().Get("cursor"), limit)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid cursor", nil)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		logger.Log.Warnf("Failed to invite collaborator: %v", err)
+		log.WithError(err).Error("Failed to fetch goal activity")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch activity", nil)
 		return
 	}
 
-	goal, _ := h.Service.GetGoal(r.Context(), goalID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
 
-	_ = h.ActivityService.LogActivity(r.Context(), requesterID, "collaborator_invited", goal.ID, fmt.Sprintf("Invited user %s to collaborate", collaboratorID))
+// GetGoalTagsHandler returns the distinct tags used on the user's goals.
+func (h *GoalHandler) GetGoalTagsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
 
-	// Send notification to invited user
-	_ = h.NotificationService.CreateNotification(
-		r.Context(),
-		collaboratorID,
-		"collaborator_added",
-		"You’ve been added to a goal",
-		fmt.Sprintf("You’ve been invited to collaborate on: %s", goal.Name),
-		&goal.ID,
-	)
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	tags, err := h.Service.GetTags(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to retrieve goal tags")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve tags", nil)
+		return
+	}
 
-	logger.Log.Infof("User %s invited %s to collaborate on goal %s", claims.UserID, req.CollaboratorID, goalID)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Collaborator successfully invited",
-	})
+	json.NewEncoder(w).Encode(tags)
 }
 
-func isCollaborator(collaborators []primitive.ObjectID, userID string) bool {
-	id, err := primitive.ObjectIDFromHex(userID)
+// defaultCalendarDays is how far ahead the calendar looks without a to date.
+const defaultCalendarDays = 30
+
+// GetCalendarHandler lists goal, step and substep due dates in a date range
+// across the caller's own and shared goals.
+// GET /goals/calendar?from=2024-01-01&to=2024-01-31 (both dates inclusive,
+// from defaults to today and to to 30 days later)
+func (h *GoalHandler) GetCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		return false
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
 	}
-	for _, c := range collaborators {
-		if c == id {
-			return true
+
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid from date, expected YYYY-MM-DD", nil)
+			return
 		}
 	}
-	return false
+	to := from.AddDate(0, 0, defaultCalendarDays)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid to date, expected YYYY-MM-DD", nil)
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	items, err := h.Service.GetCalendar(r.Context(), userID, from, to)
+	if errors.Is(err, services.ErrInvalidCalendarRange) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "to must not be before from and the range must be at most a year", nil)
+		return
+	}
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to build calendar")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve calendar", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// GetGoalStatsHandler returns dashboard statistics for the caller's goals.
+func (h *GoalHandler) GetGoalStatsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized goal stats request")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	stats, err := h.Service.GetGoalStats(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to compute goal stats")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to compute goal stats", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (h *GoalHandler) InviteCollaboratorHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to invite collaborator")
+		return
+	}
+
+	requesterID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		requestLogger(r).Errorf("Invalid user ID format: %v", err)
+		return
+	}
+
+	// Parse body to get collaboratorID and an optional role ("viewer" or "editor")
+	var req struct {
+		CollaboratorID string `json:"collaborator_id"`
+		Role           string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		requestLogger(r).Warn("Invalid request payload for collaborator invite")
+		return
+	}
+	defer r.Body.Close()
+
+	collaboratorID, err := primitive.ObjectIDFromHex(req.CollaboratorID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid collaborator ID", nil)
+		requestLogger(r).Warnf("Invalid collaborator ID: %v", err)
+		return
+	}
+
+	invitation, err := h.Service.InviteCollaborator(r.Context(), goalID, requesterID, collaboratorID, req.Role)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, repository.ErrDuplicateInvitation) {
+			status = http.StatusConflict
+		}
+		apierror.WriteError(w, status, apierror.CodeForStatus(status), err.Error(), nil)
+		requestLogger(r).Warnf("Failed to invite collaborator: %v", err)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), requesterID, "collaborator_invited", invitation.GoalID, fmt.Sprintf("Invited user %s to collaborate", collaboratorID))
+
+	// Let the invited user know there is an invitation waiting for them
+	_ = h.NotificationService.CreateNotification(
+		r.Context(),
+		collaboratorID,
+		"goal_invite",
+		"📨 You’ve been invited to a goal",
+		fmt.Sprintf("You’ve been invited to collaborate on: %s", invitation.GoalName),
+		&invitation.GoalID,
+	)
+
+	requestLogger(r).Infof("User %s invited %s to collaborate on goal %s", claims.UserID, req.CollaboratorID, goalID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// GetGoalInvitationsHandler lists the goal invitations waiting for the user's answer.
+func (h *GoalHandler) GetGoalInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to list goal invitations")
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	invitations, err := h.Service.GetPendingInvitations(r.Context(), userID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get invitations", nil)
+		requestLogger(r).Errorf("Failed to get goal invitations for user %s: %v", claims.UserID, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// RespondToGoalInvitationHandler accepts or declines a goal invitation.
+func (h *GoalHandler) RespondToGoalInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	invitationID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("invitationID", invitationID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to respond to goal invitation")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	var body struct {
+		Accept bool `json:"accept"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	invitation, err := h.Service.RespondToInvitation(r.Context(), invitationID, userID, body.Accept)
+	if err != nil {
+		log.WithError(err).Warn("Failed to respond to goal invitation")
+		switch {
+		case errors.Is(err, services.ErrInvitationNotFound):
+			apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Invitation not found", nil)
+		case errors.Is(err, repository.ErrInvitationNotPending):
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		default:
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		}
+		return
+	}
+
+	activityType := "goal_invite_declined"
+	if body.Accept {
+		activityType = "collaborator_joined"
+	}
+	_ = h.ActivityService.LogActivity(r.Context(), userID, activityType, invitation.GoalID, fmt.Sprintf("Responded to invitation for %s: %v", invitation.GoalName, body.Accept))
+
+	log.Infof("User %s responded to goal invitation (accepted: %v)", claims.UserID, body.Accept)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// TransferGoalHandler lets the owner hand a goal over to one of its collaborators.
+func (h *GoalHandler) TransferGoalHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to transfer goal")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	ownerID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		log.WithError(err).Error("Invalid user ID format")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	var req struct {
+		NewOwnerID string `json:"new_owner_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid request payload for goal transfer")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	newOwnerID, err := primitive.ObjectIDFromHex(req.NewOwnerID)
+	if err != nil {
+		log.WithError(err).Warn("Invalid new owner ID")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid new owner ID", nil)
+		return
+	}
+
+	goal, err := h.Service.GetActiveGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		log.WithError(err).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	}
+
+	if goal.UserID != ownerID {
+		log.Warn("Forbidden: Transfer attempt by non-owner")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only the owner can transfer the goal", nil)
+		return
+	}
+
+	transferred, err := h.Service.TransferGoal(r.Context(), goalID, ownerID, newOwnerID)
+	if err != nil {
+		log.WithError(err).Warn("Failed to transfer goal")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), ownerID, "goal_transferred", transferred.ID, fmt.Sprintf("Transferred goal %s to user %s", transferred.Name, newOwnerID.Hex()))
+
+	log.WithField("newOwnerID", req.NewOwnerID).Info("Goal ownership transferred")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transferred)
+}
+
+// UpdateStepHandler updates a single step (name, due date) of a goal.
+func (h *GoalHandler) UpdateStepHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	stepName := vars["stepName"]
+	log := requestLogger(r).WithFields(logrus.Fields{"goalID": goalID, "step": stepName})
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, err := h.Service.GetActiveGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		log.WithError(err).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	}
+
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleEditor) {
+		log.Warn("Forbidden: User is not the owner or a collaborator")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only owner or editors can update steps", nil)
+		return
+	}
+
+	var update services.StepUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		log.WithError(err).Warn("Invalid request payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	updatedGoal, err := h.Service.UpdateStep(r.Context(), goalID, userID, stepName, update)
+	if writeStepDueDateError(w, r, err) {
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warn("Failed to update step")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_step_updated", goal.ID, fmt.Sprintf("Updated step %s of goal: %s", stepName, goal.Name))
+
+	log.Info("Step successfully updated")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// UpdateSubstepHandler updates a single substep (title, due date) of a goal.
+func (h *GoalHandler) UpdateSubstepHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	stepName := vars["stepName"]
+	log := requestLogger(r).WithFields(logrus.Fields{"goalID": goalID, "step": stepName})
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	substepIdx, err := strconv.Atoi(vars["substepIndex"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid substep index", nil)
+		return
+	}
+
+	goal, err := h.Service.GetActiveGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		log.WithError(err).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	}
+
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleEditor) {
+		log.Warn("Forbidden: User is not the owner or a collaborator")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only owner or editors can update substeps", nil)
+		return
+	}
+
+	var update services.SubstepUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		log.WithError(err).Warn("Invalid request payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	updatedGoal, err := h.Service.UpdateSubstep(r.Context(), goalID, userID, stepName, substepIdx, update)
+	if writeStepDueDateError(w, r, err) {
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warn("Failed to update substep")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_substep_updated", goal.ID, fmt.Sprintf("Updated substep %d of step %s in goal: %s", substepIdx, stepName, goal.Name))
+
+	log.WithField("substepIndex", substepIdx).Info("Substep successfully updated")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// progressItem is a single substep update inside a bulk progress request.
+// StepIndex addresses the step; StepName is only used when no index is given,
+// for clients written before steps were addressed by position.
+type progressItem struct {
+	StepIndex  *int   `json:"step_index,omitempty"`
+	StepName   string `json:"step"`
+	SubstepIdx int    `json:"substep_index"`
+	Done       bool   `json:"done"`
+}
+
+// progressItemError describes why one item of a bulk progress request was rejected.
+type progressItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// BulkUpdateProgressHandler applies several substep updates and saves the goal once.
+// Invalid items are skipped and reported; the valid ones are still applied.
+func (h *GoalHandler) BulkUpdateProgressHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, err := h.Service.GetActiveGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		log.WithError(err).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	}
+
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleEditor) {
+		log.Warn("Forbidden: User is not the owner or a collaborator")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only owner or editors can update progress", nil)
+		return
+	}
+
+	if !h.checkNotBlocked(w, r, goal) {
+		return
+	}
+
+	var items []progressItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		log.WithError(err).Warn("Invalid request payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(items) == 0 {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "No progress updates provided", nil)
+		return
+	}
+
+	var itemErrors []progressItemError
+	applied := 0
+	for i, item := range items {
+		if _, err := applySubstepProgress(goal, item); err != nil {
+			itemErrors = append(itemErrors, progressItemError{Index: i, Error: err.Error()})
+			continue
+		}
+		applied++
+	}
+
+	updatedGoal := goal
+	if applied > 0 {
+		goal.UpdatedAt = time.Now()
+
+		updatedGoal, err = h.Service.UpdateGoal(r.Context(), goalID, goal, actorID(claims))
+		if errors.Is(err, repository.ErrConflict) {
+			log.Warn("Bulk progress update conflict")
+			h.writeVersionConflict(w, r, goalID)
+			return
+		}
+		if err != nil {
+			log.WithError(err).Error("Failed to save bulk progress update")
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update progress", nil)
+			return
+		}
+
+		_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_progress_updated", goal.ID, fmt.Sprintf("Updated progress for goal: %s (%d substeps)", goal.Name, applied))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(itemErrors) > 0 {
+		log.WithField("failedItems", len(itemErrors)).Warn("Bulk progress update had invalid items")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"applied": applied,
+			"errors":  itemErrors,
+			"goal":    updatedGoal,
+		})
+		return
+	}
+
+	log.WithField("applied", applied).Info("Bulk goal progress successfully updated")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// applySubstepProgress sets the done flag of one substep and recomputes its
+// step's completion. It returns the index of the step that was modified.
+func applySubstepProgress(goal *models.Goal, item progressItem) (int, error) {
+	stepIdx := -1
+	switch {
+	case item.StepIndex != nil:
+		if *item.StepIndex < 0 || *item.StepIndex >= len(goal.Steps) {
+			return -1, fmt.Errorf("Invalid step index")
+		}
+		stepIdx = *item.StepIndex
+	case item.StepName != "":
+		for i := range goal.Steps {
+			if goal.Steps[i].Name == item.StepName {
+				stepIdx = i
+				break
+			}
+		}
+		if stepIdx < 0 {
+			return -1, fmt.Errorf("Step not found")
+		}
+	default:
+		return -1, fmt.Errorf("step_index or step is required")
+	}
+
+	step := &goal.Steps[stepIdx]
+
+	// Validate substep index
+	if item.SubstepIdx < 0 || item.SubstepIdx >= len(step.Substeps) {
+		return -1, fmt.Errorf("Invalid substep index")
+	}
+
+	// Update the substep's done status
+	step.Substeps[item.SubstepIdx].Done = item.Done
+
+	// Auto-complete the step if all substeps are done
+	allDone := true
+	for _, sub := range step.Substeps {
+		if !sub.Done {
+			allDone = false
+			break
+		}
+	}
+	step.Completed = allDone
+	return stepIdx, nil
+}
+
+// ExportGoalsHandler returns the user's goals as a CSV or JSON file download.
+func (h *GoalHandler) ExportGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized goal export attempt")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv"
+	case "json":
+		contentType = "application/json"
+	default:
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Unsupported format, use csv or json", nil)
+		return
+	}
+
+	data, err := h.Service.ExportGoals(r.Context(), userID, format)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to export goals")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to export goals", nil)
+		return
+	}
+
+	requestLogger(r).WithFields(logrus.Fields{
+		"userID": claims.UserID,
+		"format": format,
+	}).Info("Goals exported")
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="goals.%s"`, format))
+	w.Write(data)
+}
+
+// ImportGoalsHandler creates goals from a JSON array in the export format.
+func (h *GoalHandler) ImportGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized goal import attempt")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	var goals []models.Goal
+	if err := json.NewDecoder(r.Body).Decode(&goals); err != nil {
+		requestLogger(r).WithError(err).Warn("Invalid goal import payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	created, importErrors, err := h.Service.ImportGoals(r.Context(), userID, goals)
+	if err != nil {
+		requestLogger(r).WithError(err).Warn("Goal import rejected")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	for _, goal := range created {
+		_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_created", goal.ID, fmt.Sprintf("Imported goal: %s", goal.Name))
+	}
+
+	requestLogger(r).WithFields(logrus.Fields{
+		"userID":  claims.UserID,
+		"created": len(created),
+		"skipped": len(importErrors),
+	}).Info("Goals imported")
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(created) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"created": created,
+		"errors":  importErrors,
+	})
+}
+
+// BulkGoalsHandler applies one action to several of the user's goals at once.
+func (h *GoalHandler) BulkGoalsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		requestLogger(r).Warn("Unauthorized bulk goal operation attempt")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	var req struct {
+		Action string   `json:"action"`
+		IDs    []string `json:"ids"`
+		Value  string   `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		requestLogger(r).WithError(err).Warn("Invalid bulk goal payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	log := requestLogger(r).WithFields(logrus.Fields{
+		"userID": claims.UserID,
+		"action": req.Action,
+		"count":  len(req.IDs),
+	})
+
+	results, goals, err := h.Service.BulkUpdateGoals(r.Context(), userID, req.Action, req.IDs, req.Value)
+	if errors.Is(err, services.ErrBulkRejected) {
+		log.Warn("Bulk goal operation rejected")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), map[string]interface{}{
+			"results": results,
+		})
+		return
+	}
+	if err != nil {
+		log.WithError(err).Warn("Bulk goal operation failed")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	activityType := "goal_updated"
+	if req.Action == "delete" {
+		activityType = "goal_deleted"
+	}
+	for _, goal := range goals {
+		_ = h.ActivityService.LogActivity(r.Context(), userID, activityType, goal.ID, fmt.Sprintf("Bulk %s on goal: %s", req.Action, goal.Name))
+	}
+
+	log.Info("Bulk goal operation applied")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+	})
+}
+
+// ReorderSubstepsHandler reorders the substeps of one step.
+func (h *GoalHandler) ReorderSubstepsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	stepIndex, err := strconv.Atoi(vars["stepIndex"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid step index", nil)
+		return
+	}
+
+	var req struct {
+		Order []int `json:"order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid request payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	goal, ok := h.loadEditableGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	updatedGoal, err := h.Service.ReorderSubsteps(r.Context(), goalID, userID, stepIndex, req.Order)
+	if err != nil {
+		writeStepEditError(w, log, err)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_substeps_reordered", goal.ID, fmt.Sprintf("Reordered substeps in goal: %s", goal.Name))
+
+	log.Info("Substeps reordered")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// SetStepNoteHandler sets the note on a step, or on one of its substeps when
+// substep_index is given.
+// PATCH /goals/{id}/steps/{stepIndex}/note
+func (h *GoalHandler) SetStepNoteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	stepIndex, err := strconv.Atoi(vars["stepIndex"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid step index", nil)
+		return
+	}
+
+	var req struct {
+		Note         string `json:"note"`
+		SubstepIndex *int   `json:"substep_index"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.WithError(err).Warn("Invalid request payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	goal, ok := h.loadEditableGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	updatedGoal, err := h.Service.SetStepNote(r.Context(), goalID, userID, stepIndex, req.SubstepIndex, req.Note)
+	if err != nil {
+		writeStepEditError(w, log, err)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_step_note_updated", goal.ID, fmt.Sprintf("Updated a step note in goal: %s", goal.Name))
+
+	log.Info("Step note updated")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// MoveSubstepHandler moves a substep within a step or to another step.
+func (h *GoalHandler) MoveSubstepHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var move services.SubstepMove
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		log.WithError(err).Warn("Invalid request payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	goal, ok := h.loadEditableGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	updatedGoal, err := h.Service.MoveSubstep(r.Context(), goalID, userID, move)
+	if err != nil {
+		writeStepEditError(w, log, err)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_substep_moved", goal.ID, fmt.Sprintf("Moved a substep in goal: %s", goal.Name))
+
+	log.Info("Substep moved")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// SetBlockedByHandler replaces the goals a goal depends on. While any of them
+// is not completed, progress on the goal is rejected.
+// PUT /goals/{id}/blocked-by {"blocked_by": ["<goalID>", ...]}
+func (h *GoalHandler) SetBlockedByHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to set goal dependencies")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		BlockedBy []string `json:"blocked_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	updatedGoal, err := h.Service.SetBlockedBy(r.Context(), goal, body.BlockedBy)
+	if err != nil {
+		log.WithError(err).Warn("Failed to set goal dependencies")
+		switch {
+		case errors.Is(err, services.ErrDependencyCycle):
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		case errors.Is(err, services.ErrInvalidDependency):
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		default:
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to set goal dependencies", nil)
+		}
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_dependencies_updated", goal.ID, "Updated dependencies of goal: "+goal.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// CloseGoalHandler lets the owner give up on a goal. The body is optional:
+// {"reason": "..."}.
+// POST /goals/{id}/close
+func (h *GoalHandler) CloseGoalHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to close goal")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	updatedGoal, err := h.Service.CloseGoal(r.Context(), goalID, goal.UserID, body.Reason)
+	if err != nil {
+		log.WithError(err).Warn("Failed to close goal")
+		switch {
+		case errors.Is(err, services.ErrCannotCloseGoal):
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		case errors.Is(err, services.ErrInvalidNote):
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		default:
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to close goal", nil)
+		}
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_closed", goal.ID, fmt.Sprintf("Closed goal: %s", goal.Name))
+
+	log.Info("Goal closed")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// ReopenGoalHandler takes a goal out of the closed status.
+// POST /goals/{id}/reopen
+func (h *GoalHandler) ReopenGoalHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to reopen goal")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	updatedGoal, err := h.Service.ReopenGoal(r.Context(), goalID, goal.UserID)
+	if err != nil {
+		log.WithError(err).Warn("Failed to reopen goal")
+		if errors.Is(err, services.ErrGoalNotClosed) {
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+			return
+		}
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to reopen goal", nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_reopened", goal.ID, fmt.Sprintf("Reopened goal: %s", goal.Name))
+
+	log.Info("Goal reopened")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// PinGoalHandler pins a goal so it is listed first.
+// POST /goals/{id}/pin
+func (h *GoalHandler) PinGoalHandler(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, true)
+}
+
+// UnpinGoalHandler removes a goal's pin.
+// POST /goals/{id}/unpin
+func (h *GoalHandler) UnpinGoalHandler(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, false)
+}
+
+func (h *GoalHandler) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithFields(logrus.Fields{"goalID": goalID, "pinned": pinned})
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to pin goal")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	updatedGoal, err := h.Service.SetPinned(r.Context(), goalID, goal.UserID, pinned)
+	if err != nil {
+		log.WithError(err).Warn("Failed to change goal pin")
+		if errors.Is(err, services.ErrPinLimitReached) {
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+			return
+		}
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update goal", nil)
+		return
+	}
+
+	log.Info("Goal pin changed")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// ConfirmCompletionHandler lets the owner settle a goal that is pending
+// completion. The body is optional; {"confirm": false} reopens the goal.
+func (h *GoalHandler) ConfirmCompletionHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to confirm goal completion")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	body := struct {
+		Confirm *bool `json:"confirm"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+			return
+		}
+		defer r.Body.Close()
+	}
+	confirm := body.Confirm == nil || *body.Confirm
+
+	updatedGoal, err := h.Service.ConfirmCompletion(r.Context(), goalID, goal.UserID, confirm)
+	if err != nil {
+		log.WithError(err).Warn("Failed to confirm goal completion")
+		switch {
+		case errors.Is(err, services.ErrNotPendingCompletion):
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		case errors.Is(err, repository.ErrGoalModified):
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, "Goal was changed by someone else, please retry", nil)
+		default:
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to confirm goal completion", nil)
+		}
+		return
+	}
+
+	activityType, message := "goal_completed", "Confirmed completion of goal: "
+	if !confirm {
+		activityType, message = "goal_reopened", "Reopened goal: "
+	}
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, activityType, goal.ID, message+goal.Name)
+
+	log.WithField("confirmed", confirm).Info("Goal completion settled")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
+
+// ShareGoalHandler creates a read-only share link for a goal.
+func (h *GoalHandler) ShareGoalHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to share goal")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	token, err := h.Service.CreateShareToken(r.Context(), goalID, goal.UserID)
+	if err != nil {
+		log.WithError(err).Error("Failed to create share link")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to create share link", nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_shared", goal.ID, fmt.Sprintf("Created a share link for goal: %s", goal.Name))
+
+	log.Info("Goal share link created")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+		"url":   "/goals/shared/" + token,
+	})
+}
+
+// RevokeShareHandler disables the goal's share link.
+func (h *GoalHandler) RevokeShareHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized attempt to revoke share link")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	goal, ok := h.loadOwnedGoal(w, r, goalID)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.RevokeShareToken(r.Context(), goalID, goal.UserID); err != nil {
+		log.WithError(err).Error("Failed to revoke share link")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to revoke share link", nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), goal.UserID, "goal_share_revoked", goal.ID, fmt.Sprintf("Revoked the share link for goal: %s", goal.Name))
+
+	log.Info("Goal share link revoked")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSharedGoalHandler serves the read-only view of a shared goal. It is public,
+// so unknown and revoked tokens both answer 404.
+func (h *GoalHandler) GetSharedGoalHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	shared, err := h.Service.GetGoalByShareToken(r.Context(), token)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			requestLogger(r).WithError(err).Error("Failed to look up shared goal")
+		}
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(shared)
+}
+
+// ExportICalHandler returns the due dates of the caller's unfinished goals,
+// steps and substeps as an iCalendar file.
+// GET /goals/export.ics
+func (h *GoalHandler) ExportICalHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	feed, err := h.Service.ExportICal(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to export calendar")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to export calendar", nil)
+		return
+	}
+	writeICal(w, feed)
+}
+
+// CalendarFeedHandler serves the same feed as ExportICalHandler to calendar
+// apps, which authenticate with the secret token in the URL instead of a JWT.
+// GET /goals/calendar/{token}.ics
+func (h *GoalHandler) CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	feed, err := h.Service.ExportICalByToken(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			requestLogger(r).WithError(err).Error("Failed to export calendar feed")
+		}
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Calendar not found", nil)
+		return
+	}
+	writeICal(w, feed)
+}
+
+func writeICal(w http.ResponseWriter, feed []byte) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="goals.ics"`)
+	w.Write(feed)
+}
+
+// loadOwnedGoal fetches a goal that is not in the trash and checks that the
+// caller owns it, writing the 404/403 response itself when not.
+func (h *GoalHandler) loadOwnedGoal(w http.ResponseWriter, r *http.Request, goalID string) (*models.Goal, bool) {
+	return h.loadGoalOwnedBy(w, r, goalID, h.Service.GetActiveGoal)
+}
+
+// loadOwnedTrashGoal is loadOwnedGoal for the trash endpoints, which work on
+// trashed goals.
+func (h *GoalHandler) loadOwnedTrashGoal(w http.ResponseWriter, r *http.Request, goalID string) (*models.Goal, bool) {
+	return h.loadGoalOwnedBy(w, r, goalID, h.Service.GetGoal)
+}
+
+func (h *GoalHandler) loadGoalOwnedBy(w http.ResponseWriter, r *http.Request, goalID string, load func(context.Context, string) (*models.Goal, error)) (*models.Goal, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	goal, err := load(r.Context(), goalID)
+	if err != nil || goal == nil {
+		requestLogger(r).WithField("goalID", goalID).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return nil, false
+	}
+
+	if goal.UserID.Hex() != claims.UserID {
+		requestLogger(r).WithField("goalID", goalID).Warn("Forbidden: User is not the owner")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only the owner can do this", nil)
+		return nil, false
+	}
+
+	return goal, true
+}
+
+// checkNotBlocked answers with 409 and the unfinished dependencies when the
+// goal still waits on other goals.
+func (h *GoalHandler) checkNotBlocked(w http.ResponseWriter, r *http.Request, goal *models.Goal) bool {
+	err := h.Service.CheckNotBlocked(r.Context(), goal)
+	if err == nil {
+		return true
+	}
+
+	var blocked *services.BlockedError
+	if !errors.As(err, &blocked) {
+		requestLogger(r).WithError(err).WithField("goalID", goal.ID.Hex()).Error("Failed to check goal dependencies")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to check goal dependencies", nil)
+		return false
+	}
+
+	type blocker struct {
+		ID     primitive.ObjectID `json:"id"`
+		Name   string             `json:"name"`
+		Status string             `json:"status"`
+	}
+	blockers := make([]blocker, len(blocked.Blockers))
+	for i, b := range blocked.Blockers {
+		blockers[i] = blocker{ID: b.ID, Name: b.Name, Status: b.Status}
+	}
+
+	apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, blocked.Error(), map[string]interface{}{
+		"blocked_by": blockers,
+	})
+	return false
+}
+
+// writeVersionConflict answers a rejected optimistic update with 409 and the
+// goal's current version so the client can reload and retry.
+func (h *GoalHandler) writeVersionConflict(w http.ResponseWriter, r *http.Request, goalID string) {
+	var details interface{}
+	if latest, err := h.Service.GetGoal(r.Context(), goalID); err == nil && latest != nil {
+		details = map[string]interface{}{"version": latest.Version}
+	}
+
+	apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, repository.ErrConflict.Error(), details)
+}
+
+// loadEditableGoal fetches a goal that is not in the trash and checks that the
+// caller is its owner or a collaborator, writing the 404/403 response itself when not.
+func (h *GoalHandler) loadEditableGoal(w http.ResponseWriter, r *http.Request, goalID string) (*models.Goal, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	goal, err := h.Service.GetActiveGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		requestLogger(r).WithField("goalID", goalID).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return nil, false
+	}
+
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleEditor) {
+		requestLogger(r).WithField("goalID", goalID).Warn("Forbidden: User is not the owner or a collaborator")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only owner or editors can update the goal", nil)
+		return nil, false
+	}
+
+	return goal, true
+}
+
+// writeStepEditError maps step edit errors to HTTP responses.
+func writeStepEditError(w http.ResponseWriter, log *logrus.Entry, err error) {
+	if errors.Is(err, repository.ErrGoalModified) {
+		log.Warn("Goal was modified concurrently")
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, "Goal was modified by someone else, please retry", nil)
+		return
+	}
+	log.WithError(err).Warn("Failed to edit steps")
+	apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+}
+
+// actorID returns the caller's user ID, or the nil ID if the claims hold an invalid one.
+func actorID(claims *jwtutil.Claims) primitive.ObjectID {
+	id, _ := primitive.ObjectIDFromHex(claims.UserID)
+	return id
+}
+
+// displayStatus returns the status shown for a goal: "expired" once an
+// unfinished goal is past its due date. Completed and closed goals keep
+// their status.
+func displayStatus(goal *models.Goal, now time.Time) string {
+	if !goal.DueDate.IsZero() && goal.DueDate.Before(now) && goal.Status != "completed" && goal.Status != services.GoalStatusClosed {
+		return "expired"
+	}
+	return goal.Status
+}
+
+// isCollaborator reports whether userID collaborates on the goal with at least
+// the given role: any collaborator passes for "viewer", only editors for "editor".
+func isCollaborator(collaborators []models.Collaborator, userID string, role string) bool {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false
+	}
+	for _, c := range collaborators {
+		if c.UserID == id {
+			return role == models.CollaboratorRoleViewer || c.CanEdit()
+		}
+	}
+	return false
+}
+
+// writeStepDueDateError answers 400 listing the offending steps and substeps
+// when err is a *services.StepDueDateError. It reports whether it wrote a response.
+func writeStepDueDateError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var dueErr *services.StepDueDateError
+	if !errors.As(err, &dueErr) {
+		return false
+	}
+	requestLogger(r).WithError(err).Warn("Invalid step due dates provided")
+	apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput,
+		"Step and substep due dates must not be after the goal's due date or in the past",
+		map[string]interface{}{"steps": dueErr.Issues})
+	return true
 }
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"github.com/gorilla/mux"
+)
+
+func TestDisplayStatus(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		status  string
+		dueDate time.Time
+		want    string
+	}{
+		{status: "in_progress", dueDate: past, want: "expired"},
+		{status: services.GoalStatusPendingCompletion, dueDate: past, want: "expired"},
+		{status: "in_progress", dueDate: future, want: "in_progress"},
+		{status: "in_progress", want: "in_progress"},
+		{status: "completed", dueDate: past, want: "completed"},
+		{status: services.GoalStatusClosed, dueDate: past, want: services.GoalStatusClosed},
+		{status: services.GoalStatusClosed, dueDate: future, want: services.GoalStatusClosed},
+	}
+
+	for _, tt := range tests {
+		goal := &models.Goal{Status: tt.status, DueDate: tt.dueDate}
+		if got := displayStatus(goal, now); got != tt.want {
+			t.Errorf("displayStatus(%q, due %v) = %q, want %q", tt.status, tt.dueDate, got, tt.want)
+		}
+	}
+}
+
+// twinStepsGoal has two steps with the same name, which only an index can tell apart.
+func twinStepsGoal() *models.Goal {
+	return &models.Goal{Steps: []models.Step{
+		{Name: "Read chapter", Substeps: []models.Substep{{Title: "1"}, {Title: "2", Done: true}}},
+		{Name: "Read chapter", Substeps: []models.Substep{{Title: "3"}}},
+		{Name: "Summarize", Substeps: []models.Substep{{Title: "4"}}},
+	}}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestApplySubstepProgress(t *testing.T) {
+	tests := []struct {
+		name          string
+		item          progressItem
+		wantStep      int
+		wantErr       bool
+		wantCompleted bool
+	}{
+		{name: "index completes step", item: progressItem{StepIndex: intPtr(0), SubstepIdx: 0, Done: true}, wantStep: 0, wantCompleted: true},
+		{name: "index reaches second twin", item: progressItem{StepIndex: intPtr(1), SubstepIdx: 0, Done: true}, wantStep: 1, wantCompleted: true},
+		{name: "index wins over name", item: progressItem{StepIndex: intPtr(2), StepName: "Read chapter", SubstepIdx: 0, Done: true}, wantStep: 2, wantCompleted: true},
+		{name: "name falls back to first match", item: progressItem{StepName: "Read chapter", SubstepIdx: 0, Done: true}, wantStep: 0, wantCompleted: true},
+		{name: "undone substep reopens step", item: progressItem{StepIndex: intPtr(0), SubstepIdx: 1, Done: false}, wantStep: 0, wantCompleted: false},
+		{name: "index out of range", item: progressItem{StepIndex: intPtr(3)}, wantErr: true},
+		{name: "negative index", item: progressItem{StepIndex: intPtr(-1)}, wantErr: true},
+		{name: "unknown name", item: progressItem{StepName: "Write"}, wantErr: true},
+		{name: "no step", item: progressItem{SubstepIdx: 0, Done: true}, wantErr: true},
+		{name: "substep out of range", item: progressItem{StepIndex: intPtr(1), SubstepIdx: 1, Done: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goal := twinStepsGoal()
+			stepIdx, err := applySubstepProgress(goal, tt.item)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("applySubstepProgress(%+v) succeeded, want an error", tt.item)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applySubstepProgress: %v", err)
+			}
+			if stepIdx != tt.wantStep {
+				t.Errorf("modified step = %d, want %d", stepIdx, tt.wantStep)
+			}
+			if got := goal.Steps[stepIdx].Completed; got != tt.wantCompleted {
+				t.Errorf("step completed = %v, want %v", got, tt.wantCompleted)
+			}
+			for i, step := range goal.Steps {
+				if i != stepIdx && step.Completed {
+					t.Errorf("untouched step %d was marked completed", i)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateGoalProgressHandlerAutoCompletes(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	handler := NewGoalHandler(svc.Goal, services.NewActivityService(svc.Activities), svc.Notification)
+	router := mux.NewRouter()
+	router.HandleFunc("/goals/{id}/progress", handler.UpdateGoalProgressHandler).Methods(http.MethodPatch)
+
+	owner := testutil.SeedUser(t, svc.Repositories, models.User{XP: 11000, Level: services.LevelForXP(11000)})
+	goal := twinStepsGoal()
+	goal.UserID = owner.ID
+	goal.Name = "Book"
+	created, err := svc.Goal.CreateGoal(ctx, goal)
+	if err != nil {
+		t.Fatalf("CreateGoal: %v", err)
+	}
+
+	patch := func(body string) (int, progressResponse) {
+		t.Helper()
+		req := asUser(httptest.NewRequest(http.MethodPatch, "/goals/"+created.ID.Hex()+"/progress", strings.NewReader(body)), owner.ID)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp progressResponse
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	// The second "Read chapter" is reachable by index and completes on its own
+	status, resp := patch(`{"step_index": 1, "substep_index": 0, "done": true}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	want := modifiedStep{StepIndex: 1, StepName: "Read chapter", SubstepIndex: 0, Completed: true}
+	if resp.ModifiedStep != want {
+		t.Errorf("modified_step = %+v, want %+v", resp.ModifiedStep, want)
+	}
+	if resp.Steps[0].Completed || !resp.Steps[1].Completed {
+		t.Errorf("completed flags = %v/%v, want only the second step", resp.Steps[0].Completed, resp.Steps[1].Completed)
+	}
+
+	// Finishing the remaining steps by index auto-completes the goal
+	patch(`{"step_index": 0, "substep_index": 0, "done": true}`)
+	status, resp = patch(`{"step_index": 2, "substep_index": 0, "done": true}`)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", status)
+	}
+	if resp.Status != "completed" {
+		t.Errorf("goal status = %q, want completed", resp.Status)
+	}
+
+	stored, err := svc.Goals.GetGoalByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	if stored.Status != "completed" {
+		t.Errorf("stored status = %q, want completed", stored.Status)
+	}
+	for i, step := range stored.Steps {
+		if !step.Completed {
+			t.Errorf("stored step %d not completed", i)
+		}
+	}
+
+	if status, _ := patch(`{"step_index": 3, "substep_index": 0, "done": true}`); status != http.StatusBadRequest {
+		t.Errorf("out-of-range step_index: status = %d, want 400", status)
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GroupChatHandler serves group chat management and history. Messages are
+// sent over the chat WebSocket as group_text frames.
+type GroupChatHandler struct {
+	Service *services.GroupChatService
+}
+
+// NewGroupChatHandler initializes a new GroupChatHandler.
+func NewGroupChatHandler(service *services.GroupChatService) *GroupChatHandler {
+	return &GroupChatHandler{Service: service}
+}
+
+// groupMembersRequest is the body of the create and add-members endpoints.
+type groupMembersRequest struct {
+	Name    string               `json:"name"`
+	Members []primitive.ObjectID `json:"members"`
+}
+
+// callerID returns the authenticated user's ID, writing an error response
+// when there is none.
+func (h *GroupChatHandler) callerID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return primitive.NilObjectID, false
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+// writeGroupChatError maps GroupChatService errors to responses.
+func writeGroupChatError(w http.ResponseWriter, r *http.Request, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrGroupChatNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Group chat not found", nil)
+	case errors.Is(err, services.ErrGroupMemberNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrGroupChatForbidden), errors.Is(err, services.ErrGroupMemberNotFriend):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidGroupChat), errors.Is(err, services.ErrGroupChatFull):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+	default:
+		requestLogger(r).WithError(err).Errorf("Failed to %s", action)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to "+action, nil)
+	}
+}
+
+// CreateGroupChatHandler starts a group chat with some of the caller's friends.
+// POST /chat/groups {"name": "...", "members": ["<user id>", ...]}
+func (h *GroupChatHandler) CreateGroupChatHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req groupMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	group, err := h.Service.CreateGroupChat(r.Context(), userID, req.Name, req.Members)
+	if err != nil {
+		writeGroupChatError(w, r, err, "create group chat")
+		return
+	}
+
+	requestLogger(r).Infof("User %s created group chat %s", userID.Hex(), group.ID.Hex())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(group)
+}
+
+// GetGroupChatsHandler lists the group chats the caller belongs to.
+// GET /chat/groups
+func (h *GroupChatHandler) GetGroupChatsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	groups, err := h.Service.GetGroupChats(r.Context(), userID)
+	if err != nil {
+		writeGroupChatError(w, r, err, "fetch group chats")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// AddGroupMembersHandler adds some of the caller's friends to a group chat.
+// POST /chat/groups/{id}/members {"members": ["<user id>", ...]}
+func (h *GroupChatHandler) AddGroupMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	groupID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid group chat ID", nil)
+		return
+	}
+
+	var req groupMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Members) == 0 {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Body must list at least one member", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	group, err := h.Service.AddMembers(r.Context(), groupID, userID, req.Members)
+	if err != nil {
+		writeGroupChatError(w, r, err, "add group members")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// RemoveGroupMemberHandler removes a member from a group chat. Members may
+// remove themselves; the creator may remove anyone.
+// DELETE /chat/groups/{id}/members/{userID}
+func (h *GroupChatHandler) RemoveGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	callerID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	groupID, err := primitive.ObjectIDFromHex(vars["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid group chat ID", nil)
+		return
+	}
+	memberID, err := primitive.ObjectIDFromHex(vars["userID"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	if err := h.Service.RemoveMember(r.Context(), groupID, callerID, memberID); err != nil {
+		writeGroupChatError(w, r, err, "remove group member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetGroupHistoryHandler returns one page of a group chat's messages, newest first.
+// GET /chat/groups/{id}/history?cursor=&limit=50
+func (h *GroupChatHandler) GetGroupHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	groupID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid group chat ID", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	var limit int64
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.Service.GetHistory(r.Context(), groupID, userID, query.Get("cursor"), limit)
+	if err != nil {
+		writeGroupChatError(w, r, err, "fetch group chat history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/database"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// readyTimeout bounds the MongoDB ping done by the readiness probe.
+const readyTimeout = 2 * time.Second
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	DB *mongo.Database
+}
+
+// NewHealthHandler creates a new HealthHandler.
+func NewHealthHandler(db *mongo.Database) *HealthHandler {
+	return &HealthHandler{DB: db}
+}
+
+// GET /health
+// Reports that the process is up without touching any dependency.
+func (h *HealthHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, "ok")
+}
+
+// GET /ready
+// Reports whether the server can take traffic, i.e. whether MongoDB answers
+// a ping within readyTimeout. Answers 503 when it does not.
+func (h *HealthHandler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	if err := database.Ping(ctx, h.DB); err != nil {
+		requestLogger(r).WithError(err).Warn("Readiness check failed: MongoDB ping")
+		writeProbe(w, http.StatusServiceUnavailable, "unavailable")
+		return
+	}
+	writeProbe(w, http.StatusOK, "ok")
+}
+
+func writeProbe(w http.ResponseWriter, status int, state string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": state,
+		"time":   time.Now().UTC(),
+	})
+}
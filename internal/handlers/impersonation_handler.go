@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/config"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxImpersonationReasonLen caps the reason an admin gives for impersonating.
+const maxImpersonationReasonLen = 500
+
+// ImpersonationHandler lets admins act as another user for support purposes.
+type ImpersonationHandler struct {
+	UserService     *services.UserService
+	ActivityService *services.ActivityService
+	Config          *config.Config
+}
+
+// NewImpersonationHandler creates a new ImpersonationHandler.
+func NewImpersonationHandler(userService *services.UserService, activityService *services.ActivityService, cfg *config.Config) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		UserService:     userService,
+		ActivityService: activityService,
+		Config:          cfg,
+	}
+}
+
+// POST /admin/impersonate/{userId} {"reason": "..."}
+// Issues a 15 minute access token for the target user. No refresh token is
+// issued, and the token cannot change the target's email or delete the account.
+func (h *ImpersonationHandler) ImpersonateHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	if claims.IsImpersonation() {
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: not allowed while impersonating", nil)
+		return
+	}
+	adminID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Reason is required", nil)
+		return
+	}
+	if len(req.Reason) > maxImpersonationReasonLen {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, fmt.Sprintf("Reason must be at most %d characters", maxImpersonationReasonLen), nil)
+		return
+	}
+
+	target, err := h.UserService.GetUser(r.Context(), mux.Vars(r)["userId"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "User not found", nil)
+		return
+	}
+	if target.ID == adminID {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Cannot impersonate yourself", nil)
+		return
+	}
+	if target.Role == "admin" {
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Cannot impersonate another admin", nil)
+		return
+	}
+
+	token, err := jwtutil.GenerateImpersonationToken(target.ID.Hex(), target.Email, target.Role, adminID.Hex(), h.Config.JWTKeys)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to generate impersonation token")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to generate token", nil)
+		return
+	}
+	expiresAt := time.Now().Add(jwtutil.ImpersonationTokenExpiry)
+
+	requestLogger(r).WithFields(map[string]interface{}{
+		"admin_id":   adminID.Hex(),
+		"user_id":    target.ID.Hex(),
+		"reason":     req.Reason,
+		"expires_at": expiresAt,
+	}).Warn("AUDIT: impersonation token issued")
+
+	_ = h.ActivityService.LogActivity(r.Context(), adminID, "user_impersonated", target.ID,
+		fmt.Sprintf("Started impersonating %s: %s", target.Username, req.Reason))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"expires_in":   int64(jwtutil.ImpersonationTokenExpiry.Seconds()),
+		"expires_at":   expiresAt,
+		"user_id":      target.ID.Hex(),
+	})
+}
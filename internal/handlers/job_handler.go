@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+)
+
+// JobHandler reports on scheduled background jobs.
+type JobHandler struct {
+	Service *services.JobService
+}
+
+// NewJobHandler creates a new JobHandler.
+func NewJobHandler(service *services.JobService) *JobHandler {
+	return &JobHandler{Service: service}
+}
+
+// GET /admin/jobs/status
+func (h *JobHandler) JobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.Service.GetStatus(r.Context())
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to get job status")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get job status", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
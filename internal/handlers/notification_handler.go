@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
-	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -19,23 +23,100 @@ func NewNotificationHandler(service *services.NotificationService) *Notification
 	return &NotificationHandler{Service: service}
 }
 
-// GET /notifications
+// GET /notifications?type=&read=false&from=YYYY-MM-DD&to=YYYY-MM-DD&cursor=<id>&limit=20
+// Lists the user's notifications newest first, one page at a time. from and
+// to filter on the creation date; to is inclusive.
 func (h *NotificationHandler) GetUserNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
-
 	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
-	notifications, err := h.Service.GetUserNotifications(r.Context(), userID)
+
+	filter, ok := parseNotificationFilter(w, r)
+	if !ok {
+		return
+	}
+	var limit int64
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 1 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	page, err := h.Service.GetFilteredNotifications(r.Context(), userID, filter, r.URL.Query().Get("cursor"), limit)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid cursor", nil)
+		return
+	}
+	if err != nil {
+		requestLogger(r).Errorf("Failed to fetch notifications: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get notifications", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseNotificationFilter reads the type, read, from and to query
+// parameters. It writes a 400 response and returns false when one is invalid.
+func parseNotificationFilter(w http.ResponseWriter, r *http.Request) (repository.NotificationFilter, bool) {
+	query := r.URL.Query()
+	filter := repository.NotificationFilter{Type: query.Get("type")}
+
+	if v := query.Get("read"); v != "" {
+		read, err := strconv.ParseBool(v)
+		if err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid read, expected true or false", nil)
+			return filter, false
+		}
+		filter.Read = &read
+	}
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.CreatedFrom, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid from date, expected YYYY-MM-DD", nil)
+			return filter, false
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.CreatedTo, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid to date, expected YYYY-MM-DD", nil)
+			return filter, false
+		}
+		filter.CreatedTo = filter.CreatedTo.AddDate(0, 0, 1)
+	}
+	return filter, true
+}
+
+// GET /notifications/count
+// Returns {"total": n, "unread": n} for badge counters.
+func (h *NotificationHandler) GetNotificationCountHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		logger.Log.Errorf("Failed to fetch notifications: %v", err)
-		http.Error(w, "Failed to get notifications", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
 		return
 	}
 
-	json.NewEncoder(w).Encode(notifications)
+	count, err := h.Service.GetNotificationCount(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).Errorf("Failed to count notifications: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to count notifications", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(count)
 }
 
 // POST /notifications/{id}/read
@@ -43,13 +124,13 @@ func (h *NotificationHandler) MarkAsReadHandler(w http.ResponseWriter, r *http.R
 	vars := mux.Vars(r)
 	notifID, err := primitive.ObjectIDFromHex(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid notification ID", nil)
 		return
 	}
 
 	if err := h.Service.MarkNotificationAsRead(r.Context(), notifID); err != nil {
-		logger.Log.Errorf("Failed to mark notification as read: %v", err)
-		http.Error(w, "Failed to mark as read", http.StatusInternalServerError)
+		requestLogger(r).Errorf("Failed to mark notification as read: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to mark as read", nil)
 		return
 	}
 
@@ -62,16 +143,59 @@ func (h *NotificationHandler) DeleteNotificationHandler(w http.ResponseWriter, r
 	vars := mux.Vars(r)
 	notifID, err := primitive.ObjectIDFromHex(vars["id"])
 	if err != nil {
-		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid notification ID", nil)
 		return
 	}
 
 	if err := h.Service.DeleteNotification(r.Context(), notifID); err != nil {
-		logger.Log.Errorf("Failed to delete notification: %v", err)
-		http.Error(w, "Failed to delete notification", http.StatusInternalServerError)
+		requestLogger(r).Errorf("Failed to delete notification: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to delete notification", nil)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Notification deleted"})
 }
+
+// GET /notifications/summary
+func (h *NotificationHandler) GetNotificationSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	summary, err := h.Service.GetNotificationSummary(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).Errorf("Failed to build notification summary: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get notification summary", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// GET /admin/notifications/summary?limit=10
+func (h *NotificationHandler) AdminNotificationSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	limit := int64(services.DefaultAdminSummaryLimit)
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	summary, err := h.Service.GetAdminNotificationSummary(r.Context(), limit)
+	if err != nil {
+		requestLogger(r).Errorf("Failed to build admin notification summary: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get notification summary", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/config"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	googleUserInfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
+	oauthStateCookie  = "oauth_state"
+	oauthStateTTL     = 10 * time.Minute
+)
+
+// OAuthHandler signs users in through external identity providers.
+type OAuthHandler struct {
+	Service *services.UserService
+	Config  *config.Config
+	google  *oauth2.Config
+}
+
+// NewOAuthHandler creates a new OAuthHandler. Google login stays disabled
+// until the client ID and secret are configured.
+func NewOAuthHandler(service *services.UserService, cfg *config.Config) *OAuthHandler {
+	h := &OAuthHandler{Service: service, Config: cfg}
+	if cfg.GoogleClientID != "" && cfg.GoogleClientSecret != "" {
+		h.google = &oauth2.Config{
+			ClientID:     cfg.GoogleClientID,
+			ClientSecret: cfg.GoogleClientSecret,
+			RedirectURL:  cfg.GoogleRedirectURL,
+			Scopes:       []string{"openid", "email", "profile"},
+			Endpoint:     google.Endpoint,
+		}
+	}
+	return h
+}
+
+// GoogleLoginHandler redirects the browser to Google's consent screen.
+func (h *OAuthHandler) GoogleLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if h.google == nil {
+		apierror.WriteError(w, http.StatusServiceUnavailable, apierror.CodeUnavailable, "Google login is not configured", nil)
+		return
+	}
+
+	// The state ties the callback to this browser and guards against CSRF
+	state := uuid.NewString()
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/auth/google",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, h.google.AuthCodeURL(state), http.StatusFound)
+}
+
+// GoogleCallbackHandler exchanges the authorization code, signs the user in
+// and redirects to the frontend with the JWT in the query string.
+func (h *OAuthHandler) GoogleCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if h.google == nil {
+		apierror.WriteError(w, http.StatusServiceUnavailable, apierror.CodeUnavailable, "Google login is not configured", nil)
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || cookie.Value == "" || cookie.Value != r.URL.Query().Get("state") {
+		requestLogger(r).Warn("Google callback with missing or mismatched state")
+		h.redirectWithError(w, r, "invalid_state")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/auth/google", MaxAge: -1})
+
+	if r.URL.Query().Get("error") != "" {
+		h.redirectWithError(w, r, "access_denied")
+		return
+	}
+
+	token, err := h.google.Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		requestLogger(r).WithError(err).Warn("Failed to exchange Google authorization code")
+		h.redirectWithError(w, r, "exchange_failed")
+		return
+	}
+
+	resp, err := h.google.Client(r.Context(), token).Get(googleUserInfoURL)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to fetch Google user info")
+		h.redirectWithError(w, r, "userinfo_failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	var info struct {
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&info) != nil || info.ID == "" {
+		requestLogger(r).WithField("status", resp.StatusCode).Error("Unexpected Google user info response")
+		h.redirectWithError(w, r, "userinfo_failed")
+		return
+	}
+	if !info.VerifiedEmail {
+		h.redirectWithError(w, r, "email_not_verified")
+		return
+	}
+
+	user, err := h.Service.LoginOrRegisterWithGoogle(r.Context(), info.Email, info.ID, info.Name)
+	if err != nil {
+		requestLogger(r).WithError(err).Warn("Google login failed")
+		h.redirectWithError(w, r, "login_failed")
+		return
+	}
+
+	// Accounts with 2FA still have to finish through /users/login/totp
+	params := url.Values{}
+	if user.TOTPEnabled {
+		totpToken, err := jwtutil.GeneratePurposeToken(user.ID.Hex(), jwtutil.PurposeTOTPPending, h.Config.JWTKeys, totpLoginWindow)
+		if err != nil {
+			h.redirectWithError(w, r, "token_failed")
+			return
+		}
+		params.Set("totp_token", totpToken)
+	} else {
+		if err := h.Service.UpdateLoginStreak(r.Context(), user.ID); err != nil {
+			requestLogger(r).WithError(err).Warn("Failed to update login streak")
+		}
+		jwtToken, err := jwtutil.GenerateToken(user.ID.Hex(), user.Email, user.Role, h.Config.JWTKeys, h.Config.TokenExpiry)
+		if err != nil {
+			requestLogger(r).WithError(err).Error("Failed to generate JWT token")
+			h.redirectWithError(w, r, "token_failed")
+			return
+		}
+		refreshToken, err := issueRefreshToken(r.Context(), h.Service, h.Config, user.ID)
+		if err != nil {
+			requestLogger(r).WithError(err).Error("Failed to issue refresh token")
+			h.redirectWithError(w, r, "token_failed")
+			return
+		}
+		params.Set("token", jwtToken)
+		params.Set("refresh_token", refreshToken)
+	}
+
+	requestLogger(r).WithField("userID", user.ID.Hex()).Info("User logged in with Google")
+	http.Redirect(w, r, fmt.Sprintf("%s?%s", h.Config.OAuthSuccessURL, params.Encode()), http.StatusFound)
+}
+
+// redirectWithError sends the browser back to the frontend with an error code.
+func (h *OAuthHandler) redirectWithError(w http.ResponseWriter, r *http.Request, code string) {
+	params := url.Values{"error": {code}}
+	http.Redirect(w, r, fmt.Sprintf("%s?%s", h.Config.OAuthSuccessURL, params.Encode()), http.StatusFound)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+)
+
+// historyDateLayout is the format of the from/to query parameters.
+const historyDateLayout = "2006-01-02"
+
+type ProgressHandler struct {
+	Service     *services.ProgressService
+	GoalService *services.GoalService
+}
+
+func NewProgressHandler(service *services.ProgressService, goalService *services.GoalService) *ProgressHandler {
+	return &ProgressHandler{
+		Service:     service,
+		GoalService: goalService,
+	}
+}
+
+// GetGoalHistoryHandler returns a goal's progress over time.
+// GET /goals/{id}/history?from=2024-01-01&to=2024-12-31 (both dates inclusive)
+func (h *ProgressHandler) GetGoalHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	goalID := mux.Vars(r)["id"]
+	log := requestLogger(r).WithField("goalID", goalID)
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var from, to time.Time
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid from date, expected YYYY-MM-DD", nil)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(historyDateLayout, v); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid to date, expected YYYY-MM-DD", nil)
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+
+	goal, err := h.GoalService.GetGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		log.WithError(err).Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	}
+
+	if goal.UserID.Hex() != claims.UserID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleViewer) {
+		log.Warn("Forbidden: Not owner or collaborator")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden", nil)
+		return
+	}
+
+	snapshots, err := h.Service.GetHistory(r.Context(), goal.ID, from, to)
+	if err != nil {
+		log.WithError(err).Error("Failed to fetch progress history")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch progress history", nil)
+		return
+	}
+	if snapshots == nil {
+		snapshots = []models.ProgressSnapshot{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshots)
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/sirupsen/logrus"
+)
+
+// requestLogger returns a log entry tagged with the request's ID so all
+// entries for one request can be correlated.
+func requestLogger(r *http.Request) *logrus.Entry {
+	return logger.Log.WithField("request_id", middleware.GetRequestID(r.Context()))
+}
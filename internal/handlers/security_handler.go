@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SecurityHandler exposes operational security endpoints for admins.
+type SecurityHandler struct {
+	KeyRotation     *services.KeyRotationService
+	ActivityService *services.ActivityService
+}
+
+// NewSecurityHandler creates a new SecurityHandler.
+func NewSecurityHandler(keyRotation *services.KeyRotationService, activityService *services.ActivityService) *SecurityHandler {
+	return &SecurityHandler{
+		KeyRotation:     keyRotation,
+		ActivityService: activityService,
+	}
+}
+
+// POST /admin/security/rotate-jwt
+func (h *SecurityHandler) RotateJWTKeyHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	adminID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	rotation, err := h.KeyRotation.RotateJWTKey(r.Context(), adminID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to rotate JWT signing key")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to rotate signing key", nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), adminID, "jwt_key_rotated", adminID,
+		fmt.Sprintf("Rotated JWT signing key from %s to %s", rotation.PreviousKeyID, rotation.KeyID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rotation)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StepSnippetHandler manages a user's reusable step snippets.
+type StepSnippetHandler struct {
+	Service         *services.StepSnippetService
+	GoalService     *services.GoalService
+	ActivityService *services.ActivityService
+}
+
+// NewStepSnippetHandler initializes a new StepSnippetHandler.
+func NewStepSnippetHandler(service *services.StepSnippetService, goalService *services.GoalService, activityService *services.ActivityService) *StepSnippetHandler {
+	return &StepSnippetHandler{
+		Service:         service,
+		GoalService:     goalService,
+		ActivityService: activityService,
+	}
+}
+
+// CreateSnippetHandler stores a new snippet for the caller.
+func (h *StepSnippetHandler) CreateSnippetHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var snippet models.StepSnippet
+	if err := json.NewDecoder(r.Body).Decode(&snippet); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	snippet.ID = primitive.NilObjectID
+	snippet.UserID = userID
+
+	created, err := h.Service.CreateSnippet(r.Context(), &snippet)
+	if err != nil {
+		requestLogger(r).Warnf("Failed to create snippet: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetSnippetsHandler lists the caller's snippets.
+func (h *StepSnippetHandler) GetSnippetsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	snippets, err := h.Service.GetSnippetsByUser(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).Errorf("Failed to fetch snippets: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch snippets", nil)
+		return
+	}
+	if snippets == nil {
+		snippets = []models.StepSnippet{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snippets)
+}
+
+// GetSnippetHandler returns one of the caller's snippets.
+func (h *StepSnippetHandler) GetSnippetHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	snippet, err := h.Service.GetSnippet(r.Context(), mux.Vars(r)["id"], userID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Snippet not found", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snippet)
+}
+
+// UpdateSnippetHandler replaces the name and steps of a snippet.
+func (h *StepSnippetHandler) UpdateSnippetHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var update models.StepSnippet
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	snippetID := mux.Vars(r)["id"]
+	if _, err := h.Service.GetSnippet(r.Context(), snippetID, userID); err != nil {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Snippet not found", nil)
+		return
+	}
+
+	updated, err := h.Service.UpdateSnippet(r.Context(), snippetID, userID, &update)
+	if err != nil {
+		requestLogger(r).Warnf("Failed to update snippet %s: %v", snippetID, err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// DeleteSnippetHandler removes one of the caller's snippets.
+func (h *StepSnippetHandler) DeleteSnippetHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+	if err := h.Service.DeleteSnippet(r.Context(), mux.Vars(r)["id"], userID); err != nil {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Snippet not found", nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ApplySnippetHandler appends a snippet's steps to a goal.
+// POST /goals/{id}/steps/from-snippet/{snippetId}
+func (h *StepSnippetHandler) ApplySnippetHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID := vars["id"]
+	log := requestLogger(r).WithFields(logrus.Fields{"goalID": goalID, "snippetID": vars["snippetId"]})
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		log.Warn("Unauthorized access")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, _ := primitive.ObjectIDFromHex(claims.UserID)
+
+	goal, err := h.GoalService.GetActiveGoal(r.Context(), goalID)
+	if err != nil || goal == nil {
+		log.Warn("Goal not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	}
+	if goal.UserID != userID && !isCollaborator(goal.Collaborators, claims.UserID, models.CollaboratorRoleEditor) {
+		log.Warn("Forbidden: User is not the owner or an editor")
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only owner or editors can update the goal", nil)
+		return
+	}
+
+	if _, err := h.Service.GetSnippet(r.Context(), vars["snippetId"], userID); err != nil {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Snippet not found", nil)
+		return
+	}
+
+	updatedGoal, snippet, err := h.Service.ApplySnippet(r.Context(), goalID, vars["snippetId"], userID)
+	if err != nil {
+		writeStepEditError(w, log, err)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "goal_snippet_applied", goal.ID, fmt.Sprintf("Added steps from snippet \"%s\" to goal: %s", snippet.Name, goal.Name))
+
+	log.Info("Snippet applied to goal")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGoal)
+}
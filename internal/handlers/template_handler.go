@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
-	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -34,29 +39,29 @@ func NewTemplateHandler(templateService *services.TemplateService, goalService *
 func (h *TemplateHandler) CreateTemplateHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to create a template")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to create a template")
 		return
 	}
 
 	var template models.GoalTemplate
 	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		logger.Log.Warnf("Failed to decode template: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		requestLogger(r).Warnf("Failed to decode template: %v", err)
 		return
 	}
 	defer r.Body.Close()
 
 	if template.Title == "" || len(template.Steps) == 0 {
-		http.Error(w, "Title and steps are required", http.StatusBadRequest)
-		logger.Log.Warn("Missing required template fields")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Title and steps are required", nil)
+		requestLogger(r).Warn("Missing required template fields")
 		return
 	}
 
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to parse user ID: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		requestLogger(r).Errorf("Failed to parse user ID: %v", err)
 		return
 	}
 
@@ -64,43 +69,110 @@ func (h *TemplateHandler) CreateTemplateHandler(w http.ResponseWriter, r *http.R
 	template.CreatedAt = time.Now()
 
 	createdTemplate, err := h.TemplateService.CreateTemplate(r.Context(), &template)
+	if errors.Is(err, services.ErrInvalidCategory) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid category", nil)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidTags) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to create template", http.StatusInternalServerError)
-		logger.Log.Errorf("Error creating template: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to create template", nil)
+		requestLogger(r).Errorf("Error creating template: %v", err)
 		return
 	}
 
 	_ = h.ActivityService.LogActivity(r.Context(), userID, "template_created", createdTemplate.ID, fmt.Sprintf("Created template: %s", createdTemplate.Title))
 
-	logger.Log.Infof("User %s created template %s", claims.UserID, createdTemplate.ID.Hex())
+	requestLogger(r).Infof("User %s created template %s", claims.UserID, createdTemplate.ID.Hex())
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(createdTemplate)
 }
 
+// MakeTemplateHandler turns one of the caller's goals into a template. The
+// body may override the title, description and whether it is public.
+// POST /goals/{id}/make-template {"title": "...", "description": "...", "public": true}
+func (h *TemplateHandler) MakeTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+	goalID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid goal ID", nil)
+		return
+	}
+
+	var overrides services.TemplateOverrides
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil && !errors.Is(err, io.EOF) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	template, err := h.TemplateService.CreateTemplateFromGoal(r.Context(), goalID, userID, overrides)
+	switch {
+	case errors.Is(err, services.ErrTemplateGoalNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Goal not found", nil)
+		return
+	case errors.Is(err, services.ErrTemplateGoalForbidden):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Only the owner can make a template from this goal", nil)
+		return
+	case errors.Is(err, services.ErrTemplateGoalNoSteps), errors.Is(err, services.ErrInvalidCategory):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Error("Failed to make template from goal")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "template_created", template.ID, fmt.Sprintf("Created template from goal: %s", template.Title))
+
+	requestLogger(r).Infof("User %s made template %s from goal %s", claims.UserID, template.ID.Hex(), goalID.Hex())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// AdminGetAllTemplatesHandler lists every template, newest first.
+// GET /admin/templates?limit=20&offset=0
 func (h *TemplateHandler) AdminGetAllTemplatesHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to access all templates")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to access all templates")
 		return
 	}
 
 	if claims.Role != "admin" {
-		http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
-		logger.Log.Warnf("User %s attempted to access admin-only endpoint", claims.UserID)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: Admins only", nil)
+		requestLogger(r).Warnf("User %s attempted to access admin-only endpoint", claims.UserID)
 		return
 	}
 
-	templates, err := h.TemplateService.GetAllTemplates(r.Context())
+	offset, limit, ok := parseOffsetParams(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.TemplateService.GetAllTemplates(r.Context(), offset, limit)
 	if err != nil {
-		http.Error(w, "Failed to fetch templates", http.StatusInternalServerError)
-		logger.Log.Errorf("Admin failed to fetch all templates: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch templates", nil)
+		requestLogger(r).Errorf("Admin failed to fetch all templates: %v", err)
 		return
 	}
 
-	logger.Log.Infof("Admin %s fetched %d templates", claims.UserID, len(templates))
+	requestLogger(r).Infof("Admin %s fetched %d templates", claims.UserID, len(result.Items))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(templates)
+	json.NewEncoder(w).Encode(result)
 }
 
 func (h *TemplateHandler) GetTemplateByIDHandler(w http.ResponseWriter, r *http.Request) {
@@ -109,30 +181,30 @@ func (h *TemplateHandler) GetTemplateByIDHandler(w http.ResponseWriter, r *http.
 
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized access to template by ID")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized access to template by ID")
 		return
 	}
 
 	// Parse ObjectID
 	objID, err := primitive.ObjectIDFromHex(templateID)
 	if err != nil {
-		http.Error(w, "Invalid template ID", http.StatusBadRequest)
-		logger.Log.Warnf("Invalid template ID: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid template ID", nil)
+		requestLogger(r).Warnf("Invalid template ID: %v", err)
 		return
 	}
 
 	template, err := h.TemplateService.GetTemplateByID(r.Context(), objID.Hex())
 	if err != nil {
-		http.Error(w, "Template not found", http.StatusNotFound)
-		logger.Log.Warnf("Template not found: %v", err)
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template not found", nil)
+		requestLogger(r).Warnf("Template not found: %v", err)
 		return
 	}
 
 	// Make sure only the owner can view it (or add sharing logic later)
 	if template.UserID.Hex() != claims.UserID {
-		http.Error(w, "Forbidden: You can only view your own templates", http.StatusForbidden)
-		logger.Log.Warnf("User %s tried to access template %s they do not own", claims.UserID, templateID)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only view your own templates", nil)
+		requestLogger(r).Warnf("User %s tried to access template %s they do not own", claims.UserID, templateID)
 		return
 	}
 
@@ -140,91 +212,414 @@ func (h *TemplateHandler) GetTemplateByIDHandler(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(template)
 }
 
+// CopyTemplateHandler creates a goal for the caller from a template. The
+// optional body overrides the goal's name, description, category, due date and
+// step due dates.
+// POST /templates/{id}/copy {"name": "...", "due_date": "...", "step_due_offsets": ["24h", "72h"]}
 func (h *TemplateHandler) CopyTemplateHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	templateID := vars["id"]
 
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to copy template")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to copy template")
 		return
 	}
 
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to parse user ID: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		requestLogger(r).Errorf("Failed to parse user ID: %v", err)
+		return
+	}
+
+	var overrides services.TemplateCopyOverrides
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil && !errors.Is(err, io.EOF) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
+	defer r.Body.Close()
 
-	goal, err := h.TemplateService.CopyTemplateToGoal(r.Context(), templateID, userID)
+	goal, err := h.TemplateService.CopyTemplateToGoal(r.Context(), templateID, userID, overrides)
+	if errors.Is(err, services.ErrRateLimited) {
+		w.Header().Set("Retry-After", "3600")
+		apierror.WriteError(w, http.StatusTooManyRequests, apierror.CodeTooManyRequests, "Too many template copies, try again later", nil)
+		requestLogger(r).Warnf("User %s hit the template copy limit", claims.UserID)
+		return
+	}
+	if writeStepDueDateError(w, r, err) {
+		return
+	}
+	if errors.Is(err, services.ErrTemplateVersionNotFound) {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template version not found", nil)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidTemplateCopy) || errors.Is(err, services.ErrInvalidCategory) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to copy template: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, err.Error(), nil)
+		requestLogger(r).Errorf("Failed to copy template: %v", err)
 		return
 	}
 
 	_ = h.ActivityService.LogActivity(r.Context(), userID, "template_copied", goal.ID, fmt.Sprintf("Copied template to goal: %s", goal.Name))
 
-	logger.Log.Infof("User %s copied template %s into goal %s", claims.UserID, templateID, goal.ID.Hex())
+	requestLogger(r).Infof("User %s copied template %s into goal %s", claims.UserID, templateID, goal.ID.Hex())
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(goal)
 }
 
 // GetTemplatesHandler allows a user to fetch their own templates.
+// GET /templates?limit=20&offset=0
 func (h *TemplateHandler) GetTemplatesHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to fetch templates")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to fetch templates")
 		return
 	}
 
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to parse user ID: %v", err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		requestLogger(r).Errorf("Failed to parse user ID: %v", err)
+		return
+	}
+
+	offset, limit, ok := parseOffsetParams(w, r)
+	if !ok {
 		return
 	}
 
-	templates, err := h.TemplateService.GetTemplatesByUser(r.Context(), userID)
+	result, err := h.TemplateService.GetTemplatesByUser(r.Context(), userID, offset, limit)
 	if err != nil {
-		http.Error(w, "Failed to fetch templates", http.StatusInternalServerError)
-		logger.Log.Errorf("Error fetching templates for user %s: %v", claims.UserID, err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch templates", nil)
+		requestLogger(r).Errorf("Error fetching templates for user %s: %v", claims.UserID, err)
 		return
 	}
 
-	logger.Log.Infof("Fetched %d templates for user %s", len(templates), claims.UserID)
+	requestLogger(r).Infof("Fetched %d templates for user %s", len(result.Items), claims.UserID)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(templates)
+	json.NewEncoder(w).Encode(result)
 }
 
+// GetPublicTemplatesHandler lists public templates, newest first.
+// GET /templates/public?q=&category=&tag=&limit=20&offset=0
 func (h *TemplateHandler) GetPublicTemplatesHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to fetch public templates")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to fetch public templates")
+		return
+	}
+
+	offset, limit, ok := parseOffsetParams(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.TemplateService.GetPublicTemplates(r.Context(), templateFilter(r), offset, limit)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch public templates", nil)
+		requestLogger(r).Errorf("Error fetching public templates: %v", err)
+		return
+	}
+
+	requestLogger(r).Infof("User %s fetched %d public templates", claims.UserID, len(result.Items))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateTemplateHandler changes a template the caller owns. The previous
+// content stays available under /templates/{id}/versions.
+// PATCH /templates/{id} {"title": "...", "steps": [...], "tags": [...], "public": true}
+func (h *TemplateHandler) UpdateTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	var update services.TemplateUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	template, err := h.TemplateService.UpdateTemplate(r.Context(), templateID, userID, update)
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template not found", nil)
+		return
+	case errors.Is(err, services.ErrTemplateForbidden):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+		return
+	case errors.Is(err, services.ErrTemplateVersionConflict):
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, "Template was updated by someone else, reload and try again", nil)
+		return
+	case errors.Is(err, services.ErrInvalidTemplate), errors.Is(err, services.ErrInvalidTags), errors.Is(err, services.ErrInvalidCategory):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	case err != nil:
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update template", nil)
+		requestLogger(r).Errorf("Error updating template: %v", err)
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "template_updated", template.ID, fmt.Sprintf("Updated template: %s", template.Title))
+
+	requestLogger(r).Infof("User %s updated template %s to version %d", userID.Hex(), template.ID.Hex(), template.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(template)
+}
+
+// GetTemplateVersionsHandler lists every version of a template, newest first.
+// GET /templates/{id}/versions
+func (h *TemplateHandler) GetTemplateVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
 		return
 	}
 
-	templates, err := h.TemplateService.GetPublicTemplates(r.Context())
+	versions, err := h.TemplateService.GetTemplateVersions(r.Context(), templateID, userID)
+	if errors.Is(err, services.ErrTemplateNotFound) {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template not found", nil)
+		return
+	}
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch template versions", nil)
+		requestLogger(r).Errorf("Error fetching template versions: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// GetTemplateVersionHandler returns one version of a template.
+// GET /templates/{id}/versions/{version}
+func (h *TemplateHandler) GetTemplateVersionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
 	if err != nil {
-		http.Error(w, "Failed to fetch public templates", http.StatusInternalServerError)
-		logger.Log.Errorf("Error fetching public templates: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid version", nil)
+		return
+	}
+
+	content, err := h.TemplateService.GetTemplateVersion(r.Context(), templateID, userID, version)
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template not found", nil)
+		return
+	case errors.Is(err, services.ErrTemplateVersionNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template version not found", nil)
+		return
+	case err != nil:
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch template version", nil)
+		requestLogger(r).Errorf("Error fetching template version: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(content)
+}
+
+// GetTemplatePreviewsHandler lists public templates for logged-out visitors,
+// e.g. on the marketing site. Authors are named by username only.
+// GET /public/templates?q=&category=&tag=&limit=20&offset=0
+func (h *TemplateHandler) GetTemplatePreviewsHandler(w http.ResponseWriter, r *http.Request) {
+	offset, limit, ok := parseOffsetParams(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.TemplateService.GetTemplatePreviews(r.Context(), templateFilter(r), offset, limit)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch templates", nil)
+		requestLogger(r).Errorf("Error fetching template previews: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetTemplatePreviewHandler returns one public template for logged-out visitors.
+// GET /public/templates/{id}
+func (h *TemplateHandler) GetTemplatePreviewHandler(w http.ResponseWriter, r *http.Request) {
+	templateID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid template ID", nil)
+		return
+	}
+
+	preview, err := h.TemplateService.GetTemplatePreview(r.Context(), templateID)
+	if errors.Is(err, services.ErrTemplateNotFound) {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template not found", nil)
+		return
+	}
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch template", nil)
+		requestLogger(r).Errorf("Error fetching template preview: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+// templateRequestParams reads the caller and template ID for the template
+// endpoints, writing an error response when either is invalid.
+func templateRequestParams(w http.ResponseWriter, r *http.Request) (userID, templateID primitive.ObjectID, ok bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return userID, templateID, false
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return userID, templateID, false
+	}
+	templateID, err = primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid template ID", nil)
+		return userID, templateID, false
+	}
+	return userID, templateID, true
+}
+
+// BookmarkTemplateHandler saves a template to the caller's bookmarks.
+// POST /templates/{id}/bookmark
+func (h *TemplateHandler) BookmarkTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.TemplateService.BookmarkTemplate(r.Context(), templateID, userID)
+	if errors.Is(err, services.ErrBookmarkTemplateNotFound) {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template not found", nil)
+		return
+	}
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to bookmark template", nil)
+		requestLogger(r).Errorf("Error bookmarking template: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnbookmarkTemplateHandler removes a template from the caller's bookmarks.
+// DELETE /templates/{id}/bookmark
+func (h *TemplateHandler) UnbookmarkTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	userID, templateID, ok := templateRequestParams(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.TemplateService.UnbookmarkTemplate(r.Context(), templateID, userID)
+	if errors.Is(err, services.ErrBookmarkNotFound) {
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Template is not bookmarked", nil)
+		return
+	}
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to remove bookmark", nil)
+		requestLogger(r).Errorf("Error removing template bookmark: %v", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBookmarkedTemplatesHandler lists the templates the caller bookmarked.
+// GET /templates/bookmarked
+func (h *TemplateHandler) GetBookmarkedTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	templates, err := h.TemplateService.GetBookmarkedTemplates(r.Context(), userID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch bookmarked templates", nil)
+		requestLogger(r).Errorf("Error fetching bookmarked templates: %v", err)
 		return
 	}
 
-	logger.Log.Infof("User %s fetched %d public templates", claims.UserID, len(templates))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(templates)
 }
 
+// GetTemplateTagsHandler returns the distinct tags used on public templates.
+// GET /templates/tags
+func (h *TemplateHandler) GetTemplateTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.TemplateService.GetPublicTags(r.Context())
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch template tags", nil)
+		requestLogger(r).Errorf("Error fetching template tags: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// parseOffsetParams reads the optional ?limit= and ?offset= query parameters,
+// writing a 400 response when either is malformed. Zero means the default.
+func parseOffsetParams(w http.ResponseWriter, r *http.Request) (offset, limit int64, ok bool) {
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid limit", nil)
+			return 0, 0, false
+		}
+		limit = parsed
+	}
+	if v := query.Get("offset"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 0 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid offset", nil)
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+	return offset, limit, true
+}
+
+// templateFilter reads the ?q= and ?category= filters for public templates.
+func templateFilter(r *http.Request) repository.TemplateFilter {
+	query := r.URL.Query()
+	return repository.TemplateFilter{
+		Query:    strings.TrimSpace(query.Get("q")),
+		Category: query.Get("category"),
+		Tag:      strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+	}
+}
+
+// GetTemplatesByUserHandler lists a user's templates. With public=true anyone
+// may list them and the public template filters apply; otherwise callers only
+// see their own templates.
+// GET /templates/user/{id}?public=true&q=&category=&tag=&limit=20&offset=0
 func (h *TemplateHandler) GetTemplatesByUserHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized request to get templates by user")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized request to get templates by user")
 		return
 	}
 
@@ -232,33 +627,103 @@ func (h *TemplateHandler) GetTemplatesByUserHandler(w http.ResponseWriter, r *ht
 	requestedUserID := vars["id"]
 	userID, err := primitive.ObjectIDFromHex(requestedUserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		logger.Log.Warnf("Invalid user ID: %v", err)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		requestLogger(r).Warnf("Invalid user ID: %v", err)
 		return
 	}
 
 	// Parse optional ?public=true query param
 	publicOnly := r.URL.Query().Get("public") == "true"
 
-	var templates []models.GoalTemplate
+	offset, limit, ok := parseOffsetParams(w, r)
+	if !ok {
+		return
+	}
 
 	if publicOnly {
-		templates, err = h.TemplateService.GetPublicTemplatesByUser(r.Context(), userID)
-	} else if claims.UserID == requestedUserID {
-		templates, err = h.TemplateService.GetTemplatesByUser(r.Context(), userID)
-	} else {
-		http.Error(w, "Forbidden: You can only view your own private templates", http.StatusForbidden)
-		logger.Log.Warnf("User %s attempted to access private templates of user %s", claims.UserID, requestedUserID)
+		result, err := h.TemplateService.GetPublicTemplatesByUser(r.Context(), userID, templateFilter(r), offset, limit)
+		if err != nil {
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve templates", nil)
+			requestLogger(r).Errorf("Failed to get public templates for user %s: %v", requestedUserID, err)
+			return
+		}
+		requestLogger(r).Infof("User %s fetched %d public templates for user %s", claims.UserID, len(result.Items), requestedUserID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
 		return
 	}
 
+	if claims.UserID != requestedUserID {
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only view your own private templates", nil)
+		requestLogger(r).Warnf("User %s attempted to access private templates of user %s", claims.UserID, requestedUserID)
+		return
+	}
+	result, err := h.TemplateService.GetTemplatesByUser(r.Context(), userID, offset, limit)
 	if err != nil {
-		http.Error(w, "Failed to retrieve templates", http.StatusInternalServerError)
-		logger.Log.Errorf("Failed to get templates for user %s: %v", requestedUserID, err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve templates", nil)
+		requestLogger(r).Errorf("Failed to get templates for user %s: %v", requestedUserID, err)
+		return
+	}
+
+	requestLogger(r).Infof("User %s fetched %d templates for user %s", claims.UserID, len(result.Items), requestedUserID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetTrendingTemplatesHandler lists public templates copied by the most
+// distinct users in the last week.
+// GET /templates/trending?limit=10
+func (h *TemplateHandler) GetTrendingTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	limit := int64(10)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 || parsed > 50 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "limit must be between 1 and 50", nil)
+			return
+		}
+		limit = parsed
+	}
+
+	templates, err := h.TemplateService.GetTrendingTemplates(r.Context(), limit)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch trending templates", nil)
+		requestLogger(r).Errorf("Error fetching trending templates: %v", err)
 		return
 	}
 
-	logger.Log.Infof("User %s fetched %d templates for user %s", claims.UserID, len(templates), requestedUserID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(templates)
 }
+
+// AdminTemplateCopiersHandler lists users with suspiciously many template
+// copies for review.
+// GET /admin/templates/copiers?hours=24&min=100&limit=50
+func (h *TemplateHandler) AdminTemplateCopiersHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	hours, minCopies, limit := int64(24), int64(100), int64(50)
+	for name, target := range map[string]*int64{"hours": &hours, "min": &minCopies, "limit": &limit} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 1 {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, fmt.Sprintf("%s must be a positive integer", name), nil)
+			return
+		}
+		*target = parsed
+	}
+
+	copiers, err := h.TemplateService.GetHeavyCopiers(r.Context(), time.Duration(hours)*time.Hour, minCopies, limit)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch template copiers", nil)
+		requestLogger(r).Errorf("Error fetching template copiers: %v", err)
+		return
+	}
+	if copiers == nil {
+		copiers = []models.TemplateCopier{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(copiers)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestCopyTemplateWiring drives a copy through the handler and the services
+// wired as in cmd/server, checking the activity log and the owner's
+// notification that hang off them.
+func TestCopyTemplateWiring(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	handler := NewTemplateHandler(svc.Template, svc.Goal, services.NewActivityService(svc.Activities))
+	router := mux.NewRouter()
+	router.HandleFunc("/templates/{id}/copy", handler.CopyTemplateHandler).Methods(http.MethodPost)
+
+	owner := testutil.SeedUser(t, svc.Repositories, models.User{})
+	template, err := svc.Templates.CreateTemplate(ctx, &models.GoalTemplate{
+		Title:  "Couch to 5k",
+		UserID: owner.ID,
+		Public: true,
+		Steps:  []models.TemplateStep{{Name: "Week 1", Substeps: []models.TemplateSubstep{{Title: "Run 1k"}}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+
+	copyAs := func(userID primitive.ObjectID) *models.Goal {
+		t.Helper()
+		req := asUser(httptest.NewRequest(http.MethodPost, "/templates/"+template.ID.Hex()+"/copy", nil), userID)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("copy status = %d: %s", rec.Code, rec.Body)
+		}
+		var goal models.Goal
+		if err := json.NewDecoder(rec.Body).Decode(&goal); err != nil {
+			t.Fatalf("failed to decode goal: %v", err)
+		}
+		return &goal
+	}
+	countDocs := func(collection string, filter bson.M) int64 {
+		t.Helper()
+		n, err := svc.DB.Collection(collection).CountDocuments(ctx, filter)
+		if err != nil {
+			t.Fatalf("failed to count %s: %v", collection, err)
+		}
+		return n
+	}
+
+	first := testutil.SeedUser(t, svc.Repositories, models.User{})
+	goal := copyAs(first.ID)
+	if goal.UserID != first.ID || goal.Name != template.Title {
+		t.Errorf("copied goal = %+v, want %q owned by the copier", goal, template.Title)
+	}
+	if n := countDocs("activities", bson.M{"user_id": first.ID, "type": "template_copied", "target_id": goal.ID}); n != 1 {
+		t.Errorf("%d template_copied activities for the new goal, want 1", n)
+	}
+
+	// A second copier the same day doesn't notify the owner again, and the
+	// owner's own copy never does
+	copyAs(testutil.SeedUser(t, svc.Repositories, models.User{}).ID)
+	copyAs(owner.ID)
+
+	notified := bson.M{"user_id": owner.ID, "type": "template_copied", "target_id": template.ID}
+	if n := countDocs("notifications", notified); n != 1 {
+		t.Errorf("owner got %d template_copied notifications, want 1", n)
+	}
+	counters, err := svc.Counters.Get(ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("failed to load counters: %v", err)
+	}
+	if counters.UnreadNotifications != 1 {
+		t.Errorf("owner's unread count = %d, want 1", counters.UnreadNotifications)
+	}
+}
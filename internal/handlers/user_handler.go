@@ -1,51 +1,70 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/config"
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
 	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
-	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // UserHandler handles HTTP requests related to user operations.
 type UserHandler struct {
-	Service *services.UserService
-	Config  *config.Config
+	Service       *services.UserService
+	FriendService *services.FriendService
+	Config        *config.Config
 }
 
 // NewUserHandler creates a new instance of UserHandler.
-func NewUserHandler(service *services.UserService, cfg *config.Config) *UserHandler {
+func NewUserHandler(service *services.UserService, friendService *services.FriendService, cfg *config.Config) *UserHandler {
 	return &UserHandler{
-		Service: service,
-		Config:  cfg,
+		Service:       service,
+		FriendService: friendService,
+		Config:        cfg,
 	}
 }
 
-// RegisterUserHandler handles user registration.
+// RegisterUserHandler handles user registration. An invite_token from a
+// friend invitation email makes the new user friends with the inviter.
 func (h *UserHandler) RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
-	log.Info("RegisterUserHandler called")
-	var user models.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		log.WithError(err).Warn("Failed to decode user registration request")
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	requestLogger(r).Info("RegisterUserHandler called")
+	var req struct {
+		models.User
+		InviteToken string `json:"invite_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		requestLogger(r).WithError(err).Warn("Failed to decode user registration request")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 
-	createdUser, err := h.Service.RegisterUser(r.Context(), &user)
+	createdUser, err := h.Service.RegisterUser(r.Context(), &req.User)
 	if err != nil {
-		log.WithError(err).Error("Failed to register user")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		requestLogger(r).WithError(err).Error("Failed to register user")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, err.Error(), nil)
 		return
 	}
 
-	log.WithField("userID", createdUser.ID.Hex()).Info("User registered successfully")
+	// The account exists at this point, so a bad token only costs the friendship
+	if req.InviteToken != "" {
+		if err := h.FriendService.AcceptInvitation(r.Context(), req.InviteToken, createdUser.ID); err != nil {
+			requestLogger(r).WithError(err).Warn("Failed to accept friend invitation on registration")
+		}
+	}
+
+	requestLogger(r).WithField("userID", createdUser.ID.Hex()).Info("User registered successfully")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(createdUser)
 }
@@ -54,14 +73,14 @@ func (h *UserHandler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request)
 	// Get token from query params
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		http.Error(w, "Missing verification token", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Missing verification token", nil)
 		return
 	}
 
 	// Call service layer to handle verification
 	err := h.Service.VerifyEmail(r.Context(), token)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
 		return
 	}
 
@@ -69,20 +88,76 @@ func (h *UserHandler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request)
 	w.Write([]byte("Email verified successfully!"))
 }
 
+// ChangeEmailHandler starts an email change by sending a confirmation link
+// to the new address.
+// POST /users/{id}/change-email {"new_email": "..."}
+func (h *UserHandler) ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		NewEmail string `json:"new_email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.NewEmail == "" {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	err := h.Service.RequestEmailChange(r.Context(), userID, req.NewEmail)
+	switch {
+	case errors.Is(err, services.ErrEmailTaken):
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Warn("Failed to request email change")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("A confirmation link has been sent to the new email address."))
+}
+
+// VerifyEmailChangeHandler completes an email change from the emailed link.
+// GET /users/verify-email-change?token=...
+func (h *UserHandler) VerifyEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Missing verification token", nil)
+		return
+	}
+
+	_, err := h.Service.ConfirmEmailChange(r.Context(), token)
+	switch {
+	case errors.Is(err, services.ErrEmailTaken):
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		return
+	case err != nil:
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Email changed successfully!"))
+}
+
 // RequestPasswordResetHandler handles sending a password reset email.
 func (h *UserHandler) RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Email string `json:"email"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 	defer r.Body.Close()
 
 	err := h.Service.RequestPasswordReset(r.Context(), req.Email)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
 		return
 	}
 
@@ -92,12 +167,12 @@ func (h *UserHandler) RequestPasswordResetHandler(w http.ResponseWriter, r *http
 
 // ResetPasswordHandler handles the actual password reset using token.
 func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
-	log.Info("ResetPasswordHandler called")
+	requestLogger(r).Info("ResetPasswordHandler called")
 
 	// Extract token from the query parameter
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		http.Error(w, "Missing reset token", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Missing reset token", nil)
 		return
 	}
 
@@ -106,26 +181,26 @@ func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Reques
 		NewPassword string `json:"new_password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.WithError(err).Warn("Invalid reset password request payload")
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		requestLogger(r).WithError(err).Warn("Invalid reset password request payload")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 	defer r.Body.Close()
 
 	if req.NewPassword == "" {
-		http.Error(w, "New password is required", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "New password is required", nil)
 		return
 	}
 
 	// Call service to reset password
 	err := h.Service.ResetPassword(r.Context(), token, req.NewPassword)
 	if err != nil {
-		log.WithError(err).Error("Failed to reset password")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		requestLogger(r).WithError(err).Error("Failed to reset password")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
 		return
 	}
 
-	log.Info("Password reset successful")
+	requestLogger(r).Info("Password reset successful")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Password has been reset successfully"))
 }
@@ -133,155 +208,803 @@ func (h *UserHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Reques
 // LoginUserHandler handles user login.
 func (h *UserHandler) LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Define a simple struct to receive login credentials.
-	log.Info("LoginUserHandler called")
+	requestLogger(r).Info("LoginUserHandler called")
 	var credentials struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
-		log.WithError(err).Warn("Failed to decode login request")
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		requestLogger(r).WithError(err).Warn("Failed to decode login request")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 
 	user, err := h.Service.AuthenticateUser(r.Context(), credentials.Email, credentials.Password)
+	if errors.Is(err, services.ErrTOTPRequired) {
+		// Password was right; hand out a short-lived token for the TOTP step
+		totpToken, err := jwtutil.GeneratePurposeToken(user.ID.Hex(), jwtutil.PurposeTOTPPending, h.Config.JWTKeys, totpLoginWindow)
+		if err != nil {
+			requestLogger(r).WithError(err).Error("Failed to generate TOTP login token")
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to generate token", nil)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"totp_required": true,
+			"totp_token":    totpToken,
+		})
+		return
+	}
 	if err != nil {
-		log.WithFields(log.Fields{
+		requestLogger(r).WithFields(log.Fields{
 			"email": credentials.Email,
 			"error": err,
 		}).Warn("Authentication failed")
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, err.Error(), nil)
 		return
 	}
 
+	h.writeLoginResponse(w, r, user)
+}
+
+// writeLoginResponse issues a short-lived access token and a refresh token
+// and returns them with the user.
+func (h *UserHandler) writeLoginResponse(w http.ResponseWriter, r *http.Request, user *models.User) {
 	// Generate a JWT token
-	token, err := jwtutil.GenerateToken(user.ID.Hex(), user.Email, user.Role, h.Config.JWTSecret, h.Config.TokenExpiry)
+	token, err := jwtutil.GenerateToken(user.ID.Hex(), user.Email, user.Role, h.Config.JWTKeys, h.Config.TokenExpiry)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to generate JWT token")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to generate token", nil)
+		return
+	}
+
+	refreshToken, err := issueRefreshToken(r.Context(), h.Service, h.Config, user.ID)
 	if err != nil {
-		log.WithError(err).Error("Failed to generate JWT token")
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		requestLogger(r).WithError(err).Error("Failed to issue refresh token")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to generate token", nil)
 		return
 	}
 
-	log.WithField("userID", user.ID.Hex()).Info("User logged in successfully")
+	requestLogger(r).WithField("userID", user.ID.Hex()).Info("User logged in successfully")
 
-	// Return the token and user details
+	// Return the tokens and user details. "token" is kept for older clients.
 	response := map[string]interface{}{
-		"token": token,
-		"user":  user,
+		"token":         token,
+		"access_token":  token,
+		"refresh_token": refreshToken,
+		"user":          user,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// issueRefreshToken signs a refresh token for the user and stores it so it
+// can be revoked on logout.
+func issueRefreshToken(ctx context.Context, service *services.UserService, cfg *config.Config, userID primitive.ObjectID) (string, error) {
+	token, err := jwtutil.GenerateRefreshToken(userID.Hex(), cfg.JWTKeys, cfg.RefreshTokenExpiry)
+	if err != nil {
+		return "", err
+	}
+	if err := service.SaveRefreshToken(ctx, userID, token, time.Now().Add(cfg.RefreshTokenExpiry)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RefreshTokenHandler exchanges a live refresh token for a new access token.
+// POST /users/token/refresh {"refresh_token": "..."}
+func (h *UserHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	// Refresh tokens outlive the grace period of a rotated-out signing key.
+	// One signed with a retired key is still accepted if it is on record,
+	// since only tokens the server issued are stored.
+	if _, err := jwtutil.ValidateRefreshToken(req.RefreshToken, h.Config.JWTKeys); err != nil && !errors.Is(err, jwtutil.ErrUnknownKey) {
+		requestLogger(r).WithError(err).Warn("Invalid refresh token")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, services.ErrInvalidRefreshToken.Error(), nil)
+		return
+	}
+
+	user, err := h.Service.CheckRefreshToken(r.Context(), req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to check refresh token")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to refresh token", nil)
+		return
+	}
+
+	token, err := jwtutil.GenerateToken(user.ID.Hex(), user.Email, user.Role, h.Config.JWTKeys, h.Config.TokenExpiry)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to generate JWT token")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to generate token", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": token,
+		"expires_in":   int64(h.Config.TokenExpiry.Seconds()),
+	})
+}
+
+// LogoutHandler ends a session. The access token from the Authorization
+// header is blacklisted until it expires, and the refresh token in the body,
+// if any, is revoked. At least one of the two is required.
+// POST /users/logout {"refresh_token": "..."}
+func (h *UserHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	accessToken, hasAccessToken := middleware.BearerToken(r)
+	if !hasAccessToken && req.RefreshToken == "" {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Missing access or refresh token", nil)
+		return
+	}
+
+	if hasAccessToken {
+		claims, err := jwtutil.ValidateToken(accessToken, h.Config.JWTKeys)
+		if err != nil {
+			apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid token", nil)
+			return
+		}
+
+		err = h.Service.Logout(r.Context(), claims.ID, claims.ExpiresAt.Time)
+		if errors.Is(err, repository.ErrBlacklistUnavailable) {
+			requestLogger(r).Warn("Token blacklist not configured; access token stays valid until it expires")
+		} else if err != nil {
+			// Fail open like AuthMiddleware: the refresh token can still be revoked
+			requestLogger(r).WithError(err).Error("Failed to blacklist access token")
+		}
+	}
+
+	if req.RefreshToken != "" {
+		err := h.Service.RevokeRefreshToken(r.Context(), req.RefreshToken)
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, err.Error(), nil)
+			return
+		}
+		if err != nil {
+			requestLogger(r).WithError(err).Error("Failed to revoke refresh token")
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to log out", nil)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// totpLoginWindow is how long the user has to enter their TOTP code after
+// the password step of a login.
+const totpLoginWindow = 5 * time.Minute
+
+// LoginTOTPHandler completes a 2FA login with the token from LoginUserHandler
+// and a code from the user's authenticator app.
+func (h *UserHandler) LoginTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"totp_token"`
+		Code  string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	claims, err := jwtutil.ValidatePurposeToken(req.Token, jwtutil.PurposeTOTPPending, h.Config.JWTKeys)
+	if err != nil {
+		requestLogger(r).WithError(err).Warn("Invalid TOTP login token")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid or expired login token", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid or expired login token", nil)
+		return
+	}
+
+	user, err := h.Service.VerifyTOTPLogin(r.Context(), userID, req.Code)
+	if err != nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, err.Error(), nil)
+		return
+	}
+
+	h.writeLoginResponse(w, r, user)
+}
+
+// selfUserID returns the caller's ID when it matches the {id} path variable,
+// writing a 401/403 response otherwise.
+func selfUserID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return primitive.NilObjectID, false
+	}
+	if mux.Vars(r)["id"] != claims.UserID {
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only manage your own account", nil)
+		return primitive.NilObjectID, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+// SetupTOTPHandler starts enabling 2FA and returns the secret and a QR code.
+func (h *UserHandler) SetupTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	setup, err := h.Service.SetupTOTP(r.Context(), userID)
+	if errors.Is(err, services.ErrTOTPAlreadyEnabled) {
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to set up two-factor authentication")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to set up two-factor authentication", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setup)
+}
+
+// ConfirmTOTPHandler enables 2FA after checking a code from the authenticator app.
+func (h *UserHandler) ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.Service.ConfirmTOTP(r.Context(), userID, req.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrTOTPAlreadyEnabled):
+			apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		case errors.Is(err, services.ErrTOTPNotSetUp), errors.Is(err, services.ErrInvalidTOTPCode):
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		default:
+			requestLogger(r).WithError(err).Error("Failed to confirm two-factor authentication")
+			apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to enable two-factor authentication", nil)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication enabled",
+	})
+}
+
+// DisableTOTPHandler turns 2FA off after confirming the user's password.
+func (h *UserHandler) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.Service.DisableTOTP(r.Context(), userID, req.Password); err != nil {
+		requestLogger(r).WithError(err).Warn("Failed to disable two-factor authentication")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
 // GetUserHandler handles fetching a user by ID.
 func (h *UserHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
-	log.Info("GetUserHandler called")
+	requestLogger(r).Info("GetUserHandler called")
 	vars := mux.Vars(r)
 	requestedUserID := vars["id"]
 
 	// Get the logged-in user from the request context
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		log.Warn("Unauthorized access attempt to GetUserHandler")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		requestLogger(r).Warn("Unauthorized access attempt to GetUserHandler")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Ensure that the requested user ID matches the logged-in user’s ID
 	if requestedUserID != claims.UserID {
-		log.WithFields(log.Fields{
+		requestLogger(r).WithFields(log.Fields{
 			"requestedUserID": requestedUserID,
 			"loggedInUserID":  claims.UserID,
 		}).Warn("Forbidden access attempt")
-		http.Error(w, "Forbidden: You can only access your own profile", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only access your own profile", nil)
 		return
 	}
 
 	// Fetch the user from the database
 	user, err := h.Service.GetUser(r.Context(), requestedUserID)
 	if err != nil {
-		log.WithField("userID", requestedUserID).WithError(err).Warn("User not found")
-		http.Error(w, "User not found", http.StatusNotFound)
+		requestLogger(r).WithField("userID", requestedUserID).WithError(err).Warn("User not found")
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "User not found", nil)
 		return
 	}
 
-	log.WithField("userID", user.ID.Hex()).Info("User profile fetched")
+	requestLogger(r).WithField("userID", user.ID.Hex()).Info("User profile fetched")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
 }
 
 // UpdateUserHandler handles updating a user profile.
 func (h *UserHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
-	log.Info("UpdateUserHandler called")
+	requestLogger(r).Info("UpdateUserHandler called")
 	vars := mux.Vars(r)
 	requestedUserID := vars["id"]
 
 	// Get logged-in user
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		log.Warn("Unauthorized access attempt to UpdateUserHandler")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		requestLogger(r).Warn("Unauthorized access attempt to UpdateUserHandler")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Ensure only the logged-in user can update their own profile
 	if requestedUserID != claims.UserID {
-		log.WithFields(log.Fields{
+		requestLogger(r).WithFields(log.Fields{
 			"requestedUserID": requestedUserID,
 			"loggedInUserID":  claims.UserID,
 		}).Warn("Forbidden update attempt")
-		http.Error(w, "Forbidden: You can only update your own profile", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only update your own profile", nil)
 		return
 	}
 
 	// Decode request body as a partial update (map)
 	var updatedUser map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updatedUser); err != nil {
-		log.WithError(err).Warn("Failed to decode update request")
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		requestLogger(r).WithError(err).Warn("Failed to decode update request")
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 	defer r.Body.Close()
 
 	// Strip disallowed fields
-	protected := []string{"email", "hashed_password", "role", "is_verified", "verify_token", "_id", "created_at"}
+	protected := []string{"email", "hashed_password", "role", "is_verified", "verify_token", "_id", "created_at",
+		"verified_at", "welcome_email_sent", "getting_started_sent", "totp_secret", "totp_enabled", "auth_provider", "provider_id",
+		"pending_email", "pending_email_token", "pending_email_token_exp", "deletion_token", "deletion_scheduled_for",
+		"xp", "level", "login_streak", "last_login_date", "streak_freeze_count", "schema_version", "calendar_token"}
 	for _, field := range protected {
 		delete(updatedUser, field)
 	}
 
 	// Update user in DB
 	updatedUserData, err := h.Service.UpdateUser(r.Context(), requestedUserID, updatedUser)
+	if errors.Is(err, services.ErrInvalidProfileVisibility) {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	}
 	if err != nil {
-		log.WithFields(log.Fields{
+		requestLogger(r).WithFields(log.Fields{
 			"userID": requestedUserID,
 			"error":  err,
 		}).Error("Failed to update user")
-		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update user", nil)
 		return
 	}
 
-	log.WithField("userID", updatedUserData.ID.Hex()).Info("User updated successfully")
+	requestLogger(r).WithField("userID", updatedUserData.ID.Hex()).Info("User updated successfully")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedUserData)
 }
 
+// GetProfileHandler returns a user's public profile. Signing in is only
+// needed for profiles visible to friends.
+// GET /users/{id}/profile
+func (h *UserHandler) GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	targetID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	var callerID *primitive.ObjectID
+	if claims := middleware.GetUserFromContext(r.Context()); claims != nil {
+		if id, err := primitive.ObjectIDFromHex(claims.UserID); err == nil {
+			callerID = &id
+		}
+	}
+
+	profile, err := h.Service.GetPublicProfile(r.Context(), targetID, callerID)
+	switch {
+	case errors.Is(err, services.ErrProfileNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "User not found", nil)
+		return
+	case errors.Is(err, services.ErrProfileNotVisible):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Error("Failed to load profile")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to load profile", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// BuyStreakFreezeHandler spends XP on a streak freeze that keeps the login
+// streak alive after a missed day.
+// POST /users/{id}/streak-freeze
+func (h *UserHandler) BuyStreakFreezeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.Service.BuyStreakFreeze(r.Context(), userID)
+	if errors.Is(err, services.ErrStreakFreezeUnavailable) {
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to buy streak freeze")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to buy streak freeze", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"streak_freeze_count": user.StreakFreezeCount,
+		"login_streak":        user.LoginStreak,
+		"xp":                  user.XP,
+		"level":               user.Level,
+		"cost":                services.StreakFreezeCostXP,
+	})
+}
+
+// GetCompletionStreakHandler returns the caller's current and longest streak
+// of days with at least one completed substep or goal.
+// GET /users/me/streak
+func (h *UserHandler) GetCompletionStreakHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	streak, err := h.Service.GetCompletionStreak(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to get completion streak")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get streak", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(streak)
+}
+
+// CreateCalendarTokenHandler creates the secret URL calendar apps can
+// subscribe to. Creating a new one invalidates the previous URL.
+// POST /users/me/calendar-token
+func (h *UserHandler) CreateCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	token, err := h.Service.CreateCalendarToken(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to create calendar token")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to create calendar link", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+		"url":   "/goals/calendar/" + token + ".ics",
+	})
+}
+
+// RevokeCalendarTokenHandler disables the caller's calendar feed URL.
+// DELETE /users/me/calendar-token
+func (h *UserHandler) RevokeCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	if err := h.Service.RevokeCalendarToken(r.Context(), userID); err != nil {
+		requestLogger(r).WithError(err).Error("Failed to revoke calendar token")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to revoke calendar link", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// blockIDs returns the caller's ID and the {id} path variable, writing a
+// 400/401 response when either is missing or invalid.
+func blockIDs(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, primitive.ObjectID, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	callerID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	targetID, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	return callerID, targetID, true
+}
+
+// BlockUserHandler blocks a user from sending the caller friend requests and
+// removes them from the caller's friends.
+// POST /users/{id}/block
+func (h *UserHandler) BlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	callerID, targetID, ok := blockIDs(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Service.BlockUser(r.Context(), callerID, targetID)
+	switch {
+	case errors.Is(err, services.ErrCannotBlockSelf):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	case errors.Is(err, services.ErrBlockTargetNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "User not found", nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Error("Failed to block user")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to block user", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnblockUserHandler removes a user from the caller's blocked list.
+// DELETE /users/{id}/block
+func (h *UserHandler) UnblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	callerID, targetID, ok := blockIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.UnblockUser(r.Context(), callerID, targetID); err != nil {
+		requestLogger(r).WithError(err).Error("Failed to unblock user")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to unblock user", nil)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBlockedUsersHandler lists the users the caller has blocked.
+// GET /users/blocked
+func (h *UserHandler) GetBlockedUsersHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	users, err := h.Service.GetBlockedUsers(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to get blocked users")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get blocked users", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+// GetStatsHandler returns goal statistics and activity streaks for a user.
+// Only the user themselves or an admin may read them.
+// GET /users/{id}/stats
+func (h *UserHandler) GetStatsHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	targetID := mux.Vars(r)["id"]
+	if targetID != claims.UserID && claims.Role != "admin" {
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only view your own stats", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid user ID", nil)
+		return
+	}
+
+	stats, err := h.Service.GetStats(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to get user stats")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get stats", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetPreferencesHandler returns the caller's preferences, or the defaults if
+// none were saved.
+// GET /users/{id}/preferences
+func (h *UserHandler) GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.Service.GetPreferences(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to get preferences")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to get preferences", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// SavePreferencesHandler upserts the caller's preferences. Fields missing
+// from the body keep their current values.
+// POST/PUT /users/{id}/preferences
+func (h *UserHandler) SavePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := selfUserID(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.Service.GetPreferences(r.Context(), userID)
+	if err != nil {
+		requestLogger(r).WithError(err).Error("Failed to get preferences")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to save preferences", nil)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(prefs); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+	prefs.UserID = userID
+
+	err = h.Service.SavePreferences(r.Context(), prefs)
+	switch {
+	case errors.Is(err, services.ErrInvalidPreferences):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Error("Failed to save preferences")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to save preferences", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// ResolveUsersHandler maps up to 100 user IDs to public profiles.
+// POST /users/resolve {"ids": ["...", "..."]}
+func (h *UserHandler) ResolveUsersHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	resolved, err := h.Service.ResolveUsers(r.Context(), claims.UserID, req.IDs)
+	switch {
+	case errors.Is(err, services.ErrTooManyResolveIDs):
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, err.Error(), nil)
+		return
+	case errors.Is(err, services.ErrRateLimited):
+		w.Header().Set("Retry-After", "60")
+		apierror.WriteError(w, http.StatusTooManyRequests, apierror.CodeTooManyRequests, err.Error(), nil)
+		return
+	case err != nil:
+		requestLogger(r).WithError(err).Error("Failed to resolve users")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to resolve users", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resolved)
+}
+
 func (h *UserHandler) GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 	// Auth check
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		logger.Log.Warn("Unauthorized attempt to fetch all users")
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		requestLogger(r).Warn("Unauthorized attempt to fetch all users")
 		return
 	}
 
 	users, err := h.Service.GetAllUsers(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to retrieve users", http.StatusInternalServerError)
-		logger.Log.Errorf("Admin %s failed to fetch users: %v", claims.UserID, err)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to retrieve users", nil)
+		requestLogger(r).Errorf("Admin %s failed to fetch users: %v", claims.UserID, err)
 		return
 	}
 
-	logger.Log.Infof("Admin %s fetched %d users", claims.UserID, len(users))
+	requestLogger(r).Infof("Admin %s fetched %d users", claims.UserID, len(users))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(users)
 }
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/config"
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
+)
+
+func TestRefreshTokenAfterKeyRotation(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	keys := jwtutil.NewKeyRing("env-secret")
+	handler := NewUserHandler(svc.User, svc.Friend, &config.Config{JWTKeys: keys, TokenExpiry: 15 * time.Minute})
+	user := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	issue := func(store bool) string {
+		t.Helper()
+		token, err := jwtutil.GenerateRefreshToken(user.ID.Hex(), keys, 30*24*time.Hour)
+		if err != nil {
+			t.Fatalf("GenerateRefreshToken: %v", err)
+		}
+		if store {
+			if err := svc.User.SaveRefreshToken(ctx, user.ID, token, time.Now().Add(30*24*time.Hour)); err != nil {
+				t.Fatalf("SaveRefreshToken: %v", err)
+			}
+		}
+		return token
+	}
+	stored := issue(true)
+	unknown := issue(false)
+
+	// The env key's grace period is already over when the refresh happens
+	keys.Rotate(jwtutil.SigningKey{ID: "k2", Secret: []byte("second-secret")}, time.Now().Add(-time.Second))
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users/token/refresh", strings.NewReader(`{"refresh_token":"`+token+`"}`))
+		rec := httptest.NewRecorder()
+		handler.RefreshTokenHandler(rec, req)
+		return rec
+	}
+
+	rec := refresh(stored)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stored token: status = %d, body %s, want 200", rec.Code, rec.Body)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if claims, err := jwtutil.ValidateToken(body.AccessToken, keys); err != nil || claims.UserID != user.ID.Hex() {
+		t.Errorf("new access token: %+v, %v", claims, err)
+	}
+
+	// A token signed with the retired key is only as good as its record
+	if rec := refresh(unknown); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unrecorded token: status = %d, want 401", rec.Code)
+	}
+	if err := svc.User.RevokeRefreshToken(ctx, stored); err != nil {
+		t.Fatalf("RevokeRefreshToken: %v", err)
+	}
+	if rec := refresh(stored); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d, want 401", rec.Code)
+	}
+}
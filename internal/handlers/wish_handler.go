@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,7 +11,9 @@ import (
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -36,20 +39,20 @@ func NewWishHandler(service *services.WishService, goalService *services.GoalSer
 func (h *WishHandler) CreateWishHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	var wish models.Wish
 	if err := json.NewDecoder(r.Body).Decode(&wish); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
 		return
 	}
 	defer r.Body.Close()
 
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
 		return
 	}
 	wish.UserID = userID
@@ -58,7 +61,7 @@ func (h *WishHandler) CreateWishHandler(w http.ResponseWriter, r *http.Request)
 
 	createdWish, err := h.Service.CreateWish(r.Context(), &wish)
 	if err != nil {
-		http.Error(w, "Failed to create wish", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to create wish", nil)
 		return
 	}
 
@@ -72,7 +75,7 @@ func (h *WishHandler) CreateWishHandler(w http.ResponseWriter, r *http.Request)
 func (h *WishHandler) GetWishByIDHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
@@ -80,12 +83,12 @@ func (h *WishHandler) GetWishByIDHandler(w http.ResponseWriter, r *http.Request)
 
 	wish, err := h.Service.GetWishByID(r.Context(), wishID)
 	if err != nil {
-		http.Error(w, "Wish not found", http.StatusNotFound)
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Wish not found", nil)
 		return
 	}
 
 	if wish.UserID.Hex() != claims.UserID {
-		http.Error(w, "Forbidden, not owner of Wish", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden, not owner of Wish", nil)
 		return
 	}
 
@@ -97,13 +100,13 @@ func (h *WishHandler) GetWishByIDHandler(w http.ResponseWriter, r *http.Request)
 func (h *WishHandler) GetWishesHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
 		return
 	}
 
@@ -111,13 +114,13 @@ func (h *WishHandler) GetWishesHandler(w http.ResponseWriter, r *http.Request) {
 
 	wish, err := h.Service.GetWishByID(r.Context(), wishID)
 	if err != nil || wish.UserID.Hex() != claims.UserID {
-		http.Error(w, "Forbidden or not found", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden or not found", nil)
 		return
 	}
 
 	wishes, err := h.Service.GetWishesByUser(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Failed to fetch wishes", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch wishes", nil)
 		return
 	}
 
@@ -125,11 +128,38 @@ func (h *WishHandler) GetWishesHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(wishes)
 }
 
+// GetStaleWishesHandler returns the user's old wishes that were never promoted to a goal
+func (h *WishHandler) GetStaleWishesHandler(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	wishes, err := h.Service.GetStaleWishes(r.Context(), userID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to fetch stale wishes", nil)
+		return
+	}
+	if wishes == nil {
+		wishes = []models.Wish{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(wishes)
+}
+
 // UpdateWishHandler updates a wish
 func (h *WishHandler) UpdateWishHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
@@ -137,19 +167,19 @@ func (h *WishHandler) UpdateWishHandler(w http.ResponseWriter, r *http.Request)
 
 	wish, err := h.Service.GetWishByID(r.Context(), wishID)
 	if err != nil || wish.UserID.Hex() != claims.UserID {
-		http.Error(w, "Forbidden or not found", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden or not found", nil)
 		return
 	}
 
 	var updates map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, "Invalid update payload", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid update payload", nil)
 		return
 	}
 	defer r.Body.Close()
 
 	if err := h.Service.UpdateWish(r.Context(), wishID, updates); err != nil {
-		http.Error(w, "Failed to update wish", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update wish", nil)
 		return
 	}
 
@@ -163,19 +193,19 @@ func (h *WishHandler) UpdateWishHandler(w http.ResponseWriter, r *http.Request)
 func (h *WishHandler) DeleteWishHandler(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	wishID := mux.Vars(r)["id"]
 	wish, err := h.Service.GetWishByID(r.Context(), wishID)
 	if err != nil || wish.UserID.Hex() != claims.UserID {
-		http.Error(w, "Forbidden or not found", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden or not found", nil)
 		return
 	}
 
 	if err := h.Service.DeleteWish(r.Context(), wishID); err != nil {
-		http.Error(w, "Failed to delete wish", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to delete wish", nil)
 		return
 	}
 
@@ -192,26 +222,26 @@ func (h *WishHandler) PromoteWishHandler(w http.ResponseWriter, r *http.Request)
 
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Convert user ID
 	userID, err := primitive.ObjectIDFromHex(claims.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
 		return
 	}
 
 	// Get the wish by ID
 	wish, err := h.Service.GetWishByID(r.Context(), wishID)
 	if err != nil {
-		http.Error(w, "Wish not found", http.StatusNotFound)
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, "Wish not found", nil)
 		return
 	}
 
 	if wish.UserID != userID {
-		http.Error(w, "Forbidden: You can only promote your own wish", http.StatusForbidden)
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: You can only promote your own wish", nil)
 		return
 	}
 
@@ -228,10 +258,14 @@ func (h *WishHandler) PromoteWishHandler(w http.ResponseWriter, r *http.Request)
 	// Optionally carry over any substeps if your wish had them (not implemented yet)
 	createdGoal, err := h.GoalService.CreateGoal(r.Context(), goal)
 	if err != nil {
-		http.Error(w, "Failed to promote wish to goal", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to promote wish to goal", nil)
 		return
 	}
 
+	if err := h.Service.MarkPromoted(r.Context(), wish.ID, createdGoal.ID); err != nil {
+		requestLogger(r).WithError(err).Warn("Failed to mark wish as promoted")
+	}
+
 	_ = h.ActivityService.LogActivity(r.Context(), userID, "wish_promoted", wish.ID, fmt.Sprintf("Promoted wish to goal: %s", wish.Title))
 
 	// Respond with the created goal
@@ -239,6 +273,137 @@ func (h *WishHandler) PromoteWishHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(createdGoal)
 }
 
+// DuplicateWishHandler copies one of the caller's wishes.
+// POST /wishes/{id}/duplicate, optional body {"include_images": true}
+func (h *WishHandler) DuplicateWishHandler(w http.ResponseWriter, r *http.Request) {
+	wishID := mux.Vars(r)["id"]
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	var body struct {
+		IncludeImages bool `json:"include_images"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+			return
+		}
+		defer r.Body.Close()
+	}
+
+	copied, err := h.Service.DuplicateWish(r.Context(), wishID, userID, body.IncludeImages)
+	if err != nil {
+		writeWishError(w, r, err, "Failed to duplicate wish")
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "wish_duplicated", copied.ID, fmt.Sprintf("Duplicated wish: %s", copied.Title))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(copied)
+}
+
+// SuggestWishHandler sends a snapshot of one of the caller's wishes to a friend.
+// POST /wishes/{id}/suggest {"friend_id": "..."}
+func (h *WishHandler) SuggestWishHandler(w http.ResponseWriter, r *http.Request) {
+	wishID := mux.Vars(r)["id"]
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	var body struct {
+		FriendID string `json:"friend_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid request payload", nil)
+		return
+	}
+	defer r.Body.Close()
+
+	friendID, err := primitive.ObjectIDFromHex(body.FriendID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Invalid friend ID", nil)
+		return
+	}
+
+	suggestion, err := h.Service.SuggestWish(r.Context(), wishID, userID, friendID)
+	if err != nil {
+		writeWishError(w, r, err, "Failed to suggest wish")
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "wish_suggested", suggestion.WishID, fmt.Sprintf("Suggested wish to a friend: %s", suggestion.Title))
+
+	// The token is only meant for the recipient
+	suggestion.Token = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(suggestion)
+}
+
+// AcceptWishSuggestionHandler adds a wish suggested by a friend to the
+// caller's wishlist.
+// POST /wishes/suggestions/{token}/accept
+func (h *WishHandler) AcceptWishSuggestionHandler(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if claims == nil {
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Invalid user ID", nil)
+		return
+	}
+
+	wish, err := h.Service.AcceptWishSuggestion(r.Context(), token, userID)
+	if err != nil {
+		writeWishError(w, r, err, "Failed to accept wish suggestion")
+		return
+	}
+
+	_ = h.ActivityService.LogActivity(r.Context(), userID, "wish_suggestion_accepted", wish.ID, fmt.Sprintf("Added suggested wish: %s", wish.Title))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(wish)
+}
+
+// writeWishError maps wish sharing errors to HTTP responses.
+func writeWishError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrWishNotFound), errors.Is(err, services.ErrWishSuggestionNotFound):
+		apierror.WriteError(w, http.StatusNotFound, apierror.CodeNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrWishForbidden), errors.Is(err, services.ErrNotFriends):
+		apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, err.Error(), nil)
+	case errors.Is(err, repository.ErrSuggestionNotPending):
+		apierror.WriteError(w, http.StatusConflict, apierror.CodeConflict, err.Error(), nil)
+	default:
+		requestLogger(r).WithError(err).Error(fallback)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, fallback, nil)
+	}
+}
+
 // UploadWishImageHandler handles uploading an image for a specific wish.
 func (h *WishHandler) UploadWishImageHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -246,21 +411,21 @@ func (h *WishHandler) UploadWishImageHandler(w http.ResponseWriter, r *http.Requ
 
 	claims := middleware.GetUserFromContext(r.Context())
 	if claims == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Unauthorized", nil)
 		return
 	}
 
 	// Parse multipart form (max size: 10MB)
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
-		http.Error(w, "File too big or invalid format", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "File too big or invalid format", nil)
 		return
 	}
 
 	// Get the file from form-data
 	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "Missing file in request", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Missing file in request", nil)
 		return
 	}
 	defer file.Close()
@@ -268,7 +433,7 @@ func (h *WishHandler) UploadWishImageHandler(w http.ResponseWriter, r *http.Requ
 	// Check content type
 	contentType := header.Header.Get("Content-Type")
 	if contentType != "image/jpeg" && contentType != "image/png" {
-		http.Error(w, "Only JPEG and PNG images are allowed", http.StatusBadRequest)
+		apierror.WriteError(w, http.StatusBadRequest, apierror.CodeInvalidInput, "Only JPEG and PNG images are allowed", nil)
 		return
 	}
 
@@ -279,41 +444,41 @@ func (h *WishHandler) UploadWishImageHandler(w http.ResponseWriter, r *http.Requ
 
 	// Create folder if not exists
 	if err := os.MkdirAll("uploads", os.ModePerm); err != nil {
-		http.Error(w, "Failed to create upload folder", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to create upload folder", nil)
 		return
 	}
 
 	// Save file to disk
 	out, err := os.Create(savePath)
 	if err != nil {
-		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to save file", nil)
 		return
 	}
 	defer out.Close()
 	if _, err := io.Copy(out, file); err != nil {
-		http.Error(w, "Failed to write file", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to write file", nil)
 		return
 	}
 
 	// Build file URL (can be changed later to use full domain)
 	fileURL := "/uploads/" + fileName
 
-	logrus.WithFields(logrus.Fields{
+	requestLogger(r).WithFields(logrus.Fields{
 		"wishID":  wishID,
 		"userID":  claims.UserID,
 		"fileURL": fileURL,
 	}).Info("Attempting to update wish image")
 
 	if err != nil {
-		logrus.WithError(err).Error("UpdateWishImage failed")
-		http.Error(w, "Failed to update wish with image", http.StatusInternalServerError)
+		requestLogger(r).WithError(err).Error("UpdateWishImage failed")
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update wish with image", nil)
 		return
 	}
 
 	// Update Wish with image URL
 	updated, err := h.Service.UpdateWishImage(r.Context(), wishID, claims.UserID, fileURL)
 	if err != nil {
-		http.Error(w, "Failed to update wish with image", http.StatusInternalServerError)
+		apierror.WriteError(w, http.StatusInternalServerError, apierror.CodeInternalError, "Failed to update wish with image", nil)
 		return
 	}
 
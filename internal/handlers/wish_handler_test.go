@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// asUser authenticates r as userID the way AuthMiddleware does.
+func asUser(r *http.Request, userID primitive.ObjectID) *http.Request {
+	claims := &jwtutil.Claims{UserID: userID.Hex(), Role: "user"}
+	return r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, claims))
+}
+
+func TestSuggestWishHandler(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	handler := NewWishHandler(svc.Wish, svc.Goal, services.NewActivityService(svc.Activities))
+	router := mux.NewRouter()
+	router.HandleFunc("/wishes/{id}/suggest", handler.SuggestWishHandler).Methods(http.MethodPost)
+
+	owner := testutil.SeedUser(t, svc.Repositories, models.User{})
+	friend := testutil.SeedUser(t, svc.Repositories, models.User{})
+	pending := testutil.SeedUser(t, svc.Repositories, models.User{})
+	stranger := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	request, err := svc.Friend.SendFriendRequest(ctx, owner.ID, friend.ID)
+	if err != nil {
+		t.Fatalf("SendFriendRequest: %v", err)
+	}
+	if err := svc.Friend.RespondToRequest(ctx, request.ID, true); err != nil {
+		t.Fatalf("RespondToRequest: %v", err)
+	}
+	// A request that was never answered doesn't make them friends
+	if _, err := svc.Friend.SendFriendRequest(ctx, owner.ID, pending.ID); err != nil {
+		t.Fatalf("SendFriendRequest: %v", err)
+	}
+
+	wish, err := svc.Wishes.CreateWish(ctx, &models.Wish{UserID: owner.ID, Title: "Kayak"})
+	if err != nil {
+		t.Fatalf("CreateWish: %v", err)
+	}
+
+	suggest := func(caller, to primitive.ObjectID) *httptest.ResponseRecorder {
+		body := strings.NewReader(`{"friend_id":"` + to.Hex() + `"}`)
+		req := asUser(httptest.NewRequest(http.MethodPost, "/wishes/"+wish.ID.Hex()+"/suggest", body), caller)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		caller primitive.ObjectID
+		to     primitive.ObjectID
+		status int
+		code   string
+	}{
+		{name: "stranger", caller: owner.ID, to: stranger.ID, status: http.StatusForbidden, code: apierror.CodeForbidden},
+		{name: "unanswered request", caller: owner.ID, to: pending.ID, status: http.StatusForbidden, code: apierror.CodeForbidden},
+		{name: "not the owner", caller: friend.ID, to: owner.ID, status: http.StatusForbidden, code: apierror.CodeForbidden},
+		{name: "friend", caller: owner.ID, to: friend.ID, status: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := suggest(tt.caller, tt.to)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code == "" {
+				return
+			}
+			var body apierror.APIError
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode error: %v", err)
+			}
+			if body.Code != tt.code {
+				t.Errorf("code = %q, want %q", body.Code, tt.code)
+			}
+		})
+	}
+
+	// Only the suggestion to the friend was stored and announced
+	stored, err := svc.DB.Collection("wish_suggestions").CountDocuments(ctx, bson.M{"wish_id": wish.ID})
+	if err != nil {
+		t.Fatalf("failed to count suggestions: %v", err)
+	}
+	if stored != 1 {
+		t.Errorf("%d suggestions stored, want 1", stored)
+	}
+	for _, user := range []*models.User{stranger, pending} {
+		n, err := svc.DB.Collection("notifications").CountDocuments(ctx, bson.M{"user_id": user.ID, "type": "wish_suggested"})
+		if err != nil {
+			t.Fatalf("failed to count notifications: %v", err)
+		}
+		if n != 0 {
+			t.Errorf("non-friend %s got %d wish_suggested notifications", user.Username, n)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package hub fans chat WebSocket frames out to connected users.
+//
+// The hub only routes ephemeral frames such as typing indicators and presence
+// updates; nothing it handles is persisted. Typing relays are throttled per
+// user and chat, and presence is only sent to the user's friends.
+package hub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/metrics"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
+)
+
+// DefaultTypingInterval is the minimum time between relayed typing frames
+// from one user in one chat.
+const DefaultTypingInterval = time.Second
+
+// DefaultFriendsTTL is how long a user's friend list is cached for presence fan-out.
+const DefaultFriendsTTL = time.Minute
+
+// ErrOffline is returned by Send when the user has no open connection.
+var ErrOffline = errors.New("user has no open connection")
+
+// Conn is a single client connection. Send must not block for long; slow
+// connections should buffer or drop frames themselves.
+type Conn interface {
+	Send(frame []byte) error
+}
+
+// FriendsFunc returns the IDs of a user's friends.
+type FriendsFunc func(ctx context.Context, userID string) ([]string, error)
+
+// Hub tracks connections per user and relays ephemeral frames between them.
+type Hub struct {
+	mu     sync.RWMutex
+	conns  map[string]map[Conn]struct{}
+	typing *TypingThrottle
+	fanout *PresenceFanout
+}
+
+// New creates a Hub that looks up friends through friends.
+func New(friends FriendsFunc) *Hub {
+	return &Hub{
+		conns:  make(map[string]map[Conn]struct{}),
+		typing: NewTypingThrottle(DefaultTypingInterval),
+		fanout: NewPresenceFanout(friends, DefaultFriendsTTL),
+	}
+}
+
+// Register adds a connection for userID. It reports whether this is the
+// user's first connection, i.e. whether they just came online.
+func (h *Hub) Register(userID string, conn Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	set, ok := h.conns[userID]
+	if !ok {
+		set = make(map[Conn]struct{})
+		h.conns[userID] = set
+	}
+	if _, exists := set[conn]; !exists {
+		set[conn] = struct{}{}
+		metrics.ActiveWebSocketConnections.Inc()
+	}
+	return len(set) == 1
+}
+
+// Unregister removes a connection. It reports whether the user has no
+// connections left, i.e. whether they just went offline.
+func (h *Hub) Unregister(userID string, conn Conn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	set, ok := h.conns[userID]
+	if !ok {
+		return false
+	}
+	if _, exists := set[conn]; exists {
+		delete(set, conn)
+		metrics.ActiveWebSocketConnections.Dec()
+	}
+	if len(set) > 0 {
+		return false
+	}
+	delete(h.conns, userID)
+	h.typing.Forget(userID)
+	return true
+}
+
+// Online reports whether the user has at least one connection.
+func (h *Hub) Online(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.conns[userID]) > 0
+}
+
+// RelayTyping forwards a typing frame from userID to the other chat members,
+// at most once per DefaultTypingInterval per user and chat. It reports
+// whether the frame was relayed.
+func (h *Hub) RelayTyping(userID string, frame wsproto.Typing, members []string) bool {
+	if !h.typing.Allow(userID, frame.ChatID, time.Now()) {
+		return false
+	}
+	frame.UserID = userID
+
+	recipients := make([]string, 0, len(members))
+	for _, member := range members {
+		if member != userID {
+			recipients = append(recipients, member)
+		}
+	}
+	h.send(recipients, frame)
+	return true
+}
+
+// BroadcastStatus tells the user's online friends that the user came online
+// or went offline.
+func (h *Hub) BroadcastStatus(ctx context.Context, userID string, online bool) {
+	recipients, err := h.fanout.Recipients(ctx, userID)
+	if err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID).Warn("Failed to look up friends for presence")
+		return
+	}
+
+	frame := wsproto.Status{UserID: userID, Online: online}
+	if !online {
+		now := time.Now()
+		frame.LastSeen = &now
+	}
+	h.send(recipients, frame)
+}
+
+// InvalidateFriends drops the cached friend list of a user, e.g. after a
+// friendship was added or removed.
+func (h *Hub) InvalidateFriends(userID string) {
+	h.fanout.Invalidate(userID)
+}
+
+// Send writes frame to every connection of userID. It returns ErrOffline when
+// the user has no connection, and the first write error otherwise.
+func (h *Hub) Send(userID string, frame wsproto.Frame) error {
+	h.mu.RLock()
+	targets := make([]Conn, 0, len(h.conns[userID]))
+	for conn := range h.conns[userID] {
+		targets = append(targets, conn)
+	}
+	h.mu.RUnlock()
+	if len(targets) == 0 {
+		return ErrOffline
+	}
+
+	data, err := wsproto.Encode("", frame)
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	for _, conn := range targets {
+		if err := conn.Send(data); err != nil && sendErr == nil {
+			sendErr = err
+		}
+	}
+	return sendErr
+}
+
+// send encodes frame once and writes it to every connection of the recipients.
+func (h *Hub) send(recipients []string, frame wsproto.Frame) {
+	if len(recipients) == 0 {
+		return
+	}
+	data, err := wsproto.Encode("", frame)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to encode frame")
+		return
+	}
+
+	h.mu.RLock()
+	var targets []Conn
+	for _, userID := range recipients {
+		for conn := range h.conns[userID] {
+			targets = append(targets, conn)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, conn := range targets {
+		if err := conn.Send(data); err != nil {
+			logger.Log.WithError(err).WithField("type", frame.FrameType()).Debug("Failed to send frame")
+		}
+	}
+}
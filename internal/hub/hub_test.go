@@ -0,0 +1,155 @@
+package hub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
+)
+
+// recordingConn keeps every frame sent to it.
+type recordingConn struct {
+	mu     sync.Mutex
+	frames []wsproto.Frame
+}
+
+func (c *recordingConn) Send(data []byte) error {
+	_, frame, err := wsproto.Decode(data)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames = append(c.frames, frame)
+	return nil
+}
+
+func (c *recordingConn) received() []wsproto.Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]wsproto.Frame(nil), c.frames...)
+}
+
+func TestTypingThrottle(t *testing.T) {
+	throttle := NewTypingThrottle(time.Second)
+	start := time.Now()
+
+	steps := []struct {
+		user, chat string
+		at         time.Duration
+		want       bool
+	}{
+		{"alice", "c1", 0, true},
+		{"alice", "c1", 500 * time.Millisecond, false},
+		{"alice", "c2", 500 * time.Millisecond, true},
+		{"bob", "c1", 500 * time.Millisecond, true},
+		{"alice", "c1", time.Second, true},
+		{"alice", "c1", 1500 * time.Millisecond, false},
+	}
+	for _, s := range steps {
+		if got := throttle.Allow(s.user, s.chat, start.Add(s.at)); got != s.want {
+			t.Errorf("Allow(%s, %s, +%v) = %v, want %v", s.user, s.chat, s.at, got, s.want)
+		}
+	}
+
+	throttle.Forget("alice")
+	if !throttle.Allow("alice", "c1", start.Add(1500*time.Millisecond)) {
+		t.Error("Allow after Forget = false, want true")
+	}
+	if throttle.Allow("bob", "c1", start.Add(1200*time.Millisecond)) {
+		t.Error("Forget dropped another user's state")
+	}
+}
+
+func TestPresenceFanoutCaches(t *testing.T) {
+	calls := 0
+	friends := []string{"bob"}
+	fanout := NewPresenceFanout(func(ctx context.Context, userID string) ([]string, error) {
+		calls++
+		return friends, nil
+	}, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := fanout.Recipients(ctx, "alice"); err != nil {
+			t.Fatalf("Recipients: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("friends looked up %d times, want 1", calls)
+	}
+
+	friends = []string{"bob", "carol"}
+	fanout.Invalidate("alice")
+	got, err := fanout.Recipients(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Recipients: %v", err)
+	}
+	if calls != 2 || len(got) != 2 {
+		t.Errorf("after Invalidate: %d lookups, recipients %v", calls, got)
+	}
+}
+
+func TestBroadcastStatusOnlyReachesFriends(t *testing.T) {
+	h := New(func(ctx context.Context, userID string) ([]string, error) {
+		return map[string][]string{"alice": {"bob"}}[userID], nil
+	})
+	alice, bob, stranger := &recordingConn{}, &recordingConn{}, &recordingConn{}
+
+	if !h.Register("bob", bob) || !h.Register("stranger", stranger) || !h.Register("alice", alice) {
+		t.Fatal("Register of a first connection = false")
+	}
+	second := &recordingConn{}
+	if h.Register("bob", second) {
+		t.Error("Register of a second connection = true")
+	}
+
+	h.BroadcastStatus(context.Background(), "alice", true)
+	for _, conn := range []*recordingConn{bob, second} {
+		frames := conn.received()
+		if len(frames) != 1 {
+			t.Fatalf("bob's connection got %d frames, want 1", len(frames))
+		}
+		if status, ok := frames[0].(*wsproto.Status); !ok || status.UserID != "alice" || !status.Online {
+			t.Errorf("bob got %+v, want alice online", frames[0])
+		}
+	}
+	if frames := stranger.received(); len(frames) != 0 {
+		t.Errorf("stranger got %+v, want nothing", frames)
+	}
+
+	if h.Unregister("bob", bob) {
+		t.Error("Unregister with a connection left = true")
+	}
+	if !h.Unregister("bob", second) || h.Online("bob") {
+		t.Error("bob still online after closing both connections")
+	}
+}
+
+func TestRelayTypingSkipsSender(t *testing.T) {
+	h := New(nil)
+	alice, bob := &recordingConn{}, &recordingConn{}
+	h.Register("alice", alice)
+	h.Register("bob", bob)
+
+	frame := wsproto.Typing{ChatID: "c1", UserID: "spoofed", Typing: true}
+	if !h.RelayTyping("alice", frame, []string{"alice", "bob"}) {
+		t.Fatal("first typing frame was throttled")
+	}
+	if h.RelayTyping("alice", frame, []string{"alice", "bob"}) {
+		t.Error("second typing frame within the interval was relayed")
+	}
+
+	if frames := alice.received(); len(frames) != 0 {
+		t.Errorf("sender got %+v, want nothing", frames)
+	}
+	frames := bob.received()
+	if len(frames) != 1 {
+		t.Fatalf("bob got %d frames, want 1", len(frames))
+	}
+	if typing := frames[0].(*wsproto.Typing); typing.UserID != "alice" {
+		t.Errorf("relayed user_id = %q, want the sender", typing.UserID)
+	}
+}
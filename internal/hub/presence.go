@@ -0,0 +1,58 @@
+package hub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PresenceFanout decides who receives a user's presence updates: only their
+// friends, looked up through a cache so connects and disconnects don't hit
+// the database every time.
+type PresenceFanout struct {
+	mu      sync.Mutex
+	friends FriendsFunc
+	ttl     time.Duration
+	cache   map[string]cachedFriends
+}
+
+type cachedFriends struct {
+	ids       []string
+	expiresAt time.Time
+}
+
+// NewPresenceFanout creates a fan-out that caches friend lists for ttl.
+func NewPresenceFanout(friends FriendsFunc, ttl time.Duration) *PresenceFanout {
+	return &PresenceFanout{
+		friends: friends,
+		ttl:     ttl,
+		cache:   make(map[string]cachedFriends),
+	}
+}
+
+// Recipients returns the users that should see userID's presence.
+func (p *PresenceFanout) Recipients(ctx context.Context, userID string) ([]string, error) {
+	p.mu.Lock()
+	cached, ok := p.cache[userID]
+	p.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.ids, nil
+	}
+
+	ids, err := p.friends(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.cache[userID] = cachedFriends{ids: ids, expiresAt: time.Now().Add(p.ttl)}
+	p.mu.Unlock()
+	return ids, nil
+}
+
+// Invalidate drops the cached friend list of a user.
+func (p *PresenceFanout) Invalidate(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cache, userID)
+}
@@ -0,0 +1,48 @@
+package hub
+
+import (
+	"sync"
+	"time"
+)
+
+// TypingThrottle limits how often a user's typing frames are relayed per chat.
+type TypingThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	last     map[typingKey]time.Time
+}
+
+type typingKey struct {
+	userID string
+	chatID string
+}
+
+// NewTypingThrottle creates a throttle that allows one frame per interval.
+func NewTypingThrottle(interval time.Duration) *TypingThrottle {
+	return &TypingThrottle{interval: interval, last: make(map[typingKey]time.Time)}
+}
+
+// Allow reports whether a typing frame from userID in chatID may be relayed at now.
+func (t *TypingThrottle) Allow(userID, chatID string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := typingKey{userID: userID, chatID: chatID}
+	if last, ok := t.last[key]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	t.last[key] = now
+	return true
+}
+
+// Forget drops the throttle state of a user, e.g. when their last connection closes.
+func (t *TypingThrottle) Forget(userID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.last {
+		if key.userID == userID {
+			delete(t.last, key)
+		}
+	}
+}
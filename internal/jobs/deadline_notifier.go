@@ -22,9 +22,10 @@ func NewDeadlineNotifier(goalService *services.GoalService, notifService *servic
 	}
 }
 
-// RunDailyScan checks for goals, steps and suvsteps due in next 24h and sends reminders
+// RunDailyScan sends reminders for goals due within their reminder lead time
+// and for steps and substeps due in the next 24h
 func (d *DeadlineNotifier) RunDailyScan(ctx context.Context) error {
-	goals, err := d.GoalService.GetAllGoals(ctx, 100)
+	goals, err := d.GoalService.GetGoalsDueWithin(ctx, services.MaxRemindBefore)
 	if err != nil {
 		return fmt.Errorf("failed to fetch goals: %v", err)
 	}
@@ -33,8 +34,14 @@ func (d *DeadlineNotifier) RunDailyScan(ctx context.Context) error {
 	tomorrow := now.Add(24 * time.Hour)
 
 	for _, goal := range goals {
-		//  Goal due soon
-		if goal.Status != "completed" && goal.DueDate.After(now) && goal.DueDate.Before(tomorrow) {
+		if !d.NotificationService.WantsGoalDueReminders(ctx, goal.UserID) {
+			continue
+		}
+
+		//  Goal due soon, once per lead time
+		lead := services.GoalReminderLead(goal)
+		if goal.Status != "completed" && goal.Status != services.GoalStatusClosed && goal.DueDate.After(now) && !goal.DueDate.After(now.Add(lead)) &&
+			!d.NotificationService.SentForTargetWithin(ctx, goal.UserID, "goal_due_soon", goal.ID, lead) {
 			_ = d.NotificationService.CreateNotification(
 				ctx,
 				goal.UserID,
@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/sirupsen/logrus"
+)
+
+type TrashPurger struct {
+	GoalService *services.GoalService
+}
+
+// NewTrashPurger creates a new instance of TrashPurger
+func NewTrashPurger(goalService *services.GoalService) *TrashPurger {
+	return &TrashPurger{GoalService: goalService}
+}
+
+// RunDailyPurge warns owners whose trashed goals are about to expire and
+// permanently deletes goals that have been in the trash for more than 30 days.
+// It returns how many goals were purged.
+func (p *TrashPurger) RunDailyPurge(ctx context.Context) (int, error) {
+	warned, err := p.GoalService.WarnUpcomingTrashPurge(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("Trash purge warnings failed")
+	} else {
+		logrus.WithField("warned_users", warned).Info("Trash purge warnings sent")
+	}
+
+	count, err := p.GoalService.PurgeExpiredTrash(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	logrus.WithField("purged", count).Info("Trash purge completed")
+	return int(count), nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// collaboratorRoles rewrites collaborators stored as plain ObjectIDs into
+// {user_id, role: "editor"} entries, keeping the previous permissions.
+// Entries that are already objects are left alone, so mixed arrays work.
+func collaboratorRoles(ctx context.Context, coll *mongo.Collection, filter bson.M) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"collaborators": bson.M{"$map": bson.M{
+				"input": "$collaborators",
+				"as":    "c",
+				"in": bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{bson.M{"$type": "$$c"}, "objectId"}},
+					bson.M{"user_id": "$$c", "role": models.CollaboratorRoleEditor},
+					"$$c",
+				}},
+			}},
+		}}},
+	}
+
+	match := bson.M{"collaborators": bson.M{"$type": "objectId"}}
+	for k, v := range filter {
+		match[k] = v
+	}
+	_, err := coll.UpdateMany(ctx, match, pipeline)
+	return err
+}
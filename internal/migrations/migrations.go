@@ -0,0 +1,146 @@
+// Package migrations upgrades stored documents to the current schema
+// versions in internal/models.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// batchSize is how many documents are migrated per update.
+const batchSize = 500
+
+// lockName and lockTTL guard the runner so only one instance migrates at a time.
+const (
+	lockName = "schema_migrations"
+	lockTTL  = 10 * time.Minute
+)
+
+// Migration brings documents of one collection up to Version. Up receives a
+// filter matching one batch of older documents and rewrites them; the runner
+// then stamps schema_version. A nil Up only stamps the version.
+type Migration struct {
+	Collection  string
+	Version     int
+	Description string
+	Up          func(ctx context.Context, coll *mongo.Collection, filter bson.M) error
+}
+
+// all lists every migration. Add new ones here when a schema version is bumped.
+var all = []Migration{
+	{Collection: "goals", Version: 1, Description: "collaborators as {user_id, role} objects", Up: collaboratorRoles},
+	{Collection: "users", Version: 1, Description: "add schema_version"},
+	{Collection: "templates", Version: 1, Description: "add schema_version"},
+	{Collection: "templates", Version: 2, Description: "start content versions at 1", Up: templateVersions},
+	{Collection: "wishes", Version: 1, Description: "add schema_version"},
+}
+
+// Current maps each collection to the schema version the code writes.
+var Current = map[string]int{
+	"goals":     models.GoalSchemaVersion,
+	"users":     models.UserSchemaVersion,
+	"templates": models.TemplateSchemaVersion,
+	"wishes":    models.WishSchemaVersion,
+}
+
+// Run applies every migration to the documents that still need it. It holds
+// a database lock while running, and returns without doing anything if
+// another instance holds it. Migrations are safe to run more than once.
+func Run(ctx context.Context, db *mongo.Database, locks *repository.LockRepository) error {
+	host, _ := os.Hostname()
+	owner := host + "/" + uuid.NewString()
+
+	acquired, err := locks.Acquire(ctx, lockName, owner, lockTTL)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		logger.Log.Info("Schema migrations are running on another instance, skipping")
+		return nil
+	}
+	defer func() {
+		if err := locks.Release(context.Background(), lockName, owner); err != nil {
+			logger.Log.WithError(err).Warn("Failed to release migration lock")
+		}
+	}()
+
+	migrations := make([]Migration, len(all))
+	copy(migrations, all)
+	sort.SliceStable(migrations, func(i, j int) bool {
+		if migrations[i].Collection != migrations[j].Collection {
+			return migrations[i].Collection < migrations[j].Collection
+		}
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for _, m := range migrations {
+		if m.Version > Current[m.Collection] {
+			return fmt.Errorf("migration %s v%d is newer than the current schema version %d", m.Collection, m.Version, Current[m.Collection])
+		}
+		migrated, err := apply(ctx, db.Collection(m.Collection), m)
+		if err != nil {
+			return fmt.Errorf("migration %s v%d failed: %v", m.Collection, m.Version, err)
+		}
+		if migrated > 0 {
+			logger.Log.WithFields(map[string]interface{}{
+				"collection":  m.Collection,
+				"version":     m.Version,
+				"description": m.Description,
+				"documents":   migrated,
+			}).Info("Schema migration applied")
+		}
+	}
+	return nil
+}
+
+// apply runs m in batches over the documents below its version.
+func apply(ctx context.Context, coll *mongo.Collection, m Migration) (int64, error) {
+	older := bson.M{"schema_version": bson.M{"$not": bson.M{"$gte": m.Version}}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(batchSize)
+
+	var total int64
+	for {
+		cursor, err := coll.Find(ctx, older, opts)
+		if err != nil {
+			return total, err
+		}
+		var batch []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &batch); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		ids := make([]primitive.ObjectID, len(batch))
+		for i, doc := range batch {
+			ids[i] = doc.ID
+		}
+		filter := bson.M{"_id": bson.M{"$in": ids}, "schema_version": older["schema_version"]}
+
+		if m.Up != nil {
+			if err := m.Up(ctx, coll, filter); err != nil {
+				return total, err
+			}
+		}
+		result, err := coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"schema_version": m.Version}})
+		if err != nil {
+			return total, err
+		}
+		total += result.ModifiedCount
+	}
+}
@@ -0,0 +1,148 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/migrations"
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func insertRaw(t *testing.T, coll *mongo.Collection, doc bson.M) primitive.ObjectID {
+	t.Helper()
+	id := primitive.NewObjectID()
+	doc["_id"] = id
+	if _, err := coll.InsertOne(context.Background(), doc); err != nil {
+		t.Fatalf("failed to seed %s: %v", coll.Name(), err)
+	}
+	return id
+}
+
+func loadRaw(t *testing.T, coll *mongo.Collection, id primitive.ObjectID) bson.M {
+	t.Helper()
+	var doc bson.M
+	if err := coll.FindOne(context.Background(), bson.M{"_id": id}).Decode(&doc); err != nil {
+		t.Fatalf("failed to load %s %s: %v", coll.Name(), id.Hex(), err)
+	}
+	return doc
+}
+
+func asInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	}
+	return -1
+}
+
+func TestRunMigratesMixedVersions(t *testing.T) {
+	db := testutil.NewTestDatabase(t)
+	ctx := context.Background()
+	templates := db.Collection("templates")
+	goals := db.Collection("goals")
+	users := db.Collection("users")
+
+	// Templates from every era: before schema_version, before content
+	// versions, and already current.
+	legacy := insertRaw(t, templates, bson.M{"name": "legacy"})
+	v1NoVersion := insertRaw(t, templates, bson.M{"name": "v1", "schema_version": 1})
+	v1Edited := insertRaw(t, templates, bson.M{"name": "v1 edited", "schema_version": 1, "version": 4})
+	current := insertRaw(t, templates, bson.M{"name": "current", "schema_version": 2, "version": 3})
+
+	owner := primitive.NewObjectID()
+	plain := primitive.NewObjectID()
+	viewer := primitive.NewObjectID()
+	mixedGoal := insertRaw(t, goals, bson.M{"name": "mixed", "user_id": owner, "collaborators": bson.A{
+		plain,
+		bson.M{"user_id": viewer, "role": models.CollaboratorRoleViewer},
+	}})
+	currentGoal := insertRaw(t, goals, bson.M{"name": "current", "user_id": owner, "schema_version": 1, "collaborators": bson.A{
+		bson.M{"user_id": viewer, "role": models.CollaboratorRoleViewer},
+	}})
+	legacyUser := insertRaw(t, users, bson.M{"username": "old"})
+
+	locks := repository.NewLockRepository(db)
+	if err := migrations.Run(ctx, db, locks); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	wantTemplates := map[primitive.ObjectID]int64{legacy: 1, v1NoVersion: 1, v1Edited: 4, current: 3}
+	for id, wantVersion := range wantTemplates {
+		doc := loadRaw(t, templates, id)
+		if got := asInt(doc["schema_version"]); got != models.TemplateSchemaVersion {
+			t.Errorf("template %v: schema_version = %d, want %d", doc["name"], got, models.TemplateSchemaVersion)
+		}
+		if got := asInt(doc["version"]); got != wantVersion {
+			t.Errorf("template %v: version = %d, want %d", doc["name"], got, wantVersion)
+		}
+	}
+
+	repo := repository.NewGoalRepository(db)
+	goal, err := repo.GetGoalByID(ctx, mixedGoal)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	if goal.SchemaVersion != models.GoalSchemaVersion {
+		t.Errorf("goal schema_version = %d, want %d", goal.SchemaVersion, models.GoalSchemaVersion)
+	}
+	want := []models.Collaborator{
+		{UserID: plain, Role: models.CollaboratorRoleEditor},
+		{UserID: viewer, Role: models.CollaboratorRoleViewer},
+	}
+	if len(goal.Collaborators) != len(want) {
+		t.Fatalf("collaborators = %+v, want %+v", goal.Collaborators, want)
+	}
+	for i := range want {
+		if goal.Collaborators[i] != want[i] {
+			t.Errorf("collaborator %d = %+v, want %+v", i, goal.Collaborators[i], want[i])
+		}
+	}
+	// Stored as objects now, not just readable as such
+	raw := loadRaw(t, goals, mixedGoal)
+	for _, c := range raw["collaborators"].(bson.A) {
+		if _, ok := c.(bson.M); !ok {
+			t.Errorf("collaborator stored as %T, want a document", c)
+		}
+	}
+
+	if doc := loadRaw(t, goals, currentGoal); asInt(doc["schema_version"]) != 1 {
+		t.Errorf("current goal schema_version = %v, want 1", doc["schema_version"])
+	}
+	if doc := loadRaw(t, users, legacyUser); asInt(doc["schema_version"]) != models.UserSchemaVersion {
+		t.Errorf("user schema_version = %v, want %d", doc["schema_version"], models.UserSchemaVersion)
+	}
+
+	// A second run finds nothing left to do and changes nothing
+	if err := migrations.Run(ctx, db, locks); err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if doc := loadRaw(t, templates, v1Edited); asInt(doc["version"]) != 4 {
+		t.Errorf("second run changed version to %v", doc["version"])
+	}
+}
+
+func TestRunSkipsWhileLocked(t *testing.T) {
+	db := testutil.NewTestDatabase(t)
+	ctx := context.Background()
+	locks := repository.NewLockRepository(db)
+
+	legacy := insertRaw(t, db.Collection("templates"), bson.M{"name": "legacy"})
+	if ok, err := locks.Acquire(ctx, "schema_migrations", "other-instance", time.Minute); err != nil || !ok {
+		t.Fatalf("Acquire = %v, %v", ok, err)
+	}
+
+	if err := migrations.Run(ctx, db, locks); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if doc := loadRaw(t, db.Collection("templates"), legacy); doc["schema_version"] != nil {
+		t.Errorf("template migrated while another instance held the lock: %v", doc)
+	}
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// templateVersions gives templates written before content versioning
+// version 1.
+func templateVersions(ctx context.Context, coll *mongo.Collection, filter bson.M) error {
+	match := bson.M{"version": bson.M{"$exists": false}}
+	for k, v := range filter {
+		match[k] = v
+	}
+	_, err := coll.UpdateMany(ctx, match, bson.M{"$set": bson.M{"version": 1}})
+	return err
+}
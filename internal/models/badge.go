@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Badge is an achievement users can unlock. Badges are identified by Slug.
+type Badge struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Slug        string             `bson:"slug" json:"slug"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	IconURL     string             `bson:"icon_url,omitempty" json:"icon_url,omitempty"`
+}
+
+// UserBadge records that a user unlocked a badge.
+type UserBadge struct {
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	BadgeSlug  string             `bson:"badge_slug" json:"badge_slug"`
+	UnlockedAt time.Time          `bson:"unlocked_at" json:"unlocked_at"`
+}
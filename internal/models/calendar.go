@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Calendar item types.
+const (
+	CalendarItemGoal    = "goal"
+	CalendarItemStep    = "step"
+	CalendarItemSubstep = "substep"
+)
+
+// CalendarItem is one dated entry in the calendar view: a goal, step or
+// substep due date.
+type CalendarItem struct {
+	GoalID       primitive.ObjectID `json:"goal_id"`
+	Type         string             `json:"type"`  // CalendarItemGoal, CalendarItemStep or CalendarItemSubstep
+	Title        string             `json:"title"` // goal name, step name or substep title
+	GoalName     string             `json:"goal_name"`
+	DueDate      time.Time          `json:"due_date"`
+	Completed    bool               `json:"completed"`
+	StepIndex    *int               `json:"step_index,omitempty"`
+	SubstepIndex *int               `json:"substep_index,omitempty"`
+}
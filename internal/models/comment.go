@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comment is a note left on a goal by its owner or one of its collaborators.
+type Comment struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	GoalID    primitive.ObjectID `json:"goal_id" bson:"goal_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Text      string             `json:"text" bson:"text"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	DeletedAt time.Time          `json:"-" bson:"deleted_at,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// FeatureFlag gates a feature for all or a percentage of users.
+type FeatureFlag struct {
+	Name           string    `bson:"_id" json:"name"`
+	Enabled        bool      `bson:"enabled" json:"enabled"`
+	RolloutPercent int       `bson:"rollout_percent" json:"rollout_percent"` // 0-100, share of users who get the feature when enabled
+	Description    string    `bson:"description,omitempty" json:"description,omitempty"`
+	UpdatedAt      time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FriendInvitation is an emailed invitation for someone without an account.
+// Registering with its token makes the new user and the inviter friends.
+type FriendInvitation struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InviterID  primitive.ObjectID `bson:"inviter_id" json:"inviter_id"`
+	Email      string             `bson:"email" json:"email"`
+	Token      string             `bson:"token" json:"-"`
+	SentAt     time.Time          `bson:"sent_at" json:"sent_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	AcceptedBy primitive.ObjectID `bson:"accepted_by,omitempty" json:"accepted_by,omitempty"` // the user who registered with the token
+}
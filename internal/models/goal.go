@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -19,28 +21,93 @@ var AllowedCategories = map[string]bool{
 
 // Goal represents a user's goal.
 type Goal struct {
-	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	UserID        primitive.ObjectID   `bson:"user_id" json:"user_id"`
-	Name          string               `bson:"name" json:"name"`
-	Description   string               `bson:"description" json:"description"`
-	Category      string               `bson:"category,omitempty" json:"category,omitempty"` // New Field
-	Steps         []Step               `bson:"steps" json:"steps"`
-	Status        string               `bson:"status" json:"status"`
-	DueDate       time.Time            `bson:"due_date,omitempty" json:"due_date,omitempty"`
-	Collaborators []primitive.ObjectID `bson:"collaborators,omitempty" json:"collaborators,omitempty"`
-	CreatedAt     time.Time            `bson:"created_at" json:"created_at"`
-	UpdatedAt     time.Time            `bson:"updated_at" json:"updated_at"`
+	ID                            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID                        primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	Name                          string               `bson:"name" json:"name"`
+	Description                   string               `bson:"description" json:"description"`
+	Category                      string               `bson:"category,omitempty" json:"category,omitempty"` // New Field
+	Tags                          []string             `bson:"tags" json:"tags"`
+	Steps                         []Step               `bson:"steps" json:"steps"`
+	Status                        string               `bson:"status" json:"status"`
+	ClosedReason                  string               `bson:"closed_reason,omitempty" json:"closed_reason,omitempty"` // why the owner gave up on a closed goal
+	ClosedAt                      time.Time            `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	CompletedAt                   time.Time            `bson:"completed_at,omitempty" json:"completed_at,omitempty"`                   // when the status last became completed
+	Pinned                        bool                 `bson:"pinned" json:"pinned"`                                                   // listed first for the owner, see GoalService.SetPinned
+	RequireCompletionConfirmation bool                 `bson:"require_completion_confirmation" json:"require_completion_confirmation"` // finished goals wait in pending_completion for the owner
+	DueDate                       time.Time            `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	RemindBefore                  Duration             `bson:"remind_before,omitempty" json:"remind_before,omitempty"` // how long before DueDate to remind the owner; zero uses the default
+	Collaborators                 []Collaborator       `bson:"collaborators,omitempty" json:"collaborators,omitempty"`
+	BlockedBy                     []primitive.ObjectID `bson:"blocked_by,omitempty" json:"blocked_by,omitempty"` // goals that must be completed before this one can progress
+	ShareToken                    string               `bson:"share_token,omitempty" json:"-"`                   // grants read-only access via /goals/shared/{token}
+	Visibility                    string               `bson:"visibility,omitempty" json:"visibility,omitempty"` // One of the GoalVisibility* values; empty means public
+	CreatedAt                     time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt                     time.Time            `bson:"updated_at" json:"updated_at"`
+	Version                       int64                `bson:"version" json:"version"`                           // bumped on every write, see GoalRepository.UpdateGoal
+	SchemaVersion                 int                  `bson:"schema_version" json:"schema_version"`             // see GoalSchemaVersion
+	DeletedAt                     time.Time            `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // set while the goal is in the trash
+	StatusBeforeDelete            string               `bson:"status_before_delete,omitempty" json:"-"`
+	PurgeWarnedAt                 time.Time            `bson:"purge_warned_at,omitempty" json:"-"` // set once the owner was told the goal is about to be purged
+}
+
+// Goal visibility. Private goals are left out of friends' activity feeds.
+const (
+	GoalVisibilityPublic  = "public"
+	GoalVisibilityPrivate = "private"
+)
+
+// Collaborator roles. Viewers can read a goal and its progress; editors can also change it.
+const (
+	CollaboratorRoleViewer = "viewer"
+	CollaboratorRoleEditor = "editor"
+)
+
+// Collaborator is a user invited to a goal with a given role.
+type Collaborator struct {
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role   string             `bson:"role" json:"role"`
+}
+
+// CanEdit reports whether the collaborator may change the goal.
+func (c Collaborator) CanEdit() bool {
+	return c.Role == CollaboratorRoleEditor
+}
+
+// UnmarshalBSONValue also accepts the legacy format, where collaborators were
+// stored as plain ObjectIDs. Those entries are read as editors.
+func (c *Collaborator) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	if t == bsontype.ObjectID {
+		var id primitive.ObjectID
+		if err := bson.UnmarshalValue(t, data, &id); err != nil {
+			return err
+		}
+		*c = Collaborator{UserID: id, Role: CollaboratorRoleEditor}
+		return nil
+	}
+
+	type plain Collaborator
+	var decoded plain
+	if err := bson.UnmarshalValue(t, data, &decoded); err != nil {
+		return err
+	}
+	*c = Collaborator(decoded)
+	if c.Role == "" {
+		c.Role = CollaboratorRoleEditor
+	}
+	return nil
 }
 
 type Step struct {
 	Name      string    `bson:"name" json:"name"`
+	Note      string    `bson:"note,omitempty" json:"note,omitempty"`
 	DueDate   time.Time `bson:"due_date,omitempty" json:"due_date,omitempty"`
 	Substeps  []Substep `bson:"substeps" json:"substeps"`
 	Completed bool      `bson:"completed" json:"completed"`
 }
 
 type Substep struct {
-	Title   string    `bson:"title" json:"title"`
-	DueDate time.Time `bson:"due_date,omitempty" json:"due_date,omitempty"`
-	Done    bool      `bson:"done" json:"done"`
+	Title       string    `bson:"title" json:"title"`
+	Note        string    `bson:"note,omitempty" json:"note,omitempty"`
+	DueDate     time.Time `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	Done        bool      `bson:"done" json:"done"`
+	CompletedAt time.Time `bson:"completed_at,omitempty" json:"completed_at,omitempty"` // when the substep was marked done
 }
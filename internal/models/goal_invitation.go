@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Statuses a goal invitation moves through.
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusDeclined = "declined"
+)
+
+// GoalInvitation is a pending offer to collaborate on a goal. The invitee only
+// becomes a collaborator once they accept it.
+type GoalInvitation struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GoalID      primitive.ObjectID `bson:"goal_id" json:"goal_id"`
+	GoalName    string             `bson:"goal_name" json:"goal_name"`
+	InviterID   primitive.ObjectID `bson:"inviter_id" json:"inviter_id"`
+	InviteeID   primitive.ObjectID `bson:"invitee_id" json:"invitee_id"`
+	Role        string             `bson:"role" json:"role"`
+	Status      string             `bson:"status" json:"status"` // "pending", "accepted", "declined"
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	RespondedAt time.Time          `bson:"responded_at,omitempty" json:"responded_at,omitempty"`
+}
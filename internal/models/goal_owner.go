@@ -0,0 +1,8 @@
+package models
+
+// GoalWithOwner is a goal listed to someone other than its owner, together
+// with the owner's public username.
+type GoalWithOwner struct {
+	Goal          `bson:",inline"`
+	OwnerUsername string `bson:"owner_username,omitempty" json:"owner_username,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// MonthlyCount is the number of goals for one calendar month (YYYY-MM, UTC).
+type MonthlyCount struct {
+	Month string `bson:"_id" json:"month"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// GoalStats summarizes a set of goals for the dashboard.
+type GoalStats struct {
+	Total             int64            `json:"total"`
+	ByStatus          map[string]int64 `json:"by_status"`
+	ByCategory        map[string]int64 `json:"by_category"`
+	CompletedPerMonth []MonthlyCount   `json:"completed_per_month"`
+	AvgCompletionDays float64          `json:"avg_completion_days"`
+	Overdue           int64            `json:"overdue"`
+}
+
+// UserStats summarizes a user's own goals and activity for their profile.
+type UserStats struct {
+	TotalGoals            int64            `json:"total_goals"`
+	CompletedGoals        int64            `json:"completed_goals"`
+	InProgressGoals       int64            `json:"in_progress_goals"`
+	CompletionRatePercent float64          `json:"completion_rate_percent"`
+	GoalsByCategory       map[string]int64 `json:"goals_by_category"`
+	AverageDaysToComplete float64          `json:"average_days_to_complete"`
+	CurrentStreak         int              `json:"current_streak"` // consecutive active days up to today or yesterday
+	LongestStreak         int              `json:"longest_streak"`
+	XP                    int              `json:"xp"`
+	Level                 int              `json:"level"`
+}
+
+// CompletionStreak counts consecutive days on which the user completed at
+// least one substep or goal.
+type CompletionStreak struct {
+	CurrentStreak int `json:"current_streak"` // up to today or yesterday
+	LongestStreak int `json:"longest_streak"`
+}
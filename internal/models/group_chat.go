@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GroupChat is a chat between several users. Members are always friends of
+// whoever added them.
+type GroupChat struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name      string               `bson:"name" json:"name"`
+	CreatorID primitive.ObjectID   `bson:"creator_id" json:"creator_id"`
+	Members   []primitive.ObjectID `bson:"members" json:"members"` // includes the creator
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+}
+
+// Group message types.
+const (
+	GroupMessageText = "text"
+	GroupMessageFile = "file"
+)
+
+// GroupMessage is one message posted to a group chat.
+type GroupMessage struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupChatID primitive.ObjectID `bson:"group_chat_id" json:"group_chat_id"`
+	SenderID    primitive.ObjectID `bson:"sender_id" json:"sender_id"`
+	Type        string             `bson:"type" json:"type"`
+	Text        string             `bson:"text,omitempty" json:"text,omitempty"`
+	FileURL     string             `bson:"file_url,omitempty" json:"file_url,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
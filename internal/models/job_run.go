@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JobRun is one execution of a scheduled job. A run without FinishedAt either
+// is still going or was cut short by a crash.
+type JobRun struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name           string             `bson:"name" json:"name"`
+	StartedAt      time.Time          `bson:"started_at" json:"started_at"`
+	FinishedAt     time.Time          `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+	ItemsProcessed int                `bson:"items_processed" json:"items_processed"`
+	Errors         int                `bson:"errors" json:"errors"`
+	Error          string             `bson:"error,omitempty" json:"error,omitempty"`
+	Success        bool               `bson:"success" json:"success"`
+}
+
+// JobStatus is the latest state of a scheduled job as shown to admins.
+type JobStatus struct {
+	Name          string    `json:"name"`
+	Interval      string    `json:"interval"`
+	LastRun       *JobRun   `json:"last_run,omitempty"`
+	LastSuccessAt time.Time `json:"last_success_at,omitempty"`
+	Stale         bool      `json:"stale"` // No successful run within twice the interval
+}
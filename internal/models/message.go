@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Message types.
+const (
+	MessageText    = "text"
+	MessageFile    = "file"
+	MessageDeleted = "deleted" // tombstone left by a soft delete
+)
+
+// Message is one message of a direct chat between two friends.
+type Message struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SenderID   primitive.ObjectID `bson:"sender_id" json:"sender_id"`
+	ReceiverID primitive.ObjectID `bson:"receiver_id" json:"receiver_id"`
+	Type       string             `bson:"type" json:"type"`
+	Text       string             `bson:"text,omitempty" json:"text,omitempty"`
+	FileURL    string             `bson:"file_url,omitempty" json:"file_url,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	DeletedAt  *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	ReadAt     *time.Time         `bson:"read_at,omitempty" json:"read_at"` // when the receiver first saw it
+
+	// Reactions maps each emoji to the users who reacted with it.
+	// ReactionEmoji lists the same emoji so their number can be capped in a
+	// query.
+	Reactions     map[string][]primitive.ObjectID `bson:"reactions,omitempty" json:"reactions,omitempty"`
+	ReactionEmoji []string                        `bson:"reaction_emoji,omitempty" json:"-"`
+}
@@ -7,13 +7,34 @@ import (
 )
 
 type Notification struct {
-	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
-	Type      string              `bson:"type" json:"type"`                               // e.g. "goal_completed", "substep_due"
-	Title     string              `bson:"title" json:"title"`                             // Short headline
-	Message   string              `bson:"message" json:"message"`                         // Descriptive content
-	Read      bool                `bson:"read" json:"read"`                               // True if user viewed it
-	TargetID  *primitive.ObjectID `bson:"target_id,omitempty" json:"target_id,omitempty"` // Optional reference to goal/wish/etc.
-	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
-	ExpiresAt time.Time           `bson:"expires_at" json:"expires_at"` // For auto-deletion after 7 days
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID       primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Type         string              `bson:"type" json:"type"`                               // e.g. "goal_completed", "substep_due"
+	Title        string              `bson:"title" json:"title"`                             // Short headline
+	Message      string              `bson:"message" json:"message"`                         // Descriptive content
+	Read         bool                `bson:"read" json:"read"`                               // True if user viewed it
+	TargetID     *primitive.ObjectID `bson:"target_id,omitempty" json:"target_id,omitempty"` // Optional reference to goal/wish/etc.
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	ExpiresAt    time.Time           `bson:"expires_at,omitempty" json:"expires_at"`                 // Zero for types that are kept until acted upon
+	DeliverAfter time.Time           `bson:"deliver_after,omitempty" json:"deliver_after,omitempty"` // set during quiet hours; the push waits until then
+}
+
+// NotificationDayCount is the number of notifications of one type on one day.
+type NotificationDayCount struct {
+	Day   string `bson:"day" json:"day"` // YYYY-MM-DD, UTC
+	Count int64  `bson:"count" json:"count"`
+}
+
+// NotificationTypeSummary counts notifications of one type over a period.
+type NotificationTypeSummary struct {
+	Type       string                 `bson:"_id" json:"type"`
+	Count      int64                  `bson:"count" json:"count"`
+	Recipients int64                  `bson:"recipients,omitempty" json:"recipients,omitempty"`
+	Days       []NotificationDayCount `bson:"days,omitempty" json:"days,omitempty"`
+}
+
+// NotificationRecipientSummary counts how many notifications one user received.
+type NotificationRecipientSummary struct {
+	UserID primitive.ObjectID `bson:"_id" json:"user_id"`
+	Count  int64              `bson:"count" json:"count"`
 }
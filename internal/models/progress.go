@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProgressSnapshot records a goal's completion percentage at the time of an update.
+type ProgressSnapshot struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GoalID    primitive.ObjectID `bson:"goal_id" json:"goal_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Percent   float64            `bson:"percent" json:"percent"`
+	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Current schema versions of the core documents. Documents written before
+// versioning have no schema_version and count as version 0. Bump a version
+// together with a migration in internal/migrations that brings older
+// documents up to it.
+const (
+	GoalSchemaVersion     = 1 // 1: collaborators are {user_id, role} objects
+	UserSchemaVersion     = 1
+	TemplateSchemaVersion = 2 // 2: templates carry a content version
+	WishSchemaVersion     = 1
+)
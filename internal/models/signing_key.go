@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SigningKey is a JWT signing secret stored in the secrets collection. The
+// secret is encrypted with the key encryption key from the config. The key
+// configured through JWT_SECRET is never stored; a record with kid "env" only
+// remembers when it was rotated out.
+type SigningKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	KeyID      string             `bson:"kid" json:"kid"`
+	Ciphertext []byte             `bson:"ciphertext,omitempty" json:"-"`
+	Nonce      []byte             `bson:"nonce,omitempty" json:"-"`
+	Active     bool               `bson:"active" json:"active"`
+	RetiresAt  time.Time          `bson:"retires_at,omitempty" json:"retires_at,omitempty"`
+	RotatedBy  primitive.ObjectID `bson:"rotated_by,omitempty" json:"rotated_by,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -12,9 +12,15 @@ type GoalTemplate struct {
 	Description string             `json:"description" bson:"description"`
 	Steps       []TemplateStep     `json:"steps" bson:"steps"`
 	Category    string             `json:"category,omitempty" bson:"category,omitempty"`
+	Tags        []string           `json:"tags,omitempty" bson:"tags,omitempty"`
 	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
-	Public      bool               `json:"public" bson:"public"` // New: indicates if template is public
+	Public      bool               `json:"public" bson:"public"`         // New: indicates if template is public
+	CopyCount   int64              `json:"copy_count" bson:"copy_count"` // Copies by established, verified accounts
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	Version     int                `json:"version" bson:"version"` // starts at 1, bumped on every update
+
+	SchemaVersion int `json:"schema_version" bson:"schema_version"` // see TemplateSchemaVersion
 }
 
 // For use inside templates
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateBookmark saves a template to a user's list for later.
+type TemplateBookmark struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TemplateID primitive.ObjectID `bson:"template_id" json:"template_id"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
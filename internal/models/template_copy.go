@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateCopy records one copy of a template into a goal. Copies by
+// unverified or brand-new accounts are kept for review but not Counted
+// towards the template's copy count or trending.
+type TemplateCopy struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TemplateID primitive.ObjectID `bson:"template_id" json:"template_id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	GoalID     primitive.ObjectID `bson:"goal_id" json:"goal_id"`
+	Counted    bool               `bson:"counted" json:"counted"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// TrendingTemplate is a template ranked by how many distinct users copied it.
+type TrendingTemplate struct {
+	TemplateID primitive.ObjectID `bson:"_id" json:"template_id"`
+	Users      int64              `bson:"users" json:"users"`
+}
+
+// TemplateCopier summarizes one user's copies for abuse review.
+type TemplateCopier struct {
+	UserID    primitive.ObjectID `bson:"_id" json:"user_id"`
+	Copies    int64              `bson:"copies" json:"copies"`
+	Templates int64              `bson:"templates" json:"templates"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateVersion is the content a template had at one version. Past
+// versions are stored when the template is updated; the current version
+// lives on the template itself.
+type TemplateVersion struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	TemplateID  primitive.ObjectID `bson:"template_id" json:"template_id"`
+	Version     int                `bson:"version" json:"version"`
+	Title       string             `bson:"title" json:"title"`
+	Description string             `bson:"description" json:"description"`
+	Steps       []TemplateStep     `bson:"steps" json:"steps"`
+	Category    string             `bson:"category,omitempty" json:"category,omitempty"`
+	Tags        []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	Public      bool               `bson:"public" json:"public"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"` // when this version was published
+}
@@ -8,23 +8,83 @@ import (
 
 // User represents a user account in the Achievement Manager system.
 type User struct {
-	ID             primitive.ObjectID   `bson:"_id,omitempty"`
-	Friends        []primitive.ObjectID `json:"friends,omitempty" bson:"friends,omitempty"`
-	Username       string               `bson:"username"`
-	Email          string               `bson:"email"`
-	HashedPassword string               `json:"hashed_password"`
-	Role           string               `bson:"role" json:"role"`
-	IsVerified     bool                 `bson:"is_verified" json:"is_verified"`
-	VerifyToken    string               `bson:"verify_token,omitempty" json:"-"`
-	ResetToken     string               `bson:"reset_token,omitempty" json:"-"`
-	ResetTokenExp  time.Time            `bson:"reset_token_exp,omitempty" json:"-"`
-	CreatedAt      time.Time            `bson:"created_at"`
-	UpdatedAt      time.Time            `bson:"updated_at"`
-	LastActiveAt   time.Time            `bson:"last_active_at,omitempty" json:"last_active_at,omitempty"`
+	ID                   primitive.ObjectID   `bson:"_id,omitempty"`
+	Friends              []primitive.ObjectID `json:"friends,omitempty" bson:"friends,omitempty"`
+	Username             string               `bson:"username"`
+	Email                string               `bson:"email"`
+	DisplayName          string               `bson:"display_name,omitempty" json:"display_name,omitempty"`
+	AvatarURL            string               `bson:"avatar_url,omitempty" json:"avatar_url,omitempty"`
+	Bio                  string               `bson:"bio,omitempty" json:"bio,omitempty"`
+	ProfileVisibility    string               `bson:"profile_visibility,omitempty" json:"profile_visibility,omitempty"` // One of the ProfileVisibility* values; empty means public
+	HashedPassword       string               `json:"hashed_password"`
+	Role                 string               `bson:"role" json:"role"`
+	IsVerified           bool                 `bson:"is_verified" json:"is_verified"`
+	VerifyToken          string               `bson:"verify_token,omitempty" json:"-"`
+	ResetToken           string               `bson:"reset_token,omitempty" json:"-"`
+	ResetTokenExp        time.Time            `bson:"reset_token_exp,omitempty" json:"-"`
+	PendingEmail         string               `bson:"pending_email,omitempty" json:"pending_email,omitempty"` // New address waiting for confirmation
+	PendingEmailToken    string               `bson:"pending_email_token,omitempty" json:"-"`
+	PendingEmailTokenExp time.Time            `bson:"pending_email_token_exp,omitempty" json:"-"`
+	CreatedAt            time.Time            `bson:"created_at"`
+	UpdatedAt            time.Time            `bson:"updated_at"`
+	LastActiveAt         time.Time            `bson:"last_active_at,omitempty" json:"last_active_at,omitempty"`
+	VerifiedAt           time.Time            `bson:"verified_at,omitempty" json:"verified_at,omitempty"`
+	EmailOptOut          bool                 `bson:"email_opt_out" json:"email_opt_out"` // Opt out of non-essential emails
+	TOTPSecret           string               `bson:"totp_secret,omitempty" json:"-"`     // Set during 2FA setup, confirmed once TOTPEnabled
+	TOTPEnabled          bool                 `bson:"totp_enabled" json:"totp_enabled"`
+	AuthProvider         string               `bson:"auth_provider,omitempty" json:"auth_provider,omitempty"` // "google" for accounts signed in through Google
+	ProviderID           string               `bson:"provider_id,omitempty" json:"-"`                         // The account's ID at AuthProvider
+
+	MutedNotificationTypes []string `bson:"muted_notification_types,omitempty" json:"muted_notification_types,omitempty"`
+
+	// Users who may not send this user friend requests, see UserService.BlockUser
+	BlockedUsers []primitive.ObjectID `bson:"blocked_users,omitempty" json:"blocked_users,omitempty"`
+
+	// Experience earned from progress, see UserService.AwardXP
+	XP    int `bson:"xp" json:"xp"`
+	Level int `bson:"level" json:"level"`
+
+	// Consecutive days with a login, see UserService.UpdateLoginStreak
+	LoginStreak       int       `bson:"login_streak" json:"login_streak"`
+	LastLoginDate     time.Time `bson:"last_login_date,omitempty" json:"last_login_date,omitempty"` // Midnight UTC of the last login day in the user's timezone
+	StreakFreezeCount int       `bson:"streak_freeze_count" json:"streak_freeze_count"`             // Freezes bought with XP; each one saves the streak after a missed day
+
+	// Account deletion, see AccountService.RequestDeletion
+	DeletionScheduledFor time.Time `bson:"deletion_scheduled_for,omitempty" json:"deletion_scheduled_for,omitempty"`
+	DeletionToken        string    `bson:"deletion_token,omitempty" json:"-"`
+
+	// Secret for subscribing to GET /goals/calendar/{token}.ics without a JWT
+	CalendarToken string `bson:"calendar_token,omitempty" json:"-"`
+
+	SchemaVersion int `bson:"schema_version" json:"schema_version"` // see UserSchemaVersion
+
+	// One-time onboarding flags
+	WelcomeEmailSent   bool `bson:"welcome_email_sent" json:"-"`
+	GettingStartedSent bool `bson:"getting_started_sent" json:"-"`
 }
 
+// Who can see a user's profile at GET /users/{id}/profile.
+const (
+	ProfileVisibilityPublic  = "public"
+	ProfileVisibilityFriends = "friends"
+	ProfileVisibilityPrivate = "private"
+)
+
+// PublicUser is the part of a user shown to other users. Email is only
+// filled in for friends.
 type PublicUser struct {
-	ID       primitive.ObjectID `json:"id"`
-	Username string             `json:"username"`
-	Email    string             `json:"email"`
+	ID          primitive.ObjectID `json:"id"`
+	Username    string             `json:"username"`
+	DisplayName string             `json:"display_name,omitempty"`
+	AvatarURL   string             `json:"avatar_url,omitempty"`
+	Email       string             `json:"email,omitempty"`
+}
+
+// PublicUserProfile is a user's profile page as shown to other users.
+type PublicUserProfile struct {
+	PublicUser
+	Bio          string    `json:"bio,omitempty"`
+	GoalCount    int64     `json:"goal_count"`
+	FriendsCount int       `json:"friends_count"`
+	JoinedAt     time.Time `json:"joined_at"`
 }
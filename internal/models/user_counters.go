@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserCounters holds precomputed per-user totals for the dashboard. The
+// services adjust them with $inc as things happen and a nightly job
+// recomputes them to correct any drift.
+type UserCounters struct {
+	UserID                primitive.ObjectID `bson:"user_id" json:"-"`
+	Goals                 int64              `bson:"goals" json:"goals"`                     // owned goals outside the trash
+	CompletedGoals        int64              `bson:"completed_goals" json:"completed_goals"` // owned completed goals outside the trash
+	GoalsByStatus         map[string]int64   `bson:"goals_by_status" json:"goals_by_status"` // owned goals outside the trash, keyed by status
+	UnreadNotifications   int64              `bson:"unread_notifications" json:"unread_notifications"`
+	PendingFriendRequests int64              `bson:"pending_friend_requests" json:"pending_friend_requests"` // requests waiting for this user's answer
+	UpdatedAt             time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Counter field names, for use with CounterRepository.Increment.
+const (
+	CounterGoals                 = "goals"
+	CounterCompletedGoals        = "completed_goals"
+	CounterUnreadNotifications   = "unread_notifications"
+	CounterPendingFriendRequests = "pending_friend_requests"
+)
+
+// GoalStatusCounter returns the counter field that counts the user's goals
+// with the given status.
+func GoalStatusCounter(status string) string {
+	return "goals_by_status." + status
+}
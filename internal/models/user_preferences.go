@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserPreferences holds a user's notification and UI settings. There is at
+// most one document per user.
+type UserPreferences struct {
+	UserID               primitive.ObjectID `bson:"user_id" json:"user_id"`
+	NotifyGoalDue        bool               `bson:"notify_goal_due" json:"notify_goal_due"`                         // goal, step and substep deadline reminders
+	NotifyFriendRequests bool               `bson:"notify_friend_requests" json:"notify_friend_requests"`           // friend request notifications
+	NotifyInactivity     bool               `bson:"notify_inactivity" json:"notify_inactivity"`                     // "we miss you" reminders
+	Timezone             string             `bson:"timezone" json:"timezone"`                                       // IANA name, e.g. "Europe/Berlin"
+	Language             string             `bson:"language" json:"language"`                                       // e.g. "en" or "pt-BR"
+	QuietHoursStart      string             `bson:"quiet_hours_start,omitempty" json:"quiet_hours_start,omitempty"` // "HH:MM" in Timezone; empty disables quiet hours
+	QuietHoursEnd        string             `bson:"quiet_hours_end,omitempty" json:"quiet_hours_end,omitempty"`     // "HH:MM", may be earlier than the start to span midnight
+	UpdatedAt            time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// DefaultUserPreferences are used for users without a preferences document.
+func DefaultUserPreferences(userID primitive.ObjectID) UserPreferences {
+	return UserPreferences{
+		UserID:               userID,
+		NotifyGoalDue:        true,
+		NotifyFriendRequests: true,
+		NotifyInactivity:     true,
+		Timezone:             "UTC",
+		Language:             "en",
+	}
+}
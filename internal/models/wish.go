@@ -14,4 +14,7 @@ type Wish struct {
 	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+
+	PromotedGoalID *primitive.ObjectID `bson:"promoted_goal_id,omitempty" json:"promoted_goal_id,omitempty"` // Set once the wish became a goal
+	SchemaVersion  int                 `bson:"schema_version" json:"schema_version"`                         // see WishSchemaVersion
 }
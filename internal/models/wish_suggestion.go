@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Wish suggestion statuses.
+const (
+	WishSuggestionPending  = "pending"
+	WishSuggestionAccepted = "accepted"
+)
+
+// WishSuggestion is a snapshot of one user's wish offered to a friend. The
+// friend accepts it with Token to get their own copy.
+type WishSuggestion struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WishID        primitive.ObjectID  `bson:"wish_id" json:"wish_id"`
+	FromUserID    primitive.ObjectID  `bson:"from_user_id" json:"from_user_id"`
+	ToUserID      primitive.ObjectID  `bson:"to_user_id" json:"to_user_id"`
+	Title         string              `bson:"title" json:"title"`
+	Description   string              `bson:"description" json:"description"`
+	ImageURL      string              `bson:"image_url,omitempty" json:"image_url,omitempty"`
+	Token         string              `bson:"token" json:"token"`
+	Status        string              `bson:"status" json:"status"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	AcceptedAt    time.Time           `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	CreatedWishID *primitive.ObjectID `bson:"created_wish_id,omitempty" json:"created_wish_id,omitempty"`
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"github.com/sirupsen/logrus"
@@ -22,6 +23,19 @@ func NewActivityRepository(db *mongo.Database) *ActivityRepository {
 	}
 }
 
+// EnsureIndexes creates the indexes used to list the activity about one
+// target and the recent activity of a set of users.
+func (r *ActivityRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create activity indexes: %v", err)
+	}
+	return nil
+}
+
 // CreateActivity inserts a new activity log
 func (r *ActivityRepository) CreateActivity(ctx context.Context, activity *models.Activity) error {
 	_, err := r.collection.InsertOne(ctx, activity)
@@ -51,3 +65,113 @@ func (r *ActivityRepository) GetUserActivities(ctx context.Context, userID primi
 	}
 	return activities, nil
 }
+
+// GetActivitiesByTarget returns up to limit activities about targetID, newest
+// first. A non-zero cursor skips to the activities older than that ID.
+func (r *ActivityRepository) GetActivitiesByTarget(ctx context.Context, targetID, cursor primitive.ObjectID, limit int64) ([]models.Activity, error) {
+	filter := bson.M{"target_id": targetID}
+	if !cursor.IsZero() {
+		filter["_id"] = bson.M{"$lt": cursor}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+
+	cur, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch activities: %v", err)
+	}
+	defer cur.Close(ctx)
+
+	activities := []models.Activity{}
+	if err := cur.All(ctx, &activities); err != nil {
+		return nil, fmt.Errorf("failed to decode activities: %v", err)
+	}
+	return activities, nil
+}
+
+// FriendFeedActivityTypes are the activities shown in a friend's feed.
+var FriendFeedActivityTypes = []string{"goal_created", "goal_completed"}
+
+// GetFriendActivities returns up to limit goal_created and goal_completed
+// activities by the given users, newest first. Only activities logged before
+// the given time are returned unless it is zero. Activities about goals that
+// are private or in the trash are left out.
+func (r *ActivityRepository) GetFriendActivities(ctx context.Context, friendIDs []primitive.ObjectID, before time.Time, limit int64) ([]models.Activity, error) {
+	match := bson.M{
+		"user_id": bson.M{"$in": friendIDs},
+		"type":    bson.M{"$in": FriendFeedActivityTypes},
+	}
+	if !before.IsZero() {
+		match["timestamp"] = bson.M{"$lt": before}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "goals",
+			"localField":   "target_id",
+			"foreignField": "_id",
+			"as":           "goal",
+		}}},
+		{{Key: "$match", Value: bson.M{
+			"goal": bson.M{"$elemMatch": bson.M{
+				"visibility": bson.M{"$ne": models.GoalVisibilityPrivate},
+				"deleted_at": nil,
+			}},
+		}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.M{"goal": 0}}},
+	}
+
+	cur, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch friend activities: %v", err)
+	}
+	defer cur.Close(ctx)
+
+	activities := []models.Activity{}
+	if err := cur.All(ctx, &activities); err != nil {
+		return nil, fmt.Errorf("failed to decode activities: %v", err)
+	}
+	return activities, nil
+}
+
+// GetActivityDays returns the distinct days, formatted YYYY-MM-DD in the given
+// IANA timezone, on which the user logged any activity, oldest first.
+func (r *ActivityRepository) GetActivityDays(ctx context.Context, userID primitive.ObjectID, timezone string) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$timestamp", "timezone": timezone}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate activity days: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Day string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode activity days: %v", err)
+	}
+
+	days := make([]string, len(rows))
+	for i, row := range rows {
+		days[i] = row.Day
+	}
+	return days, nil
+}
+
+// DeleteUserActivities removes every activity logged for a user.
+func (r *ActivityRepository) DeleteUserActivities(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete activities: %v", err)
+	}
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BadgeRepository stores badge definitions and the badges users unlocked.
+type BadgeRepository struct {
+	badges     *mongo.Collection
+	userBadges *mongo.Collection
+}
+
+func NewBadgeRepository(db *mongo.Database) *BadgeRepository {
+	return &BadgeRepository{
+		badges:     db.Collection("badges"),
+		userBadges: db.Collection("user_badges"),
+	}
+}
+
+// EnsureIndexes makes badge slugs unique and lets a user unlock each badge once.
+func (r *BadgeRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.badges.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create badge index: %v", err)
+	}
+	_, err = r.userBadges.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "badge_slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user badge index: %v", err)
+	}
+	return nil
+}
+
+// UpsertBadge creates or updates a badge definition by slug.
+func (r *BadgeRepository) UpsertBadge(ctx context.Context, badge models.Badge) error {
+	_, err := r.badges.UpdateOne(ctx,
+		bson.M{"slug": badge.Slug},
+		bson.M{"$set": bson.M{
+			"name":        badge.Name,
+			"description": badge.Description,
+			"icon_url":    badge.IconURL,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save badge %s: %v", badge.Slug, err)
+	}
+	return nil
+}
+
+// GetBadges returns all badge definitions.
+func (r *BadgeRepository) GetBadges(ctx context.Context) ([]models.Badge, error) {
+	cursor, err := r.badges.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch badges: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var badges []models.Badge
+	if err := cursor.All(ctx, &badges); err != nil {
+		return nil, fmt.Errorf("failed to decode badges: %v", err)
+	}
+	return badges, nil
+}
+
+// GetUserBadges returns the badges a user unlocked, oldest first.
+func (r *BadgeRepository) GetUserBadges(ctx context.Context, userID primitive.ObjectID) ([]models.UserBadge, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "unlocked_at", Value: 1}})
+	cursor, err := r.userBadges.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user badges: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var badges []models.UserBadge
+	if err := cursor.All(ctx, &badges); err != nil {
+		return nil, fmt.Errorf("failed to decode user badges: %v", err)
+	}
+	return badges, nil
+}
+
+// AwardBadge records that the user unlocked a badge. It reports false if the
+// user already had it.
+func (r *BadgeRepository) AwardBadge(ctx context.Context, userID primitive.ObjectID, slug string, at time.Time) (bool, error) {
+	_, err := r.userBadges.InsertOne(ctx, models.UserBadge{UserID: userID, BadgeSlug: slug, UnlockedAt: at})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to award badge: %v", err)
+	}
+	return true, nil
+}
+
+// DeleteUserBadges removes every badge a user unlocked.
+func (r *BadgeRepository) DeleteUserBadges(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := r.userBadges.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete user badges: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CategoryRepository stores user-defined goal categories.
+type CategoryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCategoryRepository(db *mongo.Database) *CategoryRepository {
+	return &CategoryRepository{collection: db.Collection("user_categories")}
+}
+
+// EnsureIndexes keeps category names unique per user.
+func (r *CategoryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user category index: %v", err)
+	}
+	return nil
+}
+
+// CreateCategory inserts a category. It returns an error satisfying
+// mongo.IsDuplicateKeyError if the user already has one with that name.
+func (r *CategoryRepository) CreateCategory(ctx context.Context, category *models.UserCategory) (*models.UserCategory, error) {
+	category.CreatedAt = time.Now()
+	result, err := r.collection.InsertOne(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	category.ID = result.InsertedID.(primitive.ObjectID)
+	return category, nil
+}
+
+// GetCategories returns the user's categories sorted by name.
+func (r *CategoryRepository) GetCategories(ctx context.Context, userID primitive.ObjectID) ([]models.UserCategory, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var categories []models.UserCategory
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %v", err)
+	}
+	return categories, nil
+}
+
+// GetCategory returns one of the user's categories by ID.
+func (r *CategoryRepository) GetCategory(ctx context.Context, userID, id primitive.ObjectID) (*models.UserCategory, error) {
+	var category models.UserCategory
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID}).Decode(&category); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// HasCategory reports whether the user defined a category with this name.
+func (r *CategoryRepository) HasCategory(ctx context.Context, userID primitive.ObjectID, name string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "name": name}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to look up category: %v", err)
+	}
+	return count > 0, nil
+}
+
+// RenameCategory changes a category's name.
+func (r *CategoryRepository) RenameCategory(ctx context.Context, id primitive.ObjectID, name string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"name": name}})
+	return err
+}
+
+// DeleteCategory removes a category.
+func (r *CategoryRepository) DeleteCategory(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("failed to delete category: %v", err)
+	}
+	return nil
+}
+
+// DeleteUserCategories removes every category a user defined.
+func (r *CategoryRepository) DeleteUserCategories(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete user categories: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrReactionLimit is returned by AddReaction when the message already has
+// the maximum number of different emoji, or was deleted.
+var ErrReactionLimit = errors.New("message has too many different reactions")
+
+// ChatRepository stores direct messages, group chats and group messages.
+type ChatRepository struct {
+	messages      *mongo.Collection
+	groups        *mongo.Collection
+	groupMessages *mongo.Collection
+}
+
+func NewChatRepository(db *mongo.Database) *ChatRepository {
+	return &ChatRepository{
+		messages:      db.Collection("chat_messages"),
+		groups:        db.Collection("group_chats"),
+		groupMessages: db.Collection("group_messages"),
+	}
+}
+
+// EnsureIndexes creates the indexes used to page through a conversation, to
+// list a user's groups and to page through a group's history.
+func (r *ChatRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.messages.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sender_id", Value: 1}, {Key: "receiver_id", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create chat message index: %v", err)
+	}
+	_, err = r.groups.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "members", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create group chat members index: %v", err)
+	}
+	_, err = r.groupMessages.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "group_chat_id", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create group message index: %v", err)
+	}
+	return nil
+}
+
+// CreateMessage stores a direct message.
+func (r *ChatRepository) CreateMessage(ctx context.Context, message *models.Message) (*models.Message, error) {
+	message.CreatedAt = time.Now()
+
+	result, err := r.messages.InsertOne(ctx, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert chat message: %v", err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast inserted ID")
+	}
+	message.ID = insertedID
+
+	return message, nil
+}
+
+// GetChat returns up to limit messages exchanged between two users, newest
+// first, starting before the given cursor. A nil cursor starts from the
+// newest message. Soft-deleted messages are returned as tombstones.
+func (r *ChatRepository) GetChat(ctx context.Context, userID, otherID, before primitive.ObjectID, limit int64) ([]models.Message, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"sender_id": userID, "receiver_id": otherID},
+		bson.M{"sender_id": otherID, "receiver_id": userID},
+	}}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.messages.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chat messages: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	messages := []models.Message{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode chat messages: %v", err)
+	}
+	return messages, nil
+}
+
+// GetMessageByID returns a direct message.
+func (r *ChatRepository) GetMessageByID(ctx context.Context, id primitive.ObjectID) (*models.Message, error) {
+	var message models.Message
+	if err := r.messages.FindOne(ctx, bson.M{"_id": id}).Decode(&message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// SoftDeleteMessage turns a message into a tombstone: its content is cleared
+// and deleted_at is set. It returns the tombstone, or mongo.ErrNoDocuments if
+// senderID did not write the message or it was already deleted.
+func (r *ChatRepository) SoftDeleteMessage(ctx context.Context, messageID, senderID primitive.ObjectID) (*models.Message, error) {
+	var message models.Message
+	err := r.messages.FindOneAndUpdate(ctx,
+		bson.M{"_id": messageID, "sender_id": senderID, "deleted_at": bson.M{"$exists": false}},
+		bson.M{
+			"$set":   bson.M{"type": models.MessageDeleted, "deleted_at": time.Now()},
+			"$unset": bson.M{"text": "", "file_url": "", "reactions": "", "reaction_emoji": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&message)
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// AddReaction records that userID reacted to a message with emoji. A new
+// emoji is only accepted while the message has fewer than maxEmoji different
+// ones. It reports whether anything changed, i.e. false if the user had
+// already reacted with that emoji.
+func (r *ChatRepository) AddReaction(ctx context.Context, messageID, userID primitive.ObjectID, emoji string, maxEmoji int) (bool, error) {
+	result, err := r.messages.UpdateOne(ctx,
+		bson.M{
+			"_id":        messageID,
+			"deleted_at": bson.M{"$exists": false},
+			"$or": bson.A{
+				bson.M{"reaction_emoji": emoji},
+				bson.M{fmt.Sprintf("reaction_emoji.%d", maxEmoji-1): bson.M{"$exists": false}},
+			},
+		},
+		bson.M{"$addToSet": bson.M{
+			"reaction_emoji":     emoji,
+			"reactions." + emoji: userID,
+		}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add reaction: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return false, ErrReactionLimit
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// RemoveReaction takes back userID's emoji reaction on a message, dropping
+// the emoji once nobody uses it. It reports whether the user had reacted.
+func (r *ChatRepository) RemoveReaction(ctx context.Context, messageID, userID primitive.ObjectID, emoji string) (bool, error) {
+	field := "reactions." + emoji
+	result, err := r.messages.UpdateOne(ctx,
+		bson.M{"_id": messageID, field: userID},
+		bson.M{"$pull": bson.M{field: userID}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %v", err)
+	}
+	if result.ModifiedCount == 0 {
+		return false, nil
+	}
+
+	// A reaction added in between keeps the emoji alive
+	_, err = r.messages.UpdateOne(ctx,
+		bson.M{"_id": messageID, field: bson.M{"$size": 0}},
+		bson.M{
+			"$unset": bson.M{field: ""},
+			"$pull":  bson.M{"reaction_emoji": emoji},
+		},
+	)
+	if err != nil {
+		return true, fmt.Errorf("failed to drop unused reaction: %v", err)
+	}
+	return true, nil
+}
+
+// unreadFilter matches the live messages senderID sent to readerID that
+// readerID has not read yet.
+func unreadFilter(readerID, senderID primitive.ObjectID) bson.M {
+	return bson.M{
+		"receiver_id": readerID,
+		"sender_id":   senderID,
+		"read_at":     nil,
+		"deleted_at":  bson.M{"$exists": false},
+	}
+}
+
+// MarkMessagesAsRead sets read_at on every unread message senderID sent to
+// readerID. It returns how many messages were newly marked.
+func (r *ChatRepository) MarkMessagesAsRead(ctx context.Context, readerID, senderID primitive.ObjectID, at time.Time) (int64, error) {
+	result, err := r.messages.UpdateMany(ctx, unreadFilter(readerID, senderID), bson.M{"$set": bson.M{"read_at": at}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark chat messages as read: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// CountUnreadMessages counts the messages senderID sent to readerID that
+// readerID has not read.
+func (r *ChatRepository) CountUnreadMessages(ctx context.Context, readerID, senderID primitive.ObjectID) (int64, error) {
+	count, err := r.messages.CountDocuments(ctx, unreadFilter(readerID, senderID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread chat messages: %v", err)
+	}
+	return count, nil
+}
+
+// CreateGroupChat stores a new group chat.
+func (r *ChatRepository) CreateGroupChat(ctx context.Context, group *models.GroupChat) (*models.GroupChat, error) {
+	group.CreatedAt = time.Now()
+
+	result, err := r.groups.InsertOne(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert group chat: %v", err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast inserted ID")
+	}
+	group.ID = insertedID
+
+	return group, nil
+}
+
+// GetGroupChatByID returns a group chat.
+func (r *ChatRepository) GetGroupChatByID(ctx context.Context, id primitive.ObjectID) (*models.GroupChat, error) {
+	var group models.GroupChat
+	if err := r.groups.FindOne(ctx, bson.M{"_id": id}).Decode(&group); err != nil {
+		return nil, fmt.Errorf("failed to fetch group chat: %v", err)
+	}
+	return &group, nil
+}
+
+// GetGroupChatsByMember returns the group chats the user belongs to, newest first.
+func (r *ChatRepository) GetGroupChatsByMember(ctx context.Context, userID primitive.ObjectID) ([]models.GroupChat, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.groups.Find(ctx, bson.M{"members": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch group chats: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	groups := []models.GroupChat{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode group chats: %v", err)
+	}
+	return groups, nil
+}
+
+// AddGroupMembers adds users to a group chat, skipping existing members, and
+// returns the updated group.
+func (r *ChatRepository) AddGroupMembers(ctx context.Context, id primitive.ObjectID, userIDs []primitive.ObjectID) (*models.GroupChat, error) {
+	var group models.GroupChat
+	err := r.groups.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$addToSet": bson.M{"members": bson.M{"$each": userIDs}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add group members: %v", err)
+	}
+	return &group, nil
+}
+
+// RemoveGroupMember removes a user from a group chat. It reports whether the
+// user was a member.
+func (r *ChatRepository) RemoveGroupMember(ctx context.Context, id, userID primitive.ObjectID) (bool, error) {
+	result, err := r.groups.UpdateOne(ctx,
+		bson.M{"_id": id, "members": userID},
+		bson.M{"$pull": bson.M{"members": userID}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove group member: %v", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// CreateGroupMessage stores a message posted to a group chat.
+func (r *ChatRepository) CreateGroupMessage(ctx context.Context, message *models.GroupMessage) (*models.GroupMessage, error) {
+	message.CreatedAt = time.Now()
+
+	result, err := r.groupMessages.InsertOne(ctx, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert group message: %v", err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast inserted ID")
+	}
+	message.ID = insertedID
+
+	return message, nil
+}
+
+// GetGroupMessages returns up to limit messages of a group chat, newest first,
+// starting before the given cursor. A nil cursor starts from the newest message.
+func (r *ChatRepository) GetGroupMessages(ctx context.Context, groupID, before primitive.ObjectID, limit int64) ([]models.GroupMessage, error) {
+	filter := bson.M{"group_chat_id": groupID}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+
+	cursor, err := r.groupMessages.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch group messages: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	messages := []models.GroupMessage{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode group messages: %v", err)
+	}
+	return messages, nil
+}
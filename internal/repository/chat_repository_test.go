@@ -0,0 +1,178 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func seedMessage(t *testing.T, chats *repository.ChatRepository, senderID, receiverID primitive.ObjectID, text string) *models.Message {
+	t.Helper()
+	message, err := chats.CreateMessage(context.Background(), &models.Message{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Type:       models.MessageText,
+		Text:       text,
+	})
+	if err != nil {
+		t.Fatalf("failed to seed message: %v", err)
+	}
+	return message
+}
+
+func TestGetChatReturnsOnlyTheConversation(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	chats := repository.NewChatRepository(repos.DB)
+	ctx := context.Background()
+	alice, bob, carol := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	first := seedMessage(t, chats, alice, bob, "hi bob")
+	second := seedMessage(t, chats, bob, alice, "hi alice")
+	seedMessage(t, chats, alice, carol, "hi carol")
+	third := seedMessage(t, chats, alice, bob, "how are you")
+
+	messages, err := chats.GetChat(ctx, bob, alice, primitive.NilObjectID, 10)
+	if err != nil {
+		t.Fatalf("GetChat: %v", err)
+	}
+	want := []primitive.ObjectID{third.ID, second.ID, first.ID}
+	if len(messages) != len(want) {
+		t.Fatalf("got %d messages, want %d", len(messages), len(want))
+	}
+	for i, id := range want {
+		if messages[i].ID != id {
+			t.Errorf("message %d = %q, want newest first", i, messages[i].Text)
+		}
+	}
+
+	older, err := chats.GetChat(ctx, alice, bob, second.ID, 10)
+	if err != nil {
+		t.Fatalf("GetChat with cursor: %v", err)
+	}
+	if len(older) != 1 || older[0].ID != first.ID {
+		t.Errorf("got %d messages before the cursor, want only the first one", len(older))
+	}
+}
+
+func TestSoftDeleteMessage(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	chats := repository.NewChatRepository(repos.DB)
+	ctx := context.Background()
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	message := seedMessage(t, chats, alice, bob, "oops")
+
+	if _, err := chats.SoftDeleteMessage(ctx, message.ID, bob); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Fatalf("err = %v, want mongo.ErrNoDocuments when the receiver deletes", err)
+	}
+
+	tombstone, err := chats.SoftDeleteMessage(ctx, message.ID, alice)
+	if err != nil {
+		t.Fatalf("SoftDeleteMessage: %v", err)
+	}
+	if tombstone.Type != models.MessageDeleted || tombstone.Text != "" || tombstone.DeletedAt == nil {
+		t.Errorf("tombstone = %+v, want type deleted, no text and deleted_at set", tombstone)
+	}
+
+	if _, err := chats.SoftDeleteMessage(ctx, message.ID, alice); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("err = %v, want mongo.ErrNoDocuments for a second delete", err)
+	}
+
+	messages, err := chats.GetChat(ctx, bob, alice, primitive.NilObjectID, 10)
+	if err != nil {
+		t.Fatalf("GetChat: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Type != models.MessageDeleted {
+		t.Errorf("got %+v, want the tombstone in the history", messages)
+	}
+}
+
+func TestMessageReactions(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	chats := repository.NewChatRepository(repos.DB)
+	ctx := context.Background()
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	message := seedMessage(t, chats, alice, bob, "news")
+
+	for _, userID := range []primitive.ObjectID{alice, bob, bob} {
+		if _, err := chats.AddReaction(ctx, message.ID, userID, "👍", 2); err != nil {
+			t.Fatalf("AddReaction: %v", err)
+		}
+	}
+	if _, err := chats.AddReaction(ctx, message.ID, bob, "🎉", 2); err != nil {
+		t.Fatalf("AddReaction second emoji: %v", err)
+	}
+	if _, err := chats.AddReaction(ctx, message.ID, bob, "🔥", 2); !errors.Is(err, repository.ErrReactionLimit) {
+		t.Errorf("err = %v, want ErrReactionLimit past the emoji limit", err)
+	}
+
+	stored, err := chats.GetMessageByID(ctx, message.ID)
+	if err != nil {
+		t.Fatalf("GetMessageByID: %v", err)
+	}
+	if got := stored.Reactions["👍"]; len(got) != 2 {
+		t.Errorf("👍 reactions = %v, want alice and bob once each", got)
+	}
+
+	if removed, err := chats.RemoveReaction(ctx, message.ID, bob, "🎉"); err != nil || !removed {
+		t.Fatalf("RemoveReaction = %v, %v, want true", removed, err)
+	}
+	stored, err = chats.GetMessageByID(ctx, message.ID)
+	if err != nil {
+		t.Fatalf("GetMessageByID: %v", err)
+	}
+	if _, ok := stored.Reactions["🎉"]; ok || len(stored.ReactionEmoji) != 1 {
+		t.Errorf("reactions = %v, emoji = %v, want the unused emoji dropped", stored.Reactions, stored.ReactionEmoji)
+	}
+	if _, err := chats.AddReaction(ctx, message.ID, bob, "🔥", 2); err != nil {
+		t.Errorf("AddReaction after freeing a slot: %v", err)
+	}
+}
+
+func TestMarkMessagesAsRead(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	chats := repository.NewChatRepository(repos.DB)
+	ctx := context.Background()
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	seedMessage(t, chats, alice, bob, "one")
+	seedMessage(t, chats, alice, bob, "two")
+	deleted := seedMessage(t, chats, alice, bob, "three")
+	if _, err := chats.SoftDeleteMessage(ctx, deleted.ID, alice); err != nil {
+		t.Fatalf("SoftDeleteMessage: %v", err)
+	}
+	seedMessage(t, chats, bob, alice, "reply")
+
+	unread, err := chats.CountUnreadMessages(ctx, bob, alice)
+	if err != nil {
+		t.Fatalf("CountUnreadMessages: %v", err)
+	}
+	if unread != 2 {
+		t.Errorf("unread = %d, want 2 without the deleted message", unread)
+	}
+
+	marked, err := chats.MarkMessagesAsRead(ctx, bob, alice, time.Now())
+	if err != nil {
+		t.Fatalf("MarkMessagesAsRead: %v", err)
+	}
+	if marked != 2 {
+		t.Errorf("marked = %d, want 2", marked)
+	}
+	if marked, _ := chats.MarkMessagesAsRead(ctx, bob, alice, time.Now()); marked != 0 {
+		t.Errorf("second call marked %d messages, want 0", marked)
+	}
+
+	// Bob's own reply stays unread for alice
+	if unread, _ := chats.CountUnreadMessages(ctx, alice, bob); unread != 1 {
+		t.Errorf("alice unread = %d, want 1", unread)
+	}
+	if unread, _ := chats.CountUnreadMessages(ctx, bob, alice); unread != 0 {
+		t.Errorf("bob unread = %d after reading, want 0", unread)
+	}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CommentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCommentRepository(db *mongo.Database) *CommentRepository {
+	return &CommentRepository{
+		collection: db.Collection("comments"),
+	}
+}
+
+func (r *CommentRepository) CreateComment(ctx context.Context, comment *models.Comment) (*models.Comment, error) {
+	comment.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, comment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert comment: %v", err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast inserted ID")
+	}
+	comment.ID = insertedID
+
+	return comment, nil
+}
+
+// GetCommentByID returns a comment that has not been deleted.
+func (r *CommentRepository) GetCommentByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
+	var comment models.Comment
+	filter := bson.M{"_id": id, "deleted_at": nil}
+	if err := r.collection.FindOne(ctx, filter).Decode(&comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// GetCommentsByGoal returns up to limit live comments on a goal, oldest first,
+// starting after the given cursor. A nil cursor starts from the beginning.
+func (r *CommentRepository) GetCommentsByGoal(ctx context.Context, goalID, cursor primitive.ObjectID, limit int64) ([]models.Comment, error) {
+	filter := bson.M{"goal_id": goalID, "deleted_at": nil}
+	if !cursor.IsZero() {
+		filter["_id"] = bson.M{"$gt": cursor}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+
+	cur, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch comments: %v", err)
+	}
+	defer cur.Close(ctx)
+
+	comments := []models.Comment{}
+	if err := cur.All(ctx, &comments); err != nil {
+		return nil, fmt.Errorf("failed to decode comments: %v", err)
+	}
+	return comments, nil
+}
+
+// SoftDeleteComment hides a comment from the thread without removing it.
+func (r *CommentRepository) SoftDeleteComment(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %v", err)
+	}
+	return nil
+}
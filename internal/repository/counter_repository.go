@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CounterRepository stores one user_counters document per user.
+type CounterRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCounterRepository(db *mongo.Database) *CounterRepository {
+	return &CounterRepository{collection: db.Collection("user_counters")}
+}
+
+// EnsureIndexes makes sure there is at most one counters document per user.
+func (r *CounterRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user counters index: %v", err)
+	}
+	return nil
+}
+
+// Increment atomically adds delta to a counter field, creating the document if needed.
+func (r *CounterRepository) Increment(ctx context.Context, userID primitive.ObjectID, field string, delta int64) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"user_id": userID},
+		bson.M{
+			"$inc": bson.M{field: delta},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update %s counter: %v", field, err)
+	}
+	return nil
+}
+
+// Get returns the user's counters, or mongo.ErrNoDocuments if none exist yet.
+func (r *CounterRepository) Get(ctx context.Context, userID primitive.ObjectID) (*models.UserCounters, error) {
+	var counters models.UserCounters
+	if err := r.collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&counters); err != nil {
+		return nil, err
+	}
+	return &counters, nil
+}
+
+// GetMany returns the counters of the given users, keyed by user ID. Users
+// without a counters document are missing from the map.
+func (r *CounterRepository) GetMany(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]models.UserCounters, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": bson.M{"$in": userIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user counters: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var all []models.UserCounters
+	if err := cursor.All(ctx, &all); err != nil {
+		return nil, fmt.Errorf("failed to decode user counters: %v", err)
+	}
+	byUser := make(map[primitive.ObjectID]models.UserCounters, len(all))
+	for _, counters := range all {
+		byUser[counters.UserID] = counters
+	}
+	return byUser, nil
+}
+
+// Replace overwrites the user's counters with freshly computed values.
+func (r *CounterRepository) Replace(ctx context.Context, counters *models.UserCounters) error {
+	counters.UpdatedAt = time.Now()
+	_, err := r.collection.ReplaceOne(ctx,
+		bson.M{"user_id": counters.UserID},
+		counters,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save user counters: %v", err)
+	}
+	return nil
+}
+
+// DeleteByUserID removes the user's counters.
+func (r *CounterRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete user counters: %v", err)
+	}
+	return nil
+}
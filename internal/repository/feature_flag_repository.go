@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FeatureFlagRepository struct {
+	collection *mongo.Collection
+}
+
+func NewFeatureFlagRepository(db *mongo.Database) *FeatureFlagRepository {
+	return &FeatureFlagRepository{
+		collection: db.Collection("feature_flags"),
+	}
+}
+
+// GetAllFlags returns every defined feature flag
+func (r *FeatureFlagRepository) GetAllFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feature flags: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var flags []models.FeatureFlag
+	if err := cursor.All(ctx, &flags); err != nil {
+		return nil, fmt.Errorf("failed to decode feature flags: %v", err)
+	}
+	return flags, nil
+}
+
+// GetFlag returns a single flag by name
+func (r *FeatureFlagRepository) GetFlag(ctx context.Context, name string) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	if err := r.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&flag); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// UpsertFlag creates or replaces a flag
+func (r *FeatureFlagRepository) UpsertFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	flag.UpdatedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": flag.Name}, flag, options.Replace().SetUpsert(true))
+	if err != nil {
+		logrus.WithError(err).WithField("flag", flag.Name).Error("Failed to save feature flag")
+		return fmt.Errorf("failed to save feature flag: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FriendInvitationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewFriendInvitationRepository(db *mongo.Database) *FriendInvitationRepository {
+	return &FriendInvitationRepository{
+		collection: db.Collection("friend_invitations"),
+	}
+}
+
+// EnsureIndexes makes tokens unique and lets MongoDB drop invitations once expired.
+func (r *FriendInvitationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create friend invitation indexes: %v", err)
+	}
+	return nil
+}
+
+// CreateInvitation stores a new invitation.
+func (r *FriendInvitationRepository) CreateInvitation(ctx context.Context, inv *models.FriendInvitation) (*models.FriendInvitation, error) {
+	result, err := r.collection.InsertOne(ctx, inv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create friend invitation: %v", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		inv.ID = id
+	}
+	return inv, nil
+}
+
+// GetInvitationByToken returns the invitation sent with token.
+func (r *FriendInvitationRepository) GetInvitationByToken(ctx context.Context, token string) (*models.FriendInvitation, error) {
+	var inv models.FriendInvitation
+	if err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&inv); err != nil {
+		return nil, fmt.Errorf("failed to find friend invitation: %v", err)
+	}
+	return &inv, nil
+}
+
+// MarkAccepted records that userID registered with the invitation. It
+// reports false when the invitation was already used or has expired, so each
+// token makes one friendship at most.
+func (r *FriendInvitationRepository) MarkAccepted(ctx context.Context, id, userID primitive.ObjectID, now time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "accepted_by": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"accepted_by": userID}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to accept friend invitation: %v", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type FriendRepository struct {
@@ -39,6 +40,42 @@ func (r *FriendRepository) CreateRequest(ctx context.Context, req *models.Friend
 	return req, nil
 }
 
+// EnsureIndexes creates the indexes the friend request queries rely on.
+func (r *FriendRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "receiver_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create friend request index: %v", err)
+	}
+	return nil
+}
+
+// GetPendingRequestsPage returns one page of the receiver's pending requests
+// ordered by creation time, newest first unless oldestFirst is set.
+func (r *FriendRepository) GetPendingRequestsPage(ctx context.Context, receiverID primitive.ObjectID, oldestFirst bool, offset, limit int64) ([]models.FriendRequest, error) {
+	order := -1
+	if oldestFirst {
+		order = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: order}, {Key: "_id", Value: order}}).
+		SetSkip(offset).
+		SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, bson.M{"receiver_id": receiverID, "status": "pending"}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find friend requests: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	requests := []models.FriendRequest{}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, fmt.Errorf("failed to decode friend requests: %v", err)
+	}
+	return requests, nil
+}
+
 func (r *FriendRepository) GetRequestsByReceiver(ctx context.Context, receiverID primitive.ObjectID) ([]models.FriendRequest, error) {
 	filter := bson.M{"receiver_id": receiverID, "status": "pending"}
 	cursor, err := r.collection.Find(ctx, filter)
@@ -59,18 +96,32 @@ func (r *FriendRepository) GetRequestsByReceiver(ctx context.Context, receiverID
 	return requests, nil
 }
 
+// UpdateRequestStatus answers a pending request. It fails if the request was
+// already answered, so concurrent responses are applied only once.
 func (r *FriendRepository) UpdateRequestStatus(ctx context.Context, id primitive.ObjectID, status string) error {
-	_, err := r.collection.UpdateOne(
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": id},
+		bson.M{"_id": id, "status": "pending"},
 		bson.M{"$set": bson.M{"status": status}},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update request status: %v", err)
 	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("request already responded to")
+	}
 	return nil
 }
 
+// CountPendingRequests returns how many requests wait for the receiver's answer.
+func (r *FriendRepository) CountPendingRequests(ctx context.Context, receiverID primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"receiver_id": receiverID, "status": "pending"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending requests: %v", err)
+	}
+	return count, nil
+}
+
 func (r *FriendRepository) GetFriends(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
 	filter := bson.M{
 		"$or": []bson.M{
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrDuplicateInvitation is returned when the invitee already has a pending
+// invitation to the same goal.
+var ErrDuplicateInvitation = errors.New("a pending invitation already exists for this user")
+
+// ErrInvitationNotPending is returned when responding to an invitation that
+// was already accepted or declined.
+var ErrInvitationNotPending = errors.New("invitation already responded to")
+
+type GoalInvitationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewGoalInvitationRepository(db *mongo.Database) *GoalInvitationRepository {
+	return &GoalInvitationRepository{
+		collection: db.Collection("goal_invitations"),
+	}
+}
+
+// CreateInvitation stores a pending invitation. The insert is an upsert keyed
+// on the goal, invitee and pending status, so a second invite for the same
+// pair fails with ErrDuplicateInvitation instead of creating another record.
+func (r *GoalInvitationRepository) CreateInvitation(ctx context.Context, inv *models.GoalInvitation) (*models.GoalInvitation, error) {
+	inv.ID = primitive.NewObjectID()
+	inv.Status = models.InvitationStatusPending
+	inv.CreatedAt = time.Now()
+
+	filter := bson.M{
+		"goal_id":    inv.GoalID,
+		"invitee_id": inv.InviteeID,
+		"status":     models.InvitationStatusPending,
+	}
+	update := bson.M{"$setOnInsert": bson.M{
+		"_id":        inv.ID,
+		"goal_name":  inv.GoalName,
+		"inviter_id": inv.InviterID,
+		"role":       inv.Role,
+		"created_at": inv.CreatedAt,
+	}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %v", err)
+	}
+	if result.UpsertedCount == 0 {
+		return nil, ErrDuplicateInvitation
+	}
+	return inv, nil
+}
+
+func (r *GoalInvitationRepository) GetInvitationByID(ctx context.Context, id primitive.ObjectID) (*models.GoalInvitation, error) {
+	var inv models.GoalInvitation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&inv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invitation: %v", err)
+	}
+	return &inv, nil
+}
+
+// GetPendingByInvitee returns the invitations still waiting for the user's answer, newest first.
+func (r *GoalInvitationRepository) GetPendingByInvitee(ctx context.Context, inviteeID primitive.ObjectID) ([]models.GoalInvitation, error) {
+	filter := bson.M{"invitee_id": inviteeID, "status": models.InvitationStatusPending}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invitations: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []models.GoalInvitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, fmt.Errorf("failed to decode invitations: %v", err)
+	}
+	return invitations, nil
+}
+
+// UpdateInvitationStatus moves a pending invitation to its final status. It
+// returns ErrInvitationNotPending if someone already responded to it.
+func (r *GoalInvitationRepository) UpdateInvitationStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.InvitationStatusPending},
+		bson.M{"$set": bson.M{"status": status, "responded_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update invitation status: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrInvitationNotPending
+	}
+	return nil
+}
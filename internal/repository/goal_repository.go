@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
@@ -28,6 +30,7 @@ func NewGoalRepository(db *mongo.Database) *GoalRepository {
 func (r *GoalRepository) CreateGoal(ctx context.Context, goal *models.Goal) (*models.Goal, error) {
 	goal.CreatedAt = time.Now()
 	goal.UpdatedAt = time.Now()
+	goal.SchemaVersion = models.GoalSchemaVersion
 
 	result, err := r.collection.InsertOne(ctx, goal)
 	if err != nil {
@@ -62,78 +65,401 @@ func (r *GoalRepository) GetGoalByID(ctx context.Context, id primitive.ObjectID)
 	return &goal, nil
 }
 
-// UpdateGoal updates an existing goal in the database
+// ErrConflict is returned by UpdateGoal when the stored goal's version no
+// longer matches the one the caller read.
+var ErrConflict = errors.New("goal was updated by someone else")
+
+// versionFilter matches the given goal version. Goals saved before versioning
+// have no version field and count as version 0.
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// nextVersion increments the version inside an update pipeline, where $inc
+// is not available.
+var nextVersion = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}}
+
+// UpdateGoal replaces the goal's fields if its stored version still equals
+// goal.Version, then increments the version. It returns ErrConflict otherwise.
 func (r *GoalRepository) UpdateGoal(ctx context.Context, id primitive.ObjectID, goal *models.Goal) (*models.Goal, error) {
 	goal.UpdatedAt = time.Now()
+	// The whole document is written in the current shape
+	goal.SchemaVersion = models.GoalSchemaVersion
+
+	raw, err := bson.Marshal(goal)
+	if err != nil {
+		return nil, err
+	}
+	var set bson.M
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+	// version is only ever changed through $inc
+	delete(set, "version")
+	delete(set, "_id")
+
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	// A zero completed_at is left out of $set, so clear it explicitly
+	if goal.CompletedAt.IsZero() {
+		update["$unset"] = bson.M{"completed_at": ""}
+	}
 
 	// Update the goal in the database
-	_, err := r.collection.UpdateOne(
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": id},
-		bson.M{"$set": goal},
+		bson.M{"_id": id, "version": versionFilter(goal.Version)},
+		update,
 	)
 	if err != nil {
 		logger.Log.WithError(err).WithField("goal_id", id.Hex()).Error("Failed to update goal")
 		return nil, err
 	}
+	if result.MatchedCount == 0 {
+		logger.Log.WithField("goal_id", id.Hex()).Warn("Goal update rejected, version mismatch")
+		return nil, ErrConflict
+	}
+	goal.Version++
 
 	logger.Log.WithField("goal_id", id.Hex()).Info("Goal updated successfully")
 	return goal, nil
 }
 
-// DeleteGoal deletes a goal from the database by its ID
-func (r *GoalRepository) DeleteGoal(ctx context.Context, id primitive.ObjectID) error {
+// SoftDeleteGoal moves a goal to the trash, remembering its status for a later restore
+func (r *GoalRepository) SoftDeleteGoal(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.SoftDeleteGoals(ctx, []primitive.ObjectID{id})
+	return err
+}
+
+// SoftDeleteGoals moves several goals to the trash in a single update
+func (r *GoalRepository) SoftDeleteGoals(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	now := time.Now()
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status_before_delete": "$status",
+			"status":               "deleted",
+			"deleted_at":           now,
+			"updated_at":           now,
+			"version":              nextVersion,
+		}}},
+	}
+
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "deleted_at": nil},
+		pipeline,
+	)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to move goals to trash")
+		return 0, err
+	}
+
+	logger.Log.WithField("count", result.ModifiedCount).Info("Goals moved to trash")
+	return result.ModifiedCount, nil
+}
+
+// RestoreGoal takes a goal out of the trash and puts back its previous status,
+// or in_progress if it had none.
+// It returns mongo.ErrNoDocuments if the goal is not in the trash.
+func (r *GoalRepository) RestoreGoal(ctx context.Context, id primitive.ObjectID) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$status_before_delete", ""}}, ""}},
+				"in_progress",
+				"$status_before_delete",
+			}},
+			"updated_at": time.Now(),
+			"version":    nextVersion,
+		}}},
+		{{Key: "$unset", Value: bson.A{"deleted_at", "status_before_delete", "purge_warned_at"}}},
+	}
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}},
+		pipeline,
+	)
+	if err != nil {
+		logger.Log.WithError(err).WithField("goal_id", id.Hex()).Error("Failed to restore goal")
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	logger.Log.WithField("goal_id", id.Hex()).Info("Goal restored from trash")
+	return nil
+}
+
+// PermanentDeleteGoal removes a goal from the database by its ID
+func (r *GoalRepository) PermanentDeleteGoal(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
 		logger.Log.WithError(err).WithField("goal_id", id.Hex()).Error("Failed to delete goal")
 		return err
 	}
 
-	logger.Log.WithField("goal_id", id.Hex()).Info("Goal deleted successfully")
+	logger.Log.WithField("goal_id", id.Hex()).Info("Goal permanently deleted")
 	return nil
 }
 
-// GetAllGoals fetches all goals from the database
-func (r *GoalRepository) GetAllGoals(ctx context.Context, limit int64) ([]models.Goal, error) {
-	var goals []models.Goal
+// GetDeletedGoals returns the user's goals that are in the trash, most recently deleted first
+func (r *GoalRepository) GetDeletedGoals(ctx context.Context, userID primitive.ObjectID) ([]models.Goal, error) {
+	filter := bson.M{"user_id": userID, "deleted_at": bson.M{"$ne": nil}}
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}})
 
-	findOptions := options.Find().SetLimit(limit)
-	cursor, err := r.collection.Find(ctx, bson.M{}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to fetch all goals")
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Error("Failed to fetch deleted goals")
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var goal models.Goal
-		if err := cursor.Decode(&goal); err != nil {
-			logger.Log.WithError(err).Error("Failed to decode goal")
-			return nil, err
-		}
-		goals = append(goals, goal)
+	var goals []models.Goal
+	if err := cursor.All(ctx, &goals); err != nil {
+		logger.Log.WithError(err).Error("Failed to decode deleted goals")
+		return nil, err
 	}
-
-	logger.Log.WithField("count", len(goals)).Info("All goals fetched successfully")
 	return goals, nil
 }
 
+// PurgeDeletedGoals permanently removes goals that were moved to the trash before the cutoff
+func (r *GoalRepository) PurgeDeletedGoals(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": deletedBefore}})
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to purge deleted goals")
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// CountDeletedGoals returns how many of the user's goals are in the trash.
+func (r *GoalRepository) CountDeletedGoals(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "deleted_at": bson.M{"$ne": nil}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count deleted goals: %v", err)
+	}
+	return count, nil
+}
+
+// PurgeOldestDeletedGoals permanently removes the user's n longest-trashed goals.
+func (r *GoalRepository) PurgeOldestDeletedGoals(ctx context.Context, userID primitive.ObjectID, n int64) (int64, error) {
+	filter := bson.M{"user_id": userID, "deleted_at": bson.M{"$ne": nil}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "deleted_at", Value: 1}}).
+		SetLimit(n).
+		SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find oldest deleted goals: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var oldest []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &oldest); err != nil {
+		return 0, fmt.Errorf("failed to decode oldest deleted goals: %v", err)
+	}
+	if len(oldest) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(oldest))
+	for i, goal := range oldest {
+		ids[i] = goal.ID
+	}
+	// Re-check deleted_at so a goal restored in the meantime is kept
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$ne": nil}})
+	if err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Error("Failed to purge oldest deleted goals")
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// DeleteTrashedGoals permanently removes every goal in the user's trash.
+func (r *GoalRepository) DeleteTrashedGoals(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID, "deleted_at": bson.M{"$ne": nil}})
+	if err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Error("Failed to empty trash")
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// TrashPurgeWarning lists one user's trashed goals that are due for purging
+// and whose owner has not been warned yet.
+type TrashPurgeWarning struct {
+	UserID  primitive.ObjectID   `bson:"_id"`
+	GoalIDs []primitive.ObjectID `bson:"goal_ids"`
+}
+
+// GetTrashPurgeWarnings groups, per owner, the trashed goals deleted before
+// the cutoff that have no purge_warned_at yet.
+func (r *GoalRepository) GetTrashPurgeWarnings(ctx context.Context, deletedBefore time.Time) ([]TrashPurgeWarning, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"deleted_at":      bson.M{"$ne": nil, "$lt": deletedBefore},
+			"purge_warned_at": nil,
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$user_id",
+			"goal_ids": bson.M{"$push": "$_id"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find goals due for purge: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var warnings []TrashPurgeWarning
+	if err := cursor.All(ctx, &warnings); err != nil {
+		return nil, fmt.Errorf("failed to decode goals due for purge: %v", err)
+	}
+	return warnings, nil
+}
+
+// MarkPurgeWarned records that the owners of the given trashed goals were
+// warned, so the warning is only sent once per goal. It returns how many
+// goals were still unwarned.
+func (r *GoalRepository) MarkPurgeWarned(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$ne": nil}, "purge_warned_at": nil},
+		bson.M{"$set": bson.M{"purge_warned_at": time.Now()}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark goals as warned: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// AdminGoalFilter narrows the admin goal listing. Zero fields match every goal;
+// CreatedTo is exclusive.
+type AdminGoalFilter struct {
+	UserID      primitive.ObjectID
+	Status      string
+	Category    string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+func (f AdminGoalFilter) query() bson.M {
+	query := bson.M{}
+	if !f.UserID.IsZero() {
+		query["user_id"] = f.UserID
+	}
+	if f.Status != "" {
+		query["status"] = f.Status
+	}
+	if f.Category != "" {
+		query["category"] = f.Category
+	}
+	created := bson.M{}
+	if !f.CreatedFrom.IsZero() {
+		created["$gte"] = f.CreatedFrom
+	}
+	if !f.CreatedTo.IsZero() {
+		created["$lt"] = f.CreatedTo
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+	return query
+}
+
+// GetAdminGoals returns one page of the goals matching the filter, newest
+// first, and the total number of matching goals.
+func (r *GoalRepository) GetAdminGoals(ctx context.Context, filter AdminGoalFilter, skip, limit int64) ([]models.GoalWithOwner, int64, error) {
+	return r.getGoalPageWithOwners(ctx, filter.query(), skip, limit)
+}
+
+// GetSharedGoals returns one page of the goals the user collaborates on but
+// does not own, newest first, and the total number of such goals.
+// Closed goals are left out unless includeClosed is set.
+func (r *GoalRepository) GetSharedGoals(ctx context.Context, userID primitive.ObjectID, category string, includeClosed bool, skip, limit int64) ([]models.GoalWithOwner, int64, error) {
+	query := bson.M{
+		"collaborators.user_id": userID,
+		"user_id":               bson.M{"$ne": userID},
+		"deleted_at":            nil,
+	}
+	if !includeClosed {
+		query["status"] = bson.M{"$ne": "closed"}
+	}
+	if category != "" {
+		query["category"] = category
+	}
+	return r.getGoalPageWithOwners(ctx, query, skip, limit)
+}
+
+// getGoalPageWithOwners returns one page of the goals matching query, newest
+// first, with the owner's username looked up from users, and the total
+// number of matching goals.
+func (r *GoalRepository) getGoalPageWithOwners(ctx context.Context, query bson.M, skip, limit int64) ([]models.GoalWithOwner, int64, error) {
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count goals: %v", err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: query}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "owner",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"owner_username": bson.M{"$arrayElemAt": bson.A{"$owner.username", 0}},
+		}}},
+		{{Key: "$project", Value: bson.M{"owner": 0}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch goals: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	goals := []models.GoalWithOwner{}
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode goals: %v", err)
+	}
+	return goals, total, nil
+}
+
 // GetGoals fetches goals for a specific user with an optional category filter.
 // It includes both owned and collaborated goals.
-func (r *GoalRepository) GetGoals(ctx context.Context, userID primitive.ObjectID, category string) ([]models.Goal, error) {
+// Closed goals are left out unless includeClosed is set.
+func (r *GoalRepository) GetGoals(ctx context.Context, userID primitive.ObjectID, category, tag string, includeClosed bool) ([]models.Goal, error) {
 	var goals []models.Goal
 
 	// Build the filter to include either owned or collaborated goals
 	filter := bson.M{
 		"$or": []bson.M{
 			{"user_id": userID},
-			{"collaborators": userID},
+			{"collaborators.user_id": userID},
 		},
 	}
 
+	filter["deleted_at"] = nil
+	if !includeClosed {
+		filter["status"] = bson.M{"$ne": "closed"}
+	}
+
 	if category != "" {
 		filter["category"] = category
 	}
+	if tag != "" {
+		filter["tags"] = tag
+	}
 
 	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
@@ -159,12 +485,14 @@ func (r *GoalRepository) GetGoals(ctx context.Context, userID primitive.ObjectID
 	return goals, nil
 }
 
-// AddCollaborator adds a collaborator to a goal by updating the collaborators array.
-func (r *GoalRepository) AddCollaborator(ctx context.Context, goalID, collaboratorID primitive.ObjectID) error {
-	filter := bson.M{"_id": goalID}
+// AddCollaborator adds a collaborator with the given role to a goal.
+// Users who are already collaborators are left untouched.
+func (r *GoalRepository) AddCollaborator(ctx context.Context, goalID, collaboratorID primitive.ObjectID, role string) error {
+	filter := bson.M{"_id": goalID, "collaborators.user_id": bson.M{"$ne": collaboratorID}} // Prevents duplicates
 	update := bson.M{
-		"$addToSet": bson.M{"collaborators": collaboratorID}, // Prevents duplicates
-		"$set":      bson.M{"updated_at": time.Now()},
+		"$push": bson.M{"collaborators": models.Collaborator{UserID: collaboratorID, Role: role}},
+		"$set":  bson.M{"updated_at": time.Now()},
+		"$inc":  bson.M{"version": 1},
 	}
 
 	_, err := r.collection.UpdateOne(ctx, filter, update)
@@ -179,7 +507,724 @@ func (r *GoalRepository) AddCollaborator(ctx context.Context, goalID, collaborat
 	logger.Log.WithFields(map[string]interface{}{
 		"goal_id":         goalID.Hex(),
 		"collaborator_id": collaboratorID.Hex(),
+		"role":            role,
 	}).Info("Collaborator successfully added to goal")
 
 	return nil
 }
+
+// GetGoalsDueWithin fetches unfinished goals that have a goal, step or substep
+// deadline falling between now and now+window. It is meant for background jobs
+// that only care about upcoming deadlines.
+func (r *GoalRepository) GetGoalsDueWithin(ctx context.Context, window time.Duration) ([]models.Goal, error) {
+	now := time.Now()
+	dueRange := bson.M{"$gt": now, "$lte": now.Add(window)}
+
+	filter := bson.M{
+		"status":     bson.M{"$nin": bson.A{"completed", "closed"}},
+		"deleted_at": nil,
+		"$or": []bson.M{
+			{"due_date": dueRange},
+			{"steps": bson.M{"$elemMatch": bson.M{"due_date": dueRange}}},
+			{"steps.substeps": bson.M{"$elemMatch": bson.M{"due_date": dueRange}}},
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to fetch goals due within window")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var goals []models.Goal
+	if err := cursor.All(ctx, &goals); err != nil {
+		logger.Log.WithError(err).Error("Failed to decode goals due within window")
+		return nil, err
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"window": window.String(),
+		"count":  len(goals),
+	}).Info("Goals due within window fetched successfully")
+	return goals, nil
+}
+
+// TransferOwnership sets a new owner and collaborator list on a goal, but only if
+// the goal is still owned by oldOwnerID. It returns mongo.ErrNoDocuments when
+// the ownership changed in the meantime.
+func (r *GoalRepository) TransferOwnership(ctx context.Context, goalID, oldOwnerID, newOwnerID primitive.ObjectID, collaborators []models.Collaborator) error {
+	filter := bson.M{"_id": goalID, "user_id": oldOwnerID}
+	update := bson.M{
+		"$set": bson.M{
+			"user_id":       newOwnerID,
+			"collaborators": collaborators,
+			"updated_at":    time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logger.Log.WithError(err).WithField("goal_id", goalID.Hex()).Error("Failed to transfer goal ownership")
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"goal_id":   goalID.Hex(),
+		"old_owner": oldOwnerID.Hex(),
+		"new_owner": newOwnerID.Hex(),
+	}).Info("Goal ownership transferred successfully")
+	return nil
+}
+
+// UpdateStep sets the given fields on the step with the given name using the
+// positional operator, e.g. updates = bson.M{"due_date": t} sets steps.$.due_date.
+// It returns mongo.ErrNoDocuments when the goal has no step with that name.
+func (r *GoalRepository) UpdateStep(ctx context.Context, goalID primitive.ObjectID, stepName string, updates bson.M) error {
+	set := bson.M{"updated_at": time.Now()}
+	for field, value := range updates {
+		set["steps.$."+field] = value
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": goalID, "steps.name": stepName}, bson.M{"$set": set, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		logger.Log.WithError(err).WithField("goal_id", goalID.Hex()).Error("Failed to update step")
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"goal_id": goalID.Hex(),
+		"step":    stepName,
+	}).Info("Step updated successfully")
+	return nil
+}
+
+// UpdateSubstep sets the given fields on a substep of the named step, addressed
+// by its index inside the step.
+func (r *GoalRepository) UpdateSubstep(ctx context.Context, goalID primitive.ObjectID, stepName string, substepIndex int, updates bson.M) error {
+	set := bson.M{"updated_at": time.Now()}
+	for field, value := range updates {
+		set[fmt.Sprintf("steps.$.substeps.%d.%s", substepIndex, field)] = value
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": goalID, "steps.name": stepName}, bson.M{"$set": set, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		logger.Log.WithError(err).WithField("goal_id", goalID.Hex()).Error("Failed to update substep")
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"goal_id":       goalID.Hex(),
+		"step":          stepName,
+		"substep_index": substepIndex,
+	}).Info("Substep updated successfully")
+	return nil
+}
+
+// CountGoalsByUser returns how many goals the user owns.
+func (r *GoalRepository) CountGoalsByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Error("Failed to count user goals")
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountCompletedGoals returns how many of the user's goals outside the trash are completed.
+func (r *GoalRepository) CountCompletedGoals(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "status": "completed", "deleted_at": nil})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count completed goals: %v", err)
+	}
+	return count, nil
+}
+
+// CountCompletedGoalsWithCollaborators returns how many of the user's
+// completed goals outside the trash have at least n collaborators.
+func (r *GoalRepository) CountCompletedGoalsWithCollaborators(ctx context.Context, userID primitive.ObjectID, n int) (int64, error) {
+	filter := bson.M{"user_id": userID, "status": "completed", "deleted_at": nil}
+	if n > 0 {
+		filter[fmt.Sprintf("collaborators.%d", n-1)] = bson.M{"$exists": true}
+	}
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count completed goals: %v", err)
+	}
+	return count, nil
+}
+
+// CountGoalsByStatus returns how many goals the user owns outside the trash,
+// keyed by status.
+func (r *GoalRepository) CountGoalsByStatus(ctx context.Context, userID primitive.ObjectID) (map[string]int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "deleted_at": nil}}},
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count goals by status: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode goal counts: %v", err)
+	}
+	byStatus := make(map[string]int64, len(groups))
+	for _, g := range groups {
+		if g.Status != "" {
+			byStatus[g.Status] = g.Count
+		}
+	}
+	return byStatus, nil
+}
+
+// CountGoalsOutsideTrash returns how many goals the user owns, not counting trashed ones.
+func (r *GoalRepository) CountGoalsOutsideTrash(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "deleted_at": nil})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count goals: %v", err)
+	}
+	return count, nil
+}
+
+// GetGoalsByIDs fetches all goals whose ID is in ids.
+func (r *GoalRepository) GetGoalsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Goal, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to fetch goals by IDs")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var goals []models.Goal
+	if err := cursor.All(ctx, &goals); err != nil {
+		logger.Log.WithError(err).Error("Failed to decode goals by IDs")
+		return nil, err
+	}
+	return goals, nil
+}
+
+// GetGoalsByOwner returns all goals owned by a user, including trashed ones.
+func (r *GoalRepository) GetGoalsByOwner(ctx context.Context, userID primitive.ObjectID) ([]models.Goal, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch goals: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var goals []models.Goal
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, fmt.Errorf("failed to decode goals: %v", err)
+	}
+	return goals, nil
+}
+
+// DeleteGoalsByOwner permanently removes all goals owned by a user.
+func (r *GoalRepository) DeleteGoalsByOwner(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete goals: %v", err)
+	}
+	return result.DeletedCount, nil
+}
+
+// RemoveCollaboratorEverywhere drops a user from the collaborators of all goals.
+func (r *GoalRepository) RemoveCollaboratorEverywhere(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"collaborators.user_id": userID},
+		bson.M{"$pull": bson.M{"collaborators": bson.M{"user_id": userID}}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove collaborator: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetGoalsBlockedBy returns the live goals that list goalID as a dependency.
+func (r *GoalRepository) GetGoalsBlockedBy(ctx context.Context, goalID primitive.ObjectID) ([]models.Goal, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"blocked_by": goalID, "deleted_at": nil})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dependent goals: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var goals []models.Goal
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, fmt.Errorf("failed to decode dependent goals: %v", err)
+	}
+	return goals, nil
+}
+
+// UpdateGoals sets the given fields on all goals in ids with a single UpdateMany.
+func (r *GoalRepository) UpdateGoals(ctx context.Context, ids []primitive.ObjectID, fields bson.M) (int64, error) {
+	set := bson.M{"updated_at": time.Now()}
+	for k, v := range fields {
+		set[k] = v
+	}
+
+	result, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": set, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to update goals in bulk")
+		return 0, err
+	}
+
+	logger.Log.WithField("count", result.ModifiedCount).Info("Goals updated in bulk")
+	return result.ModifiedCount, nil
+}
+
+// setCompletedAt adds completed_at to an update that already has $set and
+// $unset maps, unsetting it when completedAt is zero.
+func setCompletedAt(update bson.M, completedAt time.Time) {
+	if completedAt.IsZero() {
+		unset, ok := update["$unset"].(bson.M)
+		if !ok {
+			unset = bson.M{}
+			update["$unset"] = unset
+		}
+		unset["completed_at"] = ""
+		return
+	}
+	update["$set"].(bson.M)["completed_at"] = completedAt
+}
+
+// StampCompletedAt records a bulk status change: goals moved to completed
+// get completedAt unless they already have a completion time, any other
+// status clears it.
+func (r *GoalRepository) StampCompletedAt(ctx context.Context, ids []primitive.ObjectID, status string, completedAt time.Time) error {
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	update := bson.M{"$unset": bson.M{"completed_at": ""}, "$inc": bson.M{"version": 1}}
+	if status == "completed" {
+		filter["completed_at"] = nil
+		update = bson.M{"$set": bson.M{"completed_at": completedAt}, "$inc": bson.M{"version": 1}}
+	}
+
+	if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to update completion times: %v", err)
+	}
+	return nil
+}
+
+// GetCompletionDays returns the distinct days, formatted as YYYY-MM-DD in
+// the given timezone and sorted, on which the user completed a substep or
+// one of their goals. Goals in the trash are ignored.
+func (r *GoalRepository) GetCompletionDays(ctx context.Context, userID primitive.ObjectID, timezone string) ([]string, error) {
+	isDate := func(expr interface{}) bson.M {
+		return bson.M{"$eq": bson.A{bson.M{"$type": expr}, "date"}}
+	}
+	substepDates := bson.M{"$reduce": bson.M{
+		"input":        bson.M{"$ifNull": bson.A{"$steps", bson.A{}}},
+		"initialValue": bson.A{},
+		"in": bson.M{"$concatArrays": bson.A{"$$value", bson.M{"$map": bson.M{
+			"input": bson.M{"$filter": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$$this.substeps", bson.A{}}},
+				"as":    "sub",
+				"cond":  bson.M{"$and": bson.A{"$$sub.done", isDate("$$sub.completed_at")}},
+			}},
+			"as": "sub",
+			"in": "$$sub.completed_at",
+		}}}},
+	}}
+	goalDate := bson.M{"$cond": bson.A{isDate("$completed_at"), bson.A{"$completed_at"}, bson.A{}}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "deleted_at": nil}}},
+		{{Key: "$project", Value: bson.M{"dates": bson.M{"$concatArrays": bson.A{goalDate, substepDates}}}}},
+		{{Key: "$unwind", Value: "$dates"}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$dates", "timezone": timezone}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate completion days: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Day string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode completion days: %v", err)
+	}
+
+	days := make([]string, len(rows))
+	for i, row := range rows {
+		days[i] = row.Day
+	}
+	return days, nil
+}
+
+// ErrGoalModified is returned when a conditional goal update finds that the
+// goal was changed by someone else since it was read.
+var ErrGoalModified = errors.New("goal was modified concurrently")
+
+// ReplaceStepsIfUnchanged writes new steps, status and completion time only
+// if the goal's updated_at still equals expectedUpdatedAt, making
+// read-modify-write sequences atomic. It returns ErrGoalModified otherwise.
+// A zero completedAt clears it. The goal version is incremented like any
+// other write.
+func (r *GoalRepository) ReplaceStepsIfUnchanged(ctx context.Context, goalID primitive.ObjectID, expectedUpdatedAt time.Time, steps []models.Step, status string, completedAt time.Time) (time.Time, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"steps":      steps,
+			"status":     status,
+			"updated_at": now,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	setCompletedAt(update, completedAt)
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": goalID, "updated_at": expectedUpdatedAt},
+		update,
+	)
+	if err != nil {
+		logger.Log.WithError(err).WithField("goal_id", goalID.Hex()).Error("Failed to replace goal steps")
+		return time.Time{}, err
+	}
+	if result.MatchedCount == 0 {
+		return time.Time{}, ErrGoalModified
+	}
+	return now, nil
+}
+
+// CloseGoal moves a goal to the closed status and records why.
+func (r *GoalRepository) CloseGoal(ctx context.Context, goalID primitive.ObjectID, reason string, closedAt time.Time) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": goalID},
+		bson.M{
+			"$set": bson.M{
+				"status":        "closed",
+				"closed_reason": reason,
+				"closed_at":     closedAt,
+				"updated_at":    closedAt,
+			},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to close goal: %v", err)
+	}
+	return nil
+}
+
+// CountGoalsInCategory returns how many of the user's goals, including
+// trashed ones, use the category.
+func (r *GoalRepository) CountGoalsInCategory(ctx context.Context, userID primitive.ObjectID, category string) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "category": category})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count goals in category: %v", err)
+	}
+	return count, nil
+}
+
+// ReplaceCategory moves all of the user's goals from one category to another.
+// An empty to clears the category.
+func (r *GoalRepository) ReplaceCategory(ctx context.Context, userID primitive.ObjectID, from, to string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userID, "category": from},
+		bson.M{
+			"$set": bson.M{"category": to, "updated_at": time.Now()},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update goal categories: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// CountPinnedGoals returns how many goals outside the trash the user has pinned.
+func (r *GoalRepository) CountPinnedGoals(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "pinned": true, "deleted_at": nil})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pinned goals: %v", err)
+	}
+	return count, nil
+}
+
+// SetPinned pins or unpins a goal and returns the new updated_at.
+func (r *GoalRepository) SetPinned(ctx context.Context, goalID primitive.ObjectID, pinned bool) (time.Time, error) {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": goalID},
+		bson.M{
+			"$set": bson.M{"pinned": pinned, "updated_at": now},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to update pinned flag: %v", err)
+	}
+	return now, nil
+}
+
+// ReopenGoal gives a closed goal the new status and completion time and
+// clears the closing details.
+func (r *GoalRepository) ReopenGoal(ctx context.Context, goalID primitive.ObjectID, status string, completedAt time.Time) (time.Time, error) {
+	now := time.Now()
+	update := bson.M{
+		"$set":   bson.M{"status": status, "updated_at": now},
+		"$unset": bson.M{"closed_reason": "", "closed_at": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	setCompletedAt(update, completedAt)
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": goalID, "status": "closed"},
+		update,
+	)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to reopen goal: %v", err)
+	}
+	return now, nil
+}
+
+// GetDistinctTags returns every tag used on goals the user owns or collaborates on
+func (r *GoalRepository) GetDistinctTags(ctx context.Context, userID primitive.ObjectID) ([]string, error) {
+	filter := bson.M{
+		"$or": []bson.M{
+			{"user_id": userID},
+			{"collaborators.user_id": userID},
+		},
+	}
+
+	values, err := r.collection.Distinct(ctx, "tags", filter)
+	if err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Error("Failed to fetch distinct goal tags")
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(values))
+	for _, v := range values {
+		if tag, ok := v.(string); ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// SetShareToken stores a share token on the goal, replacing any previous one
+func (r *GoalRepository) SetShareToken(ctx context.Context, goalID primitive.ObjectID, token string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": goalID},
+		bson.M{"$set": bson.M{"share_token": token}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		logger.Log.WithError(err).WithField("goal_id", goalID.Hex()).Error("Failed to set share token")
+		return err
+	}
+	return nil
+}
+
+// ClearShareToken removes the goal's share token
+func (r *GoalRepository) ClearShareToken(ctx context.Context, goalID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": goalID},
+		bson.M{"$unset": bson.M{"share_token": ""}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		logger.Log.WithError(err).WithField("goal_id", goalID.Hex()).Error("Failed to clear share token")
+		return err
+	}
+	return nil
+}
+
+// FindByShareToken returns the goal holding the given share token. Links to
+// goals in the trash stop working until the goal is restored.
+func (r *GoalRepository) FindByShareToken(ctx context.Context, token string) (*models.Goal, error) {
+	var goal models.Goal
+	if err := r.collection.FindOne(ctx, bson.M{"share_token": token, "deleted_at": nil}).Decode(&goal); err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+// AggregateUserStats counts the user's live goals by status and category and
+// averages how long completed goals took, using updated_at as the completion
+// time like GetGoalStats. Streaks are left for the caller to fill in.
+func (r *GoalRepository) AggregateUserStats(ctx context.Context, userID primitive.ObjectID) (*models.UserStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "deleted_at": nil}}},
+		{{Key: "$facet", Value: bson.M{
+			"status": bson.A{
+				bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+			},
+			"category": bson.A{
+				bson.M{"$match": bson.M{"category": bson.M{"$nin": bson.A{nil, ""}}}},
+				bson.M{"$group": bson.M{"_id": "$category", "count": bson.M{"$sum": 1}}},
+			},
+			"duration": bson.A{
+				bson.M{"$match": bson.M{"status": "completed"}},
+				bson.M{"$group": bson.M{
+					"_id":   nil,
+					"avgMs": bson.M{"$avg": bson.M{"$subtract": bson.A{"$updated_at", "$created_at"}}},
+				}},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to aggregate user stats")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type keyCount struct {
+		Key   string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	var result []struct {
+		Status   []keyCount `bson:"status"`
+		Category []keyCount `bson:"category"`
+		Duration []struct {
+			AvgMs float64 `bson:"avgMs"`
+		} `bson:"duration"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	stats := &models.UserStats{GoalsByCategory: map[string]int64{}}
+	if len(result) == 0 {
+		return stats, nil
+	}
+
+	facets := result[0]
+	for _, s := range facets.Status {
+		stats.TotalGoals += s.Count
+		switch s.Key {
+		case "completed":
+			stats.CompletedGoals = s.Count
+		case "in_progress":
+			stats.InProgressGoals = s.Count
+		}
+	}
+	for _, c := range facets.Category {
+		stats.GoalsByCategory[c.Key] = c.Count
+	}
+	if len(facets.Duration) > 0 {
+		stats.AverageDaysToComplete = facets.Duration[0].AvgMs / float64(24*time.Hour/time.Millisecond)
+	}
+	if stats.TotalGoals > 0 {
+		stats.CompletionRatePercent = float64(stats.CompletedGoals) / float64(stats.TotalGoals) * 100
+	}
+	return stats, nil
+}
+
+// GetGoalStats aggregates the live goals matching filter into dashboard
+// statistics in a single $facet query. Completion month and duration are
+// taken from updated_at, which is the last write to a completed goal.
+func (r *GoalRepository) GetGoalStats(ctx context.Context, filter bson.M, completedSince, now time.Time) (*models.GoalStats, error) {
+	match := bson.M{"deleted_at": nil}
+	for k, v := range filter {
+		match[k] = v
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"status": bson.A{
+				bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}},
+			},
+			"category": bson.A{
+				bson.M{"$group": bson.M{"_id": bson.M{"$ifNull": bson.A{"$category", ""}}, "count": bson.M{"$sum": 1}}},
+			},
+			"months": bson.A{
+				bson.M{"$match": bson.M{"status": "completed", "updated_at": bson.M{"$gte": completedSince}}},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$updated_at"}},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+			"duration": bson.A{
+				bson.M{"$match": bson.M{"status": "completed"}},
+				bson.M{"$group": bson.M{
+					"_id":   nil,
+					"avgMs": bson.M{"$avg": bson.M{"$subtract": bson.A{"$updated_at", "$created_at"}}},
+				}},
+			},
+			"overdue": bson.A{
+				bson.M{"$match": bson.M{
+					"due_date": bson.M{"$lt": now},
+					"status":   bson.M{"$nin": bson.A{"completed", "archived", "closed"}},
+				}},
+				bson.M{"$count": "count"},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to aggregate goal stats")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type keyCount struct {
+		Key   string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	var result []struct {
+		Status   []keyCount            `bson:"status"`
+		Category []keyCount            `bson:"category"`
+		Months   []models.MonthlyCount `bson:"months"`
+		Duration []struct {
+			AvgMs float64 `bson:"avgMs"`
+		} `bson:"duration"`
+		Overdue []struct {
+			Count int64 `bson:"count"`
+		} `bson:"overdue"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	stats := &models.GoalStats{
+		ByStatus:          map[string]int64{},
+		ByCategory:        map[string]int64{},
+		CompletedPerMonth: []models.MonthlyCount{},
+	}
+	if len(result) == 0 {
+		return stats, nil
+	}
+
+	facets := result[0]
+	for _, s := range facets.Status {
+		stats.ByStatus[s.Key] = s.Count
+		stats.Total += s.Count
+	}
+	for _, c := range facets.Category {
+		stats.ByCategory[c.Key] = c.Count
+	}
+	if facets.Months != nil {
+		stats.CompletedPerMonth = facets.Months
+	}
+	if len(facets.Duration) > 0 {
+		stats.AvgCompletionDays = facets.Duration[0].AvgMs / float64(24*time.Hour/time.Millisecond)
+	}
+	if len(facets.Overdue) > 0 {
+		stats.Overdue = facets.Overdue[0].Count
+	}
+	return stats, nil
+}
@@ -0,0 +1,320 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func goalNames(goals []models.Goal) map[string]bool {
+	names := make(map[string]bool, len(goals))
+	for _, g := range goals {
+		names[g.Name] = true
+	}
+	return names
+}
+
+// trashGoal moves a goal into the trash as if it had been deleted at deletedAt.
+func trashGoal(t *testing.T, repos *testutil.Repositories, id primitive.ObjectID, deletedAt time.Time) {
+	t.Helper()
+	if _, err := repos.DB.Collection("goals").UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"deleted_at": deletedAt}}); err != nil {
+		t.Fatalf("failed to trash goal: %v", err)
+	}
+}
+
+func TestGetGoalsDueWithin(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+
+	soon := time.Now().Add(2 * time.Hour)
+	later := time.Now().Add(72 * time.Hour)
+
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "goal due", DueDate: soon})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "step due", Steps: []models.Step{
+		{Name: "s1", DueDate: soon, Substeps: []models.Substep{}},
+	}})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "substep due", Steps: []models.Step{
+		{Name: "s1", Substeps: []models.Substep{{Title: "sub", DueDate: soon}}},
+	}})
+
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "due later", DueDate: later})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "overdue", DueDate: time.Now().Add(-time.Hour)})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "no deadline"})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "completed", Status: "completed", DueDate: soon})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "closed", Status: "closed", DueDate: soon})
+	trashed := testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "trashed", DueDate: soon})
+	trashGoal(t, repos, trashed.ID, time.Now())
+
+	goals, err := repos.Goals.GetGoalsDueWithin(ctx, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetGoalsDueWithin: %v", err)
+	}
+
+	got := goalNames(goals)
+	want := []string{"goal due", "step due", "substep due"}
+	if len(goals) != len(want) {
+		t.Fatalf("got %d goals %v, want %v", len(goals), got, want)
+	}
+	for _, name := range want {
+		if !got[name] {
+			t.Errorf("goal %q missing from result %v", name, got)
+		}
+	}
+}
+
+func TestGetGoalsDueWithinEmpty(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	testutil.SeedGoal(t, repos, primitive.NewObjectID(), models.Goal{DueDate: time.Now().Add(48 * time.Hour)})
+
+	goals, err := repos.Goals.GetGoalsDueWithin(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatalf("GetGoalsDueWithin: %v", err)
+	}
+	if len(goals) != 0 {
+		t.Fatalf("got %d goals, want none", len(goals))
+	}
+}
+
+func TestGetGoalsFilters(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+	other := testutil.SeedUser(t, repos, models.User{})
+
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "health run", Category: "Health", Tags: []string{"run"}})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "career", Category: "Career", Tags: []string{"work"}})
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "closed", Category: "Health", Status: "closed"})
+	testutil.SeedGoal(t, repos, other.ID, models.Goal{Name: "shared", Category: "Health", Collaborators: []models.Collaborator{
+		{UserID: owner.ID, Role: models.CollaboratorRoleViewer},
+	}})
+	testutil.SeedGoal(t, repos, other.ID, models.Goal{Name: "foreign", Category: "Health"})
+	trashed := testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "trashed", Category: "Health"})
+	trashGoal(t, repos, trashed.ID, time.Now())
+
+	tests := []struct {
+		name          string
+		category      string
+		tag           string
+		includeClosed bool
+		want          []string
+	}{
+		{name: "owned and collaborated", want: []string{"health run", "career", "shared"}},
+		{name: "include closed", includeClosed: true, want: []string{"health run", "career", "closed", "shared"}},
+		{name: "category", category: "Health", want: []string{"health run", "shared"}},
+		{name: "category with closed", category: "Health", includeClosed: true, want: []string{"health run", "closed", "shared"}},
+		{name: "tag", tag: "work", want: []string{"career"}},
+		{name: "category and tag", category: "Health", tag: "work", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goals, err := repos.Goals.GetGoals(ctx, owner.ID, tt.category, tt.tag, tt.includeClosed)
+			if err != nil {
+				t.Fatalf("GetGoals: %v", err)
+			}
+			got := goalNames(goals)
+			if len(goals) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for _, name := range tt.want {
+				if !got[name] {
+					t.Errorf("goal %q missing from result %v", name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAddCollaboratorIsIdempotent(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+	friend := testutil.SeedUser(t, repos, models.User{})
+	goal := testutil.SeedGoal(t, repos, owner.ID, models.Goal{})
+
+	if err := repos.Goals.AddCollaborator(ctx, goal.ID, friend.ID, models.CollaboratorRoleViewer); err != nil {
+		t.Fatalf("AddCollaborator: %v", err)
+	}
+	// A second call, even with another role, must neither duplicate the entry nor change it.
+	if err := repos.Goals.AddCollaborator(ctx, goal.ID, friend.ID, models.CollaboratorRoleEditor); err != nil {
+		t.Fatalf("AddCollaborator again: %v", err)
+	}
+
+	stored, err := repos.Goals.GetGoalByID(ctx, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	if len(stored.Collaborators) != 1 {
+		t.Fatalf("got %d collaborators, want 1", len(stored.Collaborators))
+	}
+	if c := stored.Collaborators[0]; c.UserID != friend.ID || c.Role != models.CollaboratorRoleViewer {
+		t.Errorf("collaborator = %+v, want %s as viewer", c, friend.ID.Hex())
+	}
+}
+
+func TestGetTrashPurgeWarnings(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	alice := testutil.SeedUser(t, repos, models.User{})
+	bob := testutil.SeedUser(t, repos, models.User{})
+
+	cutoff := time.Now().Add(-27 * 24 * time.Hour)
+	due := map[string]primitive.ObjectID{}
+	for _, seed := range []struct {
+		name  string
+		owner primitive.ObjectID
+	}{{"alice 1", alice.ID}, {"alice 2", alice.ID}, {"bob 1", bob.ID}} {
+		goal := testutil.SeedGoal(t, repos, seed.owner, models.Goal{Name: seed.name})
+		trashGoal(t, repos, goal.ID, cutoff.Add(-time.Hour))
+		due[seed.name] = goal.ID
+	}
+
+	recent := testutil.SeedGoal(t, repos, alice.ID, models.Goal{Name: "recently trashed"})
+	trashGoal(t, repos, recent.ID, cutoff.Add(time.Hour))
+	testutil.SeedGoal(t, repos, alice.ID, models.Goal{Name: "live"})
+	warned := testutil.SeedGoal(t, repos, bob.ID, models.Goal{Name: "already warned"})
+	trashGoal(t, repos, warned.ID, cutoff.Add(-time.Hour))
+	if n, err := repos.Goals.MarkPurgeWarned(ctx, []primitive.ObjectID{warned.ID}); err != nil || n != 1 {
+		t.Fatalf("MarkPurgeWarned = %d, %v", n, err)
+	}
+
+	warnings, err := repos.Goals.GetTrashPurgeWarnings(ctx, cutoff)
+	if err != nil {
+		t.Fatalf("GetTrashPurgeWarnings: %v", err)
+	}
+	got := map[primitive.ObjectID]map[primitive.ObjectID]bool{}
+	for _, w := range warnings {
+		got[w.UserID] = map[primitive.ObjectID]bool{}
+		for _, id := range w.GoalIDs {
+			got[w.UserID][id] = true
+		}
+	}
+	if len(got) != 2 || len(got[alice.ID]) != 2 || len(got[bob.ID]) != 1 {
+		t.Fatalf("warnings = %v, want alice's two goals and bob's one", got)
+	}
+	if !got[alice.ID][due["alice 1"]] || !got[alice.ID][due["alice 2"]] || !got[bob.ID][due["bob 1"]] {
+		t.Errorf("warnings = %v, want the goals trashed before the cutoff", got)
+	}
+}
+
+func TestMarkPurgeWarnedOnlyOnce(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+
+	trashed := testutil.SeedGoal(t, repos, owner.ID, models.Goal{})
+	trashGoal(t, repos, trashed.ID, time.Now())
+	restored := testutil.SeedGoal(t, repos, owner.ID, models.Goal{})
+	ids := []primitive.ObjectID{trashed.ID, restored.ID}
+
+	if n, err := repos.Goals.MarkPurgeWarned(ctx, ids); err != nil || n != 1 {
+		t.Fatalf("MarkPurgeWarned = %d, %v, want only the trashed goal marked", n, err)
+	}
+	if n, err := repos.Goals.MarkPurgeWarned(ctx, ids); err != nil || n != 0 {
+		t.Fatalf("second MarkPurgeWarned = %d, %v, want nothing left to mark", n, err)
+	}
+
+	// Restoring clears the mark, so a goal trashed again gets a new warning
+	if err := repos.Goals.RestoreGoal(ctx, trashed.ID); err != nil {
+		t.Fatalf("RestoreGoal: %v", err)
+	}
+	trashGoal(t, repos, trashed.ID, time.Now())
+	if n, err := repos.Goals.MarkPurgeWarned(ctx, ids); err != nil || n != 1 {
+		t.Fatalf("MarkPurgeWarned after restore = %d, %v, want 1", n, err)
+	}
+}
+
+func TestPurgeOldestDeletedGoals(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+	other := testutil.SeedUser(t, repos, models.User{})
+
+	now := time.Now()
+	for i, name := range []string{"oldest", "older", "newest"} {
+		goal := testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: name})
+		trashGoal(t, repos, goal.ID, now.Add(time.Duration(i-3)*time.Hour))
+	}
+	testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: "live"})
+	foreign := testutil.SeedGoal(t, repos, other.ID, models.Goal{Name: "foreign"})
+	trashGoal(t, repos, foreign.ID, now.Add(-24*time.Hour))
+
+	purged, err := repos.Goals.PurgeOldestDeletedGoals(ctx, owner.ID, 2)
+	if err != nil {
+		t.Fatalf("PurgeOldestDeletedGoals: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("purged = %d, want 2", purged)
+	}
+
+	var left []models.Goal
+	cursor, err := repos.DB.Collection("goals").Find(ctx, bson.M{})
+	if err != nil {
+		t.Fatalf("failed to list goals: %v", err)
+	}
+	if err := cursor.All(ctx, &left); err != nil {
+		t.Fatalf("failed to decode goals: %v", err)
+	}
+	got := goalNames(left)
+	if len(left) != 3 || !got["newest"] || !got["live"] || !got["foreign"] {
+		t.Errorf("goals left = %v, want newest, live and foreign", got)
+	}
+
+	if count, err := repos.Goals.CountDeletedGoals(ctx, owner.ID); err != nil || count != 1 {
+		t.Errorf("CountDeletedGoals = %d, %v, want 1", count, err)
+	}
+}
+
+func TestGoalWritesInvalidateStaleUpdates(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, repos, models.User{})
+	collaborator := testutil.SeedUser(t, repos, models.User{})
+
+	writes := []struct {
+		name  string
+		write func(id primitive.ObjectID) error
+	}{
+		{"add collaborator", func(id primitive.ObjectID) error {
+			return repos.Goals.AddCollaborator(ctx, id, collaborator.ID, models.CollaboratorRoleEditor)
+		}},
+		{"transfer", func(id primitive.ObjectID) error {
+			return repos.Goals.TransferOwnership(ctx, id, owner.ID, collaborator.ID, nil)
+		}},
+		{"set share token", func(id primitive.ObjectID) error {
+			return repos.Goals.SetShareToken(ctx, id, primitive.NewObjectID().Hex())
+		}},
+		{"clear share token", func(id primitive.ObjectID) error {
+			return repos.Goals.ClearShareToken(ctx, id)
+		}},
+		{"stamp completion", func(id primitive.ObjectID) error {
+			return repos.Goals.StampCompletedAt(ctx, []primitive.ObjectID{id}, "completed", time.Now())
+		}},
+		{"trash", func(id primitive.ObjectID) error {
+			return repos.Goals.SoftDeleteGoal(ctx, id)
+		}},
+	}
+	for _, tt := range writes {
+		t.Run(tt.name, func(t *testing.T) {
+			goal := testutil.SeedGoal(t, repos, owner.ID, models.Goal{Name: tt.name})
+			stale, err := repos.Goals.GetGoalByID(ctx, goal.ID)
+			if err != nil {
+				t.Fatalf("GetGoalByID: %v", err)
+			}
+			if err := tt.write(goal.ID); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if _, err := repos.Goals.UpdateGoal(ctx, goal.ID, stale); !errors.Is(err, repository.ErrConflict) {
+				t.Errorf("stale update: err = %v, want ErrConflict", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// jobRunRetention is how long job run records are kept.
+const jobRunRetention = 30 * 24 * time.Hour
+
+type JobRunRepository struct {
+	collection *mongo.Collection
+}
+
+func NewJobRunRepository(db *mongo.Database) *JobRunRepository {
+	return &JobRunRepository{
+		collection: db.Collection("job_runs"),
+	}
+}
+
+// EnsureIndexes supports the latest-run lookups and drops old records.
+func (r *JobRunRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name", Value: 1}, {Key: "started_at", Value: -1}}},
+		{Keys: bson.D{{Key: "started_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(jobRunRetention.Seconds()))},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create job run indexes: %v", err)
+	}
+	return nil
+}
+
+// StartRun records that a job has started and returns the new record.
+func (r *JobRunRepository) StartRun(ctx context.Context, name string) (*models.JobRun, error) {
+	run := &models.JobRun{Name: name, StartedAt: time.Now()}
+	result, err := r.collection.InsertOne(ctx, run)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record job start: %v", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		run.ID = id
+	}
+	return run, nil
+}
+
+// FinishRun stores the outcome of a run created by StartRun.
+func (r *JobRunRepository) FinishRun(ctx context.Context, run *models.JobRun) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": run.ID},
+		bson.M{"$set": bson.M{
+			"finished_at":     run.FinishedAt,
+			"items_processed": run.ItemsProcessed,
+			"errors":          run.Errors,
+			"error":           run.Error,
+			"success":         run.Success,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record job result: %v", err)
+	}
+	return nil
+}
+
+// GetLatestRuns returns the most recent run of every job.
+func (r *JobRunRepository) GetLatestRuns(ctx context.Context) (map[string]models.JobRun, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "name", Value: 1}, {Key: "started_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$name", "run": bson.M{"$first": "$$ROOT"}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest job runs: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Run models.JobRun `bson:"run"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode latest job runs: %v", err)
+	}
+
+	runs := make(map[string]models.JobRun, len(rows))
+	for _, row := range rows {
+		runs[row.Run.Name] = row.Run
+	}
+	return runs, nil
+}
+
+// GetLastSuccesses returns, per job, when its most recent successful run finished.
+func (r *JobRunRepository) GetLastSuccesses(ctx context.Context) (map[string]time.Time, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"success": true}}},
+		{{Key: "$group", Value: bson.M{"_id": "$name", "finished_at": bson.M{"$max": "$finished_at"}}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last successful job runs: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Name       string    `bson:"_id"`
+		FinishedAt time.Time `bson:"finished_at"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode last successful job runs: %v", err)
+	}
+
+	successes := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		successes[row.Name] = row.FinishedAt
+	}
+	return successes, nil
+}
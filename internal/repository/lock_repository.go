@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LockRepository provides named locks shared by every instance that uses the
+// same database. A lock expires after its TTL so a crashed holder cannot
+// block others forever.
+type LockRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLockRepository(db *mongo.Database) *LockRepository {
+	return &LockRepository{
+		collection: db.Collection("locks"),
+	}
+}
+
+// Acquire takes the named lock for owner until ttl has passed. It reports
+// false if another owner holds a lock that has not expired.
+func (r *LockRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": name, "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": owner, "acquired_at": now, "expires_at": now.Add(ttl)}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// The lock document exists and has not expired
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %v", name, err)
+	}
+	return true, nil
+}
+
+// Release gives up the named lock if owner still holds it.
+func (r *LockRepository) Release(ctx context.Context, name, owner string) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": name, "owner": owner}); err != nil {
+		return fmt.Errorf("failed to release lock %s: %v", name, err)
+	}
+	return nil
+}
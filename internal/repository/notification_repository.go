@@ -13,60 +13,176 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// NotificationRetention decides how long a notification is kept. A
+// retention of 0 means the notification never expires.
+type NotificationRetention struct {
+	Default time.Duration
+	ByType  map[string]time.Duration
+}
+
+// For returns the retention for a notification type.
+func (p NotificationRetention) For(notifType string) time.Duration {
+	if ttl, ok := p.ByType[notifType]; ok {
+		return ttl
+	}
+	return p.Default
+}
+
+// DefaultNotificationRetention keeps every notification for 7 days.
+var DefaultNotificationRetention = NotificationRetention{Default: 7 * 24 * time.Hour}
+
 type NotificationRepository struct {
 	collection *mongo.Collection
+	retention  NotificationRetention
 }
 
-func NewNotificationRepository(db *mongo.Database) *NotificationRepository {
+func NewNotificationRepository(db *mongo.Database, retention NotificationRetention) *NotificationRepository {
 	return &NotificationRepository{
 		collection: db.Collection("notifications"),
+		retention:  retention,
 	}
 }
 
-// CreateNotification inserts a new notification
+// notExpired matches notifications that are still live. Notifications
+// without an expiry never expire. Documents written before retention became
+// configurable keep the 7-day expires_at they were created with; only new
+// notifications follow the configured retention.
+func notExpired(now time.Time) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"expires_at": bson.M{"$gt": now}},
+		bson.M{"expires_at": nil},
+		bson.M{"expires_at": time.Time{}},
+	}}
+}
+
+// CreateNotification inserts a new notification, setting ExpiresAt from the
+// retention configured for its type.
 func (r *NotificationRepository) CreateNotification(ctx context.Context, notif *models.Notification) error {
 	notif.CreatedAt = time.Now()
-	notif.ExpiresAt = notif.CreatedAt.Add(7 * 24 * time.Hour)
+	notif.ExpiresAt = time.Time{}
+	if ttl := r.retention.For(notif.Type); ttl > 0 {
+		notif.ExpiresAt = notif.CreatedAt.Add(ttl)
+	}
 
-	_, err := r.collection.InsertOne(ctx, notif)
+	result, err := r.collection.InsertOne(ctx, notif)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to insert notification")
 		return fmt.Errorf("failed to create notification: %v", err)
 	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		notif.ID = id
+	}
 	return nil
 }
 
-// GetUserNotifications returns all notifications for a user
-func (r *NotificationRepository) GetUserNotifications(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error) {
-	filter := bson.M{
-		"user_id":    userID,
-		"expires_at": bson.M{"$gt": time.Now()},
+// NotificationFilter narrows a user's notification list. Zero fields match
+// every notification; CreatedTo is exclusive.
+type NotificationFilter struct {
+	Type        string
+	Read        *bool
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+// GetFilteredNotifications returns up to limit of the user's unexpired
+// notifications matching the filter, newest first. A non-zero cursor skips
+// to the notifications older than that ID.
+func (r *NotificationRepository) GetFilteredNotifications(ctx context.Context, userID primitive.ObjectID, filter NotificationFilter, cursor primitive.ObjectID, limit int64) ([]models.Notification, error) {
+	query := notExpired(time.Now())
+	query["user_id"] = userID
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Read != nil {
+		query["read"] = *filter.Read
+	}
+	created := bson.M{}
+	if !filter.CreatedFrom.IsZero() {
+		created["$gte"] = filter.CreatedFrom
+	}
+	if !filter.CreatedTo.IsZero() {
+		created["$lt"] = filter.CreatedTo
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
 	}
-	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if !cursor.IsZero() {
+		query["_id"] = bson.M{"$lt": cursor}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cur, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch notifications: %v", err)
 	}
-	defer cursor.Close(ctx)
+	defer cur.Close(ctx)
 
-	var notifications []models.Notification
-	if err := cursor.All(ctx, &notifications); err != nil {
+	notifications := []models.Notification{}
+	if err := cur.All(ctx, &notifications); err != nil {
 		return nil, fmt.Errorf("failed to decode notifications: %v", err)
 	}
 	return notifications, nil
 }
 
-// MarkAsRead sets notification's Read to true
-func (r *NotificationRepository) MarkAsRead(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"read": true}})
-	return err
+// MarkAsRead sets notification's Read to true. It returns the notification
+// if it was unread until now, or mongo.ErrNoDocuments if it is missing or
+// was already read.
+func (r *NotificationRepository) MarkAsRead(ctx context.Context, id primitive.ObjectID) (*models.Notification, error) {
+	var notif models.Notification
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "read": false},
+		bson.M{"$set": bson.M{"read": true}},
+	).Decode(&notif)
+	if err != nil {
+		return nil, err
+	}
+	return &notif, nil
 }
 
-// DeleteNotification deletes a notification
-func (r *NotificationRepository) DeleteNotification(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
-	return err
+// DeleteNotification deletes a notification and returns it, or
+// mongo.ErrNoDocuments if it did not exist.
+func (r *NotificationRepository) DeleteNotification(ctx context.Context, id primitive.ObjectID) (*models.Notification, error) {
+	var notif models.Notification
+	if err := r.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&notif); err != nil {
+		return nil, err
+	}
+	return &notif, nil
+}
+
+// CountUnread returns how many of the user's live notifications are unread.
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID primitive.ObjectID, now time.Time) (int64, error) {
+	filter := notExpired(now)
+	filter["user_id"] = userID
+	filter["read"] = false
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %v", err)
+	}
+	return count, nil
+}
+
+// CountNotifications returns how many live notifications the user has in
+// total and how many of them are unread.
+func (r *NotificationRepository) CountNotifications(ctx context.Context, userID primitive.ObjectID) (total, unread int64, err error) {
+	now := time.Now()
+	filter := notExpired(now)
+	filter["user_id"] = userID
+	if total, err = r.collection.CountDocuments(ctx, filter); err != nil {
+		return 0, 0, fmt.Errorf("failed to count notifications: %v", err)
+	}
+	if unread, err = r.CountUnread(ctx, userID, now); err != nil {
+		return 0, 0, err
+	}
+	return total, unread, nil
+}
+
+// DeleteUserNotifications removes every notification addressed to a user.
+func (r *NotificationRepository) DeleteUserNotifications(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notifications: %v", err)
+	}
+	return result.DeletedCount, nil
 }
 
 func (r *NotificationRepository) GetLatestNotificationByType(ctx context.Context, userID primitive.ObjectID, notifType string) (*models.Notification, error) {
@@ -84,13 +200,141 @@ func (r *NotificationRepository) GetLatestNotificationByType(ctx context.Context
 	return &notif, nil
 }
 
-// DeleteExpiredNotifications удаляет уведомления, у которых истёк срок
+// GetLatestNotificationForTarget returns the user's newest notification of a
+// type about a specific target.
+func (r *NotificationRepository) GetLatestNotificationForTarget(ctx context.Context, userID primitive.ObjectID, notifType string, targetID primitive.ObjectID) (*models.Notification, error) {
+	filter := bson.M{
+		"user_id":   userID,
+		"type":      notifType,
+		"target_id": targetID,
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var notif models.Notification
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&notif)
+	if err != nil {
+		return nil, err
+	}
+	return &notif, nil
+}
+
+// ClaimDeferredNotification clears deliver_after on one notification whose
+// quiet hours have ended and returns it, so each deferred notification is
+// delivered once even when several servers run the job. It returns
+// mongo.ErrNoDocuments when none is due.
+func (r *NotificationRepository) ClaimDeferredNotification(ctx context.Context, now time.Time) (*models.Notification, error) {
+	filter := notExpired(now)
+	filter["deliver_after"] = bson.M{"$lte": now}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "deliver_after", Value: 1}})
+
+	var notif models.Notification
+	err := r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$unset": bson.M{"deliver_after": ""}}, opts).Decode(&notif)
+	if err != nil {
+		return nil, err
+	}
+	notif.DeliverAfter = time.Time{}
+	return &notif, nil
+}
+
+// expiredFilter matches notifications whose expiry has passed.
+func expiredFilter(now time.Time) bson.M {
+	return bson.M{"expires_at": bson.M{"$lte": now, "$gt": time.Time{}}}
+}
+
+// CountExpiredNotifications returns how many notifications DeleteExpiredNotifications would remove.
+func (r *NotificationRepository) CountExpiredNotifications(ctx context.Context) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, expiredFilter(time.Now()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count expired notifications: %v", err)
+	}
+	return count, nil
+}
+
+// DeleteExpiredNotifications удаляет уведомления, у которых истёк срок.
+// Notifications without an expiry (zero expires_at) are never deleted here.
 func (r *NotificationRepository) DeleteExpiredNotifications(ctx context.Context) error {
-	filter := bson.M{"expires_at": bson.M{"$lte": time.Now()}}
-	result, err := r.collection.DeleteMany(ctx, filter)
+	result, err := r.collection.DeleteMany(ctx, expiredFilter(time.Now()))
 	if err != nil {
 		return fmt.Errorf("failed to delete expired notifications: %v", err)
 	}
 	logrus.Infof("Deleted %d expired notifications", result.DeletedCount)
 	return nil
 }
+
+// CountByTypeAndDay groups the user's notifications since the given time by
+// type and day, noisiest types first.
+func (r *NotificationRepository) CountByTypeAndDay(ctx context.Context, userID primitive.ObjectID, since time.Time, limit int64) ([]models.NotificationTypeSummary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "created_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"type": "$type",
+				"day":  bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}},
+			},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.day", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$_id.type",
+			"count": bson.M{"$sum": "$count"},
+			"days":  bson.M{"$push": bson.M{"day": "$_id.day", "count": "$count"}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate notifications: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var summary []models.NotificationTypeSummary
+	if err := cursor.All(ctx, &summary); err != nil {
+		return nil, fmt.Errorf("failed to decode notification summary: %v", err)
+	}
+	return summary, nil
+}
+
+// GetTopTypesAndRecipients returns the noisiest notification types and the
+// users receiving the most notifications since the given time.
+func (r *NotificationRepository) GetTopTypesAndRecipients(ctx context.Context, since time.Time, limit int64) ([]models.NotificationTypeSummary, []models.NotificationRecipientSummary, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		{{Key: "$facet", Value: bson.M{
+			"types": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":        "$type",
+					"count":      bson.M{"$sum": 1},
+					"recipients": bson.M{"$addToSet": "$user_id"},
+				}},
+				bson.M{"$project": bson.M{"count": 1, "recipients": bson.M{"$size": "$recipients"}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": limit},
+			},
+			"recipients": bson.A{
+				bson.M{"$group": bson.M{"_id": "$user_id", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}}},
+				bson.M{"$limit": limit},
+			},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to aggregate notifications: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Types      []models.NotificationTypeSummary      `bson:"types"`
+		Recipients []models.NotificationRecipientSummary `bson:"recipients"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode notification summary: %v", err)
+	}
+	if len(result) == 0 {
+		return nil, nil, nil
+	}
+	return result[0].Types, result[0].Recipients, nil
+}
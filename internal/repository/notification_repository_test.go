@@ -0,0 +1,213 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestNotificationsExpiryFilter(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	user := testutil.SeedUser(t, repos, models.User{})
+
+	live := testutil.SeedNotification(t, repos, user.ID, "goal_completed")
+	testutil.SeedExpiredNotification(t, repos, user.ID, "goal_completed", time.Hour)
+
+	// Notifications written before expiry existed have no expires_at and stay live.
+	legacyID := primitive.NewObjectID()
+	if _, err := repos.DB.Collection("notifications").InsertOne(ctx, bson.M{
+		"_id":        legacyID,
+		"user_id":    user.ID,
+		"type":       "legacy",
+		"read":       false,
+		"created_at": time.Now(),
+	}); err != nil {
+		t.Fatalf("failed to seed legacy notification: %v", err)
+	}
+
+	notifications, err := repos.Notifications.GetFilteredNotifications(ctx, user.ID, repository.NotificationFilter{}, primitive.NilObjectID, 10)
+	if err != nil {
+		t.Fatalf("GetFilteredNotifications: %v", err)
+	}
+	got := map[primitive.ObjectID]bool{}
+	for _, n := range notifications {
+		got[n.ID] = true
+	}
+	if len(notifications) != 2 || !got[live.ID] || !got[legacyID] {
+		t.Fatalf("got %d notifications %v, want the live and the legacy one", len(notifications), got)
+	}
+
+	unread, err := repos.Notifications.CountUnread(ctx, user.ID, time.Now())
+	if err != nil {
+		t.Fatalf("CountUnread: %v", err)
+	}
+	if unread != 2 {
+		t.Errorf("CountUnread = %d, want 2", unread)
+	}
+
+	expired, err := repos.Notifications.CountExpiredNotifications(ctx)
+	if err != nil {
+		t.Fatalf("CountExpiredNotifications: %v", err)
+	}
+	if expired != 1 {
+		t.Errorf("CountExpiredNotifications = %d, want 1", expired)
+	}
+
+	if err := repos.Notifications.DeleteExpiredNotifications(ctx); err != nil {
+		t.Fatalf("DeleteExpiredNotifications: %v", err)
+	}
+	left, err := repos.DB.Collection("notifications").CountDocuments(ctx, bson.M{"user_id": user.ID})
+	if err != nil {
+		t.Fatalf("failed to count notifications: %v", err)
+	}
+	if left != 2 {
+		t.Errorf("%d notifications left after purge, want 2", left)
+	}
+}
+
+func TestGetLatestNotificationByType(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	user := testutil.SeedUser(t, repos, models.User{})
+	other := testutil.SeedUser(t, repos, models.User{})
+
+	older := testutil.SeedNotification(t, repos, user.ID, "streak")
+	if _, err := repos.DB.Collection("notifications").UpdateOne(ctx,
+		bson.M{"_id": older.ID},
+		bson.M{"$set": bson.M{"created_at": time.Now().Add(-time.Hour)}},
+	); err != nil {
+		t.Fatalf("failed to age notification: %v", err)
+	}
+	newer := testutil.SeedNotification(t, repos, user.ID, "streak")
+	testutil.SeedNotification(t, repos, user.ID, "goal_completed")
+	testutil.SeedNotification(t, repos, other.ID, "streak")
+
+	latest, err := repos.Notifications.GetLatestNotificationByType(ctx, user.ID, "streak")
+	if err != nil {
+		t.Fatalf("GetLatestNotificationByType: %v", err)
+	}
+	if latest.ID != newer.ID {
+		t.Errorf("got notification %s, want the newest one %s", latest.ID.Hex(), newer.ID.Hex())
+	}
+
+	if _, err := repos.Notifications.GetLatestNotificationByType(ctx, user.ID, "badge_earned"); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("err = %v, want mongo.ErrNoDocuments for a type the user never got", err)
+	}
+}
+
+func TestNotificationRetentionByType(t *testing.T) {
+	db := testutil.NewTestDatabase(t)
+	repo := repository.NewNotificationRepository(db, repository.NotificationRetention{
+		Default: time.Hour,
+		ByType:  map[string]time.Duration{"friend_request_received": 0, "goal_due_soon": 72 * time.Hour},
+	})
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	short := &models.Notification{UserID: userID, Type: "goal_completed"}
+	long := &models.Notification{UserID: userID, Type: "goal_due_soon"}
+	kept := &models.Notification{UserID: userID, Type: "friend_request_received"}
+	for _, n := range []*models.Notification{short, long, kept} {
+		if err := repo.CreateNotification(ctx, n); err != nil {
+			t.Fatalf("CreateNotification: %v", err)
+		}
+	}
+
+	if want := short.CreatedAt.Add(time.Hour); !short.ExpiresAt.Equal(want) {
+		t.Errorf("default ExpiresAt = %v, want %v", short.ExpiresAt, want)
+	}
+	if want := long.CreatedAt.Add(72 * time.Hour); !long.ExpiresAt.Equal(want) {
+		t.Errorf("override ExpiresAt = %v, want %v", long.ExpiresAt, want)
+	}
+	if !kept.ExpiresAt.IsZero() {
+		t.Errorf("ExpiresAt = %v, want none for a type kept until acted upon", kept.ExpiresAt)
+	}
+
+	// Kept notifications are stored without the field at all
+	var raw bson.M
+	if err := db.Collection("notifications").FindOne(ctx, bson.M{"_id": kept.ID}).Decode(&raw); err != nil {
+		t.Fatalf("failed to load notification: %v", err)
+	}
+	if _, ok := raw["expires_at"]; ok {
+		t.Errorf("stored expires_at = %v, want the field omitted", raw["expires_at"])
+	}
+}
+
+// Documents written under the old flat 7-day policy are not rewritten when
+// the retention changes: they expire on the date stored with them, and
+// documents without a date, or with a zero one, never expire.
+func TestNotificationRetentionLeavesExistingDocuments(t *testing.T) {
+	db := testutil.NewTestDatabase(t)
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	coll := db.Collection("notifications")
+
+	createdAt := time.Now().Add(-2 * 24 * time.Hour).Truncate(time.Millisecond)
+	flatExpiry := createdAt.Add(7 * 24 * time.Hour)
+	seed := map[string]bson.M{
+		"flat":    {"created_at": createdAt, "expires_at": flatExpiry},
+		"lapsed":  {"created_at": createdAt.Add(-7 * 24 * time.Hour), "expires_at": createdAt},
+		"missing": {"created_at": createdAt},
+		"zero":    {"created_at": createdAt, "expires_at": time.Time{}},
+	}
+	ids := map[string]primitive.ObjectID{}
+	for name, doc := range seed {
+		ids[name] = primitive.NewObjectID()
+		doc["_id"] = ids[name]
+		doc["user_id"] = userID
+		doc["type"] = "goal_completed"
+		doc["read"] = false
+		if _, err := coll.InsertOne(ctx, doc); err != nil {
+			t.Fatalf("failed to seed %s notification: %v", name, err)
+		}
+	}
+
+	// The type is now kept forever and the default is shorter than the age
+	// of the flat document; neither applies to what is already stored.
+	repo := repository.NewNotificationRepository(db, repository.NotificationRetention{
+		Default: time.Hour,
+		ByType:  map[string]time.Duration{"goal_completed": 0},
+	})
+
+	notifications, err := repo.GetFilteredNotifications(ctx, userID, repository.NotificationFilter{}, primitive.NilObjectID, 10)
+	if err != nil {
+		t.Fatalf("GetFilteredNotifications: %v", err)
+	}
+	listed := map[primitive.ObjectID]models.Notification{}
+	for _, n := range notifications {
+		listed[n.ID] = n
+	}
+	for _, name := range []string{"flat", "missing", "zero"} {
+		if _, ok := listed[ids[name]]; !ok {
+			t.Errorf("%s notification missing from the list", name)
+		}
+	}
+	if _, ok := listed[ids["lapsed"]]; ok {
+		t.Error("lapsed notification listed")
+	}
+	if got := listed[ids["flat"]].ExpiresAt; !got.Equal(flatExpiry) {
+		t.Errorf("flat notification expires at %v, want the stored %v", got, flatExpiry)
+	}
+
+	if err := repo.DeleteExpiredNotifications(ctx); err != nil {
+		t.Fatalf("DeleteExpiredNotifications: %v", err)
+	}
+	for name, id := range ids {
+		n, err := coll.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			t.Fatalf("failed to count notifications: %v", err)
+		}
+		if wantKept := name != "lapsed"; (n == 1) != wantKept {
+			t.Errorf("%s notification kept = %v, want %v", name, n == 1, wantKept)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PreferencesRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPreferencesRepository(db *mongo.Database) *PreferencesRepository {
+	return &PreferencesRepository{
+		collection: db.Collection("user_preferences"),
+	}
+}
+
+// EnsureIndexes keeps a single preferences document per user.
+func (r *PreferencesRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create preferences index: %v", err)
+	}
+	return nil
+}
+
+// GetByUserID returns the user's preferences or mongo.ErrNoDocuments if
+// none were saved.
+func (r *PreferencesRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) (*models.UserPreferences, error) {
+	var prefs models.UserPreferences
+	if err := r.collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&prefs); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// Upsert creates or replaces the user's preferences.
+func (r *PreferencesRepository) Upsert(ctx context.Context, prefs *models.UserPreferences) error {
+	prefs.UpdatedAt = time.Now()
+	_, err := r.collection.ReplaceOne(ctx,
+		bson.M{"user_id": prefs.UserID},
+		prefs,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %v", err)
+	}
+	return nil
+}
+
+// DeleteByUserID removes the user's preferences.
+func (r *PreferencesRepository) DeleteByUserID(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"user_id": userID}); err != nil {
+		return fmt.Errorf("failed to delete preferences: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProgressRepository struct {
+	collection *mongo.Collection
+}
+
+func NewProgressRepository(db *mongo.Database) *ProgressRepository {
+	return &ProgressRepository{
+		collection: db.Collection("progress_snapshots"),
+	}
+}
+
+// CreateSnapshot inserts a new progress snapshot
+func (r *ProgressRepository) CreateSnapshot(ctx context.Context, snapshot *models.ProgressSnapshot) error {
+	_, err := r.collection.InsertOne(ctx, snapshot)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to insert progress snapshot")
+		return fmt.Errorf("failed to insert progress snapshot: %v", err)
+	}
+	return nil
+}
+
+// GetSnapshots returns a goal's snapshots between from and to, oldest first.
+// A zero from or to leaves that side of the range open.
+func (r *ProgressRepository) GetSnapshots(ctx context.Context, goalID primitive.ObjectID, from, to time.Time) ([]models.ProgressSnapshot, error) {
+	filter := bson.M{"goal_id": goalID}
+	timeRange := bson.M{}
+	if !from.IsZero() {
+		timeRange["$gte"] = from
+	}
+	if !to.IsZero() {
+		timeRange["$lt"] = to
+	}
+	if len(timeRange) > 0 {
+		filter["timestamp"] = timeRange
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch progress snapshots: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var snapshots []models.ProgressSnapshot
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode progress snapshots: %v", err)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RefreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRefreshTokenRepository(db *mongo.Database) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		collection: db.Collection("refresh_tokens"),
+	}
+}
+
+// EnsureIndexes makes tokens unique and lets MongoDB drop them once expired.
+func (r *RefreshTokenRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token indexes: %v", err)
+	}
+	return nil
+}
+
+func (r *RefreshTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	token.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %v", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		token.ID = id
+	}
+	return nil
+}
+
+// GetRefreshToken returns the stored record for a token, revoked or not.
+func (r *RefreshTokenRepository) GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	var stored models.RefreshToken
+	if err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// RevokeRefreshToken marks a token as revoked. It reports whether a live
+// token was found.
+func (r *RefreshTokenRepository) RevokeRefreshToken(ctx context.Context, token string, at time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"token": token, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": at}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke refresh token: %v", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
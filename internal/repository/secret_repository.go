@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type SecretRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSecretRepository(db *mongo.Database) *SecretRepository {
+	return &SecretRepository{
+		collection: db.Collection("secrets"),
+	}
+}
+
+// CreateSigningKey stores a new signing key record.
+func (r *SecretRepository) CreateSigningKey(ctx context.Context, key *models.SigningKey) error {
+	key.CreatedAt = time.Now()
+	if _, err := r.collection.InsertOne(ctx, key); err != nil {
+		return fmt.Errorf("failed to store signing key: %v", err)
+	}
+	return nil
+}
+
+// RetireActiveKeys marks the active signing keys as rotated out; they stay
+// valid until retiresAt.
+func (r *SecretRepository) RetireActiveKeys(ctx context.Context, retiresAt time.Time) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"active": true},
+		bson.M{"$set": bson.M{"active": false, "retires_at": retiresAt}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to retire signing keys: %v", err)
+	}
+	return nil
+}
+
+// GetLiveSigningKeys returns the active key and the rotated-out keys still
+// within their grace period, newest first.
+func (r *SecretRepository) GetLiveSigningKeys(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"active": true},
+		{"retires_at": bson.M{"$gt": now}},
+	}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var keys []models.SigningKey
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode signing keys: %v", err)
+	}
+	return keys, nil
+}
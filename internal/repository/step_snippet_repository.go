@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StepSnippetRepository struct {
+	collection *mongo.Collection
+}
+
+func NewStepSnippetRepository(db *mongo.Database) *StepSnippetRepository {
+	return &StepSnippetRepository{
+		collection: db.Collection("step_snippets"),
+	}
+}
+
+func (r *StepSnippetRepository) CreateSnippet(ctx context.Context, snippet *models.StepSnippet) (*models.StepSnippet, error) {
+	snippet.CreatedAt = time.Now()
+	snippet.UpdatedAt = snippet.CreatedAt
+
+	result, err := r.collection.InsertOne(ctx, snippet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert snippet: %v", err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast inserted ID")
+	}
+	snippet.ID = insertedID
+
+	return snippet, nil
+}
+
+func (r *StepSnippetRepository) GetSnippetByID(ctx context.Context, id primitive.ObjectID) (*models.StepSnippet, error) {
+	var snippet models.StepSnippet
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&snippet); err != nil {
+		return nil, err
+	}
+	return &snippet, nil
+}
+
+func (r *StepSnippetRepository) GetSnippetsByUser(ctx context.Context, userID primitive.ObjectID) ([]models.StepSnippet, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch snippets: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var snippets []models.StepSnippet
+	if err := cursor.All(ctx, &snippets); err != nil {
+		return nil, fmt.Errorf("failed to decode snippets: %v", err)
+	}
+	return snippets, nil
+}
+
+func (r *StepSnippetRepository) CountSnippetsByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+// UpdateSnippet replaces the snippet's name and steps
+func (r *StepSnippetRepository) UpdateSnippet(ctx context.Context, snippet *models.StepSnippet) error {
+	snippet.UpdatedAt = time.Now()
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": snippet.ID},
+		bson.M{"$set": bson.M{
+			"name":       snippet.Name,
+			"steps":      snippet.Steps,
+			"updated_at": snippet.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update snippet: %v", err)
+	}
+	return nil
+}
+
+func (r *StepSnippetRepository) DeleteSnippet(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete snippet: %v", err)
+	}
+	return nil
+}
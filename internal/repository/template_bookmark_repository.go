@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TemplateBookmarkRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTemplateBookmarkRepository(db *mongo.Database) *TemplateBookmarkRepository {
+	return &TemplateBookmarkRepository{
+		collection: db.Collection("template_bookmarks"),
+	}
+}
+
+// EnsureIndexes creates the unique index that keeps one bookmark per user
+// and template.
+func (r *TemplateBookmarkRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "template_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create template bookmark index: %v", err)
+	}
+	return nil
+}
+
+// AddBookmark bookmarks the template for the user. Bookmarking a template
+// twice keeps the original bookmark.
+func (r *TemplateBookmarkRepository) AddBookmark(ctx context.Context, userID, templateID primitive.ObjectID) error {
+	filter := bson.M{"user_id": userID, "template_id": templateID}
+	update := bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}}
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to bookmark template: %v", err)
+	}
+	return nil
+}
+
+// RemoveBookmark deletes the user's bookmark of the template. It reports
+// whether a bookmark existed.
+func (r *TemplateBookmarkRepository) RemoveBookmark(ctx context.Context, userID, templateID primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"user_id": userID, "template_id": templateID})
+	if err != nil {
+		return false, fmt.Errorf("failed to remove template bookmark: %v", err)
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// GetBookmarks returns the user's bookmarks, newest first.
+func (r *TemplateBookmarkRepository) GetBookmarks(ctx context.Context, userID primitive.ObjectID) ([]models.TemplateBookmark, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template bookmarks: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	bookmarks := []models.TemplateBookmark{}
+	if err := cursor.All(ctx, &bookmarks); err != nil {
+		return nil, fmt.Errorf("failed to decode template bookmarks: %v", err)
+	}
+	return bookmarks, nil
+}
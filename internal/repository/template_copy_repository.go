@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TemplateCopyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTemplateCopyRepository(db *mongo.Database) *TemplateCopyRepository {
+	return &TemplateCopyRepository{
+		collection: db.Collection("template_copies"),
+	}
+}
+
+// RecordCopy stores a copy event.
+func (r *TemplateCopyRepository) RecordCopy(ctx context.Context, copyEvent *models.TemplateCopy) error {
+	copyEvent.CreatedAt = time.Now()
+	if _, err := r.collection.InsertOne(ctx, copyEvent); err != nil {
+		return fmt.Errorf("failed to record template copy: %v", err)
+	}
+	return nil
+}
+
+// GetTrending ranks templates by the number of distinct users whose counted
+// copies happened since the given time.
+func (r *TemplateCopyRepository) GetTrending(ctx context.Context, since time.Time, limit int64) ([]models.TrendingTemplate, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"counted": true, "created_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"template": "$template_id", "user": "$user_id"}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$_id.template", "users": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "users", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate trending templates: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var trending []models.TrendingTemplate
+	if err := cursor.All(ctx, &trending); err != nil {
+		return nil, fmt.Errorf("failed to decode trending templates: %v", err)
+	}
+	return trending, nil
+}
+
+// GetHeavyCopiers returns users with at least minCopies copies since the
+// given time, counted or not, most active first.
+func (r *TemplateCopyRepository) GetHeavyCopiers(ctx context.Context, since time.Time, minCopies, limit int64) ([]models.TemplateCopier, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$user_id",
+			"copies":    bson.M{"$sum": 1},
+			"templates": bson.M{"$addToSet": "$template_id"},
+		}}},
+		{{Key: "$project", Value: bson.M{"copies": 1, "templates": bson.M{"$size": "$templates"}}}},
+		{{Key: "$match", Value: bson.M{"copies": bson.M{"$gte": minCopies}}}},
+		{{Key: "$sort", Value: bson.M{"copies": -1}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate template copiers: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var copiers []models.TemplateCopier
+	if err := cursor.All(ctx, &copiers); err != nil {
+		return nil, fmt.Errorf("failed to decode template copiers: %v", err)
+	}
+	return copiers, nil
+}
@@ -3,12 +3,14 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type TemplateRepository struct {
@@ -21,8 +23,23 @@ func NewTemplateRepository(db *mongo.Database) *TemplateRepository {
 	}
 }
 
+// EnsureIndexes creates the indexes used to list public templates by
+// category and by tag.
+func (r *TemplateRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "public", Value: 1}, {Key: "category", Value: 1}}},
+		{Keys: bson.D{{Key: "public", Value: 1}, {Key: "tags", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create template public indexes: %v", err)
+	}
+	return nil
+}
+
 func (r *TemplateRepository) CreateTemplate(ctx context.Context, template *models.GoalTemplate) (*models.GoalTemplate, error) {
 	template.CreatedAt = time.Now()
+	template.Version = 1
+	template.SchemaVersion = models.TemplateSchemaVersion
 
 	result, err := r.collection.InsertOne(ctx, template)
 	if err != nil {
@@ -38,24 +55,48 @@ func (r *TemplateRepository) CreateTemplate(ctx context.Context, template *model
 	return template, nil
 }
 
-func (r *TemplateRepository) GetAllTemplates(ctx context.Context) ([]models.GoalTemplate, error) {
-	var templates []models.GoalTemplate
-
-	cursor, err := r.collection.Find(ctx, bson.M{})
+// UpdateTemplate replaces the content of a template and bumps its version,
+// provided it is still at the version the caller read. It reports whether the
+// template was updated.
+func (r *TemplateRepository) UpdateTemplate(ctx context.Context, template *models.GoalTemplate, expectedVersion int) (bool, error) {
+	now := time.Now()
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": template.ID, "version": expectedVersion},
+		bson.M{"$set": bson.M{
+			"title":       template.Title,
+			"description": template.Description,
+			"steps":       template.Steps,
+			"category":    template.Category,
+			"tags":        template.Tags,
+			"public":      template.Public,
+			"updated_at":  now,
+			"version":     expectedVersion + 1,
+		}},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch templates: %v", err)
+		return false, fmt.Errorf("failed to update template: %v", err)
 	}
-	defer cursor.Close(ctx)
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+	template.UpdatedAt = now
+	template.Version = expectedVersion + 1
+	return true, nil
+}
 
-	for cursor.Next(ctx) {
-		var template models.GoalTemplate
-		if err := cursor.Decode(&template); err != nil {
-			return nil, fmt.Errorf("failed to decode template: %v", err)
-		}
-		templates = append(templates, template)
+// IncrementCopyCount adds one to the template's copy count.
+func (r *TemplateRepository) IncrementCopyCount(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"copy_count": 1}})
+	if err != nil {
+		return fmt.Errorf("failed to increment copy count: %v", err)
 	}
+	return nil
+}
 
-	return templates, nil
+// GetAllTemplates returns one page of all templates, newest first, and the
+// total number of templates.
+func (r *TemplateRepository) GetAllTemplates(ctx context.Context, offset, limit int64) ([]models.GoalTemplate, int64, error) {
+	return r.getTemplatePage(ctx, bson.M{}, offset, limit)
 }
 
 func (r *TemplateRepository) GetTemplateByID(ctx context.Context, id primitive.ObjectID) (*models.GoalTemplate, error) {
@@ -69,73 +110,105 @@ func (r *TemplateRepository) GetTemplateByID(ctx context.Context, id primitive.O
 	return &template, nil
 }
 
-// GetTemplatesByUser fetches templates created by a specific user.
-func (r *TemplateRepository) GetTemplatesByUser(ctx context.Context, userID primitive.ObjectID) ([]models.GoalTemplate, error) {
-	var templates []models.GoalTemplate
-
-	// Filter templates by user_id
-	filter := bson.M{"user_id": userID}
-
-	cursor, err := r.collection.Find(ctx, filter)
+// GetTemplatesByIDs returns the templates with the given IDs that still
+// exist, in no particular order.
+func (r *TemplateRepository) GetTemplatesByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.GoalTemplate, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch templates: %v", err)
+		return nil, fmt.Errorf("failed to fetch templates by ids: %v", err)
 	}
 	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var template models.GoalTemplate
-		if err := cursor.Decode(&template); err != nil {
-			return nil, fmt.Errorf("failed to decode template: %v", err)
+	templates := []models.GoalTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, fmt.Errorf("failed to decode templates: %v", err)
+	}
+	return templates, nil
+}
+
+// GetTemplatesByUser returns one page of the templates created by a user,
+// public or not, newest first, and the total number of them.
+func (r *TemplateRepository) GetTemplatesByUser(ctx context.Context, userID primitive.ObjectID, offset, limit int64) ([]models.GoalTemplate, int64, error) {
+	return r.getTemplatePage(ctx, bson.M{"user_id": userID}, offset, limit)
+}
+
+// TemplateFilter narrows a listing of public templates. Empty fields match
+// everything.
+type TemplateFilter struct {
+	Query    string // Case-insensitive substring of the title or description
+	Category string
+	Tag      string
+}
+
+// query returns the Mongo filter for public templates matching f.
+func (f TemplateFilter) query() bson.M {
+	query := bson.M{"public": true}
+	if f.Category != "" {
+		query["category"] = f.Category
+	}
+	if f.Tag != "" {
+		query["tags"] = f.Tag
+	}
+	if f.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(f.Query), Options: "i"}
+		query["$or"] = bson.A{
+			bson.M{"title": pattern},
+			bson.M{"description": pattern},
 		}
-		templates = append(templates, template)
 	}
+	return query
+}
 
-	return templates, nil
+// GetPublicTemplates returns one page of the public templates matching the
+// filter, newest first, and the total number of matches.
+func (r *TemplateRepository) GetPublicTemplates(ctx context.Context, filter TemplateFilter, offset, limit int64) ([]models.GoalTemplate, int64, error) {
+	return r.getTemplatePage(ctx, filter.query(), offset, limit)
 }
 
-// GetPublicTemplates returns all public templates
-func (r *TemplateRepository) GetPublicTemplates(ctx context.Context) ([]models.GoalTemplate, error) {
-	var templates []models.GoalTemplate
+// GetPublicTemplatesByUser is GetPublicTemplates limited to one user's templates.
+func (r *TemplateRepository) GetPublicTemplatesByUser(ctx context.Context, userID primitive.ObjectID, filter TemplateFilter, offset, limit int64) ([]models.GoalTemplate, int64, error) {
+	query := filter.query()
+	query["user_id"] = userID
+	return r.getTemplatePage(ctx, query, offset, limit)
+}
 
-	filter := bson.M{"public": true}
-	cursor, err := r.collection.Find(ctx, filter)
+// GetPublicTags returns every tag used on public templates.
+func (r *TemplateRepository) GetPublicTags(ctx context.Context) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "tags", bson.M{"public": true})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch public templates: %v", err)
+		return nil, fmt.Errorf("failed to fetch distinct template tags: %v", err)
 	}
-	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var template models.GoalTemplate
-		if err := cursor.Decode(&template); err != nil {
-			return nil, fmt.Errorf("failed to decode template: %v", err)
+	tags := make([]string, 0, len(values))
+	for _, v := range values {
+		if tag, ok := v.(string); ok {
+			tags = append(tags, tag)
 		}
-		templates = append(templates, template)
 	}
-
-	return templates, nil
+	return tags, nil
 }
 
-// GetPublicTemplatesByUser fetches public templates created by a specific user.
-func (r *TemplateRepository) GetPublicTemplatesByUser(ctx context.Context, userID primitive.ObjectID) ([]models.GoalTemplate, error) {
-	var templates []models.GoalTemplate
-	filter := bson.M{
-		"user_id": userID,
-		"public":  true,
+// getTemplatePage returns one page of the templates matching query, newest
+// first, and the total number of matching templates.
+func (r *TemplateRepository) getTemplatePage(ctx context.Context, query bson.M, offset, limit int64) ([]models.GoalTemplate, int64, error) {
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count templates: %v", err)
 	}
 
-	cursor, err := r.collection.Find(ctx, filter)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(offset).
+		SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch public templates for user: %v", err)
+		return nil, 0, fmt.Errorf("failed to fetch templates: %v", err)
 	}
 	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var template models.GoalTemplate
-		if err := cursor.Decode(&template); err != nil {
-			return nil, fmt.Errorf("failed to decode template: %v", err)
-		}
-		templates = append(templates, template)
+	templates := []models.GoalTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode templates: %v", err)
 	}
-
-	return templates, nil
+	return templates, total, nil
 }
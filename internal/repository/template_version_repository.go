@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TemplateVersionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTemplateVersionRepository(db *mongo.Database) *TemplateVersionRepository {
+	return &TemplateVersionRepository{
+		collection: db.Collection("template_versions"),
+	}
+}
+
+// EnsureIndexes creates the unique index on template and version number.
+func (r *TemplateVersionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "template_id", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create template version index: %v", err)
+	}
+	return nil
+}
+
+// SaveVersion stores a past version of a template. Saving the same version
+// again keeps the first copy, so a retried update does not fail.
+func (r *TemplateVersionRepository) SaveVersion(ctx context.Context, version *models.TemplateVersion) error {
+	filter := bson.M{"template_id": version.TemplateID, "version": version.Version}
+	update := bson.M{"$setOnInsert": version}
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to save template version: %v", err)
+	}
+	return nil
+}
+
+// GetVersions returns the stored past versions of a template, newest first.
+func (r *TemplateVersionRepository) GetVersions(ctx context.Context, templateID primitive.ObjectID) ([]models.TemplateVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"template_id": templateID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template versions: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	versions := []models.TemplateVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("failed to decode template versions: %v", err)
+	}
+	return versions, nil
+}
+
+// GetVersion returns one stored past version of a template.
+func (r *TemplateVersionRepository) GetVersion(ctx context.Context, templateID primitive.ObjectID, version int) (*models.TemplateVersion, error) {
+	var v models.TemplateVersion
+	err := r.collection.FindOne(ctx, bson.M{"template_id": templateID, "version": version}).Decode(&v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch template version: %v", err)
+	}
+	return &v, nil
+}
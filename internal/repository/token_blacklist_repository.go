@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBlacklistPrefix namespaces blacklisted JTIs in Redis.
+const tokenBlacklistPrefix = "jwt:blacklist:"
+
+// ErrBlacklistUnavailable is returned when no Redis client is configured.
+var ErrBlacklistUnavailable = errors.New("token blacklist is not configured")
+
+// TokenBlacklistRepository stores the IDs of revoked access tokens in Redis
+// until the tokens would have expired anyway.
+type TokenBlacklistRepository struct {
+	client *redis.Client
+}
+
+func NewTokenBlacklistRepository(client *redis.Client) *TokenBlacklistRepository {
+	return &TokenBlacklistRepository{
+		client: client,
+	}
+}
+
+// AddToken blacklists a token ID for the given time.
+func (r *TokenBlacklistRepository) AddToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if r.client == nil {
+		return ErrBlacklistUnavailable
+	}
+	if err := r.client.Set(ctx, tokenBlacklistPrefix+jti, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to blacklist token: %v", err)
+	}
+	return nil
+}
+
+// IsBlacklisted reports whether a token ID was revoked.
+func (r *TokenBlacklistRepository) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	if r.client == nil {
+		return false, ErrBlacklistUnavailable
+	}
+	n, err := r.client.Exists(ctx, tokenBlacklistPrefix+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token blacklist: %v", err)
+	}
+	return n > 0, nil
+}
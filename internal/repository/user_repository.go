@@ -10,6 +10,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // UserRepository handles database operations related to users.
@@ -28,6 +29,7 @@ func NewUserRepository(db *mongo.Database) *UserRepository {
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
+	user.SchemaVersion = models.UserSchemaVersion
 
 	result, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
@@ -58,6 +60,35 @@ func (r *UserRepository) GetUserByVerificationToken(ctx context.Context, token s
 	return &user, nil
 }
 
+// GetUserByCalendarToken fetches the user whose calendar feed uses this token.
+func (r *UserRepository) GetUserByCalendarToken(ctx context.Context, token string) (*models.User, error) {
+	var user models.User
+	if err := r.collection.FindOne(ctx, bson.M{"calendar_token": token}).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByPendingEmailToken fetches the user who requested an email change with this token.
+func (r *UserRepository) GetUserByPendingEmailToken(ctx context.Context, token string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"pending_email_token": token}).Decode(&user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user by email change token: %v", err)
+	}
+	return &user, nil
+}
+
+// GetUserByDeletionToken fetches the user who requested account deletion with this token.
+func (r *UserRepository) GetUserByDeletionToken(ctx context.Context, token string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"deletion_token": token}).Decode(&user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user by deletion token: %v", err)
+	}
+	return &user, nil
+}
+
 func (r *UserRepository) GetUserByResetToken(ctx context.Context, token string) (*models.User, error) {
 	var user models.User
 	err := r.collection.FindOne(ctx, bson.M{"reset_token": token}).Decode(&user)
@@ -83,6 +114,16 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 	return &user, nil
 }
 
+// GetUserByProvider retrieves a user linked to an external login provider.
+func (r *UserRepository) GetUserByProvider(ctx context.Context, provider, providerID string) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, bson.M{"auth_provider": provider, "provider_id": providerID}).Decode(&user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user by provider: %v", err)
+	}
+	return &user, nil
+}
+
 // GetUserByID retrieves a user by their ID.
 func (r *UserRepository) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
 	var user models.User
@@ -169,6 +210,71 @@ func (r *UserRepository) AddFriend(ctx context.Context, userID, friendID primiti
 	return nil
 }
 
+// IncrementXP atomically adds amount to the user's XP and returns the user
+// as it is after the update.
+func (r *UserRepository) IncrementXP(ctx context.Context, userID primitive.ObjectID, amount int) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"xp": amount}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add XP: %v", err)
+	}
+	return &user, nil
+}
+
+// SetLevel changes the user's level from the given one. It reports false if
+// the level was changed concurrently, so only one caller acts on a level change.
+func (r *UserRepository) SetLevel(ctx context.Context, userID primitive.ObjectID, from, to int) (bool, error) {
+	filter := bson.M{"_id": userID, "level": from}
+	if from == 0 {
+		// Users created before levels existed have no level field
+		filter["level"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"level": to}})
+	if err != nil {
+		return false, fmt.Errorf("failed to update level: %v", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// SetLoginStreak stores the user's new login streak and last login day,
+// adding freezeDelta to their freeze count. The update only applies if the
+// last login day is still lastLogin, so concurrent logins count once.
+func (r *UserRepository) SetLoginStreak(ctx context.Context, userID primitive.ObjectID, lastLogin, today time.Time, streak, freezeDelta int) (bool, error) {
+	filter := bson.M{"_id": userID, "last_login_date": lastLogin}
+	if lastLogin.IsZero() {
+		filter["last_login_date"] = nil
+	}
+	update := bson.M{
+		"$set": bson.M{"login_streak": streak, "last_login_date": today},
+		"$inc": bson.M{"streak_freeze_count": freezeDelta},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to update login streak: %v", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// BuyStreakFreeze takes cost XP from the user and adds one streak freeze, as
+// long as they have enough XP and fewer than maxFreezes. It returns the
+// updated user, or mongo.ErrNoDocuments if either condition fails.
+func (r *UserRepository) BuyStreakFreeze(ctx context.Context, userID primitive.ObjectID, cost, maxFreezes int) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": userID, "xp": bson.M{"$gte": cost}, "streak_freeze_count": bson.M{"$not": bson.M{"$gte": maxFreezes}}},
+		bson.M{"$inc": bson.M{"xp": -cost, "streak_freeze_count": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // GetFriendIDs returns the list of friends for a user
 func (r *UserRepository) GetFriendIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
 	var user models.User
@@ -201,6 +307,34 @@ func (r *UserRepository) GetUsersByIDs(ctx context.Context, ids []primitive.Obje
 	return users, nil
 }
 
+// GetPublicProfilesByIDs is GetUsersByIDs limited to the fields anyone may
+// see: username, display name and avatar.
+func (r *UserRepository) GetPublicProfilesByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.PublicUser, error) {
+	opts := options.Find().SetProjection(bson.M{"username": 1, "display_name": 1, "avatar_url": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users by IDs: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %v", err)
+	}
+
+	profiles := make([]models.PublicUser, 0, len(users))
+	for _, u := range users {
+		profiles = append(profiles, models.PublicUser{
+			ID:          u.ID,
+			Username:    u.Username,
+			DisplayName: u.DisplayName,
+			AvatarURL:   u.AvatarURL,
+		})
+	}
+	return profiles, nil
+}
+
 // RemoveFriend removes each user from the other's friend list.
 func (r *UserRepository) RemoveFriend(ctx context.Context, userID1, userID2 primitive.ObjectID) error {
 	// Pull userID2 from userID1's friends
@@ -223,3 +357,135 @@ func (r *UserRepository) RemoveFriend(ctx context.Context, userID1, userID2 prim
 
 	return nil
 }
+
+// BlockUser adds targetID to userID's blocked list.
+func (r *UserRepository) BlockUser(ctx context.Context, userID, targetID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$addToSet": bson.M{"blocked_users": targetID}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to block user: %v", err)
+	}
+	return nil
+}
+
+// UnblockUser removes targetID from userID's blocked list.
+func (r *UserRepository) UnblockUser(ctx context.Context, userID, targetID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$pull": bson.M{"blocked_users": targetID}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to unblock user: %v", err)
+	}
+	return nil
+}
+
+// IsBlocked reports whether receiverID has blocked senderID.
+func (r *UserRepository) IsBlocked(ctx context.Context, senderID, receiverID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": receiverID, "blocked_users": senderID})
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %v", err)
+	}
+	return count > 0, nil
+}
+
+// GetUsersBlocking returns the users among userIDs who blocked targetID.
+func (r *UserRepository) GetUsersBlocking(ctx context.Context, targetID primitive.ObjectID, userIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection.Find(ctx,
+		bson.M{"_id": bson.M{"$in": userIDs}, "blocked_users": targetID},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check blocks: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode blocking users: %v", err)
+	}
+	ids := make([]primitive.ObjectID, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids, nil
+}
+
+// MarkWelcomeEmailSent sets the welcome_email_sent flag if it wasn't set yet.
+// It reports whether this call flipped the flag, so the email is sent only once.
+func (r *UserRepository) MarkWelcomeEmailSent(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.setFlagOnce(ctx, id, "welcome_email_sent")
+}
+
+// MarkGettingStartedSent sets the getting_started_sent flag if it wasn't set yet.
+func (r *UserRepository) MarkGettingStartedSent(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.setFlagOnce(ctx, id, "getting_started_sent")
+}
+
+func (r *UserRepository) setFlagOnce(ctx context.Context, id primitive.ObjectID, flag string) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, flag: bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{flag: true}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to set %s: %v", flag, err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetUsersPendingGettingStarted returns verified users who were verified before
+// the given time and haven't received the getting started nudge yet.
+func (r *UserRepository) GetUsersPendingGettingStarted(ctx context.Context, verifiedBefore time.Time) ([]models.User, error) {
+	filter := bson.M{
+		"is_verified":          true,
+		"getting_started_sent": bson.M{"$ne": true},
+		"verified_at":          bson.M{"$lte": verifiedBefore},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users pending getting started: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %v", err)
+	}
+	return users, nil
+}
+
+// UpdateLastActive moves the user's last_active_at forward to t. Older
+// timestamps are ignored thanks to $max.
+func (r *UserRepository) UpdateLastActive(ctx context.Context, id primitive.ObjectID, t time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$max": bson.M{"last_active_at": t}})
+	if err != nil {
+		return fmt.Errorf("failed to update last active time: %v", err)
+	}
+	return nil
+}
+
+// UpdateLastActiveBulk writes several users' last_active_at in one round trip
+func (r *UserRepository) UpdateLastActiveBulk(ctx context.Context, lastActive map[primitive.ObjectID]time.Time) error {
+	if len(lastActive) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(lastActive))
+	for id, t := range lastActive {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$max": bson.M{"last_active_at": t}}))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		logrus.WithError(err).WithField("count", len(writes)).Error("Failed to flush last active times")
+		return fmt.Errorf("failed to flush last active times: %v", err)
+	}
+	return nil
+}
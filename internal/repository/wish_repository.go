@@ -24,6 +24,7 @@ func NewWishRepository(db *mongo.Database) *WishRepository {
 func (r *WishRepository) CreateWish(ctx context.Context, wish *models.Wish) (*models.Wish, error) {
 	wish.CreatedAt = time.Now()
 	wish.UpdatedAt = time.Now()
+	wish.SchemaVersion = models.WishSchemaVersion
 
 	result, err := r.collection.InsertOne(ctx, wish)
 	if err != nil {
@@ -90,3 +91,40 @@ func (r *WishRepository) DeleteWish(ctx context.Context, id primitive.ObjectID)
 	}
 	return nil
 }
+
+// GetStaleWishes returns wishes created before olderThan that were never promoted
+// to a goal, oldest first. A zero userID returns stale wishes of every user.
+func (r *WishRepository) GetStaleWishes(ctx context.Context, userID primitive.ObjectID, olderThan time.Time) ([]models.Wish, error) {
+	filter := bson.M{
+		"created_at":       bson.M{"$lte": olderThan},
+		"promoted_goal_id": bson.M{"$exists": false},
+	}
+	if !userID.IsZero() {
+		filter["user_id"] = userID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale wishes: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var wishes []models.Wish
+	if err := cursor.All(ctx, &wishes); err != nil {
+		return nil, fmt.Errorf("failed to decode stale wishes: %v", err)
+	}
+	return wishes, nil
+}
+
+// MarkPromoted records the goal a wish was promoted to.
+func (r *WishRepository) MarkPromoted(ctx context.Context, id, goalID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"promoted_goal_id": goalID,
+		"updated_at":       time.Now(),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to mark wish as promoted: %v", err)
+	}
+	return nil
+}
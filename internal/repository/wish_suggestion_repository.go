@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrSuggestionNotPending is returned when accepting a suggestion twice.
+var ErrSuggestionNotPending = errors.New("wish suggestion was already accepted")
+
+type WishSuggestionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWishSuggestionRepository(db *mongo.Database) *WishSuggestionRepository {
+	return &WishSuggestionRepository{
+		collection: db.Collection("wish_suggestions"),
+	}
+}
+
+func (r *WishSuggestionRepository) CreateSuggestion(ctx context.Context, suggestion *models.WishSuggestion) (*models.WishSuggestion, error) {
+	suggestion.Status = models.WishSuggestionPending
+	suggestion.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, suggestion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wish suggestion: %v", err)
+	}
+	suggestion.ID = result.InsertedID.(primitive.ObjectID)
+	return suggestion, nil
+}
+
+func (r *WishSuggestionRepository) GetSuggestionByToken(ctx context.Context, token string) (*models.WishSuggestion, error) {
+	var suggestion models.WishSuggestion
+	if err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&suggestion); err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// MarkAccepted flips a pending suggestion to accepted and records the wish
+// created from it. It fails with ErrSuggestionNotPending if another request
+// accepted it first.
+func (r *WishSuggestionRepository) MarkAccepted(ctx context.Context, id, createdWishID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.WishSuggestionPending},
+		bson.M{"$set": bson.M{
+			"status":          models.WishSuggestionAccepted,
+			"accepted_at":     time.Now(),
+			"created_wish_id": createdWishID,
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to accept wish suggestion: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrSuggestionNotPending
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cron
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// schedule adds fn to c as the named job. Every run is recorded through jobs,
+// and the gap between two runs of spec becomes the job's expected interval.
+func schedule(c *cron.Cron, jobs *services.JobService, spec, name string, fn services.JobFunc) {
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		logrus.WithError(err).WithField("job", name).Error("Invalid cron spec")
+		return
+	}
+	next := sched.Next(time.Now())
+	jobs.Register(name, sched.Next(next).Sub(next))
+
+	c.Schedule(sched, cron.FuncJob(func() {
+		_ = jobs.Run(context.Background(), name, fn)
+	}))
+}
+
+// Every runs fn as the named job every interval in a new goroutine, recording
+// each run through jobs. With runNow the first run starts right away.
+func Every(jobs *services.JobService, name string, interval time.Duration, runNow bool, fn services.JobFunc) {
+	jobs.Register(name, interval)
+	go func() {
+		if runNow {
+			_ = jobs.Run(context.Background(), name, fn)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			_ = jobs.Run(context.Background(), name, fn)
+		}
+	}()
+}
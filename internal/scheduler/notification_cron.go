@@ -5,43 +5,64 @@ import (
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/services"
 	"github.com/robfig/cron/v3"
-	"github.com/sirupsen/logrus"
 )
 
-func StartNotificationCronJobs(notificationService *services.NotificationService) {
+func StartNotificationCronJobs(jobs *services.JobService, notificationService *services.NotificationService) {
 	c := cron.New()
 
 	// Inactive user reminders
-	c.AddFunc("0 0 * * *", func() {
-		err := notificationService.CheckInactiveUsers(context.Background())
-		if err != nil {
-			logrus.WithError(err).Error("CheckInactiveUsers failed")
-		}
+	schedule(c, jobs, "0 0 * * *", "inactive_users", func(ctx context.Context) (int, error) {
+		return 0, notificationService.CheckInactiveUsers(ctx)
 	})
 
 	// Step due soon
-	c.AddFunc("@hourly", func() {
-		err := notificationService.CheckStepDueSoon(context.Background())
-		if err != nil {
-			logrus.WithError(err).Error("CheckStepDueSoon failed")
-		}
+	schedule(c, jobs, "@hourly", "step_due_soon", func(ctx context.Context) (int, error) {
+		return 0, notificationService.CheckStepDueSoon(ctx)
 	})
 
 	// Goal due soon
-	c.AddFunc("@hourly", func() {
-		err := notificationService.CheckGoalDueSoon(context.Background())
-		if err != nil {
-			logrus.WithError(err).Error("CheckGoalDueSoon failed")
-		}
+	schedule(c, jobs, "@hourly", "goal_due_soon", func(ctx context.Context) (int, error) {
+		return 0, notificationService.CheckGoalDueSoon(ctx)
 	})
 
 	c.Start()
 
 	// Check substep deadlines hourly
-	c.AddFunc("@hourly", func() {
-		err := notificationService.CheckSubstepDueSoon(context.Background())
-		if err != nil {
-			logrus.WithError(err).Error("CheckSubstepDueSoon failed")
-		}
+	schedule(c, jobs, "@hourly", "substep_due_soon", func(ctx context.Context) (int, error) {
+		return 0, notificationService.CheckSubstepDueSoon(ctx)
 	})
 }
+
+// StartOnboardingCronJobs schedules the one-time nudges sent to new users.
+func StartOnboardingCronJobs(jobs *services.JobService, notificationService *services.NotificationService) *cron.Cron {
+	c := cron.New()
+
+	schedule(c, jobs, "@hourly", "getting_started", func(ctx context.Context) (int, error) {
+		return 0, notificationService.CheckGettingStarted(ctx)
+	})
+
+	c.Start()
+	return c
+}
+
+// StartWishCronJobs schedules the monthly stale wish review digest.
+func StartWishCronJobs(jobs *services.JobService, wishService *services.WishService) *cron.Cron {
+	c := cron.New()
+
+	schedule(c, jobs, "@monthly", "stale_wish_digest", func(ctx context.Context) (int, error) {
+		return 0, wishService.SendStaleWishDigests(ctx)
+	})
+
+	c.Start()
+	return c
+}
+
+// StartCounterCronJobs schedules the nightly recount of the dashboard counters.
+func StartCounterCronJobs(jobs *services.JobService, counterService *services.CounterService) *cron.Cron {
+	c := cron.New()
+
+	schedule(c, jobs, "0 3 * * *", "counter_reconcile", counterService.Reconcile)
+
+	c.Start()
+	return c
+}
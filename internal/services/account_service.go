@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/email"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// accountDeletionGracePeriod is how long a deletion request can be confirmed.
+const accountDeletionGracePeriod = 7 * 24 * time.Hour
+
+// ErrInvalidDeletionToken is returned when a deletion token is unknown,
+// belongs to another user or has expired.
+var ErrInvalidDeletionToken = errors.New("invalid or expired deletion token")
+
+// AccountService handles deleting a user together with the data they own.
+type AccountService struct {
+	userRepo            *repository.UserRepository
+	goalRepo            *repository.GoalRepository
+	notificationRepo    *repository.NotificationRepository
+	activityRepo        *repository.ActivityRepository
+	preferencesRepo     *repository.PreferencesRepository
+	badgeRepo           *repository.BadgeRepository
+	categoryRepo        *repository.CategoryRepository
+	notificationService *NotificationService
+	counters            *CounterService
+}
+
+// NewAccountService creates a new AccountService.
+func NewAccountService(userRepo *repository.UserRepository, goalRepo *repository.GoalRepository, notificationRepo *repository.NotificationRepository, activityRepo *repository.ActivityRepository, preferencesRepo *repository.PreferencesRepository, badgeRepo *repository.BadgeRepository, categoryRepo *repository.CategoryRepository, notificationService *NotificationService, counters *CounterService) *AccountService {
+	return &AccountService{
+		userRepo:            userRepo,
+		goalRepo:            goalRepo,
+		notificationRepo:    notificationRepo,
+		activityRepo:        activityRepo,
+		preferencesRepo:     preferencesRepo,
+		badgeRepo:           badgeRepo,
+		categoryRepo:        categoryRepo,
+		notificationService: notificationService,
+		counters:            counters,
+	}
+}
+
+// RequestDeletion marks the account for deletion and emails the user a
+// confirmation token that stays valid for the grace period.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID primitive.ObjectID) (time.Time, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get user: %v", err)
+	}
+
+	token := uuid.NewString()
+	scheduledFor := time.Now().Add(accountDeletionGracePeriod)
+	update := map[string]interface{}{
+		"deletion_token":         token,
+		"deletion_scheduled_for": scheduledFor,
+		"updated_at":             time.Now(),
+	}
+	if _, err := s.userRepo.UpdateUser(ctx, userID, update); err != nil {
+		return time.Time{}, fmt.Errorf("failed to schedule deletion: %v", err)
+	}
+
+	body := fmt.Sprintf("We received a request to delete your Achievement Manager account.\n\n"+
+		"To confirm, send DELETE /users/%s/confirm-deletion?token=%s before %s.\n"+
+		"You can download your goals first from GET /goals/export.\n\n"+
+		"If you did not request this, you can ignore this email and nothing will be deleted.",
+		userID.Hex(), token, scheduledFor.Format(time.RFC1123))
+	if err := email.SendEmail(user.Email, "Confirm your account deletion", body); err != nil {
+		logrus.WithError(err).Error("Failed to send account deletion email")
+		return time.Time{}, fmt.Errorf("failed to send confirmation email")
+	}
+
+	logrus.WithField("userID", userID.Hex()).Info("Account deletion requested")
+	return scheduledFor, nil
+}
+
+// ConfirmDeletion checks the token and permanently removes the user's goals,
+// friendships, notifications, activities, preferences, badges, counters, custom categories and finally the user record.
+// Collaborators on the removed goals are notified first.
+func (s *AccountService) ConfirmDeletion(ctx context.Context, userID primitive.ObjectID, token string) error {
+	user, err := s.userRepo.GetUserByDeletionToken(ctx, token)
+	if err != nil || token == "" || user.ID != userID || time.Now().After(user.DeletionScheduledFor) {
+		return ErrInvalidDeletionToken
+	}
+
+	goals, err := s.goalRepo.GetGoalsByOwner(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, goal := range goals {
+		for _, collab := range goal.Collaborators {
+			goalID := goal.ID
+			msg := fmt.Sprintf("%s deleted their account, so the goal %q has been removed.", user.Username, goal.Name)
+			if err := s.notificationService.CreateNotification(ctx, collab.UserID, "goal_owner_deleted", "Goal removed", msg, &goalID); err != nil {
+				logrus.WithError(err).Warn("Failed to notify collaborator about deleted owner")
+			}
+		}
+	}
+
+	if _, err := s.goalRepo.DeleteGoalsByOwner(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.goalRepo.RemoveCollaboratorEverywhere(ctx, userID); err != nil {
+		return err
+	}
+
+	for _, friendID := range user.Friends {
+		if err := s.userRepo.RemoveFriend(ctx, userID, friendID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.notificationRepo.DeleteUserNotifications(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.activityRepo.DeleteUserActivities(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.preferencesRepo.DeleteByUserID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.badgeRepo.DeleteUserBadges(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.counters.DeleteCounters(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.categoryRepo.DeleteUserCategories(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID": userID.Hex(),
+		"goals":  len(goals),
+	}).Info("Account deleted")
+	return nil
+}
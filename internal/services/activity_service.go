@@ -10,6 +10,19 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Page sizes for a goal's activity timeline.
+const (
+	DefaultActivityPageSize int64 = 20
+	MaxActivityPageSize     int64 = 100
+)
+
+// ActivityPage is one page of a goal's activity timeline or of the friend
+// activity feed. NextCursor is empty on the last page.
+type ActivityPage struct {
+	Activities []models.Activity `json:"activities"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
 type ActivityService struct {
 	repo *repository.ActivityRepository
 }
@@ -52,3 +65,37 @@ func (s *ActivityService) LogActivity(
 func (s *ActivityService) GetRecentActivities(ctx context.Context, userID primitive.ObjectID, limit int) ([]models.Activity, error) {
 	return s.repo.GetUserActivities(ctx, userID, limit)
 }
+
+// GetGoalActivity returns one page of the activity logged about a goal by
+// anyone, newest first. cursor is the ID of the last activity of the
+// previous page. Callers check that the user may see the goal.
+func (s *ActivityService) GetGoalActivity(ctx context.Context, goalID primitive.ObjectID, cursor string, limit int64) (*ActivityPage, error) {
+	if limit <= 0 {
+		limit = DefaultActivityPageSize
+	}
+	if limit > MaxActivityPageSize {
+		limit = MaxActivityPageSize
+	}
+
+	var before primitive.ObjectID
+	if cursor != "" {
+		var err error
+		before, err = primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	// Fetch one extra activity to know whether another page follows
+	activities, err := s.repo.GetActivitiesByTarget(ctx, goalID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ActivityPage{Activities: activities}
+	if int64(len(activities)) > limit {
+		page.Activities = activities[:limit]
+		page.NextCursor = page.Activities[limit-1].ID.Hex()
+	}
+	return page, nil
+}
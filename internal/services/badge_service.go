@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BadgeRule unlocks the badge Slug for users for whom Check returns true.
+type BadgeRule struct {
+	Slug  string
+	Check func(ctx context.Context, userID primitive.ObjectID) bool
+}
+
+// defaultBadges are seeded into the badges collection on startup.
+var defaultBadges = []models.Badge{
+	{Slug: "first_goal", Name: "First Step", Description: "Create your first goal."},
+	{Slug: "first_goal_completed", Name: "Finisher", Description: "Complete your first goal."},
+	{Slug: "five_goals_completed", Name: "High Five", Description: "Complete five goals."},
+	{Slug: "ten_goals_completed", Name: "Perfect Ten", Description: "Complete ten goals."},
+	{Slug: "team_goal_completed", Name: "Team Player", Description: "Complete a goal with five or more collaborators."},
+	{Slug: "seven_day_streak", Name: "On a Roll", Description: "Complete goals or substeps on seven days in a row."},
+	{Slug: "first_friend", Name: "Better Together", Description: "Add your first friend."},
+}
+
+// Thresholds for the team goal and streak badges.
+const (
+	teamGoalCollaborators = 5
+	streakBadgeDays       = 7
+)
+
+// BadgeService awards badges when users reach milestones.
+type BadgeService struct {
+	repo                *repository.BadgeRepository
+	goalRepo            *repository.GoalRepository
+	userRepo            *repository.UserRepository
+	notificationService *NotificationService
+	users               *UserService
+	rules               []BadgeRule
+}
+
+// NewBadgeService creates a new BadgeService with the default badge rules.
+func NewBadgeService(repo *repository.BadgeRepository, goalRepo *repository.GoalRepository, userRepo *repository.UserRepository, notificationService *NotificationService, users *UserService) *BadgeService {
+	s := &BadgeService{
+		repo:                repo,
+		goalRepo:            goalRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		users:               users,
+	}
+	s.rules = []BadgeRule{
+		{Slug: "first_goal", Check: s.goalCountAtLeast(1)},
+		{Slug: "first_goal_completed", Check: s.completedGoalsAtLeast(1)},
+		{Slug: "five_goals_completed", Check: s.completedGoalsAtLeast(5)},
+		{Slug: "ten_goals_completed", Check: s.completedGoalsAtLeast(10)},
+		{Slug: "team_goal_completed", Check: s.completedTeamGoal},
+		{Slug: "seven_day_streak", Check: s.longestStreakAtLeast(streakBadgeDays)},
+		{Slug: "first_friend", Check: s.hasFriend},
+	}
+	return s
+}
+
+// SeedBadges makes sure every default badge exists in the badges collection.
+func (s *BadgeService) SeedBadges(ctx context.Context) error {
+	for _, badge := range defaultBadges {
+		if err := s.repo.UpsertBadge(ctx, badge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserBadges returns the badges the user unlocked.
+func (s *BadgeService) GetUserBadges(ctx context.Context, userID primitive.ObjectID) ([]models.UserBadge, error) {
+	badges, err := s.repo.GetUserBadges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if badges == nil {
+		badges = []models.UserBadge{}
+	}
+	return badges, nil
+}
+
+// CheckAndAwardBadges evaluates every rule the user has not unlocked yet and
+// awards the ones that now pass, notifying the user about each. Failures are
+// logged rather than returned so callers can fire it after any key event.
+func (s *BadgeService) CheckAndAwardBadges(ctx context.Context, userID primitive.ObjectID) {
+	earned, err := s.repo.GetUserBadges(ctx, userID)
+	if err != nil {
+		logger.Log.WithError(err).Warn("Failed to load user badges")
+		return
+	}
+	unlocked := make(map[string]bool, len(earned))
+	for _, badge := range earned {
+		unlocked[badge.BadgeSlug] = true
+	}
+
+	for _, rule := range s.rules {
+		if unlocked[rule.Slug] || !rule.Check(ctx, userID) {
+			continue
+		}
+		awarded, err := s.repo.AwardBadge(ctx, userID, rule.Slug, time.Now())
+		if err != nil {
+			logger.Log.WithError(err).WithField("badge", rule.Slug).Warn("Failed to award badge")
+			continue
+		}
+		if !awarded {
+			continue
+		}
+
+		name := rule.Slug
+		for _, badge := range defaultBadges {
+			if badge.Slug == rule.Slug {
+				name = badge.Name
+			}
+		}
+		msg := fmt.Sprintf("You unlocked the \"%s\" badge!", name)
+		if err := s.notificationService.CreateNotification(ctx, userID, "badge_unlocked", "🏅 Badge unlocked", msg, nil); err != nil {
+			logger.Log.WithError(err).Warn("Failed to send badge_unlocked notification")
+		}
+		logger.Log.WithFields(map[string]interface{}{
+			"user_id": userID.Hex(),
+			"badge":   rule.Slug,
+		}).Info("Badge unlocked")
+	}
+}
+
+func (s *BadgeService) goalCountAtLeast(n int64) func(context.Context, primitive.ObjectID) bool {
+	return func(ctx context.Context, userID primitive.ObjectID) bool {
+		count, err := s.goalRepo.CountGoalsOutsideTrash(ctx, userID)
+		return err == nil && count >= n
+	}
+}
+
+func (s *BadgeService) completedGoalsAtLeast(n int64) func(context.Context, primitive.ObjectID) bool {
+	return func(ctx context.Context, userID primitive.ObjectID) bool {
+		count, err := s.goalRepo.CountCompletedGoals(ctx, userID)
+		return err == nil && count >= n
+	}
+}
+
+func (s *BadgeService) completedTeamGoal(ctx context.Context, userID primitive.ObjectID) bool {
+	count, err := s.goalRepo.CountCompletedGoalsWithCollaborators(ctx, userID, teamGoalCollaborators)
+	return err == nil && count > 0
+}
+
+func (s *BadgeService) longestStreakAtLeast(days int) func(context.Context, primitive.ObjectID) bool {
+	return func(ctx context.Context, userID primitive.ObjectID) bool {
+		streak, err := s.users.GetCompletionStreak(ctx, userID)
+		return err == nil && streak.LongestStreak >= days
+	}
+}
+
+func (s *BadgeService) hasFriend(ctx context.Context, userID primitive.ObjectID) bool {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	return err == nil && len(user.Friends) > 0
+}
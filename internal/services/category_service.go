@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MaxCategoryNameLen caps the length of a custom category name, in characters.
+const MaxCategoryNameLen = 30
+
+// Errors returned when managing custom categories.
+var (
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrCategoryExists   = errors.New("category already exists")
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryInUse    = errors.New("category is still used by goals")
+)
+
+// CategoryList is the set of categories a user can put goals in.
+type CategoryList struct {
+	BuiltIn []string              `json:"built_in"`
+	Custom  []models.UserCategory `json:"custom"`
+}
+
+// CategoryService manages user-defined goal categories.
+type CategoryService struct {
+	repo     *repository.CategoryRepository
+	goalRepo *repository.GoalRepository
+}
+
+// NewCategoryService creates a new CategoryService.
+func NewCategoryService(repo *repository.CategoryRepository, goalRepo *repository.GoalRepository) *CategoryService {
+	return &CategoryService{repo: repo, goalRepo: goalRepo}
+}
+
+// IsValidCategory reports whether userID may put goals in the category: it
+// is either built in or one of the user's custom categories.
+func (s *CategoryService) IsValidCategory(ctx context.Context, userID primitive.ObjectID, name string) bool {
+	if models.AllowedCategories[name] {
+		return true
+	}
+	ok, err := s.repo.HasCategory(ctx, userID, name)
+	if err != nil {
+		logger.Log.WithError(err).Warn("Failed to check custom category")
+		return false
+	}
+	return ok
+}
+
+// ListCategories returns the built-in categories and the user's own.
+func (s *CategoryService) ListCategories(ctx context.Context, userID primitive.ObjectID) (*CategoryList, error) {
+	custom, err := s.repo.GetCategories(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if custom == nil {
+		custom = []models.UserCategory{}
+	}
+
+	builtIn := make([]string, 0, len(models.AllowedCategories))
+	for name := range models.AllowedCategories {
+		builtIn = append(builtIn, name)
+	}
+	sort.Strings(builtIn)
+	return &CategoryList{BuiltIn: builtIn, Custom: custom}, nil
+}
+
+// CreateCategory adds a custom category for the user.
+func (s *CategoryService) CreateCategory(ctx context.Context, userID primitive.ObjectID, name string) (*models.UserCategory, error) {
+	name, err := normalizeCategoryName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.repo.CreateCategory(ctx, &models.UserCategory{UserID: userID, Name: name})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrCategoryExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category: %v", err)
+	}
+	return category, nil
+}
+
+// RenameCategory renames a custom category and moves the user's goals along.
+func (s *CategoryService) RenameCategory(ctx context.Context, userID primitive.ObjectID, categoryID, name string) (*models.UserCategory, error) {
+	category, err := s.getCategory(ctx, userID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	name, err = normalizeCategoryName(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == category.Name {
+		return category, nil
+	}
+
+	err = s.repo.RenameCategory(ctx, category.ID, name)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrCategoryExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename category: %v", err)
+	}
+	if _, err := s.goalRepo.ReplaceCategory(ctx, userID, category.Name, name); err != nil {
+		return nil, err
+	}
+
+	category.Name = name
+	return category, nil
+}
+
+// DeleteCategory removes a custom category. If goals still use it the call
+// fails with ErrCategoryInUse, unless reassign is set, in which case those
+// goals are left without a category.
+func (s *CategoryService) DeleteCategory(ctx context.Context, userID primitive.ObjectID, categoryID string, reassign bool) error {
+	category, err := s.getCategory(ctx, userID, categoryID)
+	if err != nil {
+		return err
+	}
+
+	inUse, err := s.goalRepo.CountGoalsInCategory(ctx, userID, category.Name)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		if !reassign {
+			return fmt.Errorf("%w: %d goals", ErrCategoryInUse, inUse)
+		}
+		if _, err := s.goalRepo.ReplaceCategory(ctx, userID, category.Name, ""); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteCategory(ctx, category.ID)
+}
+
+func (s *CategoryService) getCategory(ctx context.Context, userID primitive.ObjectID, categoryID string) (*models.UserCategory, error) {
+	objID, err := primitive.ObjectIDFromHex(categoryID)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	category, err := s.repo.GetCategory(ctx, userID, objID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCategoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %v", err)
+	}
+	return category, nil
+}
+
+// normalizeCategoryName trims the name and checks its length. Names that
+// clash with a built-in category, ignoring case, are rejected.
+func normalizeCategoryName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxCategoryNameLen {
+		return "", fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidCategory, MaxCategoryNameLen)
+	}
+	for builtIn := range models.AllowedCategories {
+		if strings.EqualFold(name, builtIn) {
+			return "", fmt.Errorf("%w: %q is a built-in category", ErrCategoryExists, builtIn)
+		}
+	}
+	return name, nil
+}
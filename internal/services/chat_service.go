@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Limits on direct chats.
+const (
+	MaxMessageLength        = 4000
+	DefaultChatHistoryLimit = 50
+	MaxChatHistoryLimit     = 100
+	MaxReactionEmoji        = 8  // different emoji per message
+	MaxReactionLength       = 16 // runes, enough for ZWJ sequences
+)
+
+// Errors returned by ChatService.
+var (
+	ErrInvalidMessage   = errors.New("invalid message")
+	ErrChatNotFriend    = errors.New("you can only message your friends")
+	ErrMessageNotFound  = errors.New("message not found")
+	ErrMessageNotOwner  = errors.New("only the sender can delete a message")
+	ErrInvalidReaction  = errors.New("invalid reaction")
+	ErrTooManyReactions = fmt.Errorf("a message can have at most %d different reactions", MaxReactionEmoji)
+)
+
+// MessagePage is one page of a direct chat's history, newest first.
+// NextCursor is empty on the last page.
+type MessagePage struct {
+	Messages   []models.Message `json:"messages"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// ChatService stores direct messages between friends and pushes chat events
+// to both parties over the hub. In frames sent to a user, chat_id is the ID
+// of the other party.
+type ChatService struct {
+	chatRepo *repository.ChatRepository
+	userRepo *repository.UserRepository
+	hub      *hub.Hub // may be nil
+}
+
+func NewChatService(chatRepo *repository.ChatRepository, userRepo *repository.UserRepository, wsHub *hub.Hub) *ChatService {
+	return &ChatService{
+		chatRepo: chatRepo,
+		userRepo: userRepo,
+		hub:      wsHub,
+	}
+}
+
+// SendMessage stores a text message from senderID to one of their friends
+// and pushes it to the receiver as a text frame.
+func (s *ChatService) SendMessage(ctx context.Context, senderID, receiverID primitive.ObjectID, text string) (*models.Message, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: message text is required", ErrInvalidMessage)
+	}
+	if utf8.RuneCountInString(text) > MaxMessageLength {
+		return nil, fmt.Errorf("%w: message must be at most %d characters", ErrInvalidMessage, MaxMessageLength)
+	}
+
+	// Blocking a user ends the friendship, so this also keeps blocked users out
+	friendIDs, err := s.userRepo.GetFriendIDs(ctx, senderID)
+	if err != nil {
+		return nil, err
+	}
+	if !containsID(friendIDs, receiverID) {
+		return nil, ErrChatNotFriend
+	}
+
+	message, err := s.chatRepo.CreateMessage(ctx, &models.Message{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Type:       models.MessageText,
+		Text:       text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.send(receiverID, wsproto.Text{
+		ChatID:    senderID.Hex(),
+		MessageID: message.ID.Hex(),
+		SenderID:  senderID.Hex(),
+		Content:   message.Text,
+		SentAt:    &message.CreatedAt,
+	})
+	return message, nil
+}
+
+// GetChatHistory returns one page of the messages between userID and
+// otherID, newest first, after marking otherID's messages as read by userID.
+// Deleted messages appear as tombstones of type "deleted". cursor is the
+// NextCursor of the previous page, or empty for the first page.
+func (s *ChatService) GetChatHistory(ctx context.Context, userID, otherID primitive.ObjectID, cursor string, limit int64) (*MessagePage, error) {
+	if limit <= 0 {
+		limit = DefaultChatHistoryLimit
+	}
+	if limit > MaxChatHistoryLimit {
+		limit = MaxChatHistoryLimit
+	}
+
+	var before primitive.ObjectID
+	if cursor != "" {
+		var err error
+		before, err = primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidMessage)
+		}
+	}
+
+	// Marked first so the page already carries the read_at times
+	if err := s.MarkMessagesAsRead(ctx, userID, otherID); err != nil {
+		logrus.WithError(err).WithField("user_id", userID.Hex()).Warn("Failed to mark chat messages as read")
+	}
+
+	// Fetch one extra message to know whether another page follows
+	messages, err := s.chatRepo.GetChat(ctx, userID, otherID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &MessagePage{Messages: messages}
+	if int64(len(messages)) > limit {
+		page.Messages = messages[:limit]
+		page.NextCursor = page.Messages[limit-1].ID.Hex()
+	}
+	return page, nil
+}
+
+// MarkMessagesAsRead marks every message senderID sent to readerID as read
+// and sends senderID a read frame whose until is the read time.
+func (s *ChatService) MarkMessagesAsRead(ctx context.Context, readerID, senderID primitive.ObjectID) error {
+	now := time.Now()
+	marked, err := s.chatRepo.MarkMessagesAsRead(ctx, readerID, senderID, now)
+	if err != nil || marked == 0 {
+		return err
+	}
+	s.send(senderID, wsproto.Read{
+		ChatID: readerID.Hex(),
+		UserID: readerID.Hex(),
+		Until:  &now,
+	})
+	return nil
+}
+
+// CountUnread counts the messages senderID sent to readerID that readerID
+// has not read yet.
+func (s *ChatService) CountUnread(ctx context.Context, readerID, senderID primitive.ObjectID) (int64, error) {
+	return s.chatRepo.CountUnreadMessages(ctx, readerID, senderID)
+}
+
+// DeleteMessage soft-deletes a message written by userID and tells both
+// parties to replace it with a tombstone. Deleting a message twice is not an
+// error.
+func (s *ChatService) DeleteMessage(ctx context.Context, messageID, userID primitive.ObjectID) error {
+	message, err := s.getMessageForParty(ctx, messageID, userID)
+	if err != nil {
+		return err
+	}
+	if message.SenderID != userID {
+		return ErrMessageNotOwner
+	}
+	if message.DeletedAt != nil {
+		return nil
+	}
+
+	tombstone, err := s.chatRepo.SoftDeleteMessage(ctx, messageID, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Deleted concurrently; that request already told both parties
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.sendToBoth(tombstone, func(chatID string) wsproto.Frame {
+		return wsproto.MessageDeleted{
+			ChatID:    chatID,
+			MessageID: tombstone.ID.Hex(),
+			DeletedBy: userID.Hex(),
+			DeletedAt: tombstone.DeletedAt,
+		}
+	})
+	return nil
+}
+
+// AddReaction adds userID's emoji reaction to a message they sent or
+// received and tells both parties.
+func (s *ChatService) AddReaction(ctx context.Context, messageID, userID primitive.ObjectID, emoji string) error {
+	message, err := s.getReactionTarget(ctx, messageID, userID, emoji)
+	if err != nil {
+		return err
+	}
+	changed, err := s.chatRepo.AddReaction(ctx, messageID, userID, emoji, MaxReactionEmoji)
+	if errors.Is(err, repository.ErrReactionLimit) {
+		return ErrTooManyReactions
+	}
+	if err != nil {
+		return err
+	}
+	if changed {
+		s.sendReaction(message, userID, emoji, false)
+	}
+	return nil
+}
+
+// RemoveReaction takes back userID's emoji reaction on a message and tells
+// both parties. Removing a reaction that is not there is not an error.
+func (s *ChatService) RemoveReaction(ctx context.Context, messageID, userID primitive.ObjectID, emoji string) error {
+	message, err := s.getReactionTarget(ctx, messageID, userID, emoji)
+	if err != nil {
+		return err
+	}
+	changed, err := s.chatRepo.RemoveReaction(ctx, messageID, userID, emoji)
+	if err != nil {
+		return err
+	}
+	if changed {
+		s.sendReaction(message, userID, emoji, true)
+	}
+	return nil
+}
+
+// getReactionTarget checks the emoji and returns the message being reacted
+// to, which must be visible to userID and not deleted.
+func (s *ChatService) getReactionTarget(ctx context.Context, messageID, userID primitive.ObjectID, emoji string) (*models.Message, error) {
+	// Emoji become field names in the reactions map
+	if emoji == "" || utf8.RuneCountInString(emoji) > MaxReactionLength || strings.ContainsAny(emoji, ".$ \t\n") {
+		return nil, fmt.Errorf("%w: emoji must be 1 to %d characters without dots, dollar signs or spaces", ErrInvalidReaction, MaxReactionLength)
+	}
+	message, err := s.getMessageForParty(ctx, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+func (s *ChatService) sendReaction(message *models.Message, userID primitive.ObjectID, emoji string, removed bool) {
+	s.sendToBoth(message, func(chatID string) wsproto.Frame {
+		return wsproto.Reaction{
+			ChatID:    chatID,
+			MessageID: message.ID.Hex(),
+			UserID:    userID.Hex(),
+			Emoji:     emoji,
+			Removed:   removed,
+		}
+	})
+}
+
+// getMessageForParty returns a message if userID sent or received it.
+// Other users' messages are reported as not found.
+func (s *ChatService) getMessageForParty(ctx context.Context, messageID, userID primitive.ObjectID) (*models.Message, error) {
+	message, err := s.chatRepo.GetMessageByID(ctx, messageID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if message.SenderID != userID && message.ReceiverID != userID {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+// sendToBoth sends a frame about message to its sender and receiver. frame
+// builds the frame for a given chat_id, which is the other party's ID.
+func (s *ChatService) sendToBoth(message *models.Message, frame func(chatID string) wsproto.Frame) {
+	s.send(message.SenderID, frame(message.ReceiverID.Hex()))
+	s.send(message.ReceiverID, frame(message.SenderID.Hex()))
+}
+
+// send pushes a frame to every open connection of userID.
+func (s *ChatService) send(userID primitive.ObjectID, frame wsproto.Frame) {
+	if s.hub == nil {
+		return
+	}
+	err := s.hub.Send(userID.Hex(), frame)
+	if err != nil && !errors.Is(err, hub.ErrOffline) {
+		logrus.WithError(err).WithFields(logrus.Fields{"user_id": userID.Hex(), "type": frame.FrameType()}).Debug("Failed to send chat frame")
+	}
+}
+
+// containsID reports whether ids contains id.
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
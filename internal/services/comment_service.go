@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Limits on goal comments.
+const (
+	MaxCommentLength        = 2000
+	DefaultCommentsPageSize = 20
+	MaxCommentsPageSize     = 100
+)
+
+// Errors returned when deleting a comment.
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentForbidden = errors.New("only the author or the goal owner can delete this comment")
+)
+
+// CommentPage is one page of a goal's comment thread. NextCursor is empty on the last page.
+type CommentPage struct {
+	Comments   []models.Comment `json:"comments"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type CommentService struct {
+	repo *repository.CommentRepository
+}
+
+func NewCommentService(repo *repository.CommentRepository) *CommentService {
+	return &CommentService{repo: repo}
+}
+
+// AddComment posts a comment on the goal. Callers check that the user may comment.
+func (s *CommentService) AddComment(ctx context.Context, goal *models.Goal, userID primitive.ObjectID, text string) (*models.Comment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("comment text is required")
+	}
+	if utf8.RuneCountInString(text) > MaxCommentLength {
+		return nil, fmt.Errorf("comment must be at most %d characters", MaxCommentLength)
+	}
+
+	return s.repo.CreateComment(ctx, &models.Comment{
+		GoalID: goal.ID,
+		UserID: userID,
+		Text:   text,
+	})
+}
+
+// GetComments returns one page of the goal's comments, oldest first.
+func (s *CommentService) GetComments(ctx context.Context, goalID primitive.ObjectID, cursor string, limit int64) (*CommentPage, error) {
+	if limit <= 0 {
+		limit = DefaultCommentsPageSize
+	}
+	if limit > MaxCommentsPageSize {
+		limit = MaxCommentsPageSize
+	}
+
+	var after primitive.ObjectID
+	if cursor != "" {
+		var err error
+		after, err = primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+
+	// Fetch one extra comment to know whether another page follows
+	comments, err := s.repo.GetCommentsByGoal(ctx, goalID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CommentPage{Comments: comments}
+	if int64(len(comments)) > limit {
+		page.Comments = comments[:limit]
+		page.NextCursor = page.Comments[limit-1].ID.Hex()
+	}
+	return page, nil
+}
+
+// DeleteComment soft-deletes a comment on the goal. Only its author or the
+// goal owner may delete it.
+func (s *CommentService) DeleteComment(ctx context.Context, goal *models.Goal, commentID string, userID primitive.ObjectID) error {
+	objID, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return ErrCommentNotFound
+	}
+
+	comment, err := s.repo.GetCommentByID(ctx, objID)
+	if err != nil || comment.GoalID != goal.ID {
+		return ErrCommentNotFound
+	}
+	if comment.UserID != userID && goal.UserID != userID {
+		return ErrCommentForbidden
+	}
+
+	return s.repo.SoftDeleteComment(ctx, objID)
+}
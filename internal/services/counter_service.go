@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/metrics"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CounterService keeps the per-user dashboard counters up to date. The
+// event methods never fail the caller: a lost increment is logged and fixed
+// by the next Reconcile.
+type CounterService struct {
+	repo             *repository.CounterRepository
+	userRepo         *repository.UserRepository
+	goalRepo         *repository.GoalRepository
+	notificationRepo *repository.NotificationRepository
+	friendRepo       *repository.FriendRepository
+}
+
+// NewCounterService creates a new CounterService.
+func NewCounterService(repo *repository.CounterRepository, userRepo *repository.UserRepository, goalRepo *repository.GoalRepository, notificationRepo *repository.NotificationRepository, friendRepo *repository.FriendRepository) *CounterService {
+	return &CounterService{
+		repo:             repo,
+		userRepo:         userRepo,
+		goalRepo:         goalRepo,
+		notificationRepo: notificationRepo,
+		friendRepo:       friendRepo,
+	}
+}
+
+// GoalCreated counts a new goal for its owner and in goals_created_total.
+func (s *CounterService) GoalCreated(ctx context.Context, goal *models.Goal) {
+	metrics.GoalsCreated.Inc()
+	s.countGoal(ctx, goal.UserID, goal.Status, 1)
+}
+
+// GoalStatusChanged moves a goal between the owner's status counts and
+// adjusts the completed goal counter when it enters or leaves the completed
+// status.
+func (s *CounterService) GoalStatusChanged(ctx context.Context, ownerID primitive.ObjectID, previousStatus, status string) {
+	if previousStatus == status {
+		return
+	}
+	if previousStatus != "" {
+		s.add(ctx, ownerID, models.GoalStatusCounter(previousStatus), -1)
+	}
+	if status != "" {
+		s.add(ctx, ownerID, models.GoalStatusCounter(status), 1)
+	}
+	switch {
+	case previousStatus != "completed" && status == "completed":
+		s.add(ctx, ownerID, models.CounterCompletedGoals, 1)
+	case previousStatus == "completed" && status != "completed":
+		s.add(ctx, ownerID, models.CounterCompletedGoals, -1)
+	}
+}
+
+// GoalTrashed stops counting a goal that was moved to the trash. status is
+// the goal's status before it was trashed.
+func (s *CounterService) GoalTrashed(ctx context.Context, ownerID primitive.ObjectID, status string) {
+	s.countGoal(ctx, ownerID, status, -1)
+}
+
+// GoalRestored counts a goal again after it left the trash with the given status.
+func (s *CounterService) GoalRestored(ctx context.Context, ownerID primitive.ObjectID, status string) {
+	s.countGoal(ctx, ownerID, status, 1)
+}
+
+// GoalTransferred moves a goal with the given status from its previous
+// owner's counters to the new owner's.
+func (s *CounterService) GoalTransferred(ctx context.Context, previousOwnerID, newOwnerID primitive.ObjectID, status string) {
+	s.countGoal(ctx, previousOwnerID, status, -1)
+	s.countGoal(ctx, newOwnerID, status, 1)
+}
+
+// NotificationCreated counts a new unread notification.
+func (s *CounterService) NotificationCreated(ctx context.Context, userID primitive.ObjectID) {
+	s.add(ctx, userID, models.CounterUnreadNotifications, 1)
+}
+
+// NotificationRead uncounts a notification that was unread until now.
+func (s *CounterService) NotificationRead(ctx context.Context, userID primitive.ObjectID) {
+	s.add(ctx, userID, models.CounterUnreadNotifications, -1)
+}
+
+// FriendRequestCreated counts a request waiting for the receiver's answer.
+func (s *CounterService) FriendRequestCreated(ctx context.Context, receiverID primitive.ObjectID) {
+	s.add(ctx, receiverID, models.CounterPendingFriendRequests, 1)
+}
+
+// FriendRequestResolved uncounts a request the receiver answered.
+func (s *CounterService) FriendRequestResolved(ctx context.Context, receiverID primitive.ObjectID) {
+	s.add(ctx, receiverID, models.CounterPendingFriendRequests, -1)
+}
+
+// GetCounters returns the user's counters, computing them on first use.
+func (s *CounterService) GetCounters(ctx context.Context, userID primitive.ObjectID) (*models.UserCounters, error) {
+	counters, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return s.reconcileUser(ctx, userID)
+	}
+	return counters, err
+}
+
+// GetCountersFor returns the stored counters of several users, keyed by user
+// ID. Users whose counters were never computed are missing from the map.
+func (s *CounterService) GetCountersFor(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]models.UserCounters, error) {
+	return s.repo.GetMany(ctx, userIDs)
+}
+
+// DeleteCounters removes the user's counters, e.g. when the account is deleted.
+func (s *CounterService) DeleteCounters(ctx context.Context, userID primitive.ObjectID) error {
+	return s.repo.DeleteByUserID(ctx, userID)
+}
+
+// Reconcile recomputes every user's counters from the source collections and
+// returns how many users were updated.
+func (s *CounterService) Reconcile(ctx context.Context) (int, error) {
+	users, err := s.userRepo.GetAllUsers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, user := range users {
+		if _, err := s.reconcileUser(ctx, user.ID); err != nil {
+			logger.Log.WithError(err).WithField("user_id", user.ID.Hex()).Warn("Failed to reconcile user counters")
+			continue
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// ReconcileUser recomputes one user's counters from the source collections.
+func (s *CounterService) ReconcileUser(ctx context.Context, userID primitive.ObjectID) (*models.UserCounters, error) {
+	return s.reconcileUser(ctx, userID)
+}
+
+func (s *CounterService) reconcileUser(ctx context.Context, userID primitive.ObjectID) (*models.UserCounters, error) {
+	counters := &models.UserCounters{UserID: userID}
+	var err error
+	if counters.Goals, err = s.goalRepo.CountGoalsOutsideTrash(ctx, userID); err != nil {
+		return nil, err
+	}
+	if counters.CompletedGoals, err = s.goalRepo.CountCompletedGoals(ctx, userID); err != nil {
+		return nil, err
+	}
+	if counters.GoalsByStatus, err = s.goalRepo.CountGoalsByStatus(ctx, userID); err != nil {
+		return nil, err
+	}
+	if counters.UnreadNotifications, err = s.notificationRepo.CountUnread(ctx, userID, time.Now()); err != nil {
+		return nil, err
+	}
+	if counters.PendingFriendRequests, err = s.friendRepo.CountPendingRequests(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Replace(ctx, counters); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// countGoal adds delta to the owner's goal, completed goal and status counts
+// for one goal with the given status.
+func (s *CounterService) countGoal(ctx context.Context, ownerID primitive.ObjectID, status string, delta int64) {
+	s.add(ctx, ownerID, models.CounterGoals, delta)
+	if status != "" {
+		s.add(ctx, ownerID, models.GoalStatusCounter(status), delta)
+	}
+	if status == "completed" {
+		s.add(ctx, ownerID, models.CounterCompletedGoals, delta)
+	}
+}
+
+func (s *CounterService) add(ctx context.Context, userID primitive.ObjectID, field string, delta int64) {
+	if err := s.repo.Increment(ctx, userID, field, delta); err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Warn("Failed to update user counter")
+	}
+}
@@ -0,0 +1,288 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// assertCounters checks the stored goal counters against want and every
+// stored counter against a fresh count of the source collections, so an
+// event applied twice or not at all shows up as drift.
+func assertCounters(t *testing.T, svc *testutil.Services, userID primitive.ObjectID, wantGoals, wantCompleted int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	stored, err := svc.Counters.Get(ctx, userID)
+	if err != nil {
+		t.Fatalf("failed to load counters: %v", err)
+	}
+	if stored.Goals != wantGoals || stored.CompletedGoals != wantCompleted {
+		t.Errorf("goals/completed = %d/%d, want %d/%d", stored.Goals, stored.CompletedGoals, wantGoals, wantCompleted)
+	}
+
+	actual := models.UserCounters{UserID: userID}
+	if actual.Goals, err = svc.Goals.CountGoalsOutsideTrash(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if actual.CompletedGoals, err = svc.Goals.CountCompletedGoals(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if actual.UnreadNotifications, err = svc.Notifications.CountUnread(ctx, userID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if actual.PendingFriendRequests, err = svc.Friends.CountPendingRequests(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if actual.GoalsByStatus, err = svc.Goals.CountGoalsByStatus(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Goals != actual.Goals || stored.CompletedGoals != actual.CompletedGoals ||
+		stored.UnreadNotifications != actual.UnreadNotifications || stored.PendingFriendRequests != actual.PendingFriendRequests ||
+		!sameStatusCounts(stored.GoalsByStatus, actual.GoalsByStatus) {
+		t.Errorf("stored counters %+v drifted from the collections %+v", *stored, actual)
+	}
+}
+
+// sameStatusCounts compares two status counts, treating a status counted
+// down to zero like one that was never counted.
+func sameStatusCounts(a, b map[string]int64) bool {
+	for status, n := range a {
+		if b[status] != n {
+			return false
+		}
+	}
+	for status, n := range b {
+		if a[status] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func completeGoal(t *testing.T, svc *testutil.Services, ownerID primitive.ObjectID, goalID primitive.ObjectID) {
+	t.Helper()
+	ctx := context.Background()
+	goal, err := svc.Goal.GetGoal(ctx, goalID.Hex())
+	if err != nil {
+		t.Fatalf("GetGoal: %v", err)
+	}
+	for i := range goal.Steps {
+		for j := range goal.Steps[i].Substeps {
+			goal.Steps[i].Substeps[j].Done = true
+		}
+	}
+	if _, err := svc.Goal.UpdateGoal(ctx, goalID.Hex(), goal, ownerID); err != nil {
+		t.Fatalf("UpdateGoal: %v", err)
+	}
+}
+
+// steadyUser seeds a user halfway through level 10, so the XP a few goals
+// are worth never levels them up and the background award cannot raise a
+// level_up notification in the middle of a counter check.
+func steadyUser(t *testing.T, svc *testutil.Services) *models.User {
+	t.Helper()
+	return testutil.SeedUser(t, svc.Repositories, models.User{XP: 11000, Level: services.LevelForXP(11000)})
+}
+
+func oneStepGoal(name string) *models.Goal {
+	return &models.Goal{
+		Name:  name,
+		Steps: []models.Step{{Name: "step", Substeps: []models.Substep{{Title: "only"}}}},
+	}
+}
+
+func TestGoalCountersAdjustOnce(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := steadyUser(t, svc)
+
+	var ids []primitive.ObjectID
+	for _, name := range []string{"a", "b", "c"} {
+		goal := oneStepGoal(name)
+		goal.UserID = owner.ID
+		created, err := svc.Goal.CreateGoal(ctx, goal)
+		if err != nil {
+			t.Fatalf("CreateGoal: %v", err)
+		}
+		ids = append(ids, created.ID)
+	}
+	assertCounters(t, svc, owner.ID, 3, 0)
+
+	completeGoal(t, svc, owner.ID, ids[0])
+	assertCounters(t, svc, owner.ID, 3, 1)
+
+	// Saving a completed goal again is not a second completion
+	completeGoal(t, svc, owner.ID, ids[0])
+	assertCounters(t, svc, owner.ID, 3, 1)
+
+	if err := svc.Goal.DeleteGoal(ctx, ids[1].Hex()); err != nil {
+		t.Fatalf("DeleteGoal: %v", err)
+	}
+	if err := svc.Goal.DeleteGoal(ctx, ids[1].Hex()); err != nil {
+		t.Fatalf("DeleteGoal again: %v", err)
+	}
+	assertCounters(t, svc, owner.ID, 2, 1)
+
+	trashed, err := svc.Goals.GetGoalByID(ctx, ids[1])
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	if err := svc.Goal.RestoreGoal(ctx, trashed); err != nil {
+		t.Fatalf("RestoreGoal: %v", err)
+	}
+	if err := svc.Goal.RestoreGoal(ctx, trashed); err == nil {
+		t.Fatal("restoring a goal that left the trash should fail")
+	}
+	assertCounters(t, svc, owner.ID, 3, 1)
+}
+
+func TestBulkUpdateGoalsAdjustsCountersOnce(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := steadyUser(t, svc)
+
+	var ids []string
+	for _, name := range []string{"a", "b", "c"} {
+		goal := oneStepGoal(name)
+		goal.UserID = owner.ID
+		created, err := svc.Goal.CreateGoal(ctx, goal)
+		if err != nil {
+			t.Fatalf("CreateGoal: %v", err)
+		}
+		ids = append(ids, created.ID.Hex())
+	}
+	a, _ := primitive.ObjectIDFromHex(ids[0])
+	c, _ := primitive.ObjectIDFromHex(ids[2])
+	completeGoal(t, svc, owner.ID, a)
+	completeGoal(t, svc, owner.ID, c)
+	assertCounters(t, svc, owner.ID, 3, 2)
+
+	// Archiving takes a and c out of completed, b was never counted
+	if _, _, err := svc.Goal.BulkUpdateGoals(ctx, owner.ID, "archive", ids[:2], ""); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	assertCounters(t, svc, owner.ID, 3, 1)
+
+	if _, _, err := svc.Goal.BulkUpdateGoals(ctx, owner.ID, "set_status", ids[:2], "in_progress"); err != nil {
+		t.Fatalf("set_status: %v", err)
+	}
+	assertCounters(t, svc, owner.ID, 3, 1)
+
+	if _, _, err := svc.Goal.BulkUpdateGoals(ctx, owner.ID, "set_category", ids, "Health"); err != nil {
+		t.Fatalf("set_category: %v", err)
+	}
+	assertCounters(t, svc, owner.ID, 3, 1)
+
+	if _, _, err := svc.Goal.BulkUpdateGoals(ctx, owner.ID, "delete", ids[1:], ""); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	assertCounters(t, svc, owner.ID, 1, 0)
+
+	// Trashed goals are rejected, so a repeated delete changes nothing
+	if _, _, err := svc.Goal.BulkUpdateGoals(ctx, owner.ID, "delete", ids[1:], ""); !errors.Is(err, services.ErrBulkRejected) {
+		t.Fatalf("repeated delete: err = %v, want ErrBulkRejected", err)
+	}
+	assertCounters(t, svc, owner.ID, 1, 0)
+}
+
+func TestGoalTransferMovesCounters(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	alice := steadyUser(t, svc)
+	bob := steadyUser(t, svc)
+
+	var ids []primitive.ObjectID
+	for _, name := range []string{"done", "open"} {
+		goal := oneStepGoal(name)
+		goal.UserID = alice.ID
+		created, err := svc.Goal.CreateGoal(ctx, goal)
+		if err != nil {
+			t.Fatalf("CreateGoal: %v", err)
+		}
+		if err := svc.Goals.AddCollaborator(ctx, created.ID, bob.ID, models.CollaboratorRoleEditor); err != nil {
+			t.Fatalf("AddCollaborator: %v", err)
+		}
+		ids = append(ids, created.ID)
+	}
+	completeGoal(t, svc, alice.ID, ids[0])
+	if _, err := svc.Goal.CloseGoal(ctx, ids[1].Hex(), alice.ID, ""); err != nil {
+		t.Fatalf("CloseGoal: %v", err)
+	}
+	assertCounters(t, svc, alice.ID, 2, 1)
+
+	if _, err := svc.Goal.TransferGoal(ctx, ids[0].Hex(), alice.ID, bob.ID); err != nil {
+		t.Fatalf("TransferGoal: %v", err)
+	}
+	// The goal changed hands, so a second transfer by alice is refused
+	if _, err := svc.Goal.TransferGoal(ctx, ids[0].Hex(), alice.ID, bob.ID); err == nil {
+		t.Fatal("transferring a goal one no longer owns should fail")
+	}
+	assertCounters(t, svc, alice.ID, 1, 0)
+	assertCounters(t, svc, bob.ID, 1, 1)
+
+	stored, err := svc.Counters.Get(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("failed to load counters: %v", err)
+	}
+	if stored.GoalsByStatus[services.GoalStatusClosed] != 1 {
+		t.Errorf("alice's goals by status = %v, want one closed", stored.GoalsByStatus)
+	}
+}
+
+func TestNotificationAndFriendCountersAdjustOnce(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	alice := testutil.SeedUser(t, svc.Repositories, models.User{})
+	bob := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	if err := svc.Notification.CreateNotification(ctx, alice.ID, "friend_accepted", "Hi", "", nil); err != nil {
+		t.Fatalf("CreateNotification: %v", err)
+	}
+	notif, err := svc.Notifications.GetLatestNotificationByType(ctx, alice.ID, "friend_accepted")
+	if err != nil {
+		t.Fatalf("GetLatestNotificationByType: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := svc.Notification.MarkNotificationAsRead(ctx, notif.ID); err != nil {
+			t.Fatalf("MarkNotificationAsRead: %v", err)
+		}
+	}
+	if err := svc.Notification.DeleteNotification(ctx, notif.ID); err != nil {
+		t.Fatalf("DeleteNotification: %v", err)
+	}
+
+	request, err := svc.Friend.SendFriendRequest(ctx, bob.ID, alice.ID)
+	if err != nil {
+		t.Fatalf("SendFriendRequest: %v", err)
+	}
+	stored, err := svc.Counters.Get(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("failed to load counters: %v", err)
+	}
+	if stored.PendingFriendRequests != 1 {
+		t.Errorf("pending friend requests = %d, want 1", stored.PendingFriendRequests)
+	}
+
+	if err := svc.Friend.RespondToRequest(ctx, request.ID, true); err != nil {
+		t.Fatalf("RespondToRequest: %v", err)
+	}
+	if err := svc.Friend.RespondToRequest(ctx, request.ID, false); err == nil {
+		t.Fatal("answering a request twice should fail")
+	}
+
+	assertCounters(t, svc, alice.ID, 0, 0)
+	stored, err = svc.Counters.Get(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("failed to load counters: %v", err)
+	}
+	if stored.PendingFriendRequests != 0 {
+		t.Errorf("pending friend requests = %d, want 0", stored.PendingFriendRequests)
+	}
+}
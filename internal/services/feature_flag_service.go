@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// Known feature flags.
+const (
+	FlagChat        = "chat"
+	FlagWishSharing = "wish_sharing"
+)
+
+// flagCacheTTL is how long flag definitions are kept in memory before reloading.
+const flagCacheTTL = 30 * time.Second
+
+type FeatureFlagService struct {
+	repo *repository.FeatureFlagRepository
+
+	mu       sync.RWMutex
+	flags    map[string]models.FeatureFlag
+	loadedAt time.Time
+}
+
+func NewFeatureFlagService(repo *repository.FeatureFlagRepository) *FeatureFlagService {
+	return &FeatureFlagService{repo: repo}
+}
+
+// ListFlags returns all flag definitions
+func (s *FeatureFlagService) ListFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	return s.repo.GetAllFlags(ctx)
+}
+
+// GetFlag returns a single flag definition
+func (s *FeatureFlagService) GetFlag(ctx context.Context, name string) (*models.FeatureFlag, error) {
+	return s.repo.GetFlag(ctx, name)
+}
+
+// SetFlag validates and stores a flag, then drops the cached definitions so
+// the change is visible immediately.
+func (s *FeatureFlagService) SetFlag(ctx context.Context, flag *models.FeatureFlag) error {
+	if flag.Name == "" {
+		return fmt.Errorf("flag name is required")
+	}
+	if flag.RolloutPercent < 0 || flag.RolloutPercent > 100 {
+		return fmt.Errorf("rollout_percent must be between 0 and 100")
+	}
+
+	if err := s.repo.UpsertFlag(ctx, flag); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.flags = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// IsEnabled reports whether the flag is on for the given user. Unknown flags are off.
+func (s *FeatureFlagService) IsEnabled(ctx context.Context, name, userID string) bool {
+	flag, ok := s.cachedFlags(ctx)[name]
+	return ok && evaluateFlag(flag, userID)
+}
+
+// EvaluateAll returns every flag's state for the given user
+func (s *FeatureFlagService) EvaluateAll(ctx context.Context, userID string) map[string]bool {
+	flags := s.cachedFlags(ctx)
+	result := make(map[string]bool, len(flags))
+	for name, flag := range flags {
+		result[name] = evaluateFlag(flag, userID)
+	}
+	return result
+}
+
+// cachedFlags returns the flag definitions, reloading them once the cache expires.
+// If reloading fails the stale definitions are kept.
+func (s *FeatureFlagService) cachedFlags(ctx context.Context) map[string]models.FeatureFlag {
+	s.mu.RLock()
+	if s.flags != nil && time.Since(s.loadedAt) < flagCacheTTL {
+		flags := s.flags
+		s.mu.RUnlock()
+		return flags
+	}
+	s.mu.RUnlock()
+
+	list, err := s.repo.GetAllFlags(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to load feature flags")
+		if s.flags == nil {
+			return map[string]models.FeatureFlag{}
+		}
+		return s.flags
+	}
+
+	flags := make(map[string]models.FeatureFlag, len(list))
+	for _, flag := range list {
+		flags[flag.Name] = flag
+	}
+	s.flags = flags
+	s.loadedAt = time.Now()
+	return flags
+}
+
+// evaluateFlag puts the user in a stable 0-99 bucket per flag, so a user keeps
+// the same result as the rollout percentage grows.
+func evaluateFlag(flag models.FeatureFlag, userID string) bool {
+	if !flag.Enabled {
+		return false
+	}
+	if flag.RolloutPercent >= 100 {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(flag.Name + ":" + userID))
+	return int(h.Sum32()%100) < flag.RolloutPercent
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Page size limits for the friend activity feed.
+const (
+	DefaultFriendFeedLimit int64 = 20
+	MaxFriendFeedLimit     int64 = 100
+)
+
+// FriendFeedCursorLayout formats the timestamps used as friend feed cursors.
+const FriendFeedCursorLayout = time.RFC3339Nano
+
+// GetFriendFeed returns one page of the goals the user's friends recently
+// created or completed, newest first, leaving out private goals. before is
+// the timestamp of the last activity of the previous page, or zero for the
+// first page. NextCursor holds that timestamp for the next page.
+func (s *FriendService) GetFriendFeed(ctx context.Context, userID primitive.ObjectID, before time.Time, limit int64) (*ActivityPage, error) {
+	if limit <= 0 {
+		limit = DefaultFriendFeedLimit
+	}
+	if limit > MaxFriendFeedLimit {
+		limit = MaxFriendFeedLimit
+	}
+
+	friendIDs, err := s.userRepo.GetFriendIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(friendIDs) == 0 {
+		return &ActivityPage{Activities: []models.Activity{}}, nil
+	}
+
+	// Fetch one extra activity to know whether another page follows
+	activities, err := s.activityRepo.GetFriendActivities(ctx, friendIDs, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ActivityPage{Activities: activities}
+	if int64(len(activities)) > limit {
+		page.Activities = activities[:limit]
+		page.NextCursor = page.Activities[limit-1].Timestamp.UTC().Format(FriendFeedCursorLayout)
+	}
+	return page, nil
+}
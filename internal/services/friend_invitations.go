@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/email"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Limits on emailed friend invitations.
+const (
+	friendInvitationExpiry = 7 * 24 * time.Hour
+	friendInviteLimit      = 20 // invitations per user per friendInviteWindow
+	friendInviteWindow     = 24 * time.Hour
+)
+
+// Errors returned by emailed friend invitations.
+var (
+	ErrInvalidInviteEmail      = errors.New("invalid email address")
+	ErrInvalidFriendInvitation = errors.New("invalid or expired invitation")
+)
+
+// Outcomes of InviteFriendByEmail.
+const (
+	FriendInviteSent          = "invited"
+	FriendInviteRequestedUser = "friend_request_sent"
+)
+
+// FriendInviteResult tells the inviter what InviteFriendByEmail did: emailed
+// an invitation, or sent a friend request because the address already
+// belongs to a user.
+type FriendInviteResult struct {
+	Status     string                   `json:"status"`
+	Invitation *models.FriendInvitation `json:"invitation,omitempty"`
+	Request    *models.FriendRequest    `json:"request,omitempty"`
+}
+
+// InviteFriendByEmail invites address to join and become inviterID's friend.
+// If the address already belongs to a user, a normal friend request is sent
+// instead. Each user may send friendInviteLimit invitations per day.
+func (s *FriendService) InviteFriendByEmail(ctx context.Context, inviterID primitive.ObjectID, address string) (*FriendInviteResult, error) {
+	address = strings.TrimSpace(address)
+	if !emailPattern.MatchString(address) {
+		return nil, ErrInvalidInviteEmail
+	}
+
+	if existing, _ := s.userRepo.GetUserByEmail(ctx, address); existing != nil {
+		request, err := s.SendFriendRequest(ctx, inviterID, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &FriendInviteResult{Status: FriendInviteRequestedUser, Request: request}, nil
+	}
+
+	if !s.inviteLimiter.allow(inviterID.Hex(), time.Now()) {
+		return nil, ErrRateLimited
+	}
+	inviter, err := s.userRepo.GetUserByID(ctx, inviterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inviter: %v", err)
+	}
+
+	now := time.Now()
+	inv, err := s.invitationRepo.CreateInvitation(ctx, &models.FriendInvitation{
+		InviterID: inviterID,
+		Email:     address,
+		Token:     uuid.NewString(),
+		SentAt:    now,
+		ExpiresAt: now.Add(friendInvitationExpiry),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("http://localhost:8080/friends/accept-invite?token=%s", inv.Token)
+	body := fmt.Sprintf("%s invited you to join them on Achievement Manager.\n\n"+
+		"Sign up with the link below before %s and you will be friends right away:\n%s",
+		inviter.Username, inv.ExpiresAt.Format(time.RFC1123), link)
+	if err := email.SendEmail(address, inviter.Username+" invited you to Achievement Manager", body); err != nil {
+		logrus.WithError(err).Error("Failed to send friend invitation email")
+		return nil, fmt.Errorf("failed to send invitation email")
+	}
+
+	logrus.WithField("inviterID", inviterID.Hex()).Info("Friend invitation sent")
+	return &FriendInviteResult{Status: FriendInviteSent, Invitation: inv}, nil
+}
+
+// CheckInvitation returns the invitation for token if it can still be used.
+func (s *FriendService) CheckInvitation(ctx context.Context, token string) (*models.FriendInvitation, error) {
+	if token == "" {
+		return nil, ErrInvalidFriendInvitation
+	}
+	inv, err := s.invitationRepo.GetInvitationByToken(ctx, token)
+	if err != nil || !inv.AcceptedBy.IsZero() || time.Now().After(inv.ExpiresAt) {
+		return nil, ErrInvalidFriendInvitation
+	}
+	return inv, nil
+}
+
+// AcceptInvitation makes userID, who just registered with token, and the
+// inviter friends. A token can only be used once.
+func (s *FriendService) AcceptInvitation(ctx context.Context, token string, userID primitive.ObjectID) error {
+	inv, err := s.CheckInvitation(ctx, token)
+	if err != nil {
+		return err
+	}
+	if inv.InviterID == userID {
+		return ErrInvalidFriendInvitation
+	}
+	accepted, err := s.invitationRepo.MarkAccepted(ctx, inv.ID, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return ErrInvalidFriendInvitation
+	}
+
+	if err := s.userRepo.AddFriend(ctx, inv.InviterID, userID); err != nil {
+		return fmt.Errorf("failed to add friend to inviter: %v", err)
+	}
+	if err := s.userRepo.AddFriend(ctx, userID, inv.InviterID); err != nil {
+		return fmt.Errorf("failed to add friend to invitee: %v", err)
+	}
+	s.friendshipChanged(inv.InviterID, userID)
+	s.badgeService.CheckAndAwardBadges(ctx, inv.InviterID)
+
+	logrus.WithFields(logrus.Fields{
+		"inviterID": inv.InviterID.Hex(),
+		"userID":    userID.Hex(),
+	}).Info("Friend invitation accepted")
+	return nil
+}
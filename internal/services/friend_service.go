@@ -2,25 +2,45 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrUserBlocked is returned when the receiver of a friend request has
+// blocked the sender.
+var ErrUserBlocked = errors.New("this user is not accepting friend requests from you")
+
 // FriendService handles business logic for managing friendships.
 type FriendService struct {
-	friendRepo *repository.FriendRepository
-	userRepo   *repository.UserRepository
+	friendRepo     *repository.FriendRepository
+	userRepo       *repository.UserRepository
+	activityRepo   *repository.ActivityRepository
+	invitationRepo *repository.FriendInvitationRepository
+	badgeService   *BadgeService
+	counters       *CounterService
+	hub            *hub.Hub // may be nil, e.g. in the admin CLI
+
+	inviteLimiter *windowLimiter
 }
 
 // NewFriendService creates a new FriendService.
-func NewFriendService(friendRepo *repository.FriendRepository, userRepo *repository.UserRepository) *FriendService {
+func NewFriendService(friendRepo *repository.FriendRepository, userRepo *repository.UserRepository, activityRepo *repository.ActivityRepository, invitationRepo *repository.FriendInvitationRepository, badgeService *BadgeService, counters *CounterService, wsHub *hub.Hub) *FriendService {
 	return &FriendService{
-		friendRepo: friendRepo,
-		userRepo:   userRepo,
+		friendRepo:     friendRepo,
+		userRepo:       userRepo,
+		activityRepo:   activityRepo,
+		invitationRepo: invitationRepo,
+		badgeService:   badgeService,
+		counters:       counters,
+		hub:            wsHub,
+
+		inviteLimiter: newWindowLimiter(friendInviteLimit, friendInviteWindow),
 	}
 }
 
@@ -30,6 +50,14 @@ func (s *FriendService) SendFriendRequest(ctx context.Context, senderID, receive
 		return nil, fmt.Errorf("cannot send a friend request to yourself")
 	}
 
+	blocked, err := s.userRepo.IsBlocked(ctx, senderID, receiverID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrUserBlocked
+	}
+
 	request := &models.FriendRequest{
 		SenderID:   senderID,
 		ReceiverID: receiverID,
@@ -37,7 +65,82 @@ func (s *FriendService) SendFriendRequest(ctx context.Context, senderID, receive
 		Status:     "pending",
 	}
 
-	return s.friendRepo.CreateRequest(ctx, request)
+	created, err := s.friendRepo.CreateRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	s.counters.FriendRequestCreated(ctx, receiverID)
+	return created, nil
+}
+
+// Page size limits for pending friend requests.
+const (
+	DefaultFriendRequestsLimit int64 = 20
+	MaxFriendRequestsLimit     int64 = 100
+)
+
+// PendingFriendRequest is a pending request together with the sender's public profile.
+type PendingFriendRequest struct {
+	models.FriendRequest
+	Sender models.PublicUser `json:"sender"`
+}
+
+// GetPendingRequestsWithSenders returns a page of the receiver's pending
+// requests with each sender's profile, loaded in one batch. Requests from
+// senders whose account no longer exists are left out, so a page can hold
+// fewer than limit entries.
+func (s *FriendService) GetPendingRequestsWithSenders(ctx context.Context, receiverID primitive.ObjectID, oldestFirst bool, offset, limit int64) ([]PendingFriendRequest, error) {
+	if limit <= 0 {
+		limit = DefaultFriendRequestsLimit
+	}
+	if limit > MaxFriendRequestsLimit {
+		limit = MaxFriendRequestsLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	requests, err := s.friendRepo.GetPendingRequestsPage(ctx, receiverID, oldestFirst, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]PendingFriendRequest, 0, len(requests))
+	if len(requests) == 0 {
+		return result, nil
+	}
+
+	senderIDs := make([]primitive.ObjectID, 0, len(requests))
+	for _, req := range requests {
+		senderIDs = append(senderIDs, req.SenderID)
+	}
+	senders, err := s.userRepo.GetUsersByIDs(ctx, senderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get senders: %v", err)
+	}
+
+	byID := make(map[primitive.ObjectID]models.User, len(senders))
+	for _, u := range senders {
+		byID[u.ID] = u
+	}
+
+	for _, req := range requests {
+		sender, ok := byID[req.SenderID]
+		if !ok {
+			continue
+		}
+		result = append(result, PendingFriendRequest{
+			FriendRequest: req,
+			Sender: models.PublicUser{
+				ID:          sender.ID,
+				Username:    sender.Username,
+				DisplayName: sender.DisplayName,
+				AvatarURL:   sender.AvatarURL,
+				Email:       sender.Email,
+			},
+		})
+	}
+	return result, nil
 }
 
 // GetPendingRequests fetches all pending requests for the receiver.
@@ -65,6 +168,7 @@ func (s *FriendService) RespondToRequest(ctx context.Context, requestID primitiv
 	if err := s.friendRepo.UpdateRequestStatus(ctx, requestID, status); err != nil {
 		return err
 	}
+	s.counters.FriendRequestResolved(ctx, request.ReceiverID)
 
 	if accept {
 		// Update both users' friend lists
@@ -74,11 +178,24 @@ func (s *FriendService) RespondToRequest(ctx context.Context, requestID primitiv
 		if err := s.userRepo.AddFriend(ctx, request.ReceiverID, request.SenderID); err != nil {
 			return fmt.Errorf("failed to add friend to receiver: %v", err)
 		}
+		s.friendshipChanged(request.SenderID, request.ReceiverID)
+		s.badgeService.CheckAndAwardBadges(ctx, request.SenderID)
+		s.badgeService.CheckAndAwardBadges(ctx, request.ReceiverID)
 	}
 
 	return nil
 }
 
+// friendshipChanged drops both users' cached friend lists in the hub so
+// presence updates reach the right people straight away.
+func (s *FriendService) friendshipChanged(a, b primitive.ObjectID) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.InvalidateFriends(a.Hex())
+	s.hub.InvalidateFriends(b.Hex())
+}
+
 // GetFriends returns a list of user IDs who are friends with the given user.
 func (s *FriendService) GetFriends(ctx context.Context, userID primitive.ObjectID) ([]models.PublicUser, error) {
 	friendIDs, err := s.userRepo.GetFriendIDs(ctx, userID)
@@ -98,15 +215,66 @@ func (s *FriendService) GetFriends(ctx context.Context, userID primitive.ObjectI
 	publicFriends := make([]models.PublicUser, 0, len(users))
 	for _, user := range users {
 		publicFriends = append(publicFriends, models.PublicUser{
-			ID:       user.ID,
-			Username: user.Username,
-			Email:    user.Email,
+			ID:          user.ID,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			AvatarURL:   user.AvatarURL,
+			Email:       user.Email,
 		})
 	}
 
 	return publicFriends, nil
 }
 
+// GetMutualFriends returns the friends callerID and targetID have in common.
+// The target's profile must be visible to the caller and the target must not
+// have blocked them.
+func (s *FriendService) GetMutualFriends(ctx context.Context, callerID, targetID primitive.ObjectID) ([]models.PublicUser, error) {
+	target, err := s.userRepo.GetUserByID(ctx, targetID)
+	if err != nil {
+		return nil, ErrProfileNotFound
+	}
+	if !profileVisibleTo(target, &callerID) {
+		return nil, ErrProfileNotVisible
+	}
+	blocked, err := s.userRepo.IsBlocked(ctx, callerID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrProfileNotVisible
+	}
+
+	callerFriends, err := s.userRepo.GetFriendIDs(ctx, callerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friend IDs: %v", err)
+	}
+	targetFriends, err := s.userRepo.GetFriendIDs(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friend IDs: %v", err)
+	}
+
+	isCallerFriend := make(map[primitive.ObjectID]bool, len(callerFriends))
+	for _, id := range callerFriends {
+		isCallerFriend[id] = true
+	}
+	var mutual []primitive.ObjectID
+	for _, id := range targetFriends {
+		if isCallerFriend[id] {
+			mutual = append(mutual, id)
+		}
+	}
+	if len(mutual) == 0 {
+		return []models.PublicUser{}, nil
+	}
+
+	return s.userRepo.GetPublicProfilesByIDs(ctx, mutual)
+}
+
 func (s *FriendService) RemoveFriend(ctx context.Context, userID, friendID primitive.ObjectID) error {
-	return s.userRepo.RemoveFriend(ctx, userID, friendID)
+	if err := s.userRepo.RemoveFriend(ctx, userID, friendID); err != nil {
+		return err
+	}
+	s.friendshipChanged(userID, friendID)
+	return nil
 }
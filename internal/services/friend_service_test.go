@@ -0,0 +1,87 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func senderNames(requests []services.PendingFriendRequest) []string {
+	names := make([]string, 0, len(requests))
+	for _, req := range requests {
+		names = append(names, req.Sender.Username)
+	}
+	return names
+}
+
+func equalNames(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPendingRequestsSkipDeletedSenders(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	receiver := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	senders := map[string]primitive.ObjectID{}
+	for _, name := range []string{"ann", "ben", "cat", "dan"} {
+		sender := testutil.SeedUser(t, svc.Repositories, models.User{Username: name, DisplayName: "Display " + name})
+		senders[name] = sender.ID
+		if _, err := svc.Friend.SendFriendRequest(ctx, sender.ID, receiver.ID); err != nil {
+			t.Fatalf("SendFriendRequest from %s: %v", name, err)
+		}
+	}
+	// A request to someone else must not show up
+	other := testutil.SeedUser(t, svc.Repositories, models.User{})
+	if _, err := svc.Friend.SendFriendRequest(ctx, senders["ann"], other.ID); err != nil {
+		t.Fatalf("SendFriendRequest: %v", err)
+	}
+
+	// Remove the account directly so its request is left behind
+	if err := svc.Users.DeleteUser(ctx, senders["ben"]); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		oldestFirst   bool
+		offset, limit int64
+		want          []string
+	}{
+		{name: "newest first", want: []string{"dan", "cat", "ann"}},
+		{name: "oldest first", oldestFirst: true, want: []string{"ann", "cat", "dan"}},
+		// The page is cut before filtering, so a deleted sender shortens it
+		{name: "first page", oldestFirst: true, limit: 2, want: []string{"ann"}},
+		{name: "second page", oldestFirst: true, offset: 2, limit: 2, want: []string{"cat", "dan"}},
+		{name: "past the end", offset: 4, want: []string{}},
+		{name: "negative offset", offset: -3, want: []string{"dan", "cat", "ann"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, err := svc.Friend.GetPendingRequestsWithSenders(ctx, receiver.ID, tt.oldestFirst, tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("GetPendingRequestsWithSenders: %v", err)
+			}
+			if got := senderNames(requests); !equalNames(got, tt.want) {
+				t.Fatalf("senders = %v, want %v", got, tt.want)
+			}
+			for _, req := range requests {
+				if req.SenderID != req.Sender.ID || req.Sender.DisplayName != "Display "+req.Sender.Username {
+					t.Errorf("request %s has profile %+v", req.ID.Hex(), req.Sender)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Limits on the number of friend suggestions returned.
+const (
+	DefaultSuggestionsLimit = 10
+	MaxSuggestionsLimit     = 50
+)
+
+// SuggestionEntry is a suggested friend and how many friends they share with
+// the user.
+type SuggestionEntry struct {
+	User               models.PublicUser `json:"user"`
+	MutualFriendsCount int               `json:"mutual_friends_count"`
+}
+
+// GetSuggestions returns friends of the user's friends, most mutual friends
+// first. Existing friends, the user themselves and anyone blocked in either
+// direction are left out. The friend lists are loaded in two batches: the
+// user's friends, then the candidates.
+func (s *FriendService) GetSuggestions(ctx context.Context, userID primitive.ObjectID, limit int) ([]SuggestionEntry, error) {
+	if limit <= 0 {
+		limit = DefaultSuggestionsLimit
+	}
+	if limit > MaxSuggestionsLimit {
+		limit = MaxSuggestionsLimit
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	if len(user.Friends) == 0 {
+		return []SuggestionEntry{}, nil
+	}
+
+	excluded := map[primitive.ObjectID]bool{userID: true}
+	for _, id := range user.Friends {
+		excluded[id] = true
+	}
+	for _, id := range user.BlockedUsers {
+		excluded[id] = true
+	}
+
+	friends, err := s.userRepo.GetUsersByIDs(ctx, user.Friends)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friends: %v", err)
+	}
+	mutual := make(map[primitive.ObjectID]int)
+	for _, friend := range friends {
+		for _, id := range friend.Friends {
+			if !excluded[id] {
+				mutual[id]++
+			}
+		}
+	}
+	if len(mutual) == 0 {
+		return []SuggestionEntry{}, nil
+	}
+
+	candidateIDs := make([]primitive.ObjectID, 0, len(mutual))
+	for id := range mutual {
+		candidateIDs = append(candidateIDs, id)
+	}
+	candidates, err := s.userRepo.GetUsersByIDs(ctx, candidateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suggested users: %v", err)
+	}
+
+	entries := make([]SuggestionEntry, 0, len(candidates))
+	for _, candidate := range candidates {
+		if hasBlocked(candidate, userID) {
+			continue
+		}
+		entries = append(entries, SuggestionEntry{
+			User: models.PublicUser{
+				ID:          candidate.ID,
+				Username:    candidate.Username,
+				DisplayName: candidate.DisplayName,
+				AvatarURL:   candidate.AvatarURL,
+			},
+			MutualFriendsCount: mutual[candidate.ID],
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].MutualFriendsCount != entries[j].MutualFriendsCount {
+			return entries[i].MutualFriendsCount > entries[j].MutualFriendsCount
+		}
+		return entries[i].User.Username < entries[j].User.Username
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// hasBlocked reports whether user has blocked id.
+func hasBlocked(user models.User, id primitive.ObjectID) bool {
+	for _, blocked := range user.BlockedUsers {
+		if blocked == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxCalendarRange is the longest period one calendar request may cover.
+const MaxCalendarRange = 366 * 24 * time.Hour
+
+// ErrInvalidCalendarRange is returned when the calendar range is empty,
+// reversed or longer than MaxCalendarRange.
+var ErrInvalidCalendarRange = errors.New("invalid calendar range")
+
+// GetCalendar lists the goal, step and substep due dates in [from, to) across
+// the goals GetGoals returns for the user, owned and shared alike, ordered by
+// due date. Closed goals are left out.
+func (s *GoalService) GetCalendar(ctx context.Context, userID primitive.ObjectID, from, to time.Time) ([]models.CalendarItem, error) {
+	if !to.After(from) || to.Sub(from) > MaxCalendarRange {
+		return nil, ErrInvalidCalendarRange
+	}
+
+	goals, err := s.GetGoals(ctx, userID, "", "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	items := calendarItems(goals, func(t time.Time) bool {
+		return !t.Before(from) && t.Before(to)
+	})
+	return items, nil
+}
+
+// calendarItems lists the goal, step and substep due dates in goals that
+// include accepts, ordered by due date. Items without a due date are skipped.
+func calendarItems(goals []models.Goal, include func(due time.Time) bool) []models.CalendarItem {
+	inRange := func(t time.Time) bool {
+		return !t.IsZero() && include(t)
+	}
+
+	items := []models.CalendarItem{}
+	for _, goal := range goals {
+		if inRange(goal.DueDate) {
+			items = append(items, models.CalendarItem{
+				GoalID:    goal.ID,
+				Type:      models.CalendarItemGoal,
+				Title:     goal.Name,
+				GoalName:  goal.Name,
+				DueDate:   goal.DueDate,
+				Completed: goal.Status == "completed",
+			})
+		}
+		for i, step := range goal.Steps {
+			stepIndex := i
+			if inRange(step.DueDate) {
+				items = append(items, models.CalendarItem{
+					GoalID:    goal.ID,
+					Type:      models.CalendarItemStep,
+					Title:     step.Name,
+					GoalName:  goal.Name,
+					DueDate:   step.DueDate,
+					Completed: step.Completed,
+					StepIndex: &stepIndex,
+				})
+			}
+			for j, sub := range step.Substeps {
+				if !inRange(sub.DueDate) {
+					continue
+				}
+				substepIndex := j
+				items = append(items, models.CalendarItem{
+					GoalID:       goal.ID,
+					Type:         models.CalendarItemSubstep,
+					Title:        sub.Title,
+					GoalName:     goal.Name,
+					DueDate:      sub.DueDate,
+					Completed:    sub.Done,
+					StepIndex:    &stepIndex,
+					SubstepIndex: &substepIndex,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DueDate.Before(items[j].DueDate) })
+	return items
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sharedProgressNoticeWindow collapses progress notifications about the same
+// goal: each recipient gets at most one per window, however often steps are
+// ticked and unticked.
+const sharedProgressNoticeWindow = 10 * time.Minute
+
+// notifySharedGoalChange tells the owner and collaborators of a shared goal,
+// except the actor, that it changed. progress reports whether the change
+// ticked or unticked steps rather than editing the goal.
+func (s *GoalService) notifySharedGoalChange(ctx context.Context, goal *models.Goal, progress bool, actorID primitive.ObjectID) {
+	if len(goal.Collaborators) == 0 {
+		return
+	}
+
+	recipients := make([]primitive.ObjectID, 0, len(goal.Collaborators)+1)
+	recipients = append(recipients, goal.UserID)
+	for _, collab := range goal.Collaborators {
+		recipients = append(recipients, collab.UserID)
+	}
+
+	actorName := "Someone"
+	if actor, err := s.userRepo.GetUserByID(ctx, actorID); err == nil {
+		actorName = actor.Username
+	}
+
+	title := "✏️ Shared goal edited"
+	msg := fmt.Sprintf("%s edited \"%s\".", actorName, goal.Name)
+	if progress {
+		done := 0
+		for _, step := range goal.Steps {
+			if step.Completed {
+				done++
+			}
+		}
+		title = "📈 Shared goal progress"
+		msg = fmt.Sprintf("%s updated progress on \"%s\": %d of %d steps done.", actorName, goal.Name, done, len(goal.Steps))
+	}
+
+	now := time.Now()
+	seen := make(map[primitive.ObjectID]bool, len(recipients))
+	for _, recipient := range recipients {
+		if recipient == actorID || seen[recipient] {
+			continue
+		}
+		seen[recipient] = true
+
+		if progress && !s.progressNotices.allow(goal.ID.Hex()+":"+recipient.Hex(), now) {
+			continue
+		}
+		if err := s.NotificationService.CreateNotification(ctx, recipient, "shared_goal_updated", title, msg, &goal.ID); err != nil {
+			logger.Log.WithError(err).Warn("Failed to notify about shared goal change")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+)
+
+func twoStepGoal(firstDone, secondDone, requireConfirmation bool) models.Goal {
+	return models.Goal{
+		RequireCompletionConfirmation: requireConfirmation,
+		Steps: []models.Step{
+			{Name: "first", Substeps: []models.Substep{{Title: "a", Done: firstDone}}},
+			{Name: "second", Substeps: []models.Substep{{Title: "b", Done: secondDone}}},
+		},
+	}
+}
+
+func TestClosedGoalIsNotAdvanced(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, svc.Repositories, models.User{})
+	goal := testutil.SeedGoal(t, svc.Repositories, owner.ID, twoStepGoal(true, false, false))
+
+	if _, err := svc.Goal.CloseGoal(ctx, goal.ID.Hex(), owner.ID, "lost interest"); err != nil {
+		t.Fatalf("CloseGoal: %v", err)
+	}
+
+	// Finishing the remaining work on a closed goal must not complete it
+	closed, err := svc.Goal.GetGoal(ctx, goal.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetGoal: %v", err)
+	}
+	closed.Steps[1].Substeps[0].Done = true
+	updated, err := svc.Goal.UpdateGoal(ctx, goal.ID.Hex(), closed, owner.ID)
+	if err != nil {
+		t.Fatalf("UpdateGoal: %v", err)
+	}
+	if updated.Status != services.GoalStatusClosed {
+		t.Errorf("status = %q after finishing every step, want closed", updated.Status)
+	}
+	if !updated.CompletedAt.IsZero() {
+		t.Errorf("CompletedAt = %v, want zero for a closed goal", updated.CompletedAt)
+	}
+}
+
+func TestReopenRestoresDerivedStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		goal models.Goal
+		want string
+	}{
+		{name: "no steps", goal: models.Goal{}, want: "in_progress"},
+		{name: "steps left", goal: twoStepGoal(true, false, false), want: "in_progress"},
+		{name: "all done", goal: twoStepGoal(true, true, false), want: "completed"},
+		{name: "all done, confirmation required", goal: twoStepGoal(true, true, true), want: services.GoalStatusPendingCompletion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := testutil.NewServices(t)
+			ctx := context.Background()
+			owner := testutil.SeedUser(t, svc.Repositories, models.User{})
+			goal := testutil.SeedGoal(t, svc.Repositories, owner.ID, tt.goal)
+
+			if _, err := svc.Goal.CloseGoal(ctx, goal.ID.Hex(), owner.ID, ""); err != nil {
+				t.Fatalf("CloseGoal: %v", err)
+			}
+			reopened, err := svc.Goal.ReopenGoal(ctx, goal.ID.Hex(), owner.ID)
+			if err != nil {
+				t.Fatalf("ReopenGoal: %v", err)
+			}
+			if reopened.Status != tt.want {
+				t.Errorf("status = %q, want %q", reopened.Status, tt.want)
+			}
+
+			stored, err := svc.Goals.GetGoalByID(ctx, goal.ID)
+			if err != nil {
+				t.Fatalf("GetGoalByID: %v", err)
+			}
+			if stored.Status != tt.want {
+				t.Errorf("stored status = %q, want %q", stored.Status, tt.want)
+			}
+			if stored.ClosedReason != "" || !stored.ClosedAt.IsZero() {
+				t.Errorf("closed reason %q / closed at %v left behind", stored.ClosedReason, stored.ClosedAt)
+			}
+			if (tt.want == "completed") == stored.CompletedAt.IsZero() {
+				t.Errorf("CompletedAt = %v for status %q", stored.CompletedAt, stored.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxGoalBlockers caps how many goals a single goal may depend on.
+const MaxGoalBlockers = 20
+
+// Errors returned when setting a goal's dependencies.
+var (
+	ErrDependencyCycle   = errors.New("goal dependencies would form a cycle")
+	ErrInvalidDependency = errors.New("invalid goal dependency")
+)
+
+// BlockedError is returned when a goal cannot progress because some of the
+// goals it depends on are not completed yet.
+type BlockedError struct {
+	Blockers []models.Goal
+}
+
+func (e *BlockedError) Error() string {
+	names := make([]string, len(e.Blockers))
+	for i, g := range e.Blockers {
+		names[i] = fmt.Sprintf("%q", g.Name)
+	}
+	return "goal is blocked by unfinished goals: " + strings.Join(names, ", ")
+}
+
+// SetBlockedBy replaces the goals the given goal depends on. Dependencies must
+// be goals the owner can see, and may not lead back to the goal itself.
+func (s *GoalService) SetBlockedBy(ctx context.Context, goal *models.Goal, blockerIDs []string) (*models.Goal, error) {
+	blockedBy, err := s.validateBlockedBy(ctx, goal, blockerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.UpdateGoals(ctx, []primitive.ObjectID{goal.ID}, bson.M{"blocked_by": blockedBy}); err != nil {
+		logger.Log.WithField("goal_id", goal.ID.Hex()).WithError(err).Error("Failed to update goal dependencies")
+		return nil, fmt.Errorf("failed to update goal dependencies: %v", err)
+	}
+	return s.repo.GetGoalByID(ctx, goal.ID)
+}
+
+// validateBlockedBy parses and checks a list of dependency IDs for goal.
+// Duplicates are dropped.
+func (s *GoalService) validateBlockedBy(ctx context.Context, goal *models.Goal, blockerIDs []string) ([]primitive.ObjectID, error) {
+	if len(blockerIDs) > MaxGoalBlockers {
+		return nil, fmt.Errorf("%w: a goal can depend on at most %d goals", ErrInvalidDependency, MaxGoalBlockers)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(blockerIDs))
+	seen := make(map[primitive.ObjectID]bool, len(blockerIDs))
+	for _, raw := range blockerIDs {
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid goal ID %q", ErrInvalidDependency, raw)
+		}
+		if id == goal.ID {
+			return nil, fmt.Errorf("%w: a goal cannot depend on itself", ErrDependencyCycle)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	blockers, err := s.repo.GetGoalsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch goal dependencies: %v", err)
+	}
+	found := make(map[primitive.ObjectID]bool, len(blockers))
+	for _, b := range blockers {
+		if !b.DeletedAt.IsZero() || !canView(&b, goal.UserID) {
+			continue
+		}
+		found[b.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, fmt.Errorf("%w: goal %s not found", ErrInvalidDependency, id.Hex())
+		}
+	}
+
+	// A new goal has no ID yet, so nothing can depend on it
+	if !goal.ID.IsZero() {
+		if err := s.checkDependencyCycle(ctx, goal.ID, blockers); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// checkDependencyCycle walks the dependency graph from the new blockers and
+// fails if it reaches goalID, which would make the goal wait on itself.
+func (s *GoalService) checkDependencyCycle(ctx context.Context, goalID primitive.ObjectID, blockers []models.Goal) error {
+	visited := map[primitive.ObjectID]bool{}
+	frontier := blockers
+	for len(frontier) > 0 {
+		var next []primitive.ObjectID
+		for _, g := range frontier {
+			visited[g.ID] = true
+			for _, dep := range g.BlockedBy {
+				if dep == goalID {
+					return ErrDependencyCycle
+				}
+				if !visited[dep] {
+					visited[dep] = true
+					next = append(next, dep)
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+
+		var err error
+		frontier, err = s.repo.GetGoalsByIDs(ctx, next)
+		if err != nil {
+			return fmt.Errorf("failed to fetch goal dependencies: %v", err)
+		}
+	}
+	return nil
+}
+
+// OpenBlockers returns the goals that goal depends on and that are not
+// completed yet. Dependencies that were deleted no longer block.
+func (s *GoalService) OpenBlockers(ctx context.Context, goal *models.Goal) ([]models.Goal, error) {
+	if len(goal.BlockedBy) == 0 {
+		return nil, nil
+	}
+
+	blockers, err := s.repo.GetGoalsByIDs(ctx, goal.BlockedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch goal dependencies: %v", err)
+	}
+
+	var open []models.Goal
+	for _, b := range blockers {
+		if b.DeletedAt.IsZero() && b.Status != "completed" {
+			open = append(open, b)
+		}
+	}
+	return open, nil
+}
+
+// CheckNotBlocked returns a *BlockedError if goal still waits on other goals.
+func (s *GoalService) CheckNotBlocked(ctx context.Context, goal *models.Goal) error {
+	open, err := s.OpenBlockers(ctx, goal)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return &BlockedError{Blockers: open}
+	}
+	return nil
+}
+
+// notifyUnblocked tells the owners of goals that depended on the just
+// completed goal when it was the last dependency they were waiting for.
+func (s *GoalService) notifyUnblocked(ctx context.Context, completed *models.Goal) {
+	dependents, err := s.repo.GetGoalsBlockedBy(ctx, completed.ID)
+	if err != nil {
+		logger.Log.WithError(err).Warn("Failed to load dependent goals")
+		return
+	}
+
+	for i := range dependents {
+		dependent := &dependents[i]
+		open, err := s.OpenBlockers(ctx, dependent)
+		if err != nil || len(open) > 0 {
+			continue
+		}
+
+		err = s.NotificationService.CreateNotification(
+			ctx,
+			dependent.UserID,
+			"goal_unblocked",
+			"🔓 Goal unblocked",
+			fmt.Sprintf("\"%s\" is done, so you can now start on \"%s\".", completed.Name, dependent.Name),
+			&dependent.ID,
+		)
+		if err != nil {
+			logger.Log.WithError(err).Warn("Failed to send goal_unblocked notification")
+		}
+	}
+}
+
+// canView reports whether userID owns goal or collaborates on it.
+func canView(goal *models.Goal, userID primitive.ObjectID) bool {
+	if goal.UserID == userID {
+		return true
+	}
+	for _, c := range goal.Collaborators {
+		if c.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
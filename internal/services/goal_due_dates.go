@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+)
+
+// ErrInvalidStepDueDate is wrapped by StepDueDateError.
+var ErrInvalidStepDueDate = errors.New("invalid step due date")
+
+// Reasons a step or substep due date is rejected.
+const (
+	DueDateAfterGoal = "after_goal_due_date"
+	DueDateInPast    = "in_the_past"
+)
+
+// StepDueDateIssue names one step or substep whose due date was rejected.
+type StepDueDateIssue struct {
+	Step    int    `json:"step"`
+	Substep *int   `json:"substep,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// StepDueDateError lists every step and substep with an invalid due date.
+type StepDueDateError struct {
+	Issues []StepDueDateIssue
+}
+
+func (e *StepDueDateError) Error() string {
+	return fmt.Sprintf("%v: %d step or substep due dates are after the goal's due date or in the past", ErrInvalidStepDueDate, len(e.Issues))
+}
+
+func (e *StepDueDateError) Unwrap() error { return ErrInvalidStepDueDate }
+
+// validateStepDueDates rejects step and substep due dates that fall after the
+// goal's due date or, for unfinished ones, before now. previous is the goal
+// as stored, or nil for a new goal: dates it already had are not checked
+// against now, and are not checked at all while the goal's own due date is
+// unchanged, so existing goals stay editable.
+func validateStepDueDates(goal, previous *models.Goal, now time.Time) error {
+	goalDueUnchanged := previous != nil && previous.DueDate.Equal(goal.DueDate)
+	check := func(due, was time.Time, done, known bool) string {
+		if due.IsZero() {
+			return ""
+		}
+		unchanged := known && was.Equal(due)
+		if unchanged && goalDueUnchanged {
+			return ""
+		}
+		if !goal.DueDate.IsZero() && due.After(goal.DueDate) {
+			return DueDateAfterGoal
+		}
+		if !done && !unchanged && due.Before(now) {
+			return DueDateInPast
+		}
+		return ""
+	}
+
+	var issues []StepDueDateIssue
+	for i, step := range goal.Steps {
+		var prevStep *models.Step
+		if previous != nil && i < len(previous.Steps) {
+			prevStep = &previous.Steps[i]
+		}
+
+		var was time.Time
+		if prevStep != nil {
+			was = prevStep.DueDate
+		}
+		if reason := check(step.DueDate, was, step.Completed, prevStep != nil); reason != "" {
+			issues = append(issues, StepDueDateIssue{Step: i, Reason: reason})
+		}
+
+		for j, sub := range step.Substeps {
+			var subWas time.Time
+			known := prevStep != nil && j < len(prevStep.Substeps)
+			if known {
+				subWas = prevStep.Substeps[j].DueDate
+			}
+			if reason := check(sub.DueDate, subWas, sub.Done, known); reason != "" {
+				j := j
+				issues = append(issues, StepDueDateIssue{Step: i, Substep: &j, Reason: reason})
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return &StepDueDateError{Issues: issues}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/ical"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// icalName is the calendar name shown in calendar apps.
+const icalName = "Achievement Manager"
+
+// ExportICal renders the due dates of the user's unfinished goals, steps and
+// substeps as an iCalendar feed, covering the same goals as GetCalendar.
+func (s *GoalService) ExportICal(ctx context.Context, userID primitive.ObjectID) ([]byte, error) {
+	goals, err := s.GetGoals(ctx, userID, "", "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []ical.Event{}
+	for _, item := range calendarItems(goals, func(time.Time) bool { return true }) {
+		if item.Completed {
+			continue
+		}
+		events = append(events, icalEvent(item))
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, icalName, events, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to render calendar: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportICalByToken is ExportICal for the user owning a calendar token.
+func (s *GoalService) ExportICalByToken(ctx context.Context, token string) ([]byte, error) {
+	user, err := s.users.GetUserByCalendarToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.ExportICal(ctx, user.ID)
+}
+
+// icalEvent turns a calendar item into an event. The UID is built from the
+// goal ID and the step and substep positions so re-exports update the same
+// events in calendar apps.
+func icalEvent(item models.CalendarItem) ical.Event {
+	event := ical.Event{Date: item.DueDate}
+	switch item.Type {
+	case models.CalendarItemGoal:
+		event.UID = fmt.Sprintf("goal-%s@achievement-manager", item.GoalID.Hex())
+		event.Summary = item.GoalName
+		event.Description = "Goal due"
+	case models.CalendarItemStep:
+		event.UID = fmt.Sprintf("step-%s-%d@achievement-manager", item.GoalID.Hex(), *item.StepIndex)
+		event.Summary = item.GoalName + ": " + item.Title
+		event.Description = "Step due"
+	default:
+		event.UID = fmt.Sprintf("substep-%s-%d-%d@achievement-manager", item.GoalID.Hex(), *item.StepIndex, *item.SubstepIndex)
+		event.Summary = item.GoalName + ": " + item.Title
+		event.Description = "Substep due"
+	}
+	return event
+}
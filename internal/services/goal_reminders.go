@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+)
+
+// DefaultRemindBefore is how long before its due date a goal is reminded
+// about when it does not set RemindBefore.
+const DefaultRemindBefore = 24 * time.Hour
+
+// MaxRemindBefore is the longest reminder lead time a goal may set.
+const MaxRemindBefore = 30 * 24 * time.Hour
+
+// ErrInvalidReminder is returned when a goal's RemindBefore is negative or
+// longer than MaxRemindBefore.
+var ErrInvalidReminder = errors.New("invalid reminder lead time")
+
+// validateRemindBefore checks a goal's reminder lead time. Zero means the default.
+func validateRemindBefore(d models.Duration) error {
+	if d < 0 || time.Duration(d) > MaxRemindBefore {
+		return fmt.Errorf("%w: remind_before must be between 0 and %s", ErrInvalidReminder, MaxRemindBefore)
+	}
+	return nil
+}
+
+// GoalReminderLead returns how long before its due date the goal's owner
+// should be reminded.
+func GoalReminderLead(goal models.Goal) time.Duration {
+	if goal.RemindBefore > 0 {
+		return time.Duration(goal.RemindBefore)
+	}
+	return DefaultRemindBefore
+}
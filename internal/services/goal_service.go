@@ -1,29 +1,98 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/export"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// Limits applied to the admin goal listing.
+const (
+	DefaultAdminGoalsLimit int64 = 10
+	MaxAdminGoalsLimit     int64 = 100
+)
+
+// Limits applied to the list of goals shared with the user.
+const (
+	DefaultSharedGoalsLimit int64 = 20
+	MaxSharedGoalsLimit     int64 = 100
+)
+
+// MaxImportGoals caps how many goals a single import request may contain.
+const MaxImportGoals = 100
+
+// Limits on free-form goal tags.
+const (
+	MaxGoalTags   = 10
+	MaxGoalTagLen = 30
+)
+
+// ErrInvalidTags is returned when a goal's tags break the limits above.
+var ErrInvalidTags = errors.New("invalid tags")
+
+// MaxStepNoteLen caps the length of a step or substep note, in characters.
+const MaxStepNoteLen = 2000
+
+// ErrInvalidNote is returned when a step or substep note is too long.
+var ErrInvalidNote = errors.New("invalid note")
+
+// MaxBulkGoals caps how many goals a single bulk operation may target.
+const MaxBulkGoals = 100
+
+// Statuses that can be set explicitly through a bulk operation. Completion
+// is left out: it must go through step progress and, when the goal requires
+// it, the owner's confirmation.
+var bulkSettableStatuses = map[string]bool{
+	"in_progress": true,
+	"archived":    true,
+}
+
 // GoalService encapsulates the business logic for goals.
 type GoalService struct {
 	repo                *repository.GoalRepository
 	userRepo            *repository.UserRepository
+	invitationRepo      *repository.GoalInvitationRepository
 	NotificationService *NotificationService
+	ProgressService     *ProgressService
+	badgeService        *BadgeService
+	counters            *CounterService
+	categories          *CategoryService
+	users               *UserService
+	maxPinned           int
+	progressNotices     *windowLimiter
 }
 
 // NewGoalService creates a new instance of GoalService.
-func NewGoalService(repo *repository.GoalRepository, userRepo *repository.UserRepository, notificationService *NotificationService) *GoalService {
+func NewGoalService(repo *repository.GoalRepository, userRepo *repository.UserRepository, invitationRepo *repository.GoalInvitationRepository, notificationService *NotificationService, progressService *ProgressService, badgeService *BadgeService, counters *CounterService, categories *CategoryService, users *UserService, maxPinned int) *GoalService {
 	return &GoalService{
 		repo:                repo,
 		userRepo:            userRepo,
+		invitationRepo:      invitationRepo,
 		NotificationService: notificationService,
+		ProgressService:     progressService,
+		badgeService:        badgeService,
+		counters:            counters,
+		categories:          categories,
+		users:               users,
+		maxPinned:           maxPinned,
+		progressNotices:     newWindowLimiter(1, sharedProgressNoticeWindow),
 	}
 }
 
@@ -34,16 +103,57 @@ func (s *GoalService) CreateGoal(ctx context.Context, goal *models.Goal) (*model
 		return nil, fmt.Errorf("goal name is required")
 	}
 
+	tags, err := NormalizeTags(goal.Tags)
+	if err != nil {
+		return nil, err
+	}
+	goal.Tags = tags
+
+	if err := normalizeStepNotes(goal.Steps); err != nil {
+		return nil, err
+	}
+	if err := validateRemindBefore(goal.RemindBefore); err != nil {
+		return nil, err
+	}
+	if err := validateVisibility(goal.Visibility); err != nil {
+		return nil, err
+	}
+	if err := validateStepDueDates(goal, nil, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Goals are pinned through SetPinned, which enforces the limit
+	goal.Pinned = false
+
+	if len(goal.BlockedBy) > 0 {
+		ids := make([]string, len(goal.BlockedBy))
+		for i, id := range goal.BlockedBy {
+			ids[i] = id.Hex()
+		}
+		if goal.BlockedBy, err = s.validateBlockedBy(ctx, goal, ids); err != nil {
+			return nil, err
+		}
+	}
+
 	createdGoal, err := s.repo.CreateGoal(ctx, goal)
 	if err != nil {
 		logger.Log.WithError(err).Error("Service failed to create goal")
 		return nil, fmt.Errorf("failed to create goal: %v", err)
 	}
 
+	s.counters.GoalCreated(ctx, createdGoal)
+	s.badgeService.CheckAndAwardBadges(ctx, createdGoal.UserID)
+
 	logger.Log.WithField("goal_id", createdGoal.ID.Hex()).Info("Goal created in service layer")
 	return createdGoal, nil
 }
 
+// ValidCategory reports whether a goal owned by ownerID may use the category:
+// empty, built in, or one of the owner's custom categories.
+func (s *GoalService) ValidCategory(ctx context.Context, ownerID primitive.ObjectID, category string) bool {
+	return category == "" || s.categories.IsValidCategory(ctx, ownerID, category)
+}
+
 // GetGoal retrieves a goal by its ID.
 func (s *GoalService) GetGoal(ctx context.Context, id string) (*models.Goal, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
@@ -62,41 +172,101 @@ func (s *GoalService) GetGoal(ctx context.Context, id string) (*models.Goal, err
 	return goal, nil
 }
 
-// UpdateGoal updates an existing goal.
-func (s *GoalService) UpdateGoal(ctx context.Context, id string, updatedGoal *models.Goal) (*models.Goal, error) {
+// GetActiveGoal retrieves a goal by its ID like GetGoal, but treats goals in
+// the trash as missing. Everything that changes a goal loads it through here.
+func (s *GoalService) GetActiveGoal(ctx context.Context, id string) (*models.Goal, error) {
+	goal, err := s.GetGoal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !goal.DeletedAt.IsZero() {
+		return nil, fmt.Errorf("goal not found")
+	}
+	return goal, nil
+}
+
+// UpdateGoal updates an existing goal. actorID is the user making the change;
+// everyone else on a shared goal is notified about it.
+func (s *GoalService) UpdateGoal(ctx context.Context, id string, updatedGoal *models.Goal, actorID primitive.ObjectID) (*models.Goal, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		logger.Log.WithField("goal_id", id).WithError(err).Warn("Invalid goal ID in UpdateGoal")
 		return nil, fmt.Errorf("invalid goal ID: %v", err)
 	}
 
+	tags, err := NormalizeTags(updatedGoal.Tags)
+	if err != nil {
+		return nil, err
+	}
+	updatedGoal.Tags = tags
+
+	if err := normalizeStepNotes(updatedGoal.Steps); err != nil {
+		return nil, err
+	}
+	if err := validateRemindBefore(updatedGoal.RemindBefore); err != nil {
+		return nil, err
+	}
+	if err := validateVisibility(updatedGoal.Visibility); err != nil {
+		return nil, err
+	}
+
+	previousStatus := ""
+	previousXP := 0
+	existing, err := s.repo.GetGoalByID(ctx, objID)
+	if err == nil && !existing.DeletedAt.IsZero() {
+		return nil, fmt.Errorf("goal not found")
+	}
+	if err == nil {
+		previousStatus = existing.Status
+		previousXP = stepProgressXP(existing.Steps)
+	}
+	if err := validateStepDueDates(updatedGoal, existing, time.Now()); err != nil {
+		return nil, err
+	}
+	if updatedGoal.Status == "" {
+		updatedGoal.Status = previousStatus
+	}
+	// The goal's completion time is never taken from the client
+	updatedGoal.CompletedAt = time.Time{}
+	if existing != nil {
+		updatedGoal.CompletedAt = existing.CompletedAt
+	}
+	s.RecalculateStatus(updatedGoal)
+
 	goal, err := s.repo.UpdateGoal(ctx, objID, updatedGoal)
+	if errors.Is(err, repository.ErrConflict) {
+		return nil, err
+	}
 	if err != nil {
 		logger.Log.WithField("goal_id", id).WithError(err).Error("Failed to update goal")
 		return nil, fmt.Errorf("failed to update goal: %v", err)
 	}
 
-	if goal.Status == "completed" {
-		go func() {
-			err := s.NotificationService.CreateNotification(
-				ctx,
-				goal.UserID,
-				"goal_completed",
-				"🎉 Goal Completed",
-				fmt.Sprintf("You’ve successfully completed your goal: \"%s\"!", goal.Name),
-				&goal.ID,
-			)
-			if err != nil {
-				logrus.WithError(err).Warn("Failed to send goal completed notification")
-			}
-		}()
+	// A missing snapshot only leaves a gap in the chart, so the update still succeeds
+	_ = s.ProgressService.RecordSnapshot(ctx, goal)
+
+	s.awardXP(ctx, goal.UserID, stepProgressXP(goal.Steps)-previousXP)
+	s.notifyStatusChange(ctx, goal, previousStatus)
+	if existing != nil {
+		s.notifySharedGoalChange(ctx, goal, stepProgressXP(goal.Steps) != previousXP, actorID)
 	}
 
 	logger.Log.WithField("goal_id", id).Info("Goal updated successfully in service layer")
 	return goal, nil
 }
 
-// DeleteGoal removes a goal from the database.
+// GoalTrashRetention is how long a deleted goal stays in the trash before it
+// can no longer be restored and gets purged.
+const GoalTrashRetention = 30 * 24 * time.Hour
+
+// MaxTrashItems is how many goals a user's trash holds. Trashing more purges
+// the oldest ones.
+const MaxTrashItems = 200
+
+// TrashPurgeWarningLead is how long before the purge owners are warned.
+const TrashPurgeWarningLead = 3 * 24 * time.Hour
+
+// DeleteGoal moves a goal to the trash.
 func (s *GoalService) DeleteGoal(ctx context.Context, id string) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -104,76 +274,403 @@ func (s *GoalService) DeleteGoal(ctx context.Context, id string) error {
 		return fmt.Errorf("invalid goal ID: %v", err)
 	}
 
-	if err := s.repo.DeleteGoal(ctx, objID); err != nil {
+	goal, err := s.repo.GetGoalByID(ctx, objID)
+	if err != nil {
+		return fmt.Errorf("failed to delete goal: %v", err)
+	}
+
+	trashed, err := s.repo.SoftDeleteGoals(ctx, []primitive.ObjectID{objID})
+	if err != nil {
 		logger.Log.WithField("goal_id", id).WithError(err).Error("Failed to delete goal")
 		return fmt.Errorf("failed to delete goal: %v", err)
 	}
+	if trashed > 0 {
+		s.counters.GoalTrashed(ctx, goal.UserID, goal.Status)
+		s.enforceTrashCap(ctx, goal.UserID)
+	}
 
-	logger.Log.WithField("goal_id", id).Info("Goal deleted successfully in service layer")
+	logger.Log.WithField("goal_id", id).Info("Goal moved to trash in service layer")
 	return nil
 }
 
-// GetAllGoals retrieves a list of goals with an optional limit.
-func (s *GoalService) GetAllGoals(ctx context.Context, limit int64) ([]models.Goal, error) {
-	goals, err := s.repo.GetAllGoals(ctx, limit)
+// GetDeletedGoals returns the user's goals that are in the trash.
+func (s *GoalService) GetDeletedGoals(ctx context.Context, userID primitive.ObjectID) ([]models.Goal, error) {
+	goals, err := s.repo.GetDeletedGoals(ctx, userID)
 	if err != nil {
-		logger.Log.WithError(err).Error("Failed to fetch all goals")
-		return nil, fmt.Errorf("failed to fetch goals: %v", err)
+		return nil, fmt.Errorf("failed to fetch deleted goals: %v", err)
+	}
+	return goals, nil
+}
+
+// RestoreGoal takes a goal out of the trash if it was deleted within the retention period.
+func (s *GoalService) RestoreGoal(ctx context.Context, goal *models.Goal) error {
+	if goal.DeletedAt.IsZero() {
+		return fmt.Errorf("goal is not in the trash")
+	}
+	if time.Since(goal.DeletedAt) > GoalTrashRetention {
+		return fmt.Errorf("goal was deleted more than 30 days ago and can no longer be restored")
+	}
+
+	if err := s.repo.RestoreGoal(ctx, goal.ID); err != nil {
+		logger.Log.WithField("goal_id", goal.ID.Hex()).WithError(err).Error("Failed to restore goal")
+		return fmt.Errorf("failed to restore goal: %v", err)
+	}
+
+	status := goal.StatusBeforeDelete
+	if status == "" {
+		status = "in_progress"
+	}
+	s.counters.GoalRestored(ctx, goal.UserID, status)
+	return nil
+}
+
+// PermanentDeleteGoal removes a goal that is already in the trash.
+func (s *GoalService) PermanentDeleteGoal(ctx context.Context, goal *models.Goal) error {
+	if goal.DeletedAt.IsZero() {
+		return fmt.Errorf("goal must be moved to the trash first")
+	}
+
+	if err := s.repo.PermanentDeleteGoal(ctx, goal.ID); err != nil {
+		logger.Log.WithField("goal_id", goal.ID.Hex()).WithError(err).Error("Failed to permanently delete goal")
+		return fmt.Errorf("failed to delete goal: %v", err)
+	}
+	return nil
+}
+
+// enforceTrashCap purges the user's oldest trashed goals once the trash holds
+// more than MaxTrashItems, and tells the user how many were removed.
+func (s *GoalService) enforceTrashCap(ctx context.Context, userID primitive.ObjectID) {
+	count, err := s.repo.CountDeletedGoals(ctx, userID)
+	if err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Warn("Failed to check trash size")
+		return
+	}
+	if count <= MaxTrashItems {
+		return
+	}
+
+	purged, err := s.repo.PurgeOldestDeletedGoals(ctx, userID, count-MaxTrashItems)
+	if err != nil || purged == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("Your trash is limited to %d goals, so the %d oldest were permanently deleted.", MaxTrashItems, purged)
+	if err := s.NotificationService.CreateNotification(ctx, userID, "trash_purged", "Trash is full", msg, nil); err != nil {
+		logger.Log.WithError(err).Warn("Failed to send trash_purged notification")
+	}
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID.Hex(),
+		"purged":  purged,
+	}).Info("Oldest trashed goals purged to stay within the trash limit")
+}
+
+// EmptyTrash permanently removes every goal in the user's trash.
+func (s *GoalService) EmptyTrash(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	count, err := s.repo.DeleteTrashedGoals(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to empty trash: %v", err)
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID.Hex(),
+		"deleted": count,
+	}).Info("Trash emptied in service layer")
+	return count, nil
+}
+
+// WarnUpcomingTrashPurge sends a "trash_purge_warning" notification to users
+// whose trashed goals will be purged within TrashPurgeWarningLead. Each goal
+// is only counted in one warning.
+func (s *GoalService) WarnUpcomingTrashPurge(ctx context.Context) (int, error) {
+	warnings, err := s.repo.GetTrashPurgeWarnings(ctx, time.Now().Add(-(GoalTrashRetention - TrashPurgeWarningLead)))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, warning := range warnings {
+		// Marking first means a concurrent run cannot warn about the same goals
+		marked, err := s.repo.MarkPurgeWarned(ctx, warning.GoalIDs)
+		if err != nil {
+			logger.Log.WithError(err).WithField("user_id", warning.UserID.Hex()).Warn("Failed to mark goals as warned")
+			continue
+		}
+		if marked == 0 {
+			continue
+		}
+
+		msg := fmt.Sprintf("%d goal(s) in your trash will be permanently deleted in %d days. Restore them from the trash to keep them.",
+			marked, int(TrashPurgeWarningLead.Hours()/24))
+		if err := s.NotificationService.CreateNotification(ctx, warning.UserID, "trash_purge_warning", "Trash will be emptied soon", msg, nil); err != nil {
+			logger.Log.WithError(err).Warn("Failed to send trash_purge_warning notification")
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// PurgeExpiredTrash permanently removes goals that have been in the trash
+// longer than the retention period.
+func (s *GoalService) PurgeExpiredTrash(ctx context.Context) (int64, error) {
+	count, err := s.repo.PurgeDeletedGoals(ctx, time.Now().Add(-GoalTrashRetention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %v", err)
+	}
+	return count, nil
+}
+
+// GoalPage is one page of goals listed with their owner's username.
+type GoalPage struct {
+	Goals []models.GoalWithOwner `json:"goals"`
+	Page  int64                  `json:"page"`
+	Limit int64                  `json:"limit"`
+	Total int64                  `json:"total"`
+}
+
+// GetAdminGoals returns one page of all users' goals matching the filter.
+// Pages start at 1. Non-positive limits fall back to the default and large
+// ones are capped.
+func (s *GoalService) GetAdminGoals(ctx context.Context, filter repository.AdminGoalFilter, page, limit int64) (*GoalPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = DefaultAdminGoalsLimit
 	}
+	if limit > MaxAdminGoalsLimit {
+		limit = MaxAdminGoalsLimit
+	}
+
+	goals, total, err := s.repo.GetAdminGoals(ctx, filter, (page-1)*limit, limit)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to fetch admin goals")
+		return nil, err
+	}
+
+	return &GoalPage{Goals: goals, Page: page, Limit: limit, Total: total}, nil
+}
+
+// GetSharedGoals returns one page of the goals the user collaborates on but
+// does not own. Pages start at 1. Non-positive limits fall back to the
+// default and large ones are capped.
+func (s *GoalService) GetSharedGoals(ctx context.Context, userID primitive.ObjectID, category string, includeClosed bool, page, limit int64) (*GoalPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = DefaultSharedGoalsLimit
+	}
+	if limit > MaxSharedGoalsLimit {
+		limit = MaxSharedGoalsLimit
+	}
+
+	goals, total, err := s.repo.GetSharedGoals(ctx, userID, category, includeClosed, (page-1)*limit, limit)
+	if err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Error("Failed to fetch shared goals")
+		return nil, err
+	}
+
+	return &GoalPage{Goals: goals, Page: page, Limit: limit, Total: total}, nil
+}
 
-	logger.Log.WithField("count", len(goals)).Info("All goals fetched successfully in service layer")
+// GetGoalsDueWithin returns unfinished goals with any deadline inside the given window.
+func (s *GoalService) GetGoalsDueWithin(ctx context.Context, window time.Duration) ([]models.Goal, error) {
+	goals, err := s.repo.GetGoalsDueWithin(ctx, window)
+	if err != nil {
+		logger.Log.WithError(err).Error("Failed to fetch goals due within window")
+		return nil, fmt.Errorf("failed to fetch goals: %v", err)
+	}
 	return goals, nil
 }
 
-func (s *GoalService) GetGoals(ctx context.Context, userID primitive.ObjectID, category string) ([]models.Goal, error) {
-	goals, err := s.repo.GetGoals(ctx, userID, category)
+func (s *GoalService) GetGoals(ctx context.Context, userID primitive.ObjectID, category, tag string, includeClosed bool) ([]models.Goal, error) {
+	goals, err := s.repo.GetGoals(ctx, userID, category, strings.ToLower(strings.TrimSpace(tag)), includeClosed)
 	if err != nil {
 		logger.Log.WithFields(map[string]interface{}{
 			"user_id":  userID.Hex(),
 			"category": category,
+			"tag":      tag,
 		}).WithError(err).Error("Failed to get filtered goals in service")
 		return nil, err
 	}
 
+	// Goals the user pinned come first; pins on goals shared with them belong to the owner
+	sort.SliceStable(goals, func(i, j int) bool {
+		return isPinnedBy(goals[i], userID) && !isPinnedBy(goals[j], userID)
+	})
+
 	logger.Log.WithFields(map[string]interface{}{
 		"user_id":  userID.Hex(),
 		"category": category,
+		"tag":      tag,
 		"count":    len(goals),
 	}).Info("Filtered goals fetched in service layer")
 	return goals, nil
 }
 
-// InviteCollaborator adds a user as a collaborator to a goal if the requester is the owner.
-func (s *GoalService) InviteCollaborator(ctx context.Context, goalID string, requesterID, collaboratorID primitive.ObjectID) error {
+// GetTags returns the distinct tags across the user's goals.
+func (s *GoalService) GetTags(ctx context.Context, userID primitive.ObjectID) ([]string, error) {
+	tags, err := s.repo.GetDistinctTags(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %v", err)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// NormalizeTags trims and lowercases tags, drops empty and duplicate ones and
+// enforces the tag limits.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxGoalTagLen {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidTags, tag, MaxGoalTagLen)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxGoalTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidTags, MaxGoalTags)
+	}
+	return normalized, nil
+}
+
+// NormalizeNote strips control characters other than newlines and tabs from a
+// step or substep note, trims surrounding whitespace and enforces MaxStepNoteLen.
+func NormalizeNote(note string) (string, error) {
+	note = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, note))
+	if utf8.RuneCountInString(note) > MaxStepNoteLen {
+		return "", fmt.Errorf("%w: notes are limited to %d characters", ErrInvalidNote, MaxStepNoteLen)
+	}
+	return note, nil
+}
+
+// normalizeStepNotes applies NormalizeNote to every step and substep in place.
+func normalizeStepNotes(steps []models.Step) error {
+	for i := range steps {
+		note, err := NormalizeNote(steps[i].Note)
+		if err != nil {
+			return err
+		}
+		steps[i].Note = note
+		for j := range steps[i].Substeps {
+			note, err := NormalizeNote(steps[i].Substeps[j].Note)
+			if err != nil {
+				return err
+			}
+			steps[i].Substeps[j].Note = note
+		}
+	}
+	return nil
+}
+
+// goalStatsMonths is how many calendar months completed_per_month covers.
+const goalStatsMonths = 12
+
+// UserGoalStats holds dashboard statistics, kept apart for the goals a user
+// owns and the ones they collaborate on.
+type UserGoalStats struct {
+	Owned        *models.GoalStats    `json:"owned"`
+	Collaborated *models.GoalStats    `json:"collaborated"`
+	Counters     *models.UserCounters `json:"counters"`
+}
+
+// GetGoalStats computes the user's goal statistics in the database and adds
+// the precomputed counters.
+func (s *GoalService) GetGoalStats(ctx context.Context, userID primitive.ObjectID) (*UserGoalStats, error) {
+	now := time.Now().UTC()
+	firstMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -(goalStatsMonths - 1), 0)
+
+	owned, err := s.repo.GetGoalStats(ctx, bson.M{"user_id": userID}, firstMonth, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute owned goal stats: %v", err)
+	}
+	collaborated, err := s.repo.GetGoalStats(ctx, bson.M{"collaborators.user_id": userID}, firstMonth, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute collaborated goal stats: %v", err)
+	}
+
+	owned.CompletedPerMonth = fillMonths(owned.CompletedPerMonth, firstMonth)
+	collaborated.CompletedPerMonth = fillMonths(collaborated.CompletedPerMonth, firstMonth)
+
+	counters, err := s.counters.GetCounters(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load counters: %v", err)
+	}
+	return &UserGoalStats{Owned: owned, Collaborated: collaborated, Counters: counters}, nil
+}
+
+// fillMonths returns one entry per month starting at first, using zero for
+// months the aggregation had no goals for.
+func fillMonths(counts []models.MonthlyCount, first time.Time) []models.MonthlyCount {
+	byMonth := make(map[string]int64, len(counts))
+	for _, c := range counts {
+		byMonth[c.Month] = c.Count
+	}
+
+	months := make([]models.MonthlyCount, 0, goalStatsMonths)
+	for i := 0; i < goalStatsMonths; i++ {
+		month := first.AddDate(0, i, 0).Format("2006-01")
+		months = append(months, models.MonthlyCount{Month: month, Count: byMonth[month]})
+	}
+	return months
+}
+
+// ErrInvitationNotFound is returned when an invitation does not exist or is
+// addressed to someone else.
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+// InviteCollaborator creates a pending invitation for a friend of the owner.
+// The invitee is only added to the goal once they accept it.
+// role is "viewer" or "editor"; an empty role defaults to editor.
+func (s *GoalService) InviteCollaborator(ctx context.Context, goalID string, requesterID, collaboratorID primitive.ObjectID, role string) (*models.GoalInvitation, error) {
+	if role == "" {
+		role = models.CollaboratorRoleEditor
+	}
+	if role != models.CollaboratorRoleViewer && role != models.CollaboratorRoleEditor {
+		return nil, fmt.Errorf("invalid collaborator role: %s", role)
+	}
+
 	objID, err := primitive.ObjectIDFromHex(goalID)
 	if err != nil {
-		return fmt.Errorf("invalid goal ID: %v", err)
+		return nil, fmt.Errorf("invalid goal ID: %v", err)
 	}
 
 	goal, err := s.repo.GetGoalByID(ctx, objID)
 	if err != nil {
-		return fmt.Errorf("goal not found: %v", err)
+		return nil, fmt.Errorf("goal not found: %v", err)
 	}
 
 	// Only the owner can invite collaborators
 	if goal.UserID != requesterID {
-		return fmt.Errorf("only the owner can invite collaborators")
+		return nil, fmt.Errorf("only the owner can invite collaborators")
 	}
 
 	// Prevent inviting self or duplicate
 	if collaboratorID == requesterID {
-		return fmt.Errorf("you cannot invite yourself")
+		return nil, fmt.Errorf("you cannot invite yourself")
 	}
 	for _, existing := range goal.Collaborators {
-		if existing == collaboratorID {
-			return fmt.Errorf("user is already a collaborator")
+		if existing.UserID == collaboratorID {
+			return nil, fmt.Errorf("user is already a collaborator")
 		}
 	}
 
 	//Check if they are friends (important!)
 	friendIDs, err := s.userRepo.GetFriendIDs(ctx, requesterID)
 	if err != nil {
-		return fmt.Errorf("failed to fetch friend list: %v", err)
+		return nil, fmt.Errorf("failed to fetch friend list: %v", err)
 	}
 
 	isFriend := false
@@ -184,8 +681,1028 @@ func (s *GoalService) InviteCollaborator(ctx context.Context, goalID string, req
 		}
 	}
 	if !isFriend {
-		return fmt.Errorf("you can only invite your friends")
+		return nil, fmt.Errorf("you can only invite your friends")
+	}
+
+	return s.invitationRepo.CreateInvitation(ctx, &models.GoalInvitation{
+		GoalID:    goal.ID,
+		GoalName:  goal.Name,
+		InviterID: requesterID,
+		InviteeID: collaboratorID,
+		Role:      role,
+	})
+}
+
+// GetPendingInvitations returns the goal invitations waiting for the user's answer.
+func (s *GoalService) GetPendingInvitations(ctx context.Context, userID primitive.ObjectID) ([]models.GoalInvitation, error) {
+	return s.invitationRepo.GetPendingByInvitee(ctx, userID)
+}
+
+// RespondToInvitation accepts or declines an invitation addressed to the user.
+// Accepting adds the user to the goal with the invited role; either way the
+// inviter is notified of the answer.
+func (s *GoalService) RespondToInvitation(ctx context.Context, invitationID string, userID primitive.ObjectID, accept bool) (*models.GoalInvitation, error) {
+	objID, err := primitive.ObjectIDFromHex(invitationID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invitation ID: %v", err)
+	}
+
+	invitation, err := s.invitationRepo.GetInvitationByID(ctx, objID)
+	if err != nil || invitation.InviteeID != userID {
+		return nil, ErrInvitationNotFound
+	}
+	if invitation.Status != models.InvitationStatusPending {
+		return nil, repository.ErrInvitationNotPending
+	}
+
+	status := models.InvitationStatusDeclined
+	if accept {
+		status = models.InvitationStatusAccepted
+		// The goal may have been deleted since the invite was sent
+		if _, err := s.repo.GetGoalByID(ctx, invitation.GoalID); err != nil {
+			return nil, fmt.Errorf("goal no longer exists")
+		}
+	}
+
+	if err := s.invitationRepo.UpdateInvitationStatus(ctx, objID, status); err != nil {
+		return nil, err
+	}
+	invitation.Status = status
+
+	if accept {
+		if err := s.repo.AddCollaborator(ctx, invitation.GoalID, userID, invitation.Role); err != nil {
+			return nil, fmt.Errorf("failed to add collaborator: %v", err)
+		}
+	}
+
+	notificationType, title, verb := "goal_invite_declined", "🙅 Invitation declined", "declined"
+	if accept {
+		notificationType, title, verb = "goal_invite_accepted", "🤝 Invitation accepted", "accepted"
+	}
+	err = s.NotificationService.CreateNotification(
+		ctx,
+		invitation.InviterID,
+		notificationType,
+		title,
+		fmt.Sprintf("Your invitation to collaborate on \"%s\" was %s.", invitation.GoalName, verb),
+		&invitation.GoalID,
+	)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to send invitation response notification")
+	}
+
+	return invitation, nil
+}
+
+// TransferGoal hands a goal over to one of its collaborators. The new owner is
+// removed from the collaborators and the previous owner becomes a collaborator.
+func (s *GoalService) TransferGoal(ctx context.Context, goalID string, currentOwnerID, newOwnerID primitive.ObjectID) (*models.Goal, error) {
+	objID, err := primitive.ObjectIDFromHex(goalID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid goal ID: %v", err)
+	}
+
+	goal, err := s.repo.GetGoalByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("goal not found: %v", err)
+	}
+	if !goal.DeletedAt.IsZero() {
+		return nil, fmt.Errorf("goal not found")
+	}
+
+	if goal.UserID != currentOwnerID {
+		return nil, fmt.Errorf("only the owner can transfer the goal")
+	}
+	if newOwnerID == currentOwnerID {
+		return nil, fmt.Errorf("you already own this goal")
+	}
+
+	isCollaborator := false
+	collaborators := make([]models.Collaborator, 0, len(goal.Collaborators))
+	for _, c := range goal.Collaborators {
+		if c.UserID == newOwnerID {
+			isCollaborator = true
+			continue
+		}
+		collaborators = append(collaborators, c)
+	}
+	if !isCollaborator {
+		return nil, fmt.Errorf("new owner must be a collaborator of the goal")
+	}
+	collaborators = append(collaborators, models.Collaborator{UserID: currentOwnerID, Role: models.CollaboratorRoleEditor})
+
+	if err := s.repo.TransferOwnership(ctx, objID, currentOwnerID, newOwnerID, collaborators); err != nil {
+		logger.Log.WithField("goal_id", goalID).WithError(err).Error("Failed to transfer goal")
+		return nil, fmt.Errorf("failed to transfer goal: %v", err)
 	}
 
-	return s.repo.AddCollaborator(ctx, objID, collaboratorID)
+	s.counters.GoalTransferred(ctx, currentOwnerID, newOwnerID, goal.Status)
+	goal.UserID = newOwnerID
+	goal.Collaborators = collaborators
+	goal.Version++
+
+	err = s.NotificationService.CreateNotification(
+		ctx,
+		newOwnerID,
+		"goal_ownership_transferred",
+		"👑 You now own a goal",
+		fmt.Sprintf("Ownership of the goal \"%s\" has been transferred to you.", goal.Name),
+		&goal.ID,
+	)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to send goal ownership transferred notification")
+	}
+
+	logger.Log.WithField("goal_id", goalID).Info("Goal ownership transferred in service layer")
+	return goal, nil
+}
+
+// StepUpdate holds the optional fields of a targeted step update.
+type StepUpdate struct {
+	Name    *string    `json:"name"`
+	DueDate *time.Time `json:"due_date"`
+}
+
+// SubstepUpdate holds the optional fields of a targeted substep update.
+// Completion is changed through the progress endpoint so step and goal
+// status stay consistent.
+type SubstepUpdate struct {
+	Title   *string    `json:"title"`
+	DueDate *time.Time `json:"due_date"`
+}
+
+// UpdateStep updates a single step of a goal without replacing the whole goal.
+func (s *GoalService) UpdateStep(ctx context.Context, goalID string, userID primitive.ObjectID, stepName string, update StepUpdate) (*models.Goal, error) {
+	goal, err := s.getEditableGoal(ctx, goalID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if findStep(goal, stepName) < 0 {
+		return nil, fmt.Errorf("step not found")
+	}
+
+	fields := bson.M{}
+	if update.Name != nil {
+		if *update.Name == "" {
+			return nil, fmt.Errorf("step name cannot be empty")
+		}
+		if *update.Name != stepName && findStep(goal, *update.Name) >= 0 {
+			return nil, fmt.Errorf("a step with this name already exists")
+		}
+		fields["name"] = *update.Name
+	}
+	if update.DueDate != nil {
+		if !update.DueDate.IsZero() && update.DueDate.Before(time.Now()) {
+			return nil, fmt.Errorf("due date cannot be in the past")
+		}
+		if !update.DueDate.IsZero() && !goal.DueDate.IsZero() && update.DueDate.After(goal.DueDate) {
+			return nil, &StepDueDateError{Issues: []StepDueDateIssue{{Step: findStep(goal, stepName), Reason: DueDateAfterGoal}}}
+		}
+		fields["due_date"] = *update.DueDate
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("nothing to update")
+	}
+
+	if err := s.repo.UpdateStep(ctx, goal.ID, stepName, fields); err != nil {
+		logger.Log.WithField("goal_id", goalID).WithError(err).Error("Failed to update step")
+		return nil, fmt.Errorf("failed to update step: %v", err)
+	}
+
+	return s.repo.GetGoalByID(ctx, goal.ID)
+}
+
+// UpdateSubstep updates a single substep of a goal without replacing the whole goal.
+func (s *GoalService) UpdateSubstep(ctx context.Context, goalID string, userID primitive.ObjectID, stepName string, substepIndex int, update SubstepUpdate) (*models.Goal, error) {
+	goal, err := s.getEditableGoal(ctx, goalID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stepIdx := findStep(goal, stepName)
+	if stepIdx < 0 {
+		return nil, fmt.Errorf("step not found")
+	}
+	if substepIndex < 0 || substepIndex >= len(goal.Steps[stepIdx].Substeps) {
+		return nil, fmt.Errorf("invalid substep index")
+	}
+
+	fields := bson.M{}
+	if update.Title != nil {
+		if *update.Title == "" {
+			return nil, fmt.Errorf("substep title cannot be empty")
+		}
+		fields["title"] = *update.Title
+	}
+	if update.DueDate != nil {
+		if !update.DueDate.IsZero() && update.DueDate.Before(time.Now()) {
+			return nil, fmt.Errorf("due date cannot be in the past")
+		}
+		if !update.DueDate.IsZero() && !goal.DueDate.IsZero() && update.DueDate.After(goal.DueDate) {
+			return nil, &StepDueDateError{Issues: []StepDueDateIssue{{Step: stepIdx, Substep: &substepIndex, Reason: DueDateAfterGoal}}}
+		}
+		fields["due_date"] = *update.DueDate
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("nothing to update")
+	}
+
+	if err := s.repo.UpdateSubstep(ctx, goal.ID, stepName, substepIndex, fields); err != nil {
+		logger.Log.WithField("goal_id", goalID).WithError(err).Error("Failed to update substep")
+		return nil, fmt.Errorf("failed to update substep: %v", err)
+	}
+
+	return s.repo.GetGoalByID(ctx, goal.ID)
+}
+
+// getEditableGoal loads a goal that is not in the trash and checks that userID is its owner or an editor.
+func (s *GoalService) getEditableGoal(ctx context.Context, goalID string, userID primitive.ObjectID) (*models.Goal, error) {
+	objID, err := primitive.ObjectIDFromHex(goalID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid goal ID: %v", err)
+	}
+
+	goal, err := s.repo.GetGoalByID(ctx, objID)
+	if err != nil {
+		return nil, fmt.Errorf("goal not found: %v", err)
+	}
+	if !goal.DeletedAt.IsZero() {
+		return nil, fmt.Errorf("goal not found")
+	}
+
+	if goal.UserID == userID {
+		return goal, nil
+	}
+	for _, c := range goal.Collaborators {
+		if c.UserID == userID && c.CanEdit() {
+			return goal, nil
+		}
+	}
+	return nil, fmt.Errorf("only owner or editors can update the goal")
+}
+
+// findStep returns the index of the step with the given name, or -1.
+func findStep(goal *models.Goal, name string) int {
+	for i := range goal.Steps {
+		if goal.Steps[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ExportGoals serializes all of the user's goals in the given format ("csv" or "json").
+func (s *GoalService) ExportGoals(ctx context.Context, userID primitive.ObjectID, format string) ([]byte, error) {
+	if format != "csv" && format != "json" {
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	goals, err := s.repo.GetGoals(ctx, userID, "", "", true)
+	if err != nil {
+		logger.Log.WithField("user_id", userID.Hex()).WithError(err).Error("Failed to fetch goals for export")
+		return nil, fmt.Errorf("failed to fetch goals: %v", err)
+	}
+	if goals == nil {
+		goals = []models.Goal{}
+	}
+
+	var buf bytes.Buffer
+	if format == "csv" {
+		err = export.WriteGoalsCSV(&buf, goals)
+	} else {
+		err = json.NewEncoder(&buf).Encode(goals)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize goals: %v", err)
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID.Hex(),
+		"format":  format,
+		"count":   len(goals),
+	}).Info("Goals exported in service layer")
+	return buf.Bytes(), nil
+}
+
+// ImportError reports why one entry of an import payload was skipped.
+type ImportError struct {
+	Index int    `json:"index"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportGoals creates goals for userID from a previously exported JSON payload.
+// Ownership, collaborators, IDs and timestamps in the payload are ignored.
+// Invalid entries are skipped and reported instead of aborting the import.
+func (s *GoalService) ImportGoals(ctx context.Context, userID primitive.ObjectID, goals []models.Goal) ([]models.Goal, []ImportError, error) {
+	if len(goals) == 0 {
+		return nil, nil, fmt.Errorf("no goals to import")
+	}
+	if len(goals) > MaxImportGoals {
+		return nil, nil, fmt.Errorf("cannot import more than %d goals at once", MaxImportGoals)
+	}
+
+	created := []models.Goal{}
+	var importErrors []ImportError
+	now := time.Now()
+
+	for i := range goals {
+		goal := goals[i]
+
+		if goal.Name == "" {
+			importErrors = append(importErrors, ImportError{Index: i, Error: "goal name is required"})
+			continue
+		}
+		if !s.ValidCategory(ctx, userID, goal.Category) {
+			importErrors = append(importErrors, ImportError{Index: i, Name: goal.Name, Error: "invalid category"})
+			continue
+		}
+		if !goal.DueDate.IsZero() && goal.DueDate.Before(now) {
+			importErrors = append(importErrors, ImportError{Index: i, Name: goal.Name, Error: "due date cannot be in the past"})
+			continue
+		}
+		tags, err := NormalizeTags(goal.Tags)
+		if err != nil {
+			importErrors = append(importErrors, ImportError{Index: i, Name: goal.Name, Error: err.Error()})
+			continue
+		}
+		goal.Tags = tags
+		if err := normalizeStepNotes(goal.Steps); err != nil {
+			importErrors = append(importErrors, ImportError{Index: i, Name: goal.Name, Error: err.Error()})
+			continue
+		}
+		if err := validateStepDueDates(&goal, nil, now); err != nil {
+			importErrors = append(importErrors, ImportError{Index: i, Name: goal.Name, Error: err.Error()})
+			continue
+		}
+
+		goal.ID = primitive.NilObjectID
+		goal.UserID = userID
+		goal.Collaborators = nil
+		goal.DeletedAt = time.Time{}
+		if goal.Steps == nil {
+			goal.Steps = []models.Step{}
+		}
+
+		// Recompute completion state instead of trusting the payload
+		if len(goal.Steps) == 0 {
+			goal.Status = "in_progress"
+		}
+		s.RecalculateStatus(&goal)
+
+		createdGoal, err := s.repo.CreateGoal(ctx, &goal)
+		if err != nil {
+			importErrors = append(importErrors, ImportError{Index: i, Name: goal.Name, Error: "failed to save goal"})
+			continue
+		}
+		s.counters.GoalCreated(ctx, createdGoal)
+		created = append(created, *createdGoal)
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID.Hex(),
+		"created": len(created),
+		"skipped": len(importErrors),
+	}).Info("Goals imported in service layer")
+	return created, importErrors, nil
+}
+
+// BulkResult reports the outcome of a bulk operation for one goal ID.
+type BulkResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"` // "ok" or "error"
+	Error  string `json:"error,omitempty"`
+}
+
+// ErrBulkRejected is returned when at least one ID failed validation;
+// nothing is applied in that case and the per-ID results explain why.
+var ErrBulkRejected = errors.New("bulk operation rejected")
+
+// BulkUpdateGoals applies one action ("delete", "archive", "set_category",
+// "set_status") to several goals owned by userID. Every ID is checked before
+// anything is written, and the change itself is a single UpdateMany/DeleteMany.
+func (s *GoalService) BulkUpdateGoals(ctx context.Context, userID primitive.ObjectID, action string, ids []string, value string) ([]BulkResult, []models.Goal, error) {
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("no goal IDs provided")
+	}
+	if len(ids) > MaxBulkGoals {
+		return nil, nil, fmt.Errorf("cannot update more than %d goals at once", MaxBulkGoals)
+	}
+
+	var fields bson.M
+	switch action {
+	case "delete":
+	case "archive":
+		fields = bson.M{"status": "archived"}
+	case "set_category":
+		if !s.ValidCategory(ctx, userID, value) {
+			return nil, nil, fmt.Errorf("invalid category")
+		}
+		fields = bson.M{"category": value}
+	case "set_status":
+		if !bulkSettableStatuses[value] {
+			return nil, nil, fmt.Errorf("invalid status")
+		}
+		fields = bson.M{"status": value}
+	default:
+		return nil, nil, fmt.Errorf("unknown bulk action: %s", action)
+	}
+
+	results := make([]BulkResult, len(ids))
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for i, id := range ids {
+		results[i] = BulkResult{ID: id, Status: "ok"}
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			results[i] = BulkResult{ID: id, Status: "error", Error: "invalid goal ID"}
+			continue
+		}
+		objIDs = append(objIDs, objID)
+	}
+
+	goals, err := s.repo.GetGoalsByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch goals: %v", err)
+	}
+	byID := make(map[string]models.Goal, len(goals))
+	for _, goal := range goals {
+		byID[goal.ID.Hex()] = goal
+	}
+
+	rejected := false
+	var targets []models.Goal
+	for i := range results {
+		if results[i].Status == "error" {
+			rejected = true
+			continue
+		}
+		goal, ok := byID[results[i].ID]
+		if !ok || !goal.DeletedAt.IsZero() {
+			results[i] = BulkResult{ID: results[i].ID, Status: "error", Error: "goal not found"}
+			rejected = true
+			continue
+		}
+		if goal.UserID != userID {
+			results[i] = BulkResult{ID: results[i].ID, Status: "error", Error: "forbidden: not the owner"}
+			rejected = true
+			continue
+		}
+		targets = append(targets, goal)
+	}
+	if rejected {
+		return results, nil, ErrBulkRejected
+	}
+
+	if action == "delete" {
+		_, err = s.repo.SoftDeleteGoals(ctx, objIDs)
+	} else {
+		_, err = s.repo.UpdateGoals(ctx, objIDs, fields)
+	}
+	if err != nil {
+		logger.Log.WithError(err).WithField("action", action).Error("Failed to apply bulk goal operation")
+		return nil, nil, fmt.Errorf("failed to apply bulk operation: %v", err)
+	}
+	if status, ok := fields["status"].(string); ok {
+		if err := s.repo.StampCompletedAt(ctx, objIDs, status, time.Now()); err != nil {
+			logger.Log.WithError(err).Warn("Failed to record completion times for bulk status change")
+		}
+	}
+
+	for _, goal := range targets {
+		if action == "delete" {
+			s.counters.GoalTrashed(ctx, goal.UserID, goal.Status)
+		} else if status, ok := fields["status"].(string); ok {
+			s.counters.GoalStatusChanged(ctx, goal.UserID, goal.Status, status)
+			s.awardXP(ctx, goal.UserID, goalStatusXP(goal.Status, status))
+		}
+	}
+	if action == "delete" {
+		s.enforceTrashCap(ctx, userID)
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID.Hex(),
+		"action":  action,
+		"count":   len(targets),
+	}).Info("Bulk goal operation applied in service layer")
+	return results, targets, nil
+}
+
+// maxStepEditRetries bounds how often a step edit is retried after a concurrent change.
+const maxStepEditRetries = 3
+
+// editSteps runs a read-modify-write cycle on a goal's steps. mutate receives a
+// fresh copy of the goal on each attempt; step completion and goal status are
+// recomputed afterwards and the write only succeeds if nobody changed the goal
+// in between.
+func (s *GoalService) editSteps(ctx context.Context, goalID string, userID primitive.ObjectID, mutate func(goal *models.Goal) error) (*models.Goal, error) {
+	for attempt := 0; attempt < maxStepEditRetries; attempt++ {
+		goal, err := s.getEditableGoal(ctx, goalID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		previousStatus := goal.Status
+		previousXP := stepProgressXP(goal.Steps)
+		if err := mutate(goal); err != nil {
+			return nil, err
+		}
+		s.RecalculateStatus(goal)
+
+		updatedAt, err := s.repo.ReplaceStepsIfUnchanged(ctx, goal.ID, goal.UpdatedAt, goal.Steps, goal.Status, goal.CompletedAt)
+		if errors.Is(err, repository.ErrGoalModified) {
+			logger.Log.WithField("goal_id", goalID).Warn("Goal changed during step edit, retrying")
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to save steps: %v", err)
+		}
+
+		goal.UpdatedAt = updatedAt
+		goal.Version++
+		s.awardXP(ctx, goal.UserID, stepProgressXP(goal.Steps)-previousXP)
+		s.notifyStatusChange(ctx, goal, previousStatus)
+		return goal, nil
+	}
+	return nil, repository.ErrGoalModified
+}
+
+// ReorderSubsteps reorders the substeps of one step. order must be a permutation
+// of the current substep indexes, listing the old index for each new position.
+func (s *GoalService) ReorderSubsteps(ctx context.Context, goalID string, userID primitive.ObjectID, stepIndex int, order []int) (*models.Goal, error) {
+	return s.editSteps(ctx, goalID, userID, func(goal *models.Goal) error {
+		if stepIndex < 0 || stepIndex >= len(goal.Steps) {
+			return fmt.Errorf("invalid step index")
+		}
+		substeps := goal.Steps[stepIndex].Substeps
+		if len(order) != len(substeps) {
+			return fmt.Errorf("order must list every substep exactly once")
+		}
+
+		seen := make([]bool, len(substeps))
+		reordered := make([]models.Substep, len(substeps))
+		for newPos, oldPos := range order {
+			if oldPos < 0 || oldPos >= len(substeps) || seen[oldPos] {
+				return fmt.Errorf("order must be a permutation of substep indexes")
+			}
+			seen[oldPos] = true
+			reordered[newPos] = substeps[oldPos]
+		}
+
+		goal.Steps[stepIndex].Substeps = reordered
+		return nil
+	})
+}
+
+// SetStepNote replaces the note on a step, or on one of its substeps when
+// substepIndex is not nil. An empty note clears it.
+func (s *GoalService) SetStepNote(ctx context.Context, goalID string, userID primitive.ObjectID, stepIndex int, substepIndex *int, note string) (*models.Goal, error) {
+	note, err := NormalizeNote(note)
+	if err != nil {
+		return nil, err
+	}
+	return s.editSteps(ctx, goalID, userID, func(goal *models.Goal) error {
+		if stepIndex < 0 || stepIndex >= len(goal.Steps) {
+			return fmt.Errorf("invalid step index")
+		}
+		if substepIndex == nil {
+			goal.Steps[stepIndex].Note = note
+			return nil
+		}
+		if *substepIndex < 0 || *substepIndex >= len(goal.Steps[stepIndex].Substeps) {
+			return fmt.Errorf("invalid substep index")
+		}
+		goal.Steps[stepIndex].Substeps[*substepIndex].Note = note
+		return nil
+	})
+}
+
+// SubstepMove describes moving one substep to another position, possibly in another step.
+type SubstepMove struct {
+	FromStep  int `json:"from_step"`
+	FromIndex int `json:"from_index"`
+	ToStep    int `json:"to_step"`
+	ToIndex   int `json:"to_index"`
+}
+
+// MoveSubstep moves a substep within a step or between steps. Completion of both
+// affected steps and the goal status are recomputed.
+func (s *GoalService) MoveSubstep(ctx context.Context, goalID string, userID primitive.ObjectID, move SubstepMove) (*models.Goal, error) {
+	return s.editSteps(ctx, goalID, userID, func(goal *models.Goal) error {
+		if move.FromStep < 0 || move.FromStep >= len(goal.Steps) || move.ToStep < 0 || move.ToStep >= len(goal.Steps) {
+			return fmt.Errorf("invalid step index")
+		}
+		from := goal.Steps[move.FromStep].Substeps
+		if move.FromIndex < 0 || move.FromIndex >= len(from) {
+			return fmt.Errorf("invalid source substep index")
+		}
+
+		substep := from[move.FromIndex]
+		goal.Steps[move.FromStep].Substeps = append(append([]models.Substep{}, from[:move.FromIndex]...), from[move.FromIndex+1:]...)
+
+		to := goal.Steps[move.ToStep].Substeps
+		if move.ToIndex < 0 || move.ToIndex > len(to) {
+			return fmt.Errorf("invalid target substep index")
+		}
+		moved := make([]models.Substep, 0, len(to)+1)
+		moved = append(moved, to[:move.ToIndex]...)
+		moved = append(moved, substep)
+		moved = append(moved, to[move.ToIndex:]...)
+		goal.Steps[move.ToStep].Substeps = moved
+		return nil
+	})
+}
+
+// Goal status set while a finished goal waits for its owner's confirmation.
+const GoalStatusPendingCompletion = "pending_completion"
+
+// ErrNotPendingCompletion is returned when confirming a goal that is not
+// waiting for confirmation.
+var ErrNotPendingCompletion = errors.New("goal is not awaiting completion confirmation")
+
+// RecalculateStatus derives each step's Completed flag from its substeps and
+// moves the goal to the status given by nextGoalStatus. Every mutation path
+// (full update, progress, step edits, template copy) goes through here so
+// the transitions stay consistent.
+func (s *GoalService) RecalculateStatus(goal *models.Goal) {
+	recalculateStatus(goal)
+}
+
+// recalculateStatus is RecalculateStatus for callers outside GoalService.
+func recalculateStatus(goal *models.Goal) {
+	allStepsDone := true
+	for i := range goal.Steps {
+		stepDone := true
+		for _, sub := range goal.Steps[i].Substeps {
+			if !sub.Done {
+				stepDone = false
+				break
+			}
+		}
+		goal.Steps[i].Completed = stepDone
+		if !stepDone {
+			allStepsDone = false
+		}
+	}
+	goal.Status = nextGoalStatus(goal.Status, len(goal.Steps) > 0, allStepsDone, goal.RequireCompletionConfirmation)
+	stampCompletion(goal, time.Now())
+}
+
+// stampCompletion records when substeps and the goal were completed. Done
+// substeps and completed goals keep an existing time and get now otherwise;
+// anything not done has its completion time cleared.
+func stampCompletion(goal *models.Goal, now time.Time) {
+	for i := range goal.Steps {
+		for j := range goal.Steps[i].Substeps {
+			sub := &goal.Steps[i].Substeps[j]
+			switch {
+			case !sub.Done:
+				sub.CompletedAt = time.Time{}
+			case sub.CompletedAt.IsZero() || sub.CompletedAt.After(now):
+				sub.CompletedAt = now
+			}
+		}
+	}
+
+	switch {
+	case goal.Status != "completed":
+		goal.CompletedAt = time.Time{}
+	case goal.CompletedAt.IsZero() || goal.CompletedAt.After(now):
+		goal.CompletedAt = now
+	}
+}
+
+// nextGoalStatus is the goal status state machine:
+//
+//	closed                                   -> closed (until ReopenGoal)
+//	deleted                                  -> deleted (until RestoreGoal)
+//	no steps, any status                     -> unchanged ("" -> in_progress)
+//	steps not all done                       -> in_progress
+//	all done, no confirmation required       -> completed
+//	all done, confirmation required, current
+//	status completed (already confirmed)     -> completed
+//	all done, confirmation required, other   -> pending_completion
+//
+// Goals without steps have nothing to derive a status from, so their status
+// is whatever was set explicitly.
+func nextGoalStatus(current string, hasSteps, allStepsDone, requireConfirmation bool) string {
+	switch {
+	case current == GoalStatusClosed, current == GoalStatusDeleted:
+		return current
+	case !hasSteps && current == "":
+		return "in_progress"
+	case !hasSteps:
+		return current
+	case !allStepsDone:
+		return "in_progress"
+	case !requireConfirmation:
+		return "completed"
+	case current == "completed":
+		return "completed"
+	default:
+		return GoalStatusPendingCompletion
+	}
+}
+
+// isDone reports whether a status counts as finished for notifications.
+func isDone(status string) bool {
+	return status == "completed" || status == GoalStatusPendingCompletion
+}
+
+// notifyStatusChange tells the owner when a goal was just completed, is
+// waiting for them to confirm its completion, or was reopened because work
+// remains. It also keeps the owner's goal counters and XP in step.
+func (s *GoalService) notifyStatusChange(ctx context.Context, goal *models.Goal, previousStatus string) {
+	if goal.Status == previousStatus {
+		return
+	}
+	s.counters.GoalStatusChanged(ctx, goal.UserID, previousStatus, goal.Status)
+	s.awardXP(ctx, goal.UserID, goalStatusXP(previousStatus, goal.Status))
+
+	var err error
+	switch {
+	case isDone(previousStatus) && goal.Status == "in_progress":
+		err = s.NotificationService.CreateNotification(
+			ctx,
+			goal.UserID,
+			"goal_reopened",
+			"🔄 Goal reopened",
+			fmt.Sprintf("\"%s\" has unfinished steps again and is back in progress.", goal.Name),
+			&goal.ID,
+		)
+	case goal.Status == "completed":
+		err = s.NotificationService.CreateNotification(
+			ctx,
+			goal.UserID,
+			"goal_completed",
+			"🎉 Goal Completed",
+			fmt.Sprintf("You’ve successfully completed your goal: \"%s\"!", goal.Name),
+			&goal.ID,
+		)
+		s.notifyUnblocked(ctx, goal)
+		s.badgeService.CheckAndAwardBadges(ctx, goal.UserID)
+	case goal.Status == GoalStatusPendingCompletion:
+		err = s.NotificationService.CreateNotification(
+			ctx,
+			goal.UserID,
+			"goal_pending_completion",
+			"✅ Confirm goal completion",
+			fmt.Sprintf("Every step of \"%s\" is done. Confirm that the goal is complete or reopen it.", goal.Name),
+			&goal.ID,
+		)
+	default:
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to send %s notification", goal.Status)
+	}
+}
+
+// Goal status of goals the owner gave up on, see CloseGoal.
+const GoalStatusClosed = "closed"
+
+// Goal status of goals in the trash, see DeleteGoal.
+const GoalStatusDeleted = "deleted"
+
+// MaxCloseReasonLen caps the reason given when closing a goal, in characters.
+const MaxCloseReasonLen = 500
+
+// Errors returned when closing and reopening goals.
+var (
+	ErrCannotCloseGoal = errors.New("completed or already closed goals cannot be closed")
+	ErrGoalNotClosed   = errors.New("goal is not closed")
+)
+
+// CloseGoal marks a goal as abandoned with an optional reason. Closed goals
+// keep their steps but are left out of reminders and default listings.
+func (s *GoalService) CloseGoal(ctx context.Context, goalID string, ownerID primitive.ObjectID, reason string) (*models.Goal, error) {
+	goal, err := s.getOwnedGoal(ctx, goalID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Status == "completed" || goal.Status == GoalStatusClosed {
+		return nil, ErrCannotCloseGoal
+	}
+
+	reason, err = NormalizeNote(reason)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.RuneCountInString(reason) > MaxCloseReasonLen {
+		return nil, fmt.Errorf("%w: the reason is limited to %d characters", ErrInvalidNote, MaxCloseReasonLen)
+	}
+
+	now := time.Now()
+	if err := s.repo.CloseGoal(ctx, goal.ID, reason, now); err != nil {
+		return nil, err
+	}
+	s.counters.GoalStatusChanged(ctx, goal.UserID, goal.Status, GoalStatusClosed)
+	goal.Status = GoalStatusClosed
+	goal.ClosedReason = reason
+	goal.ClosedAt = now
+	goal.UpdatedAt = now
+	goal.Version++
+
+	_ = s.ProgressService.RecordSnapshot(ctx, goal)
+	return goal, nil
+}
+
+// ReopenGoal takes a goal out of the closed status and recomputes its status
+// from its steps.
+func (s *GoalService) ReopenGoal(ctx context.Context, goalID string, ownerID primitive.ObjectID) (*models.Goal, error) {
+	goal, err := s.getOwnedGoal(ctx, goalID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Status != GoalStatusClosed {
+		return nil, ErrGoalNotClosed
+	}
+
+	goal.Status = ""
+	s.RecalculateStatus(goal)
+
+	updatedAt, err := s.repo.ReopenGoal(ctx, goal.ID, goal.Status, goal.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	goal.ClosedReason = ""
+	goal.ClosedAt = time.Time{}
+	goal.UpdatedAt = updatedAt
+	goal.Version++
+
+	_ = s.ProgressService.RecordSnapshot(ctx, goal)
+	s.notifyStatusChange(ctx, goal, GoalStatusClosed)
+	return goal, nil
+}
+
+// ErrPinLimitReached is returned when pinning a goal would exceed the
+// configured number of pinned goals per user.
+var ErrPinLimitReached = errors.New("pinned goal limit reached")
+
+// SetPinned pins or unpins one of the owner's goals. Pinned goals are listed
+// first by GetGoals.
+func (s *GoalService) SetPinned(ctx context.Context, goalID string, ownerID primitive.ObjectID, pinned bool) (*models.Goal, error) {
+	goal, err := s.getOwnedGoal(ctx, goalID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Pinned == pinned {
+		return goal, nil
+	}
+
+	if pinned {
+		count, err := s.repo.CountPinnedGoals(ctx, ownerID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= int64(s.maxPinned) {
+			return nil, fmt.Errorf("%w: at most %d goals can be pinned", ErrPinLimitReached, s.maxPinned)
+		}
+	}
+
+	updatedAt, err := s.repo.SetPinned(ctx, goal.ID, pinned)
+	if err != nil {
+		return nil, err
+	}
+	goal.Pinned = pinned
+	goal.UpdatedAt = updatedAt
+	goal.Version++
+	return goal, nil
+}
+
+// awardXP gives the owner XP in the background so progress updates don't
+// wait for it. A failed award is only logged.
+func (s *GoalService) awardXP(ctx context.Context, ownerID primitive.ObjectID, amount int) {
+	if amount == 0 {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.users.AwardXP(ctx, ownerID, amount); err != nil {
+			logger.Log.WithError(err).WithField("user_id", ownerID.Hex()).Warn("Failed to award XP")
+		}
+	}()
+}
+
+func isPinnedBy(goal models.Goal, userID primitive.ObjectID) bool {
+	return goal.Pinned && goal.UserID == userID
+}
+
+// ConfirmCompletion lets the owner settle a goal that is pending completion:
+// confirming completes it, declining reopens it as in progress.
+func (s *GoalService) ConfirmCompletion(ctx context.Context, goalID string, ownerID primitive.ObjectID, confirm bool) (*models.Goal, error) {
+	goal, err := s.getOwnedGoal(ctx, goalID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Status != GoalStatusPendingCompletion {
+		return nil, ErrNotPendingCompletion
+	}
+
+	previousStatus := goal.Status
+	goal.Status = "in_progress"
+	if confirm {
+		goal.Status = "completed"
+	}
+	stampCompletion(goal, time.Now())
+
+	updatedAt, err := s.repo.ReplaceStepsIfUnchanged(ctx, goal.ID, goal.UpdatedAt, goal.Steps, goal.Status, goal.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	goal.UpdatedAt = updatedAt
+	goal.Version++
+
+	_ = s.ProgressService.RecordSnapshot(ctx, goal)
+	s.notifyStatusChange(ctx, goal, previousStatus)
+	return goal, nil
+}
+
+// SharedGoal is the read-only view of a goal served through a share link.
+// It deliberately leaves out owner and collaborator IDs.
+type SharedGoal struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Category    string        `json:"category,omitempty"`
+	Tags        []string      `json:"tags,omitempty"`
+	Status      string        `json:"status"`
+	DueDate     *time.Time    `json:"due_date,omitempty"`
+	Steps       []models.Step `json:"steps"`
+	Progress    float64       `json:"progress"`
+}
+
+// CreateShareToken generates a new share token for the goal. Only the owner may
+// share a goal; generating a new token invalidates the previous link.
+func (s *GoalService) CreateShareToken(ctx context.Context, goalID string, ownerID primitive.ObjectID) (string, error) {
+	goal, err := s.getOwnedGoal(ctx, goalID, ownerID)
+	if err != nil {
+		return "", err
+	}
+
+	token := uuid.NewString()
+	if err := s.repo.SetShareToken(ctx, goal.ID, token); err != nil {
+		return "", fmt.Errorf("failed to create share link: %v", err)
+	}
+
+	logger.Log.WithField("goal_id", goalID).Info("Share link created")
+	return token, nil
+}
+
+// RevokeShareToken removes the goal's share token so existing links stop working.
+func (s *GoalService) RevokeShareToken(ctx context.Context, goalID string, ownerID primitive.ObjectID) error {
+	goal, err := s.getOwnedGoal(ctx, goalID, ownerID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.ClearShareToken(ctx, goal.ID); err != nil {
+		return fmt.Errorf("failed to revoke share link: %v", err)
+	}
+
+	logger.Log.WithField("goal_id", goalID).Info("Share link revoked")
+	return nil
+}
+
+// GetGoalByShareToken returns the read-only view of the goal behind a share token.
+func (s *GoalService) GetGoalByShareToken(ctx context.Context, token string) (*SharedGoal, error) {
+	if token == "" {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	goal, err := s.repo.FindByShareToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := &SharedGoal{
+		Name:        goal.Name,
+		Description: goal.Description,
+		Category:    goal.Category,
+		Tags:        goal.Tags,
+		Status:      goal.Status,
+		Steps:       goal.Steps,
+		Progress:    GoalProgressPercent(goal),
+	}
+	if !goal.DueDate.IsZero() {
+		shared.DueDate = &goal.DueDate
+	}
+	return shared, nil
+}
+
+// getOwnedGoal loads a goal that is not in the trash and checks that userID is its owner.
+func (s *GoalService) getOwnedGoal(ctx context.Context, goalID string, userID primitive.ObjectID) (*models.Goal, error) {
+	objID, err := primitive.ObjectIDFromHex(goalID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid goal ID: %v", err)
+	}
+
+	goal, err := s.repo.GetGoalByID(ctx, objID)
+	if err != nil || !goal.DeletedAt.IsZero() {
+		return nil, fmt.Errorf("goal not found")
+	}
+	if goal.UserID != userID {
+		return nil, fmt.Errorf("only the goal owner can do this")
+	}
+	return goal, nil
 }
@@ -0,0 +1,48 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+)
+
+func TestSharedGoalDueDate(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, svc.Repositories, models.User{})
+	due := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		dueDate time.Time
+		want    string
+	}{
+		{"no due date", time.Time{}, ""},
+		{"due date", due, `"due_date":"2030-01-02T00:00:00Z"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goal := testutil.SeedGoal(t, svc.Repositories, owner.ID, models.Goal{Name: tt.name, DueDate: tt.dueDate})
+			token, err := svc.Goal.CreateShareToken(ctx, goal.ID.Hex(), owner.ID)
+			if err != nil {
+				t.Fatalf("CreateShareToken: %v", err)
+			}
+			shared, err := svc.Goal.GetGoalByShareToken(ctx, token)
+			if err != nil {
+				t.Fatalf("GetGoalByShareToken: %v", err)
+			}
+			data, err := json.Marshal(shared)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if got := string(data); tt.want == "" && strings.Contains(got, "due_date") || !strings.Contains(got, tt.want) {
+				t.Errorf("shared view = %s, want due date %q", got, tt.want)
+			}
+		})
+	}
+}
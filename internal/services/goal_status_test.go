@@ -0,0 +1,106 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+)
+
+func TestNextGoalStatus(t *testing.T) {
+	// Every combination of current status, has steps, all steps done and
+	// confirmation required. allStepsDone is meaningless without steps, so
+	// both values must leave such goals alone.
+	tests := []struct {
+		current             string
+		hasSteps            bool
+		allStepsDone        bool
+		requireConfirmation bool
+		want                string
+	}{
+		{"", false, false, false, "in_progress"},
+		{"", false, false, true, "in_progress"},
+		{"", false, true, false, "in_progress"},
+		{"", false, true, true, "in_progress"},
+		{"", true, false, false, "in_progress"},
+		{"", true, false, true, "in_progress"},
+		{"", true, true, false, "completed"},
+		{"", true, true, true, GoalStatusPendingCompletion},
+		{"in_progress", false, false, false, "in_progress"},
+		{"in_progress", false, false, true, "in_progress"},
+		{"in_progress", false, true, false, "in_progress"},
+		{"in_progress", false, true, true, "in_progress"},
+		{"in_progress", true, false, false, "in_progress"},
+		{"in_progress", true, false, true, "in_progress"},
+		{"in_progress", true, true, false, "completed"},
+		{"in_progress", true, true, true, GoalStatusPendingCompletion},
+		{GoalStatusPendingCompletion, false, false, false, GoalStatusPendingCompletion},
+		{GoalStatusPendingCompletion, false, false, true, GoalStatusPendingCompletion},
+		{GoalStatusPendingCompletion, false, true, false, GoalStatusPendingCompletion},
+		{GoalStatusPendingCompletion, false, true, true, GoalStatusPendingCompletion},
+		{GoalStatusPendingCompletion, true, false, false, "in_progress"},
+		{GoalStatusPendingCompletion, true, false, true, "in_progress"},
+		{GoalStatusPendingCompletion, true, true, false, "completed"},
+		{GoalStatusPendingCompletion, true, true, true, GoalStatusPendingCompletion},
+		{"completed", false, false, false, "completed"},
+		{"completed", false, false, true, "completed"},
+		{"completed", false, true, false, "completed"},
+		{"completed", false, true, true, "completed"},
+		{"completed", true, false, false, "in_progress"},
+		{"completed", true, false, true, "in_progress"},
+		{"completed", true, true, false, "completed"},
+		{"completed", true, true, true, "completed"},
+		{"archived", false, false, false, "archived"},
+		{"archived", false, false, true, "archived"},
+		{"archived", false, true, false, "archived"},
+		{"archived", false, true, true, "archived"},
+		{"archived", true, false, false, "in_progress"},
+		{"archived", true, false, true, "in_progress"},
+		{"archived", true, true, false, "completed"},
+		{"archived", true, true, true, GoalStatusPendingCompletion},
+	}
+
+	for _, tt := range tests {
+		got := nextGoalStatus(tt.current, tt.hasSteps, tt.allStepsDone, tt.requireConfirmation)
+		if got != tt.want {
+			t.Errorf("nextGoalStatus(%q, steps=%v, done=%v, confirm=%v) = %q, want %q",
+				tt.current, tt.hasSteps, tt.allStepsDone, tt.requireConfirmation, got, tt.want)
+		}
+	}
+}
+
+func TestNextGoalStatusKeepsClosedAndDeleted(t *testing.T) {
+	for _, current := range []string{GoalStatusClosed, GoalStatusDeleted} {
+		for _, hasSteps := range []bool{false, true} {
+			for _, allStepsDone := range []bool{false, true} {
+				for _, requireConfirmation := range []bool{false, true} {
+					got := nextGoalStatus(current, hasSteps, allStepsDone, requireConfirmation)
+					if got != current {
+						t.Errorf("nextGoalStatus(%q, steps=%v, done=%v, confirm=%v) = %q, want it unchanged",
+							current, hasSteps, allStepsDone, requireConfirmation, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestRecalculateStatusKeepsClosedGoalClosed(t *testing.T) {
+	goal := &models.Goal{
+		Status: GoalStatusClosed,
+		Steps: []models.Step{
+			{Name: "s1", Substeps: []models.Substep{{Title: "a", Done: true}}},
+		},
+	}
+
+	recalculateStatus(goal)
+
+	if goal.Status != GoalStatusClosed {
+		t.Errorf("status = %q, want closed", goal.Status)
+	}
+	if !goal.CompletedAt.IsZero() {
+		t.Errorf("CompletedAt = %v, want zero for a closed goal", goal.CompletedAt)
+	}
+	if !goal.Steps[0].Completed {
+		t.Error("step with every substep done should still be marked completed")
+	}
+}
@@ -0,0 +1,187 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func substepTitles(step models.Step) []string {
+	titles := make([]string, 0, len(step.Substeps))
+	for _, sub := range step.Substeps {
+		titles = append(titles, sub.Title)
+	}
+	return titles
+}
+
+func assertStep(t *testing.T, step models.Step, wantTitles []string, wantCompleted bool) {
+	t.Helper()
+	got := substepTitles(step)
+	if len(got) != len(wantTitles) {
+		t.Fatalf("step %q substeps = %v, want %v", step.Name, got, wantTitles)
+	}
+	for i := range wantTitles {
+		if got[i] != wantTitles[i] {
+			t.Errorf("step %q substeps = %v, want %v", step.Name, got, wantTitles)
+			break
+		}
+	}
+	if step.Completed != wantCompleted {
+		t.Errorf("step %q completed = %v, want %v", step.Name, step.Completed, wantCompleted)
+	}
+}
+
+// createMoveGoal creates a goal whose first step is half done and whose
+// second step is done.
+func createMoveGoal(t *testing.T, svc *testutil.Services, ownerID primitive.ObjectID) *models.Goal {
+	t.Helper()
+	goal, err := svc.Goal.CreateGoal(context.Background(), &models.Goal{
+		UserID: ownerID,
+		Name:   "move",
+		Steps: []models.Step{
+			{Name: "a", Substeps: []models.Substep{{Title: "a1", Done: true}, {Title: "a2"}}},
+			{Name: "b", Substeps: []models.Substep{{Title: "b1", Done: true}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateGoal: %v", err)
+	}
+	return goal
+}
+
+func TestMoveSubstepRecomputesCompletion(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := steadyUser(t, svc)
+	goal := createMoveGoal(t, svc, owner.ID)
+
+	// Moving the open substep out completes a and reopens b
+	moved, err := svc.Goal.MoveSubstep(ctx, goal.ID.Hex(), owner.ID, services.SubstepMove{FromStep: 0, FromIndex: 1, ToStep: 1, ToIndex: 0})
+	if err != nil {
+		t.Fatalf("MoveSubstep: %v", err)
+	}
+	assertStep(t, moved.Steps[0], []string{"a1"}, true)
+	assertStep(t, moved.Steps[1], []string{"a2", "b1"}, false)
+	if moved.Status != "in_progress" {
+		t.Errorf("status = %q, want in_progress", moved.Status)
+	}
+
+	stored, err := svc.Goals.GetGoalByID(ctx, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	assertStep(t, stored.Steps[0], []string{"a1"}, true)
+	assertStep(t, stored.Steps[1], []string{"a2", "b1"}, false)
+
+	// Within one step the flags don't change, only the position
+	moved, err = svc.Goal.MoveSubstep(ctx, goal.ID.Hex(), owner.ID, services.SubstepMove{FromStep: 1, FromIndex: 0, ToStep: 1, ToIndex: 1})
+	if err != nil {
+		t.Fatalf("MoveSubstep within step: %v", err)
+	}
+	assertStep(t, moved.Steps[1], []string{"b1", "a2"}, false)
+}
+
+func TestMoveSubstepCompletesGoal(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := steadyUser(t, svc)
+	goal, err := svc.Goal.CreateGoal(ctx, &models.Goal{
+		UserID: owner.ID,
+		Name:   "finish",
+		Steps: []models.Step{
+			{Name: "a", Substeps: []models.Substep{{Title: "a1", Done: true}}},
+			{Name: "b", Substeps: []models.Substep{{Title: "b1", Done: true}, {Title: "b2"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateGoal: %v", err)
+	}
+
+	if _, err := svc.Goal.MoveSubstep(ctx, goal.ID.Hex(), owner.ID, services.SubstepMove{FromStep: 1, FromIndex: 1, ToStep: 0, ToIndex: 1}); err != nil {
+		t.Fatalf("MoveSubstep: %v", err)
+	}
+	toggled, err := svc.Goal.GetGoal(ctx, goal.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetGoal: %v", err)
+	}
+	assertStep(t, toggled.Steps[0], []string{"a1", "b2"}, false)
+
+	// Once the moved substep is done, emptying b leaves nothing open
+	toggled.Steps[0].Substeps[1].Done = true
+	if _, err := svc.Goal.UpdateGoal(ctx, goal.ID.Hex(), toggled, owner.ID); err != nil {
+		t.Fatalf("UpdateGoal: %v", err)
+	}
+	moved, err := svc.Goal.MoveSubstep(ctx, goal.ID.Hex(), owner.ID, services.SubstepMove{FromStep: 1, FromIndex: 0, ToStep: 0, ToIndex: 0})
+	if err != nil {
+		t.Fatalf("MoveSubstep: %v", err)
+	}
+	assertStep(t, moved.Steps[0], []string{"b1", "a1", "b2"}, true)
+	assertStep(t, moved.Steps[1], nil, true)
+	if moved.Status != "completed" {
+		t.Errorf("status = %q, want completed", moved.Status)
+	}
+}
+
+func TestMoveSubstepRejectsBadIndexes(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := steadyUser(t, svc)
+	goal := createMoveGoal(t, svc, owner.ID)
+
+	moves := []services.SubstepMove{
+		{FromStep: 2, ToStep: 0},
+		{FromStep: 0, ToStep: -1},
+		{FromStep: 0, FromIndex: 2, ToStep: 1},
+		{FromStep: 0, FromIndex: 0, ToStep: 1, ToIndex: 2},
+	}
+	for _, move := range moves {
+		if _, err := svc.Goal.MoveSubstep(ctx, goal.ID.Hex(), owner.ID, move); err == nil {
+			t.Errorf("MoveSubstep(%+v) succeeded, want an error", move)
+		}
+	}
+
+	stored, err := svc.Goals.GetGoalByID(ctx, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	assertStep(t, stored.Steps[0], []string{"a1", "a2"}, goal.Steps[0].Completed)
+	assertStep(t, stored.Steps[1], []string{"b1"}, goal.Steps[1].Completed)
+	if stored.Version != goal.Version {
+		t.Error("a rejected move was saved")
+	}
+}
+
+func TestReorderSubsteps(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := steadyUser(t, svc)
+	goal := createMoveGoal(t, svc, owner.ID)
+
+	reordered, err := svc.Goal.ReorderSubsteps(ctx, goal.ID.Hex(), owner.ID, 0, []int{1, 0})
+	if err != nil {
+		t.Fatalf("ReorderSubsteps: %v", err)
+	}
+	assertStep(t, reordered.Steps[0], []string{"a2", "a1"}, false)
+	if !reordered.Steps[0].Substeps[1].Done || reordered.Steps[0].Substeps[0].Done {
+		t.Errorf("done flags did not follow their substeps: %+v", reordered.Steps[0].Substeps)
+	}
+
+	for _, order := range [][]int{{0}, {0, 0}, {0, 2}, {1, 0, 2}} {
+		if _, err := svc.Goal.ReorderSubsteps(ctx, goal.ID.Hex(), owner.ID, 0, order); err == nil {
+			t.Errorf("ReorderSubsteps(%v) succeeded, want an error", order)
+		}
+	}
+	if _, err := svc.Goal.ReorderSubsteps(ctx, goal.ID.Hex(), owner.ID, 5, []int{0}); err == nil {
+		t.Error("ReorderSubsteps on a missing step succeeded, want an error")
+	}
+
+	stored, err := svc.Goals.GetGoalByID(ctx, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	assertStep(t, stored.Steps[0], []string{"a2", "a1"}, false)
+}
@@ -0,0 +1,155 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// trashedAgo seeds a goal owned by ownerID that went into the trash age ago.
+func trashedAgo(t *testing.T, svc *testutil.Services, ownerID primitive.ObjectID, age time.Duration) *models.Goal {
+	t.Helper()
+	goal := testutil.SeedGoal(t, svc.Repositories, ownerID, models.Goal{})
+	if _, err := svc.DB.Collection("goals").UpdateOne(context.Background(), bson.M{"_id": goal.ID}, bson.M{"$set": bson.M{"deleted_at": time.Now().Add(-age)}}); err != nil {
+		t.Fatalf("failed to trash goal: %v", err)
+	}
+	return goal
+}
+
+func countWarnings(t *testing.T, svc *testutil.Services, userID primitive.ObjectID) int64 {
+	t.Helper()
+	n, err := svc.DB.Collection("notifications").CountDocuments(context.Background(), bson.M{"user_id": userID, "type": "trash_purge_warning"})
+	if err != nil {
+		t.Fatalf("failed to count notifications: %v", err)
+	}
+	return n
+}
+
+func TestWarnUpcomingTrashPurgeOnce(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	alice := testutil.SeedUser(t, svc.Repositories, models.User{})
+	bob := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	// Within the warning lead of the purge
+	almost := services.GoalTrashRetention - services.TrashPurgeWarningLead + time.Hour
+	trashedAgo(t, svc, alice.ID, almost)
+	trashedAgo(t, svc, alice.ID, almost)
+	trashedAgo(t, svc, bob.ID, time.Hour)
+
+	sent, err := svc.Goal.WarnUpcomingTrashPurge(ctx)
+	if err != nil {
+		t.Fatalf("WarnUpcomingTrashPurge: %v", err)
+	}
+	if sent != 1 || countWarnings(t, svc, alice.ID) != 1 || countWarnings(t, svc, bob.ID) != 0 {
+		t.Fatalf("sent = %d, want a single warning for alice and none for bob", sent)
+	}
+	notif, err := svc.Notifications.GetLatestNotificationByType(ctx, alice.ID, "trash_purge_warning")
+	if err != nil {
+		t.Fatalf("GetLatestNotificationByType: %v", err)
+	}
+	if want := "2 goal(s) in your trash"; !strings.HasPrefix(notif.Message, want) {
+		t.Errorf("message = %q, want it to start with %q", notif.Message, want)
+	}
+
+	// The next daily run only warns about goals that became due since
+	if sent, err := svc.Goal.WarnUpcomingTrashPurge(ctx); err != nil || sent != 0 {
+		t.Fatalf("second run = %d, %v, want nothing sent", sent, err)
+	}
+	trashedAgo(t, svc, alice.ID, almost)
+	if sent, err := svc.Goal.WarnUpcomingTrashPurge(ctx); err != nil || sent != 1 {
+		t.Fatalf("third run = %d, %v, want one warning for the new goal", sent, err)
+	}
+	notif, err = svc.Notifications.GetLatestNotificationByType(ctx, alice.ID, "trash_purge_warning")
+	if err != nil {
+		t.Fatalf("GetLatestNotificationByType: %v", err)
+	}
+	if want := "1 goal(s) in your trash"; !strings.HasPrefix(notif.Message, want) {
+		t.Errorf("message = %q, want it to start with %q", notif.Message, want)
+	}
+	if n := countWarnings(t, svc, alice.ID); n != 2 {
+		t.Errorf("alice got %d warnings, want 2", n)
+	}
+}
+
+func TestPurgeExpiredTrashAfterWarning(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	expired := trashedAgo(t, svc, owner.ID, services.GoalTrashRetention+time.Hour)
+	kept := trashedAgo(t, svc, owner.ID, services.GoalTrashRetention-time.Hour)
+	if _, err := svc.Goal.WarnUpcomingTrashPurge(ctx); err != nil {
+		t.Fatalf("WarnUpcomingTrashPurge: %v", err)
+	}
+
+	purged, err := svc.Goal.PurgeExpiredTrash(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpiredTrash: %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("purged = %d, want 1", purged)
+	}
+	if _, err := svc.Goals.GetGoalByID(ctx, expired.ID); err == nil {
+		t.Error("expired goal still stored")
+	}
+	if _, err := svc.Goals.GetGoalByID(ctx, kept.ID); err != nil {
+		t.Errorf("goal inside the retention was purged: %v", err)
+	}
+}
+
+func TestTrashedGoalCannotBeChanged(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	owner := testutil.SeedUser(t, svc.Repositories, models.User{})
+	goal := testutil.SeedGoal(t, svc.Repositories, owner.ID, models.Goal{
+		Status: "in_progress",
+		Steps:  []models.Step{{Name: "only", Completed: true}},
+	})
+	token, err := svc.Goal.CreateShareToken(ctx, goal.ID.Hex(), owner.ID)
+	if err != nil {
+		t.Fatalf("CreateShareToken: %v", err)
+	}
+	if err := svc.Goal.DeleteGoal(ctx, goal.ID.Hex()); err != nil {
+		t.Fatalf("DeleteGoal: %v", err)
+	}
+
+	if _, err := svc.Goal.GetActiveGoal(ctx, goal.ID.Hex()); err == nil {
+		t.Error("GetActiveGoal found a trashed goal")
+	}
+	if _, err := svc.Goal.UpdateGoal(ctx, goal.ID.Hex(), &models.Goal{Name: "revived", Steps: goal.Steps}, owner.ID); err == nil {
+		t.Error("UpdateGoal changed a trashed goal")
+	}
+	if _, err := svc.Goal.SetPinned(ctx, goal.ID.Hex(), owner.ID, true); err == nil {
+		t.Error("SetPinned changed a trashed goal")
+	}
+	if _, err := svc.Goal.CloseGoal(ctx, goal.ID.Hex(), owner.ID, ""); err == nil {
+		t.Error("CloseGoal changed a trashed goal")
+	}
+	if _, err := svc.Goal.GetGoalByShareToken(ctx, token); err == nil {
+		t.Error("share link still serves a trashed goal")
+	}
+
+	stored, err := svc.Goals.GetGoalByID(ctx, goal.ID)
+	if err != nil {
+		t.Fatalf("GetGoalByID: %v", err)
+	}
+	if stored.Status != services.GoalStatusDeleted || stored.DeletedAt.IsZero() || stored.Name != goal.Name {
+		t.Errorf("trashed goal = %q %q deleted at %v, want it untouched", stored.Name, stored.Status, stored.DeletedAt)
+	}
+
+	// Restoring brings the link back
+	if err := svc.Goal.RestoreGoal(ctx, stored); err != nil {
+		t.Fatalf("RestoreGoal: %v", err)
+	}
+	if _, err := svc.Goal.GetGoalByShareToken(ctx, token); err != nil {
+		t.Errorf("share link after restore: %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+)
+
+// ErrInvalidVisibility is returned when a goal's visibility is not one of the
+// models.GoalVisibility* values.
+var ErrInvalidVisibility = errors.New("visibility must be public or private")
+
+// validateVisibility checks a goal's visibility. Empty means public.
+func validateVisibility(v string) error {
+	switch v {
+	case "", models.GoalVisibilityPublic, models.GoalVisibilityPrivate:
+		return nil
+	}
+	return ErrInvalidVisibility
+}
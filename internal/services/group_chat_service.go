@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Limits on group chats.
+const (
+	MaxGroupChatNameLength   = 100
+	MaxGroupChatMembers      = 50
+	MaxGroupMessageLength    = 4000
+	DefaultGroupHistoryLimit = 50
+	MaxGroupHistoryLimit     = 100
+)
+
+// Errors returned by GroupChatService.
+var (
+	ErrGroupChatNotFound    = errors.New("group chat not found")
+	ErrInvalidGroupChat     = errors.New("invalid group chat")
+	ErrGroupMemberNotFriend = errors.New("group members must be friends of the user adding them")
+	ErrGroupChatFull        = errors.New("group chat is full")
+	ErrGroupChatForbidden   = errors.New("only the group creator can remove other members")
+	ErrGroupMemberNotFound  = errors.New("user is not a member of this group chat")
+)
+
+// GroupMessagePage is one page of a group chat's history, newest first.
+// NextCursor is empty on the last page.
+type GroupMessagePage struct {
+	Messages   []models.GroupMessage `json:"messages"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// GroupChatService manages group chats and relays their messages to online
+// members.
+type GroupChatService struct {
+	chatRepo *repository.ChatRepository
+	userRepo *repository.UserRepository
+	hub      *hub.Hub // may be nil
+}
+
+func NewGroupChatService(chatRepo *repository.ChatRepository, userRepo *repository.UserRepository, wsHub *hub.Hub) *GroupChatService {
+	return &GroupChatService{
+		chatRepo: chatRepo,
+		userRepo: userRepo,
+		hub:      wsHub,
+	}
+}
+
+// CreateGroupChat starts a group chat owned by creatorID with the given
+// friends as the other members.
+func (s *GroupChatService) CreateGroupChat(ctx context.Context, creatorID primitive.ObjectID, name string, memberIDs []primitive.ObjectID) (*models.GroupChat, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidGroupChat)
+	}
+	if utf8.RuneCountInString(name) > MaxGroupChatNameLength {
+		return nil, fmt.Errorf("%w: name must be at most %d characters", ErrInvalidGroupChat, MaxGroupChatNameLength)
+	}
+
+	members := []primitive.ObjectID{creatorID}
+	added, err := s.checkNewMembers(ctx, creatorID, members, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.chatRepo.CreateGroupChat(ctx, &models.GroupChat{
+		Name:      name,
+		CreatorID: creatorID,
+		Members:   append(members, added...),
+	})
+}
+
+// GetGroupChats returns the group chats the user belongs to.
+func (s *GroupChatService) GetGroupChats(ctx context.Context, userID primitive.ObjectID) ([]models.GroupChat, error) {
+	return s.chatRepo.GetGroupChatsByMember(ctx, userID)
+}
+
+// AddMembers adds friends of callerID to a group chat callerID belongs to.
+func (s *GroupChatService) AddMembers(ctx context.Context, groupID, callerID primitive.ObjectID, userIDs []primitive.ObjectID) (*models.GroupChat, error) {
+	group, err := s.getGroupForMember(ctx, groupID, callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.checkNewMembers(ctx, callerID, group.Members, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) == 0 {
+		return group, nil
+	}
+	return s.chatRepo.AddGroupMembers(ctx, groupID, added)
+}
+
+// RemoveMember removes userID from a group chat. Members may leave on their
+// own; only the creator may remove someone else.
+func (s *GroupChatService) RemoveMember(ctx context.Context, groupID, callerID, userID primitive.ObjectID) error {
+	group, err := s.getGroupForMember(ctx, groupID, callerID)
+	if err != nil {
+		return err
+	}
+	if userID != callerID && group.CreatorID != callerID {
+		return ErrGroupChatForbidden
+	}
+
+	removed, err := s.chatRepo.RemoveGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrGroupMemberNotFound
+	}
+	return nil
+}
+
+// RecipientIDs returns the hex IDs of the group chat members who may hear
+// from senderID, i.e. all but those who blocked them, if senderID is a
+// member. The WebSocket handler uses it to scope typing relays.
+func (s *GroupChatService) RecipientIDs(ctx context.Context, groupID, senderID primitive.ObjectID) ([]string, error) {
+	group, err := s.getGroupForMember(ctx, groupID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	blockers, err := s.userRepo.GetUsersBlocking(ctx, senderID, group.Members)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(group.Members))
+	for _, memberID := range group.Members {
+		if !containsID(blockers, memberID) {
+			ids = append(ids, memberID.Hex())
+		}
+	}
+	return ids, nil
+}
+
+// GetHistory returns one page of a group chat's messages, newest first.
+// cursor is the NextCursor of the previous page, or empty for the first page.
+func (s *GroupChatService) GetHistory(ctx context.Context, groupID, userID primitive.ObjectID, cursor string, limit int64) (*GroupMessagePage, error) {
+	if _, err := s.getGroupForMember(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultGroupHistoryLimit
+	}
+	if limit > MaxGroupHistoryLimit {
+		limit = MaxGroupHistoryLimit
+	}
+
+	var before primitive.ObjectID
+	if cursor != "" {
+		var err error
+		before, err = primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid cursor", ErrInvalidGroupChat)
+		}
+	}
+
+	// Fetch one extra message to know whether another page follows
+	messages, err := s.chatRepo.GetGroupMessages(ctx, groupID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &GroupMessagePage{Messages: messages}
+	if int64(len(messages)) > limit {
+		page.Messages = messages[:limit]
+		page.NextCursor = page.Messages[limit-1].ID.Hex()
+	}
+	return page, nil
+}
+
+// SendText stores a text message from a member and relays it as a group_text
+// frame to every other member who is online and has not blocked the sender.
+// The WebSocket handler calls it for incoming group_text frames.
+func (s *GroupChatService) SendText(ctx context.Context, groupID, senderID primitive.ObjectID, text string) (*models.GroupMessage, error) {
+	group, err := s.getGroupForMember(ctx, groupID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: message text is required", ErrInvalidGroupChat)
+	}
+	if utf8.RuneCountInString(text) > MaxGroupMessageLength {
+		return nil, fmt.Errorf("%w: message must be at most %d characters", ErrInvalidGroupChat, MaxGroupMessageLength)
+	}
+
+	blockers, err := s.userRepo.GetUsersBlocking(ctx, senderID, group.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.chatRepo.CreateGroupMessage(ctx, &models.GroupMessage{
+		GroupChatID: groupID,
+		SenderID:    senderID,
+		Type:        models.GroupMessageText,
+		Text:        text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.relay(group, message, blockers)
+	return message, nil
+}
+
+// relay sends a stored message to the group's other online members, except
+// those in skip.
+func (s *GroupChatService) relay(group *models.GroupChat, message *models.GroupMessage, skip []primitive.ObjectID) {
+	if s.hub == nil {
+		return
+	}
+	frame := wsproto.GroupText{
+		GroupID:   group.ID.Hex(),
+		MessageID: message.ID.Hex(),
+		SenderID:  message.SenderID.Hex(),
+		Text:      message.Text,
+		SentAt:    &message.CreatedAt,
+	}
+	for _, memberID := range group.Members {
+		if memberID == message.SenderID || containsID(skip, memberID) {
+			continue
+		}
+		err := s.hub.Send(memberID.Hex(), frame)
+		if err != nil && !errors.Is(err, hub.ErrOffline) {
+			logrus.WithError(err).WithField("user_id", memberID.Hex()).Debug("Failed to relay group message")
+		}
+	}
+}
+
+// getGroupForMember returns the group chat if userID belongs to it. Groups
+// the user is not in are reported as not found.
+func (s *GroupChatService) getGroupForMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupChat, error) {
+	group, err := s.chatRepo.GetGroupChatByID(ctx, groupID)
+	if err != nil || !containsID(group.Members, userID) {
+		return nil, ErrGroupChatNotFound
+	}
+	return group, nil
+}
+
+// checkNewMembers returns the users in candidates that are not already in
+// members, after checking that each is a friend of addedBy and that the
+// group stays within MaxGroupChatMembers.
+func (s *GroupChatService) checkNewMembers(ctx context.Context, addedBy primitive.ObjectID, members, candidates []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	friendIDs, err := s.userRepo.GetFriendIDs(ctx, addedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []primitive.ObjectID
+	for _, id := range candidates {
+		if containsID(members, id) || containsID(added, id) {
+			continue
+		}
+		if !containsID(friendIDs, id) {
+			return nil, ErrGroupMemberNotFriend
+		}
+		added = append(added, id)
+	}
+	if len(members)+len(added) > MaxGroupChatMembers {
+		return nil, fmt.Errorf("%w: at most %d members are allowed", ErrGroupChatFull, MaxGroupChatMembers)
+	}
+	return added, nil
+}
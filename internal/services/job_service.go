@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// JobFunc is a scheduled job. It returns how many items it processed.
+type JobFunc func(ctx context.Context) (int, error)
+
+// jobCounters are the totals for one job since the process started.
+type jobCounters struct {
+	runs     int64
+	failures int64
+	items    int64
+}
+
+// JobService records every scheduled job run in the job_runs collection and
+// reports on them for the admin status page and /metrics.
+type JobService struct {
+	repo *repository.JobRunRepository
+
+	mu        sync.Mutex
+	intervals map[string]time.Duration
+	started   time.Time
+	counters  map[string]*jobCounters
+}
+
+// NewJobService creates a new JobService.
+func NewJobService(repo *repository.JobRunRepository) *JobService {
+	return &JobService{
+		repo:      repo,
+		intervals: make(map[string]time.Duration),
+		started:   time.Now(),
+		counters:  make(map[string]*jobCounters),
+	}
+}
+
+// Register declares a job and how often it is expected to run.
+func (s *JobService) Register(name string, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.intervals[name] = interval
+	if s.counters[name] == nil {
+		s.counters[name] = &jobCounters{}
+	}
+}
+
+// Run executes fn as the named job, storing when it started and finished,
+// how many items it processed and whether it failed. Failures are logged
+// here, so jobs only need to return them.
+func (s *JobService) Run(ctx context.Context, name string, fn JobFunc) error {
+	run, err := s.repo.StartRun(ctx, name)
+	if err != nil {
+		logger.Log.WithError(err).WithField("job", name).Warn("Failed to record job start")
+		run = &models.JobRun{Name: name, StartedAt: time.Now()}
+	}
+
+	items, jobErr := fn(ctx)
+
+	run.FinishedAt = time.Now()
+	run.ItemsProcessed = items
+	run.Success = jobErr == nil
+	if jobErr != nil {
+		run.Errors = 1
+		run.Error = jobErr.Error()
+	}
+	if !run.ID.IsZero() {
+		if err := s.repo.FinishRun(ctx, run); err != nil {
+			logger.Log.WithError(err).WithField("job", name).Warn("Failed to record job result")
+		}
+	}
+
+	s.mu.Lock()
+	c := s.counters[name]
+	if c == nil {
+		c = &jobCounters{}
+		s.counters[name] = c
+	}
+	c.runs++
+	c.items += int64(items)
+	if jobErr != nil {
+		c.failures++
+	}
+	s.mu.Unlock()
+
+	entry := logger.Log.WithFields(map[string]interface{}{
+		"job":         name,
+		"items":       items,
+		"duration_ms": run.FinishedAt.Sub(run.StartedAt).Milliseconds(),
+	})
+	if jobErr != nil {
+		entry.WithError(jobErr).Error("Scheduled job failed")
+	} else {
+		entry.Info("Scheduled job finished")
+	}
+	return jobErr
+}
+
+// GetStatus returns the latest run of every registered job. A job is stale
+// when it has not succeeded within twice its interval.
+func (s *JobService) GetStatus(ctx context.Context) ([]models.JobStatus, error) {
+	latest, err := s.repo.GetLatestRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	successes, err := s.repo.GetLastSuccesses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	intervals := make(map[string]time.Duration, len(s.intervals))
+	for name, interval := range s.intervals {
+		intervals[name] = interval
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]models.JobStatus, 0, len(intervals))
+	for name, interval := range intervals {
+		status := models.JobStatus{
+			Name:          name,
+			Interval:      interval.String(),
+			LastSuccessAt: successes[name],
+		}
+		if run, ok := latest[name]; ok {
+			status.LastRun = &run
+		}
+
+		// A job that never succeeded is only stale once it had time to run
+		since := status.LastSuccessAt
+		if since.IsZero() && status.LastRun == nil {
+			since = s.started
+		}
+		status.Stale = now.Sub(since) > 2*interval
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// Job metric descriptors, all labelled by job name.
+var (
+	jobRunsDesc         = prometheus.NewDesc("job_runs_total", "Scheduled job runs since the process started.", []string{"job"}, nil)
+	jobFailuresDesc     = prometheus.NewDesc("job_failures_total", "Failed scheduled job runs since the process started.", []string{"job"}, nil)
+	jobItemsDesc        = prometheus.NewDesc("job_items_processed_total", "Items processed by scheduled jobs since the process started.", []string{"job"}, nil)
+	jobLastRunDesc      = prometheus.NewDesc("job_last_run_timestamp_seconds", "When the job last started.", []string{"job"}, nil)
+	jobLastSuccessDesc  = prometheus.NewDesc("job_last_success_timestamp_seconds", "When the job last finished successfully.", []string{"job"}, nil)
+	jobLastDurationDesc = prometheus.NewDesc("job_last_duration_seconds", "How long the last finished run took.", []string{"job"}, nil)
+	jobLastItemsDesc    = prometheus.NewDesc("job_last_items_processed", "Items processed by the last run.", []string{"job"}, nil)
+	jobStaleDesc        = prometheus.NewDesc("job_stale", "1 if the job has not succeeded within twice its interval.", []string{"job"}, nil)
+)
+
+// jobMetricsTimeout bounds the job_runs lookups of a metrics scrape.
+const jobMetricsTimeout = 5 * time.Second
+
+// Describe implements prometheus.Collector.
+func (s *JobService) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{jobRunsDesc, jobFailuresDesc, jobItemsDesc, jobLastRunDesc,
+		jobLastSuccessDesc, jobLastDurationDesc, jobLastItemsDesc, jobStaleDesc} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector. The *_total counters cover this
+// process; the gauges come from job_runs and survive restarts. When job_runs
+// cannot be read only the counters are reported.
+func (s *JobService) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	counters := make(map[string]jobCounters, len(s.counters))
+	for name, c := range s.counters {
+		counters[name] = *c
+	}
+	s.mu.Unlock()
+
+	for name, c := range counters {
+		ch <- prometheus.MustNewConstMetric(jobRunsDesc, prometheus.CounterValue, float64(c.runs), name)
+		ch <- prometheus.MustNewConstMetric(jobFailuresDesc, prometheus.CounterValue, float64(c.failures), name)
+		ch <- prometheus.MustNewConstMetric(jobItemsDesc, prometheus.CounterValue, float64(c.items), name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobMetricsTimeout)
+	defer cancel()
+	statuses, err := s.GetStatus(ctx)
+	if err != nil {
+		logger.Log.WithError(err).Warn("Failed to load job status for metrics")
+		return
+	}
+	for _, st := range statuses {
+		if st.LastRun != nil {
+			ch <- prometheus.MustNewConstMetric(jobLastRunDesc, prometheus.GaugeValue, float64(st.LastRun.StartedAt.Unix()), st.Name)
+			ch <- prometheus.MustNewConstMetric(jobLastItemsDesc, prometheus.GaugeValue, float64(st.LastRun.ItemsProcessed), st.Name)
+			if !st.LastRun.FinishedAt.IsZero() {
+				ch <- prometheus.MustNewConstMetric(jobLastDurationDesc, prometheus.GaugeValue, st.LastRun.FinishedAt.Sub(st.LastRun.StartedAt).Seconds(), st.Name)
+			}
+		}
+		if !st.LastSuccessAt.IsZero() {
+			ch <- prometheus.MustNewConstMetric(jobLastSuccessDesc, prometheus.GaugeValue, float64(st.LastSuccessAt.Unix()), st.Name)
+		}
+		stale := 0.0
+		if st.Stale {
+			stale = 1
+		}
+		ch <- prometheus.MustNewConstMetric(jobStaleDesc, prometheus.GaugeValue, stale, st.Name)
+	}
+}
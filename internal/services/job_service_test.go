@@ -0,0 +1,41 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestJobServiceCollector(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	ctx := context.Background()
+	jobs := services.NewJobService(repository.NewJobRunRepository(repos.DB))
+	jobs.Register("sweep", time.Hour)
+
+	_ = jobs.Run(ctx, "sweep", func(ctx context.Context) (int, error) { return 3, nil })
+	_ = jobs.Run(ctx, "sweep", func(ctx context.Context) (int, error) { return 1, errors.New("boom") })
+
+	expected := `
+# HELP job_failures_total Failed scheduled job runs since the process started.
+# TYPE job_failures_total counter
+job_failures_total{job="sweep"} 1
+# HELP job_items_processed_total Items processed by scheduled jobs since the process started.
+# TYPE job_items_processed_total counter
+job_items_processed_total{job="sweep"} 4
+# HELP job_runs_total Scheduled job runs since the process started.
+# TYPE job_runs_total counter
+job_runs_total{job="sweep"} 2
+`
+	err := promtestutil.CollectAndCompare(jobs, strings.NewReader(expected),
+		"job_runs_total", "job_failures_total", "job_items_processed_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// signingKeySize is the length of generated HMAC secrets in bytes.
+const signingKeySize = 32
+
+// KeyRotation describes the outcome of a signing key rotation.
+type KeyRotation struct {
+	KeyID              string    `json:"kid"`
+	PreviousKeyID      string    `json:"previous_kid"`
+	PreviousValidUntil time.Time `json:"previous_valid_until"`
+}
+
+// KeyRotationService rotates the JWT signing key and persists rotated keys,
+// encrypted, so they survive restarts.
+type KeyRotationService struct {
+	repo      *repository.SecretRepository
+	keys      *jwtutil.KeyRing
+	envSecret []byte
+	aead      cipher.AEAD
+	grace     time.Duration
+}
+
+// NewKeyRotationService creates a new KeyRotationService. encryptionKey is
+// hashed to derive the AES-256 key that protects stored secrets.
+func NewKeyRotationService(repo *repository.SecretRepository, keys *jwtutil.KeyRing, envSecret, encryptionKey string, grace time.Duration) (*KeyRotationService, error) {
+	derived := sha256.Sum256([]byte(encryptionKey))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	return &KeyRotationService{
+		repo:      repo,
+		keys:      keys,
+		envSecret: []byte(envSecret),
+		aead:      aead,
+		grace:     grace,
+	}, nil
+}
+
+// LoadKeys restores the active signing key and the keys still in their grace
+// period from the secrets collection.
+func (s *KeyRotationService) LoadKeys(ctx context.Context) error {
+	stored, err := s.repo.GetLiveSigningKeys(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var previous []jwtutil.SigningKey
+	activeLoaded := false
+	for _, record := range stored {
+		key, err := s.decrypt(record)
+		if err != nil {
+			return err
+		}
+		if record.Active && !activeLoaded {
+			// The env key only stays valid if a retirement record says so
+			s.keys.Rotate(key, time.Time{})
+			activeLoaded = true
+			continue
+		}
+		if !record.Active {
+			key.RetiresAt = record.RetiresAt
+			previous = append(previous, key)
+		}
+	}
+	for _, key := range previous {
+		s.keys.AddPrevious(key)
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"active_kid": s.keys.ActiveKeyID(),
+		"previous":   len(previous),
+	}).Info("JWT signing keys loaded")
+	return nil
+}
+
+// RotateJWTKey generates a new signing key, stores it and makes it active.
+// The previous key keeps validating tokens for the configured grace period.
+func (s *KeyRotationService) RotateJWTKey(ctx context.Context, adminID primitive.ObjectID) (*KeyRotation, error) {
+	secret := make([]byte, signingKeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %v", err)
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	now := time.Now()
+	retiresAt := now.Add(s.grace)
+	previousKeyID := s.keys.ActiveKeyID()
+	newKey := jwtutil.SigningKey{ID: uuid.NewString(), Secret: secret}
+
+	if err := s.repo.RetireActiveKeys(ctx, retiresAt); err != nil {
+		return nil, err
+	}
+	if previousKeyID == jwtutil.EnvKeyID {
+		retired := &models.SigningKey{KeyID: jwtutil.EnvKeyID, RetiresAt: retiresAt, RotatedBy: adminID}
+		if err := s.repo.CreateSigningKey(ctx, retired); err != nil {
+			return nil, err
+		}
+	}
+
+	record := &models.SigningKey{
+		KeyID:      newKey.ID,
+		Ciphertext: s.aead.Seal(nil, nonce, secret, []byte(newKey.ID)),
+		Nonce:      nonce,
+		Active:     true,
+		RotatedBy:  adminID,
+	}
+	if err := s.repo.CreateSigningKey(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.keys.Rotate(newKey, retiresAt)
+
+	logger.Log.WithFields(map[string]interface{}{
+		"admin_id":     adminID.Hex(),
+		"kid":          newKey.ID,
+		"previous_kid": previousKeyID,
+		"retires_at":   retiresAt,
+	}).Warn("AUDIT: JWT signing key rotated")
+
+	return &KeyRotation{
+		KeyID:              newKey.ID,
+		PreviousKeyID:      previousKeyID,
+		PreviousValidUntil: retiresAt,
+	}, nil
+}
+
+// decrypt turns a stored record back into a signing key. The env record has
+// no ciphertext and maps to the configured secret.
+func (s *KeyRotationService) decrypt(record models.SigningKey) (jwtutil.SigningKey, error) {
+	if record.KeyID == jwtutil.EnvKeyID {
+		return jwtutil.SigningKey{ID: jwtutil.EnvKeyID, Secret: s.envSecret}, nil
+	}
+	secret, err := s.aead.Open(nil, record.Nonce, record.Ciphertext, []byte(record.KeyID))
+	if err != nil {
+		return jwtutil.SigningKey{}, fmt.Errorf("failed to decrypt signing key %s: %v", record.KeyID, err)
+	}
+	return jwtutil.SigningKey{ID: record.KeyID, Secret: secret}, nil
+}
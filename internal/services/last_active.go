@@ -0,0 +1,71 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// lastActiveTracker throttles last_active_at writes. A user's timestamp is
+// written straight away at most once per interval; activity in between is
+// kept in memory and written by the periodic batched flush.
+type lastActiveTracker struct {
+	mu       sync.Mutex
+	interval time.Duration
+	written  map[primitive.ObjectID]time.Time // last persisted timestamp per user
+	pending  map[primitive.ObjectID]time.Time // newer activity not yet persisted
+}
+
+func newLastActiveTracker(interval time.Duration) *lastActiveTracker {
+	return &lastActiveTracker{
+		interval: interval,
+		written:  make(map[primitive.ObjectID]time.Time),
+		pending:  make(map[primitive.ObjectID]time.Time),
+	}
+}
+
+// touch records activity at now and reports whether it should be written immediately.
+func (t *lastActiveTracker) touch(id primitive.ObjectID, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.written[id]; ok && now.Sub(last) < t.interval {
+		t.pending[id] = now
+		return false
+	}
+	t.written[id] = now
+	delete(t.pending, id)
+	return true
+}
+
+// requeue puts back activity whose write failed so the next flush retries it.
+func (t *lastActiveTracker) requeue(batch map[primitive.ObjectID]time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, ts := range batch {
+		if ts.After(t.pending[id]) {
+			t.pending[id] = ts
+		}
+	}
+}
+
+// drain hands out all pending activity and forgets users that have been idle
+// for longer than the interval.
+func (t *lastActiveTracker) drain(now time.Time) map[primitive.ObjectID]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	batch := t.pending
+	t.pending = make(map[primitive.ObjectID]time.Time)
+	for id, ts := range batch {
+		t.written[id] = ts
+	}
+	for id, ts := range t.written {
+		if now.Sub(ts) >= t.interval {
+			delete(t.written, id)
+		}
+	}
+	return batch
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLastActiveTrackerSuppressesWritesWithinInterval(t *testing.T) {
+	tracker := newLastActiveTracker(5 * time.Minute)
+	user := primitive.NewObjectID()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if !tracker.touch(user, start) {
+		t.Fatal("first activity should be written immediately")
+	}
+	for _, offset := range []time.Duration{time.Second, time.Minute, 5*time.Minute - time.Nanosecond} {
+		if tracker.touch(user, start.Add(offset)) {
+			t.Errorf("activity %v after a write was not suppressed", offset)
+		}
+	}
+	if got := tracker.pending[user]; !got.Equal(start.Add(5*time.Minute - time.Nanosecond)) {
+		t.Errorf("pending = %v, want the latest suppressed activity", got)
+	}
+
+	// Once the window has passed the next activity is written again and
+	// supersedes what was pending
+	if !tracker.touch(user, start.Add(5*time.Minute)) {
+		t.Error("activity at the end of the window should be written")
+	}
+	if _, ok := tracker.pending[user]; ok {
+		t.Error("pending activity kept after an immediate write")
+	}
+
+	// Other users have their own window
+	if !tracker.touch(primitive.NewObjectID(), start.Add(time.Second)) {
+		t.Error("another user's first activity was suppressed")
+	}
+}
+
+func TestLastActiveTrackerDrain(t *testing.T) {
+	tracker := newLastActiveTracker(5 * time.Minute)
+	active := primitive.NewObjectID()
+	idle := primitive.NewObjectID()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker.touch(active, start)
+	tracker.touch(idle, start)
+	tracker.touch(active, start.Add(time.Minute))
+
+	batch := tracker.drain(start.Add(2 * time.Minute))
+	if len(batch) != 1 || !batch[active].Equal(start.Add(time.Minute)) {
+		t.Fatalf("batch = %v, want only the suppressed activity", batch)
+	}
+	if len(tracker.pending) != 0 {
+		t.Errorf("pending = %v after drain, want empty", tracker.pending)
+	}
+	// The flushed timestamp starts a new window
+	if tracker.touch(active, start.Add(5*time.Minute+30*time.Second)) {
+		t.Error("activity within the window of the flushed timestamp was written")
+	}
+
+	// Users idle for a whole interval are forgotten, so their next activity
+	// is written immediately
+	tracker.drain(start.Add(6 * time.Minute))
+	if _, ok := tracker.written[idle]; ok {
+		t.Error("idle user still tracked after drain")
+	}
+	if !tracker.touch(idle, start.Add(6*time.Minute)) {
+		t.Error("idle user's next activity was suppressed")
+	}
+}
+
+func TestLastActiveTrackerRequeueKeepsNewest(t *testing.T) {
+	tracker := newLastActiveTracker(time.Minute)
+	user := primitive.NewObjectID()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker.touch(user, start)
+	tracker.touch(user, start.Add(30*time.Second))
+	failed := tracker.drain(start.Add(31 * time.Second))
+
+	// New activity arrived while the failed flush was in flight
+	tracker.touch(user, start.Add(40*time.Second))
+	tracker.requeue(failed)
+	if got := tracker.pending[user]; !got.Equal(start.Add(40 * time.Second)) {
+		t.Errorf("pending = %v, want the newer activity to win over the requeued one", got)
+	}
+
+	tracker.drain(start.Add(41 * time.Second))
+	tracker.requeue(failed)
+	if got := tracker.pending[user]; !got.Equal(start.Add(30 * time.Second)) {
+		t.Errorf("pending = %v, want the requeued activity", got)
+	}
+}
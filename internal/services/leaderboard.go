@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Leaderboard sort orders.
+const (
+	LeaderboardByXP             = "xp"
+	LeaderboardByCompletedGoals = "completed_goals"
+)
+
+// LeaderboardEntry is one user's row on the friends leaderboard.
+type LeaderboardEntry struct {
+	Rank           int                `json:"rank"`
+	UserID         primitive.ObjectID `json:"user_id"`
+	Username       string             `json:"username"`
+	XP             int                `json:"xp"`
+	CompletedGoals int64              `json:"completed_goals"`
+	Level          int                `json:"level"`
+}
+
+// GetLeaderboard ranks the user and their friends by XP or by completed
+// goals, highest first. Users with equal scores share a rank. Only friends
+// are included, never other users.
+func (s *FriendService) GetLeaderboard(ctx context.Context, userID primitive.ObjectID, sortBy string) ([]LeaderboardEntry, error) {
+	if sortBy == "" {
+		sortBy = LeaderboardByXP
+	}
+	if sortBy != LeaderboardByXP && sortBy != LeaderboardByCompletedGoals {
+		return nil, fmt.Errorf("invalid sort: %s", sortBy)
+	}
+
+	friendIDs, err := s.userRepo.GetFriendIDs(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get friend IDs: %v", err)
+	}
+	ids := append([]primitive.ObjectID{userID}, friendIDs...)
+
+	users, err := s.userRepo.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %v", err)
+	}
+	counters, err := s.counters.GetCountersFor(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(users))
+	for _, user := range users {
+		entries = append(entries, leaderboardEntry(user, counters[user.ID]))
+	}
+
+	score := func(e LeaderboardEntry) int64 {
+		if sortBy == LeaderboardByCompletedGoals {
+			return e.CompletedGoals
+		}
+		return int64(e.XP)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if score(entries[i]) != score(entries[j]) {
+			return score(entries[i]) > score(entries[j])
+		}
+		return entries[i].Username < entries[j].Username
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && score(entries[i]) == score(entries[i-1]) {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+	return entries, nil
+}
+
+func leaderboardEntry(user models.User, counters models.UserCounters) LeaderboardEntry {
+	return LeaderboardEntry{
+		UserID:         user.ID,
+		Username:       user.Username,
+		XP:             user.XP,
+		CompletedGoals: counters.CompletedGoals,
+		Level:          LevelForXP(user.XP),
+	}
+}
@@ -0,0 +1,102 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// frameRecorder is a hub connection that keeps the frames sent to it.
+type frameRecorder struct {
+	mu     sync.Mutex
+	frames []wsproto.Frame
+}
+
+func (c *frameRecorder) Send(data []byte) error {
+	_, frame, err := wsproto.Decode(data)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames = append(c.frames, frame)
+	return nil
+}
+
+// take returns the frames received so far and forgets them.
+func (c *frameRecorder) take() []wsproto.Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	frames := c.frames
+	c.frames = nil
+	return frames
+}
+
+// connect registers a recording connection for the user with the hub.
+func connect(t *testing.T, svc *testutil.Services, userID primitive.ObjectID) *frameRecorder {
+	t.Helper()
+	conn := &frameRecorder{}
+	svc.Hub.Register(userID.Hex(), conn)
+	t.Cleanup(func() { svc.Hub.Unregister(userID.Hex(), conn) })
+	return conn
+}
+
+func TestDeliverDeferredNotifications(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	user := testutil.SeedUser(t, svc.Repositories, models.User{})
+	conn := connect(t, svc, user.ID)
+
+	seed := func(title string, deliverAfter time.Time, read bool) *models.Notification {
+		t.Helper()
+		notif := &models.Notification{UserID: user.ID, Type: "goal_due_soon", Title: title, Read: read, DeliverAfter: deliverAfter}
+		if err := svc.Notifications.CreateNotification(ctx, notif); err != nil {
+			t.Fatalf("CreateNotification: %v", err)
+		}
+		return notif
+	}
+	due := seed("due", time.Now().Add(-time.Minute), false)
+	seed("already read", time.Now().Add(-time.Minute), true)
+	waiting := seed("still quiet", time.Now().Add(time.Hour), false)
+	seed("never deferred", time.Time{}, false)
+
+	delivered, err := svc.Notification.DeliverDeferred(ctx)
+	if err != nil {
+		t.Fatalf("DeliverDeferred: %v", err)
+	}
+	if delivered != 1 {
+		t.Errorf("delivered = %d, want 1", delivered)
+	}
+	frames := conn.take()
+	if len(frames) != 1 {
+		t.Fatalf("got %d frames, want 1", len(frames))
+	}
+	if frame, ok := frames[0].(*wsproto.Notification); !ok || frame.ID != due.ID.Hex() {
+		t.Errorf("pushed %+v, want the due notification", frames[0])
+	}
+
+	notifs, err := svc.Notifications.GetFilteredNotifications(ctx, user.ID, repository.NotificationFilter{}, primitive.NilObjectID, 10)
+	if err != nil {
+		t.Fatalf("GetFilteredNotifications: %v", err)
+	}
+	for _, n := range notifs {
+		if wantDeferred := n.ID == waiting.ID; n.DeliverAfter.IsZero() == wantDeferred {
+			t.Errorf("%q deliver_after = %v", n.Title, n.DeliverAfter)
+		}
+	}
+
+	// Each notification is delivered once
+	if delivered, err := svc.Notification.DeliverDeferred(ctx); err != nil || delivered != 0 {
+		t.Errorf("second run = %d, %v, want nothing delivered", delivered, err)
+	}
+	if frames := conn.take(); len(frames) != 0 {
+		t.Errorf("second run pushed %+v", frames)
+	}
+}
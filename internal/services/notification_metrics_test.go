@@ -0,0 +1,17 @@
+package services
+
+import "testing"
+
+func TestMetricType(t *testing.T) {
+	tests := map[string]string{
+		"goal_completed":                         "goal_completed",
+		"substep_due_65f0c0ffee0000000000abcd_2": "substep_due",
+		"substep_due_65f0c0ffee0000000000abcd_0": "substep_due",
+		"step_due_soon":                          "step_due_soon",
+	}
+	for notifType, want := range tests {
+		if got := metricType(notifType); got != want {
+			t.Errorf("metricType(%q) = %q, want %q", notifType, got, want)
+		}
+	}
+}
@@ -2,30 +2,86 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/metrics"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/wsproto"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// dueSoonWindow is how far ahead the due-soon checks look for deadlines.
+const dueSoonWindow = 24 * time.Hour
+
+// substepDuePrefix starts the per-substep type of substep reminders.
+const substepDuePrefix = "substep_due_"
+
+// Notification summary settings.
+const (
+	notificationSummaryPeriod   = 30 * 24 * time.Hour
+	maxNotificationSummaryTypes = 50
+	DefaultAdminSummaryLimit    = 10
+	MaxAdminSummaryLimit        = 50
+)
+
+// Page sizes for the notification list.
+const (
+	DefaultNotificationsPageSize int64 = 20
+	MaxNotificationsPageSize     int64 = 100
+)
+
+// ErrInvalidCursor is returned when a notification cursor is not a notification ID.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// NotificationSummary is a user's notification volume per type, with the types
+// they have muted so a client can offer to mute the noisiest one.
+type NotificationSummary struct {
+	Since        time.Time                        `json:"since"`
+	Total        int64                            `json:"total"`
+	ByType       []models.NotificationTypeSummary `json:"by_type"`
+	MutedTypes   []string                         `json:"muted_types"`
+	NoisiestType string                           `json:"noisiest_type,omitempty"`
+}
+
+// AdminNotificationSummary shows the noisiest types and top recipients across all users.
+type AdminNotificationSummary struct {
+	Since         time.Time                             `json:"since"`
+	TopTypes      []models.NotificationTypeSummary      `json:"top_types"`
+	TopRecipients []models.NotificationRecipientSummary `json:"top_recipients"`
+}
+
 type NotificationService struct {
-	repo     *repository.NotificationRepository
-	userRepo *repository.UserRepository
-	goalRepo *repository.GoalRepository
+	repo        *repository.NotificationRepository
+	userRepo    *repository.UserRepository
+	goalRepo    *repository.GoalRepository
+	preferences *repository.PreferencesRepository
+	counters    *CounterService
+	hub         *hub.Hub // may be nil, e.g. in the admin CLI
 }
 
-func NewNotificationService(repo *repository.NotificationRepository, userrepo *repository.UserRepository, goalrepo *repository.GoalRepository) *NotificationService {
+func NewNotificationService(repo *repository.NotificationRepository, userrepo *repository.UserRepository, goalrepo *repository.GoalRepository, preferences *repository.PreferencesRepository, counters *CounterService, wsHub *hub.Hub) *NotificationService {
 	return &NotificationService{
-		repo:     repo,
-		userRepo: userrepo,
-		goalRepo: goalrepo,
+		repo:        repo,
+		userRepo:    userrepo,
+		goalRepo:    goalrepo,
+		preferences: preferences,
+		counters:    counters,
+		hub:         wsHub,
 	}
 }
 
-// CreateNotification logs a new notification for a user
+// CreateNotification logs a new notification for a user and pushes it to
+// their open WebSocket connections. The in-app notification is always
+// created right away; during the user's quiet hours DeliverAfter is set and
+// the push is skipped until DeliverDeferred sends it once they end. Security
+// notices are never held back.
 func (s *NotificationService) CreateNotification(ctx context.Context, userID primitive.ObjectID, notifType, title, message string, targetID *primitive.ObjectID) error {
 	notif := &models.Notification{
 		UserID:   userID,
@@ -35,22 +91,263 @@ func (s *NotificationService) CreateNotification(ctx context.Context, userID pri
 		Read:     false,
 		TargetID: targetID,
 	}
-	return s.repo.CreateNotification(ctx, notif)
+	if !bypassesQuietHours(notifType) {
+		if end, quiet := quietHoursEnd(s.preferencesFor(ctx, userID), time.Now()); quiet {
+			notif.DeliverAfter = end
+		}
+	}
+	if err := s.repo.CreateNotification(ctx, notif); err != nil {
+		return err
+	}
+	metrics.NotificationsSent.WithLabelValues(metricType(notifType)).Inc()
+	s.counters.NotificationCreated(ctx, userID)
+	if notif.DeliverAfter.IsZero() {
+		s.push(notif)
+	}
+	return nil
+}
+
+// metricType is the notifications_sent_total label for a notification type.
+// Substep reminders carry their goal and substep in the type, so they share
+// one label to keep the number of series bounded.
+func metricType(notifType string) string {
+	if strings.HasPrefix(notifType, substepDuePrefix) {
+		return "substep_due"
+	}
+	return notifType
+}
+
+// push sends the notification to the user's open WebSocket connections, if any.
+func (s *NotificationService) push(notif *models.Notification) {
+	if s.hub == nil {
+		return
+	}
+	frame := wsproto.Notification{
+		ID:        notif.ID.Hex(),
+		Type:      notif.Type,
+		Title:     notif.Title,
+		Message:   notif.Message,
+		CreatedAt: notif.CreatedAt,
+	}
+	if notif.TargetID != nil {
+		frame.TargetID = notif.TargetID.Hex()
+	}
+	err := s.hub.Send(notif.UserID.Hex(), frame)
+	if err != nil && !errors.Is(err, hub.ErrOffline) {
+		logrus.WithError(err).WithField("user_id", notif.UserID.Hex()).Debug("Failed to push notification")
+	}
+}
+
+// DeliverDeferred pushes the notifications held back during quiet hours whose
+// window has ended, and returns how many were delivered. Notifications the
+// user already read in the app are released without a push.
+func (s *NotificationService) DeliverDeferred(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		notif, err := s.repo.ClaimDeferredNotification(ctx, time.Now())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return delivered, nil
+		}
+		if err != nil {
+			return delivered, fmt.Errorf("failed to claim deferred notification: %v", err)
+		}
+		if !notif.Read {
+			s.push(notif)
+			delivered++
+		}
+	}
+}
+
+// IsTypeMuted reports whether the user has muted the given notification type.
+func (s *NotificationService) IsTypeMuted(ctx context.Context, userID primitive.ObjectID, notifType string) bool {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false
+	}
+	for _, muted := range user.MutedNotificationTypes {
+		if muted == notifType {
+			return true
+		}
+	}
+	return false
+}
+
+// preferencesFor returns the user's saved preferences, or the defaults when
+// none were saved or they could not be read.
+func (s *NotificationService) preferencesFor(ctx context.Context, userID primitive.ObjectID) models.UserPreferences {
+	prefs, err := s.preferences.GetByUserID(ctx, userID)
+	if err != nil {
+		return models.DefaultUserPreferences(userID)
+	}
+	return *prefs
+}
+
+// WantsGoalDueReminders reports whether the user wants goal, step and substep deadline reminders.
+func (s *NotificationService) WantsGoalDueReminders(ctx context.Context, userID primitive.ObjectID) bool {
+	return s.preferencesFor(ctx, userID).NotifyGoalDue
+}
+
+// WantsFriendRequestNotifications reports whether the user wants notifications about friend requests.
+func (s *NotificationService) WantsFriendRequestNotifications(ctx context.Context, userID primitive.ObjectID) bool {
+	return s.preferencesFor(ctx, userID).NotifyFriendRequests
+}
+
+// SentWithin reports whether the user got a notification of this type in the last period.
+func (s *NotificationService) SentWithin(ctx context.Context, userID primitive.ObjectID, notifType string, period time.Duration) bool {
+	existing, err := s.repo.GetLatestNotificationByType(ctx, userID, notifType)
+	return err == nil && existing != nil && time.Since(existing.CreatedAt) < period
+}
+
+// SentForTargetWithin reports whether the user got a notification of this type
+// about targetID in the last period.
+func (s *NotificationService) SentForTargetWithin(ctx context.Context, userID primitive.ObjectID, notifType string, targetID primitive.ObjectID, period time.Duration) bool {
+	existing, err := s.repo.GetLatestNotificationForTarget(ctx, userID, notifType, targetID)
+	return err == nil && existing != nil && time.Since(existing.CreatedAt) < period
+}
+
+// GetNotificationSummary counts the user's notifications of the last 30 days per type and day.
+func (s *NotificationService) GetNotificationSummary(ctx context.Context, userID primitive.ObjectID) (*NotificationSummary, error) {
+	since := time.Now().Add(-notificationSummaryPeriod)
+
+	byType, err := s.repo.CountByTypeAndDay(ctx, userID, since, maxNotificationSummaryTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &NotificationSummary{
+		Since:      since,
+		ByType:     byType,
+		MutedTypes: []string{},
+	}
+	if summary.ByType == nil {
+		summary.ByType = []models.NotificationTypeSummary{}
+	}
+	for _, t := range byType {
+		summary.Total += t.Count
+	}
+	if len(byType) > 0 {
+		summary.NoisiestType = byType[0].Type
+	}
+
+	if user, err := s.userRepo.GetUserByID(ctx, userID); err == nil && user.MutedNotificationTypes != nil {
+		summary.MutedTypes = user.MutedNotificationTypes
+	}
+	return summary, nil
+}
+
+// GetAdminNotificationSummary returns the noisiest notification types and top
+// recipients of the last 30 days across all users.
+func (s *NotificationService) GetAdminNotificationSummary(ctx context.Context, limit int64) (*AdminNotificationSummary, error) {
+	if limit <= 0 {
+		limit = DefaultAdminSummaryLimit
+	}
+	if limit > MaxAdminSummaryLimit {
+		limit = MaxAdminSummaryLimit
+	}
+	since := time.Now().Add(-notificationSummaryPeriod)
+
+	types, recipients, err := s.repo.GetTopTypesAndRecipients(ctx, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &AdminNotificationSummary{
+		Since:         since,
+		TopTypes:      types,
+		TopRecipients: recipients,
+	}
+	if summary.TopTypes == nil {
+		summary.TopTypes = []models.NotificationTypeSummary{}
+	}
+	if summary.TopRecipients == nil {
+		summary.TopRecipients = []models.NotificationRecipientSummary{}
+	}
+	return summary, nil
+}
+
+// NotificationCount is how many live notifications a user has and how many are unread.
+type NotificationCount struct {
+	Total  int64 `json:"total"`
+	Unread int64 `json:"unread"`
+}
+
+// GetNotificationCount counts the user's live notifications without loading them.
+func (s *NotificationService) GetNotificationCount(ctx context.Context, userID primitive.ObjectID) (*NotificationCount, error) {
+	total, unread, err := s.repo.CountNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationCount{Total: total, Unread: unread}, nil
 }
 
-// GetUserNotifications returns all notifications for a user
-func (s *NotificationService) GetUserNotifications(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error) {
-	return s.repo.GetUserNotifications(ctx, userID)
+// NotificationPage is one page of a user's notifications. NextCursor is empty on the last page.
+type NotificationPage struct {
+	Data       []models.Notification `json:"data"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+	HasMore    bool                  `json:"has_more"`
+}
+
+// GetFilteredNotifications returns one page of the user's notifications
+// matching the filter, newest first. cursor is the ID of the last
+// notification of the previous page.
+func (s *NotificationService) GetFilteredNotifications(ctx context.Context, userID primitive.ObjectID, filter repository.NotificationFilter, cursor string, limit int64) (*NotificationPage, error) {
+	if limit <= 0 {
+		limit = DefaultNotificationsPageSize
+	}
+	if limit > MaxNotificationsPageSize {
+		limit = MaxNotificationsPageSize
+	}
+
+	var before primitive.ObjectID
+	if cursor != "" {
+		var err error
+		before, err = primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	// Fetch one extra notification to know whether another page follows
+	notifications, err := s.repo.GetFilteredNotifications(ctx, userID, filter, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &NotificationPage{Data: notifications}
+	if int64(len(notifications)) > limit {
+		page.Data = notifications[:limit]
+		page.NextCursor = page.Data[limit-1].ID.Hex()
+		page.HasMore = true
+	}
+	return page, nil
 }
 
 // MarkNotificationAsRead sets the "read" status of a notification to true
 func (s *NotificationService) MarkNotificationAsRead(ctx context.Context, notifID primitive.ObjectID) error {
-	return s.repo.MarkAsRead(ctx, notifID)
+	notif, err := s.repo.MarkAsRead(ctx, notifID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil // already read
+	}
+	if err != nil {
+		return err
+	}
+	s.counters.NotificationRead(ctx, notif.UserID)
+	return nil
 }
 
 // DeleteNotification deletes a specific notification
 func (s *NotificationService) DeleteNotification(ctx context.Context, notifID primitive.ObjectID) error {
-	return s.repo.DeleteNotification(ctx, notifID)
+	notif, err := s.repo.DeleteNotification(ctx, notifID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !notif.Read {
+		s.counters.NotificationRead(ctx, notif.UserID)
+	}
+	return nil
 }
 
 // CleanupExpiredNotifications could be called periodically (e.g. by cron) to delete old ones
@@ -68,6 +365,10 @@ func (s *NotificationService) CheckInactiveUsers(ctx context.Context) error {
 	now := time.Now()
 	for _, user := range users {
 		if user.LastActiveAt.IsZero() || now.Sub(user.LastActiveAt) >= 3*24*time.Hour {
+			if !s.preferencesFor(ctx, user.ID).NotifyInactivity {
+				continue
+			}
+
 			// Check if they already got a recent inactivity notification
 			existing, err := s.repo.GetLatestNotificationByType(ctx, user.ID, "user_inactive")
 			if err == nil && existing != nil && now.Sub(existing.CreatedAt) < 3*24*time.Hour {
@@ -88,12 +389,50 @@ func (s *NotificationService) CheckInactiveUsers(ctx context.Context) error {
 	return nil
 }
 
+// CheckGettingStarted nudges users who verified their email at least a day ago
+// and still have no goals. Each user is considered only once.
+func (s *NotificationService) CheckGettingStarted(ctx context.Context) error {
+	users, err := s.userRepo.GetUsersPendingGettingStarted(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return fmt.Errorf("failed to fetch users: %w", err)
+	}
+
+	for _, user := range users {
+		count, err := s.goalRepo.CountGoalsByUser(ctx, user.ID)
+		if err != nil {
+			continue // retry on the next run
+		}
+
+		// Flag the user either way so they are never considered again
+		first, err := s.userRepo.MarkGettingStartedSent(ctx, user.ID)
+		if err != nil || !first || count > 0 {
+			continue
+		}
+
+		err = s.CreateNotification(ctx, user.ID, "getting_started",
+			"🚀 Ready to set your first goal?",
+			"Create your first goal or browse public templates to get started.",
+			nil,
+		)
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to send getting started notification to user %s", user.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
 func (s *NotificationService) DeleteExpiredNotifications(ctx context.Context) error {
 	return s.repo.DeleteExpiredNotifications(ctx)
 }
 
+// CountExpiredNotifications returns how many notifications have expired but were not deleted yet.
+func (s *NotificationService) CountExpiredNotifications(ctx context.Context) (int64, error) {
+	return s.repo.CountExpiredNotifications(ctx)
+}
+
 func (s *NotificationService) CheckGoalDueSoon(ctx context.Context) error {
-	goals, err := s.goalRepo.GetAllGoals(ctx, 100)
+	goals, err := s.goalRepo.GetGoalsDueWithin(ctx, MaxRemindBefore)
 	if err != nil {
 		return fmt.Errorf("failed to fetch goals: %w", err)
 	}
@@ -104,18 +443,21 @@ func (s *NotificationService) CheckGoalDueSoon(ctx context.Context) error {
 		if goal.Status == "completed" || goal.DueDate.IsZero() {
 			continue
 		}
+		if !s.WantsGoalDueReminders(ctx, goal.UserID) {
+			continue
+		}
 
-		// В пределах следующих 24 часов?
+		// Within the goal's own reminder lead time?
+		lead := GoalReminderLead(goal)
 		timeLeft := goal.DueDate.Sub(now)
-		if timeLeft > 0 && timeLeft <= 24*time.Hour {
+		if timeLeft > 0 && timeLeft <= lead {
 			// Проверим, уже ли есть похожее уведомление
-			existing, err := s.repo.GetLatestNotificationByType(ctx, goal.UserID, "goal_due_soon")
-			if err == nil && existing != nil && existing.TargetID != nil && *existing.TargetID == goal.ID {
+			if s.SentForTargetWithin(ctx, goal.UserID, "goal_due_soon", goal.ID, lead) {
 				continue // уже есть активное уведомление
 			}
 
 			message := fmt.Sprintf("Goal \"%s\" is due soon! Don't forget to complete it.", goal.Name)
-			err = s.CreateNotification(ctx, goal.UserID, "goal_due_soon", "⏰ Goal Due Soon", message, &goal.ID)
+			err := s.CreateNotification(ctx, goal.UserID, "goal_due_soon", "⏰ Goal Due Soon", message, &goal.ID)
 			if err != nil {
 				logrus.WithError(err).Warnf("Failed to send goal due soon notification for goal %s", goal.ID.Hex())
 			}
@@ -126,7 +468,7 @@ func (s *NotificationService) CheckGoalDueSoon(ctx context.Context) error {
 }
 
 func (s *NotificationService) CheckStepDueSoon(ctx context.Context) error {
-	goals, err := s.goalRepo.GetAllGoals(ctx, 100)
+	goals, err := s.goalRepo.GetGoalsDueWithin(ctx, dueSoonWindow)
 	if err != nil {
 		return fmt.Errorf("failed to fetch goals: %w", err)
 	}
@@ -134,7 +476,7 @@ func (s *NotificationService) CheckStepDueSoon(ctx context.Context) error {
 	now := time.Now()
 	for _, goal := range goals {
 		// Пропускаем завершённые цели
-		if goal.Status == "completed" {
+		if goal.Status == "completed" || !s.WantsGoalDueReminders(ctx, goal.UserID) {
 			continue
 		}
 
@@ -144,7 +486,7 @@ func (s *NotificationService) CheckStepDueSoon(ctx context.Context) error {
 			}
 
 			timeLeft := step.DueDate.Sub(now)
-			if timeLeft > 0 && timeLeft <= 24*time.Hour {
+			if timeLeft > 0 && timeLeft <= dueSoonWindow {
 				// Проверим, есть ли уже уведомление
 				existing, err := s.repo.GetLatestNotificationByType(ctx, goal.UserID, "step_due_soon")
 				if err == nil && existing != nil && existing.Title == step.Name && existing.TargetID != nil && *existing.TargetID == goal.ID {
@@ -164,21 +506,24 @@ func (s *NotificationService) CheckStepDueSoon(ctx context.Context) error {
 }
 
 func (s *NotificationService) CheckSubstepDueSoon(ctx context.Context) error {
-	goals, err := s.goalRepo.GetAllGoals(ctx, 100)
+	goals, err := s.goalRepo.GetGoalsDueWithin(ctx, dueSoonWindow)
 	if err != nil {
 		return fmt.Errorf("failed to fetch goals: %w", err)
 	}
 
 	now := time.Now()
 	for _, goal := range goals {
+		if !s.WantsGoalDueReminders(ctx, goal.UserID) {
+			continue
+		}
 		for _, step := range goal.Steps {
 			for i, sub := range step.Substeps {
 				if sub.Done || sub.DueDate.IsZero() {
 					continue
 				}
-				if sub.DueDate.After(now) && sub.DueDate.Before(now.Add(24*time.Hour)) {
+				if sub.DueDate.After(now) && sub.DueDate.Before(now.Add(dueSoonWindow)) {
 					// Create unique key per substep (avoid spam)
-					key := fmt.Sprintf("substep_due_%s_%d", goal.ID.Hex(), i)
+					key := fmt.Sprintf("%s%s_%d", substepDuePrefix, goal.ID.Hex(), i)
 					existing, _ := s.repo.GetLatestNotificationByType(ctx, goal.UserID, key)
 					if existing != nil && now.Sub(existing.CreatedAt) < 12*time.Hour {
 						continue
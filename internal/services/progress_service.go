@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ProgressService struct {
+	repo *repository.ProgressRepository
+}
+
+func NewProgressService(repo *repository.ProgressRepository) *ProgressService {
+	return &ProgressService{repo: repo}
+}
+
+// RecordSnapshot stores the goal's current completion percentage
+func (s *ProgressService) RecordSnapshot(ctx context.Context, goal *models.Goal) error {
+	snapshot := &models.ProgressSnapshot{
+		GoalID:    goal.ID,
+		UserID:    goal.UserID,
+		Percent:   GoalProgressPercent(goal),
+		Timestamp: time.Now(),
+	}
+
+	if err := s.repo.CreateSnapshot(ctx, snapshot); err != nil {
+		logrus.WithError(err).WithField("goal_id", goal.ID.Hex()).Error("Failed to record progress snapshot")
+		return err
+	}
+	return nil
+}
+
+// GetHistory returns the goal's progress snapshots in the [from, to) range
+func (s *ProgressService) GetHistory(ctx context.Context, goalID primitive.ObjectID, from, to time.Time) ([]models.ProgressSnapshot, error) {
+	return s.repo.GetSnapshots(ctx, goalID, from, to)
+}
+
+// GoalProgressPercent returns the share of done substeps as a percentage.
+// Steps without substeps count as a single unit each.
+func GoalProgressPercent(goal *models.Goal) float64 {
+	total, done := 0, 0
+	for _, step := range goal.Steps {
+		if len(step.Substeps) == 0 {
+			total++
+			if step.Completed {
+				done++
+			}
+			continue
+		}
+		for _, sub := range step.Substeps {
+			total++
+			if sub.Done {
+				done++
+			}
+		}
+	}
+
+	if total == 0 {
+		if goal.Status == "completed" {
+			return 100
+		}
+		return 0
+	}
+	return float64(done) * 100 / float64(total)
+}
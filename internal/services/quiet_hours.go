@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+)
+
+// quietHoursLayout is the format of the quiet hours start and end.
+const quietHoursLayout = "15:04"
+
+// bypassesQuietHours reports whether a notification type is a security
+// notice, which is delivered right away even during quiet hours.
+func bypassesQuietHours(notifType string) bool {
+	return strings.HasPrefix(notifType, "security_") || strings.HasPrefix(notifType, "password_")
+}
+
+// validateQuietHours checks that quiet hours are either unset or have a
+// distinct start and end in HH:MM.
+func validateQuietHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	startAt, err := time.Parse(quietHoursLayout, start)
+	if err != nil {
+		return fmt.Errorf("%w: quiet_hours_start must be HH:MM", ErrInvalidPreferences)
+	}
+	endAt, err := time.Parse(quietHoursLayout, end)
+	if err != nil {
+		return fmt.Errorf("%w: quiet_hours_end must be HH:MM", ErrInvalidPreferences)
+	}
+	if startAt.Equal(endAt) {
+		return fmt.Errorf("%w: quiet hours must not start and end at the same time", ErrInvalidPreferences)
+	}
+	return nil
+}
+
+// quietHoursEnd returns when the user's quiet hours end if now falls inside
+// them. The window is read in the user's timezone and ends before its start
+// time when it spans midnight, e.g. 22:00-07:00.
+func quietHoursEnd(prefs models.UserPreferences, now time.Time) (time.Time, bool) {
+	if prefs.QuietHoursStart == "" || prefs.QuietHoursEnd == "" {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(quietHoursLayout, prefs.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := time.Parse(quietHoursLayout, prefs.QuietHoursEnd)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	if startMinute == endMinute {
+		// validateQuietHours rejects this, so treat it as unset rather than as a whole day
+		return time.Time{}, false
+	}
+	endToday := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, loc)
+
+	switch {
+	case startMinute < endMinute:
+		if minute >= startMinute && minute < endMinute {
+			return endToday, true
+		}
+	case minute >= startMinute:
+		// Spans midnight and we are before it
+		return endToday.AddDate(0, 0, 1), true
+	case minute < endMinute:
+		// Spans midnight and we are past it
+		return endToday, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	utc := func(hour, minute int) time.Time {
+		return time.Date(2026, 3, 10, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		timezone  string
+		now       time.Time
+		wantEnd   time.Time
+		wantQuiet bool
+	}{
+		{name: "unset", now: utc(23, 0)},
+		{name: "only start set", start: "22:00", now: utc(23, 0)},
+		{name: "same day inside", start: "13:00", end: "15:00", now: utc(14, 0), wantEnd: utc(15, 0), wantQuiet: true},
+		{name: "same day at start", start: "13:00", end: "15:00", now: utc(13, 0), wantEnd: utc(15, 0), wantQuiet: true},
+		{name: "same day at end", start: "13:00", end: "15:00", now: utc(15, 0)},
+		{name: "same day outside", start: "13:00", end: "15:00", now: utc(9, 0)},
+		{name: "overnight before midnight", start: "22:00", end: "07:00", now: utc(23, 30), wantEnd: time.Date(2026, 3, 11, 7, 0, 0, 0, time.UTC), wantQuiet: true},
+		{name: "overnight after midnight", start: "22:00", end: "07:00", now: utc(3, 0), wantEnd: utc(7, 0), wantQuiet: true},
+		{name: "overnight at start", start: "22:00", end: "07:00", now: utc(22, 0), wantEnd: time.Date(2026, 3, 11, 7, 0, 0, 0, time.UTC), wantQuiet: true},
+		{name: "overnight at end", start: "22:00", end: "07:00", now: utc(7, 0)},
+		{name: "overnight daytime", start: "22:00", end: "07:00", now: utc(12, 0)},
+		{name: "equal start and end", start: "08:00", end: "08:00", now: utc(8, 0)},
+		{name: "equal start and end later", start: "08:00", end: "08:00", now: utc(20, 0)},
+		// 14:00 UTC is 23:00 in Tokyo (UTC+9), inside 22:00-07:00 local time.
+		{name: "timezone ahead inside", start: "22:00", end: "07:00", timezone: "Asia/Tokyo", now: utc(14, 0), wantEnd: time.Date(2026, 3, 11, 7, 0, 0, 0, tokyo), wantQuiet: true},
+		// 23:00 UTC is 08:00 the next day in Tokyo, past the end of the window.
+		{name: "timezone ahead outside", start: "22:00", end: "07:00", timezone: "Asia/Tokyo", now: utc(23, 0)},
+		// 23:30 UTC is 18:30 in Bogotá (UTC-5), before the window starts.
+		{name: "timezone behind outside", start: "22:00", end: "07:00", timezone: "America/Bogota", now: utc(23, 30)},
+		// 04:00 UTC is 23:00 the previous day in Bogotá, so the window ends at 07:00 Bogotá time, 12:00 UTC.
+		{name: "timezone behind inside", start: "22:00", end: "07:00", timezone: "America/Bogota", now: utc(4, 0), wantEnd: utc(12, 0), wantQuiet: true},
+		{name: "unknown timezone falls back to UTC", start: "22:00", end: "07:00", timezone: "Mars/Olympus", now: utc(3, 0), wantEnd: utc(7, 0), wantQuiet: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := models.UserPreferences{QuietHoursStart: tt.start, QuietHoursEnd: tt.end, Timezone: tt.timezone}
+			end, quiet := quietHoursEnd(prefs, tt.now)
+			if quiet != tt.wantQuiet {
+				t.Fatalf("quiet = %v, want %v", quiet, tt.wantQuiet)
+			}
+			if !end.Equal(tt.wantEnd) {
+				t.Errorf("end = %v, want %v", end, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestValidateQuietHours(t *testing.T) {
+	tests := []struct {
+		start, end string
+		wantErr    bool
+	}{
+		{start: "", end: ""},
+		{start: "22:00", end: "07:00"},
+		{start: "09:00", end: "17:30"},
+		{start: "22:00", end: "22:00", wantErr: true},
+		{start: "22:00", end: "", wantErr: true},
+		{start: "", end: "07:00", wantErr: true},
+		{start: "25:00", end: "07:00", wantErr: true},
+		{start: "10pm", end: "07:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := validateQuietHours(tt.start, tt.end)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("validateQuietHours(%q, %q) = %v, want error: %v", tt.start, tt.end, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidPreferences) {
+			t.Errorf("validateQuietHours(%q, %q) = %v, want ErrInvalidPreferences", tt.start, tt.end, err)
+		}
+	}
+}
+
+func TestBypassesQuietHours(t *testing.T) {
+	for notifType, want := range map[string]bool{
+		"security_new_login": true,
+		"password_changed":   true,
+		"goal_completed":     false,
+		"friend_request":     false,
+	} {
+		if got := bypassesQuietHours(notifType); got != want {
+			t.Errorf("bypassesQuietHours(%q) = %v, want %v", notifType, got, want)
+		}
+	}
+}
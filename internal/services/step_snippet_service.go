@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxSnippetsPerUser caps how many step snippets one user can keep.
+const MaxSnippetsPerUser = 20
+
+type StepSnippetService struct {
+	repo        *repository.StepSnippetRepository
+	goalService *GoalService
+}
+
+func NewStepSnippetService(repo *repository.StepSnippetRepository, goalService *GoalService) *StepSnippetService {
+	return &StepSnippetService{
+		repo:        repo,
+		goalService: goalService,
+	}
+}
+
+// CreateSnippet stores a new snippet for the user, enforcing the per-user cap
+func (s *StepSnippetService) CreateSnippet(ctx context.Context, snippet *models.StepSnippet) (*models.StepSnippet, error) {
+	if err := validateSnippet(snippet); err != nil {
+		return nil, err
+	}
+
+	count, err := s.repo.CountSnippetsByUser(ctx, snippet.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count snippets: %v", err)
+	}
+	if count >= MaxSnippetsPerUser {
+		return nil, fmt.Errorf("you can keep at most %d snippets", MaxSnippetsPerUser)
+	}
+
+	return s.repo.CreateSnippet(ctx, snippet)
+}
+
+// GetSnippet returns one of the user's snippets
+func (s *StepSnippetService) GetSnippet(ctx context.Context, id string, userID primitive.ObjectID) (*models.StepSnippet, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snippet ID")
+	}
+
+	snippet, err := s.repo.GetSnippetByID(ctx, objID)
+	// Snippets are private, so someone else's snippet looks the same as a missing one
+	if err != nil || snippet.UserID != userID {
+		return nil, fmt.Errorf("snippet not found")
+	}
+	return snippet, nil
+}
+
+func (s *StepSnippetService) GetSnippetsByUser(ctx context.Context, userID primitive.ObjectID) ([]models.StepSnippet, error) {
+	return s.repo.GetSnippetsByUser(ctx, userID)
+}
+
+// UpdateSnippet replaces the name and steps of one of the user's snippets
+func (s *StepSnippetService) UpdateSnippet(ctx context.Context, id string, userID primitive.ObjectID, update *models.StepSnippet) (*models.StepSnippet, error) {
+	if err := validateSnippet(update); err != nil {
+		return nil, err
+	}
+
+	snippet, err := s.GetSnippet(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	snippet.Name = update.Name
+	snippet.Steps = update.Steps
+	if err := s.repo.UpdateSnippet(ctx, snippet); err != nil {
+		return nil, err
+	}
+	return snippet, nil
+}
+
+// DeleteSnippet removes one of the user's snippets
+func (s *StepSnippetService) DeleteSnippet(ctx context.Context, id string, userID primitive.ObjectID) error {
+	snippet, err := s.GetSnippet(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteSnippet(ctx, snippet.ID)
+}
+
+// ApplySnippet appends the snippet's steps to the goal and recomputes its status.
+func (s *StepSnippetService) ApplySnippet(ctx context.Context, goalID, snippetID string, userID primitive.ObjectID) (*models.Goal, *models.StepSnippet, error) {
+	snippet, err := s.GetSnippet(ctx, snippetID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	goal, err := s.goalService.editSteps(ctx, goalID, userID, func(goal *models.Goal) error {
+		goal.Steps = append(goal.Steps, templateStepsToGoalSteps(snippet.Steps)...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return goal, snippet, nil
+}
+
+func validateSnippet(snippet *models.StepSnippet) error {
+	if snippet.Name == "" || len(snippet.Steps) == 0 {
+		return fmt.Errorf("snippet must have a name and at least one step")
+	}
+	for _, step := range snippet.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("every step needs a name")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned when bookmarking templates.
+var (
+	ErrBookmarkTemplateNotFound = errors.New("template not found")
+	ErrBookmarkNotFound         = errors.New("template is not bookmarked")
+)
+
+// templateVisibleTo reports whether the user may see the template: public
+// templates are visible to everyone, private ones only to their owner.
+func templateVisibleTo(template *models.GoalTemplate, userID primitive.ObjectID) bool {
+	return template.Public || template.UserID == userID
+}
+
+// BookmarkTemplate saves a template the user can see to their bookmarks.
+func (s *TemplateService) BookmarkTemplate(ctx context.Context, templateID, userID primitive.ObjectID) error {
+	template, err := s.repo.GetTemplateByID(ctx, templateID)
+	if err != nil || !templateVisibleTo(template, userID) {
+		return ErrBookmarkTemplateNotFound
+	}
+	return s.bookmarkRepo.AddBookmark(ctx, userID, templateID)
+}
+
+// UnbookmarkTemplate removes a template from the user's bookmarks. It works
+// even when the template has since been deleted or made private.
+func (s *TemplateService) UnbookmarkTemplate(ctx context.Context, templateID, userID primitive.ObjectID) error {
+	removed, err := s.bookmarkRepo.RemoveBookmark(ctx, userID, templateID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// GetBookmarkedTemplates returns the templates the user bookmarked, most
+// recently bookmarked first. Templates that were deleted or are no longer
+// visible to the user are left out.
+func (s *TemplateService) GetBookmarkedTemplates(ctx context.Context, userID primitive.ObjectID) ([]models.GoalTemplate, error) {
+	bookmarks, err := s.bookmarkRepo.GetBookmarks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bookmarks) == 0 {
+		return []models.GoalTemplate{}, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		ids[i] = bookmark.TemplateID
+	}
+	found, err := s.repo.GetTemplatesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.GoalTemplate, len(found))
+	for _, template := range found {
+		byID[template.ID] = template
+	}
+
+	templates := make([]models.GoalTemplate, 0, len(found))
+	for _, id := range ids {
+		template, ok := byID[id]
+		if !ok || !templateVisibleTo(&template, userID) {
+			continue
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
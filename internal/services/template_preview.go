@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplatePreview is a public template as shown to logged-out visitors. The
+// author is named by username only.
+type TemplatePreview struct {
+	ID             primitive.ObjectID    `json:"id"`
+	Title          string                `json:"title"`
+	Description    string                `json:"description"`
+	Steps          []models.TemplateStep `json:"steps"`
+	Category       string                `json:"category,omitempty"`
+	Tags           []string              `json:"tags,omitempty"`
+	CopyCount      int64                 `json:"copy_count"`
+	AuthorUsername string                `json:"author_username"`
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
+// TemplatePreviewPage is one page of template previews, like TemplatePage.
+type TemplatePreviewPage struct {
+	Items  []TemplatePreview `json:"items"`
+	Total  int64             `json:"total"`
+	Limit  int64             `json:"limit"`
+	Offset int64             `json:"offset"`
+}
+
+// GetTemplatePreviews returns one page of public templates for visitors who
+// are not logged in.
+func (s *TemplateService) GetTemplatePreviews(ctx context.Context, filter repository.TemplateFilter, offset, limit int64) (*TemplatePreviewPage, error) {
+	page, err := s.GetPublicTemplates(ctx, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.templatePreviews(ctx, page.Items)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePreviewPage{Items: items, Total: page.Total, Limit: page.Limit, Offset: page.Offset}, nil
+}
+
+// GetTemplatePreview returns one public template for visitors who are not
+// logged in. Private templates are reported as not found.
+func (s *TemplateService) GetTemplatePreview(ctx context.Context, id primitive.ObjectID) (*TemplatePreview, error) {
+	template, err := s.repo.GetTemplateByID(ctx, id)
+	if err != nil || !template.Public {
+		return nil, ErrTemplateNotFound
+	}
+	items, err := s.templatePreviews(ctx, []models.GoalTemplate{*template})
+	if err != nil {
+		return nil, err
+	}
+	return &items[0], nil
+}
+
+// templatePreviews converts public templates to previews, looking up the
+// authors' usernames.
+func (s *TemplateService) templatePreviews(ctx context.Context, templates []models.GoalTemplate) ([]TemplatePreview, error) {
+	previews := make([]TemplatePreview, 0, len(templates))
+	if len(templates) == 0 {
+		return previews, nil
+	}
+
+	authorIDs := make([]primitive.ObjectID, 0, len(templates))
+	for _, template := range templates {
+		authorIDs = append(authorIDs, template.UserID)
+	}
+	authors, err := s.userRepo.GetPublicProfilesByIDs(ctx, authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	usernames := make(map[primitive.ObjectID]string, len(authors))
+	for _, author := range authors {
+		usernames[author.ID] = author.Username
+	}
+
+	for _, template := range templates {
+		previews = append(previews, TemplatePreview{
+			ID:             template.ID,
+			Title:          template.Title,
+			Description:    template.Description,
+			Steps:          template.Steps,
+			Category:       template.Category,
+			Tags:           template.Tags,
+			CopyCount:      template.CopyCount,
+			AuthorUsername: usernames[template.UserID],
+			CreatedAt:      template.CreatedAt,
+		})
+	}
+	return previews, nil
+}
@@ -2,23 +2,56 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// templateCopiedInterval limits template_copied notifications to one per
+// template in this period.
+const templateCopiedInterval = 24 * time.Hour
+
+// Limits on copying templates. Accounts younger than minCountedAccountAge or
+// unverified can still copy, but their copies are not counted.
+const (
+	templateCopyLimit    = 30
+	templateCopyWindow   = time.Hour
+	minCountedAccountAge = 24 * time.Hour
+)
+
 type TemplateService struct {
-	repo     *repository.TemplateRepository
-	goalRepo *repository.GoalRepository
+	repo                *repository.TemplateRepository
+	copyRepo            *repository.TemplateCopyRepository
+	bookmarkRepo        *repository.TemplateBookmarkRepository
+	versionRepo         *repository.TemplateVersionRepository
+	goalRepo            *repository.GoalRepository
+	userRepo            *repository.UserRepository
+	notificationService *NotificationService
+	counters            *CounterService
+	categories          *CategoryService
+
+	copyLimiter *windowLimiter
 }
 
-func NewTemplateService(repo *repository.TemplateRepository, goalRepo *repository.GoalRepository) *TemplateService {
+func NewTemplateService(repo *repository.TemplateRepository, copyRepo *repository.TemplateCopyRepository, bookmarkRepo *repository.TemplateBookmarkRepository, versionRepo *repository.TemplateVersionRepository, goalRepo *repository.GoalRepository, userRepo *repository.UserRepository, notificationService *NotificationService, counters *CounterService, categories *CategoryService) *TemplateService {
 	return &TemplateService{
-		repo:     repo,
-		goalRepo: goalRepo,
+		repo:                repo,
+		copyRepo:            copyRepo,
+		bookmarkRepo:        bookmarkRepo,
+		versionRepo:         versionRepo,
+		goalRepo:            goalRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		counters:            counters,
+		categories:          categories,
+		copyLimiter:         newWindowLimiter(templateCopyLimit, templateCopyWindow),
 	}
 }
 
@@ -27,12 +60,25 @@ func (s *TemplateService) CreateTemplate(ctx context.Context, template *models.G
 	if template.Title == "" || len(template.Steps) == 0 {
 		return nil, fmt.Errorf("template must have a title and at least one step")
 	}
+	if template.Category != "" && !s.categories.IsValidCategory(ctx, template.UserID, template.Category) {
+		return nil, ErrInvalidCategory
+	}
+	tags, err := NormalizeTags(template.Tags)
+	if err != nil {
+		return nil, err
+	}
+	template.Tags = tags
 	return s.repo.CreateTemplate(ctx, template)
 }
 
-// GetAllTemplates returns all available templates
-func (s *TemplateService) GetAllTemplates(ctx context.Context) ([]models.GoalTemplate, error) {
-	return s.repo.GetAllTemplates(ctx)
+// GetAllTemplates returns one page of all templates for admins.
+func (s *TemplateService) GetAllTemplates(ctx context.Context, offset, limit int64) (*TemplatePage, error) {
+	offset, limit = templatePageBounds(offset, limit)
+	templates, total, err := s.repo.GetAllTemplates(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePage{Items: templates, Total: total, Limit: limit, Offset: offset}, nil
 }
 
 // GetTemplateByID retrieves a single template by ID
@@ -44,19 +90,258 @@ func (s *TemplateService) GetTemplateByID(ctx context.Context, id string) (*mode
 	return s.repo.GetTemplateByID(ctx, objID)
 }
 
-func (s *TemplateService) CopyTemplateToGoal(ctx context.Context, templateID string, userID primitive.ObjectID) (*models.Goal, error) {
+// ErrInvalidTemplateCopy is returned when the overrides given to
+// CopyTemplateToGoal are invalid.
+var ErrInvalidTemplateCopy = errors.New("invalid template copy")
+
+// TemplateCopyOverrides replaces parts of the goal created from a template.
+// Nil fields keep the template's values. StepDueOffsets gives each step, by
+// index, a due date that far from the time of the copy. A non-zero Version
+// copies that version of the template instead of the current one.
+type TemplateCopyOverrides struct {
+	Version        int               `json:"version"`
+	Name           *string           `json:"name"`
+	Description    *string           `json:"description"`
+	Category       *string           `json:"category"`
+	DueDate        *time.Time        `json:"due_date"`
+	StepDueOffsets []models.Duration `json:"step_due_offsets"`
+}
+
+// CopyTemplateToGoal creates a goal for userID from the template, applying
+// overrides the same way goal creation validates them. Each user may copy
+// templateCopyLimit templates per templateCopyWindow.
+func (s *TemplateService) CopyTemplateToGoal(ctx context.Context, templateID string, userID primitive.ObjectID, overrides TemplateCopyOverrides) (*models.Goal, error) {
 	objID, err := primitive.ObjectIDFromHex(templateID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid template ID")
 	}
+	if !s.copyLimiter.allow(userID.Hex(), time.Now()) {
+		return nil, ErrRateLimited
+	}
 
 	template, err := s.repo.GetTemplateByID(ctx, objID)
 	if err != nil {
 		return nil, fmt.Errorf("template not found: %v", err)
 	}
+	content := templateVersionOf(template)
+	if overrides.Version != 0 {
+		if content, err = s.templateVersion(ctx, template, overrides.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	goal := &models.Goal{
+		Name:        content.Title,
+		Description: content.Description,
+		Steps:       templateStepsToGoalSteps(content.Steps),
+		Category:    content.Category,
+		Tags:        content.Tags,
+		UserID:      userID,
+		Status:      "in_progress",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	recalculateStatus(goal)
+
+	// Another user's custom category means nothing to the copier
+	if goal.Category != "" && !s.categories.IsValidCategory(ctx, userID, goal.Category) {
+		goal.Category = ""
+	}
+	if err := s.applyCopyOverrides(ctx, goal, overrides); err != nil {
+		return nil, err
+	}
+
+	created, err := s.goalRepo.CreateGoal(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+	s.counters.GoalCreated(ctx, created)
 
+	s.recordCopy(ctx, template, userID, created.ID)
+	s.notifyTemplateCopied(ctx, template, userID)
+	return created, nil
+}
+
+// applyCopyOverrides sets the overridden fields on a goal copied from a
+// template and validates them.
+func (s *TemplateService) applyCopyOverrides(ctx context.Context, goal *models.Goal, overrides TemplateCopyOverrides) error {
+	now := time.Now()
+	if overrides.Name != nil {
+		goal.Name = strings.TrimSpace(*overrides.Name)
+		if goal.Name == "" {
+			return fmt.Errorf("%w: name cannot be empty", ErrInvalidTemplateCopy)
+		}
+	}
+	if overrides.Description != nil {
+		goal.Description = *overrides.Description
+	}
+	if overrides.Category != nil {
+		goal.Category = *overrides.Category
+		if goal.Category != "" && !s.categories.IsValidCategory(ctx, goal.UserID, goal.Category) {
+			return ErrInvalidCategory
+		}
+	}
+	if overrides.DueDate != nil {
+		if !overrides.DueDate.IsZero() && overrides.DueDate.Before(now) {
+			return fmt.Errorf("%w: due date cannot be in the past", ErrInvalidTemplateCopy)
+		}
+		goal.DueDate = *overrides.DueDate
+	}
+	if len(overrides.StepDueOffsets) > len(goal.Steps) {
+		return fmt.Errorf("%w: %d step due offsets given for %d steps", ErrInvalidTemplateCopy, len(overrides.StepDueOffsets), len(goal.Steps))
+	}
+	for i, offset := range overrides.StepDueOffsets {
+		if offset != 0 {
+			goal.Steps[i].DueDate = now.Add(time.Duration(offset))
+		}
+	}
+	return validateStepDueDates(goal, nil, now)
+}
+
+// recordCopy stores the copy event and bumps the template's copy count when
+// the copy comes from a verified account at least minCountedAccountAge old.
+// Owners copying their own template are never counted.
+func (s *TemplateService) recordCopy(ctx context.Context, template *models.GoalTemplate, userID, goalID primitive.ObjectID) {
+	counted := false
+	if template.UserID != userID {
+		if user, err := s.userRepo.GetUserByID(ctx, userID); err == nil {
+			counted = user.IsVerified && time.Since(user.CreatedAt) >= minCountedAccountAge
+		}
+	}
+
+	event := &models.TemplateCopy{TemplateID: template.ID, UserID: userID, GoalID: goalID, Counted: counted}
+	if err := s.copyRepo.RecordCopy(ctx, event); err != nil {
+		logrus.WithError(err).Warnf("Failed to record copy of template %s", template.ID.Hex())
+	}
+	if counted {
+		if err := s.repo.IncrementCopyCount(ctx, template.ID); err != nil {
+			logrus.WithError(err).Warnf("Failed to count copy of template %s", template.ID.Hex())
+		}
+	}
+}
+
+// trendingWindow is how far back trending templates are ranked.
+const trendingWindow = 7 * 24 * time.Hour
+
+// GetTrendingTemplates returns public templates ranked by how many distinct
+// users copied them in the last week.
+func (s *TemplateService) GetTrendingTemplates(ctx context.Context, limit int64) ([]models.GoalTemplate, error) {
+	// Fetch extra entries since private templates are filtered out below
+	ranked, err := s.copyRepo.GetTrending(ctx, time.Now().Add(-trendingWindow), limit*2)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]models.GoalTemplate, 0, limit)
+	for _, entry := range ranked {
+		template, err := s.repo.GetTemplateByID(ctx, entry.TemplateID)
+		if err != nil || !template.Public {
+			continue
+		}
+		templates = append(templates, *template)
+		if int64(len(templates)) == limit {
+			break
+		}
+	}
+	return templates, nil
+}
+
+// GetHeavyCopiers lists users who copied at least minCopies templates in the
+// given period, for admins to review.
+func (s *TemplateService) GetHeavyCopiers(ctx context.Context, period time.Duration, minCopies, limit int64) ([]models.TemplateCopier, error) {
+	return s.copyRepo.GetHeavyCopiers(ctx, time.Now().Add(-period), minCopies, limit)
+}
+
+// notifyTemplateCopied tells the owner of a public template that someone
+// copied it, at most once per template per templateCopiedInterval.
+func (s *TemplateService) notifyTemplateCopied(ctx context.Context, template *models.GoalTemplate, copiedBy primitive.ObjectID) {
+	if !template.Public || template.UserID == copiedBy {
+		return
+	}
+	if s.notificationService.IsTypeMuted(ctx, template.UserID, "template_copied") {
+		return
+	}
+	if s.notificationService.SentForTargetWithin(ctx, template.UserID, "template_copied", template.ID, templateCopiedInterval) {
+		return
+	}
+
+	templateID := template.ID
+	message := fmt.Sprintf("Someone started a goal from your template \"%s\".", template.Title)
+	if err := s.notificationService.CreateNotification(ctx, template.UserID, "template_copied", "📋 Your template was copied", message, &templateID); err != nil {
+		logrus.WithError(err).Warnf("Failed to notify owner of template %s", template.ID.Hex())
+	}
+}
+
+// Errors returned when turning a goal into a template.
+var (
+	ErrTemplateGoalNotFound  = errors.New("goal not found")
+	ErrTemplateGoalForbidden = errors.New("forbidden: only the goal owner can turn it into a template")
+	ErrTemplateGoalNoSteps   = errors.New("goal has no steps to turn into a template")
+)
+
+// TemplateOverrides replaces the values a template would otherwise take from
+// the goal it is made from. Nil fields keep the goal's value.
+type TemplateOverrides struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Public      *bool   `json:"public"`
+}
+
+// CreateTemplateFromGoal publishes the structure of one of the user's goals
+// as a template. Completion flags, notes and due dates are left behind. The
+// template is private unless overrides make it public.
+func (s *TemplateService) CreateTemplateFromGoal(ctx context.Context, goalID, userID primitive.ObjectID, overrides TemplateOverrides) (*models.GoalTemplate, error) {
+	goal, err := s.goalRepo.GetGoalByID(ctx, goalID)
+	if err != nil || !goal.DeletedAt.IsZero() {
+		return nil, ErrTemplateGoalNotFound
+	}
+	if goal.UserID != userID {
+		return nil, ErrTemplateGoalForbidden
+	}
+	if len(goal.Steps) == 0 {
+		return nil, ErrTemplateGoalNoSteps
+	}
+
+	template := &models.GoalTemplate{
+		Title:       goal.Name,
+		Description: goal.Description,
+		Steps:       goalStepsToTemplateSteps(goal.Steps),
+		Category:    goal.Category,
+		Tags:        goal.Tags,
+		UserID:      userID,
+	}
+	if overrides.Title != nil {
+		template.Title = strings.TrimSpace(*overrides.Title)
+	}
+	if overrides.Description != nil {
+		template.Description = *overrides.Description
+	}
+	if overrides.Public != nil {
+		template.Public = *overrides.Public
+	}
+	return s.CreateTemplate(ctx, template)
+}
+
+// goalStepsToTemplateSteps keeps only the names of a goal's steps and substeps.
+func goalStepsToTemplateSteps(steps []models.Step) []models.TemplateStep {
+	tmplSteps := make([]models.TemplateStep, 0, len(steps))
+	for _, step := range steps {
+		substeps := make([]models.TemplateSubstep, 0, len(step.Substeps))
+		for _, sub := range step.Substeps {
+			substeps = append(substeps, models.TemplateSubstep{Title: sub.Title})
+		}
+		tmplSteps = append(tmplSteps, models.TemplateStep{
+			Name:     step.Name,
+			Substeps: substeps,
+		})
+	}
+	return tmplSteps
+}
+
+// templateStepsToGoalSteps turns template steps into fresh, not yet done goal steps.
+func templateStepsToGoalSteps(tmplSteps []models.TemplateStep) []models.Step {
 	var steps []models.Step
-	for _, tmplStep := range template.Steps {
+	for _, tmplStep := range tmplSteps {
 		var substeps []models.Substep
 		for _, tmplSub := range tmplStep.Substeps {
 			substeps = append(substeps, models.Substep{
@@ -70,29 +355,73 @@ func (s *TemplateService) CopyTemplateToGoal(ctx context.Context, templateID str
 			Completed: false,
 		})
 	}
+	return steps
+}
 
-	goal := &models.Goal{
-		Name:        template.Title,
-		Description: template.Description,
-		Steps:       steps, // Previously converted from []string
-		Category:    template.Category,
-		UserID:      userID,
-		Status:      "in_progress",
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+// Page size limits for template listings.
+const (
+	DefaultTemplatesLimit int64 = 20
+	MaxTemplatesLimit     int64 = 100
+)
+
+// TemplatePage is one page of templates together with the total number of matches.
+type TemplatePage struct {
+	Items  []models.GoalTemplate `json:"items"`
+	Total  int64                 `json:"total"`
+	Limit  int64                 `json:"limit"`
+	Offset int64                 `json:"offset"`
+}
+
+// templatePageBounds applies the defaults and caps to offset and limit.
+func templatePageBounds(offset, limit int64) (int64, int64) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = DefaultTemplatesLimit
+	}
+	if limit > MaxTemplatesLimit {
+		limit = MaxTemplatesLimit
 	}
+	return offset, limit
+}
 
-	return s.goalRepo.CreateGoal(ctx, goal)
+// GetPublicTags returns the distinct tags across public templates, sorted.
+func (s *TemplateService) GetPublicTags(ctx context.Context) ([]string, error) {
+	tags, err := s.repo.GetPublicTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tags)
+	return tags, nil
 }
 
-func (s *TemplateService) GetTemplatesByUser(ctx context.Context, userID primitive.ObjectID) ([]models.GoalTemplate, error) {
-	return s.repo.GetTemplatesByUser(ctx, userID)
+// GetTemplatesByUser returns one page of a user's templates, public or not.
+func (s *TemplateService) GetTemplatesByUser(ctx context.Context, userID primitive.ObjectID, offset, limit int64) (*TemplatePage, error) {
+	offset, limit = templatePageBounds(offset, limit)
+	templates, total, err := s.repo.GetTemplatesByUser(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePage{Items: templates, Total: total, Limit: limit, Offset: offset}, nil
 }
 
-func (s *TemplateService) GetPublicTemplates(ctx context.Context) ([]models.GoalTemplate, error) {
-	return s.repo.GetPublicTemplates(ctx)
+// GetPublicTemplates returns one page of the public templates matching the filter.
+func (s *TemplateService) GetPublicTemplates(ctx context.Context, filter repository.TemplateFilter, offset, limit int64) (*TemplatePage, error) {
+	offset, limit = templatePageBounds(offset, limit)
+	templates, total, err := s.repo.GetPublicTemplates(ctx, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePage{Items: templates, Total: total, Limit: limit, Offset: offset}, nil
 }
 
-func (s *TemplateService) GetPublicTemplatesByUser(ctx context.Context, userID primitive.ObjectID) ([]models.GoalTemplate, error) {
-	return s.repo.GetPublicTemplatesByUser(ctx, userID)
+// GetPublicTemplatesByUser is GetPublicTemplates limited to one user's templates.
+func (s *TemplateService) GetPublicTemplatesByUser(ctx context.Context, userID primitive.ObjectID, filter repository.TemplateFilter, offset, limit int64) (*TemplatePage, error) {
+	offset, limit = templatePageBounds(offset, limit)
+	templates, total, err := s.repo.GetPublicTemplatesByUser(ctx, userID, filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplatePage{Items: templates, Total: total, Limit: limit, Offset: offset}, nil
 }
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned when updating templates and reading their versions.
+var (
+	ErrTemplateNotFound        = errors.New("template not found")
+	ErrTemplateForbidden       = errors.New("forbidden: only the owner can update this template")
+	ErrInvalidTemplate         = errors.New("invalid template")
+	ErrTemplateVersionConflict = errors.New("template was updated by someone else")
+	ErrTemplateVersionNotFound = errors.New("template version not found")
+)
+
+// TemplateUpdate changes the content of a template. Nil fields keep their
+// current value.
+type TemplateUpdate struct {
+	Title       *string                `json:"title"`
+	Description *string                `json:"description"`
+	Steps       *[]models.TemplateStep `json:"steps"`
+	Category    *string                `json:"category"`
+	Tags        *[]string              `json:"tags"`
+	Public      *bool                  `json:"public"`
+}
+
+// UpdateTemplate applies an update from the template's owner. The previous
+// content is kept in the template's version history and the version number
+// goes up by one.
+func (s *TemplateService) UpdateTemplate(ctx context.Context, id, userID primitive.ObjectID, update TemplateUpdate) (*models.GoalTemplate, error) {
+	template, err := s.repo.GetTemplateByID(ctx, id)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	if template.UserID != userID {
+		return nil, ErrTemplateForbidden
+	}
+	previous := templateVersionOf(template)
+
+	if update.Title != nil {
+		template.Title = strings.TrimSpace(*update.Title)
+	}
+	if update.Description != nil {
+		template.Description = *update.Description
+	}
+	if update.Steps != nil {
+		template.Steps = *update.Steps
+	}
+	if update.Category != nil {
+		template.Category = *update.Category
+	}
+	if update.Tags != nil {
+		template.Tags = *update.Tags
+	}
+	if update.Public != nil {
+		template.Public = *update.Public
+	}
+
+	if template.Title == "" || len(template.Steps) == 0 {
+		return nil, fmt.Errorf("%w: template must have a title and at least one step", ErrInvalidTemplate)
+	}
+	if update.Category != nil && template.Category != "" && !s.categories.IsValidCategory(ctx, userID, template.Category) {
+		return nil, ErrInvalidCategory
+	}
+	tags, err := NormalizeTags(template.Tags)
+	if err != nil {
+		return nil, err
+	}
+	template.Tags = tags
+
+	if err := s.versionRepo.SaveVersion(ctx, previous); err != nil {
+		return nil, err
+	}
+	updated, err := s.repo.UpdateTemplate(ctx, template, previous.Version)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrTemplateVersionConflict
+	}
+	return template, nil
+}
+
+// GetTemplateVersions lists every version of a template the user can see,
+// newest first. The first entry is the current version.
+func (s *TemplateService) GetTemplateVersions(ctx context.Context, id, userID primitive.ObjectID) ([]models.TemplateVersion, error) {
+	template, err := s.repo.GetTemplateByID(ctx, id)
+	if err != nil || !templateVisibleTo(template, userID) {
+		return nil, ErrTemplateNotFound
+	}
+
+	past, err := s.versionRepo.GetVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return append([]models.TemplateVersion{*templateVersionOf(template)}, past...), nil
+}
+
+// GetTemplateVersion returns one version of a template the user can see.
+func (s *TemplateService) GetTemplateVersion(ctx context.Context, id, userID primitive.ObjectID, version int) (*models.TemplateVersion, error) {
+	template, err := s.repo.GetTemplateByID(ctx, id)
+	if err != nil || !templateVisibleTo(template, userID) {
+		return nil, ErrTemplateNotFound
+	}
+	return s.templateVersion(ctx, template, version)
+}
+
+// templateVersion returns the given version of a template, reading past
+// versions from the history.
+func (s *TemplateService) templateVersion(ctx context.Context, template *models.GoalTemplate, version int) (*models.TemplateVersion, error) {
+	current := templateVersionOf(template)
+	if version == current.Version {
+		return current, nil
+	}
+	if version < 1 || version > current.Version {
+		return nil, ErrTemplateVersionNotFound
+	}
+	past, err := s.versionRepo.GetVersion(ctx, template.ID, version)
+	if err != nil {
+		return nil, ErrTemplateVersionNotFound
+	}
+	return past, nil
+}
+
+// templateVersionOf snapshots the current content of a template. Templates
+// that predate versioning count as version 1.
+func templateVersionOf(template *models.GoalTemplate) *models.TemplateVersion {
+	version := &models.TemplateVersion{
+		TemplateID:  template.ID,
+		Version:     template.Version,
+		Title:       template.Title,
+		Description: template.Description,
+		Steps:       template.Steps,
+		Category:    template.Category,
+		Tags:        template.Tags,
+		Public:      template.Public,
+		CreatedAt:   template.UpdatedAt,
+	}
+	if version.Version == 0 {
+		version.Version = 1
+	}
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = template.CreatedAt
+	}
+	return version
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ErrAlreadyVerified is returned when verifying a user whose email is already verified.
+var ErrAlreadyVerified = errors.New("user is already verified")
+
+// ErrInvalidRole is returned for roles other than RoleUser and RoleAdmin.
+var ErrInvalidRole = errors.New("invalid role")
+
+// FindUser looks a user up by ID or, when ref is not an ObjectID, by email.
+func (s *UserService) FindUser(ctx context.Context, ref string) (*models.User, error) {
+	if id, err := primitive.ObjectIDFromHex(ref); err == nil {
+		user, err := s.repo.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("user not found: %v", err)
+		}
+		return user, nil
+	}
+	user, err := s.repo.GetUserByEmail(ctx, strings.TrimSpace(ref))
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+// VerifyUser marks the user's email as verified without the emailed token,
+// for support cases where the email never arrived.
+func (s *UserService) VerifyUser(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+	if user.IsVerified {
+		return ErrAlreadyVerified
+	}
+	return s.markVerified(ctx, user)
+}
+
+// ResendVerification replaces the user's verification token and emails
+// them a new link. The old link stops working.
+func (s *UserService) ResendVerification(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+	if user.IsVerified {
+		return ErrAlreadyVerified
+	}
+
+	token := uuid.NewString()
+	update := map[string]interface{}{
+		"verify_token": token,
+		"updated_at":   time.Now(),
+	}
+	if _, err := s.repo.UpdateUser(ctx, user.ID, update); err != nil {
+		return fmt.Errorf("failed to store verification token: %v", err)
+	}
+	return sendVerificationEmail(user.Email, token)
+}
+
+// SetRole changes the user's role. Tokens issued before the change keep the
+// old role until they expire.
+func (s *UserService) SetRole(ctx context.Context, userID primitive.ObjectID, role string) (*models.User, error) {
+	if role != RoleUser && role != RoleAdmin {
+		return nil, ErrInvalidRole
+	}
+	update := map[string]interface{}{
+		"role":       role,
+		"updated_at": time.Now(),
+	}
+	user, err := s.repo.UpdateUser(ctx, userID, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %v", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID": userID.Hex(),
+		"role":   role,
+	}).Info("User role changed")
+	return user, nil
+}
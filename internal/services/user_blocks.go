@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned when blocking users.
+var (
+	ErrCannotBlockSelf     = errors.New("cannot block yourself")
+	ErrBlockTargetNotFound = errors.New("user not found")
+)
+
+// BlockUser stops targetID from sending callerID friend requests and ends
+// any friendship between them. Their typing indicators and live group chat
+// messages no longer reach callerID either. The block only goes one way:
+// targetID's own blocked list is left alone.
+func (s *UserService) BlockUser(ctx context.Context, callerID, targetID primitive.ObjectID) error {
+	if callerID == targetID {
+		return ErrCannotBlockSelf
+	}
+	if _, err := s.repo.GetUserByID(ctx, targetID); err != nil {
+		return ErrBlockTargetNotFound
+	}
+
+	if err := s.repo.BlockUser(ctx, callerID, targetID); err != nil {
+		return err
+	}
+	if err := s.repo.RemoveFriend(ctx, callerID, targetID); err != nil {
+		return err
+	}
+	// Presence must stop reaching the blocked user straight away
+	if s.hub != nil {
+		s.hub.InvalidateFriends(callerID.Hex())
+		s.hub.InvalidateFriends(targetID.Hex())
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID":   callerID.Hex(),
+		"targetID": targetID.Hex(),
+	}).Info("User blocked")
+	return nil
+}
+
+// UnblockUser removes targetID from callerID's blocked list. Friendships
+// ended by the block are not restored.
+func (s *UserService) UnblockUser(ctx context.Context, callerID, targetID primitive.ObjectID) error {
+	if err := s.repo.UnblockUser(ctx, callerID, targetID); err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID":   callerID.Hex(),
+		"targetID": targetID.Hex(),
+	}).Info("User unblocked")
+	return nil
+}
+
+// GetBlockedUsers returns the public profiles of the users callerID blocked.
+// Blocked accounts that no longer exist are left out.
+func (s *UserService) GetBlockedUsers(ctx context.Context, callerID primitive.ObjectID) ([]models.PublicUser, error) {
+	user, err := s.repo.GetUserByID(ctx, callerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	if len(user.BlockedUsers) == 0 {
+		return []models.PublicUser{}, nil
+	}
+	return s.repo.GetPublicProfilesByIDs(ctx, user.BlockedUsers)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateCalendarToken generates the secret for the user's calendar feed URL.
+// Generating a new token invalidates the previous URL.
+func (s *UserService) CreateCalendarToken(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	token := uuid.NewString()
+	if _, err := s.repo.UpdateUser(ctx, userID, map[string]interface{}{"calendar_token": token}); err != nil {
+		return "", fmt.Errorf("failed to create calendar token: %v", err)
+	}
+
+	logrus.WithField("userID", userID.Hex()).Info("Calendar feed token created")
+	return token, nil
+}
+
+// RevokeCalendarToken removes the user's calendar token so the feed URL stops working.
+func (s *UserService) RevokeCalendarToken(ctx context.Context, userID primitive.ObjectID) error {
+	if _, err := s.repo.UpdateUser(ctx, userID, map[string]interface{}{"calendar_token": ""}); err != nil {
+		return fmt.Errorf("failed to revoke calendar token: %v", err)
+	}
+
+	logrus.WithField("userID", userID.Hex()).Info("Calendar feed token revoked")
+	return nil
+}
+
+// GetUserByCalendarToken returns the user whose calendar feed uses token.
+func (s *UserService) GetUserByCalendarToken(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, mongo.ErrNoDocuments
+	}
+	return s.repo.GetUserByCalendarToken(ctx, token)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/email"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// emailChangeTokenTTL is how long the link sent to a new address stays valid.
+const emailChangeTokenTTL = 24 * time.Hour
+
+// Errors returned by the email change flow.
+var (
+	ErrInvalidEmail           = errors.New("invalid email format")
+	ErrEmailTaken             = errors.New("email already in use")
+	ErrInvalidEmailChangeLink = errors.New("invalid or expired email change link")
+)
+
+// RequestEmailChange stores newEmail as the user's pending email and sends a
+// confirmation link to it. The current email stays active until the link is used.
+func (s *UserService) RequestEmailChange(ctx context.Context, userID primitive.ObjectID, newEmail string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if !emailPattern.MatchString(newEmail) {
+		return ErrInvalidEmail
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %v", err)
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return fmt.Errorf("new email is the same as the current one")
+	}
+	if existing, _ := s.repo.GetUserByEmail(ctx, newEmail); existing != nil {
+		return ErrEmailTaken
+	}
+
+	token := uuid.NewString()
+	update := map[string]interface{}{
+		"pending_email":           newEmail,
+		"pending_email_token":     token,
+		"pending_email_token_exp": time.Now().Add(emailChangeTokenTTL),
+		"updated_at":              time.Now(),
+	}
+	if _, err := s.repo.UpdateUser(ctx, userID, update); err != nil {
+		return fmt.Errorf("failed to save pending email: %v", err)
+	}
+
+	link := fmt.Sprintf("http://localhost:8080/users/verify-email-change?token=%s", token)
+	body := fmt.Sprintf("Please confirm your new email address for Achievement Manager by clicking the link below:\n%s\n\nIf you did not request this change, you can ignore this email.", link)
+	if err := email.SendEmail(newEmail, "Confirm your new email address", body); err != nil {
+		logrus.WithError(err).Error("Failed to send email change verification")
+		return fmt.Errorf("failed to send verification email")
+	}
+
+	logrus.WithField("userID", userID.Hex()).Info("Email change requested")
+	return nil
+}
+
+// ConfirmEmailChange swaps in the pending email for the token, marks it as
+// verified and alerts the previous address.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrInvalidEmailChangeLink
+	}
+
+	user, err := s.repo.GetUserByPendingEmailToken(ctx, token)
+	if err != nil || user.PendingEmail == "" || time.Now().After(user.PendingEmailTokenExp) {
+		return nil, ErrInvalidEmailChangeLink
+	}
+
+	// The address may have been registered since the change was requested
+	if existing, _ := s.repo.GetUserByEmail(ctx, user.PendingEmail); existing != nil && existing.ID != user.ID {
+		return nil, ErrEmailTaken
+	}
+
+	oldEmail := user.Email
+	update := map[string]interface{}{
+		"email":                   user.PendingEmail,
+		"is_verified":             true,
+		"verified_at":             time.Now(),
+		"pending_email":           "",
+		"pending_email_token":     "",
+		"pending_email_token_exp": time.Time{},
+		"updated_at":              time.Now(),
+	}
+	updated, err := s.repo.UpdateUser(ctx, user.ID, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change email: %v", err)
+	}
+
+	s.sendEmailChangedAlert(oldEmail, updated.Email, updated.Username)
+
+	logrus.WithField("userID", user.ID.Hex()).Info("Email changed")
+	return updated, nil
+}
+
+// sendEmailChangedAlert warns the previous address about the change. It is a
+// security notice, so it is sent even to users who opted out of emails.
+func (s *UserService) sendEmailChangedAlert(oldEmail, newEmail, username string) {
+	body := fmt.Sprintf("Hi %s,\n\nThe email address of your Achievement Manager account was changed to %s.\n\nIf you did not make this change, reset your password and contact support right away.", username, newEmail)
+	msg := email.Message{To: oldEmail, Subject: "Your email address was changed", Body: body}
+
+	if s.mailer != nil {
+		if err := s.mailer.Enqueue(msg); err == nil {
+			return
+		}
+	}
+	if err := email.SendEmail(msg.To, msg.Subject, msg.Body); err != nil {
+		logrus.WithError(err).Warn("Failed to send email change alert")
+	}
+}
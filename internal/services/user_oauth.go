@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthProviderGoogle marks users who sign in with their Google account.
+const AuthProviderGoogle = "google"
+
+// LoginOrRegisterWithGoogle returns the user for a Google account, creating
+// one on first login. An existing email/password account with the same email
+// is linked to Google, but only if its email was verified; otherwise whoever
+// registered the address first could be locked out or impersonated.
+func (s *UserService) LoginOrRegisterWithGoogle(ctx context.Context, email, googleID, name string) (*models.User, error) {
+	if user, err := s.repo.GetUserByProvider(ctx, AuthProviderGoogle, googleID); err == nil {
+		return user, nil
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, fmt.Errorf("google account has no email address")
+	}
+
+	if existing, _ := s.repo.GetUserByEmail(ctx, email); existing != nil {
+		if !existing.IsVerified {
+			logrus.WithField("email", email).Warn("Refusing to link Google login to unverified account")
+			return nil, fmt.Errorf("an account with this email exists but is not verified")
+		}
+
+		update := map[string]interface{}{
+			"auth_provider": AuthProviderGoogle,
+			"provider_id":   googleID,
+			"updated_at":    time.Now(),
+		}
+		linked, err := s.repo.UpdateUser(ctx, existing.ID, update)
+		if err != nil {
+			return nil, fmt.Errorf("failed to link google account: %v", err)
+		}
+		logrus.WithField("userID", existing.ID.Hex()).Info("Linked existing user to Google login")
+		return linked, nil
+	}
+
+	username := strings.TrimSpace(name)
+	if username == "" {
+		username = strings.SplitN(email, "@", 2)[0]
+	}
+
+	// Google already verified the address, so no verification email is sent
+	now := time.Now()
+	user, err := s.repo.CreateUser(ctx, &models.User{
+		Username:     username,
+		Email:        email,
+		Role:         "user",
+		IsVerified:   true,
+		VerifiedAt:   now,
+		AuthProvider: AuthProviderGoogle,
+		ProviderID:   googleID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register google user: %v", err)
+	}
+
+	logrus.WithField("userID", user.ID.Hex()).Info("Registered user through Google login")
+	s.createDefaultPreferences(ctx, user.ID)
+	s.queueWelcomeEmail(ctx, user)
+	return user, nil
+}
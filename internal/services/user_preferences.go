@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidPreferences is returned when saved preferences have an unknown
+// timezone, a malformed language tag or invalid quiet hours.
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+// languagePattern accepts simple language tags such as "en" or "pt-BR".
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// GetPreferences returns the user's preferences, falling back to the
+// defaults when none were saved.
+func (s *UserService) GetPreferences(ctx context.Context, userID primitive.ObjectID) (*models.UserPreferences, error) {
+	prefs, err := s.preferences.GetByUserID(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		defaults := models.DefaultUserPreferences(userID)
+		return &defaults, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %v", err)
+	}
+	return prefs, nil
+}
+
+// SavePreferences validates and upserts the user's preferences.
+func (s *UserService) SavePreferences(ctx context.Context, prefs *models.UserPreferences) error {
+	if _, err := time.LoadLocation(prefs.Timezone); err != nil || prefs.Timezone == "" {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidPreferences, prefs.Timezone)
+	}
+	if !languagePattern.MatchString(prefs.Language) {
+		return fmt.Errorf("%w: invalid language %q", ErrInvalidPreferences, prefs.Language)
+	}
+	if err := validateQuietHours(prefs.QuietHoursStart, prefs.QuietHoursEnd); err != nil {
+		return err
+	}
+	return s.preferences.Upsert(ctx, prefs)
+}
+
+// createDefaultPreferences stores the default preferences for a new user.
+// Failing to do so is not fatal since missing preferences fall back to the defaults.
+func (s *UserService) createDefaultPreferences(ctx context.Context, userID primitive.ObjectID) {
+	defaults := models.DefaultUserPreferences(userID)
+	if err := s.preferences.Upsert(ctx, &defaults); err != nil {
+		logrus.WithError(err).WithField("userID", userID.Hex()).Warn("Failed to create default preferences")
+	}
+}
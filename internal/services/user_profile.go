@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Errors returned when reading or configuring public profiles.
+var (
+	ErrProfileNotFound          = errors.New("profile not found")
+	ErrProfileNotVisible        = errors.New("this profile is not visible to you")
+	ErrInvalidProfileVisibility = errors.New("profile_visibility must be public, friends or private")
+)
+
+// isProfileVisibility reports whether v is an accepted profile_visibility value.
+func isProfileVisibility(v interface{}) bool {
+	switch v {
+	case models.ProfileVisibilityPublic, models.ProfileVisibilityFriends, models.ProfileVisibilityPrivate:
+		return true
+	}
+	return false
+}
+
+// profileVisibleTo reports whether callerID may see user's profile. callerID
+// is nil for anonymous requests.
+func profileVisibleTo(user *models.User, callerID *primitive.ObjectID) bool {
+	if callerID != nil && *callerID == user.ID {
+		return true
+	}
+	switch user.ProfileVisibility {
+	case models.ProfileVisibilityPrivate:
+		return false
+	case models.ProfileVisibilityFriends:
+		if callerID == nil {
+			return false
+		}
+		for _, id := range user.Friends {
+			if id == *callerID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// GetPublicProfile returns targetID's profile if callerID may see it. callerID
+// is nil for anonymous requests. Users can always see their own profile;
+// otherwise "friends" profiles need the caller in the friend list and
+// "private" profiles are hidden.
+func (s *UserService) GetPublicProfile(ctx context.Context, targetID primitive.ObjectID, callerID *primitive.ObjectID) (*models.PublicUserProfile, error) {
+	user, err := s.repo.GetUserByID(ctx, targetID)
+	if err != nil {
+		return nil, ErrProfileNotFound
+	}
+
+	if !profileVisibleTo(user, callerID) {
+		return nil, ErrProfileNotVisible
+	}
+
+	goalCount, err := s.goalRepo.CountGoalsOutsideTrash(ctx, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile: %v", err)
+	}
+
+	return &models.PublicUserProfile{
+		PublicUser: models.PublicUser{
+			ID:          user.ID,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			AvatarURL:   user.AvatarURL,
+		},
+		Bio:          user.Bio,
+		GoalCount:    goalCount,
+		FriendsCount: len(user.Friends),
+		JoinedAt:     user.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User resolution limits.
+const (
+	MaxResolveIDs       = 100
+	resolveCacheTTL     = time.Minute
+	resolveRateLimit    = 30 // requests per user per resolveRateWindow
+	resolveRateWindow   = time.Minute
+	resolveCacheMaxSize = 10000
+)
+
+var (
+	ErrTooManyResolveIDs = fmt.Errorf("at most %d IDs can be resolved at once", MaxResolveIDs)
+	ErrRateLimited       = errors.New("rate limit exceeded, try again later")
+)
+
+// ResolvedUsers is the result of ResolveUsers. Missing holds the requested
+// IDs that are malformed or belong to no user.
+type ResolvedUsers struct {
+	Users   []models.PublicUser `json:"users"`
+	Missing []string            `json:"missing"`
+}
+
+// ResolveUsers maps user IDs to public profiles, regardless of friendship.
+// Profiles are cached briefly since clients resolve the same IDs repeatedly,
+// and each caller is limited to resolveRateLimit calls per minute.
+func (s *UserService) ResolveUsers(ctx context.Context, callerID string, ids []string) (*ResolvedUsers, error) {
+	if len(ids) > MaxResolveIDs {
+		return nil, ErrTooManyResolveIDs
+	}
+	now := time.Now()
+	if !s.resolveLimiter.allow(callerID, now) {
+		return nil, ErrRateLimited
+	}
+
+	result := &ResolvedUsers{Users: []models.PublicUser{}, Missing: []string{}}
+	order := make([]primitive.ObjectID, 0, len(ids))
+	profiles := make(map[primitive.ObjectID]models.PublicUser, len(ids))
+	var toFetch []primitive.ObjectID
+
+	seen := make(map[string]bool, len(ids))
+	for _, raw := range ids {
+		if seen[raw] {
+			continue
+		}
+		seen[raw] = true
+
+		id, err := primitive.ObjectIDFromHex(raw)
+		if err != nil {
+			result.Missing = append(result.Missing, raw)
+			continue
+		}
+		order = append(order, id)
+		if profile, ok := s.profileCache.get(id, now); ok {
+			profiles[id] = profile
+		} else {
+			toFetch = append(toFetch, id)
+		}
+	}
+
+	if len(toFetch) > 0 {
+		fetched, err := s.repo.GetPublicProfilesByIDs(ctx, toFetch)
+		if err != nil {
+			return nil, err
+		}
+		for _, profile := range fetched {
+			profiles[profile.ID] = profile
+			s.profileCache.put(profile, now)
+		}
+	}
+
+	for _, id := range order {
+		if profile, ok := profiles[id]; ok {
+			result.Users = append(result.Users, profile)
+		} else {
+			result.Missing = append(result.Missing, id.Hex())
+		}
+	}
+	return result, nil
+}
+
+// profileCache keeps recently resolved public profiles in memory.
+type profileCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[primitive.ObjectID]cachedProfile
+}
+
+type cachedProfile struct {
+	profile   models.PublicUser
+	fetchedAt time.Time
+}
+
+func newProfileCache(ttl time.Duration, maxSize int) *profileCache {
+	return &profileCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[primitive.ObjectID]cachedProfile),
+	}
+}
+
+func (c *profileCache) get(id primitive.ObjectID, now time.Time) (models.PublicUser, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok || now.Sub(entry.fetchedAt) >= c.ttl {
+		return models.PublicUser{}, false
+	}
+	return entry.profile, true
+}
+
+func (c *profileCache) put(profile models.PublicUser, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop stale entries once the cache is full rather than growing forever
+	if len(c.entries) >= c.maxSize {
+		for id, entry := range c.entries {
+			if now.Sub(entry.fetchedAt) >= c.ttl {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= c.maxSize {
+			return
+		}
+	}
+	c.entries[profile.ID] = cachedProfile{profile: profile, fetchedAt: now}
+}
+
+// windowLimiter allows a fixed number of calls per key in each time window.
+type windowLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]limiterWindow
+}
+
+type limiterWindow struct {
+	start time.Time
+	count int
+}
+
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]limiterWindow),
+	}
+}
+
+// allow records a call for key and reports whether it is within the limit.
+func (l *windowLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.windows[key]
+	if now.Sub(w.start) >= l.window {
+		// Forget keys whose window has passed so the map stays small
+		for k, other := range l.windows {
+			if now.Sub(other.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		w = limiterWindow{start: now}
+	}
+	if w.count >= l.limit {
+		l.windows[key] = w
+		return false
+	}
+	w.count++
+	l.windows[key] = w
+	return true
+}
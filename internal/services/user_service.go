@@ -8,6 +8,7 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/email"
@@ -16,15 +17,43 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// emailPattern is the accepted shape of an email address.
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
 // UserService encapsulates the business logic for user operations.
 type UserService struct {
-	repo *repository.UserRepository
+	repo          *repository.UserRepository
+	goalRepo      *repository.GoalRepository
+	activityRepo  *repository.ActivityRepository
+	preferences   *repository.PreferencesRepository
+	refreshTokens *repository.RefreshTokenRepository
+	blacklist     *repository.TokenBlacklistRepository
+	mailer        *email.Mailer
+	notifications *NotificationService
+	hub           *hub.Hub // may be nil, e.g. in the admin CLI
+	lastActive    *lastActiveTracker
+
+	profileCache   *profileCache
+	resolveLimiter *windowLimiter
 }
 
-// NewUserService creates a new instance of UserService.
-func NewUserService(repo *repository.UserRepository) *UserService {
+// NewUserService creates a new instance of UserService. Each user's
+// last_active_at is written at most once per lastActiveInterval.
+func NewUserService(repo *repository.UserRepository, goalRepo *repository.GoalRepository, activityRepo *repository.ActivityRepository, preferences *repository.PreferencesRepository, refreshTokens *repository.RefreshTokenRepository, blacklist *repository.TokenBlacklistRepository, mailer *email.Mailer, notifications *NotificationService, wsHub *hub.Hub, lastActiveInterval time.Duration) *UserService {
 	return &UserService{
-		repo: repo,
+		repo:          repo,
+		goalRepo:      goalRepo,
+		activityRepo:  activityRepo,
+		preferences:   preferences,
+		refreshTokens: refreshTokens,
+		blacklist:     blacklist,
+		mailer:        mailer,
+		notifications: notifications,
+		hub:           wsHub,
+		lastActive:    newLastActiveTracker(lastActiveInterval),
+
+		profileCache:   newProfileCache(resolveCacheTTL, resolveCacheMaxSize),
+		resolveLimiter: newWindowLimiter(resolveRateLimit, resolveRateWindow),
 	}
 }
 
@@ -32,14 +61,12 @@ func NewUserService(repo *repository.UserRepository) *UserService {
 func (s *UserService) RegisterUser(ctx context.Context, user *models.User) (*models.User, error) {
 	logrus.Info("Registering new user")
 
-	var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
-
 	if user.Email == "" || user.Username == "" || user.HashedPassword == "" {
 		logrus.Warn("Missing required fields during registration")
 		return nil, fmt.Errorf("missing required user fields")
 	}
 
-	if !emailRegex.MatchString(user.Email) {
+	if !emailPattern.MatchString(user.Email) {
 		logrus.WithField("email", user.Email).Warn("Invalid email format during registration")
 		return nil, fmt.Errorf("invalid email format")
 	}
@@ -76,19 +103,12 @@ func (s *UserService) RegisterUser(ctx context.Context, user *models.User) (*mod
 		logrus.WithError(err).Error("User registration failed")
 		return nil, fmt.Errorf("failed to register user: %v", err)
 	}
+	s.createDefaultPreferences(ctx, createdUser.ID)
 
-	verificationLink := fmt.Sprintf("http://localhost:8080/users/verify?token=%s", verificationToken)
-
-	emailBody := fmt.Sprintf("Welcome to Achievement Manager!\n\nPlease verify your email by clicking the link below:\n%s", verificationLink)
-
-	err = email.SendEmail(user.Email, "Email Verification", emailBody)
-	if err != nil {
-		logrus.WithError(err).Error("Failed to send verification email")
-		return nil, fmt.Errorf("failed to send verification email")
+	if err := sendVerificationEmail(user.Email, verificationToken); err != nil {
+		return nil, err
 	}
 
-	logrus.Infof("Sent verification email to %s", user.Email)
-
 	logrus.WithFields(logrus.Fields{
 		"userID": createdUser.ID.Hex(),
 		"role":   createdUser.Role,
@@ -104,21 +124,70 @@ func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
 		return fmt.Errorf("invalid or expired verification token")
 	}
 
+	return s.markVerified(ctx, user)
+}
+
+// markVerified records that the user's email is verified and sends the welcome email.
+func (s *UserService) markVerified(ctx context.Context, user *models.User) error {
 	// Only update relevant fields
 	update := map[string]interface{}{
 		"is_verified":  true,
 		"verify_token": "",
+		"verified_at":  time.Now(),
 		"updated_at":   time.Now(),
 	}
 
-	_, err = s.repo.UpdateUser(ctx, user.ID, update)
+	_, err := s.repo.UpdateUser(ctx, user.ID, update)
 	if err != nil {
 		return fmt.Errorf("failed to update user verification status: %v", err)
 	}
 
+	s.queueWelcomeEmail(ctx, user)
+
 	return nil
 }
 
+// sendVerificationEmail sends the link that verifies the address.
+func sendVerificationEmail(to, token string) error {
+	verificationLink := fmt.Sprintf("http://localhost:8080/users/verify?token=%s", token)
+
+	emailBody := fmt.Sprintf("Welcome to Achievement Manager!\n\nPlease verify your email by clicking the link below:\n%s", verificationLink)
+
+	if err := email.SendEmail(to, "Email Verification", emailBody); err != nil {
+		logrus.WithError(err).Error("Failed to send verification email")
+		return fmt.Errorf("failed to send verification email")
+	}
+
+	logrus.Infof("Sent verification email to %s", to)
+	return nil
+}
+
+// queueWelcomeEmail hands the welcome email to the async mailer exactly once per user.
+func (s *UserService) queueWelcomeEmail(ctx context.Context, user *models.User) {
+	if user.EmailOptOut || s.mailer == nil {
+		return
+	}
+
+	first, err := s.repo.MarkWelcomeEmailSent(ctx, user.ID)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to flag welcome email")
+		return
+	}
+	if !first {
+		return
+	}
+
+	body, err := email.RenderWelcomeEmail(user.Username)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to render welcome email")
+		return
+	}
+
+	if err := s.mailer.Enqueue(email.Message{To: user.Email, Subject: "Welcome to Achievement Manager", Body: body, HTML: true}); err != nil {
+		logrus.WithError(err).Warn("Failed to queue welcome email")
+	}
+}
+
 func (s *UserService) RequestPasswordReset(ctx context.Context, userEmail string) error {
 	user, err := s.repo.GetUserByEmail(ctx, userEmail)
 	if err != nil {
@@ -181,6 +250,7 @@ func (s *UserService) ResetPassword(ctx context.Context, token, newPassword stri
 }
 
 // AuthenticateUser verifies the email and password and returns the user if credentials are valid.
+// For accounts with 2FA it returns the user along with ErrTOTPRequired.
 func (s *UserService) AuthenticateUser(ctx context.Context, email, password string) (*models.User, error) {
 	logrus.WithField("email", email).Info("Authenticating user")
 
@@ -202,7 +272,13 @@ func (s *UserService) AuthenticateUser(ctx context.Context, email, password stri
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
+	if user.TOTPEnabled {
+		logrus.WithField("userID", user.ID.Hex()).Info("Password accepted, waiting for TOTP code")
+		return user, ErrTOTPRequired
+	}
+
 	logrus.WithField("userID", user.ID.Hex()).Info("User authenticated successfully")
+	s.recordLogin(ctx, user.ID)
 	return user, nil
 }
 
@@ -236,6 +312,10 @@ func (s *UserService) UpdateUser(ctx context.Context, id string, updatedUser map
 		return nil, fmt.Errorf("invalid user ID: %v", err)
 	}
 
+	if visibility, ok := updatedUser["profile_visibility"]; ok && !isProfileVisibility(visibility) {
+		return nil, ErrInvalidProfileVisibility
+	}
+
 	updatedUser["updated_at"] = time.Now()
 
 	user, err := s.repo.UpdateUser(ctx, objID, updatedUser)
@@ -271,13 +351,29 @@ func (s *UserService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	return s.repo.GetAllUsers(ctx)
 }
 
+// UpdateLastActive records user activity. The write is skipped if the user's
+// timestamp was persisted recently; FlushLastActive picks it up later.
 func (s *UserService) UpdateLastActive(ctx context.Context, id primitive.ObjectID) error {
-	update := map[string]interface{}{
-		"last_active_at": time.Now(),
+	now := time.Now()
+	if !s.lastActive.touch(id, now) {
+		return nil
 	}
-	_, err := s.repo.UpdateUser(ctx, id, update)
+
+	err := s.repo.UpdateLastActive(ctx, id, now)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to update last active time")
+		s.lastActive.requeue(map[primitive.ObjectID]time.Time{id: now})
 	}
 	return err
 }
+
+// FlushLastActive writes all throttled activity in one batch. It runs
+// periodically and once more on shutdown.
+func (s *UserService) FlushLastActive(ctx context.Context) error {
+	batch := s.lastActive.drain(time.Now())
+	if err := s.repo.UpdateLastActiveBulk(ctx, batch); err != nil {
+		s.lastActive.requeue(batch)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+)
+
+func TestUpdateLastActiveWritesOncePerInterval(t *testing.T) {
+	svc := testutil.NewServices(t)
+	ctx := context.Background()
+	user := testutil.SeedUser(t, svc.Repositories, models.User{})
+
+	lastActive := func() time.Time {
+		t.Helper()
+		stored, err := svc.Users.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		return stored.LastActiveAt
+	}
+
+	if err := svc.User.UpdateLastActive(ctx, user.ID); err != nil {
+		t.Fatalf("UpdateLastActive: %v", err)
+	}
+	first := lastActive()
+	if first.IsZero() {
+		t.Fatal("first activity was not written")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if err := svc.User.UpdateLastActive(ctx, user.ID); err != nil {
+		t.Fatalf("UpdateLastActive: %v", err)
+	}
+	if got := lastActive(); !got.Equal(first) {
+		t.Errorf("last_active_at moved to %v within the write interval", got)
+	}
+
+	if err := svc.User.FlushLastActive(ctx); err != nil {
+		t.Fatalf("FlushLastActive: %v", err)
+	}
+	flushed := lastActive()
+	if !flushed.After(first) {
+		t.Errorf("last_active_at = %v after flush, want later than %v", flushed, first)
+	}
+
+	// Nothing is pending any more, so a second flush leaves it alone
+	if err := svc.User.FlushLastActive(ctx); err != nil {
+		t.Fatalf("FlushLastActive: %v", err)
+	}
+	if got := lastActive(); !got.Equal(flushed) {
+		t.Errorf("empty flush changed last_active_at to %v", got)
+	}
+}
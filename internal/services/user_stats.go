@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// dayLayout is the format of the days returned by GetActivityDays.
+const dayLayout = "2006-01-02"
+
+// GetStats returns the user's goal statistics together with their activity
+// streaks. Days are counted in the timezone from the user's preferences.
+func (s *UserService) GetStats(ctx context.Context, userID primitive.ObjectID) (*models.UserStats, error) {
+	stats, err := s.goalRepo.AggregateUserStats(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate goal stats: %v", err)
+	}
+
+	loc, err := s.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.activityRepo.GetActivityDays(ctx, userID, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	stats.CurrentStreak, stats.LongestStreak = activityStreaks(days, time.Now().In(loc))
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	stats.XP = user.XP
+	stats.Level = LevelForXP(user.XP)
+	return stats, nil
+}
+
+// GetCompletionStreak returns the current and longest runs of days on which
+// the user completed at least one substep or goal, in their timezone.
+func (s *UserService) GetCompletionStreak(ctx context.Context, userID primitive.ObjectID) (*models.CompletionStreak, error) {
+	loc, err := s.userLocation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.goalRepo.GetCompletionDays(ctx, userID, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	streak := &models.CompletionStreak{}
+	streak.CurrentStreak, streak.LongestStreak = activityStreaks(days, time.Now().In(loc))
+	return streak, nil
+}
+
+// userLocation returns the timezone from the user's preferences, or UTC if
+// it cannot be loaded.
+func (s *UserService) userLocation(ctx context.Context, userID primitive.ObjectID) (*time.Location, error) {
+	prefs, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// activityStreaks computes the current and longest runs of consecutive days
+// in days, which must be sorted and formatted with dayLayout. The current
+// streak is still alive if its last day is today or yesterday.
+func activityStreaks(days []string, now time.Time) (current, longest int) {
+	var previous time.Time
+	run := 0
+	for _, raw := range days {
+		day, err := time.Parse(dayLayout, raw)
+		if err != nil {
+			continue
+		}
+		if !previous.IsZero() && day.Sub(previous) == 24*time.Hour {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		previous = day
+	}
+
+	if previous.IsZero() {
+		return 0, longest
+	}
+	today, _ := time.Parse(dayLayout, now.Format(dayLayout))
+	if today.Sub(previous) <= 24*time.Hour {
+		current = run
+	}
+	return current, longest
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// StreakFreezeCostXP is the XP price of one streak freeze.
+const StreakFreezeCostXP = 50
+
+// MaxStreakFreezes is how many unused freezes a user can hold.
+const MaxStreakFreezes = 3
+
+// loginStreakMilestones are the streak lengths that get a notification.
+var loginStreakMilestones = map[int]bool{7: true, 30: true, 100: true}
+
+// ErrStreakFreezeUnavailable is returned when a user cannot afford another
+// streak freeze or already holds the maximum.
+var ErrStreakFreezeUnavailable = errors.New("not enough XP or already holding the maximum number of streak freezes")
+
+// UpdateLoginStreak counts today's login towards the user's streak. Days are
+// counted in the timezone from the user's preferences. A login on the day
+// after the last one extends the streak; after a longer gap a streak freeze
+// is used up if the user has one, and otherwise the streak starts over.
+func (s *UserService) UpdateLoginStreak(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %v", err)
+	}
+	loc, err := s.userLocation(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	last := user.LastLoginDate.UTC()
+
+	streak, freezeDelta := 1, 0
+	switch {
+	case last.Equal(today):
+		return nil
+	case last.Equal(today.AddDate(0, 0, -1)):
+		streak = user.LoginStreak + 1
+	case !last.IsZero() && user.StreakFreezeCount > 0:
+		streak, freezeDelta = user.LoginStreak+1, -1
+	}
+
+	updated, err := s.repo.SetLoginStreak(ctx, userID, user.LastLoginDate, today, streak, freezeDelta)
+	if err != nil || !updated {
+		return err
+	}
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id":     userID.Hex(),
+		"streak":      streak,
+		"freeze_used": freezeDelta < 0,
+	}).Info("Login streak updated")
+
+	if loginStreakMilestones[streak] {
+		msg := fmt.Sprintf("You've logged in %d days in a row. Keep it up!", streak)
+		if err := s.notifications.CreateNotification(ctx, userID, "login_streak_milestone", "🔥 Login streak", msg, nil); err != nil {
+			logger.Log.WithError(err).Warn("Failed to send login_streak_milestone notification")
+		}
+	}
+	return nil
+}
+
+// recordLogin updates the login streak without failing the login.
+func (s *UserService) recordLogin(ctx context.Context, userID primitive.ObjectID) {
+	if err := s.UpdateLoginStreak(ctx, userID); err != nil {
+		logger.Log.WithError(err).WithField("user_id", userID.Hex()).Warn("Failed to update login streak")
+	}
+}
+
+// BuyStreakFreeze spends StreakFreezeCostXP of the user's XP on a streak
+// freeze. Spending XP can lower the user's level.
+func (s *UserService) BuyStreakFreeze(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	user, err := s.repo.BuyStreakFreeze(ctx, userID, StreakFreezeCostXP, MaxStreakFreezes)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrStreakFreezeUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to buy streak freeze: %v", err)
+	}
+
+	if err := s.syncLevel(ctx, user); err != nil {
+		logger.Log.WithError(err).Warn("Failed to update level after buying a streak freeze")
+	}
+	user.Level = LevelForXP(user.XP)
+
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID.Hex(),
+		"freezes": user.StreakFreezeCount,
+	}).Info("Streak freeze bought")
+	return user, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidRefreshToken is returned for refresh tokens that are unknown,
+// expired or revoked.
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// SaveRefreshToken records a newly issued refresh token so it can be
+// checked and revoked later.
+func (s *UserService) SaveRefreshToken(ctx context.Context, userID primitive.ObjectID, token string, expiresAt time.Time) error {
+	return s.refreshTokens.CreateRefreshToken(ctx, &models.RefreshToken{
+		UserID:    userID,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// CheckRefreshToken returns the owner of a refresh token if the token is
+// still live. The token's signature must already have been verified.
+func (s *UserService) CheckRefreshToken(ctx context.Context, token string) (*models.User, error) {
+	stored, err := s.refreshTokens.GetRefreshToken(ctx, token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up refresh token: %v", err)
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.repo.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		// The account is gone; the token is worthless
+		return nil, ErrInvalidRefreshToken
+	}
+	return user, nil
+}
+
+// RevokeRefreshToken revokes a refresh token so it can no longer be
+// exchanged for access tokens.
+func (s *UserService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	revoked, err := s.refreshTokens.RevokeRefreshToken(ctx, refreshToken, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvalidRefreshToken
+	}
+
+	logrus.Info("Refresh token revoked")
+	return nil
+}
+
+// Logout blacklists an access token by its ID until the token expires.
+// Tokens that have already expired need no entry.
+func (s *UserService) Logout(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return s.blacklist.AddToken(ctx, jti, ttl)
+}
+
+// IsTokenRevoked reports whether an access token was logged out. If the
+// blacklist cannot be reached the token is treated as valid, so Redis
+// being down does not lock everyone out.
+func (s *UserService) IsTokenRevoked(ctx context.Context, jti string) bool {
+	if jti == "" {
+		return false
+	}
+	revoked, err := s.blacklist.IsBlacklisted(ctx, jti)
+	if errors.Is(err, repository.ErrBlacklistUnavailable) {
+		return false
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("Token blacklist unavailable, allowing request")
+		return false
+	}
+	return revoked
+}
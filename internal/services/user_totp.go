@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/pquerna/otp/totp"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// totpIssuer is the account issuer shown in authenticator apps.
+const totpIssuer = "Achievement Manager"
+
+// totpQRSize is the width and height of the setup QR code in pixels.
+const totpQRSize = 256
+
+// ErrTOTPRequired is returned by AuthenticateUser, together with the user,
+// when the password was correct but the account also needs a TOTP code.
+var ErrTOTPRequired = errors.New("two-factor code required")
+
+// Errors returned by the 2FA setup flow.
+var (
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotSetUp       = errors.New("two-factor authentication has not been set up")
+	ErrInvalidTOTPCode    = errors.New("invalid two-factor code")
+)
+
+// TOTPSetup is returned when a user starts enabling 2FA.
+type TOTPSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // PNG data URL of OTPAuthURL
+}
+
+// SetupTOTP generates a new TOTP secret for the user and stores it
+// unconfirmed. 2FA stays off until ConfirmTOTP succeeds.
+func (s *UserService) SetupTOTP(ctx context.Context, userID primitive.ObjectID) (*TOTPSetup, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: totpIssuer, AccountName: user.Email})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %v", err)
+	}
+
+	img, err := key.Image(totpQRSize, totpQRSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render QR code: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %v", err)
+	}
+
+	update := map[string]interface{}{
+		"totp_secret": key.Secret(),
+		"updated_at":  time.Now(),
+	}
+	if _, err := s.repo.UpdateUser(ctx, userID, update); err != nil {
+		return nil, fmt.Errorf("failed to store TOTP secret: %v", err)
+	}
+
+	return &TOTPSetup{
+		Secret:     key.Secret(),
+		OTPAuthURL: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// ConfirmTOTP enables 2FA once the user proves their authenticator works.
+func (s *UserService) ConfirmTOTP(ctx context.Context, userID primitive.ObjectID, code string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if user.TOTPEnabled {
+		return ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return ErrTOTPNotSetUp
+	}
+	if !totp.Validate(code, user.TOTPSecret) {
+		return ErrInvalidTOTPCode
+	}
+
+	update := map[string]interface{}{
+		"totp_enabled": true,
+		"updated_at":   time.Now(),
+	}
+	if _, err := s.repo.UpdateUser(ctx, userID, update); err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %v", err)
+	}
+
+	logrus.WithField("userID", userID.Hex()).Info("Two-factor authentication enabled")
+	return nil
+}
+
+// DisableTOTP turns 2FA off after checking the user's password.
+func (s *UserService) DisableTOTP(ctx context.Context, userID primitive.ObjectID, password string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.HashedPassword), []byte(password)); err != nil {
+		return fmt.Errorf("invalid credentials")
+	}
+
+	update := map[string]interface{}{
+		"totp_enabled": false,
+		"totp_secret":  "",
+		"updated_at":   time.Now(),
+	}
+	if _, err := s.repo.UpdateUser(ctx, userID, update); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %v", err)
+	}
+
+	logrus.WithField("userID", userID.Hex()).Info("Two-factor authentication disabled")
+	return nil
+}
+
+// VerifyTOTPLogin finishes a login that AuthenticateUser paused with
+// ErrTOTPRequired.
+func (s *UserService) VerifyTOTPLogin(ctx context.Context, userID primitive.ObjectID, code string) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if !user.TOTPEnabled || !totp.Validate(code, user.TOTPSecret) {
+		logrus.WithField("userID", userID.Hex()).Warn("Invalid TOTP code at login")
+		return nil, ErrInvalidTOTPCode
+	}
+	s.recordLogin(ctx, user.ID)
+	return user, nil
+}
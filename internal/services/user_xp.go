@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// XP awarded for progress. Undoing progress takes the same amount back, so
+// toggling a substep cannot be used to farm XP.
+const (
+	XPPerSubstep = 5
+	XPPerStep    = 20
+	XPPerGoal    = 100
+)
+
+// LevelForXP returns the level reached with xp: floor(sqrt(xp/100)).
+func LevelForXP(xp int) int {
+	if xp <= 0 {
+		return 0
+	}
+	return int(math.Sqrt(float64(xp) / 100))
+}
+
+// AwardXP adds amount (which may be negative) to the user's XP and updates
+// their level, sending a "level_up" notification when it goes up.
+func (s *UserService) AwardXP(ctx context.Context, userID primitive.ObjectID, amount int) error {
+	if amount == 0 {
+		return nil
+	}
+
+	user, err := s.repo.IncrementXP(ctx, userID, amount)
+	if err != nil {
+		return err
+	}
+	return s.syncLevel(ctx, user)
+}
+
+// syncLevel brings the user's stored level in line with their XP.
+func (s *UserService) syncLevel(ctx context.Context, user *models.User) error {
+	userID := user.ID
+	level := LevelForXP(user.XP)
+	if level == user.Level {
+		return nil
+	}
+
+	changed, err := s.repo.SetLevel(ctx, userID, user.Level, level)
+	if err != nil || !changed || level < user.Level {
+		return err
+	}
+
+	msg := fmt.Sprintf("You reached level %d!", level)
+	if err := s.notifications.CreateNotification(ctx, userID, "level_up", "⭐ Level up", msg, nil); err != nil {
+		logger.Log.WithError(err).Warn("Failed to send level_up notification")
+	}
+	logger.Log.WithFields(map[string]interface{}{
+		"user_id": userID.Hex(),
+		"level":   level,
+	}).Info("User levelled up")
+	return nil
+}
+
+// stepProgressXP is the XP a goal's completed steps and substeps are worth.
+func stepProgressXP(steps []models.Step) int {
+	xp := 0
+	for _, step := range steps {
+		if step.Completed {
+			xp += XPPerStep
+		}
+		for _, sub := range step.Substeps {
+			if sub.Done {
+				xp += XPPerSubstep
+			}
+		}
+	}
+	return xp
+}
+
+// goalStatusXP is the XP change for a goal moving between the two statuses.
+func goalStatusXP(previousStatus, status string) int {
+	switch {
+	case previousStatus != "completed" && status == "completed":
+		return XPPerGoal
+	case previousStatus == "completed" && status != "completed":
+		return -XPPerGoal
+	}
+	return 0
+}
@@ -3,22 +3,40 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Dias221467/Achievemenet_Manager/internal/models"
 	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Limits for the stale wish review digest.
+const (
+	maxStaleWishesInDigest = 5
+	wishReviewInterval     = 30 * 24 * time.Hour
+)
+
 type WishService struct {
-	repo     *repository.WishRepository
-	goalRepo *repository.GoalRepository
+	repo                *repository.WishRepository
+	suggestionRepo      *repository.WishSuggestionRepository
+	goalRepo            *repository.GoalRepository
+	userRepo            *repository.UserRepository
+	notificationService *NotificationService
+	counters            *CounterService
+	staleAge            time.Duration
 }
 
-func NewWishService(repo *repository.WishRepository, goalRepo *repository.GoalRepository) *WishService {
+func NewWishService(repo *repository.WishRepository, suggestionRepo *repository.WishSuggestionRepository, goalRepo *repository.GoalRepository, userRepo *repository.UserRepository, notificationService *NotificationService, counters *CounterService, staleAge time.Duration) *WishService {
 	return &WishService{
-		repo:     repo,
-		goalRepo: goalRepo,
+		repo:                repo,
+		suggestionRepo:      suggestionRepo,
+		goalRepo:            goalRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		counters:            counters,
+		staleAge:            staleAge,
 	}
 }
 
@@ -73,13 +91,80 @@ func (s *WishService) PromoteWishToGoal(ctx context.Context, id string, userID p
 		Description:   wish.Description,
 		UserID:        userID,
 		Steps:         []models.Step{},
-		Collaborators: []primitive.ObjectID{},
+		Collaborators: []models.Collaborator{},
 		Status:        "in_progress",
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
 
-	return s.goalRepo.CreateGoal(ctx, goal)
+	createdGoal, err := s.goalRepo.CreateGoal(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+	s.counters.GoalCreated(ctx, createdGoal)
+
+	if err := s.repo.MarkPromoted(ctx, objID, createdGoal.ID); err != nil {
+		logrus.WithError(err).Warn("Failed to mark wish as promoted")
+	}
+	return createdGoal, nil
+}
+
+// MarkPromoted records that a wish was turned into the given goal.
+func (s *WishService) MarkPromoted(ctx context.Context, wishID, goalID primitive.ObjectID) error {
+	return s.repo.MarkPromoted(ctx, wishID, goalID)
+}
+
+// GetStaleWishes returns the user's wishes that are older than the configured
+// age and were never promoted to a goal.
+func (s *WishService) GetStaleWishes(ctx context.Context, userID primitive.ObjectID) ([]models.Wish, error) {
+	return s.repo.GetStaleWishes(ctx, userID, time.Now().Add(-s.staleAge))
+}
+
+// SendStaleWishDigests sends each user with stale wishes a single wish_review
+// notification listing up to five of them. Users get at most one digest per
+// month and users who muted the type are skipped.
+func (s *WishService) SendStaleWishDigests(ctx context.Context) error {
+	wishes, err := s.repo.GetStaleWishes(ctx, primitive.NilObjectID, time.Now().Add(-s.staleAge))
+	if err != nil {
+		return fmt.Errorf("failed to fetch stale wishes: %w", err)
+	}
+
+	byUser := make(map[primitive.ObjectID][]models.Wish)
+	var order []primitive.ObjectID
+	for _, wish := range wishes {
+		if _, seen := byUser[wish.UserID]; !seen {
+			order = append(order, wish.UserID)
+		}
+		byUser[wish.UserID] = append(byUser[wish.UserID], wish)
+	}
+
+	for _, userID := range order {
+		if s.notificationService.IsTypeMuted(ctx, userID, "wish_review") {
+			continue
+		}
+		if s.notificationService.SentWithin(ctx, userID, "wish_review", wishReviewInterval) {
+			continue
+		}
+
+		userWishes := byUser[userID]
+		if len(userWishes) > maxStaleWishesInDigest {
+			userWishes = userWishes[:maxStaleWishesInDigest]
+		}
+
+		var lines []string
+		for _, wish := range userWishes {
+			lines = append(lines, fmt.Sprintf("• %s (/wishes/%s)", wish.Title, wish.ID.Hex()))
+		}
+		message := fmt.Sprintf("You have %d wishes waiting for a while. Turn one into a goal?\n%s",
+			len(byUser[userID]), strings.Join(lines, "\n"))
+
+		err := s.notificationService.CreateNotification(ctx, userID, "wish_review", "✨ Time to review your wishes", message, nil)
+		if err != nil {
+			logrus.WithError(err).Warnf("Failed to send wish review notification to user %s", userID.Hex())
+		}
+	}
+
+	return nil
 }
 
 func (s *WishService) UpdateWishImage(ctx context.Context, wishID string, userID string, imageURL string) (*models.Wish, error) {
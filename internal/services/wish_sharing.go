@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors returned when copying and suggesting wishes.
+var (
+	ErrWishNotFound           = errors.New("wish not found")
+	ErrWishForbidden          = errors.New("forbidden: not the owner of the wish")
+	ErrNotFriends             = errors.New("wishes can only be suggested to friends")
+	ErrWishSuggestionNotFound = errors.New("wish suggestion not found")
+)
+
+// getOwnedWish loads a wish and checks that userID owns it.
+func (s *WishService) getOwnedWish(ctx context.Context, wishID string, userID primitive.ObjectID) (*models.Wish, error) {
+	objID, err := primitive.ObjectIDFromHex(wishID)
+	if err != nil {
+		return nil, ErrWishNotFound
+	}
+	wish, err := s.repo.GetWishByID(ctx, objID)
+	if err != nil {
+		return nil, ErrWishNotFound
+	}
+	if wish.UserID != userID {
+		return nil, ErrWishForbidden
+	}
+	return wish, nil
+}
+
+// DuplicateWish copies one of the user's wishes with fresh timestamps.
+// Images are only carried over when includeImages is set.
+func (s *WishService) DuplicateWish(ctx context.Context, wishID string, userID primitive.ObjectID, includeImages bool) (*models.Wish, error) {
+	wish, err := s.getOwnedWish(ctx, wishID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := &models.Wish{
+		Title:       wish.Title,
+		Description: wish.Description,
+		UserID:      userID,
+	}
+	if includeImages && len(wish.Images) > 0 {
+		copied.Images = append([]string(nil), wish.Images...)
+	}
+	return s.repo.CreateWish(ctx, copied)
+}
+
+// SuggestWish sends a friend a snapshot of one of the user's wishes. The
+// friend gets a wish_suggested notification with the token to accept it.
+func (s *WishService) SuggestWish(ctx context.Context, wishID string, userID, friendID primitive.ObjectID) (*models.WishSuggestion, error) {
+	wish, err := s.getOwnedWish(ctx, wishID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	friendIDs, err := s.userRepo.GetFriendIDs(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load friends: %v", err)
+	}
+	isFriend := false
+	for _, id := range friendIDs {
+		if id == friendID {
+			isFriend = true
+			break
+		}
+	}
+	if !isFriend {
+		return nil, ErrNotFriends
+	}
+
+	suggestion := &models.WishSuggestion{
+		WishID:      wish.ID,
+		FromUserID:  userID,
+		ToUserID:    friendID,
+		Title:       wish.Title,
+		Description: wish.Description,
+		Token:       uuid.NewString(),
+	}
+	if len(wish.Images) > 0 {
+		suggestion.ImageURL = wish.Images[0]
+	}
+
+	suggestion, err = s.suggestionRepo.CreateSuggestion(ctx, suggestion)
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("A friend thinks you might like: %s", suggestion.Title)
+	if suggestion.Description != "" {
+		message += "\n" + suggestion.Description
+	}
+	if suggestion.ImageURL != "" {
+		message += "\n" + suggestion.ImageURL
+	}
+	message += fmt.Sprintf("\nAdd it to your wishlist: POST /wishes/suggestions/%s/accept", suggestion.Token)
+
+	err = s.notificationService.CreateNotification(ctx, friendID, "wish_suggested", "🎁 A wish for you", message, &suggestion.ID)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to send wish suggestion notification")
+	}
+	return suggestion, nil
+}
+
+// AcceptWishSuggestion copies a suggested wish into the recipient's wishlist.
+// Only the user the suggestion was sent to can accept it, and only once.
+func (s *WishService) AcceptWishSuggestion(ctx context.Context, token string, userID primitive.ObjectID) (*models.Wish, error) {
+	suggestion, err := s.suggestionRepo.GetSuggestionByToken(ctx, token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrWishSuggestionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wish suggestion: %v", err)
+	}
+	// Don't reveal other users' suggestions
+	if suggestion.ToUserID != userID {
+		return nil, ErrWishSuggestionNotFound
+	}
+	if suggestion.Status != models.WishSuggestionPending {
+		return nil, repository.ErrSuggestionNotPending
+	}
+
+	wish := &models.Wish{
+		Title:       suggestion.Title,
+		Description: suggestion.Description,
+		UserID:      userID,
+	}
+	if suggestion.ImageURL != "" {
+		wish.Images = []string{suggestion.ImageURL}
+	}
+	created, err := s.repo.CreateWish(ctx, wish)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.suggestionRepo.MarkAccepted(ctx, suggestion.ID, created.ID); err != nil {
+		// Lost a race with a concurrent accept; undo our copy
+		if delErr := s.repo.DeleteWish(ctx, created.ID); delErr != nil {
+			logrus.WithError(delErr).Warn("Failed to remove duplicate accepted wish")
+		}
+		return nil, err
+	}
+	return created, nil
+}
@@ -0,0 +1,90 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SeedUser inserts a verified user. Fields left empty on u get sensible defaults.
+func SeedUser(t testing.TB, repos *Repositories, u models.User) *models.User {
+	t.Helper()
+
+	if u.Username == "" {
+		u.Username = "user_" + primitive.NewObjectID().Hex()[18:]
+	}
+	if u.Email == "" {
+		u.Email = u.Username + "@example.com"
+	}
+	if u.Role == "" {
+		u.Role = "user"
+	}
+	u.IsVerified = true
+
+	created, err := repos.Users.CreateUser(context.Background(), &u)
+	if err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+	return created
+}
+
+// SeedGoal inserts a goal owned by ownerID.
+func SeedGoal(t testing.TB, repos *Repositories, ownerID primitive.ObjectID, g models.Goal) *models.Goal {
+	t.Helper()
+
+	g.UserID = ownerID
+	if g.Name == "" {
+		g.Name = "Test goal"
+	}
+	if g.Status == "" {
+		g.Status = "in_progress"
+	}
+	if g.Steps == nil {
+		g.Steps = []models.Step{}
+	}
+
+	created, err := repos.Goals.CreateGoal(context.Background(), &g)
+	if err != nil {
+		t.Fatalf("failed to seed goal: %v", err)
+	}
+	return created
+}
+
+// SeedNotification inserts a notification through the repository, so
+// CreatedAt/ExpiresAt are set by the same code path as production.
+func SeedNotification(t testing.TB, repos *Repositories, userID primitive.ObjectID, notifType string) *models.Notification {
+	t.Helper()
+
+	n := &models.Notification{
+		UserID: userID,
+		Type:   notifType,
+		Title:  notifType,
+	}
+	if err := repos.Notifications.CreateNotification(context.Background(), n); err != nil {
+		t.Fatalf("failed to seed notification: %v", err)
+	}
+	return n
+}
+
+// SeedExpiredNotification inserts a notification that expired at the given offset
+// in the past, bypassing the repository's ExpiresAt calculation.
+func SeedExpiredNotification(t testing.TB, repos *Repositories, userID primitive.ObjectID, notifType string, expiredAgo time.Duration) *models.Notification {
+	t.Helper()
+
+	now := time.Now()
+	n := &models.Notification{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Type:      notifType,
+		Title:     notifType,
+		CreatedAt: now.Add(-expiredAgo - time.Hour),
+		ExpiresAt: now.Add(-expiredAgo),
+	}
+	if _, err := repos.DB.Collection("notifications").InsertOne(context.Background(), n); err != nil {
+		t.Fatalf("failed to seed expired notification: %v", err)
+	}
+	return n
+}
@@ -0,0 +1,77 @@
+package testutil
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultMongoURI is used when TEST_MONGO_URI is not set, e.g. a local
+// `docker run -p 27017:27017 mongo` started by the developer or by CI.
+const DefaultMongoURI = "mongodb://localhost:27017"
+
+var initLoggerOnce sync.Once
+
+// initLogger sets up the package-level logger the repositories write to and
+// discards its output, and that of the standard logrus logger, so test runs
+// stay readable.
+func initLogger() {
+	initLoggerOnce.Do(func() {
+		if logger.Log == nil {
+			logger.InitLogger()
+		}
+		logger.Log.SetOutput(io.Discard)
+		logrus.SetOutput(io.Discard)
+	})
+}
+
+// NewTestDatabase connects to the ephemeral MongoDB instance and returns a
+// throwaway database unique to the calling test. The database is dropped and
+// the client disconnected when the test finishes. If no MongoDB is reachable
+// the test is skipped rather than failed, so `go test ./...` stays usable on
+// machines without Docker.
+func NewTestDatabase(t testing.TB) *mongo.Database {
+	t.Helper()
+	initLogger()
+
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		uri = DefaultMongoURI
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(3*time.Second))
+	if err != nil {
+		t.Skipf("skipping: cannot connect to MongoDB at %s: %v", uri, err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		_ = client.Disconnect(context.Background())
+		t.Skipf("skipping: MongoDB at %s is not reachable: %v", uri, err)
+	}
+
+	name := "am_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+	db := client.Database(name)
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := db.Drop(ctx); err != nil {
+			t.Logf("failed to drop test database %s: %v", name, err)
+		}
+		_ = client.Disconnect(ctx)
+	})
+
+	return db
+}
@@ -0,0 +1,38 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Repositories bundles every repository built against the same throwaway
+// database so a test can seed through one and assert through another.
+type Repositories struct {
+	DB            *mongo.Database
+	Users         *repository.UserRepository
+	Goals         *repository.GoalRepository
+	Friends       *repository.FriendRepository
+	Templates     *repository.TemplateRepository
+	Wishes        *repository.WishRepository
+	Activities    *repository.ActivityRepository
+	Notifications *repository.NotificationRepository
+}
+
+// NewRepositories creates a fresh test database and wires all repositories to it.
+func NewRepositories(t testing.TB) *Repositories {
+	t.Helper()
+
+	db := NewTestDatabase(t)
+	return &Repositories{
+		DB:            db,
+		Users:         repository.NewUserRepository(db),
+		Goals:         repository.NewGoalRepository(db),
+		Friends:       repository.NewFriendRepository(db),
+		Templates:     repository.NewTemplateRepository(db),
+		Wishes:        repository.NewWishRepository(db),
+		Activities:    repository.NewActivityRepository(db),
+		Notifications: repository.NewNotificationRepository(db, repository.DefaultNotificationRetention),
+	}
+}
@@ -0,0 +1,84 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/hub"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/email"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Services wires the services the way cmd/server does, on top of a fresh
+// set of repositories, so tests can drive whole flows and assert on the
+// stored documents.
+type Services struct {
+	*Repositories
+	Counters     *repository.CounterRepository
+	Hub          *hub.Hub
+	Counter      *services.CounterService
+	Notification *services.NotificationService
+	User         *services.UserService
+	Badge        *services.BadgeService
+	Goal         *services.GoalService
+	Friend       *services.FriendService
+	Template     *services.TemplateService
+	Wish         *services.WishService
+	GroupChat    *services.GroupChatService
+}
+
+// MaxPinnedGoals is the pinned goal limit the harness configures.
+const MaxPinnedGoals = 3
+
+// NewServices creates a fresh test database and wires every service to it.
+// Redis is left out, so the token blacklist is disabled.
+func NewServices(t testing.TB) *Services {
+	t.Helper()
+
+	repos := NewRepositories(t)
+	db := repos.DB
+
+	counterRepo := repository.NewCounterRepository(db)
+	preferencesRepo := repository.NewPreferencesRepository(db)
+	chatRepo := repository.NewChatRepository(db)
+
+	progressService := services.NewProgressService(repository.NewProgressRepository(db))
+	categoryService := services.NewCategoryService(repository.NewCategoryRepository(db), repos.Goals)
+	counterService := services.NewCounterService(counterRepo, repos.Users, repos.Goals, repos.Notifications, repos.Friends)
+	wsHub := hub.New(func(ctx context.Context, userID string) ([]string, error) {
+		id, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := repos.Users.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		friends := make([]string, 0, len(user.Friends))
+		for _, friendID := range user.Friends {
+			friends = append(friends, friendID.Hex())
+		}
+		return friends, nil
+	})
+	notificationService := services.NewNotificationService(repos.Notifications, repos.Users, repos.Goals, preferencesRepo, counterService, wsHub)
+	userService := services.NewUserService(repos.Users, repos.Goals, repos.Activities, preferencesRepo, repository.NewRefreshTokenRepository(db), repository.NewTokenBlacklistRepository(nil), email.NewMailer(10), notificationService, wsHub, time.Minute)
+	badgeService := services.NewBadgeService(repository.NewBadgeRepository(db), repos.Goals, repos.Users, notificationService, userService)
+
+	return &Services{
+		Repositories: repos,
+		Counters:     counterRepo,
+		Hub:          wsHub,
+		Counter:      counterService,
+		Notification: notificationService,
+		User:         userService,
+		Badge:        badgeService,
+		Goal:         services.NewGoalService(repos.Goals, repos.Users, repository.NewGoalInvitationRepository(db), notificationService, progressService, badgeService, counterService, categoryService, userService, MaxPinnedGoals),
+		Friend:       services.NewFriendService(repos.Friends, repos.Users, repos.Activities, repository.NewFriendInvitationRepository(db), badgeService, counterService, wsHub),
+		Template:     services.NewTemplateService(repos.Templates, repository.NewTemplateCopyRepository(db), repository.NewTemplateBookmarkRepository(db), repository.NewTemplateVersionRepository(db), repos.Goals, repos.Users, notificationService, counterService, categoryService),
+		Wish:         services.NewWishService(repos.Wishes, repository.NewWishSuggestionRepository(db), repos.Goals, repos.Users, notificationService, counterService, 30*24*time.Hour),
+		GroupChat:    services.NewGroupChatService(chatRepo, repos.Users, wsHub),
+	}
+}
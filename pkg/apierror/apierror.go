@@ -0,0 +1,58 @@
+// Package apierror writes error responses in the API's JSON format:
+//
+//	{"code": "not_found", "message": "Goal not found", "details": ...}
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes. Clients should branch on the code rather than the message.
+const (
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeInvalidInput    = "invalid_input"
+	CodeConflict        = "conflict"
+	CodeTooManyRequests = "too_many_requests"
+	CodeUnavailable     = "unavailable"
+	CodeInternalError   = "internal_error"
+)
+
+// APIError is the body of every error response.
+type APIError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// WriteError writes an APIError with the given status.
+func WriteError(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{Code: code, Message: message, Details: details})
+}
+
+// CodeForStatus returns the error code that goes with an HTTP status.
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return CodeInvalidInput
+	}
+	return CodeInternalError
+}
@@ -27,3 +27,27 @@ func SendEmail(to, subject, body string) error {
 	}
 	return nil
 }
+
+// SendHTMLEmail sends an HTML email using SMTP.
+func SendHTMLEmail(to, subject, htmlBody string) error {
+	from := os.Getenv("SMTP_SENDER")
+	password := os.Getenv("SMTP_PASSWORD")
+	smtpHost := os.Getenv("SMTP_HOST")
+	smtpPort := os.Getenv("SMTP_PORT")
+
+	auth := smtp.PlainAuth("", from, password, smtpHost)
+
+	msg := []byte("To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=\"UTF-8\"\r\n" +
+		"\r\n" + htmlBody + "\r\n")
+
+	address := smtpHost + ":" + smtpPort
+
+	err := smtp.SendMail(address, auth, from, []string{to}, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package email
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Message is a single email waiting to be delivered.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    bool
+}
+
+// Mailer delivers emails from a background worker so callers don't block on SMTP.
+type Mailer struct {
+	queue chan Message
+}
+
+// NewMailer creates a Mailer with the given queue size and starts its worker.
+func NewMailer(queueSize int) *Mailer {
+	m := &Mailer{queue: make(chan Message, queueSize)}
+	go m.run()
+	return m
+}
+
+// Enqueue schedules a message for delivery. It never blocks and returns an
+// error when the queue is full.
+func (m *Mailer) Enqueue(msg Message) error {
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		return fmt.Errorf("mail queue is full")
+	}
+}
+
+func (m *Mailer) run() {
+	for msg := range m.queue {
+		var err error
+		if msg.HTML {
+			err = SendHTMLEmail(msg.To, msg.Subject, msg.Body)
+		} else {
+			err = SendEmail(msg.To, msg.Subject, msg.Body)
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("subject", msg.Subject).Error("Failed to deliver queued email")
+		}
+	}
+}
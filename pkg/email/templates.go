@@ -0,0 +1,30 @@
+package email
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var welcomeTemplate = template.Must(template.New("welcome").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #333;">
+  <h2>Welcome to Achievement Manager, {{.Username}}!</h2>
+  <p>Your email is verified and your account is ready.</p>
+  <p>A few ideas to get started:</p>
+  <ul>
+    <li>Create your first goal and break it into steps.</li>
+    <li>Browse public templates to reuse plans other people made.</li>
+    <li>Add friends and invite them to collaborate on a goal.</li>
+  </ul>
+  <p>Good luck!</p>
+</body>
+</html>`))
+
+// RenderWelcomeEmail renders the HTML welcome email sent after verification.
+func RenderWelcomeEmail(username string) (string, error) {
+	var buf bytes.Buffer
+	if err := welcomeTemplate.Execute(&buf, struct{ Username string }{username}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
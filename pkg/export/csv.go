@@ -0,0 +1,53 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+)
+
+// GoalCSVHeader lists the columns written by WriteGoalsCSV.
+var GoalCSVHeader = []string{"id", "name", "description", "category", "status", "due_date", "steps_total", "steps_completed"}
+
+// WriteGoalsCSV serializes goals as CSV with one row per goal.
+func WriteGoalsCSV(w io.Writer, goals []models.Goal) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(GoalCSVHeader); err != nil {
+		return err
+	}
+
+	for _, goal := range goals {
+		completed := 0
+		for _, step := range goal.Steps {
+			if step.Completed {
+				completed++
+			}
+		}
+
+		dueDate := ""
+		if !goal.DueDate.IsZero() {
+			dueDate = goal.DueDate.Format(time.RFC3339)
+		}
+
+		row := []string{
+			goal.ID.Hex(),
+			goal.Name,
+			goal.Description,
+			goal.Category,
+			goal.Status,
+			dueDate,
+			strconv.Itoa(len(goal.Steps)),
+			strconv.Itoa(completed),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,9 +1,11 @@
 package jwtutil
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
 // Claims defines the structure for JWT claims.
@@ -11,29 +13,69 @@ type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"` // <- NEW: Include role
+	// Purpose marks a restricted token, e.g. the one issued between the
+	// password and TOTP steps of a login. Such tokens never authenticate requests.
+	Purpose string `json:"purpose,omitempty"`
+	// Impersonator is the ID of the admin acting as UserID. Set only on
+	// tokens issued by GenerateImpersonationToken.
+	Impersonator string `json:"impersonator,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for the given user.
-func GenerateToken(userID, email, role, secret string, expiry time.Duration) (string, error) {
+// PurposeTOTPPending is the purpose of the token that stands in for a session
+// until the user has entered their TOTP code.
+const PurposeTOTPPending = "totp_pending"
+
+// ErrWrongPurpose is returned when a token is used for something it was not issued for.
+var ErrWrongPurpose = errors.New("token not valid for this purpose")
+
+// GenerateToken creates a new JWT token for the given user, signed with the
+// ring's active key. The token gets a unique ID (jti) so it can be revoked on logout.
+func GenerateToken(userID, email, role string, keys *KeyRing, expiry time.Duration) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		Email:  email,
 		Role:   role, // <- include role in token
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.sign(claims)
 }
 
-// ValidateToken parses and validates a token string.
-func ValidateToken(tokenStr, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+// ImpersonationTokenExpiry is the fixed lifetime of impersonation tokens.
+// They cannot be refreshed.
+const ImpersonationTokenExpiry = 15 * time.Minute
+
+// GenerateImpersonationToken creates an access token that authenticates as
+// the target user and names the admin who requested it.
+func GenerateImpersonationToken(userID, email, role, impersonatorID string, keys *KeyRing) (string, error) {
+	claims := Claims{
+		UserID:       userID,
+		Email:        email,
+		Role:         role,
+		Impersonator: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ImpersonationTokenExpiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return keys.sign(claims)
+}
+
+// IsImpersonation reports whether the token was issued for an admin acting
+// as another user.
+func (c *Claims) IsImpersonation() bool {
+	return c.Impersonator != ""
+}
+
+// ValidateToken parses and validates a token string, looking up the
+// verification key by the token's kid.
+func ValidateToken(tokenStr string, keys *KeyRing) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, keys.keyFunc)
 	if err != nil {
 		return nil, err
 	}
@@ -48,5 +90,65 @@ func ValidateToken(tokenStr, secret string) (*Claims, error) {
 		return nil, jwt.ErrTokenExpired
 	}
 
+	// Restricted tokens are checked with ValidatePurposeToken instead
+	if claims.Purpose != "" {
+		return nil, ErrWrongPurpose
+	}
+
+	return claims, nil
+}
+
+// GeneratePurposeToken creates a restricted token that is only accepted by
+// ValidatePurposeToken with the same purpose.
+func GeneratePurposeToken(userID, purpose string, keys *KeyRing, expiry time.Duration) (string, error) {
+	claims := Claims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return keys.sign(claims)
+}
+
+// ValidatePurposeToken parses a restricted token and checks its purpose.
+func ValidatePurposeToken(tokenStr, purpose string, keys *KeyRing) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, keys.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrWrongPurpose
+	}
 	return claims, nil
 }
+
+// PurposeRefresh is the purpose of long-lived refresh tokens. They can only
+// be exchanged for a new access token at /users/token/refresh.
+const PurposeRefresh = "refresh"
+
+// GenerateRefreshToken creates a refresh token for the given user. Each token
+// carries a unique ID so two tokens issued in the same second still differ.
+func GenerateRefreshToken(userID string, keys *KeyRing, expiry time.Duration) (string, error) {
+	claims := Claims{
+		UserID:  userID,
+		Purpose: PurposeRefresh,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return keys.sign(claims)
+}
+
+// ValidateRefreshToken parses a refresh token and checks its signature and
+// expiry. Whether it was revoked is tracked in the database, not in the token.
+func ValidateRefreshToken(tokenStr string, keys *KeyRing) (*Claims, error) {
+	return ValidatePurposeToken(tokenStr, PurposeRefresh, keys)
+}
@@ -0,0 +1,112 @@
+package jwtutil
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// EnvKeyID is the kid of the signing key configured through JWT_SECRET.
+const EnvKeyID = "env"
+
+// ErrUnknownKey is returned when a token names a kid the ring does not hold
+// or whose grace period has ended.
+var ErrUnknownKey = errors.New("unknown or retired signing key")
+
+// SigningKey is an HMAC secret identified by the kid header of the tokens it signs.
+type SigningKey struct {
+	ID     string
+	Secret []byte
+	// RetiresAt is when a rotated-out key stops validating tokens. Zero for
+	// the active key.
+	RetiresAt time.Time
+}
+
+// KeyRing holds the active signing key and the previous keys that are still
+// accepted during their grace period. It is safe for concurrent use.
+type KeyRing struct {
+	mu       sync.RWMutex
+	active   SigningKey
+	previous []SigningKey
+}
+
+// NewKeyRing creates a ring whose active key is the configured JWT secret.
+func NewKeyRing(secret string) *KeyRing {
+	return &KeyRing{active: SigningKey{ID: EnvKeyID, Secret: []byte(secret)}}
+}
+
+// Rotate makes key the active signing key. The current active key keeps
+// validating tokens until retiresAt.
+func (k *KeyRing) Rotate(key SigningKey, retiresAt time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	old := k.active
+	old.RetiresAt = retiresAt
+	k.previous = append(k.liveKeys(time.Now()), old)
+	key.RetiresAt = time.Time{}
+	k.active = key
+}
+
+// AddPrevious registers a rotated-out key, e.g. one loaded from storage on startup.
+func (k *KeyRing) AddPrevious(key SigningKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.previous = append(k.liveKeys(time.Now()), key)
+}
+
+// ActiveKeyID returns the kid new tokens are signed with.
+func (k *KeyRing) ActiveKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active.ID
+}
+
+// liveKeys returns the previous keys that have not retired yet. Callers hold the lock.
+func (k *KeyRing) liveKeys(now time.Time) []SigningKey {
+	live := make([]SigningKey, 0, len(k.previous))
+	for _, key := range k.previous {
+		if now.Before(key.RetiresAt) {
+			live = append(live, key)
+		}
+	}
+	return live
+}
+
+// sign signs claims with the active key and sets the kid header.
+func (k *KeyRing) sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = active.ID
+	return token.SignedString(active.Secret)
+}
+
+// keyFunc picks the verification key by the token's kid. Tokens issued before
+// key IDs were introduced carry no kid and are checked against the env key.
+func (k *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = EnvKeyID
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if kid == k.active.ID {
+		return k.active.Secret, nil
+	}
+	now := time.Now()
+	for _, key := range k.previous {
+		if key.ID == kid && now.Before(key.RetiresAt) {
+			return key.Secret, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
@@ -0,0 +1,84 @@
+package jwtutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRotatedKeyGraceWindow(t *testing.T) {
+	keys := NewKeyRing("env-secret")
+	oldToken, err := GenerateToken("u1", "u1@example.com", "user", keys, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	keys.Rotate(SigningKey{ID: "k2", Secret: []byte("second-secret")}, time.Now().Add(time.Hour))
+	if got := keys.ActiveKeyID(); got != "k2" {
+		t.Fatalf("ActiveKeyID = %q, want k2", got)
+	}
+
+	if _, err := ValidateToken(oldToken, keys); err != nil {
+		t.Errorf("token signed before rotation rejected inside the grace window: %v", err)
+	}
+	newToken, err := GenerateToken("u1", "u1@example.com", "user", keys, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := ValidateToken(newToken, keys); err != nil {
+		t.Errorf("token signed with the new key rejected: %v", err)
+	}
+
+	// Rotating again with a grace window that already ended retires k2 at once,
+	// while the env key's own window is still open.
+	keys.Rotate(SigningKey{ID: "k3", Secret: []byte("third-secret")}, time.Now().Add(-time.Second))
+	if _, err := ValidateToken(newToken, keys); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token signed with a retired key: err = %v, want ErrUnknownKey", err)
+	}
+	if _, err := ValidateToken(oldToken, keys); err != nil {
+		t.Errorf("token signed with a key still in its grace window rejected: %v", err)
+	}
+}
+
+func TestRotatedKeyRejectedAfterGrace(t *testing.T) {
+	keys := NewKeyRing("env-secret")
+	token, err := GenerateToken("u1", "u1@example.com", "user", keys, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	keys.Rotate(SigningKey{ID: "k2", Secret: []byte("second-secret")}, time.Now().Add(50*time.Millisecond))
+	if _, err := ValidateToken(token, keys); err != nil {
+		t.Fatalf("token rejected inside the grace window: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := ValidateToken(token, keys); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token accepted after the grace window: err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestTokenWithoutKidUsesEnvKey(t *testing.T) {
+	keys := NewKeyRing("env-secret")
+	claims := Claims{
+		UserID: "u1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("env-secret"))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	if _, err := ValidateToken(legacy, keys); err != nil {
+		t.Errorf("token without kid rejected: %v", err)
+	}
+
+	keys.Rotate(SigningKey{ID: "k2", Secret: []byte("second-secret")}, time.Now().Add(-time.Second))
+	if _, err := ValidateToken(legacy, keys); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token without kid accepted after the env key retired: err = %v, want ErrUnknownKey", err)
+	}
+}
@@ -0,0 +1,57 @@
+// Package metrics holds the Prometheus collectors for the API. They are
+// registered with the default registry, which also carries the Go runtime
+// and process collectors, and are served by GET /metrics through promhttp.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPRequests counts handled requests by method, route template and status.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route template and status.",
+	}, []string{"method", "path", "status"})
+
+	// HTTPResponses counts every response by method and status code,
+	// including requests no route matched, which HTTPRequests cannot see.
+	// It is recorded by promhttp.InstrumentHandlerCounter around the router.
+	HTTPResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_responses_total",
+		Help: "HTTP responses written, including unmatched routes, by method and status code.",
+	}, []string{"code", "method"})
+
+	// HTTPRequestDuration observes request latency by route template.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"path"})
+
+	// ActiveWebSocketConnections is the number of open chat WebSocket connections.
+	ActiveWebSocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "active_websocket_connections",
+		Help: "Open chat WebSocket connections.",
+	})
+
+	// GoalsCreated counts goals created, whether directly, from a template,
+	// from a wish or by import.
+	GoalsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "goals_created_total",
+		Help: "Goals created since the process started.",
+	})
+
+	// NotificationsSent counts notifications stored for users by type.
+	NotificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notifications_sent_total",
+		Help: "Notifications created since the process started, by type.",
+	}, []string{"type"})
+
+	// PanicTotal counts handler panics recovered by RecoveryMiddleware.
+	PanicTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "panic_total",
+		Help: "Handler panics recovered since the process started.",
+	})
+)
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
 	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
 	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
 )
@@ -14,31 +16,51 @@ type contextKey string
 
 const UserContextKey contextKey = "user"
 
-// AuthMiddleware validates JWT tokens from incoming requests
-func AuthMiddleware(secret string) func(http.Handler) http.Handler {
+// AuthMiddleware validates JWT tokens from incoming requests and rejects
+// tokens that were revoked on logout.
+func AuthMiddleware(keys *jwtutil.KeyRing, userService *services.UserService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+				apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Missing Authorization header", nil)
 				return
 			}
 
-			// Expect "Bearer <token>"
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "Invalid Authorization format", http.StatusUnauthorized)
+			token, ok := BearerToken(r)
+			if !ok {
+				apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid Authorization format", nil)
 				return
 			}
 
 			// Validate token
-			claims, err := jwtutil.ValidateToken(parts[1], secret)
+			claims, err := jwtutil.ValidateToken(token, keys)
 			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Invalid token", nil)
 				return
 			}
 
+			if userService.IsTokenRevoked(r.Context(), claims.ID) {
+				apierror.WriteError(w, http.StatusUnauthorized, apierror.CodeUnauthorized, "Token has been revoked", nil)
+				return
+			}
+
+			if !limitUser(w, r, claims.UserID) {
+				return
+			}
+
+			setRequestUser(r.Context(), claims.UserID, claims.Impersonator)
+			if claims.IsImpersonation() {
+				logger.Log.WithFields(map[string]interface{}{
+					"admin_id":   claims.Impersonator,
+					"user_id":    claims.UserID,
+					"method":     r.Method,
+					"path":       r.URL.Path,
+					"request_id": GetRequestID(r.Context()),
+				}).Warn("AUDIT: request made under impersonation")
+			}
+
 			// Store user info in context and pass it to the next handler
 			ctx := context.WithValue(r.Context(), UserContextKey, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -46,6 +68,56 @@ func AuthMiddleware(secret string) func(http.Handler) http.Handler {
 	}
 }
 
+// OptionalAuthMiddleware stores the caller's claims in the context when the
+// request carries a valid, unrevoked token, and otherwise lets the request
+// through anonymously. It is meant for public endpoints that show more to
+// signed-in users.
+func OptionalAuthMiddleware(keys *jwtutil.KeyRing, userService *services.UserService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := jwtutil.ValidateToken(token, keys)
+			if err != nil || userService.IsTokenRevoked(r.Context(), claims.ID) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !limitUser(w, r, claims.UserID) {
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func BearerToken(r *http.Request) (string, bool) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// WebSocketTokenMiddleware lets WebSocket upgrades authenticate with an
+// access_token query parameter, since browsers cannot set headers on them.
+// It must run before AuthMiddleware; other requests are left untouched.
+func WebSocketTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("access_token")
+		if token != "" && r.Header.Get("Authorization") == "" && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // RequireRole enforces that the user has a specific role (e.g., "admin")
 func RequireRole(role string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -53,7 +125,7 @@ func RequireRole(role string) func(http.Handler) http.Handler {
 			claims := GetUserFromContext(r.Context())
 			if claims == nil || claims.Role != role {
 				logger.Log.Warnf("Access denied. Required role: %s, got: %s", role, claims.Role)
-				http.Error(w, "Forbidden: insufficient permissions", http.StatusForbidden)
+				apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: insufficient permissions", nil)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -61,6 +133,25 @@ func RequireRole(role string) func(http.Handler) http.Handler {
 	}
 }
 
+// DenyImpersonation rejects requests authenticated with an impersonation
+// token. It guards security-sensitive endpoints such as email changes and
+// account deletion, which only the account owner may use.
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := GetUserFromContext(r.Context())
+		if claims != nil && claims.IsImpersonation() {
+			logger.Log.WithFields(map[string]interface{}{
+				"admin_id": claims.Impersonator,
+				"user_id":  claims.UserID,
+				"path":     r.URL.Path,
+			}).Warn("AUDIT: blocked security-sensitive request under impersonation")
+			apierror.WriteError(w, http.StatusForbidden, apierror.CodeForbidden, "Forbidden: not allowed while impersonating", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // GetUserFromContext extracts user info from the request context
 func GetUserFromContext(ctx context.Context) *jwtutil.Claims {
 	claims, ok := ctx.Value(UserContextKey).(*jwtutil.Claims)
@@ -0,0 +1,14 @@
+package middleware
+
+import "net/http"
+
+// Deprecated marks a route kept only for old clients. Responses carry a
+// Deprecation header and a Link to the route that replaces it, which is
+// built from the request by successor.
+func Deprecated(successor func(r *http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor(r)+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
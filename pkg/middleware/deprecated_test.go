@@ -0,0 +1,29 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+)
+
+func TestDeprecated(t *testing.T) {
+	handler := middleware.Deprecated(func(r *http.Request) string {
+		return "/new" + r.URL.Path
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/old", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want the wrapped handler's 204", w.Code)
+	}
+	if got := w.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation = %q, want true", got)
+	}
+	if got, want := w.Header().Get("Link"), `</new/old>; rel="successor-version"`; got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+}
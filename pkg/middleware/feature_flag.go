@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+)
+
+// RequireFeature hides routes behind a feature flag. Users without the flag
+// get a 404, as if the route did not exist. Must run after AuthMiddleware.
+func RequireFeature(flags *services.FeatureFlagService, name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetUserFromContext(r.Context())
+			if claims == nil || !flags.IsEnabled(r.Context(), name, claims.UserID) {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
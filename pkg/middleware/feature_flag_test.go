@@ -0,0 +1,49 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/internal/models"
+	"github.com/Dias221467/Achievemenet_Manager/internal/repository"
+	"github.com/Dias221467/Achievemenet_Manager/internal/services"
+	"github.com/Dias221467/Achievemenet_Manager/internal/testutil"
+	jwtutil "github.com/Dias221467/Achievemenet_Manager/pkg/jwt"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRequireFeature(t *testing.T) {
+	repos := testutil.NewRepositories(t)
+	flags := services.NewFeatureFlagService(repository.NewFeatureFlagRepository(repos.DB))
+	handler := middleware.RequireFeature(flags, services.FlagChat)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(userID string) int {
+		r := httptest.NewRequest(http.MethodGet, "/chat/groups", nil)
+		if userID != "" {
+			claims := &jwtutil.Claims{UserID: userID, Role: "user"}
+			r = r.WithContext(context.WithValue(r.Context(), middleware.UserContextKey, claims))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	userID := primitive.NewObjectID().Hex()
+
+	if code := serve(userID); code != http.StatusNotFound {
+		t.Errorf("unknown flag: status = %d, want 404", code)
+	}
+	if err := flags.SetFlag(context.Background(), &models.FeatureFlag{Name: services.FlagChat, Enabled: true, RolloutPercent: 100}); err != nil {
+		t.Fatalf("SetFlag: %v", err)
+	}
+	if code := serve(userID); code != http.StatusNoContent {
+		t.Errorf("enabled flag: status = %d, want 204", code)
+	}
+	if code := serve(""); code != http.StatusNotFound {
+		t.Errorf("no user: status = %d, want 404", code)
+	}
+}
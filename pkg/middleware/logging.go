@@ -1,17 +1,117 @@
 package middleware
 
 import (
-	"log"
+	"bufio"
+	"context"
+	"errors"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
-// LoggingMiddleware logs each HTTP request with its method, URI, and duration.
+// Paths that are polled constantly and would only add noise to the logs.
+var skipLoggingPaths = map[string]bool{
+	"/healthz": true,
+	"/health":  true,
+	"/ready":   true,
+	"/metrics": true,
+}
+
+const requestLogKey contextKey = "request_log"
+
+// requestLog collects values that are only known further down the chain
+// (e.g. the user ID set by AuthMiddleware on a subrouter).
+type requestLog struct {
+	userID       string
+	impersonator string
+}
+
+// responseRecorder wraps http.ResponseWriter to capture the status code and body size.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Hijack lets WebSocket upgrades take over the connection through the recorder.
+func (rec *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rec.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	rec.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// LoggingMiddleware logs one structured line per request with method, route
+// template, status, latency, response size, user ID and request ID.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if skipLoggingPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		duration := time.Since(start)
-		log.Printf("%s %s %s", r.Method, r.RequestURI, duration)
+		entry := &requestLog{}
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestLogKey, entry)))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		fields := logrus.Fields{
+			"method":      r.Method,
+			"route":       routeTemplate(r),
+			"status":      rec.status,
+			"duration_ms": time.Since(start).Milliseconds(),
+			"bytes":       rec.bytes,
+			"user_id":     entry.userID,
+			"request_id":  GetRequestID(r.Context()),
+		}
+		if entry.impersonator != "" {
+			fields["impersonator"] = entry.impersonator
+		}
+		logger.Log.WithFields(fields).Info("HTTP request")
 	})
 }
+
+// routeTemplate returns the matched mux route template (e.g. /goals/{id}) so
+// requests for different IDs are grouped together. It falls back to the raw path.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
+
+// setRequestUser records the authenticated user, and the admin impersonating
+// them if any, on the request log.
+func setRequestUser(ctx context.Context, userID, impersonator string) {
+	if entry, ok := ctx.Value(requestLogKey).(*requestLog); ok {
+		entry.userID = userID
+		entry.impersonator = impersonator
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+// newLoggedRouter mirrors cmd/server: the request ID is assigned outside the
+// router, LoggingMiddleware runs on it, and an authenticated subrouter records
+// the user further down the chain.
+func newLoggedRouter(t *testing.T) (http.Handler, *test.Hook) {
+	t.Helper()
+
+	log, hook := test.NewNullLogger()
+	previous := logger.Log
+	logger.Log = log
+	t.Cleanup(func() { logger.Log = previous })
+
+	router := mux.NewRouter()
+	router.Use(LoggingMiddleware)
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	router.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request) {})
+
+	protected := router.PathPrefix("/goals").Subrouter()
+	protected.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			setRequestUser(r.Context(), "user-1", "admin-1")
+			next.ServeHTTP(w, r)
+		})
+	})
+	protected.HandleFunc("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})
+
+	return RequestIDMiddleware(router), hook
+}
+
+func TestLoggingMiddlewareFields(t *testing.T) {
+	handler, hook := newLoggedRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/goals/6512bd43d9caa6e02c990b0a", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(hook.AllEntries()) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(hook.AllEntries()))
+	}
+	entry := hook.LastEntry()
+	if entry.Level != logrus.InfoLevel || entry.Message != "HTTP request" {
+		t.Errorf("entry = %s %q, want info \"HTTP request\"", entry.Level, entry.Message)
+	}
+
+	want := logrus.Fields{
+		"method":       http.MethodPost,
+		"route":        "/goals/{id}",
+		"status":       http.StatusCreated,
+		"bytes":        5,
+		"user_id":      "user-1",
+		"impersonator": "admin-1",
+		"request_id":   "req-42",
+	}
+	for key, value := range want {
+		if entry.Data[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry.Data[key], value)
+		}
+	}
+	if _, ok := entry.Data["duration_ms"].(int64); !ok {
+		t.Errorf("duration_ms = %v (%T), want milliseconds as int64", entry.Data["duration_ms"], entry.Data["duration_ms"])
+	}
+}
+
+func TestLoggingMiddlewareDefaults(t *testing.T) {
+	handler, hook := newLoggedRouter(t)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public", nil))
+
+	entry := hook.LastEntry()
+	if entry == nil {
+		t.Fatal("request was not logged")
+	}
+	if entry.Data["status"] != http.StatusOK || entry.Data["bytes"] != 0 || entry.Data["user_id"] != "" {
+		t.Errorf("fields = %v, want status 200, 0 bytes and no user", entry.Data)
+	}
+	if _, ok := entry.Data["impersonator"]; ok {
+		t.Error("impersonator logged for a request without one")
+	}
+	if id, _ := entry.Data["request_id"].(string); id == "" {
+		t.Error("generated request ID missing from the log")
+	}
+}
+
+func TestLoggingMiddlewareSkipsProbes(t *testing.T) {
+	handler, hook := newLoggedRouter(t)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if len(hook.AllEntries()) != 0 {
+		t.Errorf("got %d log entries for /healthz, want none", len(hook.AllEntries()))
+	}
+}
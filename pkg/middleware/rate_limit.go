@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/apierror"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+)
+
+const rateLimitKey contextKey = "rate_limit"
+
+// slidingWindow counts requests for one key. The estimate weights the
+// previous window's count by how much of it still overlaps the sliding window.
+type slidingWindow struct {
+	mu    sync.Mutex
+	start time.Time
+	curr  int
+	prev  int
+}
+
+// advance moves the window forward so that it contains now. Callers hold the lock.
+func (sw *slidingWindow) advance(now time.Time, window time.Duration) {
+	elapsed := now.Sub(sw.start)
+	switch {
+	case elapsed < window:
+	case elapsed < 2*window:
+		sw.prev, sw.curr = sw.curr, 0
+		sw.start = sw.start.Add(window)
+	default:
+		sw.prev, sw.curr = 0, 0
+		sw.start = now
+	}
+}
+
+// rateLimiter applies a sliding-window limit per key.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	keys   sync.Map // key -> *slidingWindow
+}
+
+// allow records a request for key. When the key is over its limit it returns
+// false and how long the caller should wait.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	v, _ := l.keys.LoadOrStore(key, &slidingWindow{start: now})
+	sw := v.(*slidingWindow)
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	sw.advance(now, l.window)
+	elapsed := now.Sub(sw.start)
+	overlap := 1 - float64(elapsed)/float64(l.window)
+	if float64(sw.prev)*overlap+float64(sw.curr) >= float64(l.limit) {
+		return false, l.window - elapsed
+	}
+	sw.curr++
+	return true, 0
+}
+
+// refund takes back one request recorded by allow.
+func (l *rateLimiter) refund(key string) {
+	v, ok := l.keys.Load(key)
+	if !ok {
+		return
+	}
+	sw := v.(*slidingWindow)
+	sw.mu.Lock()
+	if sw.curr > 0 {
+		sw.curr--
+	}
+	sw.mu.Unlock()
+}
+
+// sweep forgets keys that have been idle for two windows.
+func (l *rateLimiter) sweep(now time.Time) {
+	l.keys.Range(func(key, v interface{}) bool {
+		sw := v.(*slidingWindow)
+		sw.mu.Lock()
+		idle := now.Sub(sw.start) >= 2*l.window
+		sw.mu.Unlock()
+		if idle {
+			l.keys.Delete(key)
+		}
+		return true
+	})
+}
+
+// rateLimitState is stored in the request context so AuthMiddleware can
+// apply the per-user limit once it knows who the caller is.
+type rateLimitState struct {
+	perIP   *rateLimiter
+	perUser *rateLimiter
+	ip      string
+}
+
+// RateLimiterMiddleware limits requests with a sliding window per client IP
+// and per authenticated user. Every request counts against its IP; requests
+// that AuthMiddleware authenticates are taken off the IP count again and
+// counted against the user instead, so the per-IP limit effectively covers
+// unauthenticated traffic such as login and register. A limit of 0 or less
+// turns that check off. Rejected requests get 429 with a Retry-After header.
+//
+// Counts are kept in memory, so each instance enforces its own limits.
+func RateLimiterMiddleware(perIPLimit, perUserLimit int, window time.Duration) func(http.Handler) http.Handler {
+	var perIP, perUser *rateLimiter
+	if perIPLimit > 0 {
+		perIP = &rateLimiter{limit: perIPLimit, window: window}
+	}
+	if perUserLimit > 0 {
+		perUser = &rateLimiter{limit: perUserLimit, window: window}
+	}
+
+	go func() {
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if perIP != nil {
+				perIP.sweep(now)
+			}
+			if perUser != nil {
+				perUser.sweep(now)
+			}
+		}
+	}()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := &rateLimitState{perIP: perIP, perUser: perUser, ip: clientIP(r)}
+
+			if perIP != nil {
+				if ok, retryAfter := perIP.allow(state.ip, time.Now()); !ok {
+					logger.Log.WithField("ip", state.ip).Warn("Rate limit exceeded for IP")
+					writeTooManyRequests(w, retryAfter)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rateLimitKey, state)))
+		})
+	}
+}
+
+// PerIPRateLimitMiddleware limits requests per client IP with its own sliding
+// window, on top of RateLimiterMiddleware. It is meant for unauthenticated
+// routes that need a tighter limit than the global one. A limit of 0 or less
+// turns it off.
+func PerIPRateLimitMiddleware(limit int, window time.Duration) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	perIP := &rateLimiter{limit: limit, window: window}
+
+	go func() {
+		ticker := time.NewTicker(window)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			perIP.sweep(now)
+		}
+	}()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r)
+			if ok, retryAfter := perIP.allow(ip, time.Now()); !ok {
+				logger.Log.WithFields(map[string]interface{}{"ip": ip, "path": r.URL.Path}).Warn("Rate limit exceeded for IP on public route")
+				writeTooManyRequests(w, retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitUser moves an authenticated request from the IP count to the user's
+// count. It writes a 429 response and returns false when the user is over
+// their limit.
+func limitUser(w http.ResponseWriter, r *http.Request, userID string) bool {
+	state, ok := r.Context().Value(rateLimitKey).(*rateLimitState)
+	if !ok {
+		return true
+	}
+	if state.perIP != nil {
+		state.perIP.refund(state.ip)
+	}
+	if state.perUser == nil {
+		return true
+	}
+	if ok, retryAfter := state.perUser.allow(userID, time.Now()); !ok {
+		logger.Log.WithField("user_id", userID).Warn("Rate limit exceeded for user")
+		writeTooManyRequests(w, retryAfter)
+		return false
+	}
+	return true
+}
+
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	apierror.WriteError(w, http.StatusTooManyRequests, apierror.CodeTooManyRequests, "Too many requests", nil)
+}
+
+// clientIP returns the IP of the connection. Forwarding headers are ignored
+// because clients can set them to anything.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
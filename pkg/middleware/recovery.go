@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// RecoveryMiddleware turns a panic anywhere further down the chain into a
+// logged stack trace and a 500 response instead of a dropped connection.
+// It is meant to be the outermost middleware, so it reads the request ID
+// from the response header RequestIDMiddleware sets.
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// net/http uses this panic to abort a response on purpose
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			metrics.PanicTotal.Inc()
+			requestID := w.Header().Get("X-Request-ID")
+			logger.Log.WithFields(logrus.Fields{
+				"panic":      rec,
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"request_id": requestID,
+				"stack":      string(debug.Stack()),
+			}).Error("Recovered from panic")
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error":      "internal_server_error",
+				"request_id": requestID,
+			})
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
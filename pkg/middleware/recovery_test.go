@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dias221467/Achievemenet_Manager/pkg/logger"
+	"github.com/Dias221467/Achievemenet_Manager/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRecoveryMiddlewareCountsPanics(t *testing.T) {
+	log, _ := test.NewNullLogger()
+	previous := logger.Log
+	logger.Log = log
+	t.Cleanup(func() { logger.Log = previous })
+
+	handler := RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	before := testutil.ToFloat64(metrics.PanicTotal)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/goals", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if got := testutil.ToFloat64(metrics.PanicTotal) - before; got != 1 {
+		t.Errorf("panic_total grew by %v, want 1", got)
+	}
+}
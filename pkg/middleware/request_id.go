@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const requestIDKey contextKey = "request_id"
+
+// maxRequestIDLen caps client-supplied request IDs so they cannot bloat the logs.
+const maxRequestIDLen = 128
+
+// RequestIDMiddleware gives every request an ID for correlating log entries.
+// It reuses the caller's X-Request-ID header when it looks sane and generates
+// one otherwise. The ID is stored in the context and echoed in the response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// GetRequestID returns the ID RequestIDMiddleware assigned to the request,
+// or "" outside of a request.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// validRequestID accepts short IDs made of printable ASCII without spaces,
+// which covers UUIDs and the formats common proxies generate.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
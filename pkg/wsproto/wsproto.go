@@ -0,0 +1,294 @@
+// Package wsproto defines the frames exchanged over the chat WebSocket.
+//
+// Every frame travels in an Envelope carrying the protocol version, the frame
+// type and the type-specific payload:
+//
+//	{"v": 1, "type": "text", "id": "c-42", "data": {"chat_id": "...", "content": "hi"}}
+//
+// A connection starts with the client sending a hello frame listing the
+// versions it speaks; the server answers with a hello frame naming the
+// version it picked. Handlers should only marshal and unmarshal frames
+// through Encode and Decode so field names stay consistent across clients.
+package wsproto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Version is the newest protocol version this server speaks.
+const Version = 1
+
+// SupportedVersions lists every protocol version the server accepts, newest first.
+var SupportedVersions = []int{Version}
+
+// Type names a frame type.
+type Type string
+
+// Frame types.
+const (
+	TypeHello    Type = "hello"
+	TypeText     Type = "text"
+	TypeFile     Type = "file"
+	TypeTyping   Type = "typing"
+	TypeStatus   Type = "status"
+	TypeRead     Type = "read"
+	TypeReaction Type = "reaction"
+	TypeError    Type = "error"
+	TypeAck      Type = "ack"
+
+	TypeNotification   Type = "notification"
+	TypeMessageDeleted Type = "message_deleted"
+	TypeGroupText      Type = "group_text"
+)
+
+// Error codes sent in error frames.
+const (
+	CodeUnknownType        = "unknown_type"
+	CodeBadFrame           = "bad_frame"
+	CodeUnsupportedVersion = "unsupported_version"
+	CodeRejected           = "rejected" // a well-formed frame the server refused, e.g. for a chat the user is not in
+)
+
+var (
+	// ErrUnknownType is returned by Decode for a frame type it does not know.
+	ErrUnknownType = errors.New("unknown frame type")
+	// ErrUnsupportedVersion is returned by Decode and Negotiate when no common
+	// protocol version exists.
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+)
+
+// Envelope is the outer shape of every frame on the wire.
+type Envelope struct {
+	V    int             `json:"v"`
+	Type Type            `json:"type"`
+	ID   string          `json:"id,omitempty"` // client-chosen ID echoed in ack and error frames
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Frame is implemented by every frame payload.
+type Frame interface {
+	FrameType() Type
+}
+
+// Hello opens a connection. Clients send the versions they speak in
+// Versions; the server replies with the chosen one in Version.
+type Hello struct {
+	Versions []int `json:"versions,omitempty"`
+	Version  int   `json:"version,omitempty"`
+}
+
+// Text is a plain chat message.
+type Text struct {
+	ChatID    string     `json:"chat_id"`
+	MessageID string     `json:"message_id,omitempty"`
+	SenderID  string     `json:"sender_id,omitempty"`
+	Content   string     `json:"content"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
+
+// File is a chat message carrying an uploaded file.
+type File struct {
+	ChatID    string     `json:"chat_id"`
+	MessageID string     `json:"message_id,omitempty"`
+	SenderID  string     `json:"sender_id,omitempty"`
+	FileURL   string     `json:"file_url"`
+	FileName  string     `json:"file_name,omitempty"`
+	MimeType  string     `json:"mime_type,omitempty"`
+	Size      int64      `json:"size,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
+
+// Typing tells the other members of a chat that a user started or stopped typing.
+type Typing struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id,omitempty"`
+	Typing bool   `json:"typing"`
+}
+
+// Status reports a user's presence.
+type Status struct {
+	UserID   string     `json:"user_id"`
+	Online   bool       `json:"online"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// Read marks every message up to MessageID as read by the user. Direct chats
+// set Until instead: every message sent before it has been read.
+type Read struct {
+	ChatID    string     `json:"chat_id"`
+	MessageID string     `json:"message_id,omitempty"`
+	UserID    string     `json:"user_id,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+}
+
+// Reaction adds or, with Removed set, removes an emoji reaction on a message.
+type Reaction struct {
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+	UserID    string `json:"user_id,omitempty"`
+	Emoji     string `json:"emoji"`
+	Removed   bool   `json:"removed,omitempty"`
+}
+
+// Error reports a problem with a frame the peer sent. RefID is the ID of
+// that frame, if it had one.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	RefID   string `json:"ref_id,omitempty"`
+}
+
+// Ack confirms that the frame with RefID was processed. MessageID is set when
+// the frame created a stored message.
+type Ack struct {
+	RefID     string `json:"ref_id"`
+	MessageID string `json:"message_id,omitempty"`
+}
+
+// Notification pushes a newly created notification to its recipient. The
+// fields mirror the notification returned by GET /notifications.
+type Notification struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	TargetID  string    `json:"target_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MessageDeleted tells the members of a chat that a message was soft-deleted.
+// Clients should replace the message with a tombstone rather than drop it.
+type MessageDeleted struct {
+	ChatID    string     `json:"chat_id"`
+	MessageID string     `json:"message_id"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// GroupText is a plain message in a group chat.
+type GroupText struct {
+	GroupID   string     `json:"group_id"`
+	MessageID string     `json:"message_id,omitempty"`
+	SenderID  string     `json:"sender_id,omitempty"`
+	Text      string     `json:"text"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
+
+func (Hello) FrameType() Type    { return TypeHello }
+func (Text) FrameType() Type     { return TypeText }
+func (File) FrameType() Type     { return TypeFile }
+func (Typing) FrameType() Type   { return TypeTyping }
+func (Status) FrameType() Type   { return TypeStatus }
+func (Read) FrameType() Type     { return TypeRead }
+func (Reaction) FrameType() Type { return TypeReaction }
+func (Error) FrameType() Type    { return TypeError }
+func (Ack) FrameType() Type      { return TypeAck }
+
+func (Notification) FrameType() Type   { return TypeNotification }
+func (MessageDeleted) FrameType() Type { return TypeMessageDeleted }
+func (GroupText) FrameType() Type      { return TypeGroupText }
+
+// newFrame returns an empty payload for the given type, or nil if the type is unknown.
+func newFrame(t Type) Frame {
+	switch t {
+	case TypeHello:
+		return &Hello{}
+	case TypeText:
+		return &Text{}
+	case TypeFile:
+		return &File{}
+	case TypeTyping:
+		return &Typing{}
+	case TypeStatus:
+		return &Status{}
+	case TypeRead:
+		return &Read{}
+	case TypeReaction:
+		return &Reaction{}
+	case TypeError:
+		return &Error{}
+	case TypeAck:
+		return &Ack{}
+	case TypeNotification:
+		return &Notification{}
+	case TypeMessageDeleted:
+		return &MessageDeleted{}
+	case TypeGroupText:
+		return &GroupText{}
+	}
+	return nil
+}
+
+// Encode wraps a frame in an envelope of the current version and marshals it.
+func Encode(id string, frame Frame) ([]byte, error) {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s frame: %w", frame.FrameType(), err)
+	}
+	return json.Marshal(Envelope{V: Version, Type: frame.FrameType(), ID: id, Data: data})
+}
+
+// Decode parses a frame off the wire. The returned frame is a pointer to the
+// payload type matching the envelope, e.g. *Text. The envelope is returned
+// even on error when it could be parsed, so the caller can reference its ID
+// in an error frame.
+func Decode(raw []byte) (*Envelope, Frame, error) {
+	var env Envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, nil, fmt.Errorf("invalid frame: %w", err)
+	}
+	if !supported(env.V) {
+		return &env, nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, env.V)
+	}
+
+	frame := newFrame(env.Type)
+	if frame == nil {
+		return &env, nil, fmt.Errorf("%w: %q", ErrUnknownType, env.Type)
+	}
+	if len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, frame); err != nil {
+			return &env, nil, fmt.Errorf("invalid %s frame: %w", env.Type, err)
+		}
+	}
+	return &env, frame, nil
+}
+
+// ErrorFor builds the error frame to send back for a Decode failure. env may be nil.
+func ErrorFor(env *Envelope, err error) Error {
+	frame := Error{Code: CodeBadFrame, Message: err.Error()}
+	if env != nil {
+		frame.RefID = env.ID
+	}
+	switch {
+	case errors.Is(err, ErrUnknownType):
+		frame.Code = CodeUnknownType
+	case errors.Is(err, ErrUnsupportedVersion):
+		frame.Code = CodeUnsupportedVersion
+	}
+	return frame
+}
+
+// Negotiate picks the newest version offered by the client's hello that the
+// server supports.
+func Negotiate(hello Hello) (int, error) {
+	for _, v := range SupportedVersions {
+		for _, offered := range hello.Versions {
+			if v == offered {
+				return v, nil
+			}
+		}
+	}
+	return 0, ErrUnsupportedVersion
+}
+
+func supported(v int) bool {
+	for _, s := range SupportedVersions {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package wsproto
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	at := time.Date(2026, 4, 2, 9, 30, 0, 0, time.UTC)
+
+	frames := []Frame{
+		Hello{Versions: []int{2, 1}},
+		Hello{Version: 1},
+		Text{ChatID: "c1", MessageID: "m1", SenderID: "u1", Content: "hi", SentAt: &at},
+		File{ChatID: "c1", MessageID: "m2", SenderID: "u1", FileURL: "https://files/x.png", FileName: "x.png", MimeType: "image/png", Size: 2048, SentAt: &at},
+		Typing{ChatID: "c1", UserID: "u1", Typing: true},
+		Status{UserID: "u1", Online: false, LastSeen: &at},
+		Read{ChatID: "c1", MessageID: "m1", UserID: "u2"},
+		Reaction{ChatID: "c1", MessageID: "m1", UserID: "u2", Emoji: "👍", Removed: true},
+		Error{Code: CodeBadFrame, Message: "bad", RefID: "r1"},
+		Ack{RefID: "r1", MessageID: "m1"},
+		Notification{ID: "n1", Type: "goal_completed", Title: "Done", Message: "You did it", TargetID: "g1", CreatedAt: at},
+		MessageDeleted{ChatID: "c1", MessageID: "m1", DeletedBy: "u1", DeletedAt: &at},
+		GroupText{GroupID: "g1", MessageID: "m3", SenderID: "u1", Text: "hello all", SentAt: &at},
+	}
+
+	covered := map[Type]bool{}
+	for _, frame := range frames {
+		covered[frame.FrameType()] = true
+
+		raw, err := Encode("id-1", frame)
+		if err != nil {
+			t.Fatalf("Encode(%s): %v", frame.FrameType(), err)
+		}
+		env, decoded, err := Decode(raw)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", frame.FrameType(), err)
+		}
+		if env.V != Version || env.Type != frame.FrameType() || env.ID != "id-1" {
+			t.Errorf("envelope = %+v, want v%d %s id-1", env, Version, frame.FrameType())
+		}
+
+		// Decode returns a pointer to the payload type
+		got := reflect.ValueOf(decoded).Elem().Interface()
+		if !reflect.DeepEqual(got, frame) {
+			t.Errorf("%s round trip = %+v, want %+v", frame.FrameType(), got, frame)
+		}
+	}
+
+	for _, typ := range []Type{
+		TypeHello, TypeText, TypeFile, TypeTyping, TypeStatus, TypeRead, TypeReaction,
+		TypeError, TypeAck, TypeNotification, TypeMessageDeleted, TypeGroupText,
+	} {
+		if newFrame(typ) == nil {
+			t.Errorf("newFrame(%s) = nil", typ)
+		}
+		if !covered[typ] {
+			t.Errorf("no round trip case for %s", typ)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantErr  error
+		wantCode string
+		wantRef  string
+	}{
+		{name: "not json", raw: `{`, wantCode: CodeBadFrame},
+		{name: "unknown type", raw: `{"v":1,"type":"shout","id":"a"}`, wantErr: ErrUnknownType, wantCode: CodeUnknownType, wantRef: "a"},
+		{name: "unsupported version", raw: `{"v":9,"type":"text","id":"b"}`, wantErr: ErrUnsupportedVersion, wantCode: CodeUnsupportedVersion, wantRef: "b"},
+		{name: "bad payload", raw: `{"v":1,"type":"typing","id":"c","data":{"typing":"yes"}}`, wantCode: CodeBadFrame, wantRef: "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, frame, err := Decode([]byte(tt.raw))
+			if err == nil {
+				t.Fatalf("Decode returned %+v, want an error", frame)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			errFrame := ErrorFor(env, err)
+			if errFrame.Code != tt.wantCode || errFrame.RefID != tt.wantRef {
+				t.Errorf("ErrorFor = %+v, want code %s ref %q", errFrame, tt.wantCode, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestDecodeWithoutData(t *testing.T) {
+	raw, err := json.Marshal(Envelope{V: Version, Type: TypeAck})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, frame, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if _, ok := frame.(*Ack); !ok {
+		t.Errorf("frame = %T, want *Ack", frame)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	if v, err := Negotiate(Hello{Versions: []int{3, Version}}); err != nil || v != Version {
+		t.Errorf("Negotiate = %d, %v; want %d", v, err, Version)
+	}
+	if _, err := Negotiate(Hello{Versions: []int{99}}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("err = %v, want ErrUnsupportedVersion", err)
+	}
+}